@@ -0,0 +1,214 @@
+// Command dashgen writes the Grafana dashboard and Prometheus burn-rate
+// alert rules for the server's /metrics into dashboards/, so they always
+// use the metric names and labels httpapi serves.
+//
+//  dashgen [-out DIR]
+//
+// Run it with go generate after changing the metrics; the output is
+// committed. The alerts follow the multiwindow, multi-burn-rate scheme:
+// page when a 1h and a 5m window both burn the error budget 14.4 times too
+// fast, or a 6h and a 30m window 6 times.
+package main
+
+//go:generate go run . -out ../../dashboards
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "p2p-file-share-backend/httpapi"
+)
+
+// burnRule is one multiwindow burn-rate alert
+type burnRule struct {
+    long, short string
+    factor      float64
+    severity    string
+}
+
+var burnRules = []burnRule{
+    {"1h", "5m", 14.4, "page"},
+    {"6h", "30m", 6, "page"},
+    {"3d", "6h", 1, "ticket"},
+}
+
+// sli is one SLI as a ratio of good to eligible requests
+type sli struct {
+    name, total, good string
+}
+
+var slis = []sli{
+    {"availability", httpapi.MetricAvailability, httpapi.MetricAvailabilityGood},
+    {"latency", httpapi.MetricLatency, httpapi.MetricLatencyGood},
+}
+
+func main() {
+    out := flag.String("out", "dashboards", "directory to write the dashboard and rules to")
+    flag.Parse()
+
+    if err := os.MkdirAll(*out, 0o755); err != nil {
+        log.Fatal(err)
+    }
+    dashboard, err := json.MarshalIndent(sloDashboard(), "", "  ")
+    if err != nil {
+        log.Fatal(err)
+    }
+    write(filepath.Join(*out, "slo.json"), append(dashboard, '\n'))
+    write(filepath.Join(*out, "slo-alerts.yaml"), []byte(alertRules()))
+}
+
+func write(path string, data []byte) {
+    if err := os.WriteFile(path, data, 0o644); err != nil {
+        log.Fatal(err)
+    }
+    fmt.Println("wrote", path)
+}
+
+// errorRatio is the PromQL fraction of s's eligible requests that were bad
+// over window, optionally for the dashboard's selected routes
+func errorRatio(s sli, window, selector string) string {
+    return fmt.Sprintf("(1 - sum(rate(%s%s[%s])) / sum(rate(%s%s[%s])))", s.good, selector, window, s.total, selector, window)
+}
+
+// budget is the PromQL error budget of s
+func budget(s sli) string {
+    return fmt.Sprintf("(1 - scalar(%s{sli=%q}))", httpapi.MetricObjective, s.name)
+}
+
+// target is a Grafana query
+type target struct {
+    Expr         string `json:"expr"`
+    LegendFormat string `json:"legendFormat,omitempty"`
+    RefID        string `json:"refId"`
+}
+
+type gridPos struct {
+    H int `json:"h"`
+    W int `json:"w"`
+    X int `json:"x"`
+    Y int `json:"y"`
+}
+
+type panel struct {
+    ID          int            `json:"id"`
+    Type        string         `json:"type"`
+    Title       string         `json:"title"`
+    Description string         `json:"description,omitempty"`
+    GridPos     gridPos        `json:"gridPos"`
+    Datasource  map[string]any `json:"datasource"`
+    Targets     []target       `json:"targets"`
+    FieldConfig map[string]any `json:"fieldConfig,omitempty"`
+}
+
+// sloDashboard builds the SLO dashboard: attainment and burn rate per SLI,
+// then traffic, errors and latency by route
+func sloDashboard() map[string]any {
+    const selector = `{route=~"$route"}`
+    datasource := map[string]any{"type": "prometheus", "uid": "${datasource}"}
+    var panels []panel
+    add := func(typ, title, description string, w, h int, unit string, targets ...target) {
+        x, y := 0, 0
+        if n := len(panels); n > 0 {
+            last := panels[n-1].GridPos
+            x, y = last.X+last.W, last.Y
+            if x+w > 24 {
+                x, y = 0, last.Y+last.H
+            }
+        }
+        for i := range targets {
+            targets[i].RefID = string(rune('A' + i))
+        }
+        p := panel{
+            ID:          len(panels) + 1,
+            Type:        typ,
+            Title:       title,
+            Description: description,
+            GridPos:     gridPos{H: h, W: w, X: x, Y: y},
+            Datasource:  datasource,
+            Targets:     targets,
+        }
+        if unit != "" {
+            p.FieldConfig = map[string]any{"defaults": map[string]any{"unit": unit}, "overrides": []any{}}
+        }
+        panels = append(panels, p)
+    }
+
+    for _, s := range slis {
+        add("stat", strings.ToUpper(s.name[:1])+s.name[1:]+" (30d)", "Good requests over eligible requests against the objective", 6, 6, "percentunit",
+            target{Expr: fmt.Sprintf("1 - %s", errorRatio(s, "30d", selector)), LegendFormat: "attained"},
+            target{Expr: fmt.Sprintf("%s{sli=%q}", httpapi.MetricObjective, s.name), LegendFormat: "objective"})
+        var burn []target
+        for _, r := range burnRules[:2] {
+            for _, w := range []string{r.long, r.short} {
+                burn = append(burn, target{Expr: fmt.Sprintf("%s / %s", errorRatio(s, w, selector), budget(s)), LegendFormat: w})
+            }
+        }
+        add("timeseries", strings.ToUpper(s.name[:1])+s.name[1:]+" burn rate", "How many times faster than sustainable the error budget is being spent; alerts fire at 14.4 (1h/5m) and 6 (6h/30m)", 18, 6, "",
+            burn...)
+    }
+
+    add("timeseries", "Requests by status class", "", 12, 8, "reqps",
+        target{Expr: fmt.Sprintf("sum by (code) (rate(%s%s[5m]))", httpapi.MetricRequests, selector), LegendFormat: "{{code}}"})
+    add("timeseries", "Server errors by route", "Routes answering 5xx", 12, 8, "reqps",
+        target{Expr: fmt.Sprintf(`topk(10, sum by (route, method) (rate(%s{route=~"$route",code="5xx"}[5m])))`, httpapi.MetricRequests), LegendFormat: "{{method}} {{route}}"})
+    var quantiles []target
+    for _, q := range []string{"0.5", "0.95", "0.99"} {
+        quantiles = append(quantiles, target{
+            Expr:         fmt.Sprintf("histogram_quantile(%s, sum by (le) (rate(%s_bucket%s[5m])))", q, httpapi.MetricDuration, selector),
+            LegendFormat: "p" + strings.TrimPrefix(q, "0."),
+        })
+    }
+    add("timeseries", "Latency", "Non-streaming, non-5xx responses", 12, 8, "s", quantiles...)
+    add("timeseries", "Slowest routes (p99)", "", 12, 8, "s",
+        target{Expr: fmt.Sprintf("topk(10, histogram_quantile(0.99, sum by (le, route, method) (rate(%s_bucket%s[5m]))))", httpapi.MetricDuration, selector), LegendFormat: "{{method}} {{route}}"})
+    add("timeseries", "Rooms and peers", "", 24, 6, "",
+        target{Expr: fmt.Sprintf("sum(%s)", httpapi.MetricRooms), LegendFormat: "rooms"},
+        target{Expr: fmt.Sprintf("sum(%s)", httpapi.MetricPeers), LegendFormat: "peers"})
+
+    return map[string]any{
+        "uid":           "p2p-slo",
+        "title":         "P2P signaling SLOs",
+        "tags":          []string{"p2p", "slo"},
+        "schemaVersion": 39,
+        "time":          map[string]any{"from": "now-24h", "to": "now"},
+        "refresh":       "1m",
+        "templating": map[string]any{"list": []any{
+            map[string]any{"name": "datasource", "type": "datasource", "query": "prometheus"},
+            map[string]any{
+                "name":       "route",
+                "type":       "query",
+                "datasource": datasource,
+                "query":      fmt.Sprintf("label_values(%s, route)", httpapi.MetricRequests),
+                "includeAll": true,
+                "multi":      true,
+                "allValue":   ".*",
+                "current":    map[string]any{"text": "All", "value": "$__all"},
+            },
+        }},
+        "panels": panels,
+    }
+}
+
+// alertRules renders the burn-rate alerts as a Prometheus rules file
+func alertRules() string {
+    var b strings.Builder
+    b.WriteString("# Generated by cmd/dashgen; do not edit.\ngroups:\n")
+    for _, s := range slis {
+        fmt.Fprintf(&b, "  - name: p2p-slo-%s\n    rules:\n", s.name)
+        for _, r := range burnRules {
+            name := fmt.Sprintf("P2P%sBurnRate%s", strings.ToUpper(s.name[:1])+s.name[1:], strings.ToUpper(r.long))
+            expr := fmt.Sprintf("%s > %g * %s and %s > %g * %s",
+                errorRatio(s, r.long, ""), r.factor, budget(s), errorRatio(s, r.short, ""), r.factor, budget(s))
+            fmt.Fprintf(&b, "      - alert: %s\n", name)
+            fmt.Fprintf(&b, "        expr: %s\n", expr)
+            fmt.Fprintf(&b, "        labels:\n          severity: %s\n          sli: %s\n", r.severity, s.name)
+            fmt.Fprintf(&b, "        annotations:\n          summary: %s error budget burning %gx too fast over %s and %s\n", s.name, r.factor, r.long, r.short)
+        }
+    }
+    return b.String()
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "math/rand"
+    "net/http"
+    "os"
+    "sort"
+    "sync"
+    "text/tabwriter"
+    "time"
+
+    "p2p-file-share-backend/server"
+)
+
+// loadConfig describes one load test run
+type loadConfig struct {
+    server   string
+    peers    int
+    rooms    int
+    duration time.Duration
+    interval time.Duration
+    churn    float64
+}
+
+func (cfg loadConfig) inProcess() bool {
+    return cfg.server == ""
+}
+
+// opStats collects the latencies of one kind of request
+type opStats struct {
+    name string
+
+    mu        sync.Mutex
+    latencies []time.Duration
+    errors    int
+}
+
+func (s *opStats) record(d time.Duration, err error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if err != nil {
+        s.errors++
+        return
+    }
+    s.latencies = append(s.latencies, d)
+}
+
+// percentile returns the p-th percentile latency. Call only after the run.
+func (s *opStats) percentile(p float64) time.Duration {
+    if len(s.latencies) == 0 {
+        return 0
+    }
+    i := int(float64(len(s.latencies)-1) * p / 100)
+    return s.latencies[i]
+}
+
+// loadReport is the outcome of a run
+type loadReport struct {
+    elapsed time.Duration
+    ops     []*opStats
+}
+
+func (r *loadReport) print(w io.Writer) {
+    total := 0
+    tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+    fmt.Fprintln(tw, "OP\tCOUNT\tERRORS\tP50\tP90\tP99\tMAX\t")
+    for _, op := range r.ops {
+        sort.Slice(op.latencies, func(i, j int) bool { return op.latencies[i] < op.latencies[j] })
+        total += len(op.latencies) + op.errors
+        max := time.Duration(0)
+        if n := len(op.latencies); n > 0 {
+            max = op.latencies[n-1]
+        }
+        fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", op.name, len(op.latencies), op.errors,
+            round(op.percentile(50)), round(op.percentile(90)), round(op.percentile(99)), round(max))
+    }
+    tw.Flush()
+    fmt.Fprintf(w, "\n%d requests in %s (%.0f req/s)\n", total, r.elapsed.Round(time.Millisecond),
+        float64(total)/r.elapsed.Seconds())
+}
+
+func round(d time.Duration) time.Duration {
+    return d.Round(time.Microsecond)
+}
+
+// loadRun is the shared state of the simulated peers
+type loadRun struct {
+    cfg    loadConfig
+    base   string
+    client *http.Client
+
+    join, heartbeat, poll, peers, leave *opStats
+}
+
+// runLoad simulates cfg.peers peers until cfg.duration has passed
+func runLoad(cfg loadConfig) *loadReport {
+    base := cfg.server
+    if cfg.inProcess() {
+        // Every simulated peer comes from 127.0.0.1, so lift the per-IP
+        // room quotas for the in-process server
+        os.Setenv("MAX_ROOMS_PER_IP", "0")
+        os.Setenv("ROOMS_PER_IP_PER_HOUR", "0")
+        ts := server.NewTestServer()
+        defer ts.Close()
+        base = ts.URL
+    }
+
+    run := &loadRun{
+        cfg:  cfg,
+        base: base,
+        client: &http.Client{
+            Timeout: 10 * time.Second,
+            Transport: &http.Transport{
+                MaxIdleConns:        cfg.peers,
+                MaxIdleConnsPerHost: cfg.peers,
+            },
+        },
+        join:      &opStats{name: "join"},
+        heartbeat: &opStats{name: "heartbeat"},
+        poll:      &opStats{name: "poll"},
+        peers:     &opStats{name: "peers"},
+        leave:     &opStats{name: "leave"},
+    }
+
+    fmt.Fprintf(os.Stderr, "loadgen: %d peers across %d rooms for %s against %s\n", cfg.peers, cfg.rooms, cfg.duration, base)
+
+    start := time.Now()
+    deadline := start.Add(cfg.duration)
+    runID := start.UnixNano()
+
+    var wg sync.WaitGroup
+    for i := 0; i < cfg.peers; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            peerID := fmt.Sprintf("load-%d-%d", runID, i)
+            roomCode := fmt.Sprintf("LOAD%d", i%cfg.rooms)
+            run.simulatePeer(peerID, roomCode, deadline, rand.New(rand.NewSource(runID+int64(i))))
+        }(i)
+    }
+    wg.Wait()
+
+    return &loadReport{
+        elapsed: time.Since(start),
+        ops:     []*opStats{run.join, run.heartbeat, run.poll, run.peers, run.leave},
+    }
+}
+
+// simulatePeer joins a room, then heartbeats and polls every interval,
+// occasionally leaving and rejoining, until the deadline
+func (run *loadRun) simulatePeer(peerID, roomCode string, deadline time.Time, rng *rand.Rand) {
+    // Spread the initial joins over one interval so they don't all land at once
+    time.Sleep(time.Duration(rng.Int63n(int64(run.cfg.interval))))
+
+    joined := run.joinRoom(peerID, roomCode)
+    for time.Now().Before(deadline) {
+        if !joined {
+            joined = run.joinRoom(peerID, roomCode)
+        } else if rng.Float64() < run.cfg.churn {
+            run.timed(run.leave, http.MethodPost, "/room/leave", map[string]string{"roomCode": roomCode, "peerId": peerID})
+            joined = run.joinRoom(peerID, roomCode)
+        } else {
+            run.timed(run.heartbeat, http.MethodPost, "/room/heartbeat", map[string]string{"roomCode": roomCode, "peerId": peerID})
+            run.timed(run.poll, http.MethodGet, "/notifications/"+peerID, nil)
+            run.timed(run.peers, http.MethodGet, "/room/"+roomCode+"/peers", nil)
+        }
+
+        // Jitter each round by ±20% so the peers don't move in lockstep
+        jitter := time.Duration(float64(run.cfg.interval) * (0.8 + 0.4*rng.Float64()))
+        time.Sleep(jitter)
+    }
+
+    if joined {
+        run.timed(run.leave, http.MethodPost, "/room/leave", map[string]string{"roomCode": roomCode, "peerId": peerID})
+    }
+}
+
+// joinRoom creates the room, or joins it if it already exists
+func (run *loadRun) joinRoom(peerID, roomCode string) bool {
+    return run.timed(run.join, http.MethodPost, "/room/create", map[string]string{"roomCode": roomCode, "peerId": peerID}) == nil
+}
+
+// timed sends one request and records its latency under stats
+func (run *loadRun) timed(stats *opStats, method, path string, body interface{}) error {
+    var reader io.Reader
+    if body != nil {
+        data, err := json.Marshal(body)
+        if err != nil {
+            return err
+        }
+        reader = bytes.NewReader(data)
+    }
+
+    req, err := http.NewRequest(method, run.base+path, reader)
+    if err != nil {
+        return err
+    }
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+
+    start := time.Now()
+    resp, err := run.client.Do(req)
+    if err == nil {
+        io.Copy(io.Discard, resp.Body)
+        resp.Body.Close()
+        if resp.StatusCode >= 300 {
+            err = fmt.Errorf("%s %s: %d", method, path, resp.StatusCode)
+        }
+    }
+    stats.record(time.Since(start), err)
+    return err
+}
@@ -0,0 +1,109 @@
+// Command loadgen simulates thousands of peers joining, leaving and polling
+// rooms and reports per-endpoint latency percentiles, so regressions in lock
+// contention show up before a release.
+//
+//  loadgen [-server URL] [-peers N] [-rooms N] [-duration D] [-max-p99 D]
+//          [-cpuprofile F] [-memprofile F] [-mutexprofile F]
+//
+// Without -server the load runs against an in-process server. Remote servers
+// need their per-IP room quotas raised (MAX_ROOMS_PER_IP,
+// ROOMS_PER_IP_PER_HOUR) to at least -rooms. The in-process handler
+// benchmarks live in httpapi: go test -run '^$' -bench . ./httpapi
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "runtime"
+    "runtime/pprof"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+func main() {
+    var cfg loadConfig
+    flag.StringVar(&cfg.server, "server", "", "base URL of the server under test (default: in-process server)")
+    flag.IntVar(&cfg.peers, "peers", 1000, "simulated peers")
+    flag.IntVar(&cfg.rooms, "rooms", 100, "rooms the peers are spread across")
+    flag.DurationVar(&cfg.duration, "duration", 30*time.Second, "how long to run")
+    flag.DurationVar(&cfg.interval, "interval", time.Second, "time between each peer's heartbeat/poll rounds")
+    flag.Float64Var(&cfg.churn, "churn", 0.05, "chance per round that a peer leaves and rejoins")
+    maxP99 := flag.Duration("max-p99", 0, "exit non-zero if any endpoint's p99 exceeds this")
+    cpuProfile := flag.String("cpuprofile", "", "write a CPU profile to this file")
+    memProfile := flag.String("memprofile", "", "write an allocation profile to this file")
+    mutexProfile := flag.String("mutexprofile", "", "write a mutex contention profile to this file")
+    flag.Parse()
+
+    if cfg.peers < 1 || cfg.rooms < 1 {
+        log.Fatal("loadgen: -peers and -rooms must be positive")
+    }
+
+    if cfg.inProcess() {
+        quietServerLogs()
+    }
+
+    if *mutexProfile != "" {
+        runtime.SetMutexProfileFraction(1)
+    }
+    if *cpuProfile != "" {
+        f, err := os.Create(*cpuProfile)
+        if err != nil {
+            log.Fatalf("loadgen: %v", err)
+        }
+        defer f.Close()
+        if err := pprof.StartCPUProfile(f); err != nil {
+            log.Fatalf("loadgen: %v", err)
+        }
+        defer pprof.StopCPUProfile()
+    }
+
+    failed := false
+    report := runLoad(cfg)
+    report.print(os.Stdout)
+    if *maxP99 > 0 {
+        for _, op := range report.ops {
+            if p99 := op.percentile(99); p99 > *maxP99 {
+                fmt.Fprintf(os.Stderr, "loadgen: %s p99 %s exceeds %s\n", op.name, p99, *maxP99)
+                failed = true
+            }
+        }
+    }
+
+    writeProfile("allocs", *memProfile)
+    writeProfile("mutex", *mutexProfile)
+
+    if failed {
+        pprof.StopCPUProfile()
+        os.Exit(1)
+    }
+}
+
+// quietServerLogs silences the in-process server's per-request logging so
+// it doesn't dominate the measurements
+func quietServerLogs() {
+    gin.SetMode(gin.ReleaseMode)
+    gin.DefaultWriter = io.Discard
+    log.SetOutput(io.Discard)
+}
+
+func writeProfile(name, path string) {
+    if path == "" {
+        return
+    }
+    f, err := os.Create(path)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "loadgen: %v\n", err)
+        return
+    }
+    defer f.Close()
+    if name == "allocs" {
+        runtime.GC()
+    }
+    if err := pprof.Lookup(name).WriteTo(f, 0); err != nil {
+        fmt.Fprintf(os.Stderr, "loadgen: writing %s profile: %v\n", name, err)
+    }
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+)
+
+// client calls the admin API with the admin bearer token
+type client struct {
+    baseURL string
+    token   string
+    http    *http.Client
+}
+
+// apiError is a non-2xx response from the server
+type apiError struct {
+    Status  int
+    Message string
+}
+
+func (e *apiError) Error() string {
+    return fmt.Sprintf("server returned %d: %s", e.Status, e.Message)
+}
+
+// newRequest builds an authenticated request for path on the server
+func (c *client) newRequest(ctx context.Context, method, path string, query url.Values) (*http.Request, error) {
+    u := strings.TrimRight(c.baseURL, "/") + path
+    if len(query) > 0 {
+        u += "?" + query.Encode()
+    }
+    req, err := http.NewRequestWithContext(ctx, method, u, nil)
+    if err != nil {
+        return nil, err
+    }
+    if c.token != "" {
+        req.Header.Set("Authorization", "Bearer "+c.token)
+    }
+    return req, nil
+}
+
+// do sends a request and decodes the JSON response into out. The raw body is
+// returned too so -json can print it untouched.
+func (c *client) do(ctx context.Context, method, path string, query url.Values, out interface{}) ([]byte, error) {
+    req, err := c.newRequest(ctx, method, path, query)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := c.http.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode/100 != 2 {
+        return body, responseError(resp.StatusCode, body)
+    }
+    if out != nil {
+        if err := json.Unmarshal(body, out); err != nil {
+            return body, fmt.Errorf("decoding response: %w", err)
+        }
+    }
+    return body, nil
+}
+
+// responseError turns an error response into an apiError, using the
+// server's {"error": ...} message when there is one
+func responseError(status int, body []byte) error {
+    var payload struct {
+        Error string `json:"error"`
+    }
+    msg := strings.TrimSpace(string(body))
+    if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+        msg = payload.Error
+    }
+    if msg == "" {
+        msg = http.StatusText(status)
+    }
+    return &apiError{Status: status, Message: msg}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "p2p-file-share-backend/httpapi"
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// roomPageSize is the largest page GET /admin/rooms serves
+const roomPageSize = 500
+
+func runRooms(ctx context.Context, a *app, args []string) error {
+    fs := subcommandFlags("rooms")
+    mode := fs.String("mode", "", "only rooms in this mode")
+    suspended := fs.String("suspended", "", "only suspended (true) or active (false) rooms")
+    minPeers := fs.Int("min-peers", 0, "only rooms with at least this many peers")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    query := url.Values{"limit": {strconv.Itoa(roomPageSize)}}
+    if *mode != "" {
+        query.Set("mode", *mode)
+    }
+    if *suspended != "" {
+        query.Set("suspended", *suspended)
+    }
+    if *minPeers > 0 {
+        query.Set("minPeers", strconv.Itoa(*minPeers))
+    }
+
+    all := []httpapi.RoomSummary{}
+    total := 0
+    for {
+        var page struct {
+            Rooms      []httpapi.RoomSummary `json:"rooms"`
+            Total      int                   `json:"total"`
+            NextCursor string                `json:"nextCursor"`
+        }
+        reqCtx, cancel := withTimeout(ctx)
+        _, err := a.client.do(reqCtx, http.MethodGet, "/admin/rooms", query, &page)
+        cancel()
+        if err != nil {
+            return err
+        }
+        all = append(all, page.Rooms...)
+        total = page.Total
+        if page.NextCursor == "" {
+            break
+        }
+        query.Set("cursor", page.NextCursor)
+    }
+
+    if a.json {
+        return printJSON(map[string]interface{}{"rooms": all, "total": total})
+    }
+
+    w := newTable()
+    fmt.Fprintln(w, "ROOM\tMODE\tPEERS\tFILES\tCREATED\tSUSPENDED\tHOST")
+    for _, r := range all {
+        fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%t\t%s\n", r.RoomCode, r.Mode, r.Peers, r.Files, ago(r.CreatedAt), r.Suspended, r.HostID)
+    }
+    w.Flush()
+    fmt.Printf("\n%d of %d rooms\n", len(all), total)
+    return nil
+}
+
+func runRoom(ctx context.Context, a *app, args []string) error {
+    fs := subcommandFlags("room")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+    if fs.NArg() != 1 {
+        fs.Usage()
+        return errors.New("expected a room code")
+    }
+
+    var detail struct {
+        Room    httpapi.RoomSummary                     `json:"room"`
+        Version uint64                                  `json:"version"`
+        Peers   []rooms.PeerMetadata                    `json:"peers"`
+        Files   []rooms.FileOffer                       `json:"files"`
+        Backlog map[string][]notifications.Notification `json:"backlog"`
+    }
+    reqCtx, cancel := withTimeout(ctx)
+    defer cancel()
+    body, err := a.client.do(reqCtx, http.MethodGet, "/admin/rooms/"+url.PathEscape(fs.Arg(0)), nil, &detail)
+    if err != nil {
+        return err
+    }
+    if a.json {
+        return printRaw(body)
+    }
+
+    r := detail.Room
+    fmt.Printf("Room %s (%s), version %d\n", r.RoomCode, r.Mode, detail.Version)
+    fmt.Printf("Host %s, created %s", r.HostID, ago(r.CreatedAt))
+    if r.Suspended {
+        fmt.Print(", SUSPENDED")
+    }
+    if r.Pinned {
+        fmt.Print(", PINNED")
+    }
+    fmt.Print("\n\n")
+
+    w := newTable()
+    fmt.Fprintln(w, "PEER\tROLE\tPERMISSIONS\tPRESENCE\tJOINED\tLAST SEEN\tBACKLOG\tNAME")
+    for _, p := range detail.Peers {
+        fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", p.PeerID, p.Role, p.Permissions, p.Presence,
+            ago(p.JoinedAt), ago(p.LastSeen.Load()), len(detail.Backlog[p.PeerID]), p.DisplayName)
+    }
+    w.Flush()
+
+    if len(detail.Files) > 0 {
+        fmt.Println()
+        w = newTable()
+        fmt.Fprintln(w, "FILE\tOWNER\tSIZE\tOFFERED\tNAME")
+        for _, f := range detail.Files {
+            fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", f.FileID, f.PeerID, f.Size, ago(f.OfferedAt), f.Name)
+        }
+        w.Flush()
+    }
+
+    peerIDs := make([]string, 0, len(detail.Backlog))
+    for peerID, pending := range detail.Backlog {
+        if len(pending) > 0 {
+            peerIDs = append(peerIDs, peerID)
+        }
+    }
+    sort.Strings(peerIDs)
+    for _, peerID := range peerIDs {
+        fmt.Printf("\nPending for %s:\n", peerID)
+        for _, n := range detail.Backlog[peerID] {
+            fmt.Printf("  %-20s from %-24s %s\n", n.Type, orDash(n.PeerID), ago(n.Timestamp))
+        }
+    }
+    return nil
+}
+
+func runClose(ctx context.Context, a *app, args []string) error {
+    fs := subcommandFlags("close")
+    reason := fs.String("reason", "admin", "reason sent to the room's peers")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+    if fs.NArg() != 1 {
+        fs.Usage()
+        return errors.New("expected a room code")
+    }
+
+    reqCtx, cancel := withTimeout(ctx)
+    defer cancel()
+    body, err := a.client.do(reqCtx, http.MethodDelete, "/admin/rooms/"+url.PathEscape(fs.Arg(0)), url.Values{"reason": {*reason}}, nil)
+    if err != nil {
+        return err
+    }
+    if a.json {
+        return printRaw(body)
+    }
+    fmt.Printf("Closed room %s\n", fs.Arg(0))
+    return nil
+}
+
+func runTail(ctx context.Context, a *app, args []string) error {
+    fs := subcommandFlags("tail")
+    room := fs.String("room", "", "only events for this room")
+    eventType := fs.String("type", "", "only events of this type")
+    actor := fs.String("actor", "", "only events by or about this peer")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    query := url.Values{}
+    for key, v := range map[string]string{"room": *room, "type": *eventType, "actor": *actor} {
+        if v != "" {
+            query.Set(key, v)
+        }
+    }
+
+    req, err := a.client.newRequest(ctx, http.MethodGet, "/admin/audit/stream", query)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Accept", "text/event-stream")
+    resp, err := a.client.http.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        var body [4096]byte
+        n, _ := resp.Body.Read(body[:])
+        return responseError(resp.StatusCode, body[:n])
+    }
+
+    scanner := bufio.NewScanner(resp.Body)
+    for scanner.Scan() {
+        data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+        if !ok {
+            continue
+        }
+        if a.json {
+            fmt.Println(data)
+            continue
+        }
+        var entry httpapi.AuditEntry
+        if err := json.Unmarshal([]byte(data), &entry); err != nil {
+            continue
+        }
+        details := ""
+        if len(entry.Details) > 0 {
+            encoded, _ := json.Marshal(entry.Details)
+            details = string(encoded)
+        }
+        fmt.Printf("%s  %-8s %-20s actor=%s target=%s %s\n", time.Unix(entry.Time, 0).Format(time.TimeOnly),
+            orDash(entry.RoomCode), entry.Type, orDash(entry.Actor), orDash(entry.Target), details)
+    }
+    if ctx.Err() != nil {
+        return nil
+    }
+    if err := scanner.Err(); err != nil {
+        return err
+    }
+    return errors.New("server closed the stream")
+}
+
+func runTurn(ctx context.Context, a *app, args []string) error {
+    fs := subcommandFlags("turn")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    var result struct {
+        Configured bool `json:"configured"`
+        Check      struct {
+            Status    string `json:"status"`
+            LatencyMs int64  `json:"latencyMs"`
+            Detail    string `json:"detail"`
+            Error     string `json:"error"`
+        } `json:"check"`
+    }
+    reqCtx, cancel := withTimeout(ctx)
+    defer cancel()
+    body, err := a.client.do(reqCtx, http.MethodGet, "/admin/turn", nil, &result)
+
+    // A failed check comes back as 502 with the check result in the body
+    var apiErr *apiError
+    if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadGateway && json.Unmarshal(body, &result) == nil {
+        err = nil
+    }
+    if err != nil {
+        return err
+    }
+    if a.json {
+        return printRaw(body)
+    }
+
+    if !result.Configured {
+        return errors.New("no TURN provider configured (set TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN)")
+    }
+    if result.Check.Status != "ok" {
+        return fmt.Errorf("TURN provider check failed after %dms: %s", result.Check.LatencyMs, result.Check.Error)
+    }
+    fmt.Printf("TURN provider ok (%dms): %s\n", result.Check.LatencyMs, result.Check.Detail)
+    return nil
+}
+
+func newTable() *tabwriter.Writer {
+    return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+func printJSON(v interface{}) error {
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    return enc.Encode(v)
+}
+
+func printRaw(body []byte) error {
+    var v interface{}
+    if err := json.Unmarshal(body, &v); err != nil {
+        return err
+    }
+    return printJSON(v)
+}
+
+// ago renders a Unix timestamp relative to now
+func ago(ts int64) string {
+    if ts == 0 {
+        return "-"
+    }
+    d := time.Since(time.Unix(ts, 0)).Round(time.Second)
+    if d < 0 {
+        return "in " + (-d).String()
+    }
+    return d.String() + " ago"
+}
+
+func orDash(s string) string {
+    if s == "" {
+        return "-"
+    }
+    return s
+}
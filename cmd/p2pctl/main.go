@@ -0,0 +1,128 @@
+// Command p2pctl is a terminal client for the signaling server's admin API:
+// list and inspect rooms, force-close them, tail the audit event stream and
+// verify the TURN provider.
+//
+//  p2pctl [-server URL] [-token TOKEN] [-json] <command> [args]
+//
+// The server and token default to P2PCTL_SERVER and ADMIN_TOKEN.
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "net/http"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+)
+
+const requestTimeout = 30 * time.Second
+
+// command is one p2pctl subcommand
+type command struct {
+    name    string
+    usage   string
+    summary string
+    run     func(ctx context.Context, app *app, args []string) error
+}
+
+// commands is filled in by init because subcommands look up their own usage
+var commands []command
+
+func init() {
+    commands = []command{
+        {"rooms", "rooms [-mode MODE] [-suspended true|false] [-min-peers N]", "list rooms", runRooms},
+        {"room", "room CODE", "show a room's peers, files and notification backlogs", runRoom},
+        {"close", "close [-reason REASON] CODE", "force-close a room", runClose},
+        {"tail", "tail [-room CODE] [-type TYPE] [-actor PEER]", "stream audit events as they happen", runTail},
+        {"turn", "turn", "verify the TURN provider configuration", runTurn},
+    }
+}
+
+// app is the state shared by every subcommand
+type app struct {
+    client *client
+    json   bool
+}
+
+func main() {
+    flag.Usage = usage
+    server := flag.String("server", envOr("P2PCTL_SERVER", "http://localhost:3001"), "server base URL")
+    token := flag.String("token", os.Getenv("ADMIN_TOKEN"), "admin token")
+    jsonOut := flag.Bool("json", false, "print raw JSON responses")
+    flag.Parse()
+
+    if flag.NArg() == 0 {
+        usage()
+        os.Exit(2)
+    }
+
+    var cmd *command
+    for i := range commands {
+        if commands[i].name == flag.Arg(0) {
+            cmd = &commands[i]
+        }
+    }
+    if cmd == nil {
+        fmt.Fprintf(os.Stderr, "p2pctl: unknown command %q\n\n", flag.Arg(0))
+        usage()
+        os.Exit(2)
+    }
+
+    if *token == "" {
+        fmt.Fprintln(os.Stderr, "p2pctl: no admin token; set ADMIN_TOKEN or pass -token")
+        os.Exit(2)
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    a := &app{
+        client: &client{baseURL: *server, token: *token, http: &http.Client{}},
+        json:   *jsonOut,
+    }
+    if err := cmd.run(ctx, a, flag.Args()[1:]); err != nil {
+        fmt.Fprintf(os.Stderr, "p2pctl %s: %v\n", cmd.name, err)
+        os.Exit(1)
+    }
+}
+
+func usage() {
+    out := flag.CommandLine.Output()
+    fmt.Fprintln(out, "usage: p2pctl [flags] <command> [args]")
+    fmt.Fprintln(out, "\ncommands:")
+    for _, cmd := range commands {
+        fmt.Fprintf(out, "  %-48s %s\n", cmd.usage, cmd.summary)
+    }
+    fmt.Fprintln(out, "\nflags:")
+    flag.PrintDefaults()
+}
+
+func envOr(key, fallback string) string {
+    if v := os.Getenv(key); v != "" {
+        return v
+    }
+    return fallback
+}
+
+// subcommandFlags returns a flag set for a subcommand that reports its own
+// usage line on error
+func subcommandFlags(name string) *flag.FlagSet {
+    fs := flag.NewFlagSet(name, flag.ContinueOnError)
+    fs.Usage = func() {
+        for _, cmd := range commands {
+            if cmd.name == name {
+                fmt.Fprintf(fs.Output(), "usage: p2pctl %s\n", cmd.usage)
+            }
+        }
+        fs.PrintDefaults()
+    }
+    return fs
+}
+
+// withTimeout bounds a one-shot request; tail streams without a deadline
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+    return context.WithTimeout(ctx, requestTimeout)
+}
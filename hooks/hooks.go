@@ -0,0 +1,191 @@
+// Package hooks lets programs embedding the server intercept the room
+// lifecycle without changing handler code: refuse a room, a join or a file
+// offer, or attach billing, logging or policy to them.
+//
+//  h := &hooks.Registry{}
+//  h.OnPeerJoin(func(ctx context.Context, e hooks.PeerJoin) error {
+//      if banned(e.PeerID) {
+//          return hooks.Refuse(http.StatusForbidden, "Not allowed in this room")
+//      }
+//      return nil
+//  })
+//  srv := server.New(server.Config{Hooks: h, ...})
+//
+// Hooks run on the request's goroutine, in registration order, before the
+// action takes effect, so they should be quick; the first to return an
+// error stops the rest and refuses the action. A nil Registry has no hooks.
+package hooks
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "sync"
+)
+
+// RoomCreate is a room about to be created by its host
+type RoomCreate struct {
+    RoomCode string
+    HostID   string
+    Mode     string
+    // Tenant is the frontend host the request came from
+    Tenant   string
+    ClientIP string
+}
+
+// PeerJoin is a peer about to join an existing room. Peers already in the
+// room, rejoining, don't run the hook again.
+type PeerJoin struct {
+    RoomCode    string
+    PeerID      string
+    DisplayName string
+    Tenant      string
+    ClientIP    string
+}
+
+// FileRegister is a file or folder about to be offered in a room
+type FileRegister struct {
+    RoomCode string
+    PeerID   string
+    Name     string
+    Size     int64
+    MimeType string
+    // Bundle is set for a folder offered as a bundle of entries
+    Bundle bool
+    Tenant string
+}
+
+// TransferComplete is a transfer reported finished by its sender or
+// receiver. It has already happened, so it can't be refused.
+type TransferComplete struct {
+    RoomCode   string
+    FileID     string
+    SenderID   string
+    ReceiverID string
+    Bytes      int64
+    Tenant     string
+}
+
+// Refusal is an error refusing an action, with what the client is told
+type Refusal struct {
+    Status  int
+    Message string
+}
+
+func (r *Refusal) Error() string {
+    return r.Message
+}
+
+// Refuse refuses an action with an HTTP status and a message for the
+// client. Other errors refuse it with a 500 and a generic message.
+func Refuse(status int, message string) error {
+    return &Refusal{Status: status, Message: message}
+}
+
+// Response is the status and message a hook's error refuses an action with
+func Response(err error) (int, string) {
+    var r *Refusal
+    if errors.As(err, &r) {
+        return r.Status, r.Message
+    }
+    return http.StatusInternalServerError, "Internal server error"
+}
+
+// Registry holds the registered hooks. Register before the server starts
+// serving; registering later is safe but may miss requests in flight.
+type Registry struct {
+    mu               sync.RWMutex
+    roomCreate       []func(context.Context, RoomCreate) error
+    peerJoin         []func(context.Context, PeerJoin) error
+    fileRegister     []func(context.Context, FileRegister) error
+    transferComplete []func(context.Context, TransferComplete)
+}
+
+// OnRoomCreate registers fn to run before a room is created
+func (r *Registry) OnRoomCreate(fn func(context.Context, RoomCreate) error) {
+    r.mu.Lock()
+    r.roomCreate = append(r.roomCreate, fn)
+    r.mu.Unlock()
+}
+
+// OnPeerJoin registers fn to run before a peer joins a room
+func (r *Registry) OnPeerJoin(fn func(context.Context, PeerJoin) error) {
+    r.mu.Lock()
+    r.peerJoin = append(r.peerJoin, fn)
+    r.mu.Unlock()
+}
+
+// OnFileRegister registers fn to run before a file is offered
+func (r *Registry) OnFileRegister(fn func(context.Context, FileRegister) error) {
+    r.mu.Lock()
+    r.fileRegister = append(r.fileRegister, fn)
+    r.mu.Unlock()
+}
+
+// OnTransferComplete registers fn to run when a transfer completes
+func (r *Registry) OnTransferComplete(fn func(context.Context, TransferComplete)) {
+    r.mu.Lock()
+    r.transferComplete = append(r.transferComplete, fn)
+    r.mu.Unlock()
+}
+
+// RoomCreate runs the room creation hooks
+func (r *Registry) RoomCreate(ctx context.Context, e RoomCreate) error {
+    if r == nil {
+        return nil
+    }
+    r.mu.RLock()
+    fns := r.roomCreate
+    r.mu.RUnlock()
+    for _, fn := range fns {
+        if err := fn(ctx, e); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// PeerJoin runs the join hooks
+func (r *Registry) PeerJoin(ctx context.Context, e PeerJoin) error {
+    if r == nil {
+        return nil
+    }
+    r.mu.RLock()
+    fns := r.peerJoin
+    r.mu.RUnlock()
+    for _, fn := range fns {
+        if err := fn(ctx, e); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// FileRegister runs the file offer hooks
+func (r *Registry) FileRegister(ctx context.Context, e FileRegister) error {
+    if r == nil {
+        return nil
+    }
+    r.mu.RLock()
+    fns := r.fileRegister
+    r.mu.RUnlock()
+    for _, fn := range fns {
+        if err := fn(ctx, e); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// TransferComplete runs the transfer completion hooks
+func (r *Registry) TransferComplete(ctx context.Context, e TransferComplete) {
+    if r == nil {
+        return
+    }
+    r.mu.RLock()
+    fns := r.transferComplete
+    r.mu.RUnlock()
+    for _, fn := range fns {
+        fn(ctx, e)
+    }
+}
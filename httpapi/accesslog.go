@@ -0,0 +1,176 @@
+package httpapi
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "math/rand/v2"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// defaultRouteSamples keep orchestrator probes out of the access log unless
+// ACCESS_LOG_ROUTES says otherwise
+var defaultRouteSamples = map[string]float64{
+    "GET /health":  0,
+    "GET /healthz": 0,
+    "GET /readyz":  0,
+}
+
+// accessLogEntry is one structured access log line
+type accessLogEntry struct {
+    Time      string  `json:"time"`
+    Method    string  `json:"method"`
+    Route     string  `json:"route"`
+    Path      string  `json:"path"`
+    Status    int     `json:"status"`
+    LatencyMs float64 `json:"latencyMs"`
+    Bytes     int     `json:"bytes"`
+    ClientIP  string  `json:"clientIp"`
+    Room      string  `json:"room,omitempty"`
+    Peer      string  `json:"peer,omitempty"`
+    UserAgent string  `json:"userAgent,omitempty"`
+    Error     string  `json:"error,omitempty"`
+    // Sample is the rate this entry was kept at, so counts can be scaled back
+    Sample float64 `json:"sample,omitempty"`
+}
+
+// accessLogConfig controls which requests are logged and how
+type accessLogConfig struct {
+    format string
+    sample float64
+    routes map[string]float64
+    slow   time.Duration
+}
+
+// loadAccessLogConfig reads ACCESS_LOG (json, text or off; default json, or
+// text in dev mode), ACCESS_LOG_SAMPLE (fraction of requests kept, default 1),
+// ACCESS_LOG_ROUTES (per-route overrides such as
+// "POST /room/heartbeat=0.01,GET /room/:roomCode/peers=0.1") and
+// ACCESS_LOG_SLOW (default 1s). Server errors and slow requests are always
+// logged.
+func loadAccessLogConfig(dev bool) accessLogConfig {
+    cfg := accessLogConfig{
+        format: os.Getenv("ACCESS_LOG"),
+        sample: envFloat("ACCESS_LOG_SAMPLE", 1),
+        routes: make(map[string]float64, len(defaultRouteSamples)),
+        slow:   envDuration("ACCESS_LOG_SLOW", time.Second),
+    }
+    if cfg.format == "" {
+        cfg.format = "json"
+        if dev {
+            cfg.format = "text"
+        }
+    }
+    switch cfg.format {
+    case "json", "text", "off":
+    default:
+        log.Printf("⚠️  Invalid ACCESS_LOG %q, using json", cfg.format)
+        cfg.format = "json"
+    }
+
+    for route, rate := range defaultRouteSamples {
+        cfg.routes[route] = rate
+    }
+    for _, entry := range strings.Split(os.Getenv("ACCESS_LOG_ROUTES"), ",") {
+        entry = strings.TrimSpace(entry)
+        if entry == "" {
+            continue
+        }
+        i := strings.LastIndexByte(entry, '=')
+        if i <= 0 {
+            log.Printf("⚠️  Ignoring ACCESS_LOG_ROUTES entry %q (expected \"METHOD /route=rate\")", entry)
+            continue
+        }
+        rate, err := strconv.ParseFloat(entry[i+1:], 64)
+        if err != nil {
+            log.Printf("⚠️  Ignoring ACCESS_LOG_ROUTES entry %q: %v", entry, err)
+            continue
+        }
+        cfg.routes[strings.TrimSpace(entry[:i])] = rate
+    }
+    return cfg
+}
+
+// sampleRate returns the fraction of requests to route that are logged
+func (cfg accessLogConfig) sampleRate(method, route string) float64 {
+    if rate, ok := cfg.routes[method+" "+route]; ok {
+        return rate
+    }
+    return cfg.sample
+}
+
+// accessLog writes one line per request to Gin's default writer with the
+// latency, status, response size and the room and peer involved. Tokens and
+// other credentials in the query string are redacted.
+func (a *API) accessLog() gin.HandlerFunc {
+    cfg := loadAccessLogConfig(a.cfg.Dev)
+    if cfg.format == "off" {
+        return func(c *gin.Context) { c.Next() }
+    }
+
+    var mu sync.Mutex
+    return func(c *gin.Context) {
+        start := time.Now()
+        c.Next()
+        latency := time.Since(start)
+
+        route := c.FullPath()
+        if route == "" {
+            route = "unmatched"
+        }
+        status := c.Writer.Status()
+
+        rate := 1.0
+        if status < 500 && latency < cfg.slow {
+            rate = cfg.sampleRate(c.Request.Method, route)
+            if rate <= 0 || (rate < 1 && rand.Float64() >= rate) {
+                return
+            }
+        }
+
+        entry := accessLogEntry{
+            Time:      start.UTC().Format(time.RFC3339Nano),
+            Method:    c.Request.Method,
+            Route:     route,
+            Path:      redactedURL(c.Request.URL),
+            Status:    status,
+            LatencyMs: float64(latency.Microseconds()) / 1000,
+            Bytes:     max(c.Writer.Size(), 0),
+            ClientIP:  c.ClientIP(),
+            Room:      requestRoom(c),
+            Peer:      requestPeer(c),
+            UserAgent: c.Request.UserAgent(),
+        }
+        if len(c.Errors) > 0 {
+            entry.Error = c.Errors.Last().Error()
+        }
+        if rate < 1 {
+            entry.Sample = rate
+        }
+
+        var line []byte
+        if cfg.format == "json" {
+            line, _ = json.Marshal(entry)
+        } else {
+            line = fmt.Appendf(nil, "[HTTP] %s | %3d | %9.2fms | %6dB | %15s | %-6s %s",
+                entry.Time, entry.Status, entry.LatencyMs, entry.Bytes, entry.ClientIP, entry.Method, entry.Path)
+            if entry.Room != "" || entry.Peer != "" {
+                line = fmt.Appendf(line, " room=%s peer=%s", entry.Room, entry.Peer)
+            }
+            if entry.Error != "" {
+                line = fmt.Appendf(line, " error=%q", entry.Error)
+            }
+        }
+        line = append(line, '\n')
+
+        mu.Lock()
+        gin.DefaultWriter.Write(line)
+        mu.Unlock()
+    }
+}
@@ -0,0 +1,132 @@
+package httpapi
+
+import (
+    "context"
+    "log"
+    "net/http"
+    "os"
+    "sort"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// requireAdmin guards admin endpoints with the ADMIN_TOKEN bearer token. The
+// admin API is disabled entirely when no token is configured.
+func (a *API) requireAdmin() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        token := os.Getenv("ADMIN_TOKEN")
+        if token == "" {
+            c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Admin API is not configured"})
+            return
+        }
+
+        if !isAdminRequest(c) {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+            return
+        }
+
+        c.Next()
+    }
+}
+
+// getRoomDetail dumps one room: its summary, the hash of its creator's IP
+// for suspensions, every peer and file, and each peer's undelivered
+// notifications
+func (a *API) getRoomDetail(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    summary := summarizeRoom(roomCode, room)
+
+    room.RLock()
+    version := room.Version
+    creatorIPHash := suspensionIPHash(room.CreatorIP)
+    peers := make([]rooms.PeerMetadata, 0, len(room.Peers))
+    for _, peer := range room.Peers {
+        peers = append(peers, *peer)
+    }
+    files := make([]rooms.FileOffer, 0, len(room.Files))
+    for _, file := range room.Files {
+        files = append(files, *file)
+    }
+    room.RUnlock()
+
+    sort.Slice(peers, func(i, j int) bool {
+        return peers[i].JoinedAt < peers[j].JoinedAt
+    })
+    sort.Slice(files, func(i, j int) bool {
+        return files[i].OfferedAt < files[j].OfferedAt
+    })
+
+    backlog := make(map[string][]notifications.Notification, len(peers))
+    for _, peer := range peers {
+        backlog[peer.PeerID] = a.notifications.Peek(peer.PeerID)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "room":          summary,
+        "version":       version,
+        "creatorIpHash": creatorIPHash,
+        "peers":         peers,
+        "files":         files,
+        "backlog":       backlog,
+    })
+}
+
+// forceCloseRoom closes a room immediately, telling its peers why via
+// ?reason= (default "admin")
+func (a *API) forceCloseRoom(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    reason := c.DefaultQuery("reason", "admin")
+
+    if !a.closeRoom(roomCode, reason) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    log.Printf("⛔ Room closed by admin: %s (%s)", roomCode, reason)
+    a.recordAudit(roomCode, "room_closed", "admin", "", gin.H{"reason": reason})
+
+    c.JSON(http.StatusOK, gin.H{"roomCode": roomCode, "closed": true})
+}
+
+// verifyTurnProvider checks the TURN provider's configuration on demand,
+// bypassing the readiness cache and refreshing it with the result
+func (a *API) verifyTurnProvider(c *gin.Context) {
+    if a.turn == nil {
+        c.JSON(http.StatusOK, gin.H{"configured": false})
+        return
+    }
+
+    ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+    defer cancel()
+
+    start := time.Now()
+    detail, err := a.turn.Verify(ctx)
+    result := checkResult{
+        Status:    "ok",
+        LatencyMs: time.Since(start).Milliseconds(),
+        Detail:    detail,
+    }
+
+    a.turnCheck.mu.Lock()
+    a.turnCheck.detail, a.turnCheck.err, a.turnCheck.at = detail, err, time.Now()
+    a.turnCheck.mu.Unlock()
+
+    status := http.StatusOK
+    if err != nil {
+        result.Status = "fail"
+        result.Error = err.Error()
+        status = http.StatusBadGateway
+    }
+    c.JSON(status, gin.H{"configured": true, "check": result})
+}
@@ -0,0 +1,470 @@
+package httpapi
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Behaviours the anomaly detector counts
+const (
+    // signalRooms counts rooms created per IP
+    signalRooms = "room_creation"
+    // signalChurn counts joins and leaves per peer, or per IP for requests
+    // without the peer's token
+    signalChurn = "join_leave_cycling"
+    // signalFlood counts messages sent per peer, or per IP for requests
+    // without the peer's token
+    signalFlood = "notification_flooding"
+)
+
+// What the detector does about a finding (ANOMALY_ACTION)
+const (
+    anomalyFlag     = "flag"
+    anomalyThrottle = "throttle"
+    anomalySuspend  = "suspend"
+)
+
+const (
+    defaultAnomalyWindow      = 10 * time.Minute
+    defaultAnomalyActionTTL   = time.Hour
+    defaultRoomsPerIPAnomaly  = 100
+    defaultChurnPerPeer       = 60
+    defaultMessagesPerPeer    = 1000
+    defaultThrottledPerMinute = 10
+    maxAnomalyFindings        = 500
+    anomalyWebhookTimeout     = 10 * time.Second
+    anomalyWebhookRetryEvery  = time.Minute
+    maxAnomalyWebhookAttempts = 5
+)
+
+// anomalyFinding is one actor seen exceeding a threshold. A finding stays
+// open, updated each window the actor is still over, until dismissed.
+type anomalyFinding struct {
+    ID     int64  `json:"id"`
+    Signal string `json:"signal"`
+    // Kind and Value name the actor as a suspension would: a peer ID, or
+    // the IP hash for kind "ip"
+    Kind       string `json:"kind"`
+    Value      string `json:"value"`
+    Count      int    `json:"count"`
+    Threshold  int    `json:"threshold"`
+    WindowSecs int64  `json:"windowSeconds"`
+    Action     string `json:"action"`
+    Status     string `json:"status"`
+    DetectedAt int64  `json:"detectedAt"`
+    LastSeenAt int64  `json:"lastSeenAt"`
+}
+
+// anomalyDetector counts behaviour per actor over fixed windows and keeps
+// the findings and the actors it throttled
+type anomalyDetector struct {
+    mu        sync.Mutex
+    window    time.Duration
+    action    string
+    actionTTL time.Duration
+    webhook   string
+    token     string
+    client    *http.Client
+
+    thresholds map[string]int
+    counts     map[string]map[string]int
+    findings   []*anomalyFinding
+    nextID     int64
+    // throttled maps suspensionKey(kind, value) to when its throttle ends
+    throttled map[string]int64
+    // undelivered are findings the webhook failed to take, retried every
+    // anomalyWebhookRetryEvery up to maxAnomalyWebhookAttempts times
+    undelivered []undeliveredAnomaly
+}
+
+type undeliveredAnomaly struct {
+    finding  anomalyFinding
+    attempts int
+}
+
+// loadAnomalyDetector reads the detector's configuration: ANOMALY_WINDOW,
+// the per-window thresholds ANOMALY_ROOMS_PER_IP, ANOMALY_CHURN_PER_PEER
+// and ANOMALY_MESSAGES_PER_PEER (zero disables one), ANOMALY_ACTION (flag,
+// throttle or suspend) with ANOMALY_ACTION_TTL, and ANOMALY_WEBHOOK_URL
+// with ANOMALY_WEBHOOK_TOKEN to post findings to
+func loadAnomalyDetector() *anomalyDetector {
+    d := &anomalyDetector{
+        window:    envDuration("ANOMALY_WINDOW", defaultAnomalyWindow),
+        action:    os.Getenv("ANOMALY_ACTION"),
+        actionTTL: envDuration("ANOMALY_ACTION_TTL", defaultAnomalyActionTTL),
+        webhook:   os.Getenv("ANOMALY_WEBHOOK_URL"),
+        token:     os.Getenv("ANOMALY_WEBHOOK_TOKEN"),
+        client:    &http.Client{Timeout: anomalyWebhookTimeout},
+        thresholds: map[string]int{
+            signalRooms: envInt("ANOMALY_ROOMS_PER_IP", defaultRoomsPerIPAnomaly),
+            signalChurn: envInt("ANOMALY_CHURN_PER_PEER", defaultChurnPerPeer),
+            signalFlood: envInt("ANOMALY_MESSAGES_PER_PEER", defaultMessagesPerPeer),
+        },
+        counts:    make(map[string]map[string]int),
+        nextID:    1,
+        throttled: make(map[string]int64),
+    }
+    switch d.action {
+    case "":
+        d.action = anomalyFlag
+    case anomalyFlag, anomalyThrottle, anomalySuspend:
+    default:
+        log.Printf("⚠️  Unknown ANOMALY_ACTION %q, only flagging anomalies", d.action)
+        d.action = anomalyFlag
+    }
+    return d
+}
+
+// observe counts one occurrence of signal by an actor, named as a
+// suspension would name it, in the current window
+func (d *anomalyDetector) observe(signal, kind, value string) {
+    if value == "" || d.thresholds[signal] <= 0 {
+        return
+    }
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    if d.counts[signal] == nil {
+        d.counts[signal] = make(map[string]int)
+    }
+    d.counts[signal][suspensionKey(kind, value)]++
+}
+
+// observeIP counts signal for the hash of ip, so raw addresses aren't kept
+func (d *anomalyDetector) observeIP(signal, ip string) {
+    if d.thresholds[signal] > 0 {
+        d.observe(signal, suspendIP, suspensionIPHash(ip))
+    }
+}
+
+// observePeer counts signal for peerID if the request's peer token proves
+// it, and for the client's IP otherwise, so requests naming someone else's
+// peer ID can't get that peer throttled or suspended
+func (a *API) observePeer(c *gin.Context, signal, peerID string) {
+    if authPeer, ok := authenticatedPeer(c); ok && authPeer == peerID {
+        a.anomalies.observe(signal, suspendPeer, peerID)
+        return
+    }
+    a.anomalies.observeIP(signal, c.ClientIP())
+}
+
+// analyzeAnomalies closes the window, run every ANOMALY_WINDOW: it turns
+// the window's counts into findings, applies the configured action to new
+// ones and reports them
+func (a *API) analyzeAnomalies(ctx context.Context) error {
+    d := a.anomalies
+    now := time.Now()
+
+    d.mu.Lock()
+    counts := d.counts
+    d.counts = make(map[string]map[string]int)
+    for key, until := range d.throttled {
+        if now.Unix() >= until {
+            delete(d.throttled, key)
+        }
+    }
+
+    var raised []anomalyFinding
+    for signal, byKey := range counts {
+        threshold := d.thresholds[signal]
+        for actor, n := range byKey {
+            if n < threshold {
+                continue
+            }
+            kind, key, _ := strings.Cut(actor, "\n")
+            if f := d.openFinding(signal, kind, key); f != nil {
+                f.Count = max(f.Count, n)
+                f.LastSeenAt = now.Unix()
+                continue
+            }
+            f := &anomalyFinding{
+                ID:         d.nextID,
+                Signal:     signal,
+                Kind:       kind,
+                Value:      key,
+                Count:      n,
+                Threshold:  threshold,
+                WindowSecs: int64(d.window.Seconds()),
+                Action:     d.action,
+                Status:     "open",
+                DetectedAt: now.Unix(),
+                LastSeenAt: now.Unix(),
+            }
+            d.nextID++
+            d.findings = append(d.findings, f)
+            if d.action == anomalyThrottle {
+                d.throttled[suspensionKey(kind, key)] = now.Add(d.actionTTL).Unix()
+            }
+            raised = append(raised, *f)
+        }
+    }
+    if over := len(d.findings) - maxAnomalyFindings; over > 0 {
+        d.findings = append([]*anomalyFinding(nil), d.findings[over:]...)
+    }
+    d.mu.Unlock()
+
+    for _, f := range raised {
+        log.Printf("🚨 Anomaly: %s %s %s (%d in %s, threshold %d, %s)", f.Signal, f.Kind, f.Value, f.Count, d.window, f.Threshold, f.Action)
+        a.recordAudit("", "anomaly_detected", "anomaly", f.Kind+":"+f.Value, gin.H{"findingId": f.ID, "signal": f.Signal, "count": f.Count, "action": f.Action})
+        if f.Action == anomalySuspend {
+            a.suspend(f.Kind, f.Value, "Automatic: "+f.Signal, "anomaly", d.actionTTL)
+        }
+        if d.webhook != "" {
+            go a.deliverAnomaly(f)
+        }
+    }
+    return nil
+}
+
+// openFinding returns the open finding for signal and actor, if any. The
+// caller must hold d.mu.
+func (d *anomalyDetector) openFinding(signal, kind, value string) *anomalyFinding {
+    for _, f := range d.findings {
+        if f.Status == "open" && f.Signal == signal && f.Kind == kind && f.Value == value {
+            return f
+        }
+    }
+    return nil
+}
+
+// deliverAnomaly sends a new finding to the webhook, queueing it for the
+// retry job if that fails
+func (a *API) deliverAnomaly(f anomalyFinding) {
+    err := a.postAnomaly(context.Background(), f)
+    if err == nil {
+        return
+    }
+    log.Printf("❌ Anomaly webhook: %v, will retry", err)
+    d := a.anomalies
+    d.mu.Lock()
+    d.undelivered = append(d.undelivered, undeliveredAnomaly{finding: f, attempts: 1})
+    if over := len(d.undelivered) - maxAnomalyFindings; over > 0 {
+        d.undelivered = append([]undeliveredAnomaly(nil), d.undelivered[over:]...)
+    }
+    d.mu.Unlock()
+}
+
+// retryAnomalyWebhooks sends the findings the webhook failed to take again,
+// dropping those that have failed maxAnomalyWebhookAttempts times
+func (a *API) retryAnomalyWebhooks(ctx context.Context) error {
+    d := a.anomalies
+    d.mu.Lock()
+    pending := d.undelivered
+    d.undelivered = nil
+    d.mu.Unlock()
+
+    var failed []undeliveredAnomaly
+    var failures int
+    var lastErr error
+    for _, u := range pending {
+        err := a.postAnomaly(ctx, u.finding)
+        if err == nil {
+            continue
+        }
+        failures++
+        lastErr = err
+        u.attempts++
+        if u.attempts >= maxAnomalyWebhookAttempts {
+            log.Printf("❌ Anomaly webhook: giving up on finding %d after %d attempts", u.finding.ID, u.attempts)
+            continue
+        }
+        failed = append(failed, u)
+    }
+
+    d.mu.Lock()
+    d.undelivered = append(failed, d.undelivered...)
+    d.mu.Unlock()
+    if lastErr != nil {
+        return fmt.Errorf("%d of %d deliveries failed, last: %w", failures, len(pending), lastErr)
+    }
+    return nil
+}
+
+// postAnomaly sends a finding to ANOMALY_WEBHOOK_URL
+func (a *API) postAnomaly(ctx context.Context, f anomalyFinding) error {
+    d := a.anomalies
+    body, err := json.Marshal(gin.H{"type": "anomaly_detected", "finding": f})
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.webhook, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    if d.token != "" {
+        req.Header.Set("Authorization", "Bearer "+d.token)
+    }
+    resp, err := d.client.Do(req)
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    if resp.StatusCode >= 300 {
+        return fmt.Errorf("webhook answered %s", resp.Status)
+    }
+    return nil
+}
+
+// throttleFlagged holds actors the detector throttled to
+// ANOMALY_THROTTLED_PER_MINUTE mutating requests, answering the rest
+// with 429
+func (a *API) throttleFlagged() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        switch c.Request.Method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+            c.Next()
+            return
+        }
+
+        if !a.anyThrottled() {
+            c.Next()
+            return
+        }
+        if a.throttledActor(c.ClientIP(), suspensionCandidates(c)...) {
+            c.Header("Retry-After", "60")
+            c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+            return
+        }
+        c.Next()
+    }
+}
+
+// anyThrottled reports whether the detector has throttled anyone
+func (a *API) anyThrottled() bool {
+    d := a.anomalies
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return len(d.throttled) > 0
+}
+
+// throttledActor reports whether a request from ip, acting as any of
+// peers, is over the limit of an actor the detector throttled
+func (a *API) throttledActor(ip string, peers ...string) bool {
+    if !a.anyThrottled() {
+        return false
+    }
+    keys := []string{suspensionKey(suspendIP, suspensionIPHash(ip))}
+    for _, peerID := range peers {
+        if peerID != "" {
+            keys = append(keys, suspensionKey(suspendPeer, peerID))
+        }
+    }
+
+    d := a.anomalies
+    now := time.Now().Unix()
+    d.mu.Lock()
+    var throttled string
+    for _, key := range keys {
+        if until, ok := d.throttled[key]; ok && now < until {
+            throttled = key
+            break
+        }
+    }
+    d.mu.Unlock()
+    return throttled != "" && !a.anomalyLimiter.Allow(throttled)
+}
+
+// listAnomalies lists the detector's findings, newest first, filterable by
+// status (open or dismissed)
+func (a *API) listAnomalies(c *gin.Context) {
+    status := c.Query("status")
+    d := a.anomalies
+
+    d.mu.Lock()
+    list := make([]anomalyFinding, 0, len(d.findings))
+    for i := len(d.findings) - 1; i >= 0; i-- {
+        if status == "" || d.findings[i].Status == status {
+            list = append(list, *d.findings[i])
+        }
+    }
+    thresholds := make(gin.H, len(d.thresholds))
+    for signal, n := range d.thresholds {
+        thresholds[signal] = n
+    }
+    d.mu.Unlock()
+
+    c.JSON(http.StatusOK, gin.H{
+        "findings":      list,
+        "action":        d.action,
+        "windowSeconds": int64(d.window.Seconds()),
+        "thresholds":    thresholds,
+    })
+}
+
+// dismissAnomaly closes a finding and lifts any throttle it applied. A
+// suspension it applied is lifted through /admin/suspensions.
+func (a *API) dismissAnomaly(c *gin.Context) {
+    id, err := strconv.ParseInt(c.Param("findingId"), 10, 64)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid finding ID"})
+        return
+    }
+    d := a.anomalies
+
+    d.mu.Lock()
+    var found *anomalyFinding
+    for _, f := range d.findings {
+        if f.ID == id {
+            found = f
+            break
+        }
+    }
+    if found != nil {
+        found.Status = "dismissed"
+        delete(d.throttled, suspensionKey(found.Kind, found.Value))
+    }
+    d.mu.Unlock()
+
+    if found == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Finding not found"})
+        return
+    }
+    a.recordAudit("", "anomaly_dismissed", "admin", found.Kind+":"+found.Value, gin.H{"findingId": id})
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// eraseAnomalies drops peerID's counts, throttle and findings from the
+// detector, returning how many findings named it. Findings against an IP
+// hash don't name the peer and are kept.
+func (a *API) eraseAnomalies(peerID string) int {
+    key := suspensionKey(suspendPeer, peerID)
+    names := func(f anomalyFinding) bool { return f.Kind == suspendPeer && f.Value == peerID }
+
+    d := a.anomalies
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    for _, counts := range d.counts {
+        delete(counts, key)
+    }
+    delete(d.throttled, key)
+
+    kept := d.findings[:0]
+    for _, f := range d.findings {
+        if !names(*f) {
+            kept = append(kept, f)
+        }
+    }
+    removed := len(d.findings) - len(kept)
+    clear(d.findings[len(kept):])
+    d.findings = kept
+
+    undelivered := d.undelivered[:0]
+    for _, u := range d.undelivered {
+        if !names(u.finding) {
+            undelivered = append(undelivered, u)
+        }
+    }
+    d.undelivered = undelivered
+    return removed
+}
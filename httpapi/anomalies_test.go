@@ -0,0 +1,60 @@
+package httpapi
+
+import (
+    "context"
+    "net/http"
+    "testing"
+)
+
+func TestAnomalyChurnActor(t *testing.T) {
+    tests := []struct {
+        name     string
+        roomCode string
+        token    bool
+        // wantKind is the kind of the finding raised, or "" for none
+        wantKind string
+    }{
+        {"token proves the peer", "churn", true, suspendPeer},
+        {"peer ID without its token", "churn", false, suspendIP},
+        {"refused joins", "missing", true, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            t.Setenv("ANOMALY_CHURN_PER_PEER", "3")
+            t.Setenv("ANOMALY_ACTION", anomalyThrottle)
+            a, h := newTestRouter(t)
+            request(h, http.MethodPost, "/room/create", "application/json", "", `{"roomCode":"churn","peerId":"host"}`)
+
+            token := ""
+            if tt.token {
+                token = issuePeerToken("victim")
+            }
+            body := `{"roomCode":"` + tt.roomCode + `","peerId":"victim"}`
+            for range 3 {
+                request(h, http.MethodPost, "/room/join", "application/json", token, body)
+            }
+            a.analyzeAnomalies(context.Background())
+
+            a.anomalies.mu.Lock()
+            var kinds []string
+            for _, f := range a.anomalies.findings {
+                kinds = append(kinds, f.Kind)
+            }
+            _, victimThrottled := a.anomalies.throttled[suspensionKey(suspendPeer, "victim")]
+            a.anomalies.mu.Unlock()
+
+            if tt.wantKind == "" {
+                if len(kinds) != 0 {
+                    t.Fatalf("findings for kinds %v, want none", kinds)
+                }
+                return
+            }
+            if len(kinds) != 1 || kinds[0] != tt.wantKind {
+                t.Fatalf("findings for kinds %v, want [%s]", kinds, tt.wantKind)
+            }
+            if victimThrottled != (tt.wantKind == suspendPeer) {
+                t.Errorf("victim throttled = %v", victimThrottled)
+            }
+        })
+    }
+}
@@ -0,0 +1,639 @@
+// Package httpapi is the HTTP signaling API: room management, notifications,
+// messaging, moderation and the admin endpoints.
+package httpapi
+
+import (
+    "context"
+    "log"
+    "net/http"
+    "os"
+    "sync/atomic"
+    "time"
+
+    "github.com/gin-contrib/cors"
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+    "github.com/quic-go/webtransport-go"
+
+    "p2p-file-share-backend/hooks"
+    "p2p-file-share-backend/jobs"
+    "p2p-file-share-backend/leader"
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/oidc"
+    "p2p-file-share-backend/relay"
+    "p2p-file-share-backend/reporting"
+    "p2p-file-share-backend/rooms"
+    "p2p-file-share-backend/storage"
+    "p2p-file-share-backend/turn"
+)
+
+// DefaultAllowedOrigins are the frontends permitted to call this backend
+var DefaultAllowedOrigins = []string{
+    "https://p2p-client.martinwong.me",
+    "https://p2p-file-sharing-phbh.onrender.com",
+}
+
+// Config wires an API to its dependencies. Zero values get sensible
+// defaults; everything else is read from the environment as before.
+type Config struct {
+    // AllowedOrigins for CORS and WebTransport (default DefaultAllowedOrigins)
+    AllowedOrigins []string
+    // TURN issues ICE credentials; nil means TURN is not configured
+    TURN turn.Provider
+    // Rooms and Notifications default to fresh in-memory instances
+    Rooms         *rooms.Store
+    Notifications *notifications.Hub
+    // Leader decides whether this instance runs cluster-wide work, like
+    // pruning the shared relay store (default leader.Single, which always
+    // does). Every instance sweeps its own rooms.
+    Leader leader.Elector
+    // Storage persists rooms and the audit trail; nil keeps them in memory
+    // only. Persisted state is restored by New.
+    Storage storage.Store
+    // Reporter receives panics and server errors; nil only logs them
+    Reporter reporting.Reporter
+    // Relay stores files uploaded for peers that cannot connect directly;
+    // nil disables relaying. Scanner checks them before they can be
+    // downloaded; nil serves them unscanned.
+    Relay   relay.Store
+    Scanner relay.Scanner
+    // Login are the OAuth/OpenID Connect providers users may sign in with,
+    // by name; empty leaves only anonymous peers
+    Login map[string]*oidc.Provider
+    // Hooks lets an embedding program refuse or react to rooms being
+    // created, peers joining, files offered and transfers completing; nil
+    // runs none
+    Hooks *hooks.Registry
+
+    // Dev relaxes CORS to localhost, disables rate limits, seeds a demo
+    // room and logs request and response bodies. Never use in production.
+    Dev bool
+}
+
+// API serves the HTTP endpoints. Every API owns its own state, so several can
+// run side by side in one process.
+type API struct {
+    cfg           Config
+    rooms         *rooms.Store
+    notifications *notifications.Hub
+    dispatcher    *notifications.Dispatcher
+    turn          turn.Provider
+    leader        leader.Elector
+    storage       storage.Store
+    persisted     persistence
+    reporter      reporting.Reporter
+    relay         relay.Store
+    scanner       relay.Scanner
+    staging       *relay.Disk
+    hooks         *hooks.Registry
+
+    audit       auditTrail
+    reports     reportQueue
+    quarantine  quarantine
+    shortLinks  shortLinkTable
+    pairings    pairingTable
+    idempotency idempotencyStore
+    quotas      *roomQuotaConfig
+    relayQuotas *relayQuotaConfig
+    wtLimits    *sessionLimits
+    wtSessions  wtSessionSet
+    retention   retentionConfig
+    turnCheck   turnCheckCache
+    drain       drainState
+    readOnly    readOnlyState
+    signals     signalSequencer
+    recent      recentRoomHistory
+    templates   templateLibrary
+    blocks      blockLists
+    logins      loginTable
+    devices     deviceRegistry
+    contacts    contactBook
+    nearby      nearbyPool
+    quickShares quickShareTable
+    suspensions suspensionList
+    flags       flagSet
+    analytics   connectionAnalytics
+    insights    insightsTable
+    clientLogs  clientLogBuffer
+    metrics     *sliMetrics
+    overview    overviewSeries
+    usage       usageMeter
+    usageExport *usageExporter
+    anomalies   *anomalyDetector
+    geo         *geoLocator
+    acl         atomic.Pointer[compiledACL]
+
+    filePolicies atomic.Pointer[policyFile]
+
+    healthCounts atomic.Pointer[healthCounts]
+
+    messageLimiter   *rateLimiter
+    inviterLimiter   *rateLimiter
+    recipientLimiter *rateLimiter
+    reporterLimiter  *rateLimiter
+    reportIPLimiter  *rateLimiter
+    natEchoLimiter   *rateLimiter
+    telemetryLimiter *rateLimiter
+    clientLogLimiter *rateLimiter
+    pairStartLimiter *rateLimiter
+    pairClaimLimiter *rateLimiter
+    nearbyLimiter    *rateLimiter
+    shareLimiter     *rateLimiter
+    claimLimiter     *rateLimiter
+    anomalyLimiter   *rateLimiter
+
+    // natPorts are the UDP ports answering NAT echo probes
+    natPorts []int
+
+    // peerGrace is how long a departed peer is kept as disconnected before
+    // it is removed; zero removes it immediately
+    peerGrace time.Duration
+    // resumeTTL is how long resume tokens issued on join stay valid
+    resumeTTL time.Duration
+
+    // jobs runs the periodic maintenance work, see scheduleJobs
+    jobs *jobs.Scheduler
+
+    // wt is set by WithWebTransport when HTTP/3 is enabled
+    wt *webtransport.Server
+}
+
+// New returns an API for cfg
+func New(cfg Config) *API {
+    if cfg.AllowedOrigins == nil {
+        cfg.AllowedOrigins = DefaultAllowedOrigins
+    }
+    if cfg.Rooms == nil {
+        cfg.Rooms = rooms.NewStore()
+    }
+    if cfg.Notifications == nil {
+        cfg.Notifications = notifications.NewHub()
+    }
+    if cfg.Leader == nil {
+        cfg.Leader = leader.Single{}
+    }
+    registerValidations()
+
+    a := &API{
+        cfg:              cfg,
+        rooms:            cfg.Rooms,
+        notifications:    cfg.Notifications,
+        dispatcher:       notifications.NewDispatcher(cfg.Notifications, envInt("NOTIFY_WORKERS", 8), envInt("NOTIFY_QUEUE_SIZE", 1024)),
+        turn:             cfg.TURN,
+        leader:           cfg.Leader,
+        storage:          cfg.Storage,
+        reporter:         cfg.Reporter,
+        relay:            cfg.Relay,
+        scanner:          cfg.Scanner,
+        hooks:            cfg.Hooks,
+        persisted:        persistence{saved: make(map[string]roomMark), users: make(map[string][32]byte)},
+        audit:            auditTrail{nextID: 1, appended: make(chan struct{})},
+        shortLinks:       shortLinkTable{links: make(map[string]shortLink)},
+        pairings:         pairingTable{codes: make(map[string]*pairing), byPeer: make(map[string]string)},
+        signals:          signalSequencer{channels: make(map[string]*signalChannel)},
+        recent:           recentRoomHistory{peers: make(map[string][]recentRoom)},
+        templates:        templateLibrary{peers: make(map[string]map[string]roomTemplate)},
+        blocks:           blockLists{peers: make(map[string]map[string]int64), revisions: make(map[string]int64)},
+        logins:           loginTable{pending: make(map[string]*pendingLogin), users: make(map[string]*userProfile)},
+        devices:          deviceRegistry{users: make(map[string]map[string]*device), owners: make(map[string]string)},
+        contacts:         contactBook{users: make(map[string]map[string]*contact), shares: make(map[string]*contactShare)},
+        nearby:           nearbyPool{peers: make(map[string]*nearbyPeer), requests: make(map[string]map[string]*nearbyRequest)},
+        quickShares:      quickShareTable{shares: make(map[string]*quickShare)},
+        suspensions:      suspensionList{entries: make(map[string]*suspension)},
+        usage:            usageMeter{tenants: make(map[string]*tenantUsage)},
+        flags:            flagSet{runtime: make(map[string]flagRule)},
+        idempotency:      newIdempotencyStore(),
+        quotas:           loadRoomQuotas(),
+        relayQuotas:      loadRelayQuotas(),
+        wtLimits:         loadSessionLimits(),
+        wtSessions:       wtSessionSet{open: make(map[*wtSession]bool)},
+        messageLimiter:   newRateLimiter(messagesPerMinute, time.Minute),
+        inviterLimiter:   newRateLimiter(invitesPerPeerPerHour, time.Hour),
+        recipientLimiter: newRateLimiter(invitesPerRecipientPerHour, time.Hour),
+        reporterLimiter:  newRateLimiter(reportsPerReporterPerHour, time.Hour),
+        reportIPLimiter:  newRateLimiter(reportsPerIPPerHour, time.Hour),
+        natEchoLimiter:   newRateLimiter(natEchoPerMinute, time.Minute),
+        telemetryLimiter: newRateLimiter(telemetryPerMinute, time.Minute),
+        clientLogLimiter: newRateLimiter(clientLogBatchesPerMinute, time.Minute),
+        pairStartLimiter: newRateLimiter(pairStartsPerHour, time.Hour),
+        pairClaimLimiter: newRateLimiter(pairClaimsPerMin, time.Minute),
+        nearbyLimiter:    newRateLimiter(nearbyRequestsPerMinute, time.Minute),
+        shareLimiter:     newRateLimiter(quickSharesPerHour, time.Hour),
+        claimLimiter:     newRateLimiter(quickClaimsPerMinute, time.Minute),
+        anomalyLimiter:   newRateLimiter(envInt("ANOMALY_THROTTLED_PER_MINUTE", defaultThrottledPerMinute), time.Minute),
+        anomalies:        loadAnomalyDetector(),
+        metrics:          loadSLIMetrics(),
+        usageExport:      loadUsageExporter(),
+        analytics:        connectionAnalytics{buckets: make(map[int64]*analyticsBucket)},
+        insights:         insightsTable{rooms: make(map[string]*roomInsights)},
+        clientLogs:       loadClientLogBuffer(),
+        natPorts:         natEchoPorts(),
+        geo:              loadGeoLocator(),
+        peerGrace:        envDuration("PEER_GRACE_PERIOD", time.Minute),
+        resumeTTL:        envDuration("RESUME_TOKEN_TTL", 24*time.Hour),
+        jobs:             jobs.NewScheduler(),
+    }
+    a.retention = a.loadRetentionPolicies()
+    a.usage.since = time.Now()
+    a.usage.sampled = a.usage.since
+    if a.relay != nil {
+        a.staging = openRelayStaging()
+    }
+
+    if a.storage != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+        if err := a.restoreState(ctx); err != nil {
+            log.Fatalf("❌ Failed to restore persisted state: %v", err)
+        }
+        if err := a.loadRuntimeFlags(ctx); err != nil {
+            log.Fatalf("❌ Failed to load feature flags: %v", err)
+        }
+        cancel()
+    }
+    a.pinConfiguredRooms()
+
+    // IP allow/deny lists, hot-reloaded from ACCESS_CONTROL_FILE
+    a.reloadACL()
+    // Operator file policies, hot-reloaded from FILE_POLICY_FILE
+    a.reloadFilePolicies()
+    // Feature flags, hot-reloaded from FEATURE_FLAGS_FILE
+    a.reloadFlags()
+
+    if cfg.Dev {
+        a.enableDevMode()
+    }
+    a.scheduleJobs()
+
+    return a
+}
+
+// Start runs the notification dispatcher, leader election, the background
+// jobs (see scheduleJobs), access-list, file-policy and feature-flag
+// reloads, any interrupted relay scans and any NAT echo listeners until ctx
+// is cancelled
+func (a *API) Start(ctx context.Context) {
+    go a.dispatcher.Run(ctx)
+    go a.leader.Run(ctx)
+    go a.jobs.Run(ctx)
+    go watchConfigFile(ctx, "ACCESS_CONTROL_FILE", a.reloadACL)
+    go watchConfigFile(ctx, "FILE_POLICY_FILE", a.reloadFilePolicies)
+    go watchConfigFile(ctx, "FEATURE_FLAGS_FILE", a.reloadFlags)
+    a.resumeRelayScans()
+    for _, port := range a.natPorts {
+        go a.runNATEcho(ctx, port)
+    }
+}
+
+// Router returns a Gin engine serving every endpoint
+func (a *API) Router() *gin.Engine {
+    r := gin.New()
+
+    // Structured, sampled access log with credentials redacted
+    r.Use(a.accessLog())
+
+    // Per-route request and SLI counters for /metrics
+    r.Use(a.recordMetrics())
+
+    // Response compression for clients that accept it
+    r.Use(compressResponses())
+
+    // Error responses as application/problem+json, including panics and
+    // the middleware rejections below
+    r.Use(a.problemResponses())
+
+    // Panics become 500s and, with server errors, go to the error reporter
+    r.Use(a.recoverPanics())
+
+    // CORS middleware - only allow specific origins
+    r.Use(cors.New(cors.Config{
+        AllowOrigins:     a.cfg.AllowedOrigins,
+        AllowOriginFunc:  a.originAllowed,
+        AllowMethods:     []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "If-None-Match", "Idempotency-Key", "Tus-Resumable", "Upload-Offset"},
+        ExposeHeaders:    []string{"Content-Length", "ETag", "Idempotent-Replayed", "Tus-Resumable", "Tus-Version", "Upload-Offset", "Upload-Length"},
+        AllowCredentials: true,
+    }))
+
+    r.Use(a.ipAccess(""))
+
+    // Maintenance read-only mode, before any body is read
+    r.Use(a.refuseWrites())
+
+    // Bounded bodies and JSON nesting before anything decodes them
+    r.Use(limitBodies())
+
+    // Requests acting as a peer must carry that peer's token, if any
+    r.Use(matchPeerToken())
+
+    // Room codes moved into their frontend's namespace, if namespaces are on
+    r.Use(namespaceRooms())
+
+    // Operator suspensions, checked once bodies are bounded
+    r.Use(a.refuseSuspended())
+    r.Use(a.throttleFlagged())
+
+    // Registered after compression so the logged bodies are readable
+    if a.cfg.Dev {
+        r.Use(logBodies())
+    }
+
+    // Routes
+    r.GET("/", a.rootHandler)
+    r.GET("/health", a.healthHandler)
+    r.GET("/healthz", a.healthzHandler)
+    r.GET("/readyz", a.readyzHandler)
+    r.GET("/metrics", a.ipAccess("metrics"), a.serveMetrics)
+    r.GET("/api/peer-id", a.generatePeerID)
+    r.GET("/turn-credentials", a.getTurnCredentials)
+    r.GET("/j/:slug", requireSignedURL(true), a.followShortLink)
+    r.GET("/join/:roomCode", requireSignedURL(true), a.followJoinLink)
+    r.GET("/s/:slug", requireSignedURL(true), a.followQuickShare)
+    r.POST("/s/:slug/claim", a.ipAccess("rooms"), requireSignedURL(true), a.claimQuickShare)
+    r.POST("/share", a.ipAccess("rooms"), a.idempotent(), a.createQuickShare)
+    r.GET("/nat", a.natInfo)
+    r.GET("/probe", a.probe)
+    r.POST("/nat/classify", a.classifyNAT)
+
+    pairAPI := r.Group("/pair", a.ipAccess("rooms"))
+    pairAPI.POST("/start", a.startPairing)
+    pairAPI.POST("/claim", a.claimPairing)
+
+    nearbyAPI := r.Group("/nearby", a.ipAccess("rooms"))
+    nearbyAPI.GET("", a.getNearbyPeers)
+    nearbyAPI.PUT("", a.advertiseNearby)
+    nearbyAPI.DELETE("", a.stopAdvertisingNearby)
+    nearbyAPI.POST("/:peerId/request", a.requestNearby)
+    nearbyAPI.POST("/requests/:peerId/accept", a.answerNearby(true))
+    nearbyAPI.POST("/requests/:peerId/decline", a.answerNearby(false))
+
+    roomAPI := r.Group("/room", a.ipAccess("rooms"))
+    roomAPI.POST("/create", a.idempotent(), a.createRoom)
+    roomAPI.POST("/join", a.idempotent(), a.joinRoom)
+    roomAPI.POST("/leave", a.idempotent(), a.leaveRoom)
+    roomAPI.POST("/resume", a.resumeSession)
+    roomAPI.POST("/heartbeat", a.heartbeat)
+    roomAPI.GET("/:roomCode", a.getRoomInfo)
+    roomAPI.PUT("/:roomCode", a.updateRoomInfo)
+    roomAPI.POST("/:roomCode/permissions", a.setPeerPermissions)
+    roomAPI.POST("/:roomCode/lock", a.lockRoom(true))
+    roomAPI.POST("/:roomCode/unlock", a.lockRoom(false))
+    roomAPI.POST("/:roomCode/pin", a.pinRoom(true))
+    roomAPI.POST("/:roomCode/unpin", a.pinRoom(false))
+    roomAPI.GET("/:roomCode/policy", a.getRoomPolicy)
+    roomAPI.PUT("/:roomCode/policy", a.setRoomPolicy)
+    roomAPI.GET("/:roomCode/pending", a.getPendingPeers)
+    roomAPI.POST("/:roomCode/approve", a.approveJoin)
+    roomAPI.GET("/:roomCode/qr", a.getRoomQRCode)
+    roomAPI.GET("/:roomCode/calendar.ics", a.getRoomCalendar)
+    roomAPI.POST("/:roomCode/link", a.createShortLink)
+    roomAPI.POST("/:roomCode/invite", a.inviteToRoom)
+    roomAPI.GET("/:roomCode/peers", a.getRoomPeers)
+    roomAPI.POST("/:roomCode/lan", a.announceNetwork)
+    roomAPI.GET("/:roomCode/lan", a.getLANHints)
+    roomAPI.GET("/:roomCode/events", a.getRoomEvents)
+    roomAPI.GET("/:roomCode/insights", a.getRoomInsights)
+    roomAPI.GET("/:roomCode/files", a.listFiles)
+    roomAPI.POST("/:roomCode/files", a.idempotent(), a.offerFile)
+    roomAPI.DELETE("/:roomCode/files/:fileId", a.withdrawFile)
+    roomAPI.GET("/:roomCode/files/:fileId/tree", a.getFileTree)
+    roomAPI.PUT("/:roomCode/files/:fileId/preview", a.setFilePreview)
+    roomAPI.POST("/:roomCode/files/:fileId/request", a.requestFile)
+    roomAPI.POST("/:roomCode/files/:fileId/accept", a.answerFileRequest(true))
+    roomAPI.POST("/:roomCode/files/:fileId/decline", a.answerFileRequest(false))
+    roomAPI.POST("/:roomCode/files/:fileId/progress", a.reportProgress)
+    roomAPI.GET("/:roomCode/transfers", a.getRoomTransfers)
+    roomAPI.PUT("/:roomCode/files/:fileId/hashes", a.publishFileHashes)
+    roomAPI.POST("/:roomCode/files/:fileId/verification", a.reportVerification)
+    roomAPI.GET("/:roomCode/files/:fileId/transfers", a.getFileTransfers)
+    roomAPI.PUT("/:roomCode/files/:fileId/relay", a.uploadRelayedFile)
+    roomAPI.GET("/:roomCode/files/:fileId/relay", requireSignedURL(os.Getenv("SIGNED_RELAY_DOWNLOADS") == "true"), a.downloadRelayedFile)
+    roomAPI.HEAD("/:roomCode/files/:fileId/relay", a.relayUploadOffset)
+    roomAPI.PATCH("/:roomCode/files/:fileId/relay", a.appendRelayUpload)
+    roomAPI.POST("/:roomCode/files/:fileId/relay/upload-url", a.requestRelayUpload)
+    roomAPI.POST("/:roomCode/files/:fileId/relay/download-url", a.requestRelayDownload)
+    roomAPI.POST("/:roomCode/files/:fileId/relay/complete", a.completeRelayUpload)
+
+    r.GET("/notifications/:peerId", a.getNotifications)
+    r.POST("/rooms/batch", a.ipAccess("rooms"), a.idempotent(), a.batchRooms)
+    r.POST("/rooms/schedule", a.ipAccess("rooms"), a.idempotent(), a.scheduleRoom)
+    r.POST("/rooms/:roomCode/clone", a.ipAccess("rooms"), a.idempotent(), a.cloneRoom)
+    r.POST("/messages", a.idempotent(), a.sendMessage)
+    r.GET("/auth/providers", a.listLoginProviders)
+    r.GET("/auth/:provider/login", a.startLogin)
+    r.GET("/auth/:provider/callback", a.finishLogin)
+    r.GET("/me", a.getMe)
+    r.GET("/me/devices", a.getDevices)
+    r.POST("/me/devices", a.registerDevice)
+    r.DELETE("/me/devices/:deviceId", a.removeDevice)
+    r.POST("/me/devices/:deviceId/send", a.sendToDevice)
+    r.GET("/me/contacts", a.getContacts)
+    r.POST("/me/contacts", a.requestContact)
+    r.POST("/me/contacts/:userId/accept", a.acceptContact)
+    r.DELETE("/me/contacts/:userId", a.removeContact)
+    r.POST("/me/contacts/:userId/send", a.shareWithContact)
+    r.POST("/me/shares/:roomCode/accept", a.answerShare(true))
+    r.POST("/me/shares/:roomCode/decline", a.answerShare(false))
+    r.GET("/peers/me/recent-rooms", a.getRecentRooms)
+    r.GET("/peers/me/templates", a.getTemplates)
+    r.PUT("/peers/me/templates/:name", a.saveTemplate)
+    r.DELETE("/peers/me/templates/:name", a.deleteTemplate)
+    r.GET("/peers/me/blocks", a.getBlocks)
+    r.PUT("/peers/me/blocks/:peerId", a.blockPeer)
+    r.DELETE("/peers/me/blocks/:peerId", a.unblockPeer)
+    r.GET("/peers/:peerId/rooms", a.getPeerRooms)
+    r.DELETE("/peers/:peerId/data", a.erasePeerData)
+    r.POST("/reports", a.fileReport)
+    r.POST("/telemetry/connection", a.reportConnection)
+    r.POST("/telemetry/client-log", a.reportClientLogs)
+
+    // Admin API
+    admin := r.Group("/admin", a.ipAccess("admin"), a.requireAdmin())
+    admin.GET("/audit", a.getAuditLog)
+    admin.GET("/audit/stream", a.streamAuditLog)
+    admin.GET("/notifications", a.getDispatcherStats)
+    admin.GET("/webtransport", a.getWebTransportStats)
+    admin.GET("/analytics", a.getAnalytics)
+    admin.GET("/client-logs", a.getClientLogs)
+    admin.GET("/overview", a.getOverview)
+    admin.GET("/usage", a.getUsage)
+    admin.GET("/rooms", a.listRooms)
+    admin.GET("/rooms/:roomCode", a.getRoomDetail)
+    admin.DELETE("/rooms/:roomCode", a.forceCloseRoom)
+    admin.POST("/rooms/:roomCode/pin", a.adminPinRoom(true))
+    admin.DELETE("/rooms/:roomCode/pin", a.adminPinRoom(false))
+    admin.GET("/retention", a.getRetentionStatus)
+    admin.GET("/policies", a.getFilePolicies)
+    admin.GET("/reports", a.listReports)
+    admin.POST("/reports/:reportId/resolve", a.resolveReport)
+    admin.GET("/relay", a.getRelayStats)
+    admin.GET("/quarantine", a.listQuarantine)
+    admin.POST("/quarantine/:quarantineId/resolve", a.resolveQuarantine)
+    admin.GET("/turn", a.verifyTurnProvider)
+    admin.GET("/drain", a.getDrainStatus)
+    admin.POST("/drain", a.startDrain)
+    admin.DELETE("/drain", a.stopDrain)
+    admin.GET("/readonly", a.getReadOnly)
+    admin.PUT("/readonly", a.setReadOnly)
+    admin.DELETE("/readonly", a.clearReadOnly)
+    admin.GET("/jobs", a.listJobs)
+    admin.GET("/jobs/:name", a.getJob)
+    admin.POST("/jobs/:name/run", a.runJob)
+    admin.GET("/snapshot", a.exportState)
+    admin.POST("/snapshot", a.importState)
+    admin.GET("/suspensions", a.listSuspensions)
+    admin.POST("/suspensions", a.createSuspension)
+    admin.DELETE("/suspensions/:kind/:value", a.liftSuspension)
+    admin.GET("/flags", a.listFlags)
+    admin.PUT("/flags/:name", a.setFlag)
+    admin.DELETE("/flags/:name", a.clearFlag)
+    admin.GET("/anomalies", a.listAnomalies)
+    admin.POST("/anomalies/:findingId/dismiss", a.dismissAnomaly)
+
+    return r
+}
+
+func (a *API) rootHandler(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "service": "P2P File Sharing Backend",
+        "endpoints": gin.H{
+            "peerjs":  "/peerjs",
+            "health":  "/health",
+            "healthz": "/healthz",
+            "readyz":  "/readyz",
+            "metrics": "/metrics",
+            "rooms": gin.H{
+                "create":         "POST /room/create",
+                "join":           "POST /room/join",
+                "leave":          "POST /room/leave",
+                "resume":         "POST /room/resume",
+                "heartbeat":      "POST /room/heartbeat",
+                "info":           "GET /room/:roomCode",
+                "updateInfo":     "PUT /room/:roomCode",
+                "getPeers":       "GET /room/:roomCode/peers",
+                "setPermissions": "POST /room/:roomCode/permissions",
+                "lock":           "POST /room/:roomCode/lock",
+                "unlock":         "POST /room/:roomCode/unlock",
+                "pin":            "POST /room/:roomCode/pin",
+                "unpin":          "POST /room/:roomCode/unpin",
+                "policy":         "GET /room/:roomCode/policy",
+                "setPolicy":      "PUT /room/:roomCode/policy",
+                "pending":        "GET /room/:roomCode/pending",
+                "approve":        "POST /room/:roomCode/approve",
+                "qrCode":         "GET /room/:roomCode/qr",
+                "calendar":       "GET /room/:roomCode/calendar.ics",
+                "shortLink":      "POST /room/:roomCode/link",
+                "invite":         "POST /room/:roomCode/invite",
+                "announceLan":    "POST /room/:roomCode/lan",
+                "lanHints":       "GET /room/:roomCode/lan",
+                "events":         "GET /room/:roomCode/events?since=",
+            },
+            "files": gin.H{
+                "list":          "GET /room/:roomCode/files",
+                "offer":         "POST /room/:roomCode/files",
+                "withdraw":      "DELETE /room/:roomCode/files/:fileId",
+                "tree":          "GET /room/:roomCode/files/:fileId/tree",
+                "preview":       "PUT /room/:roomCode/files/:fileId/preview",
+                "request":       "POST /room/:roomCode/files/:fileId/request",
+                "accept":        "POST /room/:roomCode/files/:fileId/accept",
+                "decline":       "POST /room/:roomCode/files/:fileId/decline",
+                "progress":      "POST /room/:roomCode/files/:fileId/progress",
+                "roomTransfers": "GET /room/:roomCode/transfers",
+                "hashes":        "PUT /room/:roomCode/files/:fileId/hashes",
+                "verification":  "POST /room/:roomCode/files/:fileId/verification",
+                "transfers":     "GET /room/:roomCode/files/:fileId/transfers",
+                "upload":        "PUT /room/:roomCode/files/:fileId/relay",
+                "download":      "GET /room/:roomCode/files/:fileId/relay",
+                "uploadUrl":     "POST /room/:roomCode/files/:fileId/relay/upload-url",
+                "downloadUrl":   "POST /room/:roomCode/files/:fileId/relay/download-url",
+                "complete":      "POST /room/:roomCode/files/:fileId/relay/complete",
+                "resumable":     "PATCH /room/:roomCode/files/:fileId/relay",
+            },
+            "quickShare": gin.H{
+                "create": "POST /share",
+                "open":   "GET /s/:slug",
+                "claim":  "POST /s/:slug/claim",
+            },
+            "batch":     "POST /rooms/batch",
+            "peerRooms": "GET /peers/:peerId/rooms",
+            "messages":  "POST /messages",
+            "erasure":   "DELETE /peers/:peerId/data",
+            "reports":   "POST /reports",
+            "telemetry": "POST /telemetry/connection",
+            "nearby": gin.H{
+                "list":      "GET /nearby",
+                "advertise": "PUT /nearby",
+                "stop":      "DELETE /nearby",
+                "request":   "POST /nearby/:peerId/request",
+                "accept":    "POST /nearby/requests/:peerId/accept",
+                "decline":   "POST /nearby/requests/:peerId/decline",
+            },
+            "pair": gin.H{
+                "start": "POST /pair/start",
+                "claim": "POST /pair/claim",
+            },
+            "nat": gin.H{
+                "info":     "GET /nat",
+                "classify": "POST /nat/classify",
+            },
+            "recentRooms": "GET /peers/me/recent-rooms",
+            "schedule":    "POST /rooms/schedule",
+            "clone":       "POST /rooms/:roomCode/clone",
+            "templates":   "GET /peers/me/templates",
+            "blocks":      "GET /peers/me/blocks",
+            "login": gin.H{
+                "providers": "GET /auth/providers",
+                "start":     "GET /auth/:provider/login?returnTo=",
+                "callback":  "GET /auth/:provider/callback",
+                "me":        "GET /me",
+                "devices":   "GET /me/devices",
+                "sendTo":    "POST /me/devices/:deviceId/send",
+                "contacts":  "GET /me/contacts",
+                "share":     "POST /me/contacts/:userId/send",
+            },
+        },
+    })
+}
+
+// healthCounts is the aggregate served by /health
+type healthCounts struct {
+    rooms int
+    peers int
+    at    time.Time
+}
+
+// healthHandler serves aggregate counts cached for healthCacheTTL
+func (a *API) healthHandler(c *gin.Context) {
+    counts := a.currentHealthCounts()
+
+    c.JSON(http.StatusOK, gin.H{
+        "status":        "ok",
+        "rooms":         counts.rooms,
+        "totalPeers":    counts.peers,
+        "peerJsEnabled": true,
+    })
+}
+
+// currentHealthCounts returns the room and peer counts, cached for
+// healthCacheTTL and built from room snapshots, so frequent probes and
+// scrapes neither walk every room nor take room locks
+func (a *API) currentHealthCounts() *healthCounts {
+    counts := a.healthCounts.Load()
+    if counts == nil || time.Since(counts.at) > healthCacheTTL {
+        counts = &healthCounts{at: time.Now()}
+        a.rooms.Range(func(_ string, room *rooms.Room) bool {
+            counts.peers += len(room.Snapshot().Peers)
+            counts.rooms++
+            return true
+        })
+        a.healthCounts.Store(counts)
+    }
+    return counts
+}
+
+func (a *API) generatePeerID(c *gin.Context) {
+    id := uuid.New().String()
+    c.JSON(http.StatusOK, gin.H{
+        "id":    id,
+        "token": issuePeerToken(id),
+    })
+}
@@ -0,0 +1,217 @@
+package httpapi
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    maxAuditEntries      = 50000
+    auditTrimSlack       = maxAuditEntries / 10
+    defaultAuditPageSize = 100
+    maxAuditPageSize     = 1000
+
+    auditKeepaliveInterval = 15 * time.Second
+)
+
+// AuditEntry is one record in the append-only room event trail
+type AuditEntry struct {
+    ID       int64  `json:"id"`
+    Time     int64  `json:"time"`
+    RoomCode string `json:"roomCode"`
+    Type     string `json:"type"`
+    Actor    string `json:"actor,omitempty"`
+    Target   string `json:"target,omitempty"`
+    Details  gin.H  `json:"details,omitempty"`
+}
+
+// auditTrail is the in-memory audit log
+type auditTrail struct {
+    mu      sync.RWMutex
+    entries []AuditEntry
+    nextID  int64
+
+    // appended is closed and replaced whenever an entry is recorded, waking
+    // anyone tailing the trail
+    appended chan struct{}
+}
+
+// recordAudit appends an event to the audit trail. Once the trail is full the
+// oldest entries are dropped so memory stays bounded.
+func (a *API) recordAudit(roomCode, eventType, actor, target string, details gin.H) {
+    a.audit.mu.Lock()
+    defer a.audit.mu.Unlock()
+
+    a.audit.entries = append(a.audit.entries, AuditEntry{
+        ID:       a.audit.nextID,
+        Time:     time.Now().Unix(),
+        RoomCode: roomCode,
+        Type:     eventType,
+        Actor:    actor,
+        Target:   target,
+        Details:  details,
+    })
+    a.audit.nextID++
+    close(a.audit.appended)
+    a.audit.appended = make(chan struct{})
+
+    // Trim in batches of auditTrimSlack so the copy isn't paid on every append
+    if len(a.audit.entries) > maxAuditEntries+auditTrimSlack {
+        a.audit.entries = append([]AuditEntry(nil), a.audit.entries[len(a.audit.entries)-maxAuditEntries:]...)
+    }
+}
+
+// auditFilter selects entries by room, type and actor (matching either the
+// actor or the target)
+type auditFilter struct {
+    roomCode  string
+    eventType string
+    actor     string
+}
+
+func auditFilterFromQuery(c *gin.Context) auditFilter {
+    return auditFilter{
+        roomCode:  c.Query("room"),
+        eventType: c.Query("type"),
+        actor:     c.Query("actor"),
+    }
+}
+
+func (f auditFilter) match(entry AuditEntry) bool {
+    if f.roomCode != "" && entry.RoomCode != f.roomCode {
+        return false
+    }
+    if f.eventType != "" && entry.Type != f.eventType {
+        return false
+    }
+    if f.actor != "" && entry.Actor != f.actor && entry.Target != f.actor {
+        return false
+    }
+    return true
+}
+
+// getAuditLog returns audit entries oldest-first, filtered by room, type,
+// actor (matches actor or target), and time range. Pass the returned
+// nextCursor as ?cursor= to fetch the following page.
+func (a *API) getAuditLog(c *gin.Context) {
+    filter := auditFilterFromQuery(c)
+
+    var cursor, since, until int64
+    var err error
+    for name, dst := range map[string]*int64{"cursor": &cursor, "since": &since, "until": &until} {
+        if v := c.Query(name); v != "" {
+            if *dst, err = strconv.ParseInt(v, 10, 64); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an integer"})
+                return
+            }
+        }
+    }
+
+    limit := defaultAuditPageSize
+    if v := c.Query("limit"); v != "" {
+        limit, err = strconv.Atoi(v)
+        if err != nil || limit < 1 || limit > maxAuditPageSize {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxAuditPageSize)})
+            return
+        }
+    }
+
+    a.audit.mu.RLock()
+    entries := make([]AuditEntry, 0, limit)
+    var nextCursor int64
+    for _, entry := range a.audit.entries {
+        if entry.ID <= cursor {
+            continue
+        }
+        if !filter.match(entry) {
+            continue
+        }
+        if since != 0 && entry.Time < since {
+            continue
+        }
+        if until != 0 && entry.Time > until {
+            continue
+        }
+        if len(entries) == limit {
+            nextCursor = entries[len(entries)-1].ID
+            break
+        }
+        entries = append(entries, entry)
+    }
+    a.audit.mu.RUnlock()
+
+    resp := gin.H{"entries": entries}
+    if nextCursor != 0 {
+        resp["nextCursor"] = nextCursor
+    }
+    c.JSON(http.StatusOK, resp)
+}
+
+// streamAuditLog tails the audit trail as server-sent events, with the same
+// room, type and actor filters as getAuditLog. Entries after ?cursor= are
+// replayed first; without a cursor only new entries are sent.
+func (a *API) streamAuditLog(c *gin.Context) {
+    filter := auditFilterFromQuery(c)
+
+    a.audit.mu.RLock()
+    cursor := a.audit.nextID - 1
+    a.audit.mu.RUnlock()
+    if v := c.Query("cursor"); v != "" {
+        var err error
+        if cursor, err = strconv.ParseInt(v, 10, 64); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "cursor must be an integer"})
+            return
+        }
+    }
+
+    c.Header("Content-Type", "text/event-stream")
+    c.Header("Cache-Control", "no-cache")
+    c.Header("X-Accel-Buffering", "no")
+    c.Status(http.StatusOK)
+    c.Writer.Flush()
+
+    keepalive := time.NewTicker(auditKeepaliveInterval)
+    defer keepalive.Stop()
+
+    for {
+        a.audit.mu.RLock()
+        start := sort.Search(len(a.audit.entries), func(i int) bool {
+            return a.audit.entries[i].ID > cursor
+        })
+        var batch []AuditEntry
+        for _, entry := range a.audit.entries[start:] {
+            if filter.match(entry) {
+                batch = append(batch, entry)
+            }
+        }
+        if n := len(a.audit.entries); n > start {
+            cursor = a.audit.entries[n-1].ID
+        }
+        appended := a.audit.appended
+        a.audit.mu.RUnlock()
+
+        for _, entry := range batch {
+            data, err := json.Marshal(entry)
+            if err != nil {
+                continue
+            }
+            fmt.Fprintf(c.Writer, "id: %d\nevent: audit\ndata: %s\n\n", entry.ID, data)
+        }
+        c.Writer.Flush()
+
+        select {
+        case <-c.Request.Context().Done():
+            return
+        case <-appended:
+        case <-keepalive.C:
+            c.Writer.WriteString(": keepalive\n\n")
+        }
+    }
+}
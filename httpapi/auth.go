@@ -0,0 +1,95 @@
+package httpapi
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/base64"
+    "log"
+    "os"
+    "strings"
+    "sync"
+
+    "github.com/gin-gonic/gin"
+)
+
+// peerTokenKeys holds the HMAC key for peer tokens and, after a rotation, the
+// key it replaced so tokens issued before the rotation keep validating
+var peerTokenKeys struct {
+    sync.Mutex
+    env      string
+    current  []byte
+    previous []byte
+}
+
+// tokenSecrets returns the current HMAC key for peer tokens and the previous
+// one, if PEER_TOKEN_SECRET has been rotated. Without PEER_TOKEN_SECRET a
+// random key is generated, so tokens stop validating after a restart.
+func tokenSecrets() (current, previous []byte) {
+    peerTokenKeys.Lock()
+    defer peerTokenKeys.Unlock()
+
+    if secret := os.Getenv("PEER_TOKEN_SECRET"); secret != "" && secret != peerTokenKeys.env {
+        if peerTokenKeys.current != nil {
+            log.Println("🔑 PEER_TOKEN_SECRET rotated, accepting the previous key until the next rotation")
+        }
+        peerTokenKeys.env = secret
+        peerTokenKeys.previous = peerTokenKeys.current
+        peerTokenKeys.current = []byte(secret)
+    }
+    if peerTokenKeys.current == nil {
+        log.Println("⚠️  PEER_TOKEN_SECRET not set, peer tokens will not survive restarts")
+        peerTokenKeys.current = make([]byte, 32)
+        if _, err := rand.Read(peerTokenKeys.current); err != nil {
+            log.Fatalf("❌ Failed to generate peer token secret: %v", err)
+        }
+    }
+    return peerTokenKeys.current, peerTokenKeys.previous
+}
+
+func signPeerID(key []byte, peerID string) string {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(peerID))
+    return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issuePeerToken returns a token proving ownership of peerID, in the form
+// "<peerID>.<signature>"
+func issuePeerToken(peerID string) string {
+    current, _ := tokenSecrets()
+    return peerID + "." + signPeerID(current, peerID)
+}
+
+// verifyPeerToken returns the peer ID a token was issued for
+func verifyPeerToken(token string) (string, bool) {
+    i := strings.LastIndexByte(token, '.')
+    if i <= 0 {
+        return "", false
+    }
+    peerID, sig := token[:i], token[i+1:]
+    current, previous := tokenSecrets()
+    if hmac.Equal([]byte(sig), []byte(signPeerID(current, peerID))) {
+        return peerID, true
+    }
+    if previous != nil && hmac.Equal([]byte(sig), []byte(signPeerID(previous, peerID))) {
+        return peerID, true
+    }
+    return "", false
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(c *gin.Context) string {
+    return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
+
+// isAdminRequest reports whether the request carries the admin token
+func isAdminRequest(c *gin.Context) bool {
+    token := os.Getenv("ADMIN_TOKEN")
+    return token != "" && subtle.ConstantTimeCompare([]byte(bearerToken(c)), []byte(token)) == 1
+}
+
+// authenticatedPeer returns the peer ID proven by the request's bearer token
+func authenticatedPeer(c *gin.Context) (string, bool) {
+    return verifyPeerToken(bearerToken(c))
+}
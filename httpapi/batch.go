@@ -0,0 +1,133 @@
+package httpapi
+
+import (
+    "net/http"
+    "sort"
+    "strconv"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/rooms"
+)
+
+// maxBatchOperations caps how many rooms one batch request may touch
+const maxBatchOperations = 20
+
+// Batch operation actions
+const (
+    batchJoin      = "join"
+    batchHeartbeat = "heartbeat"
+    batchLeave     = "leave"
+)
+
+// batchRooms lets a peer join, heartbeat or leave several rooms in one
+// request. Operations run in order and fail independently.
+func (a *API) batchRooms(c *gin.Context) {
+    var req struct {
+        PeerID     string `json:"peerId" binding:"required"`
+        Operations []struct {
+            RoomCode string `json:"roomCode" binding:"required"`
+            Action   string `json:"action" binding:"required"`
+            Presence string `json:"presence"`
+        } `json:"operations" binding:"required,dive"`
+        rooms.PeerProfile
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+
+    if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "operations must contain between 1 and " + strconv.Itoa(maxBatchOperations) + " entries"})
+        return
+    }
+
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    results := make([]gin.H, 0, len(req.Operations))
+    for _, op := range req.Operations {
+        result := gin.H{"roomCode": op.RoomCode, "action": op.Action}
+
+        switch op.Action {
+        case batchJoin:
+            status, errMsg := a.checkJoin(c, op.RoomCode, req.PeerID, req.PeerProfile)
+            var resp gin.H
+            if errMsg == "" {
+                resp, status, errMsg = a.addPeer(op.RoomCode, req.PeerID, req.PeerProfile, addressFamily(c.ClientIP()))
+            }
+            result["status"] = status
+            if errMsg != "" {
+                result["error"] = errMsg
+            } else {
+                for k, v := range resp {
+                    result[k] = v
+                }
+            }
+        case batchHeartbeat:
+            if op.Presence != "" && !rooms.ValidPresence(op.Presence) {
+                result["status"] = http.StatusBadRequest
+                result["error"] = "presence must be one of online, idle, transferring, away"
+                break
+            }
+            presence, status, errMsg := a.recordHeartbeat(op.RoomCode, req.PeerID, op.Presence)
+            result["status"] = status
+            if errMsg != "" {
+                result["error"] = errMsg
+            } else {
+                result["presence"] = presence
+            }
+        case batchLeave:
+            a.removePeerFromRoom(op.RoomCode, req.PeerID)
+            result["status"] = http.StatusOK
+        default:
+            result["status"] = http.StatusBadRequest
+            result["error"] = "action must be one of join, heartbeat, leave"
+        }
+
+        results = append(results, result)
+    }
+
+    c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// Membership describes one room a peer belongs to
+type Membership struct {
+    RoomCode    string `json:"roomCode"`
+    Role        string `json:"role"`
+    Permissions string `json:"permissions"`
+    Presence    string `json:"presence"`
+    JoinedAt    int64  `json:"joinedAt"`
+    RoomSize    int    `json:"roomSize"`
+}
+
+// getPeerRooms lists every room a peer is currently in
+func (a *API) getPeerRooms(c *gin.Context) {
+    peerID := c.Param("peerId")
+
+    memberships := make([]Membership, 0)
+    a.rooms.Range(func(roomCode string, room *rooms.Room) bool {
+        room.RLock()
+        if peer, ok := room.Peers[peerID]; ok {
+            memberships = append(memberships, Membership{
+                RoomCode:    roomCode,
+                Role:        peer.Role,
+                Permissions: peer.Permissions,
+                Presence:    peer.Presence,
+                JoinedAt:    peer.JoinedAt,
+                RoomSize:    len(room.Peers),
+            })
+        }
+        room.RUnlock()
+        return true
+    })
+
+    sort.Slice(memberships, func(i, j int) bool {
+        return memberships[i].RoomCode < memberships[j].RoomCode
+    })
+
+    c.JSON(http.StatusOK, gin.H{"rooms": memberships})
+}
@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+)
+
+// benchRooms is how many rooms the benchmark peers are spread across
+const benchRooms = 64
+
+// newBenchRouter returns a fresh API's handler. Every request comes from the
+// same address, so the per-IP room quotas are lifted.
+func newBenchRouter(b *testing.B) http.Handler {
+    b.Setenv("MAX_ROOMS_PER_IP", "0")
+    b.Setenv("ROOMS_PER_IP_PER_HOUR", "0")
+    b.ReportAllocs()
+    return New(Config{}).Router()
+}
+
+// serve sends one request straight to the handler and fails the benchmark
+// on an unexpected status
+func serve(b *testing.B, h http.Handler, method, path, body string) {
+    var req *http.Request
+    if body != "" {
+        req = httptest.NewRequest(method, path, strings.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+    } else {
+        req = httptest.NewRequest(method, path, nil)
+    }
+    rec := httptest.NewRecorder()
+    h.ServeHTTP(rec, req)
+    if rec.Code >= 300 {
+        b.Errorf("%s %s: %d %s", method, path, rec.Code, rec.Body.String())
+    }
+}
+
+func joinBody(roomCode, peerID string) string {
+    return fmt.Sprintf(`{"roomCode":%q,"peerId":%q}`, roomCode, peerID)
+}
+
+// seedPeers puts one peer in each benchmark room and returns their IDs
+func seedPeers(b *testing.B, h http.Handler) []string {
+    peers := make([]string, benchRooms)
+    for i := range peers {
+        peers[i] = fmt.Sprintf("bench-seed-%d", i)
+        serve(b, h, http.MethodPost, "/room/create", joinBody(fmt.Sprintf("BENCH%d", i), peers[i]))
+    }
+    return peers
+}
+
+func BenchmarkJoinLeave(b *testing.B) {
+    h := newBenchRouter(b)
+    seedPeers(b, h)
+    var next atomic.Int64
+    b.ResetTimer()
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            i := next.Add(1)
+            roomCode := fmt.Sprintf("BENCH%d", i%benchRooms)
+            peerID := fmt.Sprintf("bench-%d", i)
+            serve(b, h, http.MethodPost, "/room/join", joinBody(roomCode, peerID))
+            serve(b, h, http.MethodPost, "/room/leave", joinBody(roomCode, peerID))
+        }
+    })
+}
+
+func BenchmarkHeartbeat(b *testing.B) {
+    h := newBenchRouter(b)
+    peers := seedPeers(b, h)
+    var next atomic.Int64
+    b.ResetTimer()
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            i := next.Add(1) % benchRooms
+            serve(b, h, http.MethodPost, "/room/heartbeat", joinBody(fmt.Sprintf("BENCH%d", i), peers[i]))
+        }
+    })
+}
+
+func BenchmarkPollNotifications(b *testing.B) {
+    h := newBenchRouter(b)
+    peers := seedPeers(b, h)
+    var next atomic.Int64
+    b.ResetTimer()
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            serve(b, h, http.MethodGet, "/notifications/"+peers[next.Add(1)%benchRooms], "")
+        }
+    })
+}
+
+func BenchmarkRoomPeers(b *testing.B) {
+    h := newBenchRouter(b)
+    seedPeers(b, h)
+    var next atomic.Int64
+    b.ResetTimer()
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            serve(b, h, http.MethodGet, fmt.Sprintf("/room/BENCH%d/peers", next.Add(1)%benchRooms), "")
+        }
+    })
+}
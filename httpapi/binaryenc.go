@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+    "bytes"
+    "encoding/json"
+    "log"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "github.com/ugorji/go/codec"
+)
+
+// Binary response encodings offered on hot endpoints, for clients on slow
+// or metered networks
+const (
+    mimeMsgPack = "application/msgpack"
+    mimeCBOR    = "application/cbor"
+)
+
+var (
+    msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+    cborHandle    = &codec.CborHandle{}
+)
+
+// respond writes obj as JSON, MessagePack or CBOR, whichever the client's
+// Accept header prefers. Values are encoded exactly as their JSON would be,
+// field names and custom marshalers included, so the formats carry the
+// same data.
+func respond(c *gin.Context, status int, obj interface{}) {
+    varyAccept(c)
+
+    mediaType := preferredFormat(c.GetHeader("Accept"))
+    if mediaType == gin.MIMEJSON {
+        c.JSON(status, obj)
+        return
+    }
+    handle := codec.Handle(msgpackHandle)
+    if mediaType == mimeCBOR {
+        handle = cborHandle
+    }
+
+    body, err := encodeBinary(obj, handle, c.GetString(namespaceKey))
+    if err != nil {
+        log.Printf("❌ Failed to encode %s response: %v", mediaType, err)
+        c.JSON(status, obj)
+        return
+    }
+    c.Data(status, mediaType, body)
+}
+
+// varyAccept marks the response as depending on the Accept header, once
+func varyAccept(c *gin.Context) {
+    for _, v := range c.Writer.Header().Values("Vary") {
+        if strings.EqualFold(v, "Accept") {
+            return
+        }
+    }
+    c.Writer.Header().Add("Vary", "Accept")
+}
+
+// formatETag varies etag by the encoding respond will pick for the
+// request, so a cache never revalidates a CBOR client against a JSON body.
+// It also sets Vary: Accept, which 304 answers must carry too.
+func formatETag(c *gin.Context, etag string) string {
+    varyAccept(c)
+    format := preferredFormat(c.GetHeader("Accept"))
+    if format == gin.MIMEJSON {
+        return etag
+    }
+    return strings.TrimSuffix(etag, `"`) + "-" + strings.TrimPrefix(format, "application/") + `"`
+}
+
+// preferredFormat picks JSON, MessagePack or CBOR from an Accept header by
+// q-value, earlier entries winning ties. Anything else means JSON.
+func preferredFormat(accept string) string {
+    best, bestQ := gin.MIMEJSON, 0.0
+    for _, part := range strings.Split(accept, ",") {
+        name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        q := 1.0
+        if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+                q = parsed
+            }
+        }
+        var mediaType string
+        switch strings.ToLower(strings.TrimSpace(name)) {
+        case mimeMsgPack, "application/x-msgpack", "application/vnd.msgpack":
+            mediaType = mimeMsgPack
+        case mimeCBOR:
+            mediaType = mimeCBOR
+        case gin.MIMEJSON:
+            mediaType = gin.MIMEJSON
+        default:
+            continue
+        }
+        if q > bestQ {
+            best, bestQ = mediaType, q
+        }
+    }
+    return best
+}
+
+// encodeBinary encodes obj with handle by way of its JSON form, stripping
+// namespace from its room codes on the way
+func encodeBinary(obj interface{}, handle codec.Handle, namespace string) ([]byte, error) {
+    data, err := json.Marshal(obj)
+    if err != nil {
+        return nil, err
+    }
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    var generic interface{}
+    if err := dec.Decode(&generic); err != nil {
+        return nil, err
+    }
+
+    var out []byte
+    err = codec.NewEncoderBytes(&out, handle).Encode(binaryValue(stripNamespaceValue(generic, namespace)))
+    return out, err
+}
+
+// binaryValue turns JSON numbers back into integers where they are whole,
+// so they aren't all widened to floats
+func binaryValue(v interface{}) interface{} {
+    switch v := v.(type) {
+    case json.Number:
+        if i, err := v.Int64(); err == nil {
+            return i
+        }
+        f, _ := v.Float64()
+        return f
+    case map[string]interface{}:
+        for k, e := range v {
+            v[k] = binaryValue(e)
+        }
+    case []interface{}:
+        for i, e := range v {
+            v[i] = binaryValue(e)
+        }
+    }
+    return v
+}
@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// maxBlocksPerPeer bounds one peer's blocklist
+const maxBlocksPerPeer = 500
+
+// blockedPeer is one entry in a peer's blocklist
+type blockedPeer struct {
+    PeerID    string `json:"peerId"`
+    BlockedAt int64  `json:"blockedAt"`
+}
+
+// blockLists holds each peer's personal blocklist. Blocks follow the
+// blocker's identity rather than a room: blocked peers are hidden from its
+// peer listings and their messages are dropped in every room.
+type blockLists struct {
+    mu    sync.RWMutex
+    peers map[string]map[string]int64
+    // revisions change whenever a peer's list does, so cached listings
+    // filtered by it go stale
+    revisions map[string]int64
+}
+
+// blocked returns the set of peers peerID has blocked and the list's
+// revision, or nil if it has blocked nobody
+func (a *API) blocked(peerID string) (map[string]bool, int64) {
+    a.blocks.mu.RLock()
+    defer a.blocks.mu.RUnlock()
+    list := a.blocks.peers[peerID]
+    if len(list) == 0 {
+        return nil, 0
+    }
+    set := make(map[string]bool, len(list))
+    for id := range list {
+        set[id] = true
+    }
+    return set, a.blocks.revisions[peerID]
+}
+
+// isBlocked reports whether blocker has blocked peerID
+func (a *API) isBlocked(blocker, peerID string) bool {
+    a.blocks.mu.RLock()
+    defer a.blocks.mu.RUnlock()
+    _, ok := a.blocks.peers[blocker][peerID]
+    return ok
+}
+
+// eraseBlocks forgets peerID's blocklist and returns how many entries it
+// held. Blocks others placed on peerID are theirs and are kept.
+func (a *API) eraseBlocks(peerID string) int {
+    a.blocks.mu.Lock()
+    defer a.blocks.mu.Unlock()
+    n := len(a.blocks.peers[peerID])
+    delete(a.blocks.peers, peerID)
+    delete(a.blocks.revisions, peerID)
+    return n
+}
+
+// getBlocks lists the bearer-token peer's blocked peers, most recent first
+func (a *API) getBlocks(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    tagRequest(c, "", peerID)
+
+    a.blocks.mu.RLock()
+    list := make([]blockedPeer, 0, len(a.blocks.peers[peerID]))
+    for id, at := range a.blocks.peers[peerID] {
+        list = append(list, blockedPeer{PeerID: id, BlockedAt: at})
+    }
+    a.blocks.mu.RUnlock()
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].BlockedAt != list[j].BlockedAt {
+            return list[i].BlockedAt > list[j].BlockedAt
+        }
+        return list[i].PeerID < list[j].PeerID
+    })
+
+    c.JSON(http.StatusOK, gin.H{"blocked": list})
+}
+
+// blockPeer adds a peer to the bearer-token peer's blocklist. The blocked
+// peer is not told.
+func (a *API) blockPeer(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    target := c.Param("peerId")
+    tagRequest(c, "", peerID)
+
+    if target == peerID {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A peer cannot block itself"})
+        return
+    }
+
+    now := time.Now()
+    a.blocks.mu.Lock()
+    list := a.blocks.peers[peerID]
+    blockedAt, exists := list[target]
+    if !exists {
+        if len(list) >= maxBlocksPerPeer {
+            a.blocks.mu.Unlock()
+            c.JSON(http.StatusConflict, gin.H{"error": "At most " + strconv.Itoa(maxBlocksPerPeer) + " peers can be blocked"})
+            return
+        }
+        if list == nil {
+            list = make(map[string]int64)
+            a.blocks.peers[peerID] = list
+        }
+        blockedAt = now.Unix()
+        list[target] = blockedAt
+        a.blocks.revisions[peerID] = now.UnixNano()
+    }
+    a.blocks.mu.Unlock()
+
+    if !exists {
+        log.Printf("🚫 %s blocked %s", peerID, target)
+    }
+    c.JSON(http.StatusOK, blockedPeer{PeerID: target, BlockedAt: blockedAt})
+}
+
+// unblockPeer removes a peer from the bearer-token peer's blocklist
+func (a *API) unblockPeer(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    target := c.Param("peerId")
+    tagRequest(c, "", peerID)
+
+    a.blocks.mu.Lock()
+    _, exists := a.blocks.peers[peerID][target]
+    if exists {
+        delete(a.blocks.peers[peerID], target)
+        if len(a.blocks.peers[peerID]) == 0 {
+            delete(a.blocks.peers, peerID)
+            delete(a.blocks.revisions, peerID)
+        } else {
+            a.blocks.revisions[peerID] = time.Now().UnixNano()
+        }
+    }
+    a.blocks.mu.Unlock()
+
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Peer is not blocked"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
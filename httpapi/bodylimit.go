@@ -0,0 +1,101 @@
+package httpapi
+
+import (
+    "bytes"
+    "errors"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    defaultMaxBodyBytes      = 64 * 1024
+    defaultMaxAdminBodyBytes = 32 * 1024 * 1024 // snapshot imports
+    defaultMaxJSONDepth      = 32
+)
+
+// streamedBodies are routes whose handlers stream and bound their own
+// request bodies, like relay uploads
+var streamedBodies = map[string]bool{
+    "/room/:roomCode/files/:fileId/relay": true,
+}
+
+// limitBodies rejects request bodies over MAX_BODY_BYTES (default 64 KB;
+// MAX_ADMIN_BODY_BYTES, default 32 MB, for the admin API) with 413, and JSON
+// bodies nested deeper than MAX_JSON_DEPTH (default 32) with 400, before any
+// handler or the idempotency middleware reads them
+func limitBodies() gin.HandlerFunc {
+    maxBody := int64(envInt("MAX_BODY_BYTES", defaultMaxBodyBytes))
+    maxAdminBody := int64(envInt("MAX_ADMIN_BODY_BYTES", defaultMaxAdminBodyBytes))
+    maxDepth := envInt("MAX_JSON_DEPTH", defaultMaxJSONDepth)
+
+    return func(c *gin.Context) {
+        if c.Request.Body == nil || c.Request.Body == http.NoBody || streamedBodies[c.FullPath()] {
+            c.Next()
+            return
+        }
+
+        limit := maxBody
+        if strings.HasPrefix(c.FullPath(), "/admin/") {
+            limit = maxAdminBody
+        }
+        tooLarge := gin.H{"error": "Request body too large (max " + strconv.FormatInt(limit, 10) + " bytes)"}
+        if c.Request.ContentLength > limit {
+            c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, tooLarge)
+            return
+        }
+
+        // Buffer the body, which is at most limit bytes, so its nesting can be
+        // checked before the JSON decoder allocates anything for it
+        body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+        if err != nil {
+            var maxErr *http.MaxBytesError
+            if errors.As(err, &maxErr) {
+                c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, tooLarge)
+                return
+            }
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+            return
+        }
+        if maxDepth > 0 && jsonDepthExceeds(body, maxDepth) {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "JSON nested more than " + strconv.Itoa(maxDepth) + " levels deep"})
+            return
+        }
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+        c.Next()
+    }
+}
+
+// jsonDepthExceeds reports whether data nests objects and arrays more than
+// limit levels deep. It only tracks brackets outside strings, so malformed
+// JSON is left for the decoder to reject.
+func jsonDepthExceeds(data []byte, limit int) bool {
+    depth := 0
+    inString, escaped := false, false
+    for _, b := range data {
+        switch {
+        case escaped:
+            escaped = false
+        case inString:
+            switch b {
+            case '\\':
+                escaped = true
+            case '"':
+                inString = false
+            }
+        case b == '"':
+            inString = true
+        case b == '{' || b == '[':
+            depth++
+            if depth > limit {
+                return true
+            }
+        case b == '}' || b == ']':
+            depth--
+        }
+    }
+    return false
+}
@@ -0,0 +1,73 @@
+package httpapi
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "os"
+    "strings"
+    "time"
+)
+
+var captchaVerifyURLs = map[string]string{
+    "turnstile": "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+    "hcaptcha":  "https://api.hcaptcha.com/siteverify",
+}
+
+// captchaEnabled reports whether room creation requires a captcha token.
+// Set CAPTCHA_PROVIDER (turnstile or hcaptcha) and CAPTCHA_SECRET to enable.
+func captchaEnabled() bool {
+    return os.Getenv("CAPTCHA_PROVIDER") != ""
+}
+
+// verifyCaptcha checks a client's captcha token with the provider
+func verifyCaptcha(ctx context.Context, token, remoteIP string) error {
+    provider := os.Getenv("CAPTCHA_PROVIDER")
+    verifyURL, ok := captchaVerifyURLs[provider]
+    if !ok {
+        return fmt.Errorf("unknown CAPTCHA_PROVIDER %q", provider)
+    }
+    secret := os.Getenv("CAPTCHA_SECRET")
+    if secret == "" {
+        return fmt.Errorf("CAPTCHA_SECRET is not set")
+    }
+    if token == "" {
+        return fmt.Errorf("missing captcha token")
+    }
+
+    form := url.Values{}
+    form.Set("secret", secret)
+    form.Set("response", token)
+    if remoteIP != "" {
+        form.Set("remoteip", remoteIP)
+    }
+
+    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, "POST", verifyURL, strings.NewReader(form.Encode()))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    var result struct {
+        Success    bool     `json:"success"`
+        ErrorCodes []string `json:"error-codes"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return fmt.Errorf("failed to parse %s response: %v", provider, err)
+    }
+    if !result.Success {
+        return fmt.Errorf("captcha rejected: %s", strings.Join(result.ErrorCodes, ", "))
+    }
+    return nil
+}
@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+)
+
+// follower is an elector that never leads
+type follower struct{}
+
+func (follower) Run(ctx context.Context) {}
+
+func (follower) IsLeader() bool { return false }
+
+func TestFollowersSweepLocalRooms(t *testing.T) {
+    a := New(Config{AllowedOrigins: testOrigins, Leader: follower{}})
+    h := a.Router()
+    for _, code := range []string{"stale", "expired"} {
+        if w := request(h, http.MethodPost, "/room/create", "application/json", "", `{"roomCode":"`+code+`","peerId":"peer-a"}`); w.Code != http.StatusOK {
+            t.Fatalf("create %s: status = %d: %s", code, w.Code, w.Body)
+        }
+    }
+    long := time.Now().Add(-time.Hour).Unix()
+    if room, ok := a.rooms.Get("stale"); ok {
+        room.Lock()
+        room.Peers["peer-a"].LastSeen.Store(long)
+        room.Unlock()
+    }
+    if room, ok := a.rooms.Get("expired"); ok {
+        room.Lock()
+        room.DisconnectPeer("peer-a", long)
+        room.Unlock()
+    }
+
+    if err := a.cleanup(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    if err := a.expireDisconnectedPeers(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    if a.peerGrace <= 0 {
+        if _, ok := a.rooms.Get("stale"); ok {
+            t.Error("stale room kept by a follower")
+        }
+    } else if room, ok := a.rooms.Get("stale"); ok {
+        room.RLock()
+        disconnected := room.Peers["peer-a"].Disconnected()
+        room.RUnlock()
+        if !disconnected {
+            t.Error("stale peer not marked disconnected by a follower")
+        }
+    }
+    if _, ok := a.rooms.Get("expired"); ok {
+        t.Error("room of an expired peer kept by a follower")
+    }
+}
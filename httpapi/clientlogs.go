@@ -0,0 +1,200 @@
+package httpapi
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    clientLogBatchesPerMinute = 20 // per client IP
+    maxClientLogDetailsBytes  = 4096
+    defaultClientLogRetention = 24 * time.Hour
+    defaultMaxClientLogs      = 10000
+)
+
+// ClientLog is an error a frontend reported, such as an ICE failure or a
+// data channel that closed mid-transfer
+type ClientLog struct {
+    ID       int64  `json:"id"`
+    RoomCode string `json:"roomCode,omitempty"`
+    PeerID   string `json:"peerId,omitempty"`
+    Kind     string `json:"kind"`
+    Message  string `json:"message"`
+    // Details is whatever structured context the client attached, like the
+    // ICE candidate pair or the data channel state
+    Details json.RawMessage `json:"details,omitempty"`
+    Browser string          `json:"browser,omitempty"`
+    // OccurredAt is the client's clock, ReceivedAt the server's
+    OccurredAt int64 `json:"occurredAt,omitempty"`
+    ReceivedAt int64 `json:"receivedAt"`
+}
+
+// clientLogBuffer keeps reported client errors for CLIENT_LOG_RETENTION
+// (default 24h), and at most CLIENT_LOG_MAX_ENTRIES (default 10000) of
+// them, oldest first
+type clientLogBuffer struct {
+    mu        sync.RWMutex
+    entries   []ClientLog
+    lastID    int64
+    retention time.Duration
+    max       int
+}
+
+func loadClientLogBuffer() clientLogBuffer {
+    return clientLogBuffer{
+        retention: envDuration("CLIENT_LOG_RETENTION", defaultClientLogRetention),
+        max:       envInt("CLIENT_LOG_MAX_ENTRIES", defaultMaxClientLogs),
+    }
+}
+
+// append stores a batch, dropping entries past retention or the cap
+func (b *clientLogBuffer) append(batch []ClientLog) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    for i := range batch {
+        b.lastID++
+        batch[i].ID = b.lastID
+    }
+    b.entries = append(b.entries, batch...)
+
+    cutoff := time.Now().Add(-b.retention).Unix()
+    drop := 0
+    for drop < len(b.entries) && b.entries[drop].ReceivedAt < cutoff {
+        drop++
+    }
+    if over := len(b.entries) - drop - b.max; over > 0 {
+        drop += over
+    }
+    if drop > 0 {
+        b.entries = append([]ClientLog(nil), b.entries[drop:]...)
+    }
+}
+
+// reportClientLogs accepts a batch of up to 50 frontend errors, each tied to
+// the room and peer of the batch, so failed connections can be debugged
+// without asking users for their console
+func (a *API) reportClientLogs(c *gin.Context) {
+    var req struct {
+        RoomCode string `json:"roomCode"`
+        PeerID   string `json:"peerId"`
+        Browser  string `json:"browser"`
+        Entries  []struct {
+            Kind       string          `json:"kind" binding:"required,oneof=ice datachannel signaling transfer other"`
+            Message    string          `json:"message" binding:"required,max=1000"`
+            Details    json.RawMessage `json:"details"`
+            OccurredAt int64           `json:"occurredAt"`
+        } `json:"entries" binding:"required,min=1,max=50,dive"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, req.RoomCode, req.PeerID)
+
+    if !a.clientLogLimiter.Allow(c.ClientIP()) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+        return
+    }
+
+    browser := normalizeBrowser(req.Browser)
+    if browser == "" {
+        browser = browserFromUserAgent(c.Request.UserAgent())
+    }
+    now := time.Now().Unix()
+    batch := make([]ClientLog, len(req.Entries))
+    for i, e := range req.Entries {
+        if len(e.Details) > maxClientLogDetailsBytes {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "details must be at most " + strconv.Itoa(maxClientLogDetailsBytes) + " bytes"})
+            return
+        }
+        batch[i] = ClientLog{
+            RoomCode:   req.RoomCode,
+            PeerID:     req.PeerID,
+            Kind:       e.Kind,
+            Message:    e.Message,
+            Details:    e.Details,
+            Browser:    browser,
+            OccurredAt: e.OccurredAt,
+            ReceivedAt: now,
+        }
+    }
+    a.clientLogs.append(batch)
+
+    c.JSON(http.StatusAccepted, gin.H{"accepted": len(batch)})
+}
+
+// getClientLogs lists stored client errors, oldest first, filtered by
+// ?room=, ?peer=, ?kind= and ?since=/?until= (unix seconds received), and
+// paged with ?cursor= and ?limit=
+func (a *API) getClientLogs(c *gin.Context) {
+    room, peer, kind := c.Query("room"), c.Query("peer"), c.Query("kind")
+    since, ok := queryUnix(c, "since")
+    if !ok {
+        return
+    }
+    until, ok := queryUnix(c, "until")
+    if !ok {
+        return
+    }
+    var cursor int64
+    if v := c.Query("cursor"); v != "" {
+        var err error
+        if cursor, err = strconv.ParseInt(v, 10, 64); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "cursor must be an integer"})
+            return
+        }
+    }
+    limit, ok := pageLimit(c)
+    if !ok {
+        return
+    }
+
+    a.clientLogs.mu.RLock()
+    entries := make([]ClientLog, 0, min(limit, len(a.clientLogs.entries)))
+    var nextCursor int64
+    for _, e := range a.clientLogs.entries {
+        if e.ID <= cursor ||
+            (room != "" && e.RoomCode != room) ||
+            (peer != "" && e.PeerID != peer) ||
+            (kind != "" && e.Kind != kind) ||
+            (since != 0 && e.ReceivedAt < since) ||
+            (until != 0 && e.ReceivedAt > until) {
+            continue
+        }
+        if len(entries) == limit {
+            nextCursor = entries[len(entries)-1].ID
+            break
+        }
+        entries = append(entries, e)
+    }
+    a.clientLogs.mu.RUnlock()
+
+    resp := gin.H{"entries": entries}
+    if nextCursor != 0 {
+        resp["nextCursor"] = nextCursor
+    }
+    c.JSON(http.StatusOK, resp)
+}
+
+// eraseClientLogs drops the client errors a peer reported, returning how
+// many there were
+func (a *API) eraseClientLogs(peerID string) int {
+    a.clientLogs.mu.Lock()
+    defer a.clientLogs.mu.Unlock()
+    kept := a.clientLogs.entries[:0]
+    for _, e := range a.clientLogs.entries {
+        if e.PeerID != peerID {
+            kept = append(kept, e)
+        }
+    }
+    removed := len(a.clientLogs.entries) - len(kept)
+    clear(a.clientLogs.entries[len(kept):])
+    a.clientLogs.entries = kept
+    return removed
+}
@@ -0,0 +1,186 @@
+package httpapi
+
+import (
+    "bytes"
+    "compress/flate"
+    "compress/gzip"
+    "compress/zlib"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// compressibleTypes are the content types worth compressing; images such as
+// the room QR code PNG are already compressed
+var compressibleTypes = []string{
+    "application/json",
+    "application/problem+json",
+    "application/msgpack",
+    "application/cbor",
+    "application/yaml",
+    "image/svg+xml",
+    "text/",
+}
+
+// compressResponses gzips or deflates responses of at least COMPRESSION_MIN_SIZE
+// bytes (default 1024) at COMPRESSION_LEVEL (1-9, default 6; 0 disables),
+// picking the encoding from the client's Accept-Encoding
+func compressResponses() gin.HandlerFunc {
+    minSize := envInt("COMPRESSION_MIN_SIZE", 1024)
+    level := envInt("COMPRESSION_LEVEL", 6)
+    if level < flate.NoCompression || level > flate.BestCompression {
+        level = flate.DefaultCompression
+    }
+
+    return func(c *gin.Context) {
+        encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+        if level == flate.NoCompression || encoding == "" || c.Request.Method == http.MethodHead {
+            c.Next()
+            return
+        }
+
+        original := c.Writer
+        w := &bufferedWriter{ResponseWriter: original}
+        c.Writer = w
+        defer func() { c.Writer = original }()
+
+        c.Next()
+
+        if w.passthrough {
+            return
+        }
+
+        status := w.Status()
+        body := w.buf.Bytes()
+        header := original.Header()
+        if len(body) >= minSize && header.Get("Content-Encoding") == "" && isCompressible(header.Get("Content-Type")) {
+            if compressed, err := compressBody(body, encoding, level); err == nil {
+                body = compressed
+                header.Set("Content-Encoding", encoding)
+                header.Del("Content-Length")
+                header.Add("Vary", "Accept-Encoding")
+            }
+        }
+
+        original.WriteHeader(status)
+        original.Write(body)
+    }
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// honouring q-values (q=0 means "not acceptable")
+func negotiateEncoding(acceptEncoding string) string {
+    best, bestQ := "", 0.0
+    for _, part := range strings.Split(acceptEncoding, ",") {
+        name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        name = strings.ToLower(strings.TrimSpace(name))
+        q := 1.0
+        if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+                q = parsed
+            }
+        }
+        if name != "gzip" && name != "deflate" || q <= 0 {
+            continue
+        }
+        // Prefer gzip on ties, it is the better-supported of the two
+        if q > bestQ || (q == bestQ && name == "gzip") {
+            best, bestQ = name, q
+        }
+    }
+    return best
+}
+
+func isCompressible(contentType string) bool {
+    for _, t := range compressibleTypes {
+        if strings.HasPrefix(contentType, t) {
+            return true
+        }
+    }
+    return false
+}
+
+func compressBody(body []byte, encoding string, level int) ([]byte, error) {
+    var out bytes.Buffer
+    var zw io.WriteCloser
+    var err error
+    if encoding == "gzip" {
+        zw, err = gzip.NewWriterLevel(&out, level)
+    } else {
+        // HTTP "deflate" is the zlib format (RFC 1950), not raw deflate
+        zw, err = zlib.NewWriterLevel(&out, level)
+    }
+    if err != nil {
+        return nil, err
+    }
+    if _, err := zw.Write(body); err != nil {
+        return nil, err
+    }
+    if err := zw.Close(); err != nil {
+        return nil, err
+    }
+    return out.Bytes(), nil
+}
+
+// bufferedWriter holds the response until the handler finishes so its size
+// is known. A handler that flushes (streaming) switches it to pass-through.
+type bufferedWriter struct {
+    gin.ResponseWriter
+    buf         bytes.Buffer
+    status      int
+    passthrough bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+    if w.passthrough {
+        w.ResponseWriter.WriteHeader(code)
+        return
+    }
+    w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+    if w.passthrough {
+        w.ResponseWriter.WriteHeaderNow()
+    }
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+    if w.passthrough {
+        return w.ResponseWriter.Write(data)
+    }
+    return w.buf.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+    if w.passthrough {
+        return w.ResponseWriter.WriteString(s)
+    }
+    return w.buf.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+    if w.passthrough || w.status == 0 {
+        return w.ResponseWriter.Status()
+    }
+    return w.status
+}
+
+func (w *bufferedWriter) Written() bool {
+    return w.passthrough || w.status != 0 || w.buf.Len() > 0
+}
+
+func (w *bufferedWriter) Flush() {
+    if !w.passthrough {
+        w.passthrough = true
+        if w.status != 0 {
+            w.ResponseWriter.WriteHeader(w.status)
+        }
+        w.ResponseWriter.Write(w.buf.Bytes())
+        w.buf.Reset()
+    }
+    w.ResponseWriter.Flush()
+}
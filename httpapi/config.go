@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+    "log"
+    "os"
+    "strconv"
+    "time"
+)
+
+// envDuration reads a time.Duration (e.g. "30m", "720h") from the environment
+func envDuration(name string, def time.Duration) time.Duration {
+    v := os.Getenv(name)
+    if v == "" {
+        return def
+    }
+    d, err := time.ParseDuration(v)
+    if err != nil {
+        log.Printf("⚠️  Invalid %s %q, using default %s", name, v, def)
+        return def
+    }
+    return d
+}
+
+// envInt reads an integer from the environment
+func envInt(name string, def int) int {
+    v := os.Getenv(name)
+    if v == "" {
+        return def
+    }
+    n, err := strconv.Atoi(v)
+    if err != nil {
+        log.Printf("⚠️  Invalid %s %q, using default %d", name, v, def)
+        return def
+    }
+    return n
+}
+
+// envFloat reads a floating-point number from the environment
+func envFloat(name string, def float64) float64 {
+    v := os.Getenv(name)
+    if v == "" {
+        return def
+    }
+    f, err := strconv.ParseFloat(v, 64)
+    if err != nil {
+        log.Printf("⚠️  Invalid %s %q, using default %g", name, v, def)
+        return def
+    }
+    return f
+}
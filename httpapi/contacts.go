@@ -0,0 +1,464 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    // maxContactsPerUser bounds one user's contacts, pending requests
+    // included
+    maxContactsPerUser = 500
+    contactRoomPrefix  = "share-"
+    // contactShareTTL is how long a contact has to accept a share
+    contactShareTTL = 15 * time.Minute
+)
+
+// Contact states, from the user's side
+const (
+    contactPendingOut = "requested"
+    contactPendingIn  = "incoming"
+    contactAccepted   = "accepted"
+)
+
+// contact is one entry in a user's contact list
+type contact struct {
+    UserID string `json:"userId"`
+    Name   string `json:"name,omitempty"`
+    Status string `json:"status"`
+    Since  int64  `json:"since"`
+}
+
+// contactShare is a room opened for a contact, waiting for one of their
+// devices to accept it
+type contactShare struct {
+    RoomCode  string
+    From      string
+    FromPeer  string
+    To        string
+    ExpiresAt time.Time
+}
+
+// contactBook holds each user's contacts, mirrored on both sides, and the
+// shares waiting for an answer
+type contactBook struct {
+    mu     sync.Mutex
+    users  map[string]map[string]*contact
+    shares map[string]*contactShare
+}
+
+// contactStatus returns how other stands in userID's contact list
+func (a *API) contactStatus(userID, other string) string {
+    a.contacts.mu.Lock()
+    defer a.contacts.mu.Unlock()
+    if entry, ok := a.contacts.users[userID][other]; ok {
+        return entry.Status
+    }
+    return ""
+}
+
+// setContact records other in userID's contact list; the caller holds
+// a.contacts.mu
+func (a *API) setContact(userID, other, status string, since int64) {
+    list := a.contacts.users[userID]
+    if list == nil {
+        list = make(map[string]*contact)
+        a.contacts.users[userID] = list
+    }
+    list[other] = &contact{UserID: other, Status: status, Since: since}
+}
+
+// notifyUser queues n for a user's online devices, or for the user and all
+// their devices when none is online, so it is seen on next sign-in. It
+// returns the peers notified.
+func (a *API) notifyUser(userID string, n notifications.Notification) []string {
+    devices := a.userDevices(userID)
+    var targets []string
+    if len(devices) > 0 {
+        online := a.onlinePeers()
+        for _, id := range devices {
+            if online[id] {
+                targets = append(targets, id)
+            }
+        }
+    }
+    if len(targets) == 0 {
+        targets = append([]string{userID}, devices...)
+    }
+    for _, id := range targets {
+        a.queueNotification(id, n)
+    }
+    return targets
+}
+
+// getContacts lists the user's contacts and pending requests, newest first
+func (a *API) getContacts(c *gin.Context) {
+    userID, _, ok := a.requireUser(c)
+    if !ok {
+        return
+    }
+
+    a.contacts.mu.Lock()
+    list := make([]contact, 0, len(a.contacts.users[userID]))
+    for _, entry := range a.contacts.users[userID] {
+        list = append(list, *entry)
+    }
+    a.contacts.mu.Unlock()
+
+    for i := range list {
+        if profile, ok := a.userProfileFor(list[i].UserID); ok {
+            list[i].Name = profile.Name
+        }
+    }
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].Since != list[j].Since {
+            return list[i].Since > list[j].Since
+        }
+        return list[i].UserID < list[j].UserID
+    })
+
+    c.JSON(http.StatusOK, gin.H{"contacts": list})
+}
+
+// requestContact asks another user to become a contact. If they had
+// already asked, the two become contacts at once.
+func (a *API) requestContact(c *gin.Context) {
+    userID, _, ok := a.requireUser(c)
+    if !ok {
+        return
+    }
+
+    var req struct {
+        UserID string `json:"userId" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    if req.UserID == userID {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A user cannot add themselves"})
+        return
+    }
+    if _, known := a.userProfileFor(req.UserID); !known {
+        c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+        return
+    }
+    if a.isBlocked(req.UserID, userID) {
+        // Like blocked messages, refused without telling the requester
+        c.JSON(http.StatusOK, contact{UserID: req.UserID, Status: contactPendingOut, Since: time.Now().Unix()})
+        return
+    }
+
+    now := time.Now().Unix()
+    a.contacts.mu.Lock()
+    existing := a.contacts.users[userID][req.UserID]
+    switch {
+    case existing != nil && existing.Status != contactPendingIn:
+        entry := *existing
+        a.contacts.mu.Unlock()
+        c.JSON(http.StatusOK, entry)
+        return
+    case existing == nil && (len(a.contacts.users[userID]) >= maxContactsPerUser || len(a.contacts.users[req.UserID]) >= maxContactsPerUser):
+        a.contacts.mu.Unlock()
+        c.JSON(http.StatusConflict, gin.H{"error": "At most " + strconv.Itoa(maxContactsPerUser) + " contacts are allowed"})
+        return
+    }
+    status, eventType := contactPendingOut, "contact_request"
+    if existing != nil {
+        status, eventType = contactAccepted, "contact_accepted"
+        a.setContact(userID, req.UserID, contactAccepted, now)
+        a.setContact(req.UserID, userID, contactAccepted, now)
+    } else {
+        a.setContact(userID, req.UserID, contactPendingOut, now)
+        a.setContact(req.UserID, userID, contactPendingIn, now)
+    }
+    a.contacts.mu.Unlock()
+
+    a.notifyUser(req.UserID, notifications.Notification{
+        Type:      eventType,
+        PeerID:    userID,
+        Timestamp: now,
+    })
+    log.Printf("🤝 %s → %s: %s", userID, req.UserID, eventType)
+
+    c.JSON(http.StatusOK, contact{UserID: req.UserID, Status: status, Since: now})
+}
+
+// acceptContact accepts a pending request from another user
+func (a *API) acceptContact(c *gin.Context) {
+    userID, _, ok := a.requireUser(c)
+    if !ok {
+        return
+    }
+    other := c.Param("userId")
+
+    now := time.Now().Unix()
+    a.contacts.mu.Lock()
+    existing := a.contacts.users[userID][other]
+    if existing == nil || existing.Status == contactPendingOut {
+        a.contacts.mu.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "No contact request from that user"})
+        return
+    }
+    accepted := existing.Status == contactAccepted
+    if !accepted {
+        a.setContact(userID, other, contactAccepted, now)
+        a.setContact(other, userID, contactAccepted, now)
+    }
+    entry := *a.contacts.users[userID][other]
+    a.contacts.mu.Unlock()
+
+    if !accepted {
+        a.notifyUser(other, notifications.Notification{
+            Type:      "contact_accepted",
+            PeerID:    userID,
+            Timestamp: now,
+        })
+        log.Printf("🤝 %s accepted %s", userID, other)
+    }
+    c.JSON(http.StatusOK, entry)
+}
+
+// removeContact removes a contact, or declines or withdraws a request, on
+// both sides. The other user is not told.
+func (a *API) removeContact(c *gin.Context) {
+    userID, _, ok := a.requireUser(c)
+    if !ok {
+        return
+    }
+    other := c.Param("userId")
+
+    a.contacts.mu.Lock()
+    _, exists := a.contacts.users[userID][other]
+    if exists {
+        a.dropContact(userID, other)
+        a.dropContact(other, userID)
+    }
+    a.contacts.mu.Unlock()
+
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// dropContact removes other from userID's list; the caller holds
+// a.contacts.mu
+func (a *API) dropContact(userID, other string) {
+    delete(a.contacts.users[userID], other)
+    if len(a.contacts.users[userID]) == 0 {
+        delete(a.contacts.users, userID)
+    }
+}
+
+// shareWithContact opens a locked room hosted by the caller and invites an
+// accepted contact with a contact_share event on their online devices. The
+// contact answers with POST /me/shares/:roomCode/accept or decline, and the
+// caller is told which with contact_share_accepted or
+// contact_share_declined.
+func (a *API) shareWithContact(c *gin.Context) {
+    userID, peerID, ok := a.requireUser(c)
+    if !ok {
+        return
+    }
+    other := c.Param("userId")
+
+    var req struct {
+        rooms.PeerProfile
+        Message string `json:"message"`
+    }
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            invalidRequest(c, err)
+            return
+        }
+    }
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if len(req.Message) > maxInviteMessageLength {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "message must be at most " + strconv.Itoa(maxInviteMessageLength) + " characters"})
+        return
+    }
+    if a.contactStatus(userID, other) != contactAccepted {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Files can only be sent to accepted contacts"})
+        return
+    }
+
+    roomCode, room, ok := a.createPrivateRoom(c, contactRoomPrefix, peerID)
+    if !ok {
+        return
+    }
+    room.Lock()
+    role, permissions := admitPeer(room, peerID, req.PeerProfile)
+    room.Unlock()
+
+    expiresAt := time.Now().Add(contactShareTTL)
+    a.contacts.mu.Lock()
+    a.contacts.shares[roomCode] = &contactShare{
+        RoomCode:  roomCode,
+        From:      userID,
+        FromPeer:  peerID,
+        To:        other,
+        ExpiresAt: expiresAt,
+    }
+    a.contacts.mu.Unlock()
+
+    a.recordRecentRoom(peerID, roomCode, role)
+    a.recordAudit(roomCode, "contact_share", peerID, other, nil)
+    notified := a.notifyUser(other, notifications.Notification{
+        Type:      "contact_share",
+        PeerID:    userID,
+        Timestamp: time.Now().Unix(),
+        Payload: notifications.Payload(gin.H{
+            "roomCode":  roomCode,
+            "name":      req.DisplayName,
+            "message":   req.Message,
+            "expiresAt": expiresAt.Unix(),
+        }),
+    })
+    log.Printf("📨 %s shared Room: %s with %s (%d devices notified)", userID, roomCode, other, len(notified))
+
+    c.JSON(http.StatusOK, gin.H{
+        "roomCode":    roomCode,
+        "contact":     other,
+        "notified":    len(notified),
+        "expiresAt":   expiresAt.Unix(),
+        "role":        role,
+        "permissions": permissions,
+        "resumeToken": a.issueResumeToken(roomCode, peerID),
+    })
+}
+
+// answerShare accepts or declines a share from a contact. Accepting admits
+// the answering device to the room and returns the same response as
+// joining one; either way the sender is told.
+func (a *API) answerShare(accept bool) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        userID, peerID, ok := a.requireUser(c)
+        if !ok {
+            return
+        }
+        roomCode := c.Param("roomCode")
+
+        var req struct {
+            rooms.PeerProfile
+        }
+        if c.Request.ContentLength != 0 {
+            if err := c.ShouldBindJSON(&req); err != nil {
+                invalidRequest(c, err)
+                return
+            }
+        }
+        if err := req.PeerProfile.Validate(); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+
+        a.contacts.mu.Lock()
+        share, ok := a.contacts.shares[roomCode]
+        if ok && share.To == userID {
+            delete(a.contacts.shares, roomCode)
+        }
+        a.contacts.mu.Unlock()
+        if !ok || share.To != userID || time.Now().After(share.ExpiresAt) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Share not found or expired"})
+            return
+        }
+
+        if !accept {
+            a.queueNotification(share.FromPeer, notifications.Notification{
+                Type:      "contact_share_declined",
+                PeerID:    userID,
+                Timestamp: time.Now().Unix(),
+                Payload:   notifications.Payload(gin.H{"roomCode": roomCode}),
+            })
+            log.Printf("📨 %s declined Room: %s", userID, roomCode)
+            c.JSON(http.StatusOK, gin.H{"roomCode": roomCode, "accepted": false})
+            return
+        }
+
+        room, exists := a.rooms.Get(roomCode)
+        if !exists {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+            return
+        }
+        if status, errMsg := a.checkJoin(c, roomCode, peerID, req.PeerProfile); errMsg != "" {
+            c.JSON(status, gin.H{"error": errMsg})
+            return
+        }
+        room.Lock()
+        existingPeers := room.ConnectedPeers(peerID)
+        role, permissions := admitPeer(room, peerID, req.PeerProfile)
+        roomSize := len(room.Peers)
+        joined := roomEvent(room, peerID, notifications.Notification{
+            Type:      "peer_joined",
+            PeerID:    peerID,
+            Timestamp: time.Now().Unix(),
+        })
+        room.Unlock()
+
+        a.dispatcher.Fanout(existingPeers, joined)
+        a.queueNotification(share.FromPeer, notifications.Notification{
+            Type:      "contact_share_accepted",
+            PeerID:    peerID,
+            Timestamp: time.Now().Unix(),
+            Payload:   notifications.Payload(gin.H{"roomCode": roomCode, "userId": userID}),
+        })
+        log.Printf("✅ Peer joined: %s → Room: %s (contact share)", peerID, roomCode)
+        a.recordRecentRoom(peerID, roomCode, role)
+        a.recordAudit(roomCode, "peer_joined", peerID, "", gin.H{"sharedBy": share.From})
+
+        c.JSON(http.StatusOK, gin.H{
+            "roomCode":    roomCode,
+            "accepted":    true,
+            "peers":       existingPeers,
+            "roomSize":    roomSize,
+            "role":        role,
+            "permissions": permissions,
+            "roomSeq":     joined.RoomSeq,
+            "resumeToken": a.issueResumeToken(roomCode, peerID),
+        })
+    }
+}
+
+// pruneContactShares drops shares nobody answered
+func (a *API) pruneContactShares() {
+    now := time.Now()
+    a.contacts.mu.Lock()
+    defer a.contacts.mu.Unlock()
+    for roomCode, share := range a.contacts.shares {
+        if now.After(share.ExpiresAt) {
+            delete(a.contacts.shares, roomCode)
+        }
+    }
+}
+
+// eraseContacts removes peerID from every contact list, along with the
+// shares it sent or was sent, and returns how many contacts it had
+func (a *API) eraseContacts(peerID string) int {
+    a.contacts.mu.Lock()
+    defer a.contacts.mu.Unlock()
+    n := len(a.contacts.users[peerID])
+    for other := range a.contacts.users[peerID] {
+        a.dropContact(other, peerID)
+    }
+    delete(a.contacts.users, peerID)
+    for roomCode, share := range a.contacts.shares {
+        if share.From == peerID || share.To == peerID {
+            delete(a.contacts.shares, roomCode)
+        }
+    }
+    return n
+}
@@ -0,0 +1,375 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    // maxDevicesPerUser bounds one user's device registry
+    maxDevicesPerUser  = 20
+    maxDeviceNameLen   = 64
+    devicePrefix       = "device-"
+    deviceIDLength     = 12
+    deviceRoomPrefix   = "mine-"
+    deviceRoomCodeSize = 10
+)
+
+// deviceKinds are the device kinds a client may register
+var deviceKinds = map[string]bool{"laptop": true, "desktop": true, "phone": true, "tablet": true, "other": true}
+
+// device is one of a signed-in user's registered devices. Its ID is a peer
+// ID of its own, so each device keeps the same identity across sessions.
+type device struct {
+    DeviceID     string `json:"deviceId"`
+    Name         string `json:"name"`
+    Kind         string `json:"kind"`
+    RegisteredAt int64  `json:"registeredAt"`
+    Online       bool   `json:"online"`
+}
+
+// deviceRegistry holds each user's devices and which user owns each device
+type deviceRegistry struct {
+    mu     sync.Mutex
+    users  map[string]map[string]*device
+    owners map[string]string
+}
+
+// ownerOf returns the user a peer acts for: the user itself, or the owner
+// when the peer is one of the user's devices. Anonymous peers have none.
+func (a *API) ownerOf(peerID string) (string, bool) {
+    if strings.HasPrefix(peerID, userIDPrefix) {
+        return peerID, true
+    }
+    a.devices.mu.Lock()
+    defer a.devices.mu.Unlock()
+    userID, ok := a.devices.owners[peerID]
+    return userID, ok
+}
+
+// requireUser returns the bearer-token peer and the user it acts for,
+// answering 401 or 403 unless that is a signed-in user or one of their
+// devices
+func (a *API) requireUser(c *gin.Context) (userID, peerID string, ok bool) {
+    peerID, ok = authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return "", "", false
+    }
+    tagRequest(c, "", peerID)
+    userID, ok = a.ownerOf(peerID)
+    if !ok {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Sign in to use devices"})
+        return "", "", false
+    }
+    return userID, peerID, true
+}
+
+// getDevices lists the user's devices, oldest first, with whether each is
+// currently in a room
+func (a *API) getDevices(c *gin.Context) {
+    userID, _, ok := a.requireUser(c)
+    if !ok {
+        return
+    }
+
+    a.devices.mu.Lock()
+    list := make([]device, 0, len(a.devices.users[userID]))
+    for _, d := range a.devices.users[userID] {
+        list = append(list, *d)
+    }
+    a.devices.mu.Unlock()
+
+    if len(list) > 0 {
+        online := a.onlinePeers()
+        for i := range list {
+            list[i].Online = online[list[i].DeviceID]
+        }
+    }
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].RegisteredAt != list[j].RegisteredAt {
+            return list[i].RegisteredAt < list[j].RegisteredAt
+        }
+        return list[i].DeviceID < list[j].DeviceID
+    })
+
+    c.JSON(http.StatusOK, gin.H{"userId": userID, "devices": list})
+}
+
+// onlinePeers returns the peers currently online in any room
+func (a *API) onlinePeers() map[string]bool {
+    online := make(map[string]bool)
+    a.rooms.Range(func(_ string, room *rooms.Room) bool {
+        for _, peer := range room.Snapshot().Peers {
+            if peer.Presence == rooms.PresenceOnline {
+                online[peer.PeerID] = true
+            }
+        }
+        return true
+    })
+    return online
+}
+
+// registerDevice adds a named device to the user's registry and returns its
+// peer ID and token. The device uses these instead of an anonymous identity.
+func (a *API) registerDevice(c *gin.Context) {
+    userID, _, ok := a.requireUser(c)
+    if !ok {
+        return
+    }
+
+    var req struct {
+        Name string `json:"name" binding:"required"`
+        Kind string `json:"kind"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Name == "" || len(req.Name) > maxDeviceNameLen {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1-" + strconv.Itoa(maxDeviceNameLen) + " characters"})
+        return
+    }
+    if req.Kind == "" {
+        req.Kind = "other"
+    }
+    if !deviceKinds[req.Kind] {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be laptop, desktop, phone, tablet or other"})
+        return
+    }
+
+    slug, err := randomCode(shortLinkAlphabet, deviceIDLength)
+    if err != nil {
+        log.Printf("❌ Failed to generate device ID: %v", err)
+        c.Error(err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+        return
+    }
+    d := &device{
+        DeviceID:     devicePrefix + slug,
+        Name:         req.Name,
+        Kind:         req.Kind,
+        RegisteredAt: time.Now().Unix(),
+    }
+
+    a.devices.mu.Lock()
+    list := a.devices.users[userID]
+    for _, existing := range list {
+        if strings.EqualFold(existing.Name, req.Name) {
+            a.devices.mu.Unlock()
+            c.JSON(http.StatusConflict, gin.H{"error": "A device with this name is already registered"})
+            return
+        }
+    }
+    if len(list) >= maxDevicesPerUser {
+        a.devices.mu.Unlock()
+        c.JSON(http.StatusConflict, gin.H{"error": "At most " + strconv.Itoa(maxDevicesPerUser) + " devices can be registered"})
+        return
+    }
+    if list == nil {
+        list = make(map[string]*device)
+        a.devices.users[userID] = list
+    }
+    list[d.DeviceID] = d
+    a.devices.owners[d.DeviceID] = userID
+    a.devices.mu.Unlock()
+
+    log.Printf("💻 %s registered device %s (%s)", userID, d.DeviceID, d.Kind)
+
+    c.JSON(http.StatusCreated, gin.H{
+        "device": d,
+        "token":  issuePeerToken(d.DeviceID),
+    })
+}
+
+// removeDevice unregisters one of the user's devices. Its token stays
+// valid as an anonymous peer's but no longer acts for the user.
+func (a *API) removeDevice(c *gin.Context) {
+    userID, _, ok := a.requireUser(c)
+    if !ok {
+        return
+    }
+    deviceID := c.Param("deviceId")
+
+    a.devices.mu.Lock()
+    _, exists := a.devices.users[userID][deviceID]
+    if exists {
+        delete(a.devices.users[userID], deviceID)
+        delete(a.devices.owners, deviceID)
+        if len(a.devices.users[userID]) == 0 {
+            delete(a.devices.users, userID)
+        }
+    }
+    a.devices.mu.Unlock()
+
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// sendToDevice opens a room between the caller and another of the user's
+// devices, as pairing does but without a code: a locked room holding just
+// the two is created, the target device is told with a device_room event
+// and the caller gets the same response as joining a room.
+func (a *API) sendToDevice(c *gin.Context) {
+    userID, peerID, ok := a.requireUser(c)
+    if !ok {
+        return
+    }
+    target := c.Param("deviceId")
+
+    var req struct {
+        rooms.PeerProfile
+    }
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            invalidRequest(c, err)
+            return
+        }
+    }
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    a.devices.mu.Lock()
+    d, exists := a.devices.users[userID][target]
+    var targetName string
+    if exists {
+        targetName = d.Name
+    }
+    a.devices.mu.Unlock()
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+        return
+    }
+    if target == peerID {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A device cannot send to itself"})
+        return
+    }
+
+    roomCode, room, ok := a.createPrivateRoom(c, deviceRoomPrefix, peerID)
+    if !ok {
+        return
+    }
+
+    room.Lock()
+    role, permissions := admitPeer(room, peerID, req.PeerProfile)
+    targetRole, _ := admitPeer(room, target, rooms.PeerProfile{DisplayName: targetName})
+    peers := room.ConnectedPeers(peerID)
+    roomSize := len(room.Peers)
+    room.Unlock()
+
+    a.recordRecentRoom(peerID, roomCode, role)
+    a.recordRecentRoom(target, roomCode, targetRole)
+    log.Printf("💻 %s opened Room: %s from %s to %s", userID, roomCode, peerID, target)
+    a.recordAudit(roomCode, "device_room", peerID, target, nil)
+
+    a.queueNotification(target, notifications.Notification{
+        Type:      "device_room",
+        PeerID:    peerID,
+        Timestamp: time.Now().Unix(),
+        Payload: notifications.Payload(gin.H{
+            "roomCode":    roomCode,
+            "resumeToken": a.issueResumeToken(roomCode, target),
+        }),
+    })
+
+    c.JSON(http.StatusOK, gin.H{
+        "roomCode":    roomCode,
+        "device":      target,
+        "peers":       peers,
+        "roomSize":    roomSize,
+        "role":        role,
+        "permissions": permissions,
+        "resumeToken": a.issueResumeToken(roomCode, peerID),
+    })
+}
+
+// createPrivateRoom creates a locked room with a random code under prefix,
+// hosted by hostID, for peers the server admits itself. It answers the
+// request and returns false when the room can't be created.
+func (a *API) createPrivateRoom(c *gin.Context, prefix, hostID string) (string, *rooms.Room, bool) {
+    slug, err := randomCode(shortLinkAlphabet, deviceRoomCodeSize)
+    if err != nil {
+        log.Printf("❌ Failed to generate room code: %v", err)
+        c.Error(err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room"})
+        return "", nil, false
+    }
+    roomCode := namespacedCode(roomNamespace(c.GetHeader("Origin")), prefix+slug)
+    ip := c.ClientIP()
+
+    if !a.checkRoomCreate(c, roomCode, hostID, rooms.ModeOpen) {
+        return "", nil, false
+    }
+
+    room, created, err := a.rooms.Create(roomCode, func(census rooms.Census) (*rooms.Room, error) {
+        if a.draining() {
+            return nil, drainRefusal
+        }
+        if qerr := a.checkRoomQuotas(census, ip); qerr != nil {
+            return nil, qerr
+        }
+        room := rooms.New(hostID, rooms.ModeOpen, ip, time.Now().Unix())
+        room.Tenant = tenantOf(c)
+        room.Locked = true
+        return room, nil
+    })
+    if err != nil {
+        qerr := err.(*quotaError)
+        if qerr.retry {
+            c.Header("Retry-After", strconv.Itoa(int(a.quotas.retryAfter.Seconds())))
+        }
+        c.JSON(qerr.status, gin.H{"error": qerr.message})
+        return "", nil, false
+    }
+    if !created {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room"})
+        return "", nil, false
+    }
+    return roomCode, room, true
+}
+
+// userDevices returns the IDs of userID's registered devices
+func (a *API) userDevices(userID string) []string {
+    a.devices.mu.Lock()
+    defer a.devices.mu.Unlock()
+    ids := make([]string, 0, len(a.devices.users[userID]))
+    for id := range a.devices.users[userID] {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+    return ids
+}
+
+// eraseDevices forgets the devices of the user with ID peerID, or the
+// registration of the device with that ID, and returns how many it removed
+func (a *API) eraseDevices(peerID string) int {
+    a.devices.mu.Lock()
+    defer a.devices.mu.Unlock()
+    if userID, ok := a.devices.owners[peerID]; ok {
+        delete(a.devices.users[userID], peerID)
+        delete(a.devices.owners, peerID)
+        return 1
+    }
+    n := len(a.devices.users[peerID])
+    for id := range a.devices.users[peerID] {
+        delete(a.devices.owners, id)
+    }
+    delete(a.devices.users, peerID)
+    return n
+}
@@ -0,0 +1,184 @@
+package httpapi
+
+import (
+    "bytes"
+    "context"
+    "io"
+    "log"
+    "net"
+    "net/url"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    // DemoRoomCode is the room seeded in dev mode
+    DemoRoomCode = "DEMO"
+
+    demoKeepaliveInterval = time.Minute
+    maxLoggedBodyBytes    = 4096
+)
+
+// demoPeers are the fake peers seeded into the demo room; the first is host
+var demoPeers = []rooms.PeerProfile{
+    {DisplayName: "Demo Host", Platform: "web", ClientVersion: "dev"},
+    {DisplayName: "Alice", Platform: "ios", ClientVersion: "dev"},
+    {DisplayName: "Bob", Platform: "android", ClientVersion: "dev"},
+}
+
+var demoPeerIDs = []string{"demo-host", "demo-alice", "demo-bob"}
+
+// enableDevMode relaxes the API for local frontend development: rate limits
+// and per-IP room quotas are switched off and the demo room is seeded
+func (a *API) enableDevMode() {
+    log.Println("🧪 Dev mode: localhost origins allowed, rate limits off, verbose request logging")
+
+    a.messageLimiter = nil
+    a.inviterLimiter = nil
+    a.recipientLimiter = nil
+    a.reporterLimiter = nil
+    a.reportIPLimiter = nil
+    a.natEchoLimiter = nil
+    a.telemetryLimiter = nil
+    a.clientLogLimiter = nil
+    a.pairStartLimiter = nil
+    a.pairClaimLimiter = nil
+    a.nearbyLimiter = nil
+    a.shareLimiter = nil
+    a.claimLimiter = nil
+    a.anomalyLimiter = nil
+    a.quotas.perIPPerHour = 0
+    a.quotas.perIPConcurrent = 0
+    a.relayQuotas.peerBytes = 0
+    a.relayQuotas.roomBytes = 0
+    a.relayQuotas.rate = 0
+    a.wtLimits.perIP = 0
+    a.wtLimits.rate = 0
+
+    a.seedDemoRoom()
+}
+
+// seedDemoRoom creates the demo room with its fake peers and a file offer
+func (a *API) seedDemoRoom() {
+    now := time.Now().Unix()
+    _, created, _ := a.rooms.Create(DemoRoomCode, func(rooms.Census) (*rooms.Room, error) {
+        room := rooms.New(demoPeerIDs[0], rooms.ModeOpen, "127.0.0.1", now)
+        for i, peerID := range demoPeerIDs {
+            role, permissions := room.DefaultAccess(peerID)
+            room.Peers[peerID] = &rooms.PeerMetadata{
+                PeerID:      peerID,
+                JoinedAt:    now,
+                LastSeen:    rooms.NewClock(now),
+                Presence:    rooms.PresenceOnline,
+                PeerProfile: demoPeers[i],
+                Role:        role,
+                Permissions: permissions,
+            }
+            room.TouchPeer(peerID)
+        }
+        room.Files["demo-file"] = &rooms.FileOffer{
+            FileID:    "demo-file",
+            PeerID:    demoPeerIDs[1],
+            Name:      "welcome.txt",
+            Size:      1024,
+            MimeType:  "text/plain",
+            OfferedAt: now,
+        }
+        room.Touch()
+        return room, nil
+    })
+    if created {
+        log.Printf("🧪 Seeded demo room %s with %d fake peers", DemoRoomCode, len(demoPeerIDs))
+        a.recordAudit(DemoRoomCode, "room_created", demoPeerIDs[0], "", gin.H{"mode": rooms.ModeOpen, "seeded": true})
+    }
+}
+
+// keepDemoPeersAlive refreshes the fake peers so the stale-peer sweeper
+// never removes them
+func (a *API) keepDemoPeersAlive(ctx context.Context) error {
+    now := time.Now().Unix()
+    room, ok := a.rooms.Get(DemoRoomCode)
+    if !ok {
+        return nil
+    }
+    snap := room.Snapshot()
+    for _, peerID := range demoPeerIDs {
+        if peer := snap.Peer(peerID); peer != nil {
+            peer.LastSeen.Store(now)
+        }
+    }
+    return nil
+}
+
+// isLocalOrigin reports whether origin is a page served from this machine,
+// on any port
+func isLocalOrigin(origin string) bool {
+    u, err := url.Parse(origin)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+        return false
+    }
+    host := u.Hostname()
+    if host == "localhost" {
+        return true
+    }
+    ip := net.ParseIP(host)
+    return ip != nil && ip.IsLoopback()
+}
+
+// bodyLogWriter copies what the handler writes so it can be logged
+type bodyLogWriter struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *bodyLogWriter) Write(data []byte) (int, error) {
+    w.capture(data)
+    return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+    w.capture([]byte(s))
+    return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodyLogWriter) capture(data []byte) {
+    if room := maxLoggedBodyBytes - w.body.Len(); room > 0 {
+        if len(data) > room {
+            data = data[:room]
+        }
+        w.body.Write(data)
+    }
+}
+
+// logBodies logs every request and response body, truncated to
+// maxLoggedBodyBytes. Streaming responses are logged when they end.
+func logBodies() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        var reqBody []byte
+        if c.Request.Body != nil {
+            reqBody, _ = io.ReadAll(c.Request.Body)
+            c.Request.Body = io.NopCloser(bytes.NewReader(reqBody))
+        }
+
+        w := &bodyLogWriter{ResponseWriter: c.Writer}
+        c.Writer = w
+
+        c.Next()
+
+        log.Printf("🧪 %s %s\n  → %s\n  ← %d %s", c.Request.Method, c.Request.URL.RequestURI(),
+            truncateBody(reqBody), w.Status(), truncateBody(w.body.Bytes()))
+    }
+}
+
+func truncateBody(body []byte) string {
+    if len(body) == 0 {
+        return "(empty)"
+    }
+    if len(body) > maxLoggedBodyBytes {
+        return string(body[:maxLoggedBodyBytes]) + "…"
+    }
+    return string(body)
+}
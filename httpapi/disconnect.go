@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+    "context"
+    "log"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// disconnectSweepInterval is how often peers past their grace period are
+// removed, so expiry lags the configured window by at most this much
+const disconnectSweepInterval = 10 * time.Second
+
+// disconnectPeer keeps a leaving peer in its room as disconnected for the
+// PEER_GRACE_PERIOD, so a page refresh doesn't lose its files and role
+func (a *API) disconnectPeer(roomCode, peerID string) {
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        return
+    }
+
+    room.Lock()
+    disconnected := room.DisconnectPeer(peerID, time.Now().Unix())
+    room.Unlock()
+    if !disconnected {
+        return
+    }
+
+    log.Printf("🔌 Peer disconnected: %s from Room: %s (grace %s)", peerID, roomCode, a.peerGrace)
+    a.recordAudit(roomCode, "peer_disconnected", peerID, "", nil)
+}
+
+// reconnected tells the rest of the room that a peer came back within its
+// grace period
+func (a *API) reconnected(roomCode string, room *rooms.Room, peerID string) {
+    log.Printf("🔁 Peer reconnected: %s → Room: %s", peerID, roomCode)
+    a.recordAudit(roomCode, "peer_reconnected", peerID, "", nil)
+    a.notifyRoom(room, peerID, notifications.Notification{
+        Type:      "peer_reconnected",
+        PeerID:    peerID,
+        Timestamp: time.Now().Unix(),
+    })
+}
+
+// expireDisconnectedPeers removes peers whose grace period has run out, and
+// rooms left empty
+func (a *API) expireDisconnectedPeers(ctx context.Context) error {
+    now := time.Now().Unix()
+    cutoff := now - int64(a.peerGrace.Seconds())
+    a.rooms.Sweep(func(roomCode string, room *rooms.Room) bool {
+        room.Lock()
+        defer room.Unlock()
+        for peerID, peer := range room.Peers {
+            if peer.Disconnected() && peer.DisconnectedAt <= cutoff {
+                log.Printf("👋 Peer left: %s from Room: %s (grace period over)", peerID, roomCode)
+                room.RemovePeer(peerID)
+                a.recordAudit(roomCode, "peer_left", peerID, "", gin.H{"reason": "grace_expired"})
+            }
+        }
+        if room.Abandoned(now) {
+            log.Printf("🗑️  Empty room deleted: %s", roomCode)
+            a.recordAudit(roomCode, "room_deleted", "", "", gin.H{"reason": "empty"})
+            return true
+        }
+        return false
+    })
+    return nil
+}
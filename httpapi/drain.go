@@ -0,0 +1,173 @@
+package httpapi
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+const defaultDrainMessage = "This server is restarting for maintenance; you may need to reconnect shortly."
+
+// drainState tracks a drain started with POST /admin/drain. While draining,
+// new rooms are refused and readiness fails so load balancers route new
+// clients elsewhere; existing rooms keep working until the instance stops.
+type drainState struct {
+    mu        sync.Mutex
+    active    bool
+    startedAt int64
+    message   string
+    // persistedAt is when state was last flushed to the store for the drain
+    persistedAt int64
+    persistErr  string
+}
+
+func (a *API) draining() bool {
+    a.drain.mu.Lock()
+    defer a.drain.mu.Unlock()
+    return a.drain.active
+}
+
+// startDrain puts the instance into drain mode, notifies every connected
+// peer of the maintenance once, and with ?persist=true flushes state to the
+// store so a replacement instance can restore it
+func (a *API) startDrain(c *gin.Context) {
+    persist := c.Query("persist") == "true"
+    if persist && a.storage == nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "persist requires a STORE_BACKEND"})
+        return
+    }
+
+    a.drain.mu.Lock()
+    started := !a.drain.active
+    if started {
+        a.drain.active = true
+        a.drain.startedAt = time.Now().Unix()
+        a.drain.message = c.DefaultQuery("message", defaultDrainMessage)
+    }
+    message := a.drain.message
+    a.drain.mu.Unlock()
+
+    if started {
+        log.Printf("🚧 Draining: refusing new rooms")
+        a.recordAudit("", "drain_started", "admin", "", gin.H{"message": message})
+        a.notifyMaintenance(message)
+    }
+
+    if persist {
+        ctx, cancel := context.WithTimeout(c.Request.Context(), persistTimeout)
+        err := a.flushState(ctx)
+        cancel()
+
+        a.drain.mu.Lock()
+        if err != nil {
+            log.Printf("❌ Drain state flush failed: %v", err)
+            a.drain.persistErr = err.Error()
+        } else {
+            a.drain.persistedAt = time.Now().Unix()
+            a.drain.persistErr = ""
+        }
+        a.drain.mu.Unlock()
+    }
+
+    c.JSON(http.StatusOK, a.drainStatus())
+}
+
+// stopDrain cancels a drain, e.g. after an aborted deploy
+func (a *API) stopDrain(c *gin.Context) {
+    a.drain.mu.Lock()
+    wasActive := a.drain.active
+    a.drain.active = false
+    a.drain.startedAt, a.drain.persistedAt = 0, 0
+    a.drain.message, a.drain.persistErr = "", ""
+    a.drain.mu.Unlock()
+
+    if wasActive {
+        log.Printf("🚧 Drain cancelled: accepting new rooms again")
+        a.recordAudit("", "drain_cancelled", "admin", "", nil)
+    }
+    c.JSON(http.StatusOK, a.drainStatus())
+}
+
+func (a *API) getDrainStatus(c *gin.Context) {
+    c.JSON(http.StatusOK, a.drainStatus())
+}
+
+// drainStatus reports the drain's progress. The instance is safe to
+// terminate once no peers remain, or once its state was persisted for a
+// replacement to restore.
+func (a *API) drainStatus() gin.H {
+    roomCount, peerCount := 0, 0
+    a.rooms.Range(func(_ string, room *rooms.Room) bool {
+        roomCount++
+        peerCount += len(room.Snapshot().Peers)
+        return true
+    })
+
+    a.drain.mu.Lock()
+    defer a.drain.mu.Unlock()
+
+    status := gin.H{
+        "draining": a.drain.active,
+        "rooms":    roomCount,
+        "peers":    peerCount,
+        "safeToTerminate": a.drain.active &&
+            (peerCount == 0 || (a.drain.persistedAt != 0 && a.drain.persistErr == "")),
+    }
+    if a.drain.active {
+        status["startedAt"] = a.drain.startedAt
+        status["message"] = a.drain.message
+    }
+    if a.drain.persistedAt != 0 {
+        status["persistedAt"] = a.drain.persistedAt
+    }
+    if a.drain.persistErr != "" {
+        status["persistError"] = a.drain.persistErr
+    }
+    return status
+}
+
+// notifyMaintenance sends a maintenance notice to every peer in every room
+func (a *API) notifyMaintenance(message string) {
+    var all []*rooms.Room
+    a.rooms.Range(func(_ string, room *rooms.Room) bool {
+        all = append(all, room)
+        return true
+    })
+
+    n := notifications.Notification{
+        Type:      "maintenance",
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(gin.H{"message": message}),
+    }
+    for _, room := range all {
+        a.notifyRoom(room, "", n)
+    }
+    log.Printf("📢 Maintenance notice sent to %d rooms", len(all))
+}
+
+// checkDrain fails readiness while draining so no new traffic is routed here
+func (a *API) checkDrain(ctx context.Context) (string, error) {
+    a.drain.mu.Lock()
+    defer a.drain.mu.Unlock()
+
+    if a.drain.active {
+        age := time.Since(time.Unix(a.drain.startedAt, 0)).Round(time.Second)
+        return "", fmt.Errorf("draining for %s", age)
+    }
+    return "accepting traffic", nil
+}
+
+// drainRefusal is returned for room creations while draining
+var drainRefusal = &quotaError{
+    status:  http.StatusServiceUnavailable,
+    message: "Server is draining for maintenance, try again shortly",
+    retry:   true,
+}
@@ -0,0 +1,189 @@
+package httpapi
+
+import (
+    "context"
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// erasePeerData purges everything the server holds about a peer: room
+// memberships and file offers, pending join requests, its notification
+// queue and notifications it sent to others, room events kept for replay,
+// ordered signal channels, the latencies it reported, its recent-rooms
+// history, saved templates, blocklist, signed-in user profile, devices and
+// contacts, its nearby advertisement and pairing code, quick-share links and
+// claims, abuse reports, anomaly counts and findings, stored idempotent
+// responses, client error reports, and audit entries naming it. The caller must be that peer
+// (bearer peer token) or an admin.
+func (a *API) erasePeerData(c *gin.Context) {
+    peerID := c.Param("peerId")
+
+    if !isAdminRequest(c) {
+        if authPeer, ok := authenticatedPeer(c); !ok || authPeer != peerID {
+            c.JSON(http.StatusForbidden, gin.H{"error": "Only the peer itself or an admin can erase its data"})
+            return
+        }
+    }
+
+    removedRooms := a.removePeerFromAllRooms(peerID)
+    removedJoinRequests := a.eraseJoinRequests(peerID)
+    removedNotifications := a.notifications.ErasePeer(peerID)
+    removedEvents := a.eraseRoomEvents(peerID)
+    removedSignalChannels := a.eraseSignalChannels(peerID)
+    removedLatencies := a.eraseLatencies(peerID)
+    removedRecent := a.eraseRecentRooms(peerID)
+    removedTemplates := a.eraseTemplates(peerID)
+    removedBlocks := a.eraseBlocks(peerID)
+    removedUser := a.eraseUser(peerID)
+    removedDevices := a.eraseDevices(peerID)
+    removedContacts := a.eraseContacts(peerID)
+    removedNearby := a.eraseNearby(peerID)
+    removedPairing := a.erasePairing(peerID)
+    removedQuickShares := a.eraseQuickShares(peerID)
+    removedReports := a.eraseReports(peerID)
+    removedAnomalies := a.eraseAnomalies(peerID)
+    removedIdempotency := a.eraseIdempotencyKeys(peerID)
+    removedClientLogs := a.eraseClientLogs(peerID)
+    removedAudit, err := a.purgePeerAudit(c.Request.Context(), peerID)
+    if err != nil {
+        log.Printf("❌ Failed to erase persisted audit entries for %s: %v", peerID, err)
+        c.Error(err)
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to erase stored audit entries; retry later"})
+        return
+    }
+    if err := a.purgePeerUser(c.Request.Context()); err != nil {
+        log.Printf("❌ Failed to erase the persisted profile and devices of %s: %v", peerID, err)
+        c.Error(err)
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to erase the stored profile; retry later"})
+        return
+    }
+
+    receiptID := uuid.New().String()
+    log.Printf("🧽 Erased data for peer %s (receipt %s)", peerID, receiptID)
+
+    c.JSON(http.StatusOK, gin.H{
+        "receiptId": receiptID,
+        "peerId":    peerID,
+        "erasedAt":  time.Now().Unix(),
+        "removed": gin.H{
+            "roomMemberships":     len(removedRooms),
+            "joinRequests":        removedJoinRequests,
+            "notifications":       removedNotifications,
+            "roomEvents":          removedEvents,
+            "signalChannels":      removedSignalChannels,
+            "latencies":           removedLatencies,
+            "auditEntries":        removedAudit,
+            "recentRooms":         removedRecent,
+            "templates":           removedTemplates,
+            "blocks":              removedBlocks,
+            "userProfile":         removedUser,
+            "devices":             removedDevices,
+            "contacts":            removedContacts,
+            "nearby":              removedNearby,
+            "pairing":             removedPairing,
+            "quickShares":         removedQuickShares,
+            "reports":             removedReports,
+            "anomalyFindings":     removedAnomalies,
+            "idempotentResponses": removedIdempotency,
+            "clientLogs":          removedClientLogs,
+        },
+    })
+}
+
+// removePeerFromAllRooms removes a peer from every room it is in, notifying
+// the remaining members and deleting rooms left empty. It returns the codes
+// of the rooms the peer was removed from.
+func (a *API) removePeerFromAllRooms(peerID string) []string {
+    var removedFrom []string
+    var notify []*rooms.Room
+
+    a.rooms.Sweep(func(roomCode string, room *rooms.Room) bool {
+        room.Lock()
+        if _, ok := room.Peers[peerID]; !ok {
+            room.Unlock()
+            return false
+        }
+        room.RemovePeer(peerID)
+        if room.HostID == peerID {
+            room.HostID = ""
+        }
+        isEmpty := room.Abandoned(time.Now().Unix())
+        room.Unlock()
+
+        removedFrom = append(removedFrom, roomCode)
+        if isEmpty {
+            log.Printf("🗑️  Empty room deleted: %s", roomCode)
+        } else {
+            notify = append(notify, room)
+        }
+        return isEmpty
+    })
+
+    for _, room := range notify {
+        a.notifyRoom(room, peerID, notifications.Notification{
+            Type:      "peer_left",
+            PeerID:    peerID,
+            Timestamp: time.Now().Unix(),
+        })
+    }
+
+    return removedFrom
+}
+
+// eraseRoomEvents drops the events a peer sent from every room's replay
+// log, returning how many there were
+func (a *API) eraseRoomEvents(peerID string) int {
+    removed := 0
+    a.rooms.Range(func(_ string, room *rooms.Room) bool {
+        room.Lock()
+        removed += room.EraseEvents(peerID)
+        room.Unlock()
+        return true
+    })
+    return removed
+}
+
+// purgePeerAudit removes audit entries where the peer is actor or target,
+// from memory and from the durable store if there is one. Flushes are held
+// off meanwhile so a purged entry can't be written back.
+func (a *API) purgePeerAudit(ctx context.Context, peerID string) (int, error) {
+    a.persisted.mu.Lock()
+    defer a.persisted.mu.Unlock()
+
+    a.audit.mu.Lock()
+    kept := a.audit.entries[:0]
+    for _, entry := range a.audit.entries {
+        if entry.Actor == peerID || entry.Target == peerID {
+            continue
+        }
+        kept = append(kept, entry)
+    }
+    removed := len(a.audit.entries) - len(kept)
+    a.audit.entries = kept
+    a.audit.mu.Unlock()
+
+    if a.storage != nil {
+        if err := a.storage.ErasePeerAudit(ctx, peerID); err != nil {
+            return removed, err
+        }
+    }
+    return removed, nil
+}
+
+// purgePeerUser writes an erased profile and devices through to the durable
+// store now rather than at the next flush
+func (a *API) purgePeerUser(ctx context.Context) error {
+    if a.storage == nil {
+        return nil
+    }
+    a.persisted.mu.Lock()
+    defer a.persisted.mu.Unlock()
+    return a.flushUsers(ctx)
+}
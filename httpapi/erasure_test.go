@@ -0,0 +1,213 @@
+package httpapi
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "slices"
+    "strings"
+    "testing"
+    "time"
+
+    "p2p-file-share-backend/rooms"
+)
+
+func TestErasePeerData(t *testing.T) {
+    t.Setenv("ANOMALY_CHURN_PER_PEER", "1")
+    tokenA, tokenB := issuePeerToken("peer-a"), issuePeerToken("peer-b")
+    a, h := newTestRouter(t)
+
+    for _, call := range []struct{ path, token, body string }{
+        {"/room/create", tokenA, `{"roomCode":"erase","peerId":"peer-a"}`},
+        {"/room/join", tokenB, `{"roomCode":"erase","peerId":"peer-b"}`},
+        {"/room/heartbeat", tokenA, `{"roomCode":"erase","peerId":"peer-a","rttMs":20}`},
+        {"/room/heartbeat", tokenB, `{"roomCode":"erase","peerId":"peer-b","presence":"away","rttMs":40}`},
+    } {
+        if w := request(h, http.MethodPost, call.path, "application/json", call.token, call.body); w.Code != http.StatusOK {
+            t.Fatalf("%s: status = %d: %s", call.path, w.Code, w.Body)
+        }
+    }
+    seedPeerStores(t, a, h, tokenA, tokenB)
+
+    if w := request(h, http.MethodDelete, "/peers/peer-b/data", "", tokenA, ""); w.Code != http.StatusForbidden {
+        t.Fatalf("erase by another peer: status = %d, want 403", w.Code)
+    }
+    w := request(h, http.MethodDelete, "/peers/peer-b/data", "", tokenB, "")
+    if w.Code != http.StatusOK {
+        t.Fatalf("erase: status = %d: %s", w.Code, w.Body)
+    }
+    var resp struct {
+        Removed struct {
+            RoomEvents int `json:"roomEvents"`
+            Latencies  int `json:"latencies"`
+        } `json:"removed"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+        t.Fatal(err)
+    }
+    if resp.Removed.Latencies != 1 || resp.Removed.RoomEvents < 2 {
+        t.Errorf("removed = %+v, want 1 latency and the join and presence events", resp.Removed)
+    }
+
+    room, ok := a.rooms.Get("erase")
+    if !ok {
+        t.Fatal("room deleted")
+    }
+    room.RLock()
+    for _, e := range room.Events() {
+        if (e.PeerID == "peer-b" || e.Except == "peer-b") && e.Type != "peer_left" {
+            t.Errorf("event %s by the erased peer retained", e.Type)
+        }
+    }
+    room.RUnlock()
+
+    a.insights.mu.Lock()
+    latencies := a.insights.rooms["erase"].latencies
+    _, erased := latencies["peer-b"]
+    _, kept := latencies["peer-a"]
+    a.insights.mu.Unlock()
+    if erased || !kept {
+        t.Errorf("latencies = %v, want only peer-a's", latencies)
+    }
+
+    if stores := storesNaming(a, "peer-b"); len(stores) > 0 {
+        t.Errorf("erased peer still named in %s", strings.Join(stores, ", "))
+    }
+    if stores := storesNaming(a, "peer-a"); !slices.Contains(stores, "quick shares") || !slices.Contains(stores, "reports") {
+        t.Errorf("peer-a named only in %v, want its own link and report kept", stores)
+    }
+}
+
+// seedPeerStores leaves peer-b in every store erasure must purge: a waiting
+// room, anomaly counts, findings and a throttle, a held signal, a pairing
+// code, quick-share links, reports and a stored idempotent response
+func seedPeerStores(t *testing.T, a *API, h http.Handler, tokenA, tokenB string) {
+    t.Helper()
+    if err := a.analyzeAnomalies(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    a.anomalies.observe(signalChurn, suspendPeer, "peer-b")
+    a.anomalies.mu.Lock()
+    a.anomalies.throttled[suspensionKey(suspendPeer, "peer-b")] = time.Now().Add(time.Hour).Unix()
+    a.anomalies.mu.Unlock()
+
+    var shareA struct {
+        Slug string `json:"slug"`
+    }
+    for _, call := range []struct {
+        path, token, body string
+        into              any
+    }{
+        {"/room/create", tokenA, `{"roomCode":"lobby","peerId":"peer-a","waitingRoom":true}`, nil},
+        {"/room/join", tokenB, `{"roomCode":"lobby","peerId":"peer-b"}`, nil},
+        {"/pair/start", tokenB, `{"peerId":"peer-b"}`, nil},
+        {"/share", tokenB, `{"peerId":"peer-b","name":"b.txt","size":1}`, nil},
+        {"/share", tokenA, `{"peerId":"peer-a","name":"a.txt","size":1,"maxClaims":2}`, &shareA},
+        {"/reports", tokenB, `{"reporterId":"peer-b","roomCode":"erase","reason":"spam"}`, nil},
+        {"/reports", tokenA, `{"reporterId":"peer-a","roomCode":"erase","peerId":"peer-b","reason":"spam"}`, nil},
+        {"/reports", tokenA, `{"reporterId":"peer-a","peerId":"peer-b","reason":"spam"}`, nil},
+    } {
+        w := request(h, http.MethodPost, call.path, "application/json", call.token, call.body)
+        if w.Code/100 != 2 {
+            t.Fatalf("%s: status = %d: %s", call.path, w.Code, w.Body)
+        }
+        if call.into != nil {
+            if err := json.Unmarshal(w.Body.Bytes(), call.into); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+    if w := request(h, http.MethodPost, claimPath(shareA.Slug), "application/json", tokenB, `{"peerId":"peer-b"}`); w.Code != http.StatusOK {
+        t.Fatalf("claim: status = %d: %s", w.Code, w.Body)
+    }
+
+    // seq 2 is held waiting for seq 1, keeping the channel open
+    body := `{"from":"peer-b","to":"peer-a","roomCode":"erase","session":"s1","seq":2,"payload":{}}`
+    req := httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Authorization", "Bearer "+tokenB)
+    req.Header.Set("Idempotency-Key", "k1")
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, req)
+    if w.Code/100 != 2 {
+        t.Fatalf("message: status = %d: %s", w.Code, w.Body)
+    }
+
+    if stores := storesNaming(a, "peer-b"); len(stores) != 9 {
+        t.Fatalf("peer-b seeded into %v, want all 9", stores)
+    }
+}
+
+// storesNaming lists which of the stores erasure must purge still hold
+// peerID
+func storesNaming(a *API, peerID string) []string {
+    var stores []string
+    found := func(name string, ok bool) {
+        if ok {
+            stores = append(stores, name)
+        }
+    }
+
+    pending := false
+    a.rooms.Range(func(_ string, room *rooms.Room) bool {
+        room.RLock()
+        _, ok := room.Pending[peerID]
+        room.RUnlock()
+        pending = pending || ok
+        return true
+    })
+    found("join requests", pending)
+
+    key := suspensionKey(suspendPeer, peerID)
+    a.anomalies.mu.Lock()
+    counted := false
+    for _, counts := range a.anomalies.counts {
+        _, ok := counts[key]
+        counted = counted || ok
+    }
+    _, throttled := a.anomalies.throttled[key]
+    found("anomaly counts", counted)
+    found("anomaly throttles", throttled)
+    found("anomaly findings", slices.ContainsFunc(a.anomalies.findings, func(f *anomalyFinding) bool {
+        return f.Kind == suspendPeer && f.Value == peerID
+    }))
+    a.anomalies.mu.Unlock()
+
+    a.signals.mu.Lock()
+    signalled := false
+    for _, ch := range a.signals.channels {
+        signalled = signalled || ch.from == peerID || ch.to == peerID
+    }
+    a.signals.mu.Unlock()
+    found("signal channels", signalled)
+
+    a.pairings.mu.Lock()
+    _, paired := a.pairings.byPeer[peerID]
+    a.pairings.mu.Unlock()
+    found("pairings", paired)
+
+    a.quickShares.mu.Lock()
+    shared := false
+    for _, share := range a.quickShares.shares {
+        shared = shared || share.SenderID == peerID || slices.Contains(share.Claims, peerID)
+    }
+    a.quickShares.mu.Unlock()
+    found("quick shares", shared)
+
+    a.reports.mu.RLock()
+    found("reports", slices.ContainsFunc(a.reports.list, func(r *Report) bool {
+        return r.ReporterID == peerID || r.PeerID == peerID
+    }))
+    a.reports.mu.RUnlock()
+
+    a.idempotency.mu.Lock()
+    stored := false
+    for _, elem := range a.idempotency.keys {
+        stored = stored || strings.HasPrefix(elem.Value.(*idempotentResponse).scope, "peer:"+peerID+" ")
+    }
+    a.idempotency.mu.Unlock()
+    found("idempotent responses", stored)
+
+    return stores
+}
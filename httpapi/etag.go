@@ -0,0 +1,29 @@
+package httpapi
+
+import (
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// roomETag is a weak validator: lastSeen timestamps may drift between
+// versions without invalidating cached listings.
+func roomETag(version uint64) string {
+    return `W/"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// notModified sets the ETag header and answers 304 when the client already
+// holds the current version
+func notModified(c *gin.Context, etag string) bool {
+    c.Header("ETag", etag)
+    for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+        candidate = strings.TrimSpace(candidate)
+        if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+            c.Status(http.StatusNotModified)
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestRoomPeersETag(t *testing.T) {
+    _, h := newTestRouter(t)
+    if w := request(h, http.MethodPost, "/room/create", "application/json", "", `{"roomCode":"etag","peerId":"peer-a"}`); w.Code != http.StatusOK {
+        t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+    }
+
+    get := func(accept, ifNoneMatch string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodGet, "/room/etag/peers", nil)
+        if accept != "" {
+            req.Header.Set("Accept", accept)
+        }
+        if ifNoneMatch != "" {
+            req.Header.Set("If-None-Match", ifNoneMatch)
+        }
+        w := httptest.NewRecorder()
+        h.ServeHTTP(w, req)
+        return w
+    }
+
+    etags := make(map[string]string)
+    for _, accept := range []string{"", "application/json", mimeMsgPack, mimeCBOR} {
+        etags[accept] = get(accept, "").Header().Get("ETag")
+    }
+    if etags[""] != etags["application/json"] {
+        t.Errorf("JSON ETags differ: %q and %q", etags[""], etags["application/json"])
+    }
+    if etags[""] == etags[mimeMsgPack] || etags[""] == etags[mimeCBOR] || etags[mimeMsgPack] == etags[mimeCBOR] {
+        t.Errorf("ETags don't vary by encoding: %v", etags)
+    }
+
+    tests := []struct {
+        name        string
+        accept      string
+        ifNoneMatch string
+        want        int
+    }{
+        {"json revalidated", "", etags[""], http.StatusNotModified},
+        {"msgpack revalidated", mimeMsgPack, etags[mimeMsgPack], http.StatusNotModified},
+        {"msgpack against the json tag", mimeMsgPack, etags[""], http.StatusOK},
+        {"json against the cbor tag", "application/json", etags[mimeCBOR], http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            w := get(tt.accept, tt.ifNoneMatch)
+            if w.Code != tt.want {
+                t.Fatalf("status = %d, want %d", w.Code, tt.want)
+            }
+            if vary := w.Header().Values("Vary"); !containsFold(vary, "Accept") {
+                t.Errorf("Vary = %v, want Accept", vary)
+            }
+        })
+    }
+}
+
+// containsFold reports whether a header's comma-separated values include
+// want, ignoring case
+func containsFold(values []string, want string) bool {
+    for _, v := range values {
+        for _, part := range strings.Split(v, ",") {
+            if strings.EqualFold(strings.TrimSpace(part), want) {
+                return true
+            }
+        }
+    }
+    return false
+}
@@ -0,0 +1,46 @@
+package httpapi
+
+import (
+    "os"
+    "sort"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/rooms"
+)
+
+// setAddressFamily records the address family a peer reached us from, and
+// with WARN_FAMILY_MISMATCH=true and no TURN provider configured returns a
+// warning naming the connected peers on the other family, as an IPv4-only
+// and an IPv6-only peer can't connect directly. Dual-stack clients show up
+// as whichever family they used, so the warning is only a hint. The caller
+// must hold the room lock.
+func (a *API) setAddressFamily(room *rooms.Room, peerID, family string) gin.H {
+    peer, ok := room.Peers[peerID]
+    if !ok || family == "" {
+        return nil
+    }
+    if peer.AddressFamily != family {
+        peer.AddressFamily = family
+        room.TouchPeer(peerID)
+    }
+    if a.turn != nil || os.Getenv("WARN_FAMILY_MISMATCH") != "true" {
+        return nil
+    }
+
+    var mismatched []string
+    for _, other := range room.ConnectedPeers(peerID) {
+        if f := room.Peers[other].AddressFamily; f != "" && f != family {
+            mismatched = append(mismatched, other)
+        }
+    }
+    if len(mismatched) == 0 {
+        return nil
+    }
+    sort.Strings(mismatched)
+    return gin.H{
+        "family":  family,
+        "peers":   mismatched,
+        "message": "Some peers use a different IP version and no relay is configured, so direct connections to them may fail",
+    }
+}
@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// maxDeclineReasonLength bounds the note a sender may give when declining
+const maxDeclineReasonLength = 200
+
+// requestFile lets a peer ask for a file it is allowed to receive. The
+// owner is told with a file_requested event and only starts sending once it
+// accepts; asking again after a decline or a failed integrity check reopens
+// the request.
+func (a *API) requestFile(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+
+    var req struct {
+        PeerID string `json:"peerId" binding:"required"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.PeerID)
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    file, ok := room.Files[fileID]
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return
+    }
+    owner, receiver := room.Peers[file.PeerID], room.Peers[req.PeerID]
+    if receiver == nil || req.PeerID == file.PeerID || owner == nil || !rooms.CanTransfer(owner, receiver) {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Peer is not allowed to receive this file"})
+        return
+    }
+
+    now := time.Now().Unix()
+    transfer := room.Transfer(fileID, req.PeerID, now)
+    switch transfer.State {
+    case rooms.TransferRequested:
+        // Already waiting on the owner; nothing to repeat
+        result := *transfer
+        room.Unlock()
+        c.JSON(http.StatusOK, gin.H{"transfer": result})
+        return
+    case "", rooms.TransferDeclined, rooms.TransferIntegrityFailed:
+    default:
+        room.Unlock()
+        c.JSON(http.StatusConflict, gin.H{"error": "The file has already been accepted for this peer"})
+        return
+    }
+    transfer.State = rooms.TransferRequested
+    transfer.RequestedAt = now
+    transfer.Reason = ""
+    transfer.FailedChunks = nil
+    transfer.UpdatedAt = now
+    result := *transfer
+    ownerID := file.PeerID
+    room.Unlock()
+
+    a.recordAudit(roomCode, "file_requested", req.PeerID, ownerID, gin.H{"fileId": fileID})
+    a.queueNotification(ownerID, notifications.Notification{
+        Type:      "file_requested",
+        PeerID:    req.PeerID,
+        Timestamp: now,
+        Payload:   notifications.Payload(result),
+    })
+
+    c.JSON(http.StatusCreated, gin.H{"transfer": result})
+}
+
+// answerFileRequest returns the handler with which a file's owner accepts
+// or declines a peer's request for it. The requester gets the matching
+// file_request_accepted or file_request_declined event.
+func (a *API) answerFileRequest(accept bool) gin.HandlerFunc {
+    state, event := rooms.TransferDeclined, "file_request_declined"
+    if accept {
+        state, event = rooms.TransferAccepted, "file_request_accepted"
+    }
+
+    return func(c *gin.Context) {
+        roomCode := c.Param("roomCode")
+        fileID := c.Param("fileId")
+
+        var req struct {
+            PeerID     string `json:"peerId" binding:"required"`
+            ReceiverID string `json:"receiverId" binding:"required"`
+            Reason     string `json:"reason"`
+        }
+
+        if err := c.ShouldBindJSON(&req); err != nil {
+            invalidRequest(c, err)
+            return
+        }
+        tagRequest(c, roomCode, req.PeerID)
+
+        if accept {
+            req.Reason = ""
+        } else if len(req.Reason) > maxDeclineReasonLength {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "reason is too long"})
+            return
+        }
+
+        room, exists := a.rooms.Get(roomCode)
+        if !exists {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+            return
+        }
+
+        room.Lock()
+        file, ok := room.Files[fileID]
+        if !ok {
+            room.Unlock()
+            c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+            return
+        }
+        if req.PeerID != file.PeerID {
+            room.Unlock()
+            c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can answer requests for this file"})
+            return
+        }
+        transfer := room.Transfers[fileID][req.ReceiverID]
+        if transfer == nil || transfer.State != rooms.TransferRequested {
+            room.Unlock()
+            c.JSON(http.StatusConflict, gin.H{"error": "The peer has no open request for this file"})
+            return
+        }
+        now := time.Now().Unix()
+        transfer.State = state
+        transfer.Reason = req.Reason
+        transfer.UpdatedAt = now
+        result := *transfer
+        room.Unlock()
+
+        log.Printf("📨 File request %s: %s for %s in Room: %s", state, fileID, req.ReceiverID, roomCode)
+        a.recordAudit(roomCode, "file_request_"+state, req.PeerID, req.ReceiverID, gin.H{"fileId": fileID})
+        a.queueNotification(req.ReceiverID, notifications.Notification{
+            Type:      event,
+            PeerID:    req.PeerID,
+            Timestamp: now,
+            Payload:   notifications.Payload(result),
+        })
+
+        c.JSON(http.StatusOK, gin.H{"transfer": result})
+    }
+}
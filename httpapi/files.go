@@ -0,0 +1,263 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+
+    "p2p-file-share-backend/hooks"
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+func (a *API) listFiles(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    peerID := c.Query("peerId")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    files := room.VisibleFiles(peerID)
+    version := room.Version
+    room.RUnlock()
+
+    if notModified(c, roomETag(version)) {
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"files": files})
+}
+
+// offerRequest is a file or folder a peer is about to offer
+type offerRequest struct {
+    PeerID   string `json:"peerId" binding:"required"`
+    Name     string `json:"name" binding:"required"`
+    Size     int64  `json:"size"`
+    MimeType string `json:"mimeType"`
+    // Entries offer a folder; Name is then the folder's name
+    Entries []rooms.BundleEntry `json:"entries"`
+    Preview *rooms.Preview      `json:"preview"`
+}
+
+func (a *API) offerFile(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    var req offerRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.PeerID)
+
+    if len(req.Name) > rooms.MaxFileNameLength || req.Size < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file name or size"})
+        return
+    }
+    if req.Entries != nil {
+        total, err := rooms.ValidateBundle(req.Entries)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if req.Size != 0 && req.Size != total {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "size must be the total size of the entries"})
+            return
+        }
+        req.Size = total
+    }
+    if req.Preview != nil {
+        if err := req.Preview.Normalize(maxPreviewBytes()); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    file := a.registerOffer(c, roomCode, room, req)
+    if file == nil {
+        return
+    }
+    c.JSON(http.StatusCreated, gin.H{"file": file})
+}
+
+
+// registerOffer adds req to room's files once the offerer's permissions,
+// the file policies and the FileRegister hooks allow it, and tells the room.
+// It answers the request and returns nil if the offer is refused.
+func (a *API) registerOffer(c *gin.Context, roomCode string, room *rooms.Room, req offerRequest) *rooms.FileOffer {
+    room.Lock()
+    if status, refusal := a.offerRefusal(room, req); refusal != nil {
+        room.Unlock()
+        c.JSON(status, refusal)
+        return nil
+    }
+
+    // Hooks run unlocked, so the offer is checked again after them: the
+    // offerer's permissions or the room's files may have changed meanwhile
+    if a.hooks != nil {
+        tenant := room.Tenant
+        room.Unlock()
+        err := a.hooks.FileRegister(c.Request.Context(), hooks.FileRegister{
+            RoomCode: roomCode,
+            PeerID:   req.PeerID,
+            Name:     req.Name,
+            Size:     req.Size,
+            MimeType: req.MimeType,
+            Bundle:   req.Entries != nil,
+            Tenant:   tenant,
+        })
+        if err != nil {
+            status, message := hookRefusal(err)
+            c.JSON(status, gin.H{"error": message})
+            return nil
+        }
+        room.Lock()
+        if status, refusal := a.offerRefusal(room, req); refusal != nil {
+            room.Unlock()
+            c.JSON(status, refusal)
+            return nil
+        }
+    }
+
+    file := &rooms.FileOffer{
+        FileID:    uuid.New().String(),
+        PeerID:    req.PeerID,
+        Name:      req.Name,
+        Size:      req.Size,
+        MimeType:  req.MimeType,
+        OfferedAt: time.Now().Unix(),
+        Entries:   req.Entries,
+        Preview:   req.Preview,
+    }
+    room.Files[file.FileID] = file
+    room.Touch()
+
+    recipients := room.FileRecipients(file)
+    room.Unlock()
+
+    a.dispatcher.Fanout(recipients, notifications.Notification{
+        Type:      "file_offered",
+        PeerID:    req.PeerID,
+        Timestamp: file.OfferedAt,
+        Payload:   notifications.Payload(file),
+    })
+
+    log.Printf("📄 File offered: %s by %s in Room: %s", file.Name, req.PeerID, roomCode)
+    details := gin.H{"fileId": file.FileID, "name": file.Name, "size": file.Size}
+    if file.Entries != nil {
+        details["entries"] = len(file.Entries)
+    }
+    a.recordAudit(roomCode, "file_offered", req.PeerID, "", details)
+
+    return file
+}
+
+// offerRefusal returns the status and body refusing req in room, or nil if
+// the offerer may offer it. The caller must hold the room lock.
+func (a *API) offerRefusal(room *rooms.Room, req offerRequest) (int, gin.H) {
+    owner, ok := room.Peers[req.PeerID]
+    if !ok {
+        return http.StatusNotFound, gin.H{"error": "Peer not in room"}
+    }
+    if room.Suspended {
+        return http.StatusLocked, gin.H{"error": "Room is suspended pending review"}
+    }
+    if !owner.CanSend() {
+        return http.StatusForbidden, gin.H{"error": "Peer is not allowed to offer files in this room"}
+    }
+    var v *rooms.PolicyViolation
+    if req.Entries != nil {
+        v = a.checkBundlePolicy(room, req.Entries, req.Size)
+    } else {
+        v = a.checkFilePolicy(room, req.Name, req.MimeType, req.Size)
+    }
+    if v != nil {
+        return http.StatusUnprocessableEntity, gin.H{"error": v.Message, "rule": v.Rule}
+    }
+    return http.StatusOK, nil
+}
+
+// getFileTree shows a folder bundle as a tree, so receivers can see what is
+// inside before accepting it. A plain file is a tree of one.
+func (a *API) getFileTree(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    file, ok := room.Files[fileID]
+    var tree *rooms.TreeNode
+    var bundle bool
+    if ok && room.FileVisible(file, peerID) {
+        bundle = file.Entries != nil
+        if bundle {
+            tree = rooms.BundleTree(file.Name, file.Entries)
+        } else {
+            tree = &rooms.TreeNode{Name: file.Name, Path: file.Name, Size: file.Size, MimeType: file.MimeType}
+        }
+    }
+    room.RUnlock()
+
+    if tree == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"fileId": fileID, "bundle": bundle, "tree": tree})
+}
+
+// withdrawFile removes an offer; only its owner or the host may do this
+func (a *API) withdrawFile(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    file, ok := room.Files[fileID]
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return
+    }
+    if peerID != file.PeerID && peerID != room.HostID {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner or host can withdraw this file"})
+        return
+    }
+    room.RemoveFile(fileID)
+    room.Unlock()
+
+    a.recordAudit(roomCode, "file_withdrawn", peerID, file.PeerID, gin.H{"fileId": fileID})
+
+    a.notifyRoom(room, peerID, notifications.Notification{
+        Type:      "file_withdrawn",
+        PeerID:    file.PeerID,
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(gin.H{"fileId": fileID}),
+    })
+
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+    "context"
+    "net/http"
+    "testing"
+
+    "p2p-file-share-backend/hooks"
+    "p2p-file-share-backend/rooms"
+)
+
+// TestOfferRecheckedAfterHook changes the room while the FileRegister hook
+// runs, as a concurrent request could, and checks the offer is refused
+func TestOfferRecheckedAfterHook(t *testing.T) {
+    tests := []struct {
+        name   string
+        change func(room *rooms.Room)
+        want   int
+    }{
+        {"unchanged", func(*rooms.Room) {}, http.StatusCreated},
+        {"offerer left", func(room *rooms.Room) {
+            delete(room.Peers, "peer-a")
+        }, http.StatusNotFound},
+        {"room suspended", func(room *rooms.Room) {
+            room.Suspended = true
+        }, http.StatusLocked},
+        {"permission revoked", func(room *rooms.Room) {
+            room.Peers["peer-a"].Permissions = rooms.PermissionsReceiveOnly
+        }, http.StatusForbidden},
+        {"policy tightened", func(room *rooms.Room) {
+            room.Policy.MaxFileSize = 1
+        }, http.StatusUnprocessableEntity},
+        {"another offer took the last slot", func(room *rooms.Room) {
+            room.Policy.MaxFiles = 1
+            room.Files["other"] = &rooms.FileOffer{FileID: "other", PeerID: "peer-a", Name: "b.txt"}
+        }, http.StatusUnprocessableEntity},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            registry := &hooks.Registry{}
+            a := New(Config{AllowedOrigins: testOrigins, Hooks: registry})
+            registry.OnFileRegister(func(ctx context.Context, e hooks.FileRegister) error {
+                room, _ := a.rooms.Get(e.RoomCode)
+                room.Lock()
+                tt.change(room)
+                room.Unlock()
+                return nil
+            })
+            h := a.Router()
+            request(h, http.MethodPost, "/room/create", "application/json", "", `{"roomCode":"offers","peerId":"peer-a"}`)
+
+            w := request(h, http.MethodPost, "/room/offers/files", "application/json", "", `{"peerId":"peer-a","name":"a.txt","size":10}`)
+            if w.Code != tt.want {
+                t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+            }
+            if tt.want != http.StatusCreated && auditCount(a, "file_offered") != 0 {
+                t.Error("refused offer was recorded")
+            }
+        })
+    }
+}
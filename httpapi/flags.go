@@ -0,0 +1,279 @@
+package httpapi
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "hash/fnv"
+    "log"
+    "net/http"
+    "os"
+    "regexp"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/storage"
+)
+
+// Feature flags handlers consult for behaviours still being rolled out
+const (
+    // flagWebTransport lets clients open WebTransport sessions
+    flagWebTransport = "webtransport"
+    // flagProblemDetails serves error responses as application/problem+json
+    flagProblemDetails = "problem-details"
+)
+
+// flagDefaults are the built-in flags and their state when nothing sets
+// them. Flags not listed here are off unless set.
+var flagDefaults = map[string]bool{
+    flagWebTransport:   true,
+    flagProblemDetails: true,
+}
+
+// flagSyncEvery is how often runtime flags are re-read from the store, so
+// flips on one instance reach the others
+const flagSyncEvery = 30 * time.Second
+
+var flagNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// flagRule decides whether a flag is on for a request. A tenant listed in
+// Tenants gets its own setting; otherwise, with Percent set, the flag is on
+// for that share of peers (the same peers each time, and a peer in a 10%
+// rollout stays in it at 20%); otherwise Enabled applies.
+type flagRule struct {
+    Enabled bool            `json:"enabled"`
+    Percent *int            `json:"percent,omitempty"`
+    Tenants map[string]bool `json:"tenants,omitempty"`
+}
+
+func (r flagRule) validate() error {
+    if r.Percent != nil && (*r.Percent < 0 || *r.Percent > 100) {
+        return errors.New("percent must be between 0 and 100")
+    }
+    return nil
+}
+
+// on evaluates the rule for a tenant and a subject, the peer ID or, without
+// one, the client's address
+func (r flagRule) on(name, tenant, subject string) bool {
+    if enabled, ok := r.Tenants[tenant]; ok {
+        return enabled
+    }
+    if r.Percent != nil {
+        h := fnv.New32a()
+        h.Write([]byte(name + "\x00" + subject))
+        return int(h.Sum32()%100) < *r.Percent
+    }
+    return r.Enabled
+}
+
+// flagSet holds the flags in effect: FEATURE_FLAGS_FILE's, overridden by
+// those set at runtime through the admin API, which are saved to the store
+// when there is one
+type flagSet struct {
+    mu      sync.RWMutex
+    file    map[string]flagRule
+    runtime map[string]flagRule
+}
+
+// Where a flag's rule came from
+const (
+    flagSourceRuntime = "runtime"
+    flagSourceFile    = "file"
+    flagSourceDefault = "default"
+)
+
+// rule returns the rule in effect for name and its source
+func (f *flagSet) rule(name string) (flagRule, string) {
+    f.mu.RLock()
+    defer f.mu.RUnlock()
+    if r, ok := f.runtime[name]; ok {
+        return r, flagSourceRuntime
+    }
+    if r, ok := f.file[name]; ok {
+        return r, flagSourceFile
+    }
+    return flagRule{Enabled: flagDefaults[name]}, flagSourceDefault
+}
+
+// flagEnabled reports whether a flag is on for a tenant and subject
+func (a *API) flagEnabled(name, tenant, subject string) bool {
+    r, _ := a.flags.rule(name)
+    return r.on(name, tenant, subject)
+}
+
+// flagOn reports whether a flag is on for a request, by its tenant and its
+// peer or client address
+func (a *API) flagOn(c *gin.Context, name string) bool {
+    subject := requestPeer(c)
+    if subject == "" {
+        subject = c.ClientIP()
+    }
+    return a.flagEnabled(name, tenantOf(c), subject)
+}
+
+// loadFlagFile reads FEATURE_FLAGS_FILE, a JSON object of rules by name:
+//
+//  {"webtransport": {"enabled": false, "tenants": {"beta.example.com": true}},
+//   "problem-details": {"percent": 25}}
+func loadFlagFile() (map[string]flagRule, error) {
+    flags := make(map[string]flagRule)
+    path := os.Getenv("FEATURE_FLAGS_FILE")
+    if path == "" {
+        return flags, nil
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(data, &flags); err != nil {
+        return nil, fmt.Errorf("%s: %v", path, err)
+    }
+    for name, r := range flags {
+        if err := r.validate(); err != nil {
+            return nil, fmt.Errorf("flag %s: %v", name, err)
+        }
+    }
+    return flags, nil
+}
+
+// reloadFlags swaps in freshly loaded file flags, keeping the old ones on
+// error
+func (a *API) reloadFlags() {
+    flags, err := loadFlagFile()
+    if err != nil {
+        log.Printf("❌ Failed to load feature flags, keeping previous: %v", err)
+        return
+    }
+    a.flags.mu.Lock()
+    a.flags.file = flags
+    a.flags.mu.Unlock()
+    log.Printf("🚩 Feature flags loaded (%d from file)", len(flags))
+}
+
+// loadRuntimeFlags replaces the runtime flags with the store's
+func (a *API) loadRuntimeFlags(ctx context.Context) error {
+    records, err := a.storage.LoadFlags(ctx)
+    if err != nil {
+        return err
+    }
+    flags := make(map[string]flagRule, len(records))
+    for _, rec := range records {
+        var r flagRule
+        if err := json.Unmarshal(rec.Rule, &r); err != nil {
+            log.Printf("❌ Ignoring invalid stored flag %s: %v", rec.Name, err)
+            continue
+        }
+        flags[rec.Name] = r
+    }
+    a.flags.mu.Lock()
+    a.flags.runtime = flags
+    a.flags.mu.Unlock()
+    return nil
+}
+
+// syncFlags re-reads the runtime flags from the store, every flagSyncEvery
+func (a *API) syncFlags(ctx context.Context) error {
+    ctx, cancel := context.WithTimeout(ctx, persistTimeout)
+    defer cancel()
+    return a.loadRuntimeFlags(ctx)
+}
+
+// listFlags shows every flag that is built in or set, with its rule and
+// where the rule came from
+func (a *API) listFlags(c *gin.Context) {
+    names := make(map[string]bool)
+    for name := range flagDefaults {
+        names[name] = true
+    }
+    a.flags.mu.RLock()
+    for name := range a.flags.file {
+        names[name] = true
+    }
+    for name := range a.flags.runtime {
+        names[name] = true
+    }
+    a.flags.mu.RUnlock()
+
+    list := make([]gin.H, 0, len(names))
+    for name := range names {
+        r, source := a.flags.rule(name)
+        list = append(list, gin.H{"name": name, "rule": r, "source": source, "default": flagDefaults[name]})
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i]["name"].(string) < list[j]["name"].(string) })
+    c.JSON(http.StatusOK, gin.H{"flags": list})
+}
+
+// setFlag sets a flag's rule at runtime, overriding the file and default
+// until cleared
+func (a *API) setFlag(c *gin.Context) {
+    name := c.Param("name")
+    if !flagNamePattern.MatchString(name) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Flag names are up to 64 lower-case letters, digits, '.', '_' or '-'"})
+        return
+    }
+    var r flagRule
+    if err := c.ShouldBindJSON(&r); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    if err := r.validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if a.storage != nil {
+        rule, _ := json.Marshal(r)
+        ctx, cancel := context.WithTimeout(c.Request.Context(), persistTimeout)
+        err := a.storage.SaveFlag(ctx, storage.FlagRecord{Name: name, Rule: rule, UpdatedAt: time.Now().Unix()})
+        cancel()
+        if err != nil {
+            log.Printf("❌ Failed to save flag %s: %v", name, err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save flag"})
+            return
+        }
+    }
+    a.flags.mu.Lock()
+    a.flags.runtime[name] = r
+    a.flags.mu.Unlock()
+
+    log.Printf("🚩 Flag %s set", name)
+    a.recordAudit("", "flag_set", "admin", name, gin.H{"rule": r})
+    c.JSON(http.StatusOK, gin.H{"name": name, "rule": r, "source": flagSourceRuntime})
+}
+
+// clearFlag drops a flag's runtime rule, returning it to the file's or its
+// default
+func (a *API) clearFlag(c *gin.Context) {
+    name := c.Param("name")
+    a.flags.mu.RLock()
+    _, exists := a.flags.runtime[name]
+    a.flags.mu.RUnlock()
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Flag is not set at runtime"})
+        return
+    }
+
+    if a.storage != nil {
+        ctx, cancel := context.WithTimeout(c.Request.Context(), persistTimeout)
+        err := a.storage.DeleteFlag(ctx, name)
+        cancel()
+        if err != nil {
+            log.Printf("❌ Failed to delete flag %s: %v", name, err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear flag"})
+            return
+        }
+    }
+    a.flags.mu.Lock()
+    delete(a.flags.runtime, name)
+    a.flags.mu.Unlock()
+
+    log.Printf("🚩 Flag %s cleared", name)
+    a.recordAudit("", "flag_cleared", "admin", name, nil)
+    r, source := a.flags.rule(name)
+    c.JSON(http.StatusOK, gin.H{"name": name, "rule": r, "source": source})
+}
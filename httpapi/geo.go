@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+    "log"
+    "net"
+    "os"
+    "strconv"
+
+    "github.com/gin-gonic/gin"
+    "github.com/oschwald/maxminddb-golang"
+)
+
+// geoHeaders are the latitude/longitude headers set by CDNs and hosting
+// platforms that geolocate visitors
+var geoHeaders = [][2]string{
+    {"CF-IPLatitude", "CF-IPLongitude"},
+    {"CloudFront-Viewer-Latitude", "CloudFront-Viewer-Longitude"},
+    {"X-Vercel-IP-Latitude", "X-Vercel-IP-Longitude"},
+}
+
+// geoLocator estimates where a client is, from a MaxMind database
+// (GEOIP_DB) and, when GEO_TRUST_HEADERS=true, from the proxy in front of
+// us. Only trust headers when that proxy sets them and strips the client's.
+type geoLocator struct {
+    db           *maxminddb.Reader
+    trustHeaders bool
+}
+
+func loadGeoLocator() *geoLocator {
+    g := &geoLocator{trustHeaders: os.Getenv("GEO_TRUST_HEADERS") == "true"}
+    if path := os.Getenv("GEOIP_DB"); path != "" {
+        db, err := maxminddb.Open(path)
+        if err != nil {
+            log.Printf("❌ Failed to open GEOIP_DB %s: %v", path, err)
+        } else {
+            g.db = db
+            log.Printf("🌍 Geolocating clients with %s", path)
+        }
+    }
+    return g
+}
+
+// locate returns the client's approximate coordinates, if known
+func (g *geoLocator) locate(c *gin.Context) (lat, lon float64, ok bool) {
+    if g.trustHeaders {
+        for _, pair := range geoHeaders {
+            lat, errLat := strconv.ParseFloat(c.GetHeader(pair[0]), 64)
+            lon, errLon := strconv.ParseFloat(c.GetHeader(pair[1]), 64)
+            if errLat == nil && errLon == nil {
+                return lat, lon, true
+            }
+        }
+    }
+
+    if g.db == nil {
+        return 0, 0, false
+    }
+    ip := net.ParseIP(c.ClientIP())
+    if ip == nil {
+        return 0, 0, false
+    }
+    var record struct {
+        Location struct {
+            Latitude  *float64 `maxminddb:"latitude"`
+            Longitude *float64 `maxminddb:"longitude"`
+        } `maxminddb:"location"`
+    }
+    if err := g.db.Lookup(ip, &record); err != nil || record.Location.Latitude == nil || record.Location.Longitude == nil {
+        return 0, 0, false
+    }
+    return *record.Location.Latitude, *record.Location.Longitude, true
+}
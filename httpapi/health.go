@@ -0,0 +1,162 @@
+package httpapi
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    readinessTimeout = 3 * time.Second
+    turnCheckTTL     = time.Minute
+    healthCacheTTL   = time.Second
+    cleanupInterval  = 5 * time.Minute
+)
+
+// dependencyCheck is one entry in the readiness report
+type dependencyCheck struct {
+    name  string
+    check func(ctx context.Context) (string, error)
+}
+
+type checkResult struct {
+    Status    string `json:"status"`
+    LatencyMs int64  `json:"latencyMs"`
+    Detail    string `json:"detail,omitempty"`
+    Error     string `json:"error,omitempty"`
+}
+
+func (a *API) readinessChecks() []dependencyCheck {
+    checks := []dependencyCheck{
+        {name: "store", check: a.checkStore},
+        {name: "cleanup", check: a.checkCleanupLoop},
+        {name: "turn", check: a.checkTurnProvider},
+        {name: "drain", check: a.checkDrain},
+    }
+    if a.storage != nil {
+        checks = append(checks, dependencyCheck{name: "storage", check: a.checkStorage})
+    }
+    return checks
+}
+
+// healthzHandler reports liveness: the process is up and serving requests
+func (a *API) healthzHandler(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyzHandler reports readiness, running every dependency check in parallel
+func (a *API) readyzHandler(c *gin.Context) {
+    ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+    defer cancel()
+
+    checks := a.readinessChecks()
+    results := make(map[string]checkResult, len(checks))
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+
+    for _, dep := range checks {
+        wg.Add(1)
+        go func(dep dependencyCheck) {
+            defer wg.Done()
+            start := time.Now()
+            detail, err := dep.check(ctx)
+            result := checkResult{
+                Status:    "ok",
+                LatencyMs: time.Since(start).Milliseconds(),
+                Detail:    detail,
+            }
+            if err != nil {
+                result.Status = "fail"
+                result.Error = err.Error()
+            }
+            mu.Lock()
+            results[dep.name] = result
+            mu.Unlock()
+        }(dep)
+    }
+    wg.Wait()
+
+    ready := true
+    for _, dep := range checks {
+        if results[dep.name].Status != "ok" {
+            ready = false
+        }
+    }
+
+    status := http.StatusOK
+    summary := "ready"
+    if !ready {
+        status = http.StatusServiceUnavailable
+        summary = "not ready"
+    }
+
+    c.JSON(status, gin.H{
+        "status": summary,
+        "checks": results,
+    })
+}
+
+// checkStore makes sure the in-memory room store isn't wedged on its lock
+func (a *API) checkStore(ctx context.Context) (string, error) {
+    done := make(chan int, 1)
+    go func() {
+        done <- a.rooms.Len()
+    }()
+
+    select {
+    case n := <-done:
+        return fmt.Sprintf("%d rooms", n), nil
+    case <-ctx.Done():
+        return "", fmt.Errorf("timed out acquiring room store lock")
+    }
+}
+
+// checkCleanupLoop verifies the stale-peer sweeper has run recently, or
+// was started recently if it hasn't run yet
+func (a *API) checkCleanupLoop(ctx context.Context) (string, error) {
+    status, _ := a.jobs.Job(jobCleanup)
+    last := max(status.LastRun, status.Since)
+    if last == 0 {
+        return "", fmt.Errorf("cleanup loop not started")
+    }
+    age := time.Since(time.Unix(last, 0))
+    if age > 2*cleanupInterval {
+        return "", fmt.Errorf("cleanup loop last ran %s ago", age.Round(time.Second))
+    }
+    if !a.leader.IsLeader() {
+        return fmt.Sprintf("last ran %s ago, not leader (relay pruning on standby)", age.Round(time.Second)), nil
+    }
+    return fmt.Sprintf("last ran %s ago", age.Round(time.Second)), nil
+}
+
+// turnCheckCache remembers the last TURN provider check
+type turnCheckCache struct {
+    mu     sync.Mutex
+    at     time.Time
+    detail string
+    err    error
+}
+
+// checkTurnProvider validates the TURN provider's configuration. Results are
+// cached so readiness probes don't hammer the provider.
+func (a *API) checkTurnProvider(ctx context.Context) (string, error) {
+    if a.turn == nil {
+        // Nothing to verify; /turn-credentials reports this to clients itself
+        return "not configured", nil
+    }
+
+    a.turnCheck.mu.Lock()
+    defer a.turnCheck.mu.Unlock()
+
+    if time.Since(a.turnCheck.at) < turnCheckTTL {
+        return a.turnCheck.detail, a.turnCheck.err
+    }
+
+    a.turnCheck.detail, a.turnCheck.err = a.turn.Verify(ctx)
+    a.turnCheck.at = time.Now()
+    return a.turnCheck.detail, a.turnCheck.err
+}
@@ -0,0 +1,71 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/hooks"
+    "p2p-file-share-backend/rooms"
+)
+
+// hookRefusal is the status and message a lifecycle hook's error refuses
+// an action with. Hooks failing rather than refusing are logged.
+func hookRefusal(err error) (int, string) {
+    status, message := hooks.Response(err)
+    if status >= http.StatusInternalServerError {
+        log.Printf("❌ Lifecycle hook failed: %v", err)
+    }
+    return status, message
+}
+
+// checkRoomCreate runs the room creation hooks, responding and returning
+// false if they refuse
+func (a *API) checkRoomCreate(c *gin.Context, roomCode, hostID, mode string) bool {
+    err := a.hooks.RoomCreate(c.Request.Context(), hooks.RoomCreate{
+        RoomCode: roomCode,
+        HostID:   hostID,
+        Mode:     mode,
+        Tenant:   tenantOf(c),
+        ClientIP: c.ClientIP(),
+    })
+    if err != nil {
+        status, message := hookRefusal(err)
+        c.JSON(status, gin.H{"error": message})
+        return false
+    }
+    return true
+}
+
+// checkJoin runs the join hooks for a peer not yet in the room, returning
+// the status and message refusing the join, or "" to go ahead. A missing
+// room is left for the join itself to report.
+func (a *API) checkJoin(c *gin.Context, roomCode, peerID string, profile rooms.PeerProfile) (int, string) {
+    if a.hooks == nil {
+        return http.StatusOK, ""
+    }
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        return http.StatusOK, ""
+    }
+    room.RLock()
+    _, member := room.Peers[peerID]
+    tenant := room.Tenant
+    room.RUnlock()
+    if member {
+        return http.StatusOK, ""
+    }
+
+    err := a.hooks.PeerJoin(c.Request.Context(), hooks.PeerJoin{
+        RoomCode:    roomCode,
+        PeerID:      peerID,
+        DisplayName: profile.DisplayName,
+        Tenant:      tenant,
+        ClientIP:    c.ClientIP(),
+    })
+    if err != nil {
+        return hookRefusal(err)
+    }
+    return http.StatusOK, ""
+}
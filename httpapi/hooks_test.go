@@ -0,0 +1,148 @@
+package httpapi
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "p2p-file-share-backend/hooks"
+    "p2p-file-share-backend/rooms"
+)
+
+// newHookedRouter returns a router whose room creation hook refuses while
+// refuse is set
+func newHookedRouter(t *testing.T, refuse *atomic.Bool) http.Handler {
+    t.Helper()
+    registry := &hooks.Registry{}
+    registry.OnRoomCreate(func(ctx context.Context, e hooks.RoomCreate) error {
+        if refuse.Load() {
+            return hooks.Refuse(http.StatusForbidden, "refused by hook")
+        }
+        return nil
+    })
+    return New(Config{AllowedOrigins: testOrigins, Hooks: registry}).Router()
+}
+
+func TestRoomCreateHook(t *testing.T) {
+    startsAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+    tests := []struct {
+        name string
+        // setup runs before the hook starts refusing and returns the
+        // request body
+        setup func(t *testing.T, h http.Handler) string
+        path  string
+    }{
+        {"create", func(*testing.T, http.Handler) string {
+            return `{"roomCode":"hooked","peerId":"peer-a"}`
+        }, "/room/create"},
+        {"schedule", func(*testing.T, http.Handler) string {
+            return `{"roomCode":"hooked","hostId":"peer-a","startsAt":"` + startsAt + `","durationMinutes":30}`
+        }, "/rooms/schedule"},
+        {"clone", func(t *testing.T, h http.Handler) string {
+            if w := request(h, http.MethodPost, "/room/create", "application/json", "", `{"roomCode":"source","peerId":"peer-a"}`); w.Code != http.StatusOK {
+                t.Fatalf("create source: %d %s", w.Code, w.Body)
+            }
+            return `{"roomCode":"hooked","hostId":"peer-a"}`
+        }, "/rooms/source/clone"},
+        {"pairing", func(t *testing.T, h http.Handler) string {
+            return `{"code":"` + startPairing(t, h) + `","peerId":"peer-b"}`
+        }, "/pair/claim"},
+        {"quick share", func(*testing.T, http.Handler) string {
+            return `{"peerId":"peer-a","name":"a.txt","size":1}`
+        }, "/share"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var refuse atomic.Bool
+            h := newHookedRouter(t, &refuse)
+            body := tt.setup(t, h)
+
+            refuse.Store(true)
+            w := request(h, http.MethodPost, tt.path, "application/json", "", body)
+            if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "refused by hook") {
+                t.Fatalf("refused: status = %d: %s", w.Code, w.Body)
+            }
+
+            refuse.Store(false)
+            if w := request(h, http.MethodPost, tt.path, "application/json", "", body); w.Code >= 300 {
+                t.Fatalf("allowed: status = %d: %s", w.Code, w.Body)
+            }
+        })
+    }
+}
+
+// startPairing starts a pairing for peer-a and returns its code
+func startPairing(t *testing.T, h http.Handler) string {
+    t.Helper()
+    w := request(h, http.MethodPost, "/pair/start", "application/json", "", `{"peerId":"peer-a"}`)
+    var resp struct {
+        Code string `json:"code"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code == "" {
+        t.Fatalf("start pairing: %d %s", w.Code, w.Body)
+    }
+    return resp.Code
+}
+
+func TestQuickShareOfferChecks(t *testing.T) {
+    tests := []struct {
+        name   string
+        size   int64
+        hook   bool
+        policy bool
+        want   int
+    }{
+        {"allowed", 100, false, false, http.StatusCreated},
+        {"hook refuses", 100, true, false, http.StatusForbidden},
+        {"operator policy refuses", 100, false, true, http.StatusUnprocessableEntity},
+        {"within the operator policy", 5, false, true, http.StatusCreated},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            registry := &hooks.Registry{}
+            registry.OnFileRegister(func(ctx context.Context, e hooks.FileRegister) error {
+                if tt.hook {
+                    return hooks.Refuse(http.StatusForbidden, "refused by hook")
+                }
+                return nil
+            })
+            a := New(Config{AllowedOrigins: testOrigins, Hooks: registry})
+            if tt.policy {
+                a.filePolicies.Store(&policyFile{Default: rooms.FilePolicy{MaxFileSize: 10}})
+            }
+            body := fmt.Sprintf(`{"peerId":"peer-a","name":"a.txt","size":%d}`, tt.size)
+            w := request(a.Router(), http.MethodPost, "/share", "application/json", "", body)
+            if w.Code != tt.want {
+                t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+            }
+
+            offered := auditCount(a, "file_offered")
+            if tt.want != http.StatusCreated {
+                if a.rooms.Len() != 0 || offered != 0 {
+                    t.Errorf("refused share left %d rooms and %d offers", a.rooms.Len(), offered)
+                }
+            } else if offered != 1 {
+                t.Errorf("%d file_offered audit entries, want 1", offered)
+            }
+        })
+    }
+}
+
+// auditCount returns how many audit entries of eventType were recorded
+func auditCount(a *API, eventType string) int {
+    a.audit.mu.RLock()
+    defer a.audit.mu.RUnlock()
+    n := 0
+    for _, entry := range a.audit.entries {
+        if (auditFilter{eventType: eventType}).match(entry) {
+            n++
+        }
+    }
+    return n
+}
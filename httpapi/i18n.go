@@ -0,0 +1,93 @@
+package httpapi
+
+import (
+    "embed"
+    "encoding/json"
+    "io/fs"
+    "log"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Message catalogs for the errors end users see, one per language, keyed by
+// error code. en.json holds the English messages the handlers send;
+// others translate them.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs maps a lower-cased language tag to its messages
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[ErrorCode]string {
+    files, err := fs.Glob(localeFiles, "locales/*.json")
+    if err != nil {
+        log.Fatalf("❌ Failed to list message catalogs: %v", err)
+    }
+    catalogs := make(map[string]map[ErrorCode]string, len(files))
+    for _, file := range files {
+        data, err := localeFiles.ReadFile(file)
+        if err != nil {
+            log.Fatalf("❌ Failed to read %s: %v", file, err)
+        }
+        var messages map[ErrorCode]string
+        if err := json.Unmarshal(data, &messages); err != nil {
+            log.Fatalf("❌ Invalid message catalog %s: %v", file, err)
+        }
+        lang := strings.TrimSuffix(strings.TrimPrefix(file, "locales/"), ".json")
+        catalogs[strings.ToLower(lang)] = messages
+    }
+    return catalogs
+}
+
+// localize translates message into the best language of an Accept-Language
+// header that has a catalog, returning it and the language used. Only the
+// English catalog's exact messages are translated; anything else, and any
+// request without a matching language, stays in English.
+func localize(acceptLanguage string, code ErrorCode, message string) (string, string) {
+    if catalogs["en"][code] != message {
+        return message, ""
+    }
+    for _, lang := range acceptedLanguages(acceptLanguage) {
+        if lang == "*" || lang == "en" || strings.HasPrefix(lang, "en-") {
+            break
+        }
+        for _, tag := range []string{lang, strings.SplitN(lang, "-", 2)[0]} {
+            if translated, ok := catalogs[tag][code]; ok {
+                return translated, tag
+            }
+        }
+    }
+    return message, "en"
+}
+
+// acceptedLanguages lists an Accept-Language header's tags, lower-cased,
+// most preferred first; tags with q=0 are left out
+func acceptedLanguages(header string) []string {
+    type weighted struct {
+        tag string
+        q   float64
+    }
+    var tags []weighted
+    for _, part := range strings.Split(header, ",") {
+        tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        tag = strings.ToLower(strings.TrimSpace(tag))
+        q := 1.0
+        if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+                q = parsed
+            }
+        }
+        if tag != "" && q > 0 {
+            tags = append(tags, weighted{tag, q})
+        }
+    }
+    sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+    langs := make([]string, len(tags))
+    for i, t := range tags {
+        langs[i] = t.tag
+    }
+    return langs
+}
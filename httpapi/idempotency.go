@@ -0,0 +1,217 @@
+package httpapi
+
+import (
+    "bytes"
+    "container/list"
+    "crypto/sha256"
+    "encoding/json"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// defaultIdempotencyKeys is how many stored responses are kept at most
+// (IDEMPOTENCY_MAX_KEYS)
+const defaultIdempotencyKeys = 10000
+
+// idempotentResponse is the stored outcome of a request made with an
+// Idempotency-Key
+type idempotentResponse struct {
+    scope       string
+    fingerprint [32]byte
+    done        bool
+    status      int
+    contentType string
+    body        []byte
+    expires     time.Time
+}
+
+// idempotencyStore maps caller, method, path and key to the stored
+// response. Past max entries the least recently used is dropped.
+type idempotencyStore struct {
+    mu   sync.Mutex
+    max  int
+    keys map[string]*list.Element
+    // order holds the entries, least recently used first
+    order *list.List
+}
+
+func newIdempotencyStore() idempotencyStore {
+    return idempotencyStore{
+        max:   envInt("IDEMPOTENCY_MAX_KEYS", defaultIdempotencyKeys),
+        keys:  make(map[string]*list.Element),
+        order: list.New(),
+    }
+}
+
+// get returns the live entry for scope, marking it used. The caller must
+// hold the lock.
+func (s *idempotencyStore) get(scope string) (*idempotentResponse, bool) {
+    elem, ok := s.keys[scope]
+    if !ok {
+        return nil, false
+    }
+    entry := elem.Value.(*idempotentResponse)
+    if time.Now().After(entry.expires) {
+        s.remove(entry)
+        return nil, false
+    }
+    s.order.MoveToBack(elem)
+    return entry, true
+}
+
+// add stores entry, dropping the least recently used entries over the
+// limit. The caller must hold the lock.
+func (s *idempotencyStore) add(entry *idempotentResponse) {
+    s.keys[entry.scope] = s.order.PushBack(entry)
+    for s.max > 0 && s.order.Len() > s.max {
+        s.remove(s.order.Front().Value.(*idempotentResponse))
+    }
+}
+
+// remove drops entry if it is still stored. The caller must hold the lock.
+func (s *idempotencyStore) remove(entry *idempotentResponse) {
+    if elem, ok := s.keys[entry.scope]; ok && elem.Value == entry {
+        s.order.Remove(elem)
+        delete(s.keys, entry.scope)
+    }
+}
+
+// recordingWriter passes a response through while keeping a copy of the body
+type recordingWriter struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+    w.body.Write(data)
+    return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent replays the original response when a request is retried with
+// the same Idempotency-Key within IDEMPOTENCY_TTL (default 24h). Keys are
+// scoped to the caller, the peer of a verified token or else the client
+// IP, so one caller can never be replayed another's response. Reusing a key
+// with a different body is rejected, as is a retry that arrives while the
+// original is still running. Only successful responses are stored; failed
+// requests can be retried with the same key.
+func (a *API) idempotent() gin.HandlerFunc {
+    ttl := envDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+
+    return func(c *gin.Context) {
+        key := c.GetHeader("Idempotency-Key")
+        if key == "" {
+            c.Next()
+            return
+        }
+        if len(key) > maxIdempotencyKeyLength {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+            return
+        }
+
+        body, err := io.ReadAll(c.Request.Body)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+            return
+        }
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+        fingerprint := sha256.Sum256(body)
+        scope := idempotencyCaller(c) + " " + c.Request.Method + " " + c.Request.URL.Path + " " + key
+
+        a.idempotency.mu.Lock()
+        if stored, ok := a.idempotency.get(scope); ok {
+            a.idempotency.mu.Unlock()
+            switch {
+            case stored.fingerprint != fingerprint:
+                c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+            case !stored.done:
+                c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+            default:
+                c.Header("Idempotent-Replayed", "true")
+                c.Data(stored.status, stored.contentType, stored.body)
+                c.Abort()
+            }
+            return
+        }
+        entry := &idempotentResponse{scope: scope, fingerprint: fingerprint, expires: time.Now().Add(ttl)}
+        a.idempotency.add(entry)
+        a.idempotency.mu.Unlock()
+
+        recorder := &recordingWriter{ResponseWriter: c.Writer}
+        c.Writer = recorder
+        // Deferred so a panicking handler releases the key
+        defer func() {
+            c.Writer = recorder.ResponseWriter
+
+            a.idempotency.mu.Lock()
+            defer a.idempotency.mu.Unlock()
+            status := recorder.Status()
+            if !recorder.Written() || status < http.StatusOK || status >= http.StatusMultipleChoices {
+                a.idempotency.remove(entry)
+                return
+            }
+            entry.done = true
+            entry.status = status
+            entry.contentType = recorder.Header().Get("Content-Type")
+            entry.body = recorder.body.Bytes()
+        }()
+        c.Next()
+    }
+}
+
+// idempotencyCaller identifies who made a request for scoping its keys: the
+// peer of a verified token, or else the client IP
+func idempotencyCaller(c *gin.Context) string {
+    if peerID, ok := authenticatedPeer(c); ok {
+        return "peer:" + peerID
+    }
+    return "ip:" + c.ClientIP()
+}
+
+// pruneIdempotencyKeys drops stored responses whose window has passed
+func (a *API) pruneIdempotencyKeys() {
+    now := time.Now()
+    a.idempotency.mu.Lock()
+    defer a.idempotency.mu.Unlock()
+    for elem := a.idempotency.order.Front(); elem != nil; {
+        next := elem.Next()
+        if entry := elem.Value.(*idempotentResponse); now.After(entry.expires) {
+            a.idempotency.remove(entry)
+        }
+        elem = next
+    }
+}
+
+// eraseIdempotencyKeys drops the responses stored for peerID's requests,
+// and any other stored response that names it, returning how many there
+// were
+func (a *API) eraseIdempotencyKeys(peerID string) int {
+    caller := "peer:" + peerID + " "
+    quoted, _ := json.Marshal(peerID)
+
+    a.idempotency.mu.Lock()
+    defer a.idempotency.mu.Unlock()
+
+    removed := 0
+    for elem := a.idempotency.order.Front(); elem != nil; {
+        next := elem.Next()
+        entry := elem.Value.(*idempotentResponse)
+        if strings.HasPrefix(entry.scope, caller) || bytes.Contains(entry.body, quoted) {
+            a.idempotency.remove(entry)
+            removed++
+        }
+        elem = next
+    }
+    return removed
+}
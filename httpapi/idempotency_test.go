@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// idempotentCall is one request in an idempotency test
+type idempotentCall struct {
+    path   string
+    key    string
+    token  string
+    addr   string
+    body   string
+    want   int
+    replay bool
+}
+
+func TestIdempotency(t *testing.T) {
+    tokenA, tokenB := issuePeerToken("peer-a"), issuePeerToken("peer-b")
+    create := `{"roomCode":"idem","peerId":"peer-a"}`
+    join := `{"roomCode":"late","peerId":"peer-a"}`
+
+    tests := []struct {
+        name    string
+        maxKeys string
+        calls   []idempotentCall
+    }{
+        {"replayed for the same caller", "", []idempotentCall{
+            {"/room/create", "k1", tokenA, "", create, http.StatusOK, false},
+            {"/room/create", "k1", tokenA, "", create, http.StatusOK, true},
+        }},
+        {"different body", "", []idempotentCall{
+            {"/room/create", "k1", tokenA, "", create, http.StatusOK, false},
+            {"/room/create", "k1", tokenA, "", `{"roomCode":"other","peerId":"peer-a"}`, http.StatusUnprocessableEntity, false},
+        }},
+        {"not replayed to another token", "", []idempotentCall{
+            {"/room/create", "k1", tokenA, "", create, http.StatusOK, false},
+            {"/room/create", "k1", tokenB, "", `{"roomCode":"idem","peerId":"peer-b"}`, http.StatusOK, false},
+        }},
+        {"not replayed to another address", "", []idempotentCall{
+            {"/room/create", "k1", "", "192.0.2.1:1000", create, http.StatusOK, false},
+            {"/room/create", "k1", "", "192.0.2.2:1000", create, http.StatusOK, false},
+            {"/room/create", "k1", "", "192.0.2.1:2000", create, http.StatusOK, true},
+        }},
+        {"failures are not stored", "", []idempotentCall{
+            {"/room/join", "k1", tokenA, "", join, http.StatusNotFound, false},
+            {"/room/create", "k2", tokenA, "", `{"roomCode":"late","peerId":"peer-b"}`, http.StatusForbidden, false},
+            {"/room/create", "k2", tokenB, "", `{"roomCode":"late","peerId":"peer-b"}`, http.StatusOK, false},
+            {"/room/join", "k1", tokenA, "", join, http.StatusOK, false},
+        }},
+        {"least recently used dropped", "2", []idempotentCall{
+            {"/room/create", "k1", tokenA, "", create, http.StatusOK, false},
+            {"/room/create", "k2", tokenA, "", create, http.StatusOK, false},
+            {"/room/create", "k1", tokenA, "", create, http.StatusOK, true},
+            {"/room/create", "k3", tokenA, "", create, http.StatusOK, false},
+            {"/room/create", "k1", tokenA, "", create, http.StatusOK, true},
+            {"/room/create", "k2", tokenA, "", create, http.StatusOK, false},
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if tt.maxKeys != "" {
+                t.Setenv("IDEMPOTENCY_MAX_KEYS", tt.maxKeys)
+            }
+            a, h := newTestRouter(t)
+            for i, call := range tt.calls {
+                req := httptest.NewRequest(http.MethodPost, call.path, strings.NewReader(call.body))
+                req.Header.Set("Content-Type", "application/json")
+                req.Header.Set("Idempotency-Key", call.key)
+                if call.token != "" {
+                    req.Header.Set("Authorization", "Bearer "+call.token)
+                }
+                if call.addr != "" {
+                    req.RemoteAddr = call.addr
+                }
+                w := httptest.NewRecorder()
+                h.ServeHTTP(w, req)
+
+                if w.Code != call.want {
+                    t.Fatalf("call %d: status = %d, want %d: %s", i, w.Code, call.want, w.Body)
+                }
+                if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != call.replay {
+                    t.Fatalf("call %d: replayed = %v, want %v", i, replayed, call.replay)
+                }
+            }
+            if max := a.idempotency.max; max > 0 && a.idempotency.order.Len() > max {
+                t.Errorf("%d keys stored, limit %d", a.idempotency.order.Len(), max)
+            }
+        })
+    }
+}
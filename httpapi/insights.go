@@ -0,0 +1,191 @@
+package httpapi
+
+import (
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/rooms"
+)
+
+// timelineEvents are the room events that make up the join/leave timeline
+var timelineEvents = map[string]bool{
+    "peer_joined":       true,
+    "peer_left":         true,
+    "peer_disconnected": true,
+    "peer_reconnected":  true,
+    "peer_expired":      true,
+}
+
+// latencySample is a peer's last reported round-trip time to the others
+type latencySample struct {
+    RTTMs      int   `json:"rttMs"`
+    ReportedAt int64 `json:"reportedAt"`
+}
+
+// roomInsights is what clients have told the server about one room's
+// connections
+type roomInsights struct {
+    latencies       map[string]latencySample
+    outcomes        map[string]int64
+    relayedBytes    int64
+    turnCredentials int64
+}
+
+// insightsTable holds the insights of rooms that have any. Entries of rooms
+// that no longer exist are dropped whenever a new room gets one.
+type insightsTable struct {
+    mu    sync.Mutex
+    rooms map[string]*roomInsights
+}
+
+// insightsFor returns a room's insights, creating them if the room exists.
+// The caller must hold the insights lock.
+func (a *API) insightsFor(roomCode string) *roomInsights {
+    if in, ok := a.insights.rooms[roomCode]; ok {
+        return in
+    }
+    if _, exists := a.rooms.Get(roomCode); !exists {
+        return nil
+    }
+    for code := range a.insights.rooms {
+        if _, exists := a.rooms.Get(code); !exists {
+            delete(a.insights.rooms, code)
+        }
+    }
+    in := &roomInsights{
+        latencies: make(map[string]latencySample),
+        outcomes:  make(map[string]int64),
+    }
+    a.insights.rooms[roomCode] = in
+    return in
+}
+
+// recordLatency keeps a peer's reported round-trip time
+func (a *API) recordLatency(roomCode, peerID string, rttMs int) {
+    a.insights.mu.Lock()
+    defer a.insights.mu.Unlock()
+    if in := a.insightsFor(roomCode); in != nil {
+        in.latencies[peerID] = latencySample{RTTMs: rttMs, ReportedAt: time.Now().Unix()}
+    }
+}
+
+// recordRoomConnection counts a connection outcome reported for a room
+func (a *API) recordRoomConnection(roomCode, outcome string, relayedBytes int64) {
+    a.insights.mu.Lock()
+    defer a.insights.mu.Unlock()
+    if in := a.insightsFor(roomCode); in != nil {
+        in.outcomes[outcome]++
+        in.relayedBytes += relayedBytes
+    }
+}
+
+// recordRoomTurn counts TURN credentials issued for a room
+func (a *API) recordRoomTurn(roomCode string) {
+    a.insights.mu.Lock()
+    defer a.insights.mu.Unlock()
+    if in := a.insightsFor(roomCode); in != nil {
+        in.turnCredentials++
+    }
+}
+
+// eraseLatencies drops the latencies a peer reported in every room,
+// returning how many there were
+func (a *API) eraseLatencies(peerID string) int {
+    a.insights.mu.Lock()
+    defer a.insights.mu.Unlock()
+    removed := 0
+    for _, in := range a.insights.rooms {
+        if _, ok := in.latencies[peerID]; ok {
+            delete(in.latencies, peerID)
+            removed++
+        }
+    }
+    return removed
+}
+
+// getRoomInsights shows the host what it needs to work out why someone
+// can't connect: the join/leave timeline the room still retains, the
+// latencies its peers last reported, the files offered, how transfers ended
+// and how much went through TURN. The host must present its peer token.
+func (a *API) getRoomInsights(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    hostID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required to see room insights"})
+        return
+    }
+    tagRequest(c, roomCode, hostID)
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    if hostID != room.HostID {
+        room.RUnlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can see room insights"})
+        return
+    }
+    timeline := make([]gin.H, 0)
+    for _, e := range room.Events() {
+        if timelineEvents[e.Type] {
+            timeline = append(timeline, gin.H{"type": e.Type, "peerId": e.PeerID, "timestamp": e.Timestamp})
+        }
+    }
+    peers := make(map[string]bool, len(room.Peers))
+    for peerID := range room.Peers {
+        peers[peerID] = true
+    }
+    var offeredBytes int64
+    for _, f := range room.Files {
+        offeredBytes += f.Size
+    }
+    files := len(room.Files)
+    transfers := make(map[string]int)
+    for _, byReceiver := range room.Transfers {
+        for _, t := range byReceiver {
+            transfers[t.State]++
+        }
+    }
+    room.RUnlock()
+
+    latencies := make(map[string]latencySample)
+    connections := make(map[string]int64)
+    var relayedBytes, turnCredentials int64
+    a.insights.mu.Lock()
+    if in, ok := a.insights.rooms[roomCode]; ok {
+        for peerID, sample := range in.latencies {
+            if peers[peerID] {
+                latencies[peerID] = sample
+            }
+        }
+        for outcome, n := range in.outcomes {
+            connections[outcome] = n
+        }
+        relayedBytes, turnCredentials = in.relayedBytes, in.turnCredentials
+    }
+    a.insights.mu.Unlock()
+
+    c.JSON(http.StatusOK, gin.H{
+        "roomCode":  roomCode,
+        "timeline":  timeline,
+        "latencies": latencies,
+        "files":     gin.H{"offered": files, "bytes": offeredBytes},
+        "transfers": gin.H{
+            "completed":  transfers[rooms.TransferCompleted] + transfers[rooms.TransferVerified],
+            "failed":     transfers[rooms.TransferIntegrityFailed],
+            "declined":   transfers[rooms.TransferDeclined],
+            "inProgress": transfers[rooms.TransferInProgress],
+        },
+        "turn": gin.H{
+            "credentials":  turnCredentials,
+            "connections":  connections,
+            "relayedBytes": relayedBytes,
+        },
+    })
+}
@@ -0,0 +1,36 @@
+package httpapi
+
+import (
+    "net/http"
+    "testing"
+)
+
+func TestRoomInsightsAccess(t *testing.T) {
+    tokenA, tokenB := issuePeerToken("peer-a"), issuePeerToken("peer-b")
+
+    tests := []struct {
+        name  string
+        path  string
+        token string
+        want  int
+    }{
+        {"host token", "/room/insights/insights", tokenA, http.StatusOK},
+        {"host token and hostId", "/room/insights/insights?hostId=peer-a", tokenA, http.StatusOK},
+        {"hostId alone", "/room/insights/insights?hostId=peer-a", "", http.StatusUnauthorized},
+        {"member token", "/room/insights/insights", tokenB, http.StatusForbidden},
+        {"member token claiming the host", "/room/insights/insights?hostId=peer-a", tokenB, http.StatusForbidden},
+        {"forged token", "/room/insights/insights", tokenA + "x", http.StatusUnauthorized},
+        {"no room", "/room/missing/insights", tokenA, http.StatusNotFound},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, h := newTestRouter(t)
+            request(h, http.MethodPost, "/room/create", "application/json", tokenA, `{"roomCode":"insights","peerId":"peer-a"}`)
+            request(h, http.MethodPost, "/room/join", "application/json", tokenB, `{"roomCode":"insights","peerId":"peer-b"}`)
+
+            if w := request(h, http.MethodGet, tt.path, "", tt.token, ""); w.Code != tt.want {
+                t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+            }
+        })
+    }
+}
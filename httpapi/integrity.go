@@ -0,0 +1,207 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// publishFileHashes lets the owner of a file offer publish its chunk
+// hashes, once, and sends them to every peer allowed to receive it
+func (a *API) publishFileHashes(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+
+    var req struct {
+        PeerID string `json:"peerId" binding:"required"`
+        rooms.Integrity
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.PeerID)
+
+    integrity := req.Integrity
+    for i, h := range integrity.Chunks {
+        integrity.Chunks[i] = strings.ToLower(h)
+    }
+    integrity.SHA256 = strings.ToLower(integrity.SHA256)
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    file, ok := room.Files[fileID]
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return
+    }
+    if req.PeerID != file.PeerID {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can publish hashes for this file"})
+        return
+    }
+    if file.Integrity != nil {
+        room.Unlock()
+        c.JSON(http.StatusConflict, gin.H{"error": "Hashes have already been published for this file"})
+        return
+    }
+    if err := integrity.Validate(file.Size); err != nil {
+        room.Unlock()
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    file.Integrity = &integrity
+    room.Touch()
+
+    recipients := room.FileRecipients(file)
+    room.Unlock()
+
+    a.dispatcher.Fanout(recipients, notifications.Notification{
+        Type:      "file_hashes",
+        PeerID:    req.PeerID,
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(gin.H{"fileId": fileID, "integrity": integrity}),
+    })
+
+    c.JSON(http.StatusOK, gin.H{"fileId": fileID, "integrity": integrity})
+}
+
+// reportVerification records a receiver's check of a file against its
+// published hashes. A failed check marks the transfer integrity_failed and
+// tells the sender and the host, so the file can be sent again.
+func (a *API) reportVerification(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+
+    var req struct {
+        PeerID       string `json:"peerId" binding:"required"`
+        OK           *bool  `json:"ok" binding:"required"`
+        FailedChunks []int  `json:"failedChunks"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.PeerID)
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    file, ok := room.Files[fileID]
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return
+    }
+    owner, receiver := room.Peers[file.PeerID], room.Peers[req.PeerID]
+    if receiver == nil || req.PeerID == file.PeerID || owner == nil || !rooms.CanTransfer(owner, receiver) {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Peer is not a receiver of this file"})
+        return
+    }
+    if file.Integrity == nil {
+        room.Unlock()
+        c.JSON(http.StatusConflict, gin.H{"error": "No hashes have been published for this file"})
+        return
+    }
+    for _, i := range req.FailedChunks {
+        if i < 0 || i >= len(file.Integrity.Chunks) {
+            room.Unlock()
+            c.JSON(http.StatusBadRequest, gin.H{"error": "failedChunks must be chunk indexes"})
+            return
+        }
+    }
+
+    now := time.Now().Unix()
+    transfer := room.Transfer(fileID, req.PeerID, now)
+    transfer.UpdatedAt = now
+    if *req.OK {
+        transfer.State = rooms.TransferVerified
+        transfer.FailedChunks = nil
+    } else {
+        transfer.State = rooms.TransferIntegrityFailed
+        transfer.FailedChunks = req.FailedChunks
+    }
+    result := *transfer
+    notify := []string{file.PeerID}
+    if room.HostID != "" && room.HostID != file.PeerID && room.HostID != req.PeerID {
+        notify = append(notify, room.HostID)
+    }
+    fileName, ownerID := file.Name, file.PeerID
+    room.Unlock()
+
+    eventType := "transfer_verified"
+    if !*req.OK {
+        eventType = "integrity_failed"
+        log.Printf("⚠️  Integrity check failed: %s for %s in Room: %s (%d chunks)", fileName, req.PeerID, roomCode, len(req.FailedChunks))
+        a.recordAudit(roomCode, "integrity_failed", req.PeerID, ownerID, gin.H{"fileId": fileID, "failedChunks": len(req.FailedChunks)})
+    }
+    a.dispatcher.Fanout(notify, notifications.Notification{
+        Type:      eventType,
+        PeerID:    req.PeerID,
+        Timestamp: now,
+        Payload:   notifications.Payload(result),
+    })
+
+    c.JSON(http.StatusOK, gin.H{"transfer": result})
+}
+
+// getFileTransfers lists the transfers of a file: all of them for its owner
+// and the host, and only their own for anyone else
+func (a *API) getFileTransfers(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    file, ok := room.Files[fileID]
+    var transfers []rooms.Transfer
+    if ok {
+        transfers = room.FileTransfers(fileID)
+        if peerID != file.PeerID && peerID != room.HostID {
+            own := transfers[:0]
+            for _, t := range transfers {
+                if t.PeerID == peerID {
+                    own = append(own, t)
+                }
+            }
+            transfers = own
+        }
+    }
+    _, member := room.Peers[peerID]
+    room.RUnlock()
+
+    if !ok {
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return
+    }
+    if !member {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Peer not in room"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
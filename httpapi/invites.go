@@ -0,0 +1,312 @@
+package httpapi
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "net/mail"
+    "net/smtp"
+    "net/url"
+    "os"
+    "regexp"
+    "strings"
+    "text/template"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    invitesPerPeerPerHour      = 10
+    invitesPerRecipientPerHour = 3
+    maxInviteMessageLength     = 280
+)
+
+var (
+    e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+    inviteSubjectTemplate = template.Must(template.New("subject").Parse(
+        `{{if .InviterName}}{{.InviterName}}{{else}}Someone{{end}} invited you to share files`))
+    inviteEmailTemplate = template.Must(template.New("email").Parse(
+        `{{if .InviterName}}{{.InviterName}}{{else}}Someone{{end}} invited you to a P2P file sharing room.
+{{if .Message}}
+"{{.Message}}"
+{{end}}
+Join here: {{.JoinURL}}
+Room code: {{.RoomCode}}
+`))
+    inviteSMSTemplate = template.Must(template.New("sms").Parse(
+        `{{if .InviterName}}{{.InviterName}}{{else}}Someone{{end}} invited you to share files: {{.JoinURL}}{{if .Message}} - {{.Message}}{{end}}`))
+)
+
+// inviteData is the input to the invite message templates
+type inviteData struct {
+    RoomCode    string
+    JoinURL     string
+    InviterName string
+    Message     string
+}
+
+// inviteSender delivers an invitation through a single channel
+type inviteSender interface {
+    Send(to, subject, body string) error
+}
+
+// emailSender picks the email provider from EMAIL_PROVIDER (smtp or sendgrid)
+func emailSender() (inviteSender, error) {
+    from := os.Getenv("EMAIL_FROM")
+    if from == "" {
+        return nil, fmt.Errorf("EMAIL_FROM is not set")
+    }
+
+    switch os.Getenv("EMAIL_PROVIDER") {
+    case "smtp":
+        host := os.Getenv("SMTP_HOST")
+        if host == "" {
+            return nil, fmt.Errorf("SMTP_HOST is not set")
+        }
+        port := os.Getenv("SMTP_PORT")
+        if port == "" {
+            port = "587"
+        }
+        return &smtpSender{
+            addr:     host + ":" + port,
+            host:     host,
+            username: os.Getenv("SMTP_USERNAME"),
+            password: os.Getenv("SMTP_PASSWORD"),
+            from:     from,
+        }, nil
+    case "sendgrid":
+        apiKey := os.Getenv("SENDGRID_API_KEY")
+        if apiKey == "" {
+            return nil, fmt.Errorf("SENDGRID_API_KEY is not set")
+        }
+        return &sendGridSender{apiKey: apiKey, from: from}, nil
+    default:
+        return nil, fmt.Errorf("EMAIL_PROVIDER must be smtp or sendgrid")
+    }
+}
+
+// smsSender sends text messages through Twilio Messaging, reusing the
+// account credentials already used for TURN
+func smsSender() (inviteSender, error) {
+    accountSid := os.Getenv("TWILIO_ACCOUNT_SID")
+    authToken := os.Getenv("TWILIO_AUTH_TOKEN")
+    from := os.Getenv("TWILIO_MESSAGING_FROM")
+    if accountSid == "" || authToken == "" || from == "" {
+        return nil, fmt.Errorf("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_MESSAGING_FROM must be set")
+    }
+    return &twilioSMSSender{accountSid: accountSid, authToken: authToken, from: from}, nil
+}
+
+type smtpSender struct {
+    addr     string
+    host     string
+    username string
+    password string
+    from     string
+}
+
+func (s *smtpSender) Send(to, subject, body string) error {
+    var auth smtp.Auth
+    if s.username != "" {
+        auth = smtp.PlainAuth("", s.username, s.password, s.host)
+    }
+    // Display names end up in the subject, so keep them from injecting headers
+    subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(subject)
+    msg := "From: " + s.from + "\r\n" +
+        "To: " + to + "\r\n" +
+        "Subject: " + subject + "\r\n" +
+        "Content-Type: text/plain; charset=UTF-8\r\n" +
+        "\r\n" + body
+    return smtp.SendMail(s.addr, auth, s.from, []string{to}, []byte(msg))
+}
+
+type sendGridSender struct {
+    apiKey string
+    from   string
+}
+
+func (s *sendGridSender) Send(to, subject, body string) error {
+    payload, err := json.Marshal(gin.H{
+        "personalizations": []gin.H{{"to": []gin.H{{"email": to}}}},
+        "from":             gin.H{"email": s.from},
+        "subject":          subject,
+        "content":          []gin.H{{"type": "text/plain", "value": body}},
+    })
+    if err != nil {
+        return err
+    }
+
+    req, err := http.NewRequest("POST", "https://api.sendgrid.com/v3/mail/send", bytes.NewReader(payload))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Authorization", "Bearer "+s.apiKey)
+    req.Header.Set("Content-Type", "application/json")
+
+    return doProviderRequest(req, "SendGrid")
+}
+
+type twilioSMSSender struct {
+    accountSid string
+    authToken  string
+    from       string
+}
+
+func (s *twilioSMSSender) Send(to, _, body string) error {
+    form := url.Values{}
+    form.Set("To", to)
+    form.Set("From", s.from)
+    form.Set("Body", body)
+
+    endpoint := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", s.accountSid)
+    req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+    if err != nil {
+        return err
+    }
+    req.SetBasicAuth(s.accountSid, s.authToken)
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    return doProviderRequest(req, "Twilio Messaging")
+}
+
+// doProviderRequest sends req and turns any non-2xx response into an error
+func doProviderRequest(req *http.Request, provider string) error {
+    client := &http.Client{Timeout: 10 * time.Second}
+    resp, err := client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+        return fmt.Errorf("%s API error %d: %s", provider, resp.StatusCode, string(body))
+    }
+    return nil
+}
+
+func renderTemplate(t *template.Template, data inviteData) (string, error) {
+    var b strings.Builder
+    if err := t.Execute(&b, data); err != nil {
+        return "", err
+    }
+    return b.String(), nil
+}
+
+// inviteToRoom sends a signed join link, valid for INVITE_LINK_TTL, to
+// someone outside the app by email or SMS
+func (a *API) inviteToRoom(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    var req struct {
+        PeerID  string `json:"peerId" binding:"required"`
+        Channel string `json:"channel" binding:"required"`
+        To      string `json:"to" binding:"required"`
+        Message string `json:"message"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.PeerID)
+
+    if len([]rune(req.Message)) > maxInviteMessageLength {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("message must be at most %d characters", maxInviteMessageLength)})
+        return
+    }
+
+    var sender inviteSender
+    var err error
+    switch req.Channel {
+    case "email":
+        addr, parseErr := mail.ParseAddress(req.To)
+        if parseErr != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+            return
+        }
+        req.To = addr.Address
+        sender, err = emailSender()
+    case "sms":
+        if !e164Pattern.MatchString(req.To) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Phone number must be in E.164 format"})
+            return
+        }
+        sender, err = smsSender()
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "channel must be email or sms"})
+        return
+    }
+    if err != nil {
+        log.Printf("❌ Invite provider not configured: %v", err)
+        c.JSON(http.StatusServiceUnavailable, gin.H{
+            "error":   "Invitations via " + req.Channel + " are not configured",
+            "message": err.Error(),
+        })
+        return
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    inviter, ok := room.Peers[req.PeerID]
+    var inviterName string
+    if ok {
+        inviterName = inviter.DisplayName
+    }
+    room.RUnlock()
+
+    if !ok {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only room members can send invitations"})
+        return
+    }
+
+    if !a.inviterLimiter.Allow(req.PeerID) || !a.recipientLimiter.Allow(strings.ToLower(req.To)) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+        return
+    }
+
+    data := inviteData{
+        RoomCode:    roomCode,
+        JoinURL:     signedJoinURL(c, roomCode, envDuration("INVITE_LINK_TTL", defaultInviteLinkTTL)),
+        InviterName: inviterName,
+        Message:     req.Message,
+    }
+
+    var subject, body string
+    if req.Channel == "email" {
+        subject, err = renderTemplate(inviteSubjectTemplate, data)
+        if err == nil {
+            body, err = renderTemplate(inviteEmailTemplate, data)
+        }
+    } else {
+        body, err = renderTemplate(inviteSMSTemplate, data)
+    }
+    if err != nil {
+        log.Printf("❌ Failed to render invite: %v", err)
+        c.Error(err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render invitation"})
+        return
+    }
+
+    if err := sender.Send(req.To, subject, body); err != nil {
+        log.Printf("❌ Failed to send %s invite: %v", req.Channel, err)
+        c.Error(err)
+        c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send invitation"})
+        return
+    }
+
+    log.Printf("📨 Invite sent via %s for Room: %s by %s", req.Channel, roomCode, req.PeerID)
+    a.recordAudit(roomCode, "invite_sent", req.PeerID, "", gin.H{"channel": req.Channel})
+
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
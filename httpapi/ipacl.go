@@ -0,0 +1,210 @@
+package httpapi
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "net/netip"
+    "os"
+    "os/signal"
+    "strings"
+    "syscall"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ipRules is one allow/deny pair. Entries are CIDR ranges or bare addresses.
+type ipRules struct {
+    Allow []string `json:"allow"`
+    Deny  []string `json:"deny"`
+}
+
+// aclFile is the on-disk format of ACCESS_CONTROL_FILE:
+//
+//  {"global": {"allow": [...], "deny": [...]}, "groups": {"admin": {...}}}
+type aclFile struct {
+    Global ipRules            `json:"global"`
+    Groups map[string]ipRules `json:"groups"`
+}
+
+type prefixRules struct {
+    allow []netip.Prefix
+    deny  []netip.Prefix
+}
+
+type compiledACL struct {
+    global prefixRules
+    groups map[string]prefixRules
+}
+
+// parsePrefixes accepts CIDR ranges and single addresses
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+    prefixes := make([]netip.Prefix, 0, len(entries))
+    for _, entry := range entries {
+        entry = strings.TrimSpace(entry)
+        if entry == "" {
+            continue
+        }
+        if strings.Contains(entry, "/") {
+            p, err := netip.ParsePrefix(entry)
+            if err != nil {
+                return nil, err
+            }
+            prefixes = append(prefixes, p.Masked())
+            continue
+        }
+        addr, err := netip.ParseAddr(entry)
+        if err != nil {
+            return nil, err
+        }
+        prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+    }
+    return prefixes, nil
+}
+
+func compileRules(rules ipRules) (prefixRules, error) {
+    allow, err := parsePrefixes(rules.Allow)
+    if err != nil {
+        return prefixRules{}, err
+    }
+    deny, err := parsePrefixes(rules.Deny)
+    if err != nil {
+        return prefixRules{}, err
+    }
+    return prefixRules{allow: allow, deny: deny}, nil
+}
+
+// loadACL builds the access rules from ACCESS_CONTROL_FILE plus the
+// IP_ALLOWLIST / IP_DENYLIST env vars (comma-separated, applied globally)
+func loadACL() (*compiledACL, error) {
+    var cfg aclFile
+    if path := os.Getenv("ACCESS_CONTROL_FILE"); path != "" {
+        data, err := os.ReadFile(path)
+        if err != nil {
+            return nil, err
+        }
+        if err := json.Unmarshal(data, &cfg); err != nil {
+            return nil, fmt.Errorf("%s: %v", path, err)
+        }
+    }
+
+    if v := os.Getenv("IP_ALLOWLIST"); v != "" {
+        cfg.Global.Allow = append(cfg.Global.Allow, strings.Split(v, ",")...)
+    }
+    if v := os.Getenv("IP_DENYLIST"); v != "" {
+        cfg.Global.Deny = append(cfg.Global.Deny, strings.Split(v, ",")...)
+    }
+
+    global, err := compileRules(cfg.Global)
+    if err != nil {
+        return nil, fmt.Errorf("global: %v", err)
+    }
+    acl := &compiledACL{global: global, groups: make(map[string]prefixRules)}
+    for name, rules := range cfg.Groups {
+        compiled, err := compileRules(rules)
+        if err != nil {
+            return nil, fmt.Errorf("group %s: %v", name, err)
+        }
+        acl.groups[name] = compiled
+    }
+    return acl, nil
+}
+
+// reloadACL swaps in freshly loaded rules, keeping the old ones on error
+func (a *API) reloadACL() {
+    acl, err := loadACL()
+    if err != nil {
+        log.Printf("❌ Failed to load access control rules, keeping previous: %v", err)
+        return
+    }
+    a.acl.Store(acl)
+    log.Printf("🛡️  Access control rules loaded (%d groups)", len(acl.groups))
+}
+
+// watchConfigFile calls reload on SIGHUP or when the file named by the env
+// var changes, until ctx is cancelled
+func watchConfigFile(ctx context.Context, env string, reload func()) {
+    var mtime time.Time
+    if info, err := os.Stat(os.Getenv(env)); err == nil {
+        mtime = info.ModTime()
+    }
+
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
+    defer signal.Stop(hup)
+
+    ticker := time.NewTicker(30 * time.Second)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-hup:
+            reload()
+        case <-ticker.C:
+            path := os.Getenv(env)
+            if path == "" {
+                continue
+            }
+            info, err := os.Stat(path)
+            if err != nil || info.ModTime().Equal(mtime) {
+                continue
+            }
+            mtime = info.ModTime()
+            reload()
+        }
+    }
+}
+
+func (r prefixRules) permits(addr netip.Addr) bool {
+    for _, p := range r.deny {
+        if p.Contains(addr) {
+            return false
+        }
+    }
+    if len(r.allow) == 0 {
+        return true
+    }
+    for _, p := range r.allow {
+        if p.Contains(addr) {
+            return true
+        }
+    }
+    return false
+}
+
+// ipAccess rejects clients outside the global rules or the named group's
+// rules. Use group "" to apply only the global rules.
+func (a *API) ipAccess(group string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !a.ipPermitted(c.ClientIP(), group) {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+            return
+        }
+        c.Next()
+    }
+}
+
+// ipPermitted reports whether ip passes the global rules and the named
+// group's rules. Unparseable addresses are refused.
+func (a *API) ipPermitted(ip, group string) bool {
+    acl := a.acl.Load()
+    if acl == nil {
+        return true
+    }
+    addr, err := netip.ParseAddr(ip)
+    if err != nil {
+        return false
+    }
+    addr = addr.Unmap()
+
+    allowed := acl.global.permits(addr)
+    if rules, ok := acl.groups[group]; ok && allowed {
+        allowed = rules.permits(addr)
+    }
+    return allowed
+}
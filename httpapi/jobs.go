@@ -0,0 +1,83 @@
+package httpapi
+
+import (
+    "context"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/jobs"
+)
+
+// Background jobs, as named by /admin/jobs
+const (
+    jobCleanup        = "cleanup"
+    jobPeerExpiry     = "peer-expiry"
+    jobRetention      = "retention"
+    jobAnomalies      = "anomaly-detection"
+    jobWebhookRetries = "anomaly-webhook-retries"
+    jobOverview       = "overview-sample"
+    jobUsageSample    = "usage-sample"
+    jobUsageExport    = "usage-export"
+    jobPersistence    = "persistence"
+    jobFlagSync       = "flag-sync"
+    jobDemoKeepalive  = "demo-keepalive"
+)
+
+// scheduleJobs registers the periodic maintenance work with the scheduler
+// Start runs
+func (a *API) scheduleJobs() {
+    a.jobs.Add(jobs.Job{Name: jobCleanup, Every: cleanupInterval, Run: a.cleanup})
+    if a.peerGrace > 0 {
+        a.jobs.Add(jobs.Job{Name: jobPeerExpiry, Every: disconnectSweepInterval, Run: a.expireDisconnectedPeers})
+    }
+    a.jobs.Add(jobs.Job{Name: jobRetention, Every: a.retention.interval, Run: a.applyRetention})
+    a.jobs.Add(jobs.Job{Name: jobAnomalies, Every: a.anomalies.window, Run: a.analyzeAnomalies})
+    if a.anomalies.webhook != "" {
+        a.jobs.Add(jobs.Job{Name: jobWebhookRetries, Every: anomalyWebhookRetryEvery, Run: a.retryAnomalyWebhooks})
+    }
+    a.jobs.Add(jobs.Job{Name: jobOverview, Every: overviewSampleEvery, Immediate: true, Run: a.sampleOverview})
+    a.jobs.Add(jobs.Job{Name: jobUsageSample, Every: usageSampleEvery, Run: func(ctx context.Context) error {
+        a.meterRooms()
+        return nil
+    }})
+    if a.usageExport != nil {
+        a.jobs.Add(jobs.Job{Name: jobUsageExport, Every: a.usageExport.interval, Run: a.exportUsage})
+    }
+    if a.storage != nil {
+        a.jobs.Add(jobs.Job{Name: jobPersistence, Every: envDuration("PERSIST_INTERVAL", 5*time.Second), Final: true, Run: a.persistState})
+        a.jobs.Add(jobs.Job{Name: jobFlagSync, Every: flagSyncEvery, Run: a.syncFlags})
+    }
+    if a.cfg.Dev {
+        a.jobs.Add(jobs.Job{Name: jobDemoKeepalive, Every: demoKeepaliveInterval, Run: a.keepDemoPeersAlive})
+    }
+}
+
+// listJobs shows every background job's schedule, run counts and last
+// outcome
+func (a *API) listJobs(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{"jobs": a.jobs.Jobs()})
+}
+
+func (a *API) getJob(c *gin.Context) {
+    status, ok := a.jobs.Job(c.Param("name"))
+    if !ok {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+        return
+    }
+    c.JSON(http.StatusOK, status)
+}
+
+// runJob runs a job now, outside its schedule. The run is queued behind any
+// run in progress; its outcome shows in GET /admin/jobs/:name.
+func (a *API) runJob(c *gin.Context) {
+    name := c.Param("name")
+    if !a.jobs.Trigger(name) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+        return
+    }
+    a.recordAudit("", "job_triggered", "admin", name, nil)
+    status, _ := a.jobs.Job(name)
+    c.JSON(http.StatusAccepted, gin.H{"queued": true, "job": status})
+}
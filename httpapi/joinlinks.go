@@ -0,0 +1,199 @@
+package httpapi
+
+import (
+    "crypto/rand"
+    "fmt"
+    "log"
+    "math/big"
+    "net/http"
+    "net/url"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "rsc.io/qr"
+)
+
+const (
+    defaultFrontendURL = "https://p2p-client.martinwong.me"
+    shortLinkTTL       = 24 * time.Hour
+    shortLinkLength    = 6
+    // Unambiguous characters only, so slugs can be read aloud or typed
+    shortLinkAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+)
+
+// shortLink maps a slug to a room code until it expires
+type shortLink struct {
+    RoomCode  string
+    ExpiresAt time.Time
+}
+
+// shortLinkTable holds the live short links by slug
+type shortLinkTable struct {
+    mu    sync.RWMutex
+    links map[string]shortLink
+}
+
+// joinURL returns the frontend URL that opens with the room pre-filled
+func joinURL(roomCode string) string {
+    base := os.Getenv("FRONTEND_URL")
+    if base == "" {
+        base = defaultFrontendURL
+    }
+    return strings.TrimRight(base, "/") + "/?room=" + url.QueryEscape(bareRoomCode(roomCode))
+}
+
+// publicBaseURL returns the externally visible URL of this backend
+func publicBaseURL(c *gin.Context) string {
+    if base := os.Getenv("PUBLIC_URL"); base != "" {
+        return strings.TrimRight(base, "/")
+    }
+    scheme := "http"
+    if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+        scheme = "https"
+    }
+    return scheme + "://" + c.Request.Host
+}
+
+// getRoomQRCode renders a signed join link for the room as a QR code (PNG
+// or SVG). The link expires after SIGNED_URL_TTL.
+func (a *API) getRoomQRCode(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    _, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    code, err := qr.Encode(signedJoinURL(c, roomCode, envDuration("SIGNED_URL_TTL", defaultSignedURLTTL)), qr.M)
+    if err != nil {
+        log.Printf("❌ Failed to encode QR code: %v", err)
+        c.Error(err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+        return
+    }
+
+    if scale, err := strconv.Atoi(c.Query("scale")); err == nil && scale >= 1 && scale <= 32 {
+        code.Scale = scale
+    }
+
+    switch c.DefaultQuery("format", "png") {
+    case "png":
+        c.Data(http.StatusOK, "image/png", code.PNG())
+    case "svg":
+        c.Data(http.StatusOK, "image/svg+xml", qrSVG(code))
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "format must be png or svg"})
+    }
+}
+
+// qrSVG draws a QR code as an SVG with the standard 4-module quiet zone
+func qrSVG(code *qr.Code) []byte {
+    const quiet = 4
+    size := code.Size + 2*quiet
+
+    var b strings.Builder
+    fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" shape-rendering="crispEdges">`,
+        size, size, size*code.Scale, size*code.Scale)
+    fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, size, size)
+    for y := 0; y < code.Size; y++ {
+        for x := 0; x < code.Size; x++ {
+            if code.Black(x, y) {
+                fmt.Fprintf(&b, "M%d %dh1v1h-1z", x+quiet, y+quiet)
+            }
+        }
+    }
+    b.WriteString(`"/></svg>`)
+    return []byte(b.String())
+}
+
+func newShortLinkSlug() (string, error) {
+    return randomCode(shortLinkAlphabet, shortLinkLength)
+}
+
+// randomCode returns n characters drawn uniformly from alphabet
+func randomCode(alphabet string, n int) (string, error) {
+    code := make([]byte, n)
+    max := big.NewInt(int64(len(alphabet)))
+    for i := range code {
+        r, err := rand.Int(rand.Reader, max)
+        if err != nil {
+            return "", err
+        }
+        code[i] = alphabet[r.Int64()]
+    }
+    return string(code), nil
+}
+
+// createShortLink issues a /j/:slug link that redirects to the room's join URL
+func (a *API) createShortLink(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    _, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    expiresAt := time.Now().Add(shortLinkTTL)
+
+    a.shortLinks.mu.Lock()
+    var slug string
+    for {
+        var err error
+        slug, err = newShortLinkSlug()
+        if err != nil {
+            a.shortLinks.mu.Unlock()
+            log.Printf("❌ Failed to generate short link: %v", err)
+            c.Error(err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate short link"})
+            return
+        }
+        if _, taken := a.shortLinks.links[slug]; !taken {
+            break
+        }
+    }
+    a.shortLinks.links[slug] = shortLink{RoomCode: roomCode, ExpiresAt: expiresAt}
+    a.shortLinks.mu.Unlock()
+
+    log.Printf("🔗 Short link created: %s → Room: %s", slug, roomCode)
+
+    c.JSON(http.StatusOK, gin.H{
+        "slug":      slug,
+        "url":       signedURL(c, "/j/"+slug, nil, shortLinkTTL),
+        "joinUrl":   joinURL(roomCode),
+        "expiresAt": expiresAt.Unix(),
+    })
+}
+
+// followShortLink redirects a short link to the frontend with the room pre-filled
+func (a *API) followShortLink(c *gin.Context) {
+    slug := c.Param("slug")
+
+    a.shortLinks.mu.RLock()
+    link, ok := a.shortLinks.links[slug]
+    a.shortLinks.mu.RUnlock()
+
+    if !ok || time.Now().After(link.ExpiresAt) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Link not found or expired"})
+        return
+    }
+
+    c.Redirect(http.StatusFound, joinURL(link.RoomCode))
+}
+
+// pruneShortLinks drops expired short links
+func (a *API) pruneShortLinks() {
+    now := time.Now()
+    a.shortLinks.mu.Lock()
+    for slug, link := range a.shortLinks.links {
+        if now.After(link.ExpiresAt) {
+            delete(a.shortLinks.links, slug)
+        }
+    }
+    a.shortLinks.mu.Unlock()
+}
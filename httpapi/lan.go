@@ -0,0 +1,152 @@
+package httpapi
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// networkHash identifies the network a request came from within one room:
+// a keyed hash of the room code and the client's public IP, so the raw
+// address is never kept and hashes can't be matched across rooms
+func networkHash(roomCode, ip string) string {
+    current, _ := tokenSecrets()
+    key := hmac.New(sha256.New, current)
+    key.Write([]byte("network-hint"))
+    mac := hmac.New(sha256.New, key.Sum(nil))
+    mac.Write([]byte(roomCode + "\n" + ip))
+    return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// lanPeer is a peer on the same network, with the local addresses it
+// offered
+type lanPeer struct {
+    PeerID     string   `json:"peerId"`
+    Candidates []string `json:"candidates,omitempty"`
+}
+
+// sameNetworkPeers lists the peers sharing network with peerID, with their
+// local candidates when the two may exchange files. The caller must hold
+// the room lock.
+func sameNetworkPeers(room *rooms.Room, peerID, network string) []lanPeer {
+    self := room.Peers[peerID]
+    peers := make([]lanPeer, 0)
+    for _, id := range room.SameNetwork(peerID, network) {
+        other := room.Peers[id]
+        p := lanPeer{PeerID: id}
+        if self != nil && (rooms.CanTransfer(self, other) || rooms.CanTransfer(other, self)) {
+            p.Candidates = other.LocalCandidates
+        }
+        peers = append(peers, p)
+    }
+    return peers
+}
+
+// announceNetwork records the network a peer is on, from the request's
+// public IP, along with any local addresses it offers. Peers already known
+// on the same network get a lan_peer event, so both sides can try a direct
+// LAN path before TURN.
+func (a *API) announceNetwork(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    var req struct {
+        PeerID     string   `json:"peerId" binding:"required"`
+        Candidates []string `json:"candidates"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.PeerID)
+
+    if len(req.Candidates) > rooms.MaxLocalCandidates {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Too many candidates"})
+        return
+    }
+    for _, candidate := range req.Candidates {
+        if err := rooms.ValidLocalCandidate(candidate); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    network := networkHash(roomCode, c.ClientIP())
+
+    room.Lock()
+    peer, ok := room.Peers[req.PeerID]
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "Peer not in room"})
+        return
+    }
+    peer.Network = network
+    peer.LocalCandidates = req.Candidates
+    peers := sameNetworkPeers(room, req.PeerID, network)
+    // Each neighbour hears about this peer, with its candidates if the two
+    // may exchange files
+    announcements := make(map[string]lanPeer, len(peers))
+    for _, p := range peers {
+        other := room.Peers[p.PeerID]
+        announcement := lanPeer{PeerID: req.PeerID}
+        if rooms.CanTransfer(peer, other) || rooms.CanTransfer(other, peer) {
+            announcement.Candidates = req.Candidates
+        }
+        announcements[p.PeerID] = announcement
+    }
+    room.Unlock()
+
+    now := time.Now().Unix()
+    for peerID, p := range announcements {
+        a.queueNotification(peerID, notifications.Notification{
+            Type:      "lan_peer",
+            PeerID:    req.PeerID,
+            Timestamp: now,
+            Payload:   notifications.Payload(p),
+        })
+    }
+
+    c.JSON(http.StatusOK, gin.H{"sameNetwork": peers})
+}
+
+// getLANHints lists the room's peers that look to be on the requester's
+// network, judged by the request's public IP, without recording anything
+func (a *API) getLANHints(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    peerID := c.Query("peerId")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    network := networkHash(roomCode, c.ClientIP())
+
+    room.RLock()
+    _, member := room.Peers[peerID]
+    var peers []lanPeer
+    if member {
+        peers = sameNetworkPeers(room, peerID, network)
+    }
+    room.RUnlock()
+
+    if !member {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Peer not in room"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"sameNetwork": peers})
+}
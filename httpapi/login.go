@@ -0,0 +1,283 @@
+package httpapi
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "log"
+    "net/http"
+    "net/url"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/oidc"
+)
+
+const (
+    // loginStateTTL is how long a user has to finish signing in at the
+    // provider
+    loginStateTTL = 10 * time.Minute
+    // maxPendingLogins bounds the sign-ins in progress at once
+    maxPendingLogins  = 10000
+    loginCodeAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+    // loginCodeLength gives state, nonce and PKCE verifier about 256 bits
+    loginCodeLength = 43
+    userIDPrefix    = "user-"
+)
+
+// pendingLogin is a sign-in started at /auth/:provider/login, waiting for
+// the provider to redirect back
+type pendingLogin struct {
+    Provider    string
+    Verifier    string
+    Nonce       string
+    RedirectURI string
+    ReturnTo    string
+    ExpiresAt   time.Time
+}
+
+// userProfile is what the server remembers about a signed-in user
+type userProfile struct {
+    UserID     string `json:"userId"`
+    Provider   string `json:"provider"`
+    Name       string `json:"name,omitempty"`
+    Email      string `json:"email,omitempty"`
+    FirstLogin int64  `json:"firstLogin"`
+    LastLogin  int64  `json:"lastLogin"`
+}
+
+// loginTable holds sign-ins in progress and the users who have signed in.
+// A user's ID doubles as a peer ID, so its peer token works everywhere an
+// anonymous one does and follows the user across devices and restarts.
+type loginTable struct {
+    mu      sync.Mutex
+    pending map[string]*pendingLogin
+    users   map[string]*userProfile
+}
+
+// userIDFor derives a stable user ID from the provider's issuer and subject
+func userIDFor(id *oidc.Identity) string {
+    sum := sha256.Sum256([]byte(id.Issuer + "\n" + id.Subject))
+    return userIDPrefix + hex.EncodeToString(sum[:])[:20]
+}
+
+// listLoginProviders lists the configured login providers; an empty list
+// means only anonymous peers are available
+func (a *API) listLoginProviders(c *gin.Context) {
+    names := make([]string, 0, len(a.cfg.Login))
+    for name := range a.cfg.Login {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    c.JSON(http.StatusOK, gin.H{"providers": names, "anonymous": true})
+}
+
+// startLogin redirects to the provider's sign-in page. returnTo, when
+// given, must be on an allowed origin; the callback sends the user back
+// there with the peer ID and token in the URL fragment.
+func (a *API) startLogin(c *gin.Context) {
+    name := c.Param("provider")
+    provider, ok := a.cfg.Login[name]
+    if !ok {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+        return
+    }
+    returnTo := c.Query("returnTo")
+    if returnTo != "" && !a.returnAllowed(returnTo) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "returnTo must be on an allowed origin"})
+        return
+    }
+
+    var codes [3]string
+    for i := range codes {
+        code, err := randomCode(loginCodeAlphabet, loginCodeLength)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+            return
+        }
+        codes[i] = code
+    }
+    state, nonce, verifier := codes[0], codes[1], codes[2]
+    redirectURI := loginRedirectBase(c) + "/auth/" + name + "/callback"
+
+    authURL, err := provider.AuthCodeURL(c.Request.Context(), redirectURI, state, nonce, verifier)
+    if err != nil {
+        log.Printf("❌ Failed to start %s login: %v", name, err)
+        c.Error(err)
+        c.JSON(http.StatusBadGateway, gin.H{"error": "Login provider is unavailable"})
+        return
+    }
+
+    a.logins.mu.Lock()
+    if len(a.logins.pending) >= maxPendingLogins {
+        a.logins.mu.Unlock()
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many logins in progress, try again shortly"})
+        return
+    }
+    a.logins.pending[state] = &pendingLogin{
+        Provider:    name,
+        Verifier:    verifier,
+        Nonce:       nonce,
+        RedirectURI: redirectURI,
+        ReturnTo:    returnTo,
+        ExpiresAt:   time.Now().Add(loginStateTTL),
+    }
+    a.logins.mu.Unlock()
+
+    c.Redirect(http.StatusFound, authURL)
+}
+
+// finishLogin completes a sign-in: it redeems the provider's code, records
+// the user and issues a peer token for their stable user ID
+func (a *API) finishLogin(c *gin.Context) {
+    name := c.Param("provider")
+    provider, ok := a.cfg.Login[name]
+    if !ok {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+        return
+    }
+
+    state := c.Query("state")
+    a.logins.mu.Lock()
+    login, ok := a.logins.pending[state]
+    delete(a.logins.pending, state)
+    a.logins.mu.Unlock()
+    if !ok || login.Provider != name || time.Now().After(login.ExpiresAt) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Login expired or was not started here; try again"})
+        return
+    }
+    if reason := c.Query("error"); reason != "" {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Login was not completed: " + reason})
+        return
+    }
+    code := c.Query("code")
+    if code == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+        return
+    }
+
+    identity, err := provider.Exchange(c.Request.Context(), login.RedirectURI, code, login.Verifier, login.Nonce)
+    if err != nil {
+        log.Printf("⚠️  %s login failed: %v", name, err)
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Login could not be verified"})
+        return
+    }
+
+    userID := userIDFor(identity)
+    tagRequest(c, "", userID)
+    now := time.Now().Unix()
+
+    a.logins.mu.Lock()
+    user, returning := a.logins.users[userID]
+    if !returning {
+        user = &userProfile{UserID: userID, FirstLogin: now}
+        a.logins.users[userID] = user
+    }
+    user.Provider = name
+    user.Name = identity.Name
+    user.Email = identity.Email
+    user.LastLogin = now
+    profile := *user
+    a.logins.mu.Unlock()
+
+    if !returning {
+        log.Printf("👤 New user %s signed in with %s", userID, name)
+    }
+    token := issuePeerToken(userID)
+
+    if login.ReturnTo != "" {
+        fragment := url.Values{"peerId": {userID}, "token": {token}}
+        c.Redirect(http.StatusFound, login.ReturnTo+"#"+fragment.Encode())
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "id":    userID,
+        "token": token,
+        "user":  profile,
+    })
+}
+
+// getMe returns the signed-in user behind the bearer token. Anonymous peer
+// tokens are valid but have no profile.
+func (a *API) getMe(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    tagRequest(c, "", peerID)
+
+    a.logins.mu.Lock()
+    user, ok := a.logins.users[peerID]
+    var profile userProfile
+    if ok {
+        profile = *user
+    }
+    a.logins.mu.Unlock()
+
+    if !ok {
+        c.JSON(http.StatusOK, gin.H{"id": peerID, "anonymous": true})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"id": peerID, "anonymous": false, "user": profile})
+}
+
+// userProfileFor returns the signed-in user with ID userID, if any
+func (a *API) userProfileFor(userID string) (userProfile, bool) {
+    a.logins.mu.Lock()
+    defer a.logins.mu.Unlock()
+    user, ok := a.logins.users[userID]
+    if !ok {
+        return userProfile{}, false
+    }
+    return *user, true
+}
+
+// eraseUser forgets the profile of the user with ID peerID and reports
+// whether there was one
+func (a *API) eraseUser(peerID string) bool {
+    a.logins.mu.Lock()
+    defer a.logins.mu.Unlock()
+    _, ok := a.logins.users[peerID]
+    delete(a.logins.users, peerID)
+    return ok
+}
+
+// pruneLogins drops sign-ins that were never finished
+func (a *API) pruneLogins() {
+    now := time.Now()
+    a.logins.mu.Lock()
+    defer a.logins.mu.Unlock()
+    for state, login := range a.logins.pending {
+        if now.After(login.ExpiresAt) {
+            delete(a.logins.pending, state)
+        }
+    }
+}
+
+// returnAllowed reports whether a post-login redirect target is on an
+// allowed origin
+func (a *API) returnAllowed(returnTo string) bool {
+    u, err := url.Parse(returnTo)
+    if err != nil || u.Scheme == "" || u.Host == "" {
+        return false
+    }
+    return a.originAllowed(u.Scheme + "://" + u.Host)
+}
+
+// loginRedirectBase is the public base URL providers redirect back to:
+// OIDC_REDIRECT_BASE, or else the URL this request came in on
+func loginRedirectBase(c *gin.Context) string {
+    if base := os.Getenv("OIDC_REDIRECT_BASE"); base != "" {
+        return strings.TrimSuffix(base, "/")
+    }
+    scheme := "http"
+    if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+        scheme = "https"
+    }
+    return scheme + "://" + c.Request.Host
+}
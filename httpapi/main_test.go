@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+    "io"
+    "log"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+    gin.SetMode(gin.TestMode)
+    os.Setenv("ACCESS_LOG", "off")
+    log.SetOutput(io.Discard)
+    os.Exit(m.Run())
+}
+
+// testOrigins are the frontends test requests may come from
+var testOrigins = []string{"https://a.example", "https://b.example"}
+
+// newTestRouter returns a fresh API and its router, built after the test
+// has set any environment it needs
+func newTestRouter(t *testing.T) (*API, http.Handler) {
+    t.Helper()
+    a := New(Config{AllowedOrigins: testOrigins})
+    return a, a.Router()
+}
+
+// request sends a request with an optional bearer token and returns the
+// recorded response
+func request(h http.Handler, method, path, contentType, token, body string) *httptest.ResponseRecorder {
+    var r io.Reader
+    if body != "" {
+        r = strings.NewReader(body)
+    }
+    req := httptest.NewRequest(method, path, r)
+    if contentType != "" {
+        req.Header.Set("Content-Type", contentType)
+    }
+    if token != "" {
+        req.Header.Set("Authorization", "Bearer "+token)
+    }
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, req)
+    return w
+}
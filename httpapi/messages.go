@@ -0,0 +1,125 @@
+package httpapi
+
+import (
+    "encoding/json"
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    maxMessagePayloadBytes = 4 * 1024 // 4 KB per message payload
+    maxQueuedMessages      = 100      // pending messages per recipient
+    messagesPerMinute      = 60       // per sender
+)
+
+// sendMessage relays a small JSON payload from one peer to another. The
+// message is queued on the recipient's notification channel, so offline
+// peers receive it the next time they poll.
+func (a *API) sendMessage(c *gin.Context) {
+    var req struct {
+        From     string          `json:"from" binding:"required"`
+        To       string          `json:"to" binding:"required"`
+        RoomCode string          `json:"roomCode"`
+        Session  string          `json:"session"`
+        Seq      uint64          `json:"seq"`
+        Payload  json.RawMessage `json:"payload" binding:"required"`
+    }
+
+    // Cap the body so an oversized payload is rejected before it is buffered
+    c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMessagePayloadBytes+1024)
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, req.RoomCode, req.From)
+
+    if status, msg := a.relayMessage(req.From, req.To, req.RoomCode, req.Session, req.Seq, req.Payload); status != http.StatusOK {
+        c.JSON(status, gin.H{"error": msg})
+        return
+    }
+    a.observePeer(c, signalFlood, req.From)
+
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// relayMessage validates and queues a message for its recipient. It returns
+// http.StatusOK on success, or an error status and message. Shared by every
+// transport that can carry peer messages. A non-zero seq delivers the
+// message in order with the rest of its session; see relayOrdered.
+func (a *API) relayMessage(from, to, roomCode, session string, seq uint64, payload json.RawMessage) (int, string) {
+    if len(payload) > maxMessagePayloadBytes {
+        return http.StatusRequestEntityTooLarge, "Payload too large"
+    }
+
+    if !a.messageLimiter.Allow(from) {
+        return http.StatusTooManyRequests, "Rate limit exceeded"
+    }
+
+    // Messages scoped to a room double as signaling, so enforce the room's
+    // permissions: at least one direction must be allowed to transfer files
+    if roomCode != "" {
+        if status, msg := a.checkSignalPermission(roomCode, from, to); status != http.StatusOK {
+            return status, msg
+        }
+    }
+
+    // Messages from a peer the recipient blocked are dropped without telling
+    // the sender, so a harasser can't tell it has been blocked
+    if a.isBlocked(to, from) {
+        return http.StatusOK, ""
+    }
+
+    if seq != 0 {
+        return a.relayOrdered(from, to, roomCode, session, seq, payload)
+    }
+    return a.deliverMessage(from, to, 0, payload)
+}
+
+// deliverMessage queues a message on the recipient's notification channel
+func (a *API) deliverMessage(from, to string, seq uint64, payload json.RawMessage) (int, string) {
+    queued := a.notifications.QueueLimited(to, notifications.Notification{
+        Type:      "message",
+        PeerID:    from,
+        Timestamp: time.Now().Unix(),
+        Seq:       seq,
+        Payload:   payload,
+    }, maxQueuedMessages)
+    if !queued {
+        return http.StatusServiceUnavailable, "Recipient message queue is full"
+    }
+
+    log.Printf("✉️  Message relayed: %s → %s (%d bytes)", from, to, len(payload))
+    return http.StatusOK, ""
+}
+
+// checkSignalPermission returns http.StatusOK if two peers in a room may
+// exchange signaling messages, or an error status and message otherwise
+func (a *API) checkSignalPermission(roomCode, from, to string) (int, string) {
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        return http.StatusNotFound, "Room not found"
+    }
+
+    room.RLock()
+    defer room.RUnlock()
+
+    sender, ok := room.Peers[from]
+    recipient, ok2 := room.Peers[to]
+    if !ok || !ok2 {
+        return http.StatusNotFound, "Peer not in room"
+    }
+    if room.Suspended {
+        return http.StatusLocked, "Room is suspended pending review"
+    }
+    if !rooms.CanTransfer(sender, recipient) && !rooms.CanTransfer(recipient, sender) {
+        return http.StatusForbidden, "Not permitted to signal this peer"
+    }
+    return http.StatusOK, ""
+}
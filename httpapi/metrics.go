@@ -0,0 +1,251 @@
+package httpapi
+
+import (
+    "fmt"
+    "net/http"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/jobs"
+)
+
+// Metric names served by /metrics. Every HTTP series is labelled with the
+// route template (route, e.g. "/room/:roomCode/files", or "unmatched") and
+// the method, so cardinality is bounded by the router; room codes and peer
+// IDs never appear in labels. cmd/dashgen builds the Grafana dashboards in
+// dashboards/ from these names.
+//
+// The SLI counters pair a total with a "good" count so burn-rate alerts are
+// a ratio of two rates, e.g. for availability over one hour:
+//
+//  1 - sum(rate(p2p_sli_availability_good_total[1h])) / sum(rate(p2p_sli_availability_total[1h]))
+//
+// divided by 1 - p2p_slo_objective{sli="availability"}.
+const (
+    // MetricRequests counts responses by route, method and code class
+    // (code: 2xx, 3xx, 4xx or 5xx)
+    MetricRequests = "p2p_http_requests_total"
+    // MetricDuration is a latency histogram by route and method
+    MetricDuration = "p2p_http_request_duration_seconds"
+    // MetricAvailability counts requests eligible for the availability SLI,
+    // and MetricAvailabilityGood those answered without a 5xx
+    MetricAvailability     = "p2p_sli_availability_total"
+    MetricAvailabilityGood = "p2p_sli_availability_good_total"
+    // MetricLatency counts successful requests eligible for the latency SLI,
+    // and MetricLatencyGood those answered within SLO_LATENCY_THRESHOLD
+    MetricLatency     = "p2p_sli_latency_total"
+    MetricLatencyGood = "p2p_sli_latency_good_total"
+    // MetricObjective is each SLI's target ratio (label sli)
+    MetricObjective = "p2p_slo_objective"
+    // MetricLatencyThreshold is SLO_LATENCY_THRESHOLD in seconds
+    MetricLatencyThreshold = "p2p_slo_latency_threshold_seconds"
+    // MetricRooms and MetricPeers are the current room and peer counts
+    MetricRooms = "p2p_rooms"
+    MetricPeers = "p2p_peers"
+    // MetricJobRuns and MetricJobFailures count each background job's
+    // finished and failed runs (label job), MetricJobDuration is its last
+    // run's duration and MetricJobLastSuccess when it last succeeded
+    MetricJobRuns        = "p2p_job_runs_total"
+    MetricJobFailures    = "p2p_job_failures_total"
+    MetricJobDuration    = "p2p_job_last_duration_seconds"
+    MetricJobLastSuccess = "p2p_job_last_success_timestamp_seconds"
+)
+
+const (
+    defaultAvailabilityObjective = 0.999
+    defaultLatencyObjective      = 0.99
+    defaultLatencyThreshold      = 500 * time.Millisecond
+)
+
+// durationBuckets are the latency histogram's upper bounds in seconds
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// streamingRoutes hold their response open, so their duration says nothing
+// about latency and they are left out of the latency SLI and histogram
+var streamingRoutes = map[string]bool{
+    "/admin/audit/stream": true,
+    WebTransportPath:      true,
+}
+
+// routeSeries are one route and method's counters
+type routeSeries struct {
+    classes   [6]uint64 // by status / 100
+    buckets   []uint64  // cumulative counts per durationBuckets, then +Inf
+    sum       float64
+    avail     uint64
+    availGood uint64
+    lat       uint64
+    latGood   uint64
+}
+
+// sliMetrics are the per-endpoint request counters behind /metrics
+type sliMetrics struct {
+    mu     sync.Mutex
+    series map[[2]string]*routeSeries
+
+    availabilityObjective float64
+    latencyObjective      float64
+    latencyThreshold      time.Duration
+}
+
+func loadSLIMetrics() *sliMetrics {
+    return &sliMetrics{
+        series:                make(map[[2]string]*routeSeries),
+        availabilityObjective: envFloat("SLO_AVAILABILITY_TARGET", defaultAvailabilityObjective),
+        latencyObjective:      envFloat("SLO_LATENCY_TARGET", defaultLatencyObjective),
+        latencyThreshold:      envDuration("SLO_LATENCY_THRESHOLD", defaultLatencyThreshold),
+    }
+}
+
+// recordMetrics counts every response for /metrics and the admin overview.
+// Registered ahead of panic recovery, so a panicking handler counts as the
+// 500 it becomes.
+func (a *API) recordMetrics() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        start := time.Now()
+        c.Next()
+        a.metrics.observe(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+        a.overview.request(c.Writer.Status())
+    }
+}
+
+func (m *sliMetrics) observe(method, route string, status int, latency time.Duration) {
+    if route == "" {
+        route = "unmatched"
+    }
+    class := min(max(status/100, 0), 5)
+    streaming := streamingRoutes[route]
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    s, ok := m.series[[2]string{route, method}]
+    if !ok {
+        s = &routeSeries{buckets: make([]uint64, len(durationBuckets)+1)}
+        m.series[[2]string{route, method}] = s
+    }
+    s.classes[class]++
+
+    // Client errors are the client's doing, and don't count against the
+    // server either way
+    if class == 4 {
+        return
+    }
+    s.avail++
+    if class != 5 {
+        s.availGood++
+    }
+    if streaming || class == 5 {
+        return
+    }
+    seconds := latency.Seconds()
+    for i, le := range durationBuckets {
+        if seconds <= le {
+            s.buckets[i]++
+        }
+    }
+    s.buckets[len(durationBuckets)]++
+    s.sum += seconds
+    s.lat++
+    if latency <= m.latencyThreshold {
+        s.latGood++
+    }
+}
+
+// serveMetrics serves the counters in the Prometheus text format. With
+// METRICS_TOKEN set, scrapers must send it as a bearer token.
+func (a *API) serveMetrics(c *gin.Context) {
+    if token := os.Getenv("METRICS_TOKEN"); token != "" && c.GetHeader("Authorization") != "Bearer "+token {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+        return
+    }
+
+    m := a.metrics
+    m.mu.Lock()
+    keys := make([][2]string, 0, len(m.series))
+    for key := range m.series {
+        keys = append(keys, key)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        if keys[i][0] != keys[j][0] {
+            return keys[i][0] < keys[j][0]
+        }
+        return keys[i][1] < keys[j][1]
+    })
+    series := make([]routeSeries, len(keys))
+    for i, key := range keys {
+        s := m.series[key]
+        series[i] = *s
+        series[i].buckets = append([]uint64(nil), s.buckets...)
+    }
+    m.mu.Unlock()
+
+    var b strings.Builder
+    counter := func(name, help string, value func(s *routeSeries) uint64) {
+        fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+        for i, key := range keys {
+            fmt.Fprintf(&b, "%s{%s} %d\n", name, routeLabels(key), value(&series[i]))
+        }
+    }
+
+    fmt.Fprintf(&b, "# HELP %s HTTP responses by route, method and status class.\n# TYPE %s counter\n", MetricRequests, MetricRequests)
+    for i, key := range keys {
+        for class := 1; class <= 5; class++ {
+            if n := series[i].classes[class]; n > 0 {
+                fmt.Fprintf(&b, "%s{%s,code=\"%dxx\"} %d\n", MetricRequests, routeLabels(key), class, n)
+            }
+        }
+    }
+
+    fmt.Fprintf(&b, "# HELP %s Latency of non-streaming, non-5xx responses.\n# TYPE %s histogram\n", MetricDuration, MetricDuration)
+    for i, key := range keys {
+        s := &series[i]
+        if s.lat == 0 {
+            continue
+        }
+        for j, le := range durationBuckets {
+            fmt.Fprintf(&b, "%s_bucket{%s,le=\"%s\"} %d\n", MetricDuration, routeLabels(key), strconv.FormatFloat(le, 'g', -1, 64), s.buckets[j])
+        }
+        fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", MetricDuration, routeLabels(key), s.buckets[len(durationBuckets)])
+        fmt.Fprintf(&b, "%s_sum{%s} %g\n", MetricDuration, routeLabels(key), s.sum)
+        fmt.Fprintf(&b, "%s_count{%s} %d\n", MetricDuration, routeLabels(key), s.lat)
+    }
+
+    counter(MetricAvailability, "Requests eligible for the availability SLI (all but 4xx).", func(s *routeSeries) uint64 { return s.avail })
+    counter(MetricAvailabilityGood, "Eligible requests answered without a 5xx.", func(s *routeSeries) uint64 { return s.availGood })
+    counter(MetricLatency, "Requests eligible for the latency SLI (non-streaming 1xx-3xx).", func(s *routeSeries) uint64 { return s.lat })
+    counter(MetricLatencyGood, "Eligible requests answered within the latency threshold.", func(s *routeSeries) uint64 { return s.latGood })
+
+    fmt.Fprintf(&b, "# HELP %s Target good ratio of each SLI.\n# TYPE %s gauge\n", MetricObjective, MetricObjective)
+    fmt.Fprintf(&b, "%s{sli=\"availability\"} %g\n", MetricObjective, m.availabilityObjective)
+    fmt.Fprintf(&b, "%s{sli=\"latency\"} %g\n", MetricObjective, m.latencyObjective)
+    fmt.Fprintf(&b, "# HELP %s Latency SLI threshold.\n# TYPE %s gauge\n%s %g\n", MetricLatencyThreshold, MetricLatencyThreshold, MetricLatencyThreshold, m.latencyThreshold.Seconds())
+
+    counts := a.currentHealthCounts()
+    fmt.Fprintf(&b, "# HELP %s Open rooms.\n# TYPE %s gauge\n%s %d\n", MetricRooms, MetricRooms, MetricRooms, counts.rooms)
+    fmt.Fprintf(&b, "# HELP %s Peers in rooms.\n# TYPE %s gauge\n%s %d\n", MetricPeers, MetricPeers, MetricPeers, counts.peers)
+
+    statuses := a.jobs.Jobs()
+    job := func(name, kind, help string, value func(s jobs.Status) float64) {
+        fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+        for _, s := range statuses {
+            fmt.Fprintf(&b, "%s{job=%s} %g\n", name, strconv.Quote(s.Name), value(s))
+        }
+    }
+    job(MetricJobRuns, "counter", "Finished runs of each background job.", func(s jobs.Status) float64 { return float64(s.Runs) })
+    job(MetricJobFailures, "counter", "Failed runs of each background job.", func(s jobs.Status) float64 { return float64(s.Failures) })
+    job(MetricJobDuration, "gauge", "Duration of each background job's last run.", func(s jobs.Status) float64 { return s.LastDurationMs / 1000 })
+    job(MetricJobLastSuccess, "gauge", "When each background job last succeeded.", func(s jobs.Status) float64 { return float64(s.LastSuccess) })
+
+    c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// routeLabels formats a series' route and method labels
+func routeLabels(key [2]string) string {
+    return "route=" + strconv.Quote(key[0]) + ",method=" + strconv.Quote(key[1])
+}
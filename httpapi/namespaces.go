@@ -0,0 +1,219 @@
+package httpapi
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Room namespaces keep frontends sharing one backend apart. With
+// ROOM_NAMESPACE_SECRET set, a room code sent with an Origin header is
+// stored under "<code>~<namespace>", the namespace being an HMAC of the
+// origin, so two frontends can each have a room "1234" without seeing each
+// other's peers. Clients only ever see their own codes: the suffix is added
+// to room codes in routes and bodies and stripped from responses.
+// Requests without an Origin, like native clients', share the namespace of
+// plain codes, and the admin API works on the full keys. Changing the secret
+// moves every frontend to a new namespace.
+const namespaceSep = "~"
+
+// namespaceKey is the context key holding the request's namespace
+const namespaceKey = "roomNamespace"
+
+// signedPaths are routes taking the signed links the server hands out,
+// whose room codes are already full keys. requireSignedURL checks them.
+var signedPaths = map[string]bool{
+    "/join/:roomCode":                     true,
+    "/room/:roomCode/files/:fileId/relay": true,
+}
+
+// roomNamespace returns the namespace of rooms created from origin, or ""
+// when namespaces are off or the request has no origin
+func roomNamespace(origin string) string {
+    secret := os.Getenv("ROOM_NAMESPACE_SECRET")
+    if secret == "" || origin == "" {
+        return ""
+    }
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(strings.ToLower(strings.TrimRight(origin, "/"))))
+    return hex.EncodeToString(mac.Sum(nil)[:6])
+}
+
+// namespacedCode returns the key a room code is stored under in namespace
+func namespacedCode(namespace, code string) string {
+    if namespace == "" || code == "" {
+        return code
+    }
+    return code + namespaceSep + namespace
+}
+
+// bareRoomCode returns the code clients know a room by
+func bareRoomCode(key string) string {
+    code, _, _ := strings.Cut(key, namespaceSep)
+    return code
+}
+
+// namespaceRooms moves the room codes of requests with an Origin into that
+// origin's namespace, and refuses room codes naming a namespace themselves
+func namespaceRooms() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if os.Getenv("ROOM_NAMESPACE_SECRET") == "" || strings.HasPrefix(c.FullPath(), "/admin/") {
+            c.Next()
+            return
+        }
+        namespace := roomNamespace(c.GetHeader("Origin"))
+        refused := gin.H{"error": "Room codes may not contain '" + namespaceSep + "'"}
+
+        signed := c.Request.Method == http.MethodGet && signedPaths[c.FullPath()] && c.Query("sig") != ""
+        if !signed {
+            for i, p := range c.Params {
+                if p.Key != "roomCode" {
+                    continue
+                }
+                if strings.Contains(p.Value, namespaceSep) {
+                    c.AbortWithStatusJSON(http.StatusBadRequest, refused)
+                    return
+                }
+                c.Params[i].Value = namespacedCode(namespace, p.Value)
+            }
+        }
+        if strings.Contains(c.Query("roomCode"), namespaceSep) {
+            c.AbortWithStatusJSON(http.StatusBadRequest, refused)
+            return
+        }
+        if !namespaceBody(c, namespace) {
+            c.AbortWithStatusJSON(http.StatusBadRequest, refused)
+            return
+        }
+
+        if namespace != "" {
+            c.Set(namespaceKey, namespace)
+            c.Writer = &namespaceWriter{ResponseWriter: c.Writer, namespace: namespace}
+        }
+        c.Next()
+    }
+}
+
+// namespaceBody rewrites every roomCode field of a body into namespace,
+// returning false if one already names a namespace. Handlers bind bodies as
+// JSON whatever their Content-Type, and match keys case-insensitively, so
+// so does this. Bodies that aren't JSON are left for the handler to reject.
+func namespaceBody(c *gin.Context, namespace string) bool {
+    if c.Request.Body == nil || c.Request.Body == http.NoBody || streamedBodies[c.FullPath()] {
+        return true
+    }
+    // limitBodies has already buffered and bounded the body
+    body, err := io.ReadAll(c.Request.Body)
+    c.Request.Body = io.NopCloser(bytes.NewReader(body))
+    if err != nil {
+        return true
+    }
+    dec := json.NewDecoder(bytes.NewReader(body))
+    dec.UseNumber()
+    var v any
+    if dec.Decode(&v) != nil {
+        return true
+    }
+
+    valid := true
+    var walk func(v any)
+    walk = func(v any) {
+        switch v := v.(type) {
+        case map[string]any:
+            for key, field := range v {
+                if code, ok := field.(string); ok && strings.EqualFold(key, "roomCode") {
+                    if strings.Contains(code, namespaceSep) {
+                        valid = false
+                    }
+                    v[key] = namespacedCode(namespace, code)
+                    continue
+                }
+                walk(field)
+            }
+        case []any:
+            for _, item := range v {
+                walk(item)
+            }
+        }
+    }
+    walk(v)
+    if !valid || namespace == "" {
+        return valid
+    }
+
+    rewritten, err := json.Marshal(v)
+    if err != nil {
+        return true
+    }
+    c.Request.Body = io.NopCloser(bytes.NewReader(rewritten))
+    c.Request.ContentLength = int64(len(rewritten))
+    return true
+}
+
+// stripNamespace removes a namespace from the room codes in JSON data
+func stripNamespace(data []byte, namespace string) []byte {
+    if namespace == "" {
+        return data
+    }
+    return bytes.ReplaceAll(data, []byte(namespaceSep+namespace+`"`), []byte(`"`))
+}
+
+// stripNamespaceValue removes a namespace from the room codes in a decoded
+// JSON value, for responses encoded some other way. Like stripNamespace, it
+// only touches whole strings ending in the namespace.
+func stripNamespaceValue(v any, namespace string) any {
+    if namespace == "" {
+        return v
+    }
+    switch v := v.(type) {
+    case string:
+        return strings.TrimSuffix(v, namespaceSep+namespace)
+    case map[string]any:
+        for k, e := range v {
+            v[k] = stripNamespaceValue(e, namespace)
+        }
+    case []any:
+        for i, e := range v {
+            v[i] = stripNamespaceValue(e, namespace)
+        }
+    }
+    return v
+}
+
+// namespaceWriter strips the request's namespace from room codes in JSON
+// and event stream responses. Only whole JSON strings ending in the
+// namespace are touched, so signed links carrying a full key keep working.
+// MessagePack and CBOR responses are stripped by respond before encoding.
+type namespaceWriter struct {
+    gin.ResponseWriter
+    namespace string
+}
+
+func (w *namespaceWriter) Write(data []byte) (int, error) {
+    contentType := w.Header().Get("Content-Type")
+    if w.Header().Get("Content-Encoding") != "" ||
+        !(strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "text/event-stream")) {
+        return w.ResponseWriter.Write(data)
+    }
+    stripped := stripNamespace(data, w.namespace)
+    if len(stripped) == len(data) {
+        return w.ResponseWriter.Write(data)
+    }
+    w.Header().Del("Content-Length")
+    if _, err := w.ResponseWriter.Write(stripped); err != nil {
+        return 0, err
+    }
+    return len(data), nil
+}
+
+func (w *namespaceWriter) WriteString(s string) (int, error) {
+    return w.Write([]byte(s))
+}
@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/ugorji/go/codec"
+)
+
+func TestNamespaceBody(t *testing.T) {
+    t.Setenv("ROOM_NAMESPACE_SECRET", "secret")
+
+    tests := []struct {
+        name        string
+        contentType string
+        body        string
+        want        int
+    }{
+        {"json", "application/json", `{"roomCode":"1234~abc","peerId":"p"}`, http.StatusBadRequest},
+        {"text/plain", "text/plain", `{"roomCode":"1234~abc","peerId":"p"}`, http.StatusBadRequest},
+        {"no content type", "", `{"roomCode":"1234~abc","peerId":"p"}`, http.StatusBadRequest},
+        {"upper-case key", "text/plain", `{"ROOMCODE":"1234~abc","peerId":"p"}`, http.StatusBadRequest},
+        {"escaped key", "application/json", `{"\u0072oomCode":"1234~abc","peerId":"p"}`, http.StatusBadRequest},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, h := newTestRouter(t)
+            w := request(h, http.MethodPost, "/room/join", tt.contentType, "", tt.body)
+            if w.Code != tt.want || !strings.Contains(w.Body.String(), "may not contain") {
+                t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+            }
+        })
+    }
+}
+
+func TestNamespaceIsolation(t *testing.T) {
+    t.Setenv("ROOM_NAMESPACE_SECRET", "secret")
+    _, h := newTestRouter(t)
+
+    send := func(method, path, origin, accept, contentType, body string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(method, path, strings.NewReader(body))
+        req.Header.Set("Origin", origin)
+        if accept != "" {
+            req.Header.Set("Accept", accept)
+        }
+        if contentType != "" {
+            req.Header.Set("Content-Type", contentType)
+        }
+        w := httptest.NewRecorder()
+        h.ServeHTTP(w, req)
+        return w
+    }
+
+    // A body sent as text/plain with an odd key case is still namespaced
+    w := send(http.MethodPost, "/room/create", "https://a.example", "", "text/plain", `{"RoomCode":"1234","peerId":"host","waitingRoom":true}`)
+    if w.Code != http.StatusOK {
+        t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+    }
+    if w := send(http.MethodGet, "/room/1234", "https://b.example", "", "", ""); w.Code != http.StatusNotFound {
+        t.Fatalf("other origin: status = %d, want 404", w.Code)
+    }
+
+    // The denial notification's payload carries the room code
+    for _, tt := range []struct {
+        accept string
+        handle codec.Handle
+    }{
+        {"application/json", &codec.JsonHandle{}},
+        {mimeMsgPack, msgpackHandle},
+        {mimeCBOR, cborHandle},
+    } {
+        if w := send(http.MethodPost, "/room/join", "https://a.example", "", "application/json", `{"roomCode":"1234","peerId":"guest"}`); w.Code != http.StatusAccepted {
+            t.Fatalf("join: status = %d: %s", w.Code, w.Body)
+        }
+        if w := send(http.MethodPost, "/room/1234/approve", "https://a.example", "", "application/json", `{"hostId":"host","peerId":"guest","approve":false}`); w.Code != http.StatusOK {
+            t.Fatalf("deny: status = %d: %s", w.Code, w.Body)
+        }
+
+        w := send(http.MethodGet, "/notifications/guest", "https://a.example", tt.accept, "", "")
+        if w.Code != http.StatusOK {
+            t.Fatalf("%s: status = %d: %s", tt.accept, w.Code, w.Body)
+        }
+        var decoded any
+        if err := codec.NewDecoderBytes(w.Body.Bytes(), tt.handle).Decode(&decoded); err != nil {
+            t.Fatalf("%s: decode: %v", tt.accept, err)
+        }
+        if !strings.Contains(fmt.Sprint(decoded), "1234") {
+            t.Fatalf("%s: no room code in %v", tt.accept, decoded)
+        }
+        if key := findNamespaced(decoded); key != "" {
+            t.Errorf("%s: response leaks namespaced key %q", tt.accept, key)
+        }
+    }
+}
+
+// findNamespaced returns the first string in v carrying a namespace
+func findNamespaced(v any) string {
+    switch v := v.(type) {
+    case string:
+        if strings.Contains(v, namespaceSep) {
+            return v
+        }
+    case []byte:
+        return findNamespaced(string(v))
+    case map[any]any:
+        for k, e := range v {
+            if s := findNamespaced(k); s != "" {
+                return s
+            }
+            if s := findNamespaced(e); s != "" {
+                return s
+            }
+        }
+    case map[string]any:
+        for _, e := range v {
+            if s := findNamespaced(e); s != "" {
+                return s
+            }
+        }
+    case []any:
+        for _, e := range v {
+            if s := findNamespaced(e); s != "" {
+                return s
+            }
+        }
+    }
+    return ""
+}
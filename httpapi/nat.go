@@ -0,0 +1,183 @@
+package httpapi
+
+import (
+    "context"
+    "encoding/json"
+    "log"
+    "net"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    natEchoPerMinute = 120 // datagrams answered per source IP
+    natMinProbeBytes = 128 // room for the reply to an IPv6 source
+    maxNATMappings   = 8
+)
+
+// NAT classifications returned by POST /nat/classify
+const (
+    natOpen       = "open"
+    natCone       = "cone"
+    natSymmetric  = "symmetric"
+    natUDPBlocked = "udp_blocked"
+    natUnknown    = "unknown"
+)
+
+// natEchoPorts reads NAT_ECHO_PORTS, a comma-separated list of UDP ports to
+// answer echo probes on. Two or more are needed to spot symmetric NATs.
+func natEchoPorts() []int {
+    var ports []int
+    for _, field := range strings.Split(os.Getenv("NAT_ECHO_PORTS"), ",") {
+        field = strings.TrimSpace(field)
+        if field == "" {
+            continue
+        }
+        port, err := strconv.Atoi(field)
+        if err != nil || port <= 0 || port > 65535 {
+            log.Printf("⚠️  Invalid NAT_ECHO_PORTS entry %q", field)
+            continue
+        }
+        ports = append(ports, port)
+    }
+    return ports
+}
+
+// runNATEcho answers each UDP datagram on port with the address it came
+// from, as seen by the server, until ctx is cancelled. Replies are never
+// larger than the probe, so the listener can't be used for amplification.
+func (a *API) runNATEcho(ctx context.Context, port int) {
+    conn, err := net.ListenPacket("udp", ":"+strconv.Itoa(port))
+    if err != nil {
+        log.Printf("❌ NAT echo listener on UDP %d failed: %v", port, err)
+        return
+    }
+    go func() {
+        <-ctx.Done()
+        conn.Close()
+    }()
+    log.Printf("🧭 NAT echo listening on UDP %d", port)
+
+    buf := make([]byte, 1500)
+    for {
+        n, addr, err := conn.ReadFrom(buf)
+        if err != nil {
+            if ctx.Err() != nil {
+                return
+            }
+            continue
+        }
+        udpAddr, ok := addr.(*net.UDPAddr)
+        if !ok || !a.natEchoLimiter.Allow(udpAddr.IP.String()) {
+            continue
+        }
+
+        reply, _ := json.Marshal(gin.H{"mapped": udpAddr.String(), "port": port})
+        if len(reply) > n {
+            continue
+        }
+        conn.WriteTo(reply, addr)
+    }
+}
+
+// natInfo tells a client how to probe its NAT: the public IP it reached us
+// from and the UDP echo ports to send probes to
+func (a *API) natInfo(c *gin.Context) {
+    ports := a.natPorts
+    if ports == nil {
+        ports = []int{}
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "observedIp": c.ClientIP(),
+        "echoPorts":  ports,
+        // Probes shorter than the reply are ignored
+        "minProbeBytes": natMinProbeBytes,
+    })
+}
+
+// classifyNAT turns a client's probe results into a NAT type and a TURN
+// recommendation. Mappings are the public addresses the client saw for one
+// local socket, from the UDP echo ports or from STUN servers.
+func (a *API) classifyNAT(c *gin.Context) {
+    var req struct {
+        // HostAddresses are the client's local IPs, from host ICE candidates
+        HostAddresses []string `json:"hostAddresses"`
+        Mappings      []struct {
+            Via    string `json:"via"`
+            Mapped string `json:"mapped" binding:"required"`
+        } `json:"mappings" binding:"dive"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    if len(req.Mappings) > maxNATMappings {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "at most " + strconv.Itoa(maxNATMappings) + " mappings"})
+        return
+    }
+
+    mapped := make([]*net.UDPAddr, 0, len(req.Mappings))
+    for _, m := range req.Mappings {
+        addr, err := net.ResolveUDPAddr("udp", m.Mapped)
+        if err != nil || addr.IP == nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "mapped must be an ip:port address"})
+            return
+        }
+        mapped = append(mapped, addr)
+    }
+
+    natType := classifyMappings(mapped, req.HostAddresses)
+    turnRequired, recommendation := natRecommendation(natType)
+    c.JSON(http.StatusOK, gin.H{
+        "natType":        natType,
+        "turnRequired":   turnRequired,
+        "recommendation": recommendation,
+        "observedIp":     c.ClientIP(),
+    })
+}
+
+// classifyMappings compares the public addresses one local socket was seen
+// from. A NAT that keeps the same mapping for every destination is cone-like
+// and hole punching works; one that picks a new port per destination is
+// symmetric.
+func classifyMappings(mapped []*net.UDPAddr, hostAddresses []string) string {
+    if len(mapped) == 0 {
+        return natUDPBlocked
+    }
+
+    for _, host := range hostAddresses {
+        if ip := net.ParseIP(host); ip != nil && ip.Equal(mapped[0].IP) {
+            return natOpen
+        }
+    }
+    if len(mapped) < 2 {
+        return natUnknown
+    }
+
+    for _, addr := range mapped[1:] {
+        if !addr.IP.Equal(mapped[0].IP) || addr.Port != mapped[0].Port {
+            return natSymmetric
+        }
+    }
+    return natCone
+}
+
+func natRecommendation(natType string) (bool, string) {
+    switch natType {
+    case natOpen:
+        return false, "No NAT detected; direct connections should succeed."
+    case natCone:
+        return false, "Hole punching should work; TURN is only needed if the other peer is behind a symmetric NAT."
+    case natSymmetric:
+        return true, "Symmetric NAT: direct connections rarely succeed, so start with TURN relay candidates."
+    case natUDPBlocked:
+        return true, "UDP appears blocked; use TURN over TCP or TLS."
+    default:
+        return false, "Not enough probes to classify; send probes to at least two echo ports or STUN servers."
+    }
+}
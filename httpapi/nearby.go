@@ -0,0 +1,370 @@
+package httpapi
+
+import (
+    "log"
+    "net"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    defaultNearbyTTL = 2 * time.Minute
+    maxNearbyTTL     = 10 * time.Minute
+    // nearbyRequestTTL is how long a connection request can be answered
+    nearbyRequestTTL        = 2 * time.Minute
+    nearbyRequestsPerMinute = 10 // per requesting peer
+    maxNearbyPeers          = 50000
+    nearbyRoomPrefix        = "near-"
+    // nearbyScope keeps nearby locality hashes apart from room LAN hints
+    nearbyScope = "\x00nearby"
+)
+
+// nearbyPeer is a peer advertising itself to others on its network
+type nearbyPeer struct {
+    PeerID      string `json:"peerId"`
+    DisplayName string `json:"displayName"`
+    Platform    string `json:"platform,omitempty"`
+    ExpiresAt   int64  `json:"expiresAt"`
+
+    network string
+}
+
+// nearbyRequest is a connection request waiting for the target's answer
+type nearbyRequest struct {
+    Profile   rooms.PeerProfile
+    ExpiresAt time.Time
+}
+
+// nearbyPool is the opt-in discovery pool. Peers are only visible while
+// they advertise, only to requests from the same coarse network, and only
+// by the display name they chose.
+type nearbyPool struct {
+    mu    sync.Mutex
+    peers map[string]*nearbyPeer
+    // requests are pending connection requests by target, then requester
+    requests map[string]map[string]*nearbyRequest
+}
+
+// nearbyNetwork is the coarse locality a client is grouped by: its public
+// IPv4 address, which a household or office shares behind NAT, or its IPv6
+// /64, which is usually one LAN
+func nearbyNetwork(clientIP string) string {
+    ip := net.ParseIP(clientIP)
+    if ip == nil {
+        return networkHash(nearbyScope, clientIP)
+    }
+    if ip.To4() == nil {
+        ip = ip.Mask(net.CIDRMask(64, 128))
+    }
+    return networkHash(nearbyScope, ip.String())
+}
+
+// advertiseNearby makes the bearer-token peer visible to others on its
+// network for ttl seconds (default 120, at most 600). Advertising again
+// refreshes it.
+func (a *API) advertiseNearby(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    tagRequest(c, "", peerID)
+
+    var req struct {
+        DisplayName string `json:"displayName" binding:"required"`
+        Platform    string `json:"platform"`
+        TTL         int    `json:"ttl"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    profile := rooms.PeerProfile{DisplayName: strings.TrimSpace(req.DisplayName), Platform: req.Platform}
+    if err := profile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if profile.DisplayName == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "displayName is required"})
+        return
+    }
+    ttl := defaultNearbyTTL
+    if req.TTL > 0 {
+        ttl = min(time.Duration(req.TTL)*time.Second, maxNearbyTTL)
+    }
+
+    entry := &nearbyPeer{
+        PeerID:      peerID,
+        DisplayName: profile.DisplayName,
+        Platform:    profile.Platform,
+        ExpiresAt:   time.Now().Add(ttl).Unix(),
+        network:     nearbyNetwork(c.ClientIP()),
+    }
+
+    a.nearby.mu.Lock()
+    if _, exists := a.nearby.peers[peerID]; !exists && len(a.nearby.peers) >= maxNearbyPeers {
+        a.nearby.mu.Unlock()
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many peers nearby, try again shortly"})
+        return
+    }
+    a.nearby.peers[peerID] = entry
+    a.nearby.mu.Unlock()
+
+    c.JSON(http.StatusOK, entry)
+}
+
+// stopAdvertisingNearby hides the bearer-token peer again
+func (a *API) stopAdvertisingNearby(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    tagRequest(c, "", peerID)
+
+    a.eraseNearby(peerID)
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// getNearbyPeers lists the peers advertising on the requester's network.
+// Browsing does not require advertising.
+func (a *API) getNearbyPeers(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    tagRequest(c, "", peerID)
+
+    network := nearbyNetwork(c.ClientIP())
+    now := time.Now().Unix()
+    blocked, _ := a.blocked(peerID)
+
+    a.nearby.mu.Lock()
+    peers := make([]nearbyPeer, 0)
+    for id, p := range a.nearby.peers {
+        // Blocks hide peers both ways
+        if id != peerID && p.network == network && p.ExpiresAt > now && !blocked[id] && !a.isBlocked(id, peerID) {
+            peers = append(peers, *p)
+        }
+    }
+    a.nearby.mu.Unlock()
+
+    sort.Slice(peers, func(i, j int) bool {
+        if peers[i].DisplayName != peers[j].DisplayName {
+            return peers[i].DisplayName < peers[j].DisplayName
+        }
+        return peers[i].PeerID < peers[j].PeerID
+    })
+
+    c.JSON(http.StatusOK, gin.H{"peers": peers})
+}
+
+// requestNearby asks a peer advertising on the requester's network to
+// connect. The target gets a nearby_request event and answers with
+// POST /nearby/requests/:peerId/accept or decline.
+func (a *API) requestNearby(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    target := c.Param("peerId")
+    tagRequest(c, "", peerID)
+
+    var req struct {
+        rooms.PeerProfile
+    }
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            invalidRequest(c, err)
+            return
+        }
+    }
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if target == peerID {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A peer cannot connect to itself"})
+        return
+    }
+    if !a.nearbyLimiter.Allow(peerID) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+        return
+    }
+
+    network := nearbyNetwork(c.ClientIP())
+    expiresAt := time.Now().Add(nearbyRequestTTL)
+
+    a.nearby.mu.Lock()
+    p, ok := a.nearby.peers[target]
+    if !ok || p.network != network || p.ExpiresAt <= time.Now().Unix() {
+        a.nearby.mu.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "Peer is not nearby"})
+        return
+    }
+    // A target that blocked the requester never hears of it
+    blocked := a.isBlocked(target, peerID)
+    if !blocked {
+        pending := a.nearby.requests[target]
+        if pending == nil {
+            pending = make(map[string]*nearbyRequest)
+            a.nearby.requests[target] = pending
+        }
+        pending[peerID] = &nearbyRequest{Profile: req.PeerProfile, ExpiresAt: expiresAt}
+    }
+    a.nearby.mu.Unlock()
+
+    if !blocked {
+        a.queueNotification(target, notifications.Notification{
+            Type:      "nearby_request",
+            PeerID:    peerID,
+            Timestamp: time.Now().Unix(),
+            Payload: notifications.Payload(gin.H{
+                "displayName": req.DisplayName,
+                "platform":    req.Platform,
+                "expiresAt":   expiresAt.Unix(),
+            }),
+        })
+    }
+
+    c.JSON(http.StatusAccepted, gin.H{"peerId": target, "expiresAt": expiresAt.Unix()})
+}
+
+// answerNearby accepts or declines a connection request. Accepting opens a
+// locked room holding just the two peers, as pairing does, and returns the
+// same response as joining it; the requester gets nearby_accepted with the
+// room code, or nearby_declined.
+func (a *API) answerNearby(accept bool) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        peerID, ok := authenticatedPeer(c)
+        if !ok {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+            return
+        }
+        requester := c.Param("peerId")
+        tagRequest(c, "", peerID)
+
+        var req struct {
+            rooms.PeerProfile
+        }
+        if c.Request.ContentLength != 0 {
+            if err := c.ShouldBindJSON(&req); err != nil {
+                invalidRequest(c, err)
+                return
+            }
+        }
+        if err := req.PeerProfile.Validate(); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+
+        a.nearby.mu.Lock()
+        request, ok := a.nearby.requests[peerID][requester]
+        if ok {
+            delete(a.nearby.requests[peerID], requester)
+            if len(a.nearby.requests[peerID]) == 0 {
+                delete(a.nearby.requests, peerID)
+            }
+        }
+        a.nearby.mu.Unlock()
+        if !ok || time.Now().After(request.ExpiresAt) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "No connection request from that peer"})
+            return
+        }
+
+        if !accept {
+            a.queueNotification(requester, notifications.Notification{
+                Type:      "nearby_declined",
+                PeerID:    peerID,
+                Timestamp: time.Now().Unix(),
+            })
+            c.JSON(http.StatusOK, gin.H{"peerId": requester, "accepted": false})
+            return
+        }
+
+        roomCode, room, ok := a.createPrivateRoom(c, nearbyRoomPrefix, requester)
+        if !ok {
+            return
+        }
+        room.Lock()
+        requesterRole, _ := admitPeer(room, requester, request.Profile)
+        role, permissions := admitPeer(room, peerID, req.PeerProfile)
+        peers := room.ConnectedPeers(peerID)
+        roomSize := len(room.Peers)
+        room.Unlock()
+
+        a.recordRecentRoom(requester, roomCode, requesterRole)
+        a.recordRecentRoom(peerID, roomCode, role)
+        log.Printf("📡 Nearby connection: %s ↔ %s in Room: %s", requester, peerID, roomCode)
+        a.recordAudit(roomCode, "nearby_connected", peerID, requester, nil)
+
+        a.queueNotification(requester, notifications.Notification{
+            Type:      "nearby_accepted",
+            PeerID:    peerID,
+            Timestamp: time.Now().Unix(),
+            Payload: notifications.Payload(gin.H{
+                "roomCode":    roomCode,
+                "resumeToken": a.issueResumeToken(roomCode, requester),
+            }),
+        })
+
+        c.JSON(http.StatusOK, gin.H{
+            "roomCode":    roomCode,
+            "accepted":    true,
+            "peers":       peers,
+            "roomSize":    roomSize,
+            "role":        role,
+            "permissions": permissions,
+            "resumeToken": a.issueResumeToken(roomCode, peerID),
+        })
+    }
+}
+
+// pruneNearby drops expired advertisements and requests
+func (a *API) pruneNearby() {
+    now := time.Now()
+    a.nearby.mu.Lock()
+    defer a.nearby.mu.Unlock()
+    for id, p := range a.nearby.peers {
+        if p.ExpiresAt <= now.Unix() {
+            delete(a.nearby.peers, id)
+        }
+    }
+    for target, pending := range a.nearby.requests {
+        for requester, r := range pending {
+            if now.After(r.ExpiresAt) {
+                delete(pending, requester)
+            }
+        }
+        if len(pending) == 0 {
+            delete(a.nearby.requests, target)
+        }
+    }
+}
+
+// eraseNearby stops peerID advertising and drops requests to or from it,
+// returning whether it was advertising
+func (a *API) eraseNearby(peerID string) bool {
+    a.nearby.mu.Lock()
+    defer a.nearby.mu.Unlock()
+    _, advertising := a.nearby.peers[peerID]
+    delete(a.nearby.peers, peerID)
+    delete(a.nearby.requests, peerID)
+    for target, pending := range a.nearby.requests {
+        delete(pending, peerID)
+        if len(pending) == 0 {
+            delete(a.nearby.requests, target)
+        }
+    }
+    return advertising
+}
@@ -0,0 +1,129 @@
+package httpapi
+
+import (
+    "net/http"
+    "strconv"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// getNotifications drains a peer's queue. Clients on constrained links can
+// ask for ?prioritized=true to get signaling first and telemetry last.
+func (a *API) getNotifications(c *gin.Context) {
+    peerID := c.Param("peerId")
+
+    var pending []notifications.Notification
+    if c.Query("prioritized") == "true" {
+        pending = a.notifications.DrainPrioritized(peerID)
+    } else {
+        pending = a.notifications.Drain(peerID)
+    }
+
+    respond(c, http.StatusOK, gin.H{
+        "notifications": pending,
+    })
+}
+
+// queueNotification hands a notification to the dispatcher for delivery to
+// a peer's pending queue
+func (a *API) queueNotification(peerID string, n notifications.Notification) {
+    a.dispatcher.Send(peerID, n)
+}
+
+// notifyRoom queues a notification for every peer in the room except the sender
+func (a *API) notifyRoom(room *rooms.Room, exceptPeer string, n notifications.Notification) {
+    room.Lock()
+    n = roomEvent(room, exceptPeer, n)
+    recipients := make([]string, 0, len(room.Peers))
+    for peerID := range room.Peers {
+        if peerID != exceptPeer {
+            recipients = append(recipients, peerID)
+        }
+    }
+    room.Unlock()
+
+    a.dispatcher.Fanout(recipients, n)
+}
+
+// roomEvent records a room-wide notification in the room's replay log and
+// returns it stamped with its sequence number. The caller must hold the
+// room lock.
+func roomEvent(room *rooms.Room, exceptPeer string, n notifications.Notification) notifications.Notification {
+    n.RoomSeq = room.RecordEvent(rooms.RoomEvent{
+        Type:      n.Type,
+        PeerID:    n.PeerID,
+        Timestamp: n.Timestamp,
+        Payload:   n.Payload,
+        Except:    exceptPeer,
+    })
+    return n
+}
+
+// replayRoomEvents returns the room events after since that peerID should
+// have received, as notifications, and the room's latest sequence number.
+// ok is false when since is older than the retained history.
+func replayRoomEvents(room *rooms.Room, peerID string, since uint64) (replay []notifications.Notification, latest uint64, ok bool) {
+    room.RLock()
+    defer room.RUnlock()
+
+    latest = room.EventSeq()
+    events, ok := room.EventsSince(since, peerID)
+    if !ok {
+        return nil, latest, false
+    }
+    replay = make([]notifications.Notification, 0, len(events))
+    for _, e := range events {
+        replay = append(replay, notifications.Notification{
+            Type:      e.Type,
+            PeerID:    e.PeerID,
+            Timestamp: e.Timestamp,
+            RoomSeq:   e.Seq,
+            Payload:   e.Payload,
+        })
+    }
+    return replay, latest, true
+}
+
+// getRoomEvents replays the room events after ?since= for a reconnecting
+// peer, so it can catch up without refetching the peer list. When since is
+// older than the retained history the response says to resync instead.
+func (a *API) getRoomEvents(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    peerID := c.Query("peerId")
+    tagRequest(c, roomCode, peerID)
+
+    since, err := strconv.ParseUint(c.Query("since"), 10, 64)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "since must be a room event sequence number"})
+        return
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    _, member := room.Peers[peerID]
+    room.RUnlock()
+    if !member {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Peer not in room"})
+        return
+    }
+
+    events, latest, ok := replayRoomEvents(room, peerID, since)
+    if !ok {
+        c.JSON(http.StatusOK, gin.H{"resync": true, "roomSeq": latest})
+        return
+    }
+    respond(c, http.StatusOK, gin.H{"events": events, "roomSeq": latest})
+}
+
+// getDispatcherStats reports notification fan-out backlog and backpressure
+func (a *API) getDispatcherStats(c *gin.Context) {
+    c.JSON(http.StatusOK, a.dispatcher.Stats())
+}
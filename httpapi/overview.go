@@ -0,0 +1,154 @@
+package httpapi
+
+import (
+    "context"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    // overviewBucket is the width of one point in /admin/overview's series
+    overviewBucket = time.Minute
+    // overviewBuckets is how many points are kept: six hours of minutes
+    overviewBuckets     = 360
+    defaultOverviewMins = 60
+    // overviewSampleEvery is how often room and peer counts are sampled
+    overviewSampleEvery = 10 * time.Second
+)
+
+// Counters kept per overview bucket
+const (
+    overviewRoomsCreated = iota
+    overviewJoins
+    overviewTurnFetches
+    overviewRequests
+    overviewServerErrors
+    overviewClientErrors
+    overviewCounters
+)
+
+// overviewPoint is one bucket of the operator overview. Counters are
+// totals over the bucket; rooms and peers are the highest sampled.
+type overviewPoint struct {
+    start    int64
+    counters [overviewCounters]int64
+    rooms    int
+    peers    int
+}
+
+// overviewSeries is a ring buffer of the last overviewBuckets buckets
+type overviewSeries struct {
+    mu     sync.Mutex
+    points [overviewBuckets]overviewPoint
+}
+
+// bucket returns the point for now, resetting it if it last held an older
+// bucket. The caller must hold s.mu.
+func (s *overviewSeries) bucket(now time.Time) *overviewPoint {
+    start := now.Truncate(overviewBucket).Unix()
+    p := &s.points[(start/int64(overviewBucket.Seconds()))%overviewBuckets]
+    if p.start != start {
+        *p = overviewPoint{start: start}
+    }
+    return p
+}
+
+// count adds one to counter in the current bucket
+func (s *overviewSeries) count(counter int) {
+    s.mu.Lock()
+    s.bucket(time.Now()).counters[counter]++
+    s.mu.Unlock()
+}
+
+// request counts a response by its status
+func (s *overviewSeries) request(status int) {
+    s.mu.Lock()
+    p := s.bucket(time.Now())
+    p.counters[overviewRequests]++
+    switch {
+    case status >= 500:
+        p.counters[overviewServerErrors]++
+    case status >= 400:
+        p.counters[overviewClientErrors]++
+    }
+    s.mu.Unlock()
+}
+
+// sample records the current room and peer counts
+func (s *overviewSeries) sample(rooms, peers int) {
+    s.mu.Lock()
+    p := s.bucket(time.Now())
+    p.rooms = max(p.rooms, rooms)
+    p.peers = max(p.peers, peers)
+    s.mu.Unlock()
+}
+
+// sampleOverview records room and peer counts for the overview, every
+// overviewSampleEvery
+func (a *API) sampleOverview(ctx context.Context) error {
+    counts := a.currentHealthCounts()
+    a.overview.sample(counts.rooms, counts.peers)
+    return nil
+}
+
+// getOverview serves the operator dashboard's live series: per-minute rooms
+// created, joins, active peers and rooms, TURN credential fetches, requests
+// and error rates for the last ?minutes= (default 60, at most six hours).
+// Minutes without data are zero, so the series are contiguous.
+func (a *API) getOverview(c *gin.Context) {
+    minutes := defaultOverviewMins
+    if v := c.Query("minutes"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n <= 0 || n > overviewBuckets {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "minutes must be between 1 and " + strconv.Itoa(overviewBuckets)})
+            return
+        }
+        minutes = n
+    }
+
+    now := time.Now().Truncate(overviewBucket)
+    width := int64(overviewBucket.Seconds())
+    series := make([]gin.H, 0, minutes)
+
+    a.overview.mu.Lock()
+    for i := minutes - 1; i >= 0; i-- {
+        start := now.Unix() - int64(i)*width
+        p := a.overview.points[(start/width)%overviewBuckets]
+        if p.start != start {
+            p = overviewPoint{start: start}
+        }
+        requests := p.counters[overviewRequests]
+        var errorRate float64
+        if requests > 0 {
+            errorRate = float64(p.counters[overviewServerErrors]) / float64(requests)
+        }
+        series = append(series, gin.H{
+            "t":            start,
+            "roomsCreated": p.counters[overviewRoomsCreated],
+            "joins":        p.counters[overviewJoins],
+            "activeRooms":  p.rooms,
+            "activePeers":  p.peers,
+            "turnFetches":  p.counters[overviewTurnFetches],
+            "requests":     requests,
+            "serverErrors": p.counters[overviewServerErrors],
+            "clientErrors": p.counters[overviewClientErrors],
+            "errorRate":    errorRate,
+        })
+    }
+    a.overview.mu.Unlock()
+
+    counts := a.currentHealthCounts()
+    c.JSON(http.StatusOK, gin.H{
+        "bucketSeconds": width,
+        "series":        series,
+        "current": gin.H{
+            "rooms":    counts.rooms,
+            "peers":    counts.peers,
+            "draining": a.draining(),
+        },
+    })
+}
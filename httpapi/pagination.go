@@ -0,0 +1,137 @@
+package httpapi
+
+import (
+    "net/http"
+    "sort"
+    "strconv"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    defaultPageSize = 100
+    maxPageSize     = 500
+)
+
+// pageLimit parses the limit query parameter, answering 400 if it is invalid
+func pageLimit(c *gin.Context) (int, bool) {
+    limit := defaultPageSize
+    if v := c.Query("limit"); v != "" {
+        var err error
+        limit, err = strconv.Atoi(v)
+        if err != nil || limit < 1 || limit > maxPageSize {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+            return 0, false
+        }
+    }
+    return limit, true
+}
+
+// queryUnix parses an optional unix-timestamp query parameter
+func queryUnix(c *gin.Context, name string) (int64, bool) {
+    v := c.Query(name)
+    if v == "" {
+        return 0, true
+    }
+    ts, err := strconv.ParseInt(v, 10, 64)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a unix timestamp"})
+        return 0, false
+    }
+    return ts, true
+}
+
+// RoomSummary is the admin view of a room
+type RoomSummary struct {
+    RoomCode  string `json:"roomCode"`
+    Mode      string `json:"mode"`
+    HostID    string `json:"hostId"`
+    Peers     int    `json:"peers"`
+    Files     int    `json:"files"`
+    CreatedAt int64  `json:"createdAt"`
+    Suspended bool   `json:"suspended"`
+    Pinned    bool   `json:"pinned"`
+}
+
+// summarizeRoom builds the admin summary of a room. The caller must not hold
+// the room lock.
+func summarizeRoom(code string, room *rooms.Room) RoomSummary {
+    room.RLock()
+    defer room.RUnlock()
+
+    return RoomSummary{
+        RoomCode:  code,
+        Mode:      room.Mode,
+        HostID:    room.HostID,
+        Peers:     len(room.Peers),
+        Files:     len(room.Files),
+        CreatedAt: room.CreatedAt,
+        Suspended: room.Suspended,
+        Pinned:    room.Pinned,
+    }
+}
+
+// listRooms serves GET /admin/rooms with cursor pagination and filters on
+// mode, suspension, minimum size and creation time
+func (a *API) listRooms(c *gin.Context) {
+    limit, ok := pageLimit(c)
+    if !ok {
+        return
+    }
+    createdAfter, ok := queryUnix(c, "createdAfter")
+    if !ok {
+        return
+    }
+    minPeers := 0
+    if v := c.Query("minPeers"); v != "" {
+        var err error
+        if minPeers, err = strconv.Atoi(v); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "minPeers must be an integer"})
+            return
+        }
+    }
+    mode := c.Query("mode")
+    suspended := c.Query("suspended")
+    cursor := c.Query("cursor")
+
+    var summaries []RoomSummary
+    total := 0
+    a.rooms.Range(func(code string, room *rooms.Room) bool {
+        total++
+        if code <= cursor {
+            return true
+        }
+        summary := summarizeRoom(code, room)
+        if mode != "" && summary.Mode != mode {
+            return true
+        }
+        if suspended != "" && strconv.FormatBool(summary.Suspended) != suspended {
+            return true
+        }
+        if summary.Peers < minPeers || (createdAfter != 0 && summary.CreatedAt <= createdAfter) {
+            return true
+        }
+        summaries = append(summaries, summary)
+        return true
+    })
+    sort.Slice(summaries, func(i, j int) bool {
+        return summaries[i].RoomCode < summaries[j].RoomCode
+    })
+
+    var nextCursor string
+    if len(summaries) > limit {
+        summaries = summaries[:limit]
+        nextCursor = summaries[limit-1].RoomCode
+    }
+    if summaries == nil {
+        summaries = []RoomSummary{}
+    }
+
+    resp := gin.H{"rooms": summaries, "total": total}
+    if nextCursor != "" {
+        resp["nextCursor"] = nextCursor
+    }
+    c.JSON(http.StatusOK, resp)
+}
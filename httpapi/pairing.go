@@ -0,0 +1,259 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    defaultPairCodeTTL = 2 * time.Minute
+    pairCodeLength     = 6
+    pairCodeAlphabet   = "0123456789"
+    // maxActivePairings keeps the million possible codes sparse, so
+    // guessing one stays impractical
+    maxActivePairings = 10000
+    pairStartsPerHour = 30 // per IP
+    pairClaimsPerMin  = 10 // per IP, right or wrong
+    pairRoomPrefix    = "pair-"
+)
+
+// pairing is an open pairing code, waiting for the starting peer's other
+// device to claim it
+type pairing struct {
+    PeerID    string
+    Profile   rooms.PeerProfile
+    IP        string
+    Tenant    string
+    Namespace string
+    ExpiresAt time.Time
+}
+
+// pairingTable holds the open pairing codes
+type pairingTable struct {
+    mu     sync.Mutex
+    codes  map[string]*pairing
+    byPeer map[string]string
+}
+
+// startPairing issues a short numeric code for the peer to type on another
+// device. Each peer has at most one open code; starting again replaces it.
+func (a *API) startPairing(c *gin.Context) {
+    var req struct {
+        PeerID string `json:"peerId" binding:"required"`
+        rooms.PeerProfile
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, "", req.PeerID)
+
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if !a.pairStartLimiter.Allow(c.ClientIP()) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+        return
+    }
+
+    expiresAt := time.Now().Add(envDuration("PAIR_CODE_TTL", defaultPairCodeTTL))
+
+    a.pairings.mu.Lock()
+    if old, ok := a.pairings.byPeer[req.PeerID]; ok {
+        delete(a.pairings.codes, old)
+    }
+    if len(a.pairings.codes) >= maxActivePairings {
+        a.pairings.mu.Unlock()
+        c.Header("Retry-After", strconv.Itoa(int(defaultPairCodeTTL.Seconds())))
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many pairings in progress, try again shortly"})
+        return
+    }
+    var code string
+    for {
+        var err error
+        code, err = randomCode(pairCodeAlphabet, pairCodeLength)
+        if err != nil {
+            a.pairings.mu.Unlock()
+            log.Printf("❌ Failed to generate pairing code: %v", err)
+            c.Error(err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate pairing code"})
+            return
+        }
+        if _, taken := a.pairings.codes[code]; !taken {
+            break
+        }
+    }
+    a.pairings.codes[code] = &pairing{
+        PeerID:    req.PeerID,
+        Profile:   req.PeerProfile,
+        IP:        c.ClientIP(),
+        Tenant:    tenantOf(c),
+        Namespace: roomNamespace(c.GetHeader("Origin")),
+        ExpiresAt: expiresAt,
+    }
+    a.pairings.byPeer[req.PeerID] = code
+    a.pairings.mu.Unlock()
+
+    log.Printf("📲 Pairing started by %s", req.PeerID)
+
+    c.JSON(http.StatusCreated, gin.H{"code": code, "expiresAt": expiresAt.Unix()})
+}
+
+// claimPairing redeems a pairing code: a locked room holding just the two
+// peers is created, the starting peer is told with a pair_claimed event and
+// the claimer gets the same response as joining a room. Codes work once.
+func (a *API) claimPairing(c *gin.Context) {
+    var req struct {
+        Code   string `json:"code" binding:"required"`
+        PeerID string `json:"peerId" binding:"required"`
+        rooms.PeerProfile
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, "", req.PeerID)
+
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if !a.pairClaimLimiter.Allow(c.ClientIP()) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+        return
+    }
+
+    a.pairings.mu.Lock()
+    p, ok := a.pairings.codes[req.Code]
+    if !ok || time.Now().After(p.ExpiresAt) {
+        a.pairings.mu.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "Pairing code not found or expired"})
+        return
+    }
+    if p.PeerID == req.PeerID {
+        a.pairings.mu.Unlock()
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A peer cannot claim its own pairing code"})
+        return
+    }
+    a.pairings.mu.Unlock()
+
+    slug, err := randomCode(shortLinkAlphabet, 10)
+    if err != nil {
+        log.Printf("❌ Failed to generate room code: %v", err)
+        c.Error(err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room"})
+        return
+    }
+    roomCode := namespacedCode(p.Namespace, pairRoomPrefix+slug)
+
+    // The hooks run before the code is used up, so a refusal leaves it
+    // for another claim
+    if !a.checkRoomCreate(c, roomCode, p.PeerID, rooms.ModeOpen) {
+        return
+    }
+
+    a.pairings.mu.Lock()
+    if a.pairings.codes[req.Code] != p {
+        a.pairings.mu.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "Pairing code not found or expired"})
+        return
+    }
+    delete(a.pairings.codes, req.Code)
+    delete(a.pairings.byPeer, p.PeerID)
+    a.pairings.mu.Unlock()
+
+    room, created, err := a.rooms.Create(roomCode, func(census rooms.Census) (*rooms.Room, error) {
+        if a.draining() {
+            return nil, drainRefusal
+        }
+        if qerr := a.checkRoomQuotas(census, p.IP); qerr != nil {
+            return nil, qerr
+        }
+        room := rooms.New(p.PeerID, rooms.ModeOpen, p.IP, time.Now().Unix())
+        room.Tenant = p.Tenant
+        // Nobody else may join a pairing
+        room.Locked = true
+        return room, nil
+    })
+    if err != nil {
+        qerr := err.(*quotaError)
+        if qerr.retry {
+            c.Header("Retry-After", strconv.Itoa(int(a.quotas.retryAfter.Seconds())))
+        }
+        c.JSON(qerr.status, gin.H{"error": qerr.message})
+        return
+    }
+    if !created {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room"})
+        return
+    }
+
+    room.Lock()
+    starterRole, _ := admitPeer(room, p.PeerID, p.Profile)
+    role, permissions := admitPeer(room, req.PeerID, req.PeerProfile)
+    peers := room.ConnectedPeers(req.PeerID)
+    roomSize := len(room.Peers)
+    room.Unlock()
+
+    a.recordRecentRoom(p.PeerID, roomCode, starterRole)
+    a.recordRecentRoom(req.PeerID, roomCode, role)
+    log.Printf("📲 Pairing claimed: %s ↔ %s in Room: %s", p.PeerID, req.PeerID, roomCode)
+    a.recordAudit(roomCode, "room_paired", req.PeerID, p.PeerID, nil)
+
+    a.queueNotification(p.PeerID, notifications.Notification{
+        Type:      "pair_claimed",
+        PeerID:    req.PeerID,
+        Timestamp: time.Now().Unix(),
+        Payload: notifications.Payload(gin.H{
+            "roomCode":    roomCode,
+            "resumeToken": a.issueResumeToken(roomCode, p.PeerID),
+        }),
+    })
+
+    c.JSON(http.StatusOK, gin.H{
+        "roomCode":    roomCode,
+        "pairedWith":  p.PeerID,
+        "peers":       peers,
+        "roomSize":    roomSize,
+        "role":        role,
+        "permissions": permissions,
+        "resumeToken": a.issueResumeToken(roomCode, req.PeerID),
+    })
+}
+
+// prunePairings drops expired pairing codes
+func (a *API) prunePairings() {
+    now := time.Now()
+    a.pairings.mu.Lock()
+    for code, p := range a.pairings.codes {
+        if now.After(p.ExpiresAt) {
+            delete(a.pairings.codes, code)
+            delete(a.pairings.byPeer, p.PeerID)
+        }
+    }
+    a.pairings.mu.Unlock()
+}
+
+// erasePairing drops the pairing code peerID started, returning whether it
+// had one
+func (a *API) erasePairing(peerID string) bool {
+    a.pairings.mu.Lock()
+    defer a.pairings.mu.Unlock()
+    code, ok := a.pairings.byPeer[peerID]
+    if ok {
+        delete(a.pairings.codes, code)
+        delete(a.pairings.byPeer, peerID)
+    }
+    return ok
+}
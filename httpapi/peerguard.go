@@ -0,0 +1,154 @@
+package httpapi
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// actorPaths are routes whose :peerId is the peer making the request, rather
+// than one it acts on like a nearby peer it asks or a peer it blocks
+var actorPaths = map[string]bool{
+    "/notifications/:peerId": true,
+    "/peers/:peerId/rooms":   true,
+    "/peers/:peerId/data":    true,
+}
+
+// actorFields are routes whose body names the acting peer in a field other
+// than hostId or peerId
+var actorFields = map[string]string{
+    "/messages": "from",
+    "/reports":  "reporterId",
+}
+
+// tokenlessPaths are routes proving the peer some other way, or that are
+// used before a peer has a token
+var tokenlessPaths = map[string]bool{
+    "/room/resume": true,
+}
+
+// tokenPaths are routes needing a valid peer token even without
+// REQUIRE_PEER_TOKEN, as what they hand out is tied to the peer ID: a
+// quick-share claim admits the named peer to the sender's room and answers
+// with its resume token
+var tokenPaths = map[string]bool{
+    "/s/:slug/claim": true,
+}
+
+// matchPeerToken answers 403 to requests acting as a peer other than the
+// one their bearer peer token proves, whether the peer is named in the
+// route, a peerId or hostId query parameter or the body (hostId where there
+// is one, as the peerId is then the peer acted on). The admin API and admin
+// requests are exempt.
+//
+// Unless REQUIRE_PEER_TOKEN=true, which answers them 401, requests without a
+// valid peer token are still let through and act as whoever they name, as
+// anonymous peers always have: anyone knowing a peer's ID can then act as
+// it. Deployments whose clients all fetch a token from /api/peer-id should
+// set REQUIRE_PEER_TOKEN=true. Whatever it says, tokenPaths need a token,
+// and features acting against a peer don't take a tokenless request's word
+// for who sent it: only token-verified reports count toward auto-suspend,
+// and anomaly counts go to the client's IP rather than the peer named.
+func matchPeerToken() gin.HandlerFunc {
+    required := os.Getenv("REQUIRE_PEER_TOKEN") == "true"
+    if !required {
+        log.Println("⚠️  REQUIRE_PEER_TOKEN not set, requests without a peer token may act as any peer")
+    }
+
+    return func(c *gin.Context) {
+        if strings.HasPrefix(c.FullPath(), "/admin/") || tokenlessPaths[c.FullPath()] || isAdminRequest(c) {
+            c.Next()
+            return
+        }
+        claimed := assertedPeers(c)
+        if len(claimed) == 0 {
+            c.Next()
+            return
+        }
+
+        authPeer, ok := authenticatedPeer(c)
+        if !ok {
+            if required || tokenPaths[c.FullPath()] {
+                c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+                return
+            }
+            c.Next()
+            return
+        }
+        for _, peerID := range claimed {
+            if peerID != authPeer {
+                c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Peer ID does not match the peer token"})
+                return
+            }
+        }
+        c.Next()
+    }
+}
+
+// assertedPeers returns the peer IDs a request claims to act as
+func assertedPeers(c *gin.Context) []string {
+    route := c.FullPath()
+    fields := bodyFields(c)
+    actor := fields.get("peerId")
+    if field, ok := actorFields[route]; ok {
+        actor = fields.get(field)
+    } else if hosts := fields.get("hostId"); len(hosts) > 0 {
+        actor = hosts
+    }
+
+    candidates := append([]string{c.Query("peerId"), c.Query("hostId")}, actor...)
+    if actorPaths[route] {
+        candidates = append(candidates, c.Param("peerId"))
+    }
+    var claimed []string
+    for _, peerID := range candidates {
+        if peerID != "" {
+            claimed = append(claimed, peerID)
+        }
+    }
+    return claimed
+}
+
+// requestFields are the top-level string fields of a request body, keyed by
+// their lower-cased name
+type requestFields map[string][]string
+
+// get returns every value of the field name, whatever the case of its key,
+// as encoding/json binds a key to a field case-insensitively
+func (f requestFields) get(name string) []string {
+    return f[strings.ToLower(name)]
+}
+
+// bodyFields returns the top-level string fields of a request body, leaving
+// the body for the handler to read again. Bodies are read whatever their
+// Content-Type, since handlers bind JSON without checking it.
+func bodyFields(c *gin.Context) requestFields {
+    if c.Request.Body == nil || c.Request.Body == http.NoBody || streamedBodies[c.FullPath()] {
+        return nil
+    }
+    // limitBodies has already buffered and bounded the body
+    body, err := io.ReadAll(c.Request.Body)
+    c.Request.Body = io.NopCloser(bytes.NewReader(body))
+    if err != nil {
+        return nil
+    }
+    var raw map[string]json.RawMessage
+    if json.Unmarshal(body, &raw) != nil {
+        return nil
+    }
+    fields := make(requestFields, len(raw))
+    for name, value := range raw {
+        var s string
+        if json.Unmarshal(value, &s) == nil {
+            key := strings.ToLower(name)
+            fields[key] = append(fields[key], s)
+        }
+    }
+    return fields
+}
@@ -0,0 +1,76 @@
+package httpapi
+
+import (
+    "net/http"
+    "testing"
+)
+
+func TestMatchPeerToken(t *testing.T) {
+    t.Setenv("REQUIRE_PEER_TOKEN", "true")
+    tokenA, tokenB := issuePeerToken("peer-a"), issuePeerToken("peer-b")
+
+    tests := []struct {
+        name        string
+        contentType string
+        token       string
+        body        string
+        want        int
+    }{
+        {"own peer", "application/json", tokenA, `{"roomCode":"guard","peerId":"peer-a"}`, http.StatusOK},
+        {"other peer", "application/json", tokenB, `{"roomCode":"guard","peerId":"peer-a"}`, http.StatusForbidden},
+        {"no token", "application/json", "", `{"roomCode":"guard","peerId":"peer-a"}`, http.StatusUnauthorized},
+        {"no token, text/plain", "text/plain", "", `{"roomCode":"guard","peerId":"peer-a"}`, http.StatusUnauthorized},
+        {"no token, no content type", "", "", `{"roomCode":"guard","peerId":"peer-a"}`, http.StatusUnauthorized},
+        {"other peer, upper-case key", "application/json", tokenB, `{"roomCode":"guard","PEERID":"peer-a"}`, http.StatusForbidden},
+        {"other peer, mixed-case key", "text/plain", tokenB, `{"roomCode":"guard","PeerId":"peer-a"}`, http.StatusForbidden},
+        {"other peer, duplicate keys", "application/json", tokenB, `{"roomCode":"guard","peerId":"peer-b","peerid":"peer-a"}`, http.StatusForbidden},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, h := newTestRouter(t)
+            request(h, http.MethodPost, "/room/create", "application/json", tokenA, `{"roomCode":"guard","peerId":"peer-a"}`)
+
+            w := request(h, http.MethodPost, "/room/leave", tt.contentType, tt.token, tt.body)
+            if w.Code != tt.want {
+                t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+            }
+        })
+    }
+}
+
+func TestMatchPeerTokenActorFields(t *testing.T) {
+    tokenB := issuePeerToken("peer-b")
+    _, h := newTestRouter(t)
+
+    for _, body := range []string{
+        `{"from":"peer-a","to":"peer-b","payload":{}}`,
+        `{"FROM":"peer-a","to":"peer-b","payload":{}}`,
+    } {
+        w := request(h, http.MethodPost, "/messages", "text/plain", tokenB, body)
+        if w.Code != http.StatusForbidden {
+            t.Errorf("%s: status = %d, want 403", body, w.Code)
+        }
+    }
+}
+
+func TestMatchPeerTokenRequiredPaths(t *testing.T) {
+    _, h, slug := newQuickShare(t, 2)
+
+    tests := []struct {
+        name  string
+        token string
+        want  int
+    }{
+        {"no token", "", http.StatusUnauthorized},
+        {"other peer", issuePeerToken("peer-c"), http.StatusForbidden},
+        {"own peer", issuePeerToken("peer-b"), http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            w := request(h, http.MethodPost, claimPath(slug), "application/json", tt.token, `{"peerId":"peer-b"}`)
+            if w.Code != tt.want {
+                t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+            }
+        })
+    }
+}
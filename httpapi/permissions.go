@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// setPeerPermissions lets the host override a guest's permissions
+func (a *API) setPeerPermissions(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    var req struct {
+        HostID      string `json:"hostId" binding:"required"`
+        PeerID      string `json:"peerId" binding:"required"`
+        Permissions string `json:"permissions" binding:"required"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+
+    if !rooms.ValidPermissions(req.Permissions) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "permissions must be one of full, send-only, receive-only"})
+        return
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    if req.HostID != room.HostID {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can change permissions"})
+        return
+    }
+    if req.PeerID == room.HostID {
+        room.Unlock()
+        c.JSON(http.StatusBadRequest, gin.H{"error": "The host always has full permissions"})
+        return
+    }
+    peer, ok := room.Peers[req.PeerID]
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "Peer not in room"})
+        return
+    }
+    peer.Permissions = req.Permissions
+    room.TouchPeer(req.PeerID)
+    room.Unlock()
+
+    a.queueNotification(req.PeerID, notifications.Notification{
+        Type:      "permissions_changed",
+        PeerID:    req.HostID,
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(gin.H{"permissions": req.Permissions}),
+    })
+
+    log.Printf("🔐 Permissions changed: %s → %s in Room: %s", req.PeerID, req.Permissions, roomCode)
+    a.recordAudit(roomCode, "permissions_changed", req.HostID, req.PeerID, gin.H{"permissions": req.Permissions})
+
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
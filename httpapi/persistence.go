@@ -0,0 +1,293 @@
+package httpapi
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/json"
+    "fmt"
+    "log"
+    "sort"
+    "sync"
+    "time"
+
+    "p2p-file-share-backend/rooms"
+    "p2p-file-share-backend/storage"
+)
+
+const persistTimeout = 30 * time.Second
+
+// persistence tracks what has already been written to the durable store, so
+// each flush only writes rooms that changed since the last one
+type persistence struct {
+    mu sync.Mutex
+    // saved maps each persisted room to the state it was saved in
+    saved map[string]roomMark
+    // auditID is the newest audit entry saved
+    auditID int64
+    // users maps each persisted user to a hash of the record saved
+    users map[string][32]byte
+}
+
+type roomMark struct {
+    version   uint64
+    suspended bool
+}
+
+// restoreState loads persisted rooms, audit entries and signed-in users into
+// memory. Restored peers get a fresh LastSeen, so they have a full grace
+// period to reconnect before the sweeper removes them.
+func (a *API) restoreState(ctx context.Context) error {
+    records, err := a.storage.LoadRooms(ctx)
+    if err != nil {
+        return err
+    }
+    for _, rec := range records {
+        if room, ok := a.adoptRoom(rec); ok {
+            a.persisted.saved[rec.Code] = roomMark{version: room.Version, suspended: room.Suspended}
+        }
+    }
+
+    entries, err := a.storage.LoadAudit(ctx, maxAuditEntries)
+    if err != nil {
+        return err
+    }
+    a.audit.mu.Lock()
+    for _, e := range entries {
+        a.audit.entries = append(a.audit.entries, AuditEntry{
+            ID:       e.ID,
+            Time:     e.Time,
+            RoomCode: e.RoomCode,
+            Type:     e.Type,
+            Actor:    e.Actor,
+            Target:   e.Target,
+            Details:  e.Details,
+        })
+        a.audit.nextID = e.ID + 1
+        a.persisted.auditID = e.ID
+    }
+    a.audit.mu.Unlock()
+
+    users, err := a.storage.LoadUsers(ctx)
+    if err != nil {
+        return err
+    }
+    for _, rec := range users {
+        if err := a.adoptUser(rec); err != nil {
+            log.Printf("❌ Ignoring invalid stored user %s: %v", rec.ID, err)
+            continue
+        }
+        a.persisted.users[rec.ID] = userMark(rec)
+    }
+
+    log.Printf("🗄️  Restored %d rooms, %d audit entries and %d users", len(records), len(entries), len(users))
+    return nil
+}
+
+// adoptUser restores a user's profile and devices from rec
+func (a *API) adoptUser(rec storage.UserRecord) error {
+    var profile *userProfile
+    if len(rec.Profile) > 0 {
+        profile = &userProfile{}
+        if err := json.Unmarshal(rec.Profile, profile); err != nil {
+            return err
+        }
+    }
+    var devices []device
+    if len(rec.Devices) > 0 {
+        if err := json.Unmarshal(rec.Devices, &devices); err != nil {
+            return err
+        }
+    }
+
+    if profile != nil {
+        a.logins.mu.Lock()
+        a.logins.users[rec.ID] = profile
+        a.logins.mu.Unlock()
+    }
+    if len(devices) > 0 {
+        a.devices.mu.Lock()
+        list := make(map[string]*device, len(devices))
+        for i := range devices {
+            list[devices[i].DeviceID] = &devices[i]
+            a.devices.owners[devices[i].DeviceID] = rec.ID
+        }
+        a.devices.users[rec.ID] = list
+        a.devices.mu.Unlock()
+    }
+    return nil
+}
+
+// userRecords captures every signed-in user's profile and devices for
+// saving, keyed by user ID
+func (a *API) userRecords() (map[string]storage.UserRecord, error) {
+    records := make(map[string]storage.UserRecord)
+
+    a.logins.mu.Lock()
+    for userID, user := range a.logins.users {
+        data, err := json.Marshal(user)
+        if err != nil {
+            a.logins.mu.Unlock()
+            return nil, err
+        }
+        records[userID] = storage.UserRecord{ID: userID, Profile: data}
+    }
+    a.logins.mu.Unlock()
+
+    a.devices.mu.Lock()
+    defer a.devices.mu.Unlock()
+    for userID, byID := range a.devices.users {
+        if len(byID) == 0 {
+            continue
+        }
+        list := make([]device, 0, len(byID))
+        for _, d := range byID {
+            list = append(list, *d)
+        }
+        sort.Slice(list, func(i, j int) bool { return list[i].DeviceID < list[j].DeviceID })
+        data, err := json.Marshal(list)
+        if err != nil {
+            return nil, err
+        }
+        rec := records[userID]
+        rec.ID, rec.Devices = userID, data
+        records[userID] = rec
+    }
+    return records, nil
+}
+
+// userMark identifies the state a user record was saved in
+func userMark(rec storage.UserRecord) [32]byte {
+    h := sha256.New()
+    h.Write(rec.Profile)
+    h.Write([]byte{0})
+    h.Write(rec.Devices)
+    var mark [32]byte
+    h.Sum(mark[:0])
+    return mark
+}
+
+// flushUsers saves users whose profile or devices changed since the last
+// flush and deletes users that are gone. The caller must hold the
+// persistence lock.
+func (a *API) flushUsers(ctx context.Context) error {
+    records, err := a.userRecords()
+    if err != nil {
+        return err
+    }
+    for userID, rec := range records {
+        mark := userMark(rec)
+        if saved, ok := a.persisted.users[userID]; ok && saved == mark {
+            continue
+        }
+        if err := a.storage.SaveUser(ctx, rec); err != nil {
+            return err
+        }
+        a.persisted.users[userID] = mark
+    }
+    for userID := range a.persisted.users {
+        if _, live := records[userID]; live {
+            continue
+        }
+        if err := a.storage.DeleteUser(ctx, userID); err != nil {
+            return err
+        }
+        delete(a.persisted.users, userID)
+    }
+    return nil
+}
+
+// adoptRoom adds a room rebuilt from rec unless its code is already taken.
+// Its peers get a fresh LastSeen.
+func (a *API) adoptRoom(rec storage.RoomRecord) (*rooms.Room, bool) {
+    room := rec.Room()
+    now := time.Now().Unix()
+    for _, peer := range room.Peers {
+        peer.LastSeen.Store(now)
+    }
+    _, created, _ := a.rooms.Create(rec.Code, func(rooms.Census) (*rooms.Room, error) {
+        return room, nil
+    })
+    return room, created
+}
+
+// persistState flushes state to the durable store, every PERSIST_INTERVAL
+// and once more on shutdown
+func (a *API) persistState(ctx context.Context) error {
+    ctx, cancel := context.WithTimeout(ctx, persistTimeout)
+    defer cancel()
+    return a.flushState(ctx)
+}
+
+// flushState saves rooms that changed since the last flush, deletes rooms
+// that are gone, appends new audit entries and saves changed users
+func (a *API) flushState(ctx context.Context) error {
+    a.persisted.mu.Lock()
+    defer a.persisted.mu.Unlock()
+
+    live := make(map[string]bool)
+    var changed []storage.RoomRecord
+    var marks []roomMark
+    a.rooms.Range(func(code string, room *rooms.Room) bool {
+        live[code] = true
+        room.RLock()
+        mark := roomMark{version: room.Version, suspended: room.Suspended}
+        if saved, ok := a.persisted.saved[code]; !ok || saved != mark {
+            changed = append(changed, storage.Record(code, room))
+            marks = append(marks, mark)
+        }
+        room.RUnlock()
+        return true
+    })
+
+    for i, rec := range changed {
+        if err := a.storage.SaveRoom(ctx, rec); err != nil {
+            return err
+        }
+        a.persisted.saved[rec.Code] = marks[i]
+    }
+    for code := range a.persisted.saved {
+        if live[code] {
+            continue
+        }
+        if err := a.storage.DeleteRoom(ctx, code); err != nil {
+            return err
+        }
+        delete(a.persisted.saved, code)
+    }
+
+    a.audit.mu.RLock()
+    start := sort.Search(len(a.audit.entries), func(i int) bool {
+        return a.audit.entries[i].ID > a.persisted.auditID
+    })
+    pending := make([]storage.AuditRecord, 0, len(a.audit.entries)-start)
+    for _, e := range a.audit.entries[start:] {
+        pending = append(pending, storage.AuditRecord{
+            ID:       e.ID,
+            Time:     e.Time,
+            RoomCode: e.RoomCode,
+            Type:     e.Type,
+            Actor:    e.Actor,
+            Target:   e.Target,
+            Details:  e.Details,
+        })
+    }
+    a.audit.mu.RUnlock()
+
+    if err := a.storage.AppendAudit(ctx, pending); err != nil {
+        return err
+    }
+    if len(pending) > 0 {
+        a.persisted.auditID = pending[len(pending)-1].ID
+    }
+    return a.flushUsers(ctx)
+}
+
+// checkStorage pings the durable store
+func (a *API) checkStorage(ctx context.Context) (string, error) {
+    if err := a.storage.Ping(ctx); err != nil {
+        return "", err
+    }
+    a.persisted.mu.Lock()
+    defer a.persisted.mu.Unlock()
+    return fmt.Sprintf("%d rooms persisted", len(a.persisted.saved)), nil
+}
@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "testing"
+
+    "p2p-file-share-backend/storage"
+)
+
+// openTestStore opens a local store in dir, closed when the test ends
+func openTestStore(t *testing.T, dir string) *storage.Bolt {
+    t.Helper()
+    store, err := storage.NewBolt(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return store
+}
+
+func TestPersistUsers(t *testing.T) {
+    dir := t.TempDir()
+    ctx := context.Background()
+    userID := userIDPrefix + "persisted"
+    token := issuePeerToken(userID)
+
+    store := openTestStore(t, dir)
+    a := New(Config{Storage: store, AllowedOrigins: testOrigins})
+    h := a.Router()
+    a.logins.mu.Lock()
+    a.logins.users[userID] = &userProfile{UserID: userID, Provider: "test", Name: "Ada", FirstLogin: 1, LastLogin: 2}
+    a.logins.mu.Unlock()
+
+    w := request(h, http.MethodPost, "/me/devices", "application/json", token, `{"name":"Laptop","kind":"laptop"}`)
+    if w.Code != http.StatusCreated {
+        t.Fatalf("register: status = %d: %s", w.Code, w.Body)
+    }
+    var registered struct {
+        Device device `json:"device"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &registered); err != nil || registered.Device.DeviceID == "" {
+        t.Fatalf("register: %s: %v", w.Body, err)
+    }
+    deviceID := registered.Device.DeviceID
+    if err := a.flushState(ctx); err != nil {
+        t.Fatal(err)
+    }
+    store.Close()
+
+    // A restarted instance knows the user, the device and its owner
+    store = openTestStore(t, dir)
+    a = New(Config{Storage: store, AllowedOrigins: testOrigins})
+    if profile, ok := a.userProfileFor(userID); !ok || profile.Name != "Ada" {
+        t.Errorf("profile = %+v, %v after restart", profile, ok)
+    }
+    if owner, ok := a.ownerOf(deviceID); !ok || owner != userID {
+        t.Errorf("owner of %s = %q, %v after restart", deviceID, owner, ok)
+    }
+    w = request(a.Router(), http.MethodGet, "/me/devices", "", issuePeerToken(deviceID), "")
+    if w.Code != http.StatusOK || !json.Valid(w.Body.Bytes()) {
+        t.Fatalf("devices from the device: status = %d: %s", w.Code, w.Body)
+    }
+
+    // Erasure reaches the store without waiting for a flush
+    if w := request(a.Router(), http.MethodDelete, "/peers/"+userID+"/data", "", token, ""); w.Code != http.StatusOK {
+        t.Fatalf("erase: status = %d: %s", w.Code, w.Body)
+    }
+    users, err := store.LoadUsers(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(users) != 0 {
+        t.Errorf("%d users still stored after erasure", len(users))
+    }
+    store.Close()
+}
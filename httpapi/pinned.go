@@ -0,0 +1,133 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/rooms"
+)
+
+// pinConfiguredRooms creates and pins the rooms listed in PINNED_ROOMS, a
+// comma-separated list of codes each optionally followed by :mode, e.g.
+// "dropzone:dropbox,team". They have no host and, being pinned, are never
+// cleaned up, so their codes stay valid indefinitely.
+func (a *API) pinConfiguredRooms() {
+    for _, entry := range strings.Split(os.Getenv("PINNED_ROOMS"), ",") {
+        entry = strings.TrimSpace(entry)
+        if entry == "" {
+            continue
+        }
+        code, mode, _ := strings.Cut(entry, ":")
+        if mode == "" {
+            mode = rooms.ModeOpen
+        }
+        if !rooms.ValidMode(mode) {
+            log.Fatalf("❌ Invalid PINNED_ROOMS entry %q: mode must be one of open, dropbox, distribution", entry)
+        }
+
+        room, created, _ := a.rooms.Create(code, func(rooms.Census) (*rooms.Room, error) {
+            return rooms.New("", mode, "", time.Now().Unix()), nil
+        })
+        room.Lock()
+        if !room.Pinned {
+            room.Pinned = true
+            room.Touch()
+        }
+        room.Unlock()
+        if created {
+            log.Printf("📌 Pinned room created: %s (%s)", code, mode)
+        }
+    }
+}
+
+// setPinned pins or unpins a room, reporting whether the room exists and
+// whether anything changed
+func (a *API) setPinned(roomCode string, pinned bool, actor string) (exists, changed bool) {
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        return false, false
+    }
+
+    room.Lock()
+    if room.Pinned != pinned {
+        room.Pinned = pinned
+        room.Touch()
+        changed = true
+    }
+    room.Unlock()
+
+    if changed {
+        if pinned {
+            log.Printf("📌 Room pinned: %s by %s", roomCode, actor)
+            a.recordAudit(roomCode, "room_pinned", actor, "", nil)
+        } else {
+            log.Printf("📌 Room unpinned: %s by %s", roomCode, actor)
+            a.recordAudit(roomCode, "room_unpinned", actor, "", nil)
+        }
+    }
+    return true, changed
+}
+
+// pinRoom returns a handler letting the host keep the room, and its code,
+// after everyone has left, or undo that. Pinned rooms survive restarts only
+// when a durable store is configured.
+func (a *API) pinRoom(pinned bool) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        roomCode := c.Param("roomCode")
+
+        var req struct {
+            HostID string `json:"hostId" binding:"required"`
+        }
+
+        if err := c.ShouldBindJSON(&req); err != nil {
+            invalidRequest(c, err)
+            return
+        }
+        tagRequest(c, roomCode, req.HostID)
+
+        room, exists := a.rooms.Get(roomCode)
+        if !exists {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+            return
+        }
+        room.RLock()
+        isHost := req.HostID == room.HostID
+        room.RUnlock()
+        if !isHost {
+            c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can pin or unpin the room"})
+            return
+        }
+
+        if exists, _ := a.setPinned(roomCode, pinned, req.HostID); !exists {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{
+            "roomCode": roomCode,
+            "pinned":   pinned,
+            "durable":  a.storage != nil,
+        })
+    }
+}
+
+// adminPinRoom returns the admin counterpart of pinRoom
+func (a *API) adminPinRoom(pinned bool) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        roomCode := c.Param("roomCode")
+
+        if exists, _ := a.setPinned(roomCode, pinned, "admin"); !exists {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{
+            "roomCode": roomCode,
+            "pinned":   pinned,
+            "durable":  a.storage != nil,
+        })
+    }
+}
@@ -0,0 +1,195 @@
+package httpapi
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "net/url"
+    "os"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// policyFile is the on-disk format of FILE_POLICY_FILE. Tenants are keyed by
+// the host name of the frontend origin rooms are created from:
+//
+//  {"default": {"maxFileSize": 1073741824},
+//   "tenants": {"p2p-client.martinwong.me": {"allowedTypes": ["image/*"]}}}
+type policyFile struct {
+    Default rooms.FilePolicy            `json:"default"`
+    Tenants map[string]rooms.FilePolicy `json:"tenants"`
+}
+
+// loadFilePolicies reads and validates FILE_POLICY_FILE; without one no
+// operator policy applies
+func loadFilePolicies() (*policyFile, error) {
+    cfg := &policyFile{}
+    path := os.Getenv("FILE_POLICY_FILE")
+    if path == "" {
+        return cfg, nil
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(data, cfg); err != nil {
+        return nil, fmt.Errorf("%s: %v", path, err)
+    }
+
+    cfg.Default.Normalize()
+    if err := cfg.Default.Validate(); err != nil {
+        return nil, fmt.Errorf("default: %v", err)
+    }
+    for tenant, policy := range cfg.Tenants {
+        policy.Normalize()
+        if err := policy.Validate(); err != nil {
+            return nil, fmt.Errorf("tenant %s: %v", tenant, err)
+        }
+        cfg.Tenants[tenant] = policy
+    }
+    return cfg, nil
+}
+
+// reloadFilePolicies swaps in freshly loaded policies, keeping the old ones
+// on error
+func (a *API) reloadFilePolicies() {
+    cfg, err := loadFilePolicies()
+    if err != nil {
+        log.Printf("❌ Failed to load file policies, keeping previous: %v", err)
+        return
+    }
+    a.filePolicies.Store(cfg)
+    log.Printf("📏 File policies loaded (%d tenants)", len(cfg.Tenants))
+}
+
+// tenantOf names the tenant a request comes from: the host of its Origin,
+// or "" for clients that send none
+func tenantOf(c *gin.Context) string {
+    return originTenant(c.GetHeader("Origin"))
+}
+
+// originTenant is the tenant named by an Origin header
+func originTenant(origin string) string {
+    u, err := url.Parse(origin)
+    if err != nil {
+        return ""
+    }
+    return u.Hostname()
+}
+
+// tenantPolicy returns the operator's policy for a tenant, falling back to
+// the default
+func (a *API) tenantPolicy(tenant string) rooms.FilePolicy {
+    cfg := a.filePolicies.Load()
+    if cfg == nil {
+        return rooms.FilePolicy{}
+    }
+    if policy, ok := cfg.Tenants[tenant]; ok {
+        return policy
+    }
+    return cfg.Default
+}
+
+// checkFilePolicy applies the tenant's policy and then the room's own to a
+// new file offer. The caller must hold the room lock.
+func (a *API) checkFilePolicy(room *rooms.Room, name, mimeType string, size int64) *rooms.PolicyViolation {
+    files := len(room.Files)
+    if v := a.tenantPolicy(room.Tenant).Check(name, mimeType, size, files); v != nil {
+        return v
+    }
+    return room.Policy.Check(name, mimeType, size, files)
+}
+
+// checkBundlePolicy is checkFilePolicy for a folder bundle
+func (a *API) checkBundlePolicy(room *rooms.Room, entries []rooms.BundleEntry, size int64) *rooms.PolicyViolation {
+    files := len(room.Files)
+    if v := a.tenantPolicy(room.Tenant).CheckBundle(entries, size, files); v != nil {
+        return v
+    }
+    return room.Policy.CheckBundle(entries, size, files)
+}
+
+// getRoomPolicy shows the file constraints in force in a room, so clients
+// can check files before offering them
+func (a *API) getRoomPolicy(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    policy, tenant := room.Policy, room.Tenant
+    room.RUnlock()
+
+    c.JSON(http.StatusOK, gin.H{
+        "roomCode": roomCode,
+        "room":     policy,
+        "tenant":   a.tenantPolicy(tenant),
+    })
+}
+
+// setRoomPolicy lets the host replace the room's file policy. It can only
+// tighten what the tenant allows, since both apply, and files already
+// offered are kept.
+func (a *API) setRoomPolicy(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    var req struct {
+        HostID string `json:"hostId" binding:"required"`
+        rooms.FilePolicy
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.HostID)
+
+    policy := req.FilePolicy
+    policy.Normalize()
+    if err := policy.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    if req.HostID != room.HostID {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can change the file policy"})
+        return
+    }
+    room.Policy = policy
+    room.Touch()
+    room.Unlock()
+
+    a.notifyRoom(room, req.HostID, notifications.Notification{
+        Type:      "policy_updated",
+        PeerID:    req.HostID,
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(policy),
+    })
+
+    log.Printf("📏 File policy updated: %s", roomCode)
+    a.recordAudit(roomCode, "policy_updated", req.HostID, "", gin.H{"policy": policy})
+
+    c.JSON(http.StatusOK, gin.H{"policy": policy})
+}
+
+// getFilePolicies shows the operator's default and per-tenant policies
+func (a *API) getFilePolicies(c *gin.Context) {
+    c.JSON(http.StatusOK, a.filePolicies.Load())
+}
@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// heartbeat keeps a peer alive in a room and optionally updates its presence
+// and its round-trip time to the other peers, shown to the host in the room
+// insights. Presence changes are broadcast to the rest of the room as
+// presence_changed.
+func (a *API) heartbeat(c *gin.Context) {
+    var req struct {
+        RoomCode string `json:"roomCode" binding:"required"`
+        PeerID   string `json:"peerId" binding:"required"`
+        Presence string `json:"presence"`
+        RTTMs    *int   `json:"rttMs" binding:"omitempty,min=0,max=60000"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, req.RoomCode, req.PeerID)
+
+    if req.Presence != "" && !rooms.ValidPresence(req.Presence) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "presence must be one of online, idle, transferring, away"})
+        return
+    }
+
+    presence, status, errMsg := a.recordHeartbeat(req.RoomCode, req.PeerID, req.Presence)
+    if errMsg != "" {
+        c.JSON(status, gin.H{"error": errMsg})
+        return
+    }
+    if req.RTTMs != nil {
+        a.recordLatency(req.RoomCode, req.PeerID, *req.RTTMs)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "success":  true,
+        "presence": presence,
+    })
+}
+
+// recordHeartbeat refreshes a peer's LastSeen and applies a presence change,
+// returning the peer's current presence
+func (a *API) recordHeartbeat(roomCode, peerID, presence string) (string, int, string) {
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        return "", http.StatusNotFound, "Room not found"
+    }
+
+    // The LastSeen bump is atomic, so the common no-change heartbeat only
+    // needs the read lock
+    room.RLock()
+    peer, ok := room.Peers[peerID]
+    var current string
+    var disconnected bool
+    if ok {
+        peer.LastSeen.Store(time.Now().Unix())
+        current = peer.Presence
+        disconnected = peer.Disconnected()
+    }
+    room.RUnlock()
+    if !ok {
+        return "", http.StatusNotFound, "Peer not in room"
+    }
+
+    // A heartbeat during the grace period means the peer never really left
+    if disconnected {
+        room.Lock()
+        reconnected := room.ReconnectPeer(peerID, time.Now().Unix())
+        if peer, ok := room.Peers[peerID]; ok {
+            current = peer.Presence
+        }
+        room.Unlock()
+        if reconnected {
+            a.reconnected(roomCode, room, peerID)
+        }
+    }
+
+    changed := false
+    if presence != "" && presence != current {
+        room.Lock()
+        if peer, ok := room.Peers[peerID]; ok && peer.Presence != presence {
+            peer.Presence = presence
+            room.TouchPeer(peerID)
+            changed = true
+        }
+        room.Unlock()
+    }
+    if !changed {
+        return current, http.StatusOK, ""
+    }
+
+    log.Printf("🟢 Presence changed: %s → %s in Room: %s", peerID, presence, roomCode)
+    a.notifyRoom(room, peerID, notifications.Notification{
+        Type:      "presence_changed",
+        PeerID:    peerID,
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(gin.H{"presence": presence}),
+    })
+
+    return presence, http.StatusOK, ""
+}
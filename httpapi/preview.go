@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// maxPreviewBytes is the largest preview image accepted, decoded, from
+// PREVIEW_MAX_BYTES (default 16 KB). Previews travel in every file listing
+// and file_offered notification, so they are kept small.
+func maxPreviewBytes() int {
+    return envInt("PREVIEW_MAX_BYTES", rooms.DefaultMaxPreviewBytes)
+}
+
+// setFilePreview lets the owner of a file offer attach or replace its
+// preview, after the offer was made, and sends it to the peers who can
+// receive the file. A null preview removes it.
+func (a *API) setFilePreview(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+
+    var req struct {
+        PeerID  string         `json:"peerId" binding:"required"`
+        Preview *rooms.Preview `json:"preview"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.PeerID)
+
+    if req.Preview != nil {
+        if err := req.Preview.Normalize(maxPreviewBytes()); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    file, ok := room.Files[fileID]
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return
+    }
+    if req.PeerID != file.PeerID {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can set the preview of this file"})
+        return
+    }
+    file.Preview = req.Preview
+    room.Touch()
+
+    recipients := room.FileRecipients(file)
+    room.Unlock()
+
+    a.dispatcher.Fanout(recipients, notifications.Notification{
+        Type:      "file_preview",
+        PeerID:    req.PeerID,
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(gin.H{"fileId": fileID, "preview": req.Preview}),
+    })
+
+    c.JSON(http.StatusOK, gin.H{"fileId": fileID, "preview": req.Preview})
+}
@@ -0,0 +1,88 @@
+package httpapi
+
+import (
+    "net"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Address families
+const (
+    familyIPv4 = "ipv4"
+    familyIPv6 = "ipv6"
+)
+
+// proxyHeaders are headers that proxies, load balancers and CDNs add on the
+// way to us
+var proxyHeaders = []string{
+    "Forwarded",
+    "Via",
+    "X-Forwarded-For",
+    "X-Forwarded-Proto",
+    "X-Real-IP",
+    "CF-Connecting-IP",
+    "True-Client-IP",
+    "Fastly-Client-IP",
+}
+
+// addressFamily returns the family of an IP address, counting IPv4-mapped
+// IPv6 addresses as IPv4, or "" if it isn't one
+func addressFamily(ip string) string {
+    parsed := net.ParseIP(ip)
+    switch {
+    case parsed == nil:
+        return ""
+    case parsed.To4() != nil:
+        return familyIPv4
+    }
+    return familyIPv6
+}
+
+// probe lets a client check its connectivity before trying WebRTC: the
+// address and family it reached us from, whether a proxy sits in between,
+// and timestamps for working out the round trip. A client sends its clock
+// as ?t= (Unix milliseconds) and gets it back with ours, so the round trip
+// is its clock on receipt minus t.
+func (a *API) probe(c *gin.Context) {
+    received := time.Now()
+
+    var echo *int64
+    if v := c.Query("t"); v != "" {
+        t, err := strconv.ParseInt(v, 10, 64)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "t must be a Unix time in milliseconds"})
+            return
+        }
+        echo = &t
+    }
+
+    clientIP := c.ClientIP()
+    remoteIP, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+    if err != nil {
+        remoteIP = c.Request.RemoteAddr
+    }
+    seen := make([]string, 0)
+    for _, h := range proxyHeaders {
+        if c.GetHeader(h) != "" {
+            seen = append(seen, h)
+        }
+    }
+
+    resp := gin.H{
+        "clientIp":     clientIP,
+        "family":       addressFamily(clientIP),
+        "protocol":     c.Request.Proto,
+        "tls":          c.Request.TLS != nil,
+        "proxied":      len(seen) > 0 || remoteIP != clientIP,
+        "proxyHeaders": seen,
+        "serverTime":   received.UnixMilli(),
+    }
+    if echo != nil {
+        resp["t"] = *echo
+    }
+    c.Header("Cache-Control", "no-store")
+    c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,237 @@
+package httpapi
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "os"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ErrorCode is the stable, machine-readable code of an error response.
+// Messages may be reworded; codes are never renamed or reused, so clients
+// should branch on them.
+type ErrorCode string
+
+// Codes for specific failures
+const (
+    CodeRoomNotFound       ErrorCode = "ROOM_NOT_FOUND"
+    CodeRoomFull           ErrorCode = "ROOM_FULL"
+    CodeRoomLocked         ErrorCode = "ROOM_LOCKED"
+    CodeRoomSuspended      ErrorCode = "ROOM_SUSPENDED"
+    CodeRoomCodeTaken      ErrorCode = "ROOM_CODE_TAKEN"
+    CodePeerNotInRoom      ErrorCode = "PEER_NOT_IN_ROOM"
+    CodePeerSuspended      ErrorCode = "PEER_SUSPENDED"
+    CodePeerTokenRequired  ErrorCode = "PEER_TOKEN_REQUIRED"
+    CodePeerMismatch       ErrorCode = "PEER_MISMATCH"
+    CodeFileNotFound       ErrorCode = "FILE_NOT_FOUND"
+    CodeFileQuarantined    ErrorCode = "FILE_QUARANTINED"
+    CodeRelayDisabled      ErrorCode = "RELAY_DISABLED"
+    CodeRelayQuotaExceeded ErrorCode = "RELAY_QUOTA_EXCEEDED"
+    CodeLinkNotFound       ErrorCode = "LINK_NOT_FOUND"
+    CodeLinkExpired        ErrorCode = "LINK_EXPIRED"
+    CodeLinkUsed           ErrorCode = "LINK_USED"
+    CodeLinkUnsigned       ErrorCode = "LINK_SIGNATURE_INVALID"
+    CodeCaptchaFailed      ErrorCode = "CAPTCHA_FAILED"
+    CodeAccessDenied       ErrorCode = "ACCESS_DENIED"
+    CodeServerAtCapacity   ErrorCode = "SERVER_AT_CAPACITY"
+    CodeReadOnly           ErrorCode = "READ_ONLY"
+)
+
+// Codes for everything else, by status
+const (
+    CodeInvalidRequest       ErrorCode = "INVALID_REQUEST"
+    CodeUnauthorized         ErrorCode = "UNAUTHORIZED"
+    CodeForbidden            ErrorCode = "FORBIDDEN"
+    CodeNotFound             ErrorCode = "NOT_FOUND"
+    CodeConflict             ErrorCode = "CONFLICT"
+    CodeGone                 ErrorCode = "GONE"
+    CodePreconditionFailed   ErrorCode = "PRECONDITION_FAILED"
+    CodePayloadTooLarge      ErrorCode = "PAYLOAD_TOO_LARGE"
+    CodeUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+    CodeValidationFailed     ErrorCode = "VALIDATION_FAILED"
+    CodeLocked               ErrorCode = "LOCKED"
+    CodeRateLimited          ErrorCode = "RATE_LIMITED"
+    CodeInternal             ErrorCode = "INTERNAL_ERROR"
+    CodeUpstreamFailed       ErrorCode = "UPSTREAM_FAILED"
+    CodeUnavailable          ErrorCode = "UNAVAILABLE"
+)
+
+// messageCodes gives the code of each error message that has its own
+var messageCodes = map[string]ErrorCode{
+    "Room not found": CodeRoomNotFound,
+    "Too many peers are waiting to join this room":     CodeRoomFull,
+    "Room is locked by the host":                       CodeRoomLocked,
+    "Room is suspended pending review":                 CodeRoomSuspended,
+    "Room code is already in use":                      CodeRoomCodeTaken,
+    "Peer not in room":                                 CodePeerNotInRoom,
+    "Suspended by an operator":                         CodePeerSuspended,
+    "A valid peer token is required":                   CodePeerTokenRequired,
+    "A valid peer token is required to use a template": CodePeerTokenRequired,
+    "Peer ID does not match the peer token":            CodePeerMismatch,
+    "File not found":                                   CodeFileNotFound,
+    "File was quarantined by the content scanner":      CodeFileQuarantined,
+    "Relaying files through the server is not enabled": CodeRelayDisabled,
+    "Relay quota exceeded":                             CodeRelayQuotaExceeded,
+    "Link not found or expired":                        CodeLinkNotFound,
+    "Link has expired":                                 CodeLinkExpired,
+    "Link has already been used":                       CodeLinkUsed,
+    "Link must be signed":                              CodeLinkUnsigned,
+    "Link signature is invalid":                        CodeLinkUnsigned,
+    "Captcha verification failed":                      CodeCaptchaFailed,
+    "Access denied":                                    CodeAccessDenied,
+    "Server is at room capacity, try again later":      CodeServerAtCapacity,
+    "Rate limit exceeded":                              CodeRateLimited,
+    "Internal server error":                            CodeInternal,
+}
+
+// statusCodes gives the code of any other error by its status
+var statusCodes = map[int]ErrorCode{
+    http.StatusBadRequest:            CodeInvalidRequest,
+    http.StatusUnauthorized:          CodeUnauthorized,
+    http.StatusForbidden:             CodeForbidden,
+    http.StatusNotFound:              CodeNotFound,
+    http.StatusConflict:              CodeConflict,
+    http.StatusGone:                  CodeGone,
+    http.StatusPreconditionFailed:    CodePreconditionFailed,
+    http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+    http.StatusUnsupportedMediaType:  CodeUnsupportedMediaType,
+    http.StatusUnprocessableEntity:   CodeValidationFailed,
+    http.StatusLocked:                CodeLocked,
+    http.StatusTooManyRequests:       CodeRateLimited,
+    http.StatusInternalServerError:   CodeInternal,
+    http.StatusBadGateway:            CodeUpstreamFailed,
+    http.StatusServiceUnavailable:    CodeUnavailable,
+    http.StatusGatewayTimeout:        CodeUpstreamFailed,
+}
+
+// errorCode picks the code of an error response: the handler's own "code",
+// then the message's, then the status's
+func errorCode(status int, message string, fields map[string]any) ErrorCode {
+    if code, ok := fields["code"].(string); ok && code != "" {
+        return ErrorCode(code)
+    }
+    if code, ok := messageCodes[message]; ok {
+        return code
+    }
+    if code, ok := statusCodes[status]; ok {
+        return code
+    }
+    if status >= http.StatusInternalServerError {
+        return CodeInternal
+    }
+    return CodeInvalidRequest
+}
+
+// problemResponses rewrites JSON error responses, {"error": "..."} with a
+// 4xx or 5xx status, as RFC 7807 application/problem+json:
+//
+//  {"type": "about:blank", "title": "Not Found", "status": 404,
+//   "detail": "Room not found", "instance": "/room/ABC123",
+//   "code": "ROOM_NOT_FOUND", "error": "Room not found"}
+//
+// The rest of the original body is kept as extension members, and "error"
+// stays for clients that read it. Messages end users see are translated
+// per Accept-Language (see localize). With PROBLEM_TYPE_BASE set the type is
+// that URL followed by the lower-cased code, e.g. .../room_not_found.
+// Handlers name a code of their own by putting it in the body as "code".
+// Requests the problem-details flag is off for keep the plain bodies.
+func (a *API) problemResponses() gin.HandlerFunc {
+    typeBase := os.Getenv("PROBLEM_TYPE_BASE")
+
+    return func(c *gin.Context) {
+        original := c.Writer
+        w := &problemWriter{ResponseWriter: original}
+        c.Writer = w
+        defer func() { c.Writer = original }()
+
+        c.Next()
+
+        if !w.holding {
+            return
+        }
+        if !a.flagOn(c, flagProblemDetails) {
+            original.Write(w.buf.Bytes())
+            return
+        }
+        body := w.buf.Bytes()
+        var fields map[string]any
+        if json.Unmarshal(body, &fields) == nil {
+            if message, ok := fields["error"].(string); ok {
+                status := w.Status()
+                code := errorCode(status, message, fields)
+                message, lang := localize(c.GetHeader("Accept-Language"), code, message)
+                if lang != "" {
+                    original.Header().Set("Content-Language", lang)
+                    original.Header().Add("Vary", "Accept-Language")
+                }
+                fields["error"] = message
+                fields["type"] = "about:blank"
+                if typeBase != "" {
+                    fields["type"] = typeBase + strings.ToLower(string(code))
+                }
+                fields["title"] = http.StatusText(status)
+                fields["status"] = status
+                fields["detail"] = message
+                fields["instance"] = c.Request.URL.Path
+                fields["code"] = code
+                if problem, err := json.Marshal(fields); err == nil {
+                    body = problem
+                    original.Header().Set("Content-Type", "application/problem+json")
+                    original.Header().Del("Content-Length")
+                }
+            }
+        }
+        original.Write(body)
+    }
+}
+
+// problemWriter holds back JSON error responses so problemResponses can
+// rewrite them, and passes everything else straight through
+type problemWriter struct {
+    gin.ResponseWriter
+    buf     bytes.Buffer
+    decided bool
+    holding bool
+}
+
+// hold decides, on the first write, whether the response is held back
+func (w *problemWriter) hold() bool {
+    if !w.decided {
+        w.decided = true
+        w.holding = w.Status() >= http.StatusBadRequest &&
+            strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") &&
+            w.Header().Get("Content-Encoding") == ""
+    }
+    return w.holding
+}
+
+func (w *problemWriter) Write(data []byte) (int, error) {
+    if w.hold() {
+        return w.buf.Write(data)
+    }
+    return w.ResponseWriter.Write(data)
+}
+
+func (w *problemWriter) WriteString(s string) (int, error) {
+    if w.hold() {
+        return w.buf.WriteString(s)
+    }
+    return w.ResponseWriter.WriteString(s)
+}
+
+func (w *problemWriter) Written() bool {
+    return w.holding || w.ResponseWriter.Written()
+}
+
+func (w *problemWriter) Flush() {
+    if w.holding {
+        // A streamed error can't be rewritten; send it as it is
+        w.holding = false
+        w.ResponseWriter.Write(w.buf.Bytes())
+        w.buf.Reset()
+    }
+    w.ResponseWriter.Flush()
+}
@@ -0,0 +1,161 @@
+package httpapi
+
+import (
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/hooks"
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// maxQueuedProgress bounds the transfer_progress events waiting for one
+// peer, so an observer that stops polling doesn't collect them forever
+const maxQueuedProgress = 100
+
+// reportProgress takes a progress report for a transfer from its sender or
+// its receiver and passes it on, coarsely, to every room member who can see
+// the file as a transfer_progress event. Reaching the file's size, or 100
+// percent, completes the transfer.
+func (a *API) reportProgress(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+
+    var req struct {
+        PeerID string `json:"peerId" binding:"required"`
+        // ReceiverID names the receiver when the sender reports; receivers
+        // may leave it out
+        ReceiverID     string `json:"receiverId"`
+        Bytes          int64  `json:"bytes"`
+        Percent        *int   `json:"percent"`
+        BytesPerSecond int64  `json:"bytesPerSecond"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.PeerID)
+
+    if req.ReceiverID == "" {
+        req.ReceiverID = req.PeerID
+    }
+    if req.Bytes < 0 || req.BytesPerSecond < 0 || (req.Percent != nil && (*req.Percent < 0 || *req.Percent > 100)) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid progress"})
+        return
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    file, ok := room.Files[fileID]
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return
+    }
+    if req.PeerID != file.PeerID && req.PeerID != req.ReceiverID {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the sender or receiver can report progress"})
+        return
+    }
+    owner, receiver := room.Peers[file.PeerID], room.Peers[req.ReceiverID]
+    if receiver == nil || req.ReceiverID == file.PeerID || owner == nil || !rooms.CanTransfer(owner, receiver) {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Peer is not a receiver of this file"})
+        return
+    }
+
+    percent := 0
+    switch {
+    case file.Size > 0:
+        if req.Bytes > file.Size {
+            room.Unlock()
+            c.JSON(http.StatusBadRequest, gin.H{"error": "bytes is larger than the file"})
+            return
+        }
+        percent = int(req.Bytes * 100 / file.Size)
+    case req.Percent != nil:
+        percent = *req.Percent
+    }
+
+    now := time.Now()
+    transfer := room.Transfer(fileID, req.ReceiverID, now.Unix())
+    if transfer.State == rooms.TransferDeclined {
+        room.Unlock()
+        c.JSON(http.StatusConflict, gin.H{"error": "The request for this file was declined"})
+        return
+    }
+    wasCompleted := transfer.State == rooms.TransferCompleted
+    announce := transfer.ReportProgress(req.Bytes, percent, req.BytesPerSecond, now)
+    result := *transfer
+    completed := !wasCompleted && result.State == rooms.TransferCompleted
+    tenant, sender := room.Tenant, file.PeerID
+    var observers []string
+    if announce {
+        for peerID := range room.Peers {
+            if peerID != req.PeerID && room.FileVisible(file, peerID) {
+                observers = append(observers, peerID)
+            }
+        }
+    }
+    room.Unlock()
+
+    n := notifications.Notification{
+        Type:      "transfer_progress",
+        PeerID:    req.PeerID,
+        Timestamp: now.Unix(),
+        Payload:   notifications.Payload(result),
+    }
+    for _, peerID := range observers {
+        a.notifications.QueueLimited(peerID, n, maxQueuedProgress)
+    }
+    if completed {
+        a.hooks.TransferComplete(c.Request.Context(), hooks.TransferComplete{
+            RoomCode:   roomCode,
+            FileID:     fileID,
+            SenderID:   sender,
+            ReceiverID: req.ReceiverID,
+            Bytes:      req.Bytes,
+            Tenant:     tenant,
+        })
+    }
+
+    c.JSON(http.StatusOK, gin.H{"transfer": result, "announced": announce})
+}
+
+// getRoomTransfers lists the transfers of every file the peer can see in
+// the room, most recently active first, so a host can follow them all
+func (a *API) getRoomTransfers(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    peerID := c.Query("peerId")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    _, member := room.Peers[peerID]
+    var transfers []rooms.Transfer
+    if member {
+        transfers = room.VisibleTransfers(peerID)
+    }
+    room.RUnlock()
+
+    if !member {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Peer not in room"})
+        return
+    }
+    if transfers == nil {
+        transfers = []rooms.Transfer{}
+    }
+    c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
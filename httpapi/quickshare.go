@@ -0,0 +1,367 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "os"
+    "slices"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    defaultQuickShareTTL = time.Hour
+    maxQuickShareTTL     = 24 * time.Hour
+    maxQuickShareClaims  = 10
+    quickShareRoomPrefix = "qs-"
+    quickShareSlugLength = 8
+    quickSharesPerHour   = 20 // per IP
+    quickClaimsPerMinute = 10 // per IP, right or wrong
+)
+
+// quickShare is a link offering one file to its first visitors
+type quickShare struct {
+    RoomCode  string
+    FileID    string
+    SenderID  string
+    ExpiresAt time.Time
+    MaxClaims int
+    Claims    []string
+}
+
+// quickShareTable holds the live quick-share links by slug
+type quickShareTable struct {
+    mu     sync.Mutex
+    shares map[string]*quickShare
+}
+
+// quickShareURL returns the frontend URL that opens a quick-share link. It
+// carries the exp, kid and sig of a signed claim path, valid until the link
+// expires, for the frontend to pass on to POST /s/:slug/claim.
+func quickShareURL(slug string, expiresAt time.Time) string {
+    base := os.Getenv("FRONTEND_URL")
+    if base == "" {
+        base = defaultFrontendURL
+    }
+    _, signature, _ := strings.Cut(signPath("/s/"+slug+"/claim", nil, time.Until(expiresAt)), "?")
+    return strings.TrimRight(base, "/") + "/?share=" + slug + "&" + signature
+}
+
+// createQuickShare offers one file through a link: a locked room is created
+// holding just the sender and the file, and the first maxClaims visitors to
+// claim the link are admitted to it as receivers.
+func (a *API) createQuickShare(c *gin.Context) {
+    var req struct {
+        PeerID   string `json:"peerId" binding:"required"`
+        Name     string `json:"name" binding:"required"`
+        Size     int64  `json:"size"`
+        MimeType string `json:"mimeType"`
+        // TTL is in seconds (default one hour, at most a day)
+        TTL       int `json:"ttl"`
+        MaxClaims int `json:"maxClaims"`
+        rooms.PeerProfile
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, "", req.PeerID)
+
+    if len(req.Name) > rooms.MaxFileNameLength || req.Size < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file name or size"})
+        return
+    }
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if req.MaxClaims == 0 {
+        req.MaxClaims = 1
+    }
+    if req.MaxClaims < 1 || req.MaxClaims > maxQuickShareClaims {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "maxClaims must be between 1 and " + strconv.Itoa(maxQuickShareClaims)})
+        return
+    }
+    ttl := defaultQuickShareTTL
+    if req.TTL > 0 {
+        ttl = min(time.Duration(req.TTL)*time.Second, maxQuickShareTTL)
+    }
+    if !a.shareLimiter.Allow(c.ClientIP()) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+        return
+    }
+
+    roomCode, room, ok := a.createPrivateRoom(c, quickShareRoomPrefix, req.PeerID)
+    if !ok {
+        return
+    }
+
+    room.Lock()
+    role, permissions := admitPeer(room, req.PeerID, req.PeerProfile)
+    room.Unlock()
+
+    // The offer goes through the same checks as one made in the room, and
+    // the room goes if it is refused
+    file := a.registerOffer(c, roomCode, room, offerRequest{
+        PeerID:   req.PeerID,
+        Name:     req.Name,
+        Size:     req.Size,
+        MimeType: req.MimeType,
+    })
+    if file == nil {
+        a.rooms.Delete(roomCode)
+        return
+    }
+
+    share := &quickShare{
+        RoomCode:  roomCode,
+        FileID:    file.FileID,
+        SenderID:  req.PeerID,
+        ExpiresAt: time.Now().Add(ttl),
+        MaxClaims: req.MaxClaims,
+    }
+    a.quickShares.mu.Lock()
+    var slug string
+    for {
+        var err error
+        slug, err = randomCode(shortLinkAlphabet, quickShareSlugLength)
+        if err != nil {
+            a.quickShares.mu.Unlock()
+            log.Printf("❌ Failed to generate quick-share link: %v", err)
+            c.Error(err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate link"})
+            return
+        }
+        if _, taken := a.quickShares.shares[slug]; !taken {
+            break
+        }
+    }
+    a.quickShares.shares[slug] = share
+    a.quickShares.mu.Unlock()
+
+    a.recordRecentRoom(req.PeerID, roomCode, role)
+    log.Printf("🔗 Quick share created: %s → %s in Room: %s", slug, file.Name, roomCode)
+    a.recordAudit(roomCode, "quick_share_created", req.PeerID, "", gin.H{"fileId": file.FileID, "maxClaims": req.MaxClaims})
+
+    c.JSON(http.StatusCreated, gin.H{
+        "slug":        slug,
+        "url":         signedURL(c, "/s/"+slug, nil, ttl),
+        "shareUrl":    quickShareURL(slug, share.ExpiresAt),
+        "claimUrl":    signedURL(c, "/s/"+slug+"/claim", nil, ttl),
+        "roomCode":    roomCode,
+        "file":        file,
+        "expiresAt":   share.ExpiresAt.Unix(),
+        "maxClaims":   share.MaxClaims,
+        "role":        role,
+        "permissions": permissions,
+        "resumeToken": a.issueResumeToken(roomCode, req.PeerID),
+    })
+}
+
+// followQuickShare sends a browser to the frontend, which claims the link.
+// Visiting does not claim it, so link previews can't use it up.
+func (a *API) followQuickShare(c *gin.Context) {
+    slug := c.Param("slug")
+
+    a.quickShares.mu.Lock()
+    share, ok := a.quickShares.shares[slug]
+    var remaining int
+    var expiresAt time.Time
+    if ok {
+        remaining = share.MaxClaims - len(share.Claims)
+        expiresAt = share.ExpiresAt
+    }
+    a.quickShares.mu.Unlock()
+
+    if !ok || time.Now().After(expiresAt) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Link not found or expired"})
+        return
+    }
+    if remaining <= 0 {
+        c.JSON(http.StatusGone, gin.H{"error": "Link has already been used"})
+        return
+    }
+    c.Redirect(http.StatusFound, quickShareURL(slug, expiresAt))
+}
+
+// claimQuickShare admits the claimer to the share's room as a receiver and
+// returns the same response as joining it, with the shared file. The sender
+// is told with a quick_share_claimed event. A peer claiming again keeps the
+// membership it has and uses up no other claim. Claims must be signed, with
+// the signature from the share's shareUrl or claimUrl.
+func (a *API) claimQuickShare(c *gin.Context) {
+    slug := c.Param("slug")
+
+    var req struct {
+        PeerID string `json:"peerId" binding:"required"`
+        rooms.PeerProfile
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, "", req.PeerID)
+
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if !a.claimLimiter.Allow(c.ClientIP()) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+        return
+    }
+
+    a.quickShares.mu.Lock()
+    share, ok := a.quickShares.shares[slug]
+    if !ok || time.Now().After(share.ExpiresAt) {
+        a.quickShares.mu.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "Link not found or expired"})
+        return
+    }
+    if req.PeerID == share.SenderID {
+        a.quickShares.mu.Unlock()
+        c.JSON(http.StatusBadRequest, gin.H{"error": "The sender cannot claim its own link"})
+        return
+    }
+    claimed := false
+    for _, id := range share.Claims {
+        if id == req.PeerID {
+            claimed = true
+            break
+        }
+    }
+    if !claimed {
+        if len(share.Claims) >= share.MaxClaims {
+            a.quickShares.mu.Unlock()
+            c.JSON(http.StatusGone, gin.H{"error": "Link has already been used"})
+            return
+        }
+        share.Claims = append(share.Claims, req.PeerID)
+    }
+    roomCode, fileID, senderID := share.RoomCode, share.FileID, share.SenderID
+    a.quickShares.mu.Unlock()
+
+    // A refused claim doesn't use up the link
+    refuse := func(status int, errMsg string) {
+        if !claimed {
+            a.quickShares.mu.Lock()
+            share.Claims = slices.DeleteFunc(share.Claims, func(id string) bool { return id == req.PeerID })
+            a.quickShares.mu.Unlock()
+        }
+        c.JSON(status, gin.H{"error": errMsg})
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusGone, gin.H{"error": "The sender has left"})
+        return
+    }
+    if !claimed {
+        if status, errMsg := a.checkJoin(c, roomCode, req.PeerID, req.PeerProfile); errMsg != "" {
+            refuse(status, errMsg)
+            return
+        }
+    }
+    room.Lock()
+    if room.Suspended {
+        room.Unlock()
+        refuse(http.StatusLocked, "Room is suspended pending review")
+        return
+    }
+    file, ok := room.Files[fileID]
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusGone, gin.H{"error": "The file is no longer offered"})
+        return
+    }
+    offer := *file
+    existingPeers := room.ConnectedPeers(req.PeerID)
+    // A peer claiming again keeps the membership it has
+    var role, permissions string
+    member, admitted := room.Peers[req.PeerID], false
+    if member != nil {
+        role, permissions = member.Role, member.Permissions
+    } else {
+        role, permissions = admitPeer(room, req.PeerID, req.PeerProfile)
+        admitted = true
+    }
+    roomSize := len(room.Peers)
+    var joined notifications.Notification
+    if admitted {
+        joined = roomEvent(room, req.PeerID, notifications.Notification{
+            Type:      "peer_joined",
+            PeerID:    req.PeerID,
+            Timestamp: time.Now().Unix(),
+        })
+    }
+    roomSeq := room.EventSeq()
+    room.Unlock()
+
+    if admitted {
+        a.dispatcher.Fanout(existingPeers, joined)
+        log.Printf("✅ Peer joined: %s → Room: %s (quick share %s)", req.PeerID, roomCode, slug)
+        a.recordRecentRoom(req.PeerID, roomCode, role)
+    }
+    if !claimed {
+        a.queueNotification(senderID, notifications.Notification{
+            Type:      "quick_share_claimed",
+            PeerID:    req.PeerID,
+            Timestamp: time.Now().Unix(),
+            Payload:   notifications.Payload(gin.H{"slug": slug, "roomCode": roomCode, "fileId": fileID}),
+        })
+        a.recordAudit(roomCode, "peer_joined", req.PeerID, "", gin.H{"quickShare": slug})
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "roomCode":    roomCode,
+        "file":        offer,
+        "peers":       existingPeers,
+        "roomSize":    roomSize,
+        "role":        role,
+        "permissions": permissions,
+        "roomSeq":     roomSeq,
+        "resumeToken": a.issueResumeToken(roomCode, req.PeerID),
+    })
+}
+
+// pruneQuickShares drops expired quick-share links
+func (a *API) pruneQuickShares() {
+    now := time.Now()
+    a.quickShares.mu.Lock()
+    defer a.quickShares.mu.Unlock()
+    for slug, share := range a.quickShares.shares {
+        if now.After(share.ExpiresAt) {
+            delete(a.quickShares.shares, slug)
+        }
+    }
+}
+
+// eraseQuickShares drops the links peerID shared and blanks its claims on
+// others' links, returning how many links it appeared in. A blanked claim
+// still uses up its slot so erasing can't reopen a single-use link.
+func (a *API) eraseQuickShares(peerID string) int {
+    a.quickShares.mu.Lock()
+    defer a.quickShares.mu.Unlock()
+
+    removed := 0
+    for slug, share := range a.quickShares.shares {
+        if share.SenderID == peerID {
+            delete(a.quickShares.shares, slug)
+            removed++
+            continue
+        }
+        if i := slices.Index(share.Claims, peerID); i >= 0 {
+            share.Claims[i] = ""
+            removed++
+        }
+    }
+    return removed
+}
@@ -0,0 +1,187 @@
+package httpapi
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "testing"
+    "time"
+
+    "p2p-file-share-backend/hooks"
+    "p2p-file-share-backend/rooms"
+)
+
+// newQuickShare has peer-a share a file with up to maxClaims claimers and
+// returns the API, its router and the link's slug
+func newQuickShare(t *testing.T, maxClaims int) (*API, http.Handler, string) {
+    t.Helper()
+    registry := &hooks.Registry{}
+    registry.OnPeerJoin(func(ctx context.Context, e hooks.PeerJoin) error {
+        if e.PeerID == "refused" {
+            return hooks.Refuse(http.StatusForbidden, "refused by hook")
+        }
+        return nil
+    })
+    a := New(Config{AllowedOrigins: testOrigins, Hooks: registry})
+    h := a.Router()
+    body := fmt.Sprintf(`{"peerId":"peer-a","name":"a.txt","size":1,"maxClaims":%d}`, maxClaims)
+    w := request(h, http.MethodPost, "/share", "application/json", "", body)
+    var resp struct {
+        Slug string `json:"slug"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Slug == "" {
+        t.Fatalf("create share: %d %s", w.Code, w.Body)
+    }
+    return a, h, resp.Slug
+}
+
+// claimPath returns the signed path claiming a quick-share link
+func claimPath(slug string) string {
+    return signPath("/s/"+slug+"/claim", nil, time.Hour)
+}
+
+// quickShareRoom returns the room behind a quick-share link
+func quickShareRoom(a *API, slug string) *rooms.Room {
+    a.quickShares.mu.Lock()
+    roomCode := a.quickShares.shares[slug].RoomCode
+    a.quickShares.mu.Unlock()
+    room, _ := a.rooms.Get(roomCode)
+    return room
+}
+
+func TestClaimQuickShare(t *testing.T) {
+    type claim struct {
+        peerID string
+        want   int
+    }
+    tests := []struct {
+        name      string
+        maxClaims int
+        setup     func(a *API, slug string)
+        claims    []claim
+    }{
+        {"claim", 1, nil, []claim{{"peer-b", http.StatusOK}}},
+        {"sender claims its own link", 1, nil, []claim{{"peer-a", http.StatusBadRequest}, {"peer-b", http.StatusOK}}},
+        {"past maxClaims", 2, nil, []claim{{"peer-b", http.StatusOK}, {"peer-c", http.StatusOK}, {"peer-d", http.StatusGone}}},
+        {"claiming again uses no claim", 2, nil, []claim{{"peer-b", http.StatusOK}, {"peer-b", http.StatusOK}, {"peer-c", http.StatusOK}}},
+        {"expired", 1, func(a *API, slug string) {
+            a.quickShares.mu.Lock()
+            a.quickShares.shares[slug].ExpiresAt = time.Now().Add(-time.Second)
+            a.quickShares.mu.Unlock()
+        }, []claim{{"peer-b", http.StatusNotFound}}},
+        {"refused claim gives the slot back", 1, nil, []claim{{"refused", http.StatusForbidden}, {"peer-b", http.StatusOK}}},
+        {"suspended room", 1, func(a *API, slug string) {
+            room := quickShareRoom(a, slug)
+            room.Lock()
+            room.Suspended = true
+            room.Unlock()
+        }, []claim{{"peer-b", http.StatusLocked}}},
+        {"unknown link", 1, func(a *API, slug string) {
+            a.quickShares.mu.Lock()
+            delete(a.quickShares.shares, slug)
+            a.quickShares.mu.Unlock()
+        }, []claim{{"peer-b", http.StatusNotFound}}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            a, h, slug := newQuickShare(t, tt.maxClaims)
+            if tt.setup != nil {
+                tt.setup(a, slug)
+            }
+            for i, cl := range tt.claims {
+                w := request(h, http.MethodPost, claimPath(slug), "application/json", issuePeerToken(cl.peerID), `{"peerId":"`+cl.peerID+`"}`)
+                if w.Code != cl.want {
+                    t.Fatalf("claim %d by %s: status = %d, want %d: %s", i, cl.peerID, w.Code, cl.want, w.Body)
+                }
+            }
+        })
+    }
+}
+
+func TestClaimQuickShareAgainKeepsMembership(t *testing.T) {
+    a, h, slug := newQuickShare(t, 1)
+    tokenB := issuePeerToken("peer-b")
+    claim := func() map[string]any {
+        w := request(h, http.MethodPost, claimPath(slug), "application/json", tokenB, `{"peerId":"peer-b"}`)
+        if w.Code != http.StatusOK {
+            t.Fatalf("claim: status = %d: %s", w.Code, w.Body)
+        }
+        var resp map[string]any
+        json.Unmarshal(w.Body.Bytes(), &resp)
+        return resp
+    }
+    claim()
+
+    room := quickShareRoom(a, slug)
+    room.Lock()
+    room.Peers["peer-b"].Permissions = rooms.PermissionsReceiveOnly
+    joinedAt := room.Peers["peer-b"].JoinedAt
+    room.Peers["peer-b"].JoinedAt = joinedAt - 100
+    room.Unlock()
+
+    resp := claim()
+    if resp["permissions"] != rooms.PermissionsReceiveOnly {
+        t.Errorf("permissions after claiming again = %v, want %s", resp["permissions"], rooms.PermissionsReceiveOnly)
+    }
+    room.RLock()
+    defer room.RUnlock()
+    if room.Peers["peer-b"].JoinedAt != joinedAt-100 {
+        t.Error("claiming again replaced the member")
+    }
+}
+
+func TestQuickShareSignedLinks(t *testing.T) {
+    // Another share on the same server, to see every link it hands out
+    _, h, _ := newQuickShare(t, 1)
+    w := request(h, http.MethodPost, "/share", "application/json", "", `{"peerId":"peer-a","name":"a.txt","size":1}`)
+    var created struct {
+        Slug     string `json:"slug"`
+        URL      string `json:"url"`
+        ShareURL string `json:"shareUrl"`
+        ClaimURL string `json:"claimUrl"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+        t.Fatal(err)
+    }
+    slug := created.Slug
+    share, err := url.Parse(created.ShareURL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // The frontend passes the share URL's signature on to the claim
+    signature := share.Query()
+    signature.Del("share")
+    claimURL, _ := url.Parse(created.ClaimURL)
+    redirect, _ := url.Parse(created.URL)
+
+    tests := []struct {
+        name   string
+        method string
+        path   string
+        want   int
+    }{
+        {"redirect, signed", http.MethodGet, redirect.RequestURI(), http.StatusFound},
+        {"redirect, unsigned", http.MethodGet, "/s/" + slug, http.StatusForbidden},
+        {"claim, unsigned", http.MethodPost, "/s/" + slug + "/claim", http.StatusForbidden},
+        {"claim, signed for another link", http.MethodPost, "/s/other/claim?" + signature.Encode(), http.StatusForbidden},
+        {"claim, with the share URL's signature", http.MethodPost, "/s/" + slug + "/claim?" + signature.Encode(), http.StatusOK},
+        {"claim, claimUrl", http.MethodPost, claimURL.RequestURI(), http.StatusOK},
+    }
+    tokenB := issuePeerToken("peer-b")
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            w := request(h, tt.method, tt.path, "application/json", tokenB, `{"peerId":"peer-b"}`)
+            if w.Code != tt.want {
+                t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+            }
+            if w.Code == http.StatusFound {
+                location, _ := url.Parse(w.Header().Get("Location"))
+                if location.Query().Get("share") != slug || location.Query().Get("sig") == "" {
+                    t.Errorf("redirect to unsigned %s", location)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+    "net/http"
+    "time"
+
+    "p2p-file-share-backend/rooms"
+)
+
+// Room creation limits, protecting the in-memory state from exhaustion
+type roomQuotaConfig struct {
+    perIPPerHour    int // new rooms one IP may create per hour
+    perIPConcurrent int // rooms created by one IP that may exist at once
+    globalMax       int // total rooms across the instance
+    retryAfter      time.Duration
+
+    hourly *rateLimiter
+}
+
+// loadRoomQuotas reads the room creation limits from the environment
+func loadRoomQuotas() *roomQuotaConfig {
+    q := &roomQuotaConfig{
+        perIPPerHour:    envInt("ROOMS_PER_IP_PER_HOUR", 30),
+        perIPConcurrent: envInt("MAX_ROOMS_PER_IP", 10),
+        globalMax:       envInt("MAX_ROOMS", 10000),
+        retryAfter:      envDuration("ROOMS_RETRY_AFTER", time.Minute),
+    }
+    q.hourly = newRateLimiter(q.perIPPerHour, time.Hour)
+    return q
+}
+
+// quotaError describes why a room creation was refused
+type quotaError struct {
+    status  int
+    message string
+    retry   bool
+}
+
+func (e *quotaError) Error() string {
+    return e.message
+}
+
+// checkRoomQuotas decides whether ip may create another room, given a
+// census of the existing rooms. It runs inside rooms.Store.Create so the
+// counts can't change underneath it.
+// A limit of zero or less disables that check.
+func (a *API) checkRoomQuotas(census rooms.Census, ip string) *quotaError {
+    q := a.quotas
+
+    if q.globalMax > 0 && census.Total >= q.globalMax {
+        return &quotaError{status: http.StatusServiceUnavailable, message: "Server is at room capacity, try again later", retry: true}
+    }
+
+    if q.perIPConcurrent > 0 && census.CreatedBy(ip) >= q.perIPConcurrent {
+        return &quotaError{status: http.StatusTooManyRequests, message: "Too many open rooms from this address"}
+    }
+
+    if q.perIPPerHour > 0 && !q.hourly.Allow(ip) {
+        return &quotaError{status: http.StatusTooManyRequests, message: "Room creation rate limit exceeded", retry: true}
+    }
+
+    a.anomalies.observeIP(signalRooms, ip)
+    a.overview.count(overviewRoomsCreated)
+    return nil
+}
@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+    "sync"
+    "time"
+)
+
+// rateLimiter is a fixed-window counter keyed by an arbitrary string
+// (peer ID, IP, ...)
+type rateLimiter struct {
+    limit  int
+    window time.Duration
+    hits   map[string]*rateWindow
+    mu     sync.Mutex
+}
+
+type rateWindow struct {
+    start time.Time
+    count int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+    return &rateLimiter{
+        limit:  limit,
+        window: window,
+        hits:   make(map[string]*rateWindow),
+    }
+}
+
+// Allow records a hit for key and reports whether it is within the limit. A
+// nil limiter allows everything; dev mode uses that to switch limits off.
+func (rl *rateLimiter) Allow(key string) bool {
+    if rl == nil {
+        return true
+    }
+
+    rl.mu.Lock()
+    defer rl.mu.Unlock()
+
+    now := time.Now()
+    w, ok := rl.hits[key]
+    if !ok || now.Sub(w.start) >= rl.window {
+        // Drop expired windows while we hold the lock so the map can't grow forever
+        for k, old := range rl.hits {
+            if now.Sub(old.start) >= rl.window {
+                delete(rl.hits, k)
+            }
+        }
+        rl.hits[key] = &rateWindow{start: now, count: 1}
+        return true
+    }
+
+    if w.count >= rl.limit {
+        return false
+    }
+    w.count++
+    return true
+}
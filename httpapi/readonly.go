@@ -0,0 +1,146 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const defaultReadOnlyMessage = "The service is read-only for maintenance, try again shortly"
+
+// readOnlySafePaths are mutating routes that keep working in read-only mode:
+// heartbeats, so peers aren't expired for the duration, and NAT
+// classification, which stores nothing
+var readOnlySafePaths = map[string]bool{
+    "/room/heartbeat": true,
+    "/nat/classify":   true,
+}
+
+// readOnlyState tracks read-only mode, set with PUT /admin/readonly while
+// the store is migrated. Mutating requests are refused with 503, either to
+// every route or only to the routes given; reads, including notification
+// drains, carry on.
+type readOnlyState struct {
+    mu      sync.RWMutex
+    active  bool
+    since   int64
+    message string
+    // routes are the route patterns refused, or all if empty
+    routes map[string]bool
+}
+
+// refuseWrites answers 503 to mutating requests while in read-only mode.
+// The admin API stays writable so the mode can be lifted.
+func (a *API) refuseWrites() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        switch c.Request.Method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+            c.Next()
+            return
+        }
+        route := c.FullPath()
+        if strings.HasPrefix(route, "/admin/") || readOnlySafePaths[route] {
+            c.Next()
+            return
+        }
+
+        if message, refused := a.readOnlyRefusal(route); refused {
+            c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": message, "code": CodeReadOnly})
+            return
+        }
+        c.Next()
+    }
+}
+
+// readOnlyRefusal returns the read-only message if writes to route are
+// refused. WebTransport messages count as POST /messages.
+func (a *API) readOnlyRefusal(route string) (string, bool) {
+    a.readOnly.mu.RLock()
+    defer a.readOnly.mu.RUnlock()
+    refused := a.readOnly.active && (len(a.readOnly.routes) == 0 || a.readOnly.routes[route])
+    return a.readOnly.message, refused
+}
+
+// setReadOnly turns read-only mode on, or updates its message and routes.
+// Routes are patterns as registered, like "/room/:roomCode/files".
+func (a *API) setReadOnly(c *gin.Context) {
+    var req struct {
+        Message string   `json:"message" binding:"max=500"`
+        Routes  []string `json:"routes" binding:"max=100"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    routes := make(map[string]bool, len(req.Routes))
+    for _, route := range req.Routes {
+        if !strings.HasPrefix(route, "/") || strings.HasPrefix(route, "/admin/") {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "routes must be paths outside /admin/, like /room/:roomCode/files"})
+            return
+        }
+        routes[route] = true
+    }
+    if req.Message == "" {
+        req.Message = defaultReadOnlyMessage
+    }
+
+    a.readOnly.mu.Lock()
+    started := !a.readOnly.active
+    if started {
+        a.readOnly.active = true
+        a.readOnly.since = time.Now().Unix()
+    }
+    a.readOnly.message = req.Message
+    a.readOnly.routes = routes
+    a.readOnly.mu.Unlock()
+
+    if started {
+        log.Printf("🚧 Read-only mode: refusing writes")
+    }
+    a.recordAudit("", "read_only_set", "admin", "", gin.H{"message": req.Message, "routes": req.Routes})
+    c.JSON(http.StatusOK, a.readOnlyStatus())
+}
+
+// clearReadOnly makes the service writable again
+func (a *API) clearReadOnly(c *gin.Context) {
+    a.readOnly.mu.Lock()
+    wasActive := a.readOnly.active
+    a.readOnly.active = false
+    a.readOnly.since = 0
+    a.readOnly.message = ""
+    a.readOnly.routes = nil
+    a.readOnly.mu.Unlock()
+
+    if wasActive {
+        log.Printf("🚧 Read-only mode lifted: accepting writes again")
+        a.recordAudit("", "read_only_cleared", "admin", "", nil)
+    }
+    c.JSON(http.StatusOK, a.readOnlyStatus())
+}
+
+func (a *API) getReadOnly(c *gin.Context) {
+    c.JSON(http.StatusOK, a.readOnlyStatus())
+}
+
+func (a *API) readOnlyStatus() gin.H {
+    a.readOnly.mu.RLock()
+    defer a.readOnly.mu.RUnlock()
+
+    status := gin.H{"readOnly": a.readOnly.active}
+    if a.readOnly.active {
+        routes := make([]string, 0, len(a.readOnly.routes))
+        for route := range a.readOnly.routes {
+            routes = append(routes, route)
+        }
+        sort.Strings(routes)
+        status["since"] = a.readOnly.since
+        status["message"] = a.readOnly.message
+        status["routes"] = routes
+    }
+    return status
+}
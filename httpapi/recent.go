@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    // maxRecentRooms bounds the history kept per peer
+    maxRecentRooms = 20
+    // recentRoomsTTL is how long a room stays in a peer's history after it
+    // last joined
+    recentRoomsTTL = 30 * 24 * time.Hour
+)
+
+// recentRoom is one entry in a peer's room history
+type recentRoom struct {
+    RoomCode      string `json:"roomCode"`
+    Role          string `json:"role"`
+    FirstJoinedAt int64  `json:"firstJoinedAt"`
+    LastJoinedAt  int64  `json:"lastJoinedAt"`
+    Joins         int    `json:"joins"`
+}
+
+// recentRoomHistory remembers which rooms each peer joined, newest last, so
+// the frontend can offer a quick rejoin
+type recentRoomHistory struct {
+    mu    sync.Mutex
+    peers map[string][]recentRoom
+}
+
+// recordRecentRoom adds or refreshes roomCode in peerID's history
+func (a *API) recordRecentRoom(peerID, roomCode, role string) {
+    now := time.Now().Unix()
+
+    a.recent.mu.Lock()
+    defer a.recent.mu.Unlock()
+
+    history := a.recent.peers[peerID]
+    entry := recentRoom{RoomCode: roomCode, FirstJoinedAt: now}
+    for i, r := range history {
+        if r.RoomCode == roomCode {
+            entry = r
+            history = append(history[:i], history[i+1:]...)
+            break
+        }
+    }
+    entry.Role = role
+    entry.LastJoinedAt = now
+    entry.Joins++
+    history = append(history, entry)
+    if len(history) > maxRecentRooms {
+        history = history[len(history)-maxRecentRooms:]
+    }
+    a.recent.peers[peerID] = history
+}
+
+// eraseRecentRooms forgets peerID's history and returns how many entries it
+// held
+func (a *API) eraseRecentRooms(peerID string) int {
+    a.recent.mu.Lock()
+    defer a.recent.mu.Unlock()
+    n := len(a.recent.peers[peerID])
+    delete(a.recent.peers, peerID)
+    return n
+}
+
+// pruneRecentRooms drops history entries older than recentRoomsTTL
+func (a *API) pruneRecentRooms() {
+    cutoff := time.Now().Add(-recentRoomsTTL).Unix()
+
+    a.recent.mu.Lock()
+    defer a.recent.mu.Unlock()
+    for peerID, history := range a.recent.peers {
+        kept := history[:0]
+        for _, r := range history {
+            if r.LastJoinedAt > cutoff {
+                kept = append(kept, r)
+            }
+        }
+        if len(kept) == 0 {
+            delete(a.recent.peers, peerID)
+        } else {
+            a.recent.peers[peerID] = kept
+        }
+    }
+}
+
+// getRecentRooms lists the rooms the bearer-token peer joined recently, most
+// recent first, with whether each room is still open and whether the peer is
+// still a member
+func (a *API) getRecentRooms(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    tagRequest(c, "", peerID)
+
+    cutoff := time.Now().Add(-recentRoomsTTL).Unix()
+    a.recent.mu.Lock()
+    stored := a.recent.peers[peerID]
+    history := make([]recentRoom, 0, len(stored))
+    for i := len(stored) - 1; i >= 0; i-- {
+        if stored[i].LastJoinedAt > cutoff {
+            history = append(history, stored[i])
+        }
+    }
+    a.recent.mu.Unlock()
+
+    type recentRoomStatus struct {
+        recentRoom
+        Open     bool `json:"open"`
+        Member   bool `json:"member"`
+        RoomSize int  `json:"roomSize,omitempty"`
+    }
+    result := make([]recentRoomStatus, 0, len(history))
+    for _, r := range history {
+        status := recentRoomStatus{recentRoom: r}
+        if room, exists := a.rooms.Get(r.RoomCode); exists {
+            snap := room.Snapshot()
+            status.Open = true
+            status.Member = snap.Peer(peerID) != nil
+            status.RoomSize = len(snap.Peers)
+        }
+        result = append(result, status)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "peerId": peerID,
+        "rooms":  result,
+    })
+}
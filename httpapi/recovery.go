@@ -0,0 +1,130 @@
+package httpapi
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "runtime/debug"
+    "strconv"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/reporting"
+)
+
+// Context keys for the room and peer a request acts on, set by handlers once
+// they have parsed the body so logs and error reports can be tagged with them
+const (
+    ctxRoomCode = "p2p.roomCode"
+    ctxPeerID   = "p2p.peerId"
+)
+
+// tagRequest records the room and peer a request acts on
+func tagRequest(c *gin.Context, roomCode, peerID string) {
+    if roomCode != "" {
+        c.Set(ctxRoomCode, roomCode)
+    }
+    if peerID != "" {
+        c.Set(ctxPeerID, peerID)
+    }
+}
+
+// requestRoom returns the room a request acts on, from tagRequest or the
+// route parameters
+func requestRoom(c *gin.Context) string {
+    if roomCode := c.GetString(ctxRoomCode); roomCode != "" {
+        return roomCode
+    }
+    return c.Param("roomCode")
+}
+
+// requestPeer returns the peer a request acts on, from tagRequest, the route
+// parameters or the bearer token
+func requestPeer(c *gin.Context) string {
+    if peerID := c.GetString(ctxPeerID); peerID != "" {
+        return peerID
+    }
+    if peerID := c.Param("peerId"); peerID != "" {
+        return peerID
+    }
+    peerID, _ := authenticatedPeer(c)
+    return peerID
+}
+
+// recoverPanics turns a panicking handler into a 500 response and reports
+// the panic, as well as 500 responses and 5xx responses carrying handler
+// errors, to the configured error reporter
+func (a *API) recoverPanics() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        defer func() {
+            r := recover()
+            if r == nil {
+                return
+            }
+            if r == http.ErrAbortHandler {
+                // Deliberate abort of the response; let net/http handle it
+                panic(r)
+            }
+
+            message := fmt.Sprint(r)
+            log.Printf("💥 Panic serving %s %s: %s\n%s", c.Request.Method, redactedURL(c.Request.URL), message, debug.Stack())
+            a.reportError(c, reporting.Event{
+                Level:   reporting.LevelFatal,
+                Type:    "panic",
+                Message: message,
+                Stack:   reporting.Callers(1),
+            })
+            if c.Writer.Written() {
+                c.Abort()
+                return
+            }
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+        }()
+
+        c.Next()
+
+        status := c.Writer.Status()
+        if status < http.StatusInternalServerError || (status != http.StatusInternalServerError && len(c.Errors) == 0) {
+            return
+        }
+        message := http.StatusText(status)
+        if len(c.Errors) > 0 {
+            message = c.Errors.Last().Error()
+        }
+        a.reportError(c, reporting.Event{
+            Level:   reporting.LevelError,
+            Type:    "http_" + strconv.Itoa(status),
+            Message: message,
+        })
+    }
+}
+
+// reportError sends ev to the error reporter, tagged with the request's
+// route, room and peer
+func (a *API) reportError(c *gin.Context, ev reporting.Event) {
+    if a.reporter == nil {
+        return
+    }
+
+    route := c.FullPath()
+    if route == "" {
+        route = "unmatched"
+    }
+    ev.Tags = map[string]string{
+        "route":  route,
+        "method": c.Request.Method,
+    }
+    if roomCode := requestRoom(c); roomCode != "" {
+        ev.Tags["room"] = roomCode
+    }
+    if peerID := requestPeer(c); peerID != "" {
+        ev.Tags["peer"] = peerID
+    }
+    ev.Request = &reporting.Request{
+        Method:  c.Request.Method,
+        URL:     redactedURL(c.Request.URL),
+        Route:   route,
+        Headers: redactedHeaders(c.Request.Header),
+    }
+    a.reporter.Report(ev)
+}
@@ -0,0 +1,53 @@
+package httpapi
+
+import (
+    "net/http"
+    "net/url"
+    "strings"
+)
+
+const redacted = "[redacted]"
+
+// sensitiveNameParts mark header and query parameter names whose values are
+// credentials: bearer and peer tokens, cookies, API keys and captcha answers
+var sensitiveNameParts = []string{"auth", "token", "secret", "password", "cookie", "key", "captcha", "signature", "credential"}
+
+// isSensitiveName reports whether a header or query parameter called name
+// may carry a credential
+func isSensitiveName(name string) bool {
+    name = strings.ToLower(name)
+    for _, part := range sensitiveNameParts {
+        if strings.Contains(name, part) {
+            return true
+        }
+    }
+    return false
+}
+
+// redactedHeaders flattens h for logs and error reports, masking credentials
+func redactedHeaders(h http.Header) map[string]string {
+    headers := make(map[string]string, len(h))
+    for name, values := range h {
+        if isSensitiveName(name) {
+            headers[name] = redacted
+        } else {
+            headers[name] = strings.Join(values, ", ")
+        }
+    }
+    return headers
+}
+
+// redactedURL returns the request path and query with credential parameters
+// masked
+func redactedURL(u *url.URL) string {
+    if u.RawQuery == "" {
+        return u.Path
+    }
+    query := u.Query()
+    for name := range query {
+        if isSensitiveName(name) {
+            query[name] = []string{redacted}
+        }
+    }
+    return u.Path + "?" + query.Encode()
+}
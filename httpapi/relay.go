@@ -0,0 +1,731 @@
+package httpapi
+
+import (
+    "context"
+    "errors"
+    "log"
+    "mime"
+    "net/http"
+    "net/url"
+    "slices"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/relay"
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    // defaultRelayMaxBytes bounds an upload whose offer declared no size
+    defaultRelayMaxBytes = 100 * 1024 * 1024
+    // defaultRelayScanTimeout bounds one scan of a relayed file
+    defaultRelayScanTimeout = 2 * time.Minute
+    // defaultRelayPresignTTL is how long presigned relay URLs stay valid
+    defaultRelayPresignTTL = 15 * time.Minute
+)
+
+// Quarantine statuses
+const (
+    quarantineHeld     = "quarantined"
+    quarantineReleased = "released"
+    quarantinePurged   = "purged"
+)
+
+// quarantinedFile is a relayed file the scanner flagged. Its blob is kept,
+// but not served, until an admin releases or purges it.
+type quarantinedFile struct {
+    ID            string `json:"id"`
+    RoomCode      string `json:"roomCode"`
+    FileID        string `json:"fileId"`
+    PeerID        string `json:"peerId"`
+    Name          string `json:"name"`
+    Size          int64  `json:"size"`
+    Reason        string `json:"reason,omitempty"`
+    Status        string `json:"status"`
+    QuarantinedAt int64  `json:"quarantinedAt"`
+    ResolvedAt    int64  `json:"resolvedAt,omitempty"`
+}
+
+// quarantine holds every file the scanner has flagged
+type quarantine struct {
+    mu   sync.RWMutex
+    list []*quarantinedFile
+}
+
+// held reports whether the blob under key is awaiting review
+func (q *quarantine) held(key string) bool {
+    q.mu.RLock()
+    defer q.mu.RUnlock()
+    for _, f := range q.list {
+        if f.Status == quarantineHeld && relayKey(f.RoomCode, f.FileID) == key {
+            return true
+        }
+    }
+    return false
+}
+
+// relayKey names the blob of a file offer in the relay store
+func relayKey(roomCode, fileID string) string {
+    return roomCode + "/" + fileID
+}
+
+// claimRelayUpload checks that peerID owns the file and may upload it now,
+// i.e. its relay state is one of from, and moves it to state to. Otherwise
+// it responds with the reason and returns ok false.
+func (a *API) claimRelayUpload(c *gin.Context, roomCode, fileID, peerID, to string, from ...string) (room *rooms.Room, offer rooms.FileOffer, ok bool) {
+    if a.relay == nil {
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Relaying files through the server is not enabled"})
+        return nil, offer, false
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return nil, offer, false
+    }
+
+    room.Lock()
+    defer room.Unlock()
+    file, found := room.Files[fileID]
+    switch {
+    case !found:
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return nil, offer, false
+    case peerID != file.PeerID:
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can upload this file"})
+        return nil, offer, false
+    case room.Suspended:
+        c.JSON(http.StatusLocked, gin.H{"error": "Room is suspended pending review"})
+        return nil, offer, false
+    case !slices.Contains(from, file.Relay):
+        if file.Relay == "" {
+            c.JSON(http.StatusConflict, gin.H{"error": "No direct upload was started for this file", "relay": file.Relay})
+        } else {
+            c.JSON(http.StatusConflict, gin.H{"error": "File has already been uploaded", "relay": file.Relay})
+        }
+        return nil, offer, false
+    }
+    file.Relay = to
+    room.Touch()
+    return room, *file, true
+}
+
+// uploadRelayedFile stores the content of a file offer, uploaded by its
+// owner as the request body, for peers that cannot receive it directly.
+// With a scanner configured it only becomes downloadable once scanned clean.
+func (a *API) uploadRelayedFile(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+    tagRequest(c, roomCode, peerID)
+
+    room, offer, ok := a.claimRelayUpload(c, roomCode, fileID, peerID, rooms.RelayScanning, "")
+    if !ok {
+        return
+    }
+    declared := offer.Size
+
+    limit := int64(envInt("RELAY_MAX_BYTES", defaultRelayMaxBytes))
+    if declared > 0 && declared < limit {
+        limit = declared
+    }
+    left, resets := a.relayQuotas.remaining(roomCode, peerID)
+    if left == 0 || (left > 0 && declared > left) {
+        a.abandonRelay(room, fileID)
+        quotaRetryAfter(c, resets)
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Relay quota exceeded"})
+        return
+    }
+    if left > 0 && left < limit {
+        limit = left
+    }
+    if c.Request.ContentLength > limit {
+        a.abandonRelay(room, fileID)
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Upload is larger than " + strconv.FormatInt(limit, 10) + " bytes"})
+        return
+    }
+
+    key := relayKey(roomCode, fileID)
+    body := a.relayQuotas.throttle(c.Request.Context(), peerID, http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+    size, err := a.relay.Put(c.Request.Context(), key, body)
+    a.relayQuotas.charge(roomCode, peerID, size, 0)
+    if err != nil {
+        a.abandonRelay(room, fileID)
+        a.relay.Delete(context.Background(), key)
+        var maxErr *http.MaxBytesError
+        if errors.As(err, &maxErr) {
+            c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Upload is larger than " + strconv.FormatInt(limit, 10) + " bytes"})
+            return
+        }
+        log.Printf("❌ Relay upload failed for %s: %v", key, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+        return
+    }
+    if declared > 0 && size != declared {
+        a.abandonRelay(room, fileID)
+        a.relay.Delete(context.Background(), key)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Upload size does not match the offered file size"})
+        return
+    }
+
+    a.relayStored(c, offer, roomCode, size)
+}
+
+// requestRelayUpload hands the owner of a file offer a presigned URL to
+// upload its content straight to the object store, bypassing the server.
+// The offer must declare its size, which the URL is signed for.
+func (a *API) requestRelayUpload(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+    tagRequest(c, roomCode, peerID)
+
+    presigner, ok := a.relay.(relay.Presigner)
+    if a.relay != nil && !ok {
+        c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct uploads need an object store relay"})
+        return
+    }
+
+    room, offer, ok := a.claimRelayUpload(c, roomCode, fileID, peerID, rooms.RelayUploading, "", rooms.RelayUploading)
+    if !ok {
+        return
+    }
+
+    if limit := int64(envInt("RELAY_MAX_BYTES", defaultRelayMaxBytes)); offer.Size <= 0 || offer.Size > limit {
+        a.abandonRelay(room, fileID)
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Direct uploads need a declared size of at most " + strconv.FormatInt(limit, 10) + " bytes"})
+        return
+    }
+    if left, resets := a.relayQuotas.remaining(roomCode, peerID); left == 0 || (left > 0 && offer.Size > left) {
+        a.abandonRelay(room, fileID)
+        quotaRetryAfter(c, resets)
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Relay quota exceeded"})
+        return
+    }
+
+    ttl := envDuration("RELAY_PRESIGN_TTL", defaultRelayPresignTTL)
+    url, err := presigner.PresignPut(relayKey(roomCode, fileID), offer.Size, ttl)
+    if err != nil {
+        a.abandonRelay(room, fileID)
+        log.Printf("❌ Failed to presign relay upload %s: %v", relayKey(roomCode, fileID), err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue upload URL"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "fileId":    fileID,
+        "method":    http.MethodPut,
+        "url":       url,
+        "headers":   gin.H{"Content-Length": strconv.FormatInt(offer.Size, 10)},
+        "expiresAt": time.Now().Add(ttl).Unix(),
+    })
+}
+
+// completeRelayUpload is called by the owner once a direct upload has
+// finished, to check it and scan or publish it like an upload through the
+// server
+func (a *API) completeRelayUpload(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+    tagRequest(c, roomCode, peerID)
+
+    presigner, ok := a.relay.(relay.Presigner)
+    if a.relay != nil && !ok {
+        c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct uploads need an object store relay"})
+        return
+    }
+
+    room, offer, ok := a.claimRelayUpload(c, roomCode, fileID, peerID, rooms.RelayScanning, rooms.RelayUploading)
+    if !ok {
+        return
+    }
+
+    key := relayKey(roomCode, fileID)
+    size, err := presigner.Stat(c.Request.Context(), key)
+    if err != nil {
+        a.setRelayState(room, fileID, rooms.RelayUploading)
+        if errors.Is(err, relay.ErrNotFound) {
+            c.JSON(http.StatusConflict, gin.H{"error": "The upload has not finished"})
+            return
+        }
+        log.Printf("❌ Failed to check relay upload %s: %v", key, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check upload"})
+        return
+    }
+    a.relayQuotas.charge(roomCode, peerID, size, 0)
+    if size != offer.Size {
+        a.abandonRelay(room, fileID)
+        a.relay.Delete(context.Background(), key)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Upload size does not match the offered file size"})
+        return
+    }
+
+    a.relayStored(c, offer, roomCode, size)
+}
+
+// relayStored scans a newly stored relay upload, or publishes it straight
+// away without a scanner, and responds to the uploader
+func (a *API) relayStored(c *gin.Context, offer rooms.FileOffer, roomCode string, size int64) {
+    log.Printf("📦 File relayed: %s (%d bytes) by %s in Room: %s", offer.Name, size, offer.PeerID, roomCode)
+    a.recordAudit(roomCode, "file_relayed", offer.PeerID, "", gin.H{"fileId": offer.FileID, "size": size})
+    if room, ok := a.rooms.Get(roomCode); ok {
+        room.RLock()
+        tenant := room.Tenant
+        room.RUnlock()
+        a.usage.addRelayed(tenant, size)
+    }
+
+    if a.scanner == nil {
+        a.finishRelay(roomCode, offer.FileID, rooms.RelayAvailable)
+        c.JSON(http.StatusCreated, gin.H{"fileId": offer.FileID, "relay": rooms.RelayAvailable, "size": size})
+        return
+    }
+    go a.scanRelayedFile(roomCode, offer.FileID)
+    c.JSON(http.StatusAccepted, gin.H{"fileId": offer.FileID, "relay": rooms.RelayScanning, "size": size})
+}
+
+// abandonRelay forgets an upload that did not complete, so the owner can
+// try again
+func (a *API) abandonRelay(room *rooms.Room, fileID string) {
+    a.setRelayState(room, fileID, "")
+}
+
+// setRelayState moves a file offer to a relay state without telling anyone
+func (a *API) setRelayState(room *rooms.Room, fileID, state string) {
+    room.Lock()
+    if file, ok := room.Files[fileID]; ok {
+        file.Relay = state
+        room.Touch()
+    }
+    room.Unlock()
+}
+
+// scanRelayedFile runs the scanner over a relayed file and makes it
+// available or quarantines it. A scan that fails discards the upload.
+func (a *API) scanRelayedFile(roomCode, fileID string) {
+    ctx, cancel := context.WithTimeout(context.Background(), envDuration("RELAY_SCAN_TIMEOUT", defaultRelayScanTimeout))
+    defer cancel()
+
+    key := relayKey(roomCode, fileID)
+    blob, size, err := a.relay.Open(ctx, key)
+    if err != nil {
+        log.Printf("❌ Failed to open relayed file %s for scanning: %v", key, err)
+        a.finishRelay(roomCode, fileID, "")
+        return
+    }
+    var name string
+    if room, exists := a.rooms.Get(roomCode); exists {
+        room.RLock()
+        if file, ok := room.Files[fileID]; ok {
+            name = file.Name
+        }
+        room.RUnlock()
+    }
+    verdict, err := a.scanner.Scan(ctx, name, size, blob)
+    blob.Close()
+
+    switch {
+    case err != nil:
+        log.Printf("❌ Scan failed for relayed file %s: %v", key, err)
+        a.relay.Delete(context.Background(), key)
+        a.finishRelay(roomCode, fileID, "")
+    case verdict.Clean:
+        a.finishRelay(roomCode, fileID, rooms.RelayAvailable)
+    default:
+        a.quarantineRelayedFile(roomCode, fileID, verdict.Reason)
+    }
+}
+
+// finishRelay records the outcome of a relay upload and tells the owner,
+// and when the file became available the peers allowed to receive it
+func (a *API) finishRelay(roomCode, fileID, state string) {
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        return
+    }
+
+    room.Lock()
+    file, ok := room.Files[fileID]
+    if !ok {
+        room.Unlock()
+        return
+    }
+    file.Relay = state
+    room.Touch()
+    offer := *file
+    recipients := []string{file.PeerID}
+    if owner, ok := room.Peers[file.PeerID]; ok && state == rooms.RelayAvailable {
+        for peerID, peer := range room.Peers {
+            if peerID != file.PeerID && rooms.CanTransfer(owner, peer) {
+                recipients = append(recipients, peerID)
+            }
+        }
+    }
+    room.Unlock()
+
+    eventType := "relay_available"
+    if state == "" {
+        eventType = "relay_failed"
+    }
+    a.dispatcher.Fanout(recipients, notifications.Notification{
+        Type:      eventType,
+        PeerID:    offer.PeerID,
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(offer),
+    })
+}
+
+// quarantineRelayedFile withholds a flagged file, tells its owner and puts
+// it in front of the admins, both in the quarantine list and as a report
+func (a *API) quarantineRelayedFile(roomCode, fileID, reason string) {
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        a.relay.Delete(context.Background(), relayKey(roomCode, fileID))
+        return
+    }
+
+    room.Lock()
+    file, ok := room.Files[fileID]
+    if !ok {
+        room.Unlock()
+        a.relay.Delete(context.Background(), relayKey(roomCode, fileID))
+        return
+    }
+    file.Relay = rooms.RelayQuarantined
+    room.Touch()
+    offer := *file
+    room.Unlock()
+
+    now := time.Now().Unix()
+    entry := &quarantinedFile{
+        ID:            uuid.New().String(),
+        RoomCode:      roomCode,
+        FileID:        fileID,
+        PeerID:        offer.PeerID,
+        Name:          offer.Name,
+        Size:          offer.Size,
+        Reason:        reason,
+        Status:        quarantineHeld,
+        QuarantinedAt: now,
+    }
+    a.quarantine.mu.Lock()
+    a.quarantine.list = append(a.quarantine.list, entry)
+    a.quarantine.mu.Unlock()
+
+    a.reports.mu.Lock()
+    a.reports.list = append(a.reports.list, &Report{
+        ID:         uuid.New().String(),
+        RoomCode:   roomCode,
+        PeerID:     offer.PeerID,
+        ReporterID: "scanner",
+        Reason:     "malware",
+        Details:    "Relayed file " + offer.Name + " quarantined: " + reason,
+        Verified:   true,
+        Status:     reportOpen,
+        CreatedAt:  now,
+    })
+    a.reports.mu.Unlock()
+
+    log.Printf("☣️  Relayed file quarantined: %s in Room: %s (%s)", offer.Name, roomCode, reason)
+    a.recordAudit(roomCode, "file_quarantined", "", offer.PeerID, gin.H{"fileId": fileID, "quarantineId": entry.ID, "reason": reason})
+
+    a.queueNotification(offer.PeerID, notifications.Notification{
+        Type:      "relay_quarantined",
+        PeerID:    offer.PeerID,
+        Timestamp: now,
+        Payload:   notifications.Payload(offer),
+    })
+}
+
+// downloadRelayedFile serves a relayed file that has been scanned clean to
+// a peer allowed to receive it
+func (a *API) downloadRelayedFile(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+    tagRequest(c, roomCode, peerID)
+
+    offer, ok := a.relayDownloadable(c, roomCode, fileID, peerID)
+    if !ok {
+        return
+    }
+
+    if presigner, ok := a.relay.(relay.Presigner); ok {
+        a.redirectRelayDownload(c, presigner, offer, roomCode, peerID)
+        return
+    }
+
+    blob, size, err := a.relay.Open(c.Request.Context(), relayKey(roomCode, fileID))
+    if err != nil {
+        if errors.Is(err, relay.ErrNotFound) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File has not been uploaded for relaying"})
+            return
+        }
+        log.Printf("❌ Failed to open relayed file %s: %v", relayKey(roomCode, fileID), err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+        return
+    }
+    defer blob.Close()
+
+    if !a.relayQuotas.reserve(roomCode, peerID, size) {
+        _, resets := a.relayQuotas.remaining(roomCode, peerID)
+        quotaRetryAfter(c, resets)
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Relay quota exceeded"})
+        return
+    }
+
+    contentType := offer.MimeType
+    if contentType == "" {
+        contentType = "application/octet-stream"
+    }
+    c.DataFromReader(http.StatusOK, size, contentType, a.relayQuotas.throttle(c.Request.Context(), peerID, blob), map[string]string{
+        "Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": offer.Name}),
+    })
+}
+
+// relayDownloadable returns the file offer peerID may download from the
+// relay, answering the request and returning false when it can't
+func (a *API) relayDownloadable(c *gin.Context, roomCode, fileID, peerID string) (rooms.FileOffer, bool) {
+    if a.relay == nil {
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Relaying files through the server is not enabled"})
+        return rooms.FileOffer{}, false
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return rooms.FileOffer{}, false
+    }
+
+    room.RLock()
+    file, ok := room.Files[fileID]
+    var offer rooms.FileOffer
+    allowed := false
+    if ok {
+        offer = *file
+        owner, viewer := room.Peers[file.PeerID], room.Peers[peerID]
+        allowed = peerID == file.PeerID || (owner != nil && viewer != nil && rooms.CanTransfer(owner, viewer))
+    }
+    suspended := room.Suspended
+    room.RUnlock()
+
+    switch {
+    case !ok:
+        c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+        return rooms.FileOffer{}, false
+    case !allowed:
+        c.JSON(http.StatusForbidden, gin.H{"error": "Peer is not allowed to receive this file"})
+        return rooms.FileOffer{}, false
+    case suspended:
+        c.JSON(http.StatusLocked, gin.H{"error": "Room is suspended pending review"})
+        return rooms.FileOffer{}, false
+    case offer.Relay == rooms.RelayScanning:
+        c.JSON(http.StatusConflict, gin.H{"error": "File is still being scanned", "relay": offer.Relay})
+        return rooms.FileOffer{}, false
+    case offer.Relay == rooms.RelayQuarantined:
+        c.JSON(http.StatusForbidden, gin.H{"error": "File was quarantined by the content scanner", "relay": offer.Relay})
+        return rooms.FileOffer{}, false
+    case offer.Relay != rooms.RelayAvailable:
+        c.JSON(http.StatusNotFound, gin.H{"error": "File has not been uploaded for relaying"})
+        return rooms.FileOffer{}, false
+    }
+    return offer, true
+}
+
+// requestRelayDownload hands a peer allowed to receive a relayed file a
+// signed download URL for it, valid for RELAY_PRESIGN_TTL, to pass to a
+// browser or download manager that can't send the peer's credentials
+func (a *API) requestRelayDownload(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+    tagRequest(c, roomCode, peerID)
+
+    if _, ok := a.relayDownloadable(c, roomCode, fileID, peerID); !ok {
+        return
+    }
+
+    ttl := envDuration("RELAY_PRESIGN_TTL", defaultRelayPresignTTL)
+    c.JSON(http.StatusOK, gin.H{
+        "fileId":    fileID,
+        "method":    http.MethodGet,
+        "url":       signedURL(c, "/room/"+roomCode+"/files/"+fileID+"/relay", url.Values{"peerId": {peerID}}, ttl),
+        "expiresAt": time.Now().Add(ttl).Unix(),
+    })
+}
+
+// redirectRelayDownload sends the downloader to a presigned URL, so the
+// blob comes straight from the object store. Its quota is charged in full
+// up front, and the transfer rate can't be paced.
+func (a *API) redirectRelayDownload(c *gin.Context, presigner relay.Presigner, offer rooms.FileOffer, roomCode, peerID string) {
+    key := relayKey(roomCode, offer.FileID)
+    size, err := presigner.Stat(c.Request.Context(), key)
+    if errors.Is(err, relay.ErrNotFound) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "File has not been uploaded for relaying"})
+        return
+    }
+    if err != nil {
+        log.Printf("❌ Failed to check relayed file %s: %v", key, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+        return
+    }
+
+    if !a.relayQuotas.reserve(roomCode, peerID, size) {
+        _, resets := a.relayQuotas.remaining(roomCode, peerID)
+        quotaRetryAfter(c, resets)
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Relay quota exceeded"})
+        return
+    }
+
+    url, err := presigner.PresignGet(key, offer.Name, envDuration("RELAY_PRESIGN_TTL", defaultRelayPresignTTL))
+    if err != nil {
+        log.Printf("❌ Failed to presign relay download %s: %v", key, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+        return
+    }
+    c.Redirect(http.StatusFound, url)
+}
+
+// resumeRelayScans rescans files whose scan was cut short by a restart
+func (a *API) resumeRelayScans() {
+    if a.relay == nil || a.scanner == nil {
+        return
+    }
+    a.rooms.Range(func(roomCode string, room *rooms.Room) bool {
+        room.RLock()
+        for fileID, file := range room.Files {
+            if file.Relay == rooms.RelayScanning {
+                go a.scanRelayedFile(roomCode, fileID)
+            }
+        }
+        room.RUnlock()
+        return true
+    })
+}
+
+// pruneRelayBlobs deletes blobs whose offer is gone, once withdrawn or its
+// room closed, except those held in quarantine
+func (a *API) pruneRelayBlobs() {
+    if a.relay == nil {
+        return
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+    defer cancel()
+
+    keys, err := a.relay.List(ctx)
+    if err != nil {
+        log.Printf("❌ Failed to list relayed files: %v", err)
+        return
+    }
+    live := make(map[string]bool)
+    a.rooms.Range(func(roomCode string, room *rooms.Room) bool {
+        room.RLock()
+        for fileID, file := range room.Files {
+            if file.Relay != "" {
+                live[relayKey(roomCode, fileID)] = true
+            }
+        }
+        room.RUnlock()
+        return true
+    })
+    for _, key := range keys {
+        if live[key] || a.quarantine.held(key) {
+            continue
+        }
+        if err := a.relay.Delete(ctx, key); err != nil {
+            log.Printf("❌ Failed to delete relayed file %s: %v", key, err)
+        }
+    }
+}
+
+// listQuarantine shows flagged relayed files, newest first, optionally
+// filtered by ?status=
+func (a *API) listQuarantine(c *gin.Context) {
+    status := c.Query("status")
+
+    a.quarantine.mu.RLock()
+    list := make([]quarantinedFile, 0, len(a.quarantine.list))
+    for i := len(a.quarantine.list) - 1; i >= 0; i-- {
+        if f := a.quarantine.list[i]; status == "" || f.Status == status {
+            list = append(list, *f)
+        }
+    }
+    a.quarantine.mu.RUnlock()
+
+    c.JSON(http.StatusOK, gin.H{"files": list})
+}
+
+// resolveQuarantine releases a quarantined file, making it downloadable
+// after all, or purges it, deleting its blob for good
+func (a *API) resolveQuarantine(c *gin.Context) {
+    id := c.Param("quarantineId")
+
+    var req struct {
+        Action string `json:"action" binding:"required"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+
+    var status string
+    switch req.Action {
+    case "release":
+        status = quarantineReleased
+    case "purge":
+        status = quarantinePurged
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "action must be release or purge"})
+        return
+    }
+
+    a.quarantine.mu.Lock()
+    var entry *quarantinedFile
+    for _, f := range a.quarantine.list {
+        if f.ID == id {
+            entry = f
+            break
+        }
+    }
+    if entry == nil {
+        a.quarantine.mu.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "Quarantined file not found"})
+        return
+    }
+    if entry.Status != quarantineHeld {
+        resolved := *entry
+        a.quarantine.mu.Unlock()
+        c.JSON(http.StatusConflict, gin.H{"error": "File has already been " + resolved.Status})
+        return
+    }
+    entry.Status = status
+    entry.ResolvedAt = time.Now().Unix()
+    resolved := *entry
+    a.quarantine.mu.Unlock()
+
+    if status == quarantineReleased {
+        a.finishRelay(resolved.RoomCode, resolved.FileID, rooms.RelayAvailable)
+    } else {
+        a.relay.Delete(c.Request.Context(), relayKey(resolved.RoomCode, resolved.FileID))
+        if room, exists := a.rooms.Get(resolved.RoomCode); exists {
+            room.Lock()
+            if file, ok := room.Files[resolved.FileID]; ok {
+                file.Relay = ""
+                room.Touch()
+            }
+            room.Unlock()
+        }
+    }
+
+    log.Printf("☣️  Quarantined file %s: %s in Room: %s", status, resolved.Name, resolved.RoomCode)
+    a.recordAudit(resolved.RoomCode, "file_"+status, "admin", resolved.PeerID, gin.H{"fileId": resolved.FileID, "quarantineId": resolved.ID})
+
+    c.JSON(http.StatusOK, gin.H{"file": resolved})
+}
@@ -0,0 +1,260 @@
+package httpapi
+
+import (
+    "context"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Relay transfer limits, so the fallback path can't be used as free bulk
+// hosting. A limit of zero or less disables that check.
+type relayQuotaConfig struct {
+    peerBytes int64         // bytes one peer may move through the relay per window
+    roomBytes int64         // bytes one room may move through the relay per window
+    window    time.Duration // length of a quota window
+    rate      int64         // bytes per second one peer may transfer, across its transfers
+
+    mu     sync.Mutex
+    peers  map[string]*relayUsage
+    rooms  map[string]*relayUsage
+    pacers map[string]*pacer
+}
+
+// relayUsage counts the bytes moved through the relay in one window
+type relayUsage struct {
+    Start      time.Time `json:"windowStart"`
+    Uploaded   int64     `json:"uploaded"`
+    Downloaded int64     `json:"downloaded"`
+}
+
+func (u *relayUsage) total() int64 {
+    return u.Uploaded + u.Downloaded
+}
+
+// loadRelayQuotas reads the relay limits from the environment
+func loadRelayQuotas() *relayQuotaConfig {
+    return &relayQuotaConfig{
+        peerBytes: int64(envInt("RELAY_PEER_QUOTA_BYTES", 2<<30)),
+        roomBytes: int64(envInt("RELAY_ROOM_QUOTA_BYTES", 10<<30)),
+        window:    envDuration("RELAY_QUOTA_WINDOW", 24*time.Hour),
+        rate:      int64(envInt("RELAY_PEER_BYTES_PER_SECOND", 5<<20)),
+        peers:     make(map[string]*relayUsage),
+        rooms:     make(map[string]*relayUsage),
+        pacers:    make(map[string]*pacer),
+    }
+}
+
+// usage returns the current window for key, starting a new one if the last
+// has ended. The caller must hold q.mu.
+func (q *relayQuotaConfig) usage(m map[string]*relayUsage, key string, now time.Time) *relayUsage {
+    u, ok := m[key]
+    if !ok || now.Sub(u.Start) >= q.window {
+        u = &relayUsage{Start: now}
+        m[key] = u
+    }
+    return u
+}
+
+// remaining returns how many more bytes peerID may move through the relay
+// in roomCode this window, or -1 when neither is limited, and when the
+// tighter of the two windows resets
+func (q *relayQuotaConfig) remaining(roomCode, peerID string) (left int64, resets time.Time) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    left = -1
+    check := func(limit int64, u *relayUsage) {
+        if limit <= 0 {
+            return
+        }
+        if n := max(limit-u.total(), 0); left < 0 || n < left {
+            left, resets = n, u.Start.Add(q.window)
+        }
+    }
+    check(q.peerBytes, q.usage(q.peers, peerID, now))
+    check(q.roomBytes, q.usage(q.rooms, roomCode, now))
+    return left, resets
+}
+
+// charge records bytes moved through the relay by peerID in roomCode
+func (q *relayQuotaConfig) charge(roomCode, peerID string, uploaded, downloaded int64) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    for _, u := range []*relayUsage{q.usage(q.peers, peerID, now), q.usage(q.rooms, roomCode, now)} {
+        u.Uploaded += uploaded
+        u.Downloaded += downloaded
+    }
+}
+
+// reserve charges a download of size bytes up front if it fits in both
+// quotas, reporting whether it did
+func (q *relayQuotaConfig) reserve(roomCode, peerID string, size int64) bool {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    peer, room := q.usage(q.peers, peerID, now), q.usage(q.rooms, roomCode, now)
+    if (q.peerBytes > 0 && peer.total()+size > q.peerBytes) || (q.roomBytes > 0 && room.total()+size > q.roomBytes) {
+        return false
+    }
+    peer.Downloaded += size
+    room.Downloaded += size
+    return true
+}
+
+// throttle paces r to the per-peer transfer rate, shared by all of peerID's
+// concurrent transfers
+func (q *relayQuotaConfig) throttle(ctx context.Context, peerID string, r io.Reader) io.Reader {
+    if q.rate <= 0 {
+        return r
+    }
+    q.mu.Lock()
+    p, ok := q.pacers[peerID]
+    if !ok {
+        p = &pacer{rate: q.rate}
+        q.pacers[peerID] = p
+    }
+    q.mu.Unlock()
+    return &pacedReader{ctx: ctx, r: r, p: p}
+}
+
+// prune drops windows that have ended and idle pacers
+func (q *relayQuotaConfig) prune() {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    for _, m := range []map[string]*relayUsage{q.peers, q.rooms} {
+        for key, u := range m {
+            if now.Sub(u.Start) >= q.window {
+                delete(m, key)
+            }
+        }
+    }
+    for key, p := range q.pacers {
+        if p.idle(now) {
+            delete(q.pacers, key)
+        }
+    }
+}
+
+// quotaRetryAfter sets Retry-After to when an exhausted relay quota resets
+func quotaRetryAfter(c *gin.Context, resets time.Time) {
+    c.Header("Retry-After", strconv.Itoa(int(time.Until(resets).Seconds())+1))
+}
+
+// pacer spaces out bytes so they flow at most rate bytes per second
+type pacer struct {
+    mu   sync.Mutex
+    rate int64
+    next time.Time
+}
+
+// wait blocks until n more bytes may flow
+func (p *pacer) wait(ctx context.Context, n int) error {
+    p.mu.Lock()
+    now := time.Now()
+    if p.next.Before(now) {
+        p.next = now
+    }
+    delay := p.next.Sub(now)
+    p.next = p.next.Add(time.Duration(int64(n) * int64(time.Second) / p.rate))
+    p.mu.Unlock()
+
+    if delay <= 0 {
+        return nil
+    }
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
+
+func (p *pacer) idle(now time.Time) bool {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return p.next.Before(now)
+}
+
+// pacedReader reads through a pacer in chunks of at most relayChunk bytes,
+// so the pacing stays smooth
+type pacedReader struct {
+    ctx context.Context
+    r   io.Reader
+    p   *pacer
+}
+
+const relayChunk = 32 * 1024
+
+func (pr *pacedReader) Read(b []byte) (int, error) {
+    if len(b) > relayChunk {
+        b = b[:relayChunk]
+    }
+    n, err := pr.r.Read(b)
+    if n > 0 {
+        if werr := pr.p.wait(pr.ctx, n); werr != nil {
+            return n, werr
+        }
+    }
+    return n, err
+}
+
+// relayUsageEntry is one peer's or room's usage in the relay stats
+type relayUsageEntry struct {
+    Key string `json:"key"`
+    relayUsage
+}
+
+// getRelayStats reports the relay limits and the current usage of every
+// peer and room, heaviest first
+func (a *API) getRelayStats(c *gin.Context) {
+    q := a.relayQuotas
+    q.mu.Lock()
+    collect := func(m map[string]*relayUsage) []relayUsageEntry {
+        list := make([]relayUsageEntry, 0, len(m))
+        for key, u := range m {
+            if time.Since(u.Start) < q.window {
+                list = append(list, relayUsageEntry{Key: key, relayUsage: *u})
+            }
+        }
+        sort.Slice(list, func(i, j int) bool {
+            return list[i].total() > list[j].total()
+        })
+        return list
+    }
+    peers, roomUsage := collect(q.peers), collect(q.rooms)
+    q.mu.Unlock()
+
+    var uploaded, downloaded int64
+    for _, u := range roomUsage {
+        uploaded += u.Uploaded
+        downloaded += u.Downloaded
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "enabled": a.relay != nil,
+        "limits": gin.H{
+            "peerBytes":          q.peerBytes,
+            "roomBytes":          q.roomBytes,
+            "windowSeconds":      int64(q.window.Seconds()),
+            "peerBytesPerSecond": q.rate,
+        },
+        "uploaded":   uploaded,
+        "downloaded": downloaded,
+        "peers":      peers,
+        "rooms":      roomUsage,
+    })
+}
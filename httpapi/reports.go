@@ -0,0 +1,302 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "os"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+
+    "p2p-file-share-backend/notifications"
+)
+
+// Report reasons
+var reportReasons = map[string]bool{
+    "illegal_content": true,
+    "malware":         true,
+    "harassment":      true,
+    "spam":            true,
+    "other":           true,
+}
+
+// Report statuses
+const (
+    reportOpen      = "open"
+    reportDismissed = "dismissed"
+    reportUpheld    = "upheld"
+)
+
+const (
+    reportsPerReporterPerHour = 10
+    reportsPerIPPerHour       = 30
+    maxReportDetailsLength    = 1000
+)
+
+// Report is a user complaint about a room or a peer
+type Report struct {
+    ID           string `json:"id"`
+    RoomCode     string `json:"roomCode,omitempty"`
+    PeerID       string `json:"peerId,omitempty"`
+    ReporterID   string `json:"reporterId"`
+    Reason       string `json:"reason"`
+    Details      string `json:"details,omitempty"`
+    EvidenceHash string `json:"evidenceHash,omitempty"`
+    // Verified reports were filed with the reporter's peer token by a
+    // member of the reported room, or by the server itself
+    Verified       bool   `json:"verified"`
+    Status         string `json:"status"`
+    CreatedAt      int64  `json:"createdAt"`
+    ResolvedAt     int64  `json:"resolvedAt,omitempty"`
+    ResolutionNote string `json:"resolutionNote,omitempty"`
+}
+
+// reportQueue holds every filed report
+type reportQueue struct {
+    mu   sync.RWMutex
+    list []*Report
+}
+
+// autoSuspendThreshold is the number of distinct reporters with open
+// verified reports against a room that freezes it pending review (0
+// disables auto-suspend). Only verified reports count, so reporter IDs
+// made up without a peer token can't suspend a room.
+func autoSuspendThreshold() int {
+    n, _ := strconv.Atoi(os.Getenv("REPORT_AUTO_SUSPEND_THRESHOLD"))
+    return n
+}
+
+// fileReport records an abuse report and, past the threshold, suspends the room
+func (a *API) fileReport(c *gin.Context) {
+    var req struct {
+        ReporterID   string `json:"reporterId" binding:"required"`
+        RoomCode     string `json:"roomCode"`
+        PeerID       string `json:"peerId"`
+        Reason       string `json:"reason" binding:"required"`
+        Details      string `json:"details"`
+        EvidenceHash string `json:"evidenceHash"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+
+    if req.RoomCode == "" && req.PeerID == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A report must name a roomCode or peerId"})
+        return
+    }
+    if !reportReasons[req.Reason] {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be one of illegal_content, malware, harassment, spam, other"})
+        return
+    }
+    if len(req.Details) > maxReportDetailsLength || len(req.EvidenceHash) > 128 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "details or evidenceHash too long"})
+        return
+    }
+
+    if !a.reportIPLimiter.Allow(c.ClientIP()) || !a.reporterLimiter.Allow(req.ReporterID) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+        return
+    }
+
+    // matchPeerToken has already refused tokens for another reporter
+    _, verified := authenticatedPeer(c)
+    if verified && req.RoomCode != "" {
+        verified = false
+        if room, exists := a.rooms.Get(req.RoomCode); exists {
+            room.RLock()
+            _, verified = room.Peers[req.ReporterID]
+            room.RUnlock()
+        }
+    }
+
+    report := &Report{
+        ID:           uuid.New().String(),
+        RoomCode:     req.RoomCode,
+        PeerID:       req.PeerID,
+        ReporterID:   req.ReporterID,
+        Reason:       req.Reason,
+        Details:      req.Details,
+        EvidenceHash: req.EvidenceHash,
+        Verified:     verified,
+        Status:       reportOpen,
+        CreatedAt:    time.Now().Unix(),
+    }
+
+    a.reports.mu.Lock()
+    a.reports.list = append(a.reports.list, report)
+    reporters := make(map[string]bool)
+    if req.RoomCode != "" {
+        for _, r := range a.reports.list {
+            if r.RoomCode == req.RoomCode && r.Status == reportOpen && r.Verified {
+                reporters[r.ReporterID] = true
+            }
+        }
+    }
+    a.reports.mu.Unlock()
+
+    log.Printf("🚩 Report filed: room=%s peer=%s reason=%s", req.RoomCode, req.PeerID, req.Reason)
+    a.recordAudit(req.RoomCode, "report_filed", req.ReporterID, req.PeerID, gin.H{"reportId": report.ID, "reason": req.Reason, "verified": verified})
+
+    if threshold := autoSuspendThreshold(); threshold > 0 && len(reporters) >= threshold {
+        if a.suspendRoom(req.RoomCode, true) {
+            log.Printf("⛔ Room auto-suspended after %d reports: %s", len(reporters), req.RoomCode)
+            a.recordAudit(req.RoomCode, "room_suspended", "", "", gin.H{"reason": "report_threshold", "reporters": len(reporters)})
+        }
+    }
+
+    c.JSON(http.StatusCreated, gin.H{"id": report.ID, "status": report.Status})
+}
+
+// suspendRoom freezes or unfreezes a room. It reports whether the state changed.
+func (a *API) suspendRoom(roomCode string, suspended bool) bool {
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        return false
+    }
+
+    room.Lock()
+    changed := room.Suspended != suspended
+    room.Suspended = suspended
+    room.Unlock()
+
+    if changed {
+        eventType := "room_suspended"
+        if !suspended {
+            eventType = "room_unsuspended"
+        }
+        a.notifyRoom(room, "", notifications.Notification{Type: eventType, Timestamp: time.Now().Unix()})
+    }
+    return changed
+}
+
+// closeRoom removes every peer from a room and deletes it
+func (a *API) closeRoom(roomCode, reason string) bool {
+    room, exists := a.rooms.Delete(roomCode)
+
+    if !exists {
+        return false
+    }
+
+    a.notifyRoom(room, "", notifications.Notification{
+        Type:      "room_closed",
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(gin.H{"reason": reason}),
+    })
+    return true
+}
+
+// listReports is the admin review queue, filterable by status
+func (a *API) listReports(c *gin.Context) {
+    status := c.Query("status")
+
+    a.reports.mu.RLock()
+    result := make([]Report, 0, len(a.reports.list))
+    for _, r := range a.reports.list {
+        if status == "" || r.Status == status {
+            result = append(result, *r)
+        }
+    }
+    a.reports.mu.RUnlock()
+
+    c.JSON(http.StatusOK, gin.H{"reports": result})
+}
+
+// resolveReport dismisses a report (unfreezing its room if no other open
+// reports remain) or upholds it (closing the reported room)
+func (a *API) resolveReport(c *gin.Context) {
+    id := c.Param("reportId")
+
+    var req struct {
+        Action string `json:"action" binding:"required"`
+        Note   string `json:"note"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+
+    var status string
+    switch req.Action {
+    case "dismiss":
+        status = reportDismissed
+    case "uphold":
+        status = reportUpheld
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "action must be dismiss or uphold"})
+        return
+    }
+
+    a.reports.mu.Lock()
+    var report *Report
+    for _, r := range a.reports.list {
+        if r.ID == id {
+            report = r
+            break
+        }
+    }
+    if report == nil {
+        a.reports.mu.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+        return
+    }
+    report.Status = status
+    report.ResolvedAt = time.Now().Unix()
+    report.ResolutionNote = req.Note
+
+    stillOpen := false
+    for _, r := range a.reports.list {
+        if r.RoomCode == report.RoomCode && r.Status == reportOpen {
+            stillOpen = true
+        }
+    }
+    resolved := *report
+    a.reports.mu.Unlock()
+
+    a.recordAudit(resolved.RoomCode, "report_"+status, "admin", resolved.PeerID, gin.H{"reportId": resolved.ID})
+
+    if resolved.RoomCode != "" {
+        if status == reportUpheld {
+            if a.closeRoom(resolved.RoomCode, "moderation") {
+                log.Printf("⛔ Room closed after upheld report: %s", resolved.RoomCode)
+                a.recordAudit(resolved.RoomCode, "room_closed", "admin", "", gin.H{"reportId": resolved.ID})
+            }
+        } else if !stillOpen && a.suspendRoom(resolved.RoomCode, false) {
+            a.recordAudit(resolved.RoomCode, "room_unsuspended", "admin", "", gin.H{"reportId": resolved.ID})
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{"report": resolved})
+}
+
+// eraseReports drops the reports peerID filed and its ID from reports made
+// about it, returning how many reports it appeared in. A report about only
+// the peer, with no room, has nothing left to review and is dropped too.
+func (a *API) eraseReports(peerID string) int {
+    a.reports.mu.Lock()
+    defer a.reports.mu.Unlock()
+
+    removed := 0
+    kept := a.reports.list[:0]
+    for _, report := range a.reports.list {
+        if report.ReporterID == peerID || report.PeerID == peerID {
+            removed++
+            if report.ReporterID == peerID {
+                continue
+            }
+            report.PeerID = ""
+            if report.RoomCode == "" {
+                continue
+            }
+        }
+        kept = append(kept, report)
+    }
+    clear(a.reports.list[len(kept):])
+    a.reports.list = kept
+    return removed
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+    "fmt"
+    "net/http"
+    "testing"
+)
+
+func TestReportAutoSuspend(t *testing.T) {
+    tests := []struct {
+        name string
+        // reporters file one report each; members join the room first
+        reporters []string
+        members   []string
+        tokens    bool
+        want      bool
+    }{
+        {"verified members", []string{"peer-b", "peer-c"}, []string{"peer-b", "peer-c"}, true, true},
+        {"made-up reporter IDs", []string{"fake-1", "fake-2", "fake-3"}, nil, false, false},
+        {"members without tokens", []string{"peer-b", "peer-c"}, []string{"peer-b", "peer-c"}, false, false},
+        {"tokens but not members", []string{"peer-b", "peer-c"}, nil, true, false},
+        {"one reporter twice", []string{"peer-b", "peer-b"}, []string{"peer-b"}, true, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            t.Setenv("REPORT_AUTO_SUSPEND_THRESHOLD", "2")
+            a, h := newTestRouter(t)
+            request(h, http.MethodPost, "/room/create", "application/json", "", `{"roomCode":"reported","peerId":"peer-a"}`)
+            for _, peerID := range tt.members {
+                request(h, http.MethodPost, "/room/join", "application/json", "", `{"roomCode":"reported","peerId":"`+peerID+`"}`)
+            }
+
+            for _, reporter := range tt.reporters {
+                token := ""
+                if tt.tokens {
+                    token = issuePeerToken(reporter)
+                }
+                body := `{"reporterId":"` + reporter + `","roomCode":"reported","reason":"spam"}`
+                if w := request(h, http.MethodPost, "/reports", "application/json", token, body); w.Code != http.StatusCreated {
+                    t.Fatalf("report: status = %d: %s", w.Code, w.Body)
+                }
+            }
+
+            room, _ := a.rooms.Get("reported")
+            room.RLock()
+            suspended := room.Suspended
+            room.RUnlock()
+            if suspended != tt.want {
+                t.Errorf("suspended = %v, want %v", suspended, tt.want)
+            }
+        })
+    }
+}
+
+func TestReportRateLimitByIP(t *testing.T) {
+    _, h := newTestRouter(t)
+    for i := range reportsPerIPPerHour + 1 {
+        body := fmt.Sprintf(`{"reporterId":"reporter-%d","peerId":"peer-a","reason":"spam"}`, i)
+        w := request(h, http.MethodPost, "/reports", "application/json", "", body)
+        if i < reportsPerIPPerHour && w.Code != http.StatusCreated {
+            t.Fatalf("report %d: status = %d: %s", i, w.Code, w.Body)
+        }
+        if i == reportsPerIPPerHour && w.Code != http.StatusTooManyRequests {
+            t.Fatalf("report past the per-IP limit: status = %d, want 429", w.Code)
+        }
+    }
+}
@@ -0,0 +1,204 @@
+package httpapi
+
+import (
+    "context"
+    "errors"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/relay"
+    "p2p-file-share-backend/rooms"
+)
+
+// tusVersion is the version of the tus resumable upload protocol spoken on
+// the relay endpoints: HEAD reports how much of an upload has arrived and
+// PATCH appends the next chunk at that offset
+const tusVersion = "1.0.0"
+
+// defaultRelayStagingTTL is how long an unfinished resumable upload is kept
+// without progress
+const defaultRelayStagingTTL = 24 * time.Hour
+
+// openRelayStaging opens RELAY_STAGING_DIR (default a directory under the
+// system temp dir), where resumable uploads collect until they are complete
+// and are handed to the relay store
+func openRelayStaging() *relay.Disk {
+    dir := os.Getenv("RELAY_STAGING_DIR")
+    if dir == "" {
+        dir = filepath.Join(os.TempDir(), "p2p-relay-staging")
+    }
+    staging, err := relay.NewDisk(dir)
+    if err != nil {
+        log.Fatalf("❌ Failed to open relay staging directory: %v", err)
+    }
+    return staging
+}
+
+// tusHeaders marks a response as speaking tus
+func tusHeaders(c *gin.Context) {
+    c.Header("Tus-Resumable", tusVersion)
+    c.Header("Cache-Control", "no-store")
+}
+
+// relayUploadOffset reports how many bytes of a resumable upload the server
+// has, and the upload's full length
+func (a *API) relayUploadOffset(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+    tagRequest(c, roomCode, peerID)
+    tusHeaders(c)
+
+    if a.relay == nil {
+        c.Status(http.StatusServiceUnavailable)
+        return
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.Status(http.StatusNotFound)
+        return
+    }
+    room.RLock()
+    file, ok := room.Files[fileID]
+    var offer rooms.FileOffer
+    if ok {
+        offer = *file
+    }
+    room.RUnlock()
+    switch {
+    case !ok:
+        c.Status(http.StatusNotFound)
+        return
+    case peerID != offer.PeerID:
+        c.Status(http.StatusForbidden)
+        return
+    }
+
+    offset := offer.Size
+    if offer.Relay == "" || offer.Relay == rooms.RelayUploading {
+        n, err := a.staging.Stat(c.Request.Context(), relayKey(roomCode, fileID))
+        if err != nil && !errors.Is(err, relay.ErrNotFound) {
+            c.Status(http.StatusInternalServerError)
+            return
+        }
+        offset = n
+    }
+    c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+    c.Header("Upload-Length", strconv.FormatInt(offer.Size, 10))
+    c.Status(http.StatusOK)
+}
+
+// appendRelayUpload receives the next chunk of a resumable upload of a file
+// offer's content, sent by its owner at the offset the server reported.
+// Chunks collect in the staging directory, so a dropped connection only
+// loses the chunk in flight; the last one hands the file to the relay store
+// to be scanned or published like any other upload. The offer must declare
+// its size, which is the upload's length.
+func (a *API) appendRelayUpload(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    fileID := c.Param("fileId")
+    peerID := c.Query("peerId")
+    tagRequest(c, roomCode, peerID)
+    tusHeaders(c)
+
+    if v := c.GetHeader("Tus-Resumable"); v != tusVersion {
+        c.Header("Tus-Version", tusVersion)
+        c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Unsupported tus version " + strconv.Quote(v)})
+        return
+    }
+    if c.ContentType() != "application/offset+octet-stream" {
+        c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+        return
+    }
+    offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+    if err != nil || offset < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset must be a non-negative integer"})
+        return
+    }
+
+    room, offer, ok := a.claimRelayUpload(c, roomCode, fileID, peerID, rooms.RelayUploading, "", rooms.RelayUploading)
+    if !ok {
+        return
+    }
+    if limit := int64(envInt("RELAY_MAX_BYTES", defaultRelayMaxBytes)); offer.Size <= 0 || offer.Size > limit {
+        a.abandonRelay(room, fileID)
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Resumable uploads need a declared size of at most " + strconv.FormatInt(limit, 10) + " bytes"})
+        return
+    }
+
+    // Each chunk is charged as it arrives, so it only has to fit what is
+    // left of the quota
+    chunkLimit := offer.Size - offset
+    left, resets := a.relayQuotas.remaining(roomCode, peerID)
+    if left == 0 {
+        quotaRetryAfter(c, resets)
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Relay quota exceeded"})
+        return
+    }
+    if left > 0 && left < chunkLimit {
+        chunkLimit = left
+    }
+
+    key := relayKey(roomCode, fileID)
+    body := a.relayQuotas.throttle(c.Request.Context(), peerID, http.MaxBytesReader(c.Writer, c.Request.Body, max(chunkLimit, 0)))
+    n, err := a.staging.Append(c.Request.Context(), key, offset, body)
+    a.relayQuotas.charge(roomCode, peerID, n, 0)
+    if errors.Is(err, relay.ErrOffsetMismatch) {
+        if current, serr := a.staging.Stat(c.Request.Context(), key); serr == nil {
+            c.Header("Upload-Offset", strconv.FormatInt(current, 10))
+        }
+        c.JSON(http.StatusConflict, gin.H{"error": "Upload-Offset does not match the upload"})
+        return
+    }
+    offset += n
+    c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+    if err != nil {
+        var maxErr *http.MaxBytesError
+        if errors.As(err, &maxErr) {
+            c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Chunk runs past the end of the upload or the relay quota"})
+            return
+        }
+        log.Printf("⚠️  Resumable upload %s interrupted at %d bytes: %v", key, offset, err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Upload interrupted, resume from Upload-Offset"})
+        return
+    }
+    if offset < offer.Size {
+        c.Status(http.StatusNoContent)
+        return
+    }
+
+    // Complete: move it from staging into the relay store
+    a.setRelayState(room, fileID, rooms.RelayScanning)
+    staged, _, err := a.staging.Open(c.Request.Context(), key)
+    if err == nil {
+        _, err = a.relay.Put(c.Request.Context(), key, staged)
+        staged.Close()
+    }
+    if err != nil {
+        a.setRelayState(room, fileID, rooms.RelayUploading)
+        log.Printf("❌ Failed to store resumable upload %s: %v", key, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+        return
+    }
+    a.staging.Delete(context.Background(), key)
+
+    a.relayStored(c, offer, roomCode, offset)
+}
+
+// pruneRelayStaging deletes resumable uploads that stopped making progress
+// more than RELAY_STAGING_TTL ago
+func (a *API) pruneRelayStaging() {
+    if a.staging == nil {
+        return
+    }
+    if err := a.staging.Prune(envDuration("RELAY_STAGING_TTL", defaultRelayStagingTTL)); err != nil {
+        log.Printf("❌ Failed to prune relay staging directory: %v", err)
+    }
+}
@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+)
+
+// resumeKey derives the resume token key from a peer token key, so a resume
+// token can never be passed off as a peer token or the other way round
+func resumeKey(peerKey []byte) []byte {
+    mac := hmac.New(sha256.New, peerKey)
+    mac.Write([]byte("resume-token"))
+    return mac.Sum(nil)
+}
+
+func signResume(key []byte, payload string) string {
+    mac := hmac.New(sha256.New, resumeKey(key))
+    mac.Write([]byte(payload))
+    return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issueResumeToken returns a token letting peerID reclaim its place in
+// roomCode after a refresh or network change, in the form
+// "<payload>.<signature>". It expires after RESUME_TOKEN_TTL, but a peer can
+// only resume while it is still in the room, i.e. connected or within its
+// PEER_GRACE_PERIOD.
+func (a *API) issueResumeToken(roomCode, peerID string) string {
+    expires := time.Now().Add(a.resumeTTL).Unix()
+    payload := roomCode + "\n" + peerID + "\n" + strconv.FormatInt(expires, 10)
+    current, _ := tokenSecrets()
+    return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + signResume(current, payload)
+}
+
+// verifyResumeToken returns the room and peer a resume token was issued for
+func verifyResumeToken(token string) (roomCode, peerID string, ok bool) {
+    encoded, sig, found := strings.Cut(token, ".")
+    if !found {
+        return "", "", false
+    }
+    raw, err := base64.RawURLEncoding.DecodeString(encoded)
+    if err != nil {
+        return "", "", false
+    }
+    payload := string(raw)
+
+    current, previous := tokenSecrets()
+    valid := hmac.Equal([]byte(sig), []byte(signResume(current, payload)))
+    if !valid && previous != nil {
+        valid = hmac.Equal([]byte(sig), []byte(signResume(previous, payload)))
+    }
+    if !valid {
+        return "", "", false
+    }
+
+    parts := strings.Split(payload, "\n")
+    if len(parts) != 3 {
+        return "", "", false
+    }
+    expires, err := strconv.ParseInt(parts[2], 10, 64)
+    if err != nil || time.Now().Unix() > expires {
+        return "", "", false
+    }
+    return parts[0], parts[1], true
+}
+
+// resumeSession lets a peer reclaim its membership after a refresh, new tab
+// or network change. With a new peerId (PeerJS hands out a fresh ID per
+// page load) its role, permissions, file offers and pending notifications
+// move to the new ID; otherwise it simply comes back online under the old
+// one.
+func (a *API) resumeSession(c *gin.Context) {
+    var req struct {
+        ResumeToken string `json:"resumeToken" binding:"required"`
+        PeerID      string `json:"peerId"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+
+    roomCode, oldID, ok := verifyResumeToken(req.ResumeToken)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired resume token"})
+        return
+    }
+    newID := req.PeerID
+    if newID == "" {
+        newID = oldID
+    }
+    tagRequest(c, roomCode, newID)
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusGone, gin.H{"error": "Room no longer exists"})
+        return
+    }
+
+    room.Lock()
+    if room.Suspended {
+        room.Unlock()
+        c.JSON(http.StatusLocked, gin.H{"error": "Room is suspended pending review"})
+        return
+    }
+    if _, ok := room.Peers[oldID]; !ok {
+        room.Unlock()
+        c.JSON(http.StatusGone, gin.H{"error": "Session expired; join the room again"})
+        return
+    }
+    if newID != oldID && !room.RenamePeer(oldID, newID) {
+        room.Unlock()
+        c.JSON(http.StatusConflict, gin.H{"error": "peerId is already in the room"})
+        return
+    }
+    room.ReconnectPeer(newID, time.Now().Unix())
+    peer := room.Peers[newID]
+    peer.LastSeen.Store(time.Now().Unix())
+    role, permissions := peer.Role, peer.Permissions
+    peers := room.ConnectedPeers(newID)
+    files := room.VisibleFiles(newID)
+    roomSize := len(room.Peers)
+    room.Unlock()
+
+    // Notifications queued for the old ID follow the peer to its new one
+    moved := 0
+    if newID != oldID {
+        for _, n := range a.notifications.Drain(oldID) {
+            a.notifications.Queue(newID, n)
+            moved++
+        }
+    }
+
+    log.Printf("🔁 Peer resumed: %s → %s in Room: %s", oldID, newID, roomCode)
+    a.recordRecentRoom(newID, roomCode, role)
+    a.recordAudit(roomCode, "peer_resumed", newID, "", gin.H{"previousPeerId": oldID, "notificationsMoved": moved})
+    a.notifyRoom(room, newID, notifications.Notification{
+        Type:      "peer_reconnected",
+        PeerID:    newID,
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(gin.H{"previousPeerId": oldID}),
+    })
+
+    c.JSON(http.StatusOK, gin.H{
+        "roomCode":       roomCode,
+        "peerId":         newID,
+        "previousPeerId": oldID,
+        "peers":          peers,
+        "files":          files,
+        "roomSize":       roomSize,
+        "role":           role,
+        "permissions":    permissions,
+        "resumeToken":    a.issueResumeToken(roomCode, newID),
+    })
+}
@@ -0,0 +1,105 @@
+package httpapi
+
+import (
+    "context"
+    "log"
+    "net/http"
+    "sync/atomic"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// retentionPolicy is how long one kind of record is kept. A zero maxAge keeps
+// records forever.
+type retentionPolicy struct {
+    name   string
+    maxAge time.Duration
+    purge  func(cutoff time.Time) int
+
+    purged  atomic.Int64
+    lastRun atomic.Int64
+}
+
+// retentionConfig is the janitor schedule and its policies
+type retentionConfig struct {
+    interval time.Duration
+    policies []*retentionPolicy
+}
+
+// loadRetentionPolicies reads retention settings from the environment
+func (a *API) loadRetentionPolicies() retentionConfig {
+    return retentionConfig{
+        interval: envDuration("RETENTION_INTERVAL", 10*time.Minute),
+        policies: []*retentionPolicy{
+            {
+                name:   "auditLog",
+                maxAge: envDuration("RETENTION_AUDIT_MAX_AGE", 30*24*time.Hour),
+                purge:  a.purgeAuditBefore,
+            },
+            {
+                name:   "notifications",
+                maxAge: envDuration("RETENTION_NOTIFICATIONS_MAX_AGE", 24*time.Hour),
+                purge:  a.notifications.PurgeBefore,
+            },
+        },
+    }
+}
+
+// applyRetention purges records older than their policy
+func (a *API) applyRetention(ctx context.Context) error {
+    now := time.Now()
+    for _, policy := range a.retention.policies {
+        if policy.maxAge <= 0 {
+            continue
+        }
+        n := policy.purge(now.Add(-policy.maxAge))
+        policy.purged.Add(int64(n))
+        policy.lastRun.Store(now.Unix())
+        if n > 0 {
+            log.Printf("🧹 Retention: purged %d %s records", n, policy.name)
+        }
+    }
+    return nil
+}
+
+func (a *API) purgeAuditBefore(cutoff time.Time) int {
+    if a.storage != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+        if err := a.storage.PurgeAuditBefore(ctx, cutoff.Unix()); err != nil {
+            log.Printf("❌ Failed to purge persisted audit entries: %v", err)
+        }
+        cancel()
+    }
+
+    a.audit.mu.Lock()
+    defer a.audit.mu.Unlock()
+
+    // Entries are appended in time order, so everything old is a prefix
+    i := 0
+    for i < len(a.audit.entries) && a.audit.entries[i].Time < cutoff.Unix() {
+        i++
+    }
+    if i > 0 {
+        a.audit.entries = append([]AuditEntry(nil), a.audit.entries[i:]...)
+    }
+    return i
+}
+
+// getRetentionStatus reports the configured policies and how much each purged
+func (a *API) getRetentionStatus(c *gin.Context) {
+    policies := make([]gin.H, 0, len(a.retention.policies))
+    for _, policy := range a.retention.policies {
+        policies = append(policies, gin.H{
+            "name":          policy.name,
+            "maxAgeSeconds": int64(policy.maxAge.Seconds()),
+            "purged":        policy.purged.Load(),
+            "lastRun":       policy.lastRun.Load(),
+        })
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "intervalSeconds": int64(a.retention.interval.Seconds()),
+        "policies":        policies,
+    })
+}
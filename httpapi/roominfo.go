@@ -0,0 +1,119 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+)
+
+// getRoomInfo describes a room for join screens: its name, description,
+// icon and tags alongside the mode and size
+func (a *API) getRoomInfo(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    resp := gin.H{
+        "roomCode":    roomCode,
+        "mode":        room.Mode,
+        "hostId":      room.HostID,
+        "createdAt":   room.CreatedAt,
+        "roomSize":    len(room.Peers),
+        "suspended":   room.Suspended,
+        "locked":      room.Locked,
+        "waitingRoom": room.WaitingRoom,
+        "pinned":      room.Pinned,
+        "info":        room.Info,
+    }
+    if room.Schedule != nil {
+        start, end, ok := room.Schedule.Session(time.Now().Unix())
+        resp["schedule"] = room.Schedule
+        resp["rrule"] = room.Schedule.RRule()
+        if ok {
+            resp["nextSession"] = gin.H{"startsAt": start, "endsAt": end}
+        }
+    }
+    version := room.Version
+    room.RUnlock()
+
+    if notModified(c, roomETag(version)) {
+        return
+    }
+    c.JSON(http.StatusOK, resp)
+}
+
+// updateRoomInfo lets the host change the room's name, description, icon or
+// tags; fields left out of the request are kept
+func (a *API) updateRoomInfo(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    var req struct {
+        HostID      string    `json:"hostId" binding:"required"`
+        Name        *string   `json:"name"`
+        Description *string   `json:"description"`
+        Icon        *string   `json:"icon"`
+        Tags        *[]string `json:"tags"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.HostID)
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    if req.HostID != room.HostID {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can edit the room"})
+        return
+    }
+    info := room.Info
+    if req.Name != nil {
+        info.Name = *req.Name
+    }
+    if req.Description != nil {
+        info.Description = *req.Description
+    }
+    if req.Icon != nil {
+        info.Icon = *req.Icon
+    }
+    if req.Tags != nil {
+        info.Tags = *req.Tags
+    }
+    info.Normalize()
+    if err := info.Validate(); err != nil {
+        room.Unlock()
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    room.Info = info
+    room.Touch()
+    room.Unlock()
+
+    a.notifyRoom(room, req.HostID, notifications.Notification{
+        Type:      "room_updated",
+        PeerID:    req.HostID,
+        Timestamp: time.Now().Unix(),
+        Payload:   notifications.Payload(info),
+    })
+
+    log.Printf("📝 Room details updated: %s", roomCode)
+    a.recordAudit(roomCode, "room_updated", req.HostID, "", nil)
+
+    c.JSON(http.StatusOK, gin.H{"info": info})
+}
@@ -0,0 +1,74 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+)
+
+// lockRoom returns a handler letting the host freeze or reopen membership.
+// While locked, joins from peers not already in the room get 423 Locked;
+// members, including those in their grace period, can still rejoin.
+func (a *API) lockRoom(locked bool) gin.HandlerFunc {
+    event := "room_unlocked"
+    if locked {
+        event = "room_locked"
+    }
+
+    return func(c *gin.Context) {
+        roomCode := c.Param("roomCode")
+
+        var req struct {
+            HostID string `json:"hostId" binding:"required"`
+        }
+
+        if err := c.ShouldBindJSON(&req); err != nil {
+            invalidRequest(c, err)
+            return
+        }
+        tagRequest(c, roomCode, req.HostID)
+
+        room, exists := a.rooms.Get(roomCode)
+        if !exists {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+            return
+        }
+
+        room.Lock()
+        if req.HostID != room.HostID {
+            room.Unlock()
+            c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can lock or unlock the room"})
+            return
+        }
+        changed := room.Locked != locked
+        if changed {
+            room.Locked = locked
+            room.Touch()
+        }
+        roomSize := len(room.Peers)
+        room.Unlock()
+
+        if changed {
+            a.notifyRoom(room, req.HostID, notifications.Notification{
+                Type:      event,
+                PeerID:    req.HostID,
+                Timestamp: time.Now().Unix(),
+            })
+            if locked {
+                log.Printf("🔒 Room locked: %s with %d peers", roomCode, roomSize)
+            } else {
+                log.Printf("🔓 Room unlocked: %s", roomCode)
+            }
+            a.recordAudit(roomCode, event, req.HostID, "", gin.H{"roomSize": roomSize})
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+            "locked":   locked,
+            "roomSize": roomSize,
+        })
+    }
+}
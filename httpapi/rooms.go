@@ -0,0 +1,558 @@
+package httpapi
+
+import (
+    "context"
+    "log"
+    "net/http"
+    "slices"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+func (a *API) createRoom(c *gin.Context) {
+    var req struct {
+        RoomCode string `json:"roomCode" binding:"required,roomcode"`
+        PeerID   string `json:"peerId" binding:"required"`
+        Mode     string `json:"mode"`
+        rooms.PeerProfile
+
+        // WaitingRoom makes later joins wait for the host's approval;
+        // applied only when this request creates the room
+        WaitingRoom bool `json:"waitingRoom"`
+        // Template names one of the caller's saved templates (bearer peer
+        // token) supplying defaults for the fields above and below
+        Template string `json:"template"`
+
+        // Room details, applied only when this request creates the room
+        rooms.RoomInfo
+
+        CaptchaToken string `json:"captchaToken"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, req.RoomCode, req.PeerID)
+
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    // The file policy can only come from a template
+    var policy rooms.FilePolicy
+    if req.Template != "" {
+        owner, ok := authenticatedPeer(c)
+        if !ok {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required to use a template"})
+            return
+        }
+        tmpl, found := a.lookupTemplate(owner, req.Template)
+        if !found {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+            return
+        }
+        if req.Mode == "" {
+            req.Mode = tmpl.Mode
+        }
+        req.WaitingRoom = req.WaitingRoom || tmpl.WaitingRoom
+        if req.Name == "" {
+            req.Name = tmpl.Info.Name
+        }
+        if req.Description == "" {
+            req.Description = tmpl.Info.Description
+        }
+        if req.Icon == "" {
+            req.Icon = tmpl.Info.Icon
+        }
+        if len(req.Tags) == 0 {
+            req.Tags = tmpl.Info.Tags
+        }
+        policy = tmpl.Policy
+    }
+    req.RoomInfo.Normalize()
+    if err := req.RoomInfo.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if req.Mode == "" {
+        req.Mode = rooms.ModeOpen
+    }
+    if !rooms.ValidMode(req.Mode) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be one of open, dropbox, distribution"})
+        return
+    }
+
+    // Only brand-new rooms need a captcha; creating an existing room just joins it
+    if captchaEnabled() {
+        _, exists := a.rooms.Get(req.RoomCode)
+        if !exists {
+            if err := verifyCaptcha(c.Request.Context(), req.CaptchaToken, c.ClientIP()); err != nil {
+                log.Printf("🤖 Captcha verification failed for room %s: %v", req.RoomCode, err)
+                c.JSON(http.StatusForbidden, gin.H{"error": "Captcha verification failed"})
+                return
+            }
+        }
+    }
+
+    // Lifecycle hooks see a creation or a join, whichever this turns out to be
+    if _, exists := a.rooms.Get(req.RoomCode); !exists {
+        if !a.checkRoomCreate(c, req.RoomCode, req.PeerID, req.Mode) {
+            return
+        }
+    } else if status, errMsg := a.checkJoin(c, req.RoomCode, req.PeerID, req.PeerProfile); errMsg != "" {
+        c.JSON(status, gin.H{"error": errMsg})
+        return
+    }
+
+    room, created, err := a.rooms.Create(req.RoomCode, func(census rooms.Census) (*rooms.Room, error) {
+        if a.draining() {
+            return nil, drainRefusal
+        }
+        if qerr := a.checkRoomQuotas(census, c.ClientIP()); qerr != nil {
+            return nil, qerr
+        }
+        room := rooms.New(req.PeerID, req.Mode, c.ClientIP(), time.Now().Unix())
+        room.Info = req.RoomInfo
+        room.WaitingRoom = req.WaitingRoom
+        room.Tenant = tenantOf(c)
+        room.Policy = policy
+        return room, nil
+    })
+    if err != nil {
+        qerr := err.(*quotaError)
+        if qerr.retry {
+            c.Header("Retry-After", strconv.Itoa(int(a.quotas.retryAfter.Seconds())))
+        }
+        c.JSON(qerr.status, gin.H{"error": qerr.message})
+        return
+    }
+    exists := !created
+
+    room.Lock()
+    if room.Suspended {
+        room.Unlock()
+        c.JSON(http.StatusLocked, gin.H{"error": "Room is suspended pending review"})
+        return
+    }
+    if _, member := room.Peers[req.PeerID]; room.Locked && !member {
+        room.Unlock()
+        c.JSON(http.StatusLocked, gin.H{"error": "Room is locked by the host"})
+        return
+    }
+    if _, member := room.Peers[req.PeerID]; !member {
+        if resp, status := scheduleGate(room); resp != nil {
+            room.Unlock()
+            c.JSON(status, resp)
+            return
+        }
+    }
+    if needsApproval(room, req.PeerID) {
+        created, ok := room.RequestJoin(req.PeerID, req.PeerProfile, time.Now().Unix())
+        hostID := room.HostID
+        room.Unlock()
+        resp, status, errMsg := a.joinRequested(req.RoomCode, hostID, req.PeerID, req.PeerProfile, created, ok)
+        if errMsg != "" {
+            c.JSON(status, gin.H{"error": errMsg})
+            return
+        }
+        c.JSON(status, resp)
+        return
+    }
+    role, permissions, rejoined := rejoinPeer(room, req.PeerID, req.PeerProfile)
+    if !rejoined {
+        role, permissions = admitPeer(room, req.PeerID, req.PeerProfile)
+    }
+    familyWarning := a.setAddressFamily(room, req.PeerID, addressFamily(c.ClientIP()))
+    peers := room.ConnectedPeers(req.PeerID)
+    roomSize := len(room.Peers)
+    room.Unlock()
+
+    a.recordRecentRoom(req.PeerID, req.RoomCode, role)
+    switch {
+    case rejoined:
+        a.reconnected(req.RoomCode, room, req.PeerID)
+    case exists:
+        log.Printf("✅ Peer joined: %s → Room: %s", req.PeerID, req.RoomCode)
+        a.recordAudit(req.RoomCode, "peer_joined", req.PeerID, "", nil)
+    default:
+        log.Printf("✅ Room created: %s, peer: %s", req.RoomCode, req.PeerID)
+        a.recordAudit(req.RoomCode, "room_created", req.PeerID, "", gin.H{"mode": req.Mode})
+    }
+
+    resp := gin.H{
+        "peers":       peers,
+        "roomSize":    roomSize,
+        "role":        role,
+        "permissions": permissions,
+        "resumeToken": a.issueResumeToken(req.RoomCode, req.PeerID),
+    }
+    if familyWarning != nil {
+        resp["familyMismatch"] = familyWarning
+    }
+    c.JSON(http.StatusOK, resp)
+}
+
+func (a *API) joinRoom(c *gin.Context) {
+    var req struct {
+        RoomCode string `json:"roomCode" binding:"required"`
+        PeerID   string `json:"peerId" binding:"required"`
+        rooms.PeerProfile
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, req.RoomCode, req.PeerID)
+
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if status, errMsg := a.checkJoin(c, req.RoomCode, req.PeerID, req.PeerProfile); errMsg != "" {
+        c.JSON(status, gin.H{"error": errMsg})
+        return
+    }
+    resp, status, errMsg := a.addPeer(req.RoomCode, req.PeerID, req.PeerProfile, addressFamily(c.ClientIP()))
+    if errMsg != "" {
+        c.JSON(status, gin.H{"error": errMsg})
+        return
+    }
+    a.observePeer(c, signalChurn, req.PeerID)
+
+    c.JSON(status, resp)
+}
+
+// addPeer adds a peer joining over the given address family to an existing
+// room and notifies the other members
+func (a *API) addPeer(roomCode, peerID string, profile rooms.PeerProfile, family string) (gin.H, int, string) {
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        return nil, http.StatusNotFound, "Room not found"
+    }
+
+    room.Lock()
+    if room.Suspended {
+        room.Unlock()
+        return nil, http.StatusLocked, "Room is suspended pending review"
+    }
+    if _, member := room.Peers[peerID]; room.Locked && !member {
+        room.Unlock()
+        return nil, http.StatusLocked, "Room is locked by the host"
+    }
+    // Outside a scheduled session the refusal carries when the room opens,
+    // so it is returned as the response rather than a bare error
+    if _, member := room.Peers[peerID]; !member {
+        if resp, status := scheduleGate(room); resp != nil {
+            room.Unlock()
+            return resp, status, ""
+        }
+    }
+    if needsApproval(room, peerID) {
+        created, ok := room.RequestJoin(peerID, profile, time.Now().Unix())
+        hostID := room.HostID
+        room.Unlock()
+        return a.joinRequested(roomCode, hostID, peerID, profile, created, ok)
+    }
+    existingPeers := room.ConnectedPeers(peerID)
+
+    role, permissions, rejoined := rejoinPeer(room, peerID, profile)
+    if rejoined {
+        familyWarning := a.setAddressFamily(room, peerID, family)
+        roomSize := len(room.Peers)
+        roomSeq := room.EventSeq()
+        room.Unlock()
+        a.reconnected(roomCode, room, peerID)
+        a.recordRecentRoom(peerID, roomCode, role)
+        resp := gin.H{
+            "peers":       existingPeers,
+            "roomSize":    roomSize,
+            "role":        role,
+            "permissions": permissions,
+            "reconnected": true,
+            "roomSeq":     roomSeq,
+            "resumeToken": a.issueResumeToken(roomCode, peerID),
+        }
+        if familyWarning != nil {
+            resp["familyMismatch"] = familyWarning
+        }
+        return resp, http.StatusOK, ""
+    }
+
+    role, permissions = admitPeer(room, peerID, profile)
+    familyWarning := a.setAddressFamily(room, peerID, family)
+    roomSize := len(room.Peers)
+    joined := roomEvent(room, peerID, notifications.Notification{
+        Type:      "peer_joined",
+        PeerID:    peerID,
+        Timestamp: time.Now().Unix(),
+    })
+    room.Unlock()
+
+    // Notify existing peers
+    a.dispatcher.Fanout(existingPeers, joined)
+
+    log.Printf("✅ Peer joined: %s → Room: %s", peerID, roomCode)
+    a.recordRecentRoom(peerID, roomCode, role)
+    a.recordAudit(roomCode, "peer_joined", peerID, "", nil)
+    a.overview.count(overviewJoins)
+
+    resp := gin.H{
+        "peers":       existingPeers,
+        "roomSize":    roomSize,
+        "role":        role,
+        "permissions": permissions,
+        "roomSeq":     joined.RoomSeq,
+        "resumeToken": a.issueResumeToken(roomCode, peerID),
+    }
+    if familyWarning != nil {
+        resp["familyMismatch"] = familyWarning
+    }
+    return resp, http.StatusOK, ""
+}
+
+// leaveRoom removes a peer from a room. With a PEER_GRACE_PERIOD the peer is
+// only marked disconnected, unless it asks to leave for good with
+// "permanent": true, so a page refresh can rejoin without losing its state.
+func (a *API) leaveRoom(c *gin.Context) {
+    var req struct {
+        RoomCode  string `json:"roomCode" binding:"required"`
+        PeerID    string `json:"peerId" binding:"required"`
+        Permanent bool   `json:"permanent"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, req.RoomCode, req.PeerID)
+
+    if a.withdrawJoinRequest(req.RoomCode, req.PeerID) {
+        a.observePeer(c, signalChurn, req.PeerID)
+        c.JSON(http.StatusOK, gin.H{"success": true})
+        return
+    }
+    if a.peerGrace > 0 && !req.Permanent {
+        a.disconnectPeer(req.RoomCode, req.PeerID)
+    } else {
+        a.removePeerFromRoom(req.RoomCode, req.PeerID)
+    }
+    a.observePeer(c, signalChurn, req.PeerID)
+
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// rejoinPeer brings back a peer that is still in its grace period, keeping
+// its role, permissions and files and updating its profile. The caller must
+// hold the room lock.
+func rejoinPeer(room *rooms.Room, peerID string, profile rooms.PeerProfile) (role, permissions string, ok bool) {
+    if !room.ReconnectPeer(peerID, time.Now().Unix()) {
+        return "", "", false
+    }
+    peer := room.Peers[peerID]
+    peer.PeerProfile = profile
+    return peer.Role, peer.Permissions, true
+}
+
+// admitPeer adds a new member with the room's default role and permissions.
+// The caller must hold the room lock.
+func admitPeer(room *rooms.Room, peerID string, profile rooms.PeerProfile) (role, permissions string) {
+    role, permissions = room.DefaultAccess(peerID)
+    room.Peers[peerID] = &rooms.PeerMetadata{
+        PeerID:      peerID,
+        JoinedAt:    time.Now().Unix(),
+        LastSeen:    rooms.NewClock(time.Now().Unix()),
+        Presence:    rooms.PresenceOnline,
+        PeerProfile: profile,
+        Role:        role,
+        Permissions: permissions,
+    }
+    room.TouchPeer(peerID)
+    return role, permissions
+}
+
+// removePeerFromRoom removes a peer and deletes the room once it is empty.
+// Only the removal itself runs under the store lock.
+func (a *API) removePeerFromRoom(roomCode, peerID string) {
+    var isEmpty bool
+    existed := a.rooms.Update(roomCode, func(room *rooms.Room) bool {
+        room.Lock()
+        room.RemovePeer(peerID)
+        isEmpty = room.Abandoned(time.Now().Unix())
+        room.Unlock()
+        return isEmpty
+    })
+    if !existed {
+        return
+    }
+
+    log.Printf("👋 Peer left: %s from Room: %s", peerID, roomCode)
+    a.recordAudit(roomCode, "peer_left", peerID, "", nil)
+
+    if isEmpty {
+        log.Printf("🗑️  Empty room deleted: %s", roomCode)
+        a.recordAudit(roomCode, "room_deleted", "", "", gin.H{"reason": "empty"})
+    }
+}
+
+func (a *API) getRoomPeers(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    requestingPeer := c.Query("peerId")
+
+    limit, ok := pageLimit(c)
+    if !ok {
+        return
+    }
+    joinedAfter, ok := queryUnix(c, "joinedAfter")
+    if !ok {
+        return
+    }
+    if presence := c.Query("presence"); presence != "" && !rooms.ValidPresence(presence) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "presence must be one of online, idle, transferring, away"})
+        return
+    }
+    // Peers the viewer blocked are left out of its listings
+    blocked, blockRevision := a.blocked(requestingPeer)
+    filter := rooms.PeerFilter{Presence: c.Query("presence"), JoinedAfter: joinedAfter, Exclude: blocked}
+
+    // ?since=N asks for only the changes after room version N
+    var since uint64
+    sinceParam := c.Query("since")
+    if sinceParam != "" {
+        var err error
+        if since, err = strconv.ParseUint(sinceParam, 10, 64); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "since must be a room version number"})
+            return
+        }
+    }
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    // Served from the room's immutable snapshot, so polling takes no room
+    // lock; LastSeen is an atomic clock shared with the live peer
+    snap := room.Snapshot()
+    if requestingPeer != "" {
+        if peer := snap.Peer(requestingPeer); peer != nil {
+            peer.LastSeen.Store(time.Now().Unix())
+        }
+    }
+
+    version := snap.Version
+    roomSize := len(snap.Peers)
+    if sinceParam != "" {
+        room.RLock()
+        changed, left, ok := room.PeerDelta(since)
+        version, roomSize = room.Version, len(room.Peers)
+        room.RUnlock()
+        if ok {
+            if blocked != nil {
+                changed = slices.DeleteFunc(changed, func(p rooms.PeerMetadata) bool { return blocked[p.PeerID] })
+            }
+            respond(c, http.StatusOK, gin.H{
+                "version":  version,
+                "since":    since,
+                "changed":  changed,
+                "left":     left,
+                "roomSize": roomSize,
+            })
+            return
+        }
+        // Too far behind the retained history; fall back to the full list
+        snap = room.Snapshot()
+        version, roomSize = snap.Version, len(snap.Peers)
+    }
+
+    peers, nextCursor := snap.Page(filter, c.Query("cursor"), limit)
+
+    etag := roomETag(version)
+    if blocked != nil {
+        etag = `W/"` + strconv.FormatUint(version, 10) + "-" + strconv.FormatInt(blockRevision, 36) + `"`
+    }
+    if notModified(c, formatETag(c, etag)) {
+        return
+    }
+
+    resp := gin.H{
+        "peers":    peers,
+        "roomSize": roomSize,
+        "version":  version,
+    }
+    if nextCursor != "" {
+        resp["nextCursor"] = nextCursor
+    }
+    if sinceParam != "" {
+        resp["resync"] = true
+    }
+    respond(c, http.StatusOK, resp)
+}
+
+// cleanup drops peers that stopped polling, rooms left empty and expired
+// links, pairings, logins and the like
+func (a *API) cleanup(ctx context.Context) error {
+    // Rooms live in each instance's memory, so every instance sweeps its
+    // own; only the leader prunes the relay store the replicas share
+    a.sweepStaleRooms()
+    if a.leader.IsLeader() {
+        a.pruneRelayBlobs()
+    }
+    a.pruneShortLinks()
+    a.prunePairings()
+    a.pruneRecentRooms()
+    a.pruneLogins()
+    a.pruneContactShares()
+    a.pruneNearby()
+    a.pruneQuickShares()
+    a.pruneSuspensions()
+    a.pruneSignalChannels()
+    a.pruneIdempotencyKeys()
+    a.relayQuotas.prune()
+    a.pruneRelayStaging()
+    return nil
+}
+
+// sweepStaleRooms drops peers that stopped polling and rooms left empty
+func (a *API) sweepStaleRooms() {
+    now := time.Now().Unix()
+    staleThreshold := int64(5 * 60) // 5 minutes
+
+    a.rooms.Sweep(func(roomCode string, room *rooms.Room) bool {
+        room.Lock()
+        defer room.Unlock()
+        for peerID, peer := range room.Peers {
+            if peer.Disconnected() || now-peer.LastSeen.Load() <= staleThreshold {
+                continue
+            }
+            if a.peerGrace > 0 {
+                // Removed by expireDisconnectedPeers unless it comes back
+                log.Printf("🧹 Marking stale peer %s disconnected in room %s", peerID, roomCode)
+                room.DisconnectPeer(peerID, now)
+            } else {
+                log.Printf("🧹 Removing stale peer %s from room %s", peerID, roomCode)
+                room.RemovePeer(peerID)
+            }
+            a.recordAudit(roomCode, "peer_expired", "", peerID, nil)
+        }
+        for _, peerID := range room.ExpirePending(now - int64(pendingJoinTTL.Seconds())) {
+            a.recordAudit(roomCode, "join_expired", "", peerID, nil)
+        }
+
+        if room.Abandoned(now) {
+            log.Printf("🧹 Removing empty room %s", roomCode)
+            a.recordAudit(roomCode, "room_deleted", "", "", gin.H{"reason": "empty"})
+            return true
+        }
+        return false
+    })
+}
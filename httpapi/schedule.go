@@ -0,0 +1,246 @@
+package httpapi
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/rooms"
+)
+
+// scheduleRoom creates a room that only admits peers during its scheduled
+// sessions: a single one, or a daily or weekly series for classes and teams
+// that share files at a standing time. The room exists, and keeps its code,
+// from now until the last session ends, and the response carries what a
+// calendar needs to book it.
+func (a *API) scheduleRoom(c *gin.Context) {
+    var req struct {
+        RoomCode        string    `json:"roomCode" binding:"required,roomcode"`
+        HostID          string    `json:"hostId" binding:"required"`
+        Mode            string    `json:"mode"`
+        StartsAt        time.Time `json:"startsAt" binding:"required"`
+        DurationMinutes int       `json:"durationMinutes" binding:"required"`
+        TimeZone        string    `json:"timeZone"`
+        Recurrence      struct {
+            Frequency string     `json:"frequency"`
+            Interval  int        `json:"interval"`
+            Count     int        `json:"count"`
+            Until     *time.Time `json:"until"`
+        } `json:"recurrence"`
+        WaitingRoom bool `json:"waitingRoom"`
+        rooms.RoomInfo
+
+        CaptchaToken string `json:"captchaToken"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, req.RoomCode, req.HostID)
+
+    req.RoomInfo.Normalize()
+    if err := req.RoomInfo.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if req.Mode == "" {
+        req.Mode = rooms.ModeOpen
+    }
+    if !rooms.ValidMode(req.Mode) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be one of open, dropbox, distribution"})
+        return
+    }
+    if !req.StartsAt.After(time.Now()) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "startsAt must be in the future"})
+        return
+    }
+
+    schedule := &rooms.Schedule{
+        StartsAt:  req.StartsAt.Unix(),
+        Duration:  int64(req.DurationMinutes) * 60,
+        TimeZone:  req.TimeZone,
+        Frequency: req.Recurrence.Frequency,
+        Interval:  req.Recurrence.Interval,
+        Count:     req.Recurrence.Count,
+    }
+    if req.Recurrence.Until != nil {
+        schedule.Until = req.Recurrence.Until.Unix()
+    }
+    if err := schedule.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if captchaEnabled() {
+        if err := verifyCaptcha(c.Request.Context(), req.CaptchaToken, c.ClientIP()); err != nil {
+            log.Printf("🤖 Captcha verification failed for room %s: %v", req.RoomCode, err)
+            c.JSON(http.StatusForbidden, gin.H{"error": "Captcha verification failed"})
+            return
+        }
+    }
+
+    if !a.checkRoomCreate(c, req.RoomCode, req.HostID, req.Mode) {
+        return
+    }
+
+    _, created, err := a.rooms.Create(req.RoomCode, func(census rooms.Census) (*rooms.Room, error) {
+        if a.draining() {
+            return nil, drainRefusal
+        }
+        if qerr := a.checkRoomQuotas(census, c.ClientIP()); qerr != nil {
+            return nil, qerr
+        }
+        room := rooms.New(req.HostID, req.Mode, c.ClientIP(), time.Now().Unix())
+        room.Info = req.RoomInfo
+        room.WaitingRoom = req.WaitingRoom
+        room.Schedule = schedule
+        room.Tenant = tenantOf(c)
+        return room, nil
+    })
+    if err != nil {
+        qerr := err.(*quotaError)
+        if qerr.retry {
+            c.Header("Retry-After", strconv.Itoa(int(a.quotas.retryAfter.Seconds())))
+        }
+        c.JSON(qerr.status, gin.H{"error": qerr.message})
+        return
+    }
+    if !created {
+        c.JSON(http.StatusConflict, gin.H{"error": "Room code is already in use"})
+        return
+    }
+
+    log.Printf("📅 Room scheduled: %s at %s (%s)", req.RoomCode, req.StartsAt.UTC().Format(time.RFC3339), schedule.TimeZone)
+    a.recordAudit(req.RoomCode, "room_scheduled", req.HostID, "", gin.H{"mode": req.Mode, "schedule": schedule})
+
+    start, end, _ := schedule.Session(time.Now().Unix())
+    c.JSON(http.StatusCreated, gin.H{
+        "roomCode":    req.RoomCode,
+        "hostId":      req.HostID,
+        "schedule":    schedule,
+        "nextSession": gin.H{"startsAt": start, "endsAt": end},
+        "rrule":       schedule.RRule(),
+        "joinUrl":     joinURL(req.RoomCode),
+        "calendarUrl": publicBaseURL(c) + "/room/" + req.RoomCode + "/calendar.ics",
+    })
+}
+
+// scheduleGate turns away peers outside a scheduled room's sessions. Before
+// a session it returns a 425 body the join page can show, with when the
+// room opens and what it is; after the last one, a 410. It returns nil while
+// a session is in progress or for unscheduled rooms. The caller must hold
+// the room lock.
+func scheduleGate(room *rooms.Room) (gin.H, int) {
+    if room.Schedule == nil {
+        return nil, 0
+    }
+    now := time.Now().Unix()
+    start, end, ok := room.Schedule.Session(now)
+    if !ok {
+        return gin.H{"error": "This room's schedule has ended"}, http.StatusGone
+    }
+    if start <= now {
+        return nil, 0
+    }
+    return gin.H{
+        "error":     "Room is not open yet",
+        "scheduled": true,
+        "opensAt":   start,
+        "closesAt":  end,
+        "timeZone":  room.Schedule.TimeZone,
+        "info":      room.Info,
+    }, http.StatusTooEarly
+}
+
+// getRoomCalendar serves a scheduled room as an iCalendar event, so it can
+// be added to a calendar app
+func (a *API) getRoomCalendar(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    schedule, info := room.Schedule, room.Info
+    room.RUnlock()
+    if schedule == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room is not scheduled"})
+        return
+    }
+
+    summary := info.Name
+    if summary == "" {
+        summary = "File sharing room " + roomCode
+    }
+    loc, _ := time.LoadLocation(schedule.TimeZone)
+    start := time.Unix(schedule.StartsAt, 0).In(loc)
+    end := start.Add(time.Duration(schedule.Duration) * time.Second)
+
+    lines := []string{
+        "BEGIN:VCALENDAR",
+        "VERSION:2.0",
+        "PRODID:-//P2P File Sharing//Scheduled Rooms//EN",
+        "BEGIN:VEVENT",
+        "UID:" + roomCode + "@" + c.Request.Host,
+        "DTSTAMP:" + time.Now().UTC().Format("20060102T150405Z"),
+        icsTime("DTSTART", start, schedule.TimeZone),
+        icsTime("DTEND", end, schedule.TimeZone),
+    }
+    if rule := schedule.RRule(); rule != "" {
+        lines = append(lines, "RRULE:"+rule)
+    }
+    lines = append(lines,
+        "SUMMARY:"+icsEscape(summary),
+        "DESCRIPTION:"+icsEscape(strings.TrimSpace(info.Description+"\n\nJoin: "+joinURL(roomCode))),
+        "URL:"+joinURL(roomCode),
+        "END:VEVENT",
+        "END:VCALENDAR",
+    )
+
+    var body strings.Builder
+    for _, line := range lines {
+        body.WriteString(icsFold(line))
+    }
+    c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", roomCode+".ics"))
+    c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(body.String()))
+}
+
+// icsTime formats a DTSTART or DTEND property in the schedule's time zone
+func icsTime(name string, t time.Time, zone string) string {
+    if zone == "UTC" {
+        return name + ":" + t.UTC().Format("20060102T150405Z")
+    }
+    return name + ";TZID=" + zone + ":" + t.Format("20060102T150405")
+}
+
+// icsEscape escapes iCalendar TEXT values
+func icsEscape(s string) string {
+    return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icsFold ends a content line with CRLF, folding it at 75 octets without
+// splitting a UTF-8 sequence
+func icsFold(line string) string {
+    var b strings.Builder
+    width := 0
+    for _, r := range line {
+        n := len(string(r))
+        if width+n > 75 {
+            b.WriteString("\r\n ")
+            width = 1
+        }
+        b.WriteRune(r)
+        width += n
+    }
+    b.WriteString("\r\n")
+    return b.String()
+}
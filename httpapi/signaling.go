@@ -0,0 +1,183 @@
+package httpapi
+
+import (
+    "encoding/json"
+    "log"
+    "net/http"
+    "sort"
+    "sync"
+    "time"
+)
+
+const (
+    // signalGapTimeout is how long a message waits for an earlier one that
+    // hasn't arrived before the gap is skipped
+    signalGapTimeout = 2 * time.Second
+    // maxHeldSignals bounds the out-of-order messages buffered per channel
+    maxHeldSignals = 64
+    // signalChannelTTL is how long an idle channel's sequence is remembered
+    signalChannelTTL = 10 * time.Minute
+)
+
+// signalChannel orders the messages one peer sends another in one room.
+// Senders number messages from 1, starting again with a new session ID,
+// e.g. on an ICE restart; messages that arrive early, e.g. over
+// WebTransport while an earlier one is still in flight over HTTP, are held
+// until the gap fills or signalGapTimeout passes.
+type signalChannel struct {
+    from, to string
+
+    mu       sync.Mutex
+    next     uint64
+    held     map[uint64]json.RawMessage
+    gapTimer *time.Timer
+    lastUsed time.Time
+    // closed is set once the channel is pruned
+    closed bool
+}
+
+// signalSequencer holds every ordered channel, keyed by room, session,
+// sender and recipient
+type signalSequencer struct {
+    mu       sync.Mutex
+    channels map[string]*signalChannel
+}
+
+// channel returns the channel from → to in roomCode for a signaling
+// session, creating it on first use
+func (s *signalSequencer) channel(roomCode, session, from, to string) *signalChannel {
+    key := roomCode + "\x00" + session + "\x00" + from + "\x00" + to
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    ch := s.channels[key]
+    if ch == nil {
+        ch = &signalChannel{from: from, to: to, next: 1, held: make(map[uint64]json.RawMessage)}
+        s.channels[key] = ch
+    }
+    return ch
+}
+
+func (ch *signalChannel) close() {
+    ch.mu.Lock()
+    ch.closed = true
+    if ch.gapTimer != nil {
+        ch.gapTimer.Stop()
+    }
+    ch.mu.Unlock()
+}
+
+// relayOrdered delivers a sequenced message once every earlier message on
+// its channel has been delivered. Duplicates of delivered messages are
+// accepted and dropped, so senders may retry freely.
+func (a *API) relayOrdered(from, to, roomCode, session string, seq uint64, payload json.RawMessage) (int, string) {
+    ch := a.signals.channel(roomCode, session, from, to)
+
+    ch.mu.Lock()
+    defer ch.mu.Unlock()
+    ch.lastUsed = time.Now()
+
+    switch {
+    case seq < ch.next:
+        return http.StatusOK, ""
+    case seq > ch.next:
+        if _, dup := ch.held[seq]; !dup {
+            if len(ch.held) >= maxHeldSignals {
+                return http.StatusConflict, "Too many out-of-order messages; resend from the missing sequence number"
+            }
+            ch.held[seq] = payload
+        }
+        if ch.gapTimer == nil {
+            ch.gapTimer = time.AfterFunc(signalGapTimeout, func() { a.skipSignalGap(ch) })
+        }
+        return http.StatusOK, ""
+    }
+
+    if status, msg := a.deliverMessage(from, to, seq, payload); status != http.StatusOK {
+        return status, msg
+    }
+    ch.next++
+    a.flushHeldSignals(ch)
+    return http.StatusOK, ""
+}
+
+// flushHeldSignals delivers held messages that are now next in line. The
+// caller must hold the channel lock.
+func (a *API) flushHeldSignals(ch *signalChannel) {
+    for {
+        payload, ok := ch.held[ch.next]
+        if !ok {
+            break
+        }
+        delete(ch.held, ch.next)
+        if status, msg := a.deliverMessage(ch.from, ch.to, ch.next, payload); status != http.StatusOK {
+            log.Printf("⚠️  Dropped held message %d %s → %s: %s", ch.next, ch.from, ch.to, msg)
+        }
+        ch.next++
+    }
+    if len(ch.held) == 0 && ch.gapTimer != nil {
+        ch.gapTimer.Stop()
+        ch.gapTimer = nil
+    }
+}
+
+// skipSignalGap gives up on the message a channel is waiting for and moves
+// on to the earliest held one
+func (a *API) skipSignalGap(ch *signalChannel) {
+    ch.mu.Lock()
+    defer ch.mu.Unlock()
+
+    ch.gapTimer = nil
+    if ch.closed || len(ch.held) == 0 {
+        return
+    }
+
+    seqs := make([]uint64, 0, len(ch.held))
+    for seq := range ch.held {
+        seqs = append(seqs, seq)
+    }
+    sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+    log.Printf("⏭️  Skipping signaling gap %s → %s: %d..%d never arrived", ch.from, ch.to, ch.next, seqs[0]-1)
+    ch.next = seqs[0]
+    a.flushHeldSignals(ch)
+    if len(ch.held) > 0 {
+        ch.gapTimer = time.AfterFunc(signalGapTimeout, func() { a.skipSignalGap(ch) })
+    }
+}
+
+// pruneSignalChannels forgets channels idle for longer than signalChannelTTL
+func (a *API) pruneSignalChannels() {
+    cutoff := time.Now().Add(-signalChannelTTL)
+
+    a.signals.mu.Lock()
+    defer a.signals.mu.Unlock()
+
+    for key, ch := range a.signals.channels {
+        ch.mu.Lock()
+        idle := ch.lastUsed.Before(cutoff)
+        ch.mu.Unlock()
+        if idle {
+            ch.close()
+            delete(a.signals.channels, key)
+        }
+    }
+}
+
+// eraseSignalChannels closes the ordered channels to or from peerID, with
+// any messages they hold, returning how many there were
+func (a *API) eraseSignalChannels(peerID string) int {
+    a.signals.mu.Lock()
+    defer a.signals.mu.Unlock()
+
+    removed := 0
+    for key, ch := range a.signals.channels {
+        if ch.from == peerID || ch.to == peerID {
+            ch.close()
+            delete(a.signals.channels, key)
+            removed++
+        }
+    }
+    return removed
+}
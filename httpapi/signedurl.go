@@ -0,0 +1,174 @@
+package httpapi
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "errors"
+    "log"
+    "net/http"
+    "net/url"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    // defaultSignedURLTTL is how long QR codes and other join links stay
+    // valid (SIGNED_URL_TTL)
+    defaultSignedURLTTL = 24 * time.Hour
+    // defaultInviteLinkTTL is how long an emailed or texted invitation link
+    // stays valid (INVITE_LINK_TTL)
+    defaultInviteLinkTTL = 7 * 24 * time.Hour
+)
+
+var (
+    errURLUnsigned = errors.New("link is not signed")
+    errURLExpired  = errors.New("link has expired")
+    errURLInvalid  = errors.New("link signature is invalid")
+)
+
+// urlKey is one key signed URLs are checked against
+type urlKey struct {
+    id     string
+    secret []byte
+}
+
+// urlSigningKeys returns the keys for signed URLs, the one to sign with
+// first. URL_SIGNING_KEYS lists them as "id:secret,..."; to rotate, put a
+// new key first and drop the old one once the links it signed have
+// expired. Without it, keys are derived from the peer token secrets, so
+// they rotate along with PEER_TOKEN_SECRET.
+func urlSigningKeys() []urlKey {
+    if env := os.Getenv("URL_SIGNING_KEYS"); env != "" {
+        var keys []urlKey
+        for _, entry := range strings.Split(env, ",") {
+            id, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+            if ok && id != "" && secret != "" {
+                keys = append(keys, urlKey{id: id, secret: []byte(secret)})
+            }
+        }
+        if len(keys) > 0 {
+            return keys
+        }
+        log.Println("⚠️  URL_SIGNING_KEYS has no id:secret entries, using keys derived from the peer token secret")
+    }
+
+    current, previous := tokenSecrets()
+    keys := []urlKey{derivedURLKey(current)}
+    if previous != nil {
+        keys = append(keys, derivedURLKey(previous))
+    }
+    return keys
+}
+
+// derivedURLKey derives a URL signing key from a peer token key, named by a
+// fingerprint so links name the key that signed them across rotations
+func derivedURLKey(peerKey []byte) urlKey {
+    mac := hmac.New(sha256.New, peerKey)
+    mac.Write([]byte("signed-url"))
+    secret := mac.Sum(nil)
+    fingerprint := sha256.Sum256(secret)
+    return urlKey{id: base64.RawURLEncoding.EncodeToString(fingerprint[:6]), secret: secret}
+}
+
+// urlSignature signs a path and its query, less any sig parameter
+func urlSignature(secret []byte, path string, query url.Values) string {
+    signed := make(url.Values, len(query))
+    for k, v := range query {
+        if k != "sig" {
+            signed[k] = v
+        }
+    }
+    mac := hmac.New(sha256.New, secret)
+    mac.Write([]byte(path + "?" + signed.Encode()))
+    return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signPath returns path, escaped, with query, an expiry ttl from now and a
+// signature added, relative to the router
+func signPath(path string, query url.Values, ttl time.Duration) string {
+    if query == nil {
+        query = url.Values{}
+    }
+    key := urlSigningKeys()[0]
+    query.Set("exp", strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))
+    query.Set("kid", key.id)
+    query.Set("sig", urlSignature(key.secret, path, query))
+    return (&url.URL{Path: path}).EscapedPath() + "?" + query.Encode()
+}
+
+// signedURL returns the absolute signed URL of path on this backend
+func signedURL(c *gin.Context, path string, query url.Values, ttl time.Duration) string {
+    return publicBaseURL(c) + signPath(path, query, ttl)
+}
+
+// verifySignedPath checks a signed path's key, signature and expiry
+func verifySignedPath(path string, query url.Values) error {
+    sig := query.Get("sig")
+    if sig == "" {
+        return errURLUnsigned
+    }
+    kid := query.Get("kid")
+    valid := false
+    for _, key := range urlSigningKeys() {
+        if key.id == kid && hmac.Equal([]byte(sig), []byte(urlSignature(key.secret, path, query))) {
+            valid = true
+            break
+        }
+    }
+    if !valid {
+        return errURLInvalid
+    }
+    exp, err := strconv.ParseInt(query.Get("exp"), 10, 64)
+    if err != nil {
+        return errURLInvalid
+    }
+    if time.Now().Unix() > exp {
+        return errURLExpired
+    }
+    return nil
+}
+
+// requireSignedURL rejects requests whose URL isn't signed by signPath or
+// has expired. With required false, unsigned URLs pass but a signature, if
+// present, must still be valid.
+func requireSignedURL(required bool) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        query := c.Request.URL.Query()
+        if !required && query.Get("sig") == "" {
+            c.Next()
+            return
+        }
+        switch verifySignedPath(c.Request.URL.Path, query) {
+        case nil:
+            c.Next()
+        case errURLExpired:
+            c.AbortWithStatusJSON(http.StatusGone, gin.H{"error": "Link has expired"})
+        case errURLUnsigned:
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Link must be signed"})
+        default:
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Link signature is invalid"})
+        }
+    }
+}
+
+// followJoinLink redirects a signed join link, as found in QR codes and
+// invitations, to the frontend with the room pre-filled
+func (a *API) followJoinLink(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    if _, exists := a.rooms.Get(roomCode); !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+    c.Redirect(http.StatusFound, joinURL(roomCode))
+}
+
+// signedJoinURL returns a signed join link for roomCode valid for ttl
+func signedJoinURL(c *gin.Context, roomCode string, ttl time.Duration) string {
+    return signedURL(c, "/join/"+roomCode, nil, ttl)
+}
@@ -0,0 +1,147 @@
+package httpapi
+
+import (
+    "net/http"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// splitSigned splits a path from signPath into its unescaped path and query
+func splitSigned(t *testing.T, signed string) (string, url.Values) {
+    t.Helper()
+    u, err := url.Parse(signed)
+    if err != nil {
+        t.Fatalf("parsing %q: %v", signed, err)
+    }
+    return u.Path, u.Query()
+}
+
+func TestVerifySignedPath(t *testing.T) {
+    tests := []struct {
+        name string
+        // signKeys and verifyKeys are URL_SIGNING_KEYS when signing and
+        // when verifying
+        signKeys   string
+        verifyKeys string
+        ttl        time.Duration
+        tamper     func(path string, query url.Values) string
+        want       error
+    }{
+        {"valid", "k1:one", "k1:one", time.Hour, nil, nil},
+        {"expired", "k1:one", "k1:one", -time.Minute, nil, errURLExpired},
+        {"unsigned", "k1:one", "k1:one", time.Hour, func(path string, q url.Values) string {
+            q.Del("sig")
+            return path
+        }, errURLUnsigned},
+        {"other path", "k1:one", "k1:one", time.Hour, func(string, url.Values) string {
+            return "/join/OTHER"
+        }, errURLInvalid},
+        {"expiry extended", "k1:one", "k1:one", time.Hour, func(path string, q url.Values) string {
+            q.Set("exp", "99999999999")
+            return path
+        }, errURLInvalid},
+        {"parameter added", "k1:one", "k1:one", time.Hour, func(path string, q url.Values) string {
+            q.Set("host", "1")
+            return path
+        }, errURLInvalid},
+        {"signature altered", "k1:one", "k1:one", time.Hour, func(path string, q url.Values) string {
+            q.Set("sig", strings.Repeat("A", len(q.Get("sig"))))
+            return path
+        }, errURLInvalid},
+        {"unknown key id", "k1:one", "k1:one", time.Hour, func(path string, q url.Values) string {
+            q.Set("kid", "k2")
+            return path
+        }, errURLInvalid},
+        {"wrong secret", "k1:one", "k1:two", time.Hour, nil, errURLInvalid},
+        {"signed with the previous key after rotation", "k1:one", "k2:two,k1:one", time.Hour, nil, nil},
+        {"previous key dropped", "k1:one", "k2:two", time.Hour, nil, errURLInvalid},
+        {"malformed entries skipped", "k1:one", " k1:one , broken, :x", time.Hour, nil, nil},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            t.Setenv("URL_SIGNING_KEYS", tt.signKeys)
+            path, query := splitSigned(t, signPath("/join/ROOM", nil, tt.ttl))
+            if tt.tamper != nil {
+                path = tt.tamper(path, query)
+            }
+            t.Setenv("URL_SIGNING_KEYS", tt.verifyKeys)
+            if err := verifySignedPath(path, query); err != tt.want {
+                t.Errorf("verifySignedPath = %v, want %v", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestSignPathDerivedKey(t *testing.T) {
+    t.Setenv("URL_SIGNING_KEYS", "")
+    path, query := splitSigned(t, signPath("/s/a b", url.Values{"x": {"1"}}, time.Hour))
+    if path != "/s/a b" || query.Get("x") != "1" {
+        t.Fatalf("signed path %q, query %v", path, query)
+    }
+    if err := verifySignedPath(path, query); err != nil {
+        t.Errorf("verifySignedPath = %v with the derived key", err)
+    }
+    if kid := query.Get("kid"); kid != urlSigningKeys()[0].id {
+        t.Errorf("kid %q does not name the derived key", kid)
+    }
+}
+
+func TestRequireSignedURL(t *testing.T) {
+    t.Setenv("URL_SIGNING_KEYS", "k1:one")
+    valid := signPath("/file", nil, time.Hour)
+    expired := signPath("/file", nil, -time.Minute)
+
+    tests := []struct {
+        name     string
+        required bool
+        path     string
+        want     int
+    }{
+        {"required, valid", true, valid, http.StatusOK},
+        {"required, unsigned", true, "/file", http.StatusForbidden},
+        {"required, expired", true, expired, http.StatusGone},
+        {"required, tampered", true, valid + "&x=1", http.StatusForbidden},
+        {"optional, unsigned", false, "/file", http.StatusOK},
+        {"optional, valid", false, valid, http.StatusOK},
+        {"optional, expired", false, expired, http.StatusGone},
+        {"optional, tampered", false, valid + "&x=1", http.StatusForbidden},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := gin.New()
+            r.GET("/file", requireSignedURL(tt.required), func(c *gin.Context) { c.Status(http.StatusOK) })
+            if w := request(r, http.MethodGet, tt.path, "", "", ""); w.Code != tt.want {
+                t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+            }
+        })
+    }
+}
+
+func TestFollowJoinLink(t *testing.T) {
+    t.Setenv("URL_SIGNING_KEYS", "k1:one")
+    _, h := newTestRouter(t)
+    request(h, http.MethodPost, "/room/create", "application/json", "", `{"roomCode":"linked","peerId":"peer-a"}`)
+
+    tests := []struct {
+        name string
+        path string
+        want int
+    }{
+        {"signed", signPath("/join/linked", nil, time.Hour), http.StatusFound},
+        {"unsigned", "/join/linked", http.StatusForbidden},
+        {"signed for another room", strings.Replace(signPath("/join/linked", nil, time.Hour), "linked", "other", 1), http.StatusForbidden},
+        {"expired", signPath("/join/linked", nil, -time.Minute), http.StatusGone},
+        {"signed, no room", signPath("/join/missing", nil, time.Hour), http.StatusNotFound},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if w := request(h, http.MethodGet, tt.path, "", "", ""); w.Code != tt.want {
+                t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+            }
+        })
+    }
+}
@@ -0,0 +1,159 @@
+package httpapi
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "sort"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+    "p2p-file-share-backend/storage"
+)
+
+// stateSnapshotVersion is bumped whenever StateSnapshot changes incompatibly
+const stateSnapshotVersion = 1
+
+// StateSnapshot is the full live state of an instance, for moving it to
+// another host or store backend
+type StateSnapshot struct {
+    Version    int                  `json:"version"`
+    ExportedAt int64                `json:"exportedAt"`
+    Rooms      []storage.RoomRecord `json:"rooms"`
+    // Notifications holds the pending queue of every peer in a room
+    Notifications map[string][]notifications.Notification `json:"notifications"`
+    ShortLinks    []ShortLinkRecord                       `json:"shortLinks"`
+    Audit         []AuditEntry                            `json:"audit"`
+}
+
+// ShortLinkRecord is a live short link in a snapshot
+type ShortLinkRecord struct {
+    Slug      string `json:"slug"`
+    RoomCode  string `json:"roomCode"`
+    ExpiresAt int64  `json:"expiresAt"`
+}
+
+// exportState returns a snapshot of every room, the notifications pending
+// for their peers, live short links and the audit trail
+func (a *API) exportState(c *gin.Context) {
+    snap := StateSnapshot{
+        Version:       stateSnapshotVersion,
+        ExportedAt:    time.Now().Unix(),
+        Rooms:         []storage.RoomRecord{},
+        Notifications: make(map[string][]notifications.Notification),
+        ShortLinks:    []ShortLinkRecord{},
+    }
+
+    a.rooms.Range(func(code string, room *rooms.Room) bool {
+        room.RLock()
+        snap.Rooms = append(snap.Rooms, storage.Record(code, room))
+        room.RUnlock()
+        return true
+    })
+    sort.Slice(snap.Rooms, func(i, j int) bool { return snap.Rooms[i].Code < snap.Rooms[j].Code })
+
+    for _, rec := range snap.Rooms {
+        for _, peer := range rec.Peers {
+            if _, done := snap.Notifications[peer.PeerID]; done {
+                continue
+            }
+            if pending := a.notifications.Peek(peer.PeerID); len(pending) > 0 {
+                snap.Notifications[peer.PeerID] = pending
+            }
+        }
+    }
+
+    now := time.Now()
+    a.shortLinks.mu.RLock()
+    for slug, link := range a.shortLinks.links {
+        if now.Before(link.ExpiresAt) {
+            snap.ShortLinks = append(snap.ShortLinks, ShortLinkRecord{Slug: slug, RoomCode: link.RoomCode, ExpiresAt: link.ExpiresAt.Unix()})
+        }
+    }
+    a.shortLinks.mu.RUnlock()
+
+    a.audit.mu.RLock()
+    snap.Audit = append([]AuditEntry{}, a.audit.entries...)
+    a.audit.mu.RUnlock()
+
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="p2p-snapshot-%d.json"`, snap.ExportedAt))
+    c.JSON(http.StatusOK, snap)
+}
+
+// importState loads a snapshot exported by another instance. Rooms and short
+// links whose code or slug is already in use here are skipped; the audit
+// trail is only imported into an instance that has not recorded any yet.
+func (a *API) importState(c *gin.Context) {
+    var snap StateSnapshot
+    if err := c.ShouldBindJSON(&snap); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    if snap.Version != stateSnapshotVersion {
+        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported snapshot version %d (expected %d)", snap.Version, stateSnapshotVersion)})
+        return
+    }
+
+    imported, skipped := 0, []string{}
+    importedPeers := make(map[string]bool)
+    for _, rec := range snap.Rooms {
+        if _, ok := a.adoptRoom(rec); !ok {
+            skipped = append(skipped, rec.Code)
+            continue
+        }
+        imported++
+        for _, peer := range rec.Peers {
+            importedPeers[peer.PeerID] = true
+        }
+    }
+
+    queued := 0
+    for peerID, pending := range snap.Notifications {
+        if !importedPeers[peerID] {
+            continue
+        }
+        for _, n := range pending {
+            a.notifications.Queue(peerID, n)
+            queued++
+        }
+    }
+
+    links := 0
+    now := time.Now()
+    a.shortLinks.mu.Lock()
+    for _, link := range snap.ShortLinks {
+        expiresAt := time.Unix(link.ExpiresAt, 0)
+        if _, taken := a.shortLinks.links[link.Slug]; taken || now.After(expiresAt) {
+            continue
+        }
+        a.shortLinks.links[link.Slug] = shortLink{RoomCode: link.RoomCode, ExpiresAt: expiresAt}
+        links++
+    }
+    a.shortLinks.mu.Unlock()
+
+    audit := 0
+    a.audit.mu.Lock()
+    if len(a.audit.entries) == 0 && len(snap.Audit) > 0 {
+        a.audit.entries = append(a.audit.entries, snap.Audit...)
+        a.audit.nextID = snap.Audit[len(snap.Audit)-1].ID + 1
+        audit = len(snap.Audit)
+        close(a.audit.appended)
+        a.audit.appended = make(chan struct{})
+    }
+    a.audit.mu.Unlock()
+
+    log.Printf("📦 Imported snapshot from %s: %d rooms, %d notifications, %d short links",
+        time.Unix(snap.ExportedAt, 0).UTC().Format(time.RFC3339), imported, queued, links)
+    a.recordAudit("", "snapshot_imported", "admin", "", gin.H{"rooms": imported, "skippedRooms": len(skipped)})
+
+    c.JSON(http.StatusOK, gin.H{
+        "rooms":         imported,
+        "skippedRooms":  skipped,
+        "notifications": queued,
+        "shortLinks":    links,
+        "auditEntries":  audit,
+    })
+}
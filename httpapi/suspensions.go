@@ -0,0 +1,303 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// Kinds of actor an operator can suspend
+const (
+    suspendPeer   = "peer"
+    suspendIP     = "ip"
+    suspendTenant = "tenant"
+)
+
+// suspensionScope keys IP hashes for suspensions apart from room network
+// hints, so neither can be matched against the other
+const suspensionScope = "\x00suspension"
+
+// suspension bars one peer, IP or tenant from changing anything
+type suspension struct {
+    Kind        string `json:"kind"`
+    Value       string `json:"value"`
+    Reason      string `json:"reason,omitempty"`
+    Actor       string `json:"actor"`
+    SuspendedAt int64  `json:"suspendedAt"`
+    // ExpiresAt is zero for a suspension lifted only by an operator
+    ExpiresAt int64 `json:"expiresAt,omitempty"`
+}
+
+// suspensionList holds the active suspensions by kind and value
+type suspensionList struct {
+    mu      sync.RWMutex
+    entries map[string]*suspension
+}
+
+func suspensionKey(kind, value string) string {
+    return kind + "\n" + value
+}
+
+// suspensionIPHash identifies a client IP in suspensions without keeping
+// the address. It is keyed by the peer token secret, so IP suspensions
+// lapse when PEER_TOKEN_SECRET is rotated.
+func suspensionIPHash(ip string) string {
+    return networkHash(suspensionScope, ip)
+}
+
+// suspended returns the active suspension of kind for value, if any
+func (a *API) suspended(kind, value string) (*suspension, bool) {
+    if value == "" {
+        return nil, false
+    }
+    a.suspensions.mu.RLock()
+    defer a.suspensions.mu.RUnlock()
+    s, ok := a.suspensions.entries[suspensionKey(kind, value)]
+    if !ok || (s.ExpiresAt != 0 && time.Now().Unix() >= s.ExpiresAt) {
+        return nil, false
+    }
+    return s, true
+}
+
+// suspendedPeer reports whether peerID, or the user it acts for, is
+// suspended
+func (a *API) suspendedPeer(peerID string) (*suspension, bool) {
+    if s, ok := a.suspended(suspendPeer, peerID); ok {
+        return s, true
+    }
+    if userID, ok := a.ownerOf(peerID); ok && userID != peerID {
+        return a.suspended(suspendPeer, userID)
+    }
+    return nil, false
+}
+
+// refuseSuspended answers mutating requests from a suspended IP, tenant or
+// peer with 403. The peer is taken from the route, the bearer token, a
+// peerId query parameter or the peerId (or from) field of a JSON body.
+// Reads and the admin API are never refused.
+func (a *API) refuseSuspended() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        switch c.Request.Method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+            c.Next()
+            return
+        }
+        if strings.HasPrefix(c.FullPath(), "/admin/") {
+            c.Next()
+            return
+        }
+
+        if !a.anySuspended() {
+            c.Next()
+            return
+        }
+        if s, ok := a.suspendedActor(c.ClientIP(), tenantOf(c), suspensionCandidates(c)...); ok {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Suspended by an operator", "reason": s.Reason})
+            return
+        }
+        c.Next()
+    }
+}
+
+// anySuspended reports whether there are suspensions to check at all
+func (a *API) anySuspended() bool {
+    a.suspensions.mu.RLock()
+    defer a.suspensions.mu.RUnlock()
+    return len(a.suspensions.entries) > 0
+}
+
+// suspendedActor returns the suspension barring a client IP, a tenant or
+// any of peers, if one does
+func (a *API) suspendedActor(ip, tenant string, peers ...string) (*suspension, bool) {
+    if !a.anySuspended() {
+        return nil, false
+    }
+
+    if s, ok := a.suspended(suspendIP, suspensionIPHash(ip)); ok {
+        return s, true
+    }
+    if s, ok := a.suspended(suspendTenant, tenant); ok {
+        return s, true
+    }
+    for _, peerID := range peers {
+        if s, ok := a.suspendedPeer(peerID); ok {
+            return s, true
+        }
+    }
+    return nil, false
+}
+
+// suspensionCandidates returns the peers a request may be acting as
+func suspensionCandidates(c *gin.Context) []string {
+    fields := bodyFields(c)
+    candidates := []string{requestPeer(c), c.Query("peerId"), c.Query("hostId")}
+    for _, name := range []string{"peerId", "hostId", "from", "reporterId"} {
+        candidates = append(candidates, fields.get(name)...)
+    }
+    return candidates
+}
+
+// suspend bars kind/value from mutating endpoints for duration (zero for
+// good) and revokes what it currently holds: a suspended peer is removed
+// from every room and told with a suspended event, while rooms created from
+// a suspended IP or under a suspended tenant are closed. Open WebTransport
+// sessions of the actor are closed too. It returns the codes of the rooms
+// affected.
+func (a *API) suspend(kind, value, reason, actor string, duration time.Duration) (*suspension, []string) {
+    now := time.Now()
+    s := &suspension{
+        Kind:        kind,
+        Value:       value,
+        Reason:      reason,
+        Actor:       actor,
+        SuspendedAt: now.Unix(),
+    }
+    if duration > 0 {
+        s.ExpiresAt = now.Add(duration).Unix()
+    }
+    a.suspensions.mu.Lock()
+    a.suspensions.entries[suspensionKey(kind, value)] = s
+    a.suspensions.mu.Unlock()
+
+    var revoked []string
+    switch kind {
+    case suspendPeer:
+        peers := []string{value}
+        if strings.HasPrefix(value, userIDPrefix) {
+            peers = append(peers, a.userDevices(value)...)
+        }
+        for _, peerID := range peers {
+            revoked = append(revoked, a.removePeerFromAllRooms(peerID)...)
+            a.eraseNearby(peerID)
+            a.queueNotification(peerID, notifications.Notification{
+                Type:      "suspended",
+                PeerID:    peerID,
+                Timestamp: now.Unix(),
+                Payload:   notifications.Payload(gin.H{"reason": reason, "expiresAt": s.ExpiresAt}),
+            })
+        }
+    case suspendIP, suspendTenant:
+        var codes []string
+        a.rooms.Range(func(roomCode string, room *rooms.Room) bool {
+            room.RLock()
+            match := (kind == suspendIP && suspensionIPHash(room.CreatorIP) == value) ||
+                (kind == suspendTenant && room.Tenant == value)
+            room.RUnlock()
+            if match {
+                codes = append(codes, roomCode)
+            }
+            return true
+        })
+        for _, roomCode := range codes {
+            if a.closeRoom(roomCode, "suspended") {
+                revoked = append(revoked, roomCode)
+            }
+        }
+    }
+
+    a.closeSuspendedSessions()
+
+    log.Printf("⛔ Suspended %s %s by %s: %s (%d rooms revoked)", kind, value, actor, reason, len(revoked))
+    a.recordAudit("", "suspended", actor, kind+":"+value, gin.H{"reason": reason, "expiresAt": s.ExpiresAt, "rooms": revoked})
+    return s, revoked
+}
+
+// listSuspensions lists the active suspensions, newest first
+func (a *API) listSuspensions(c *gin.Context) {
+    now := time.Now().Unix()
+    a.suspensions.mu.RLock()
+    list := make([]suspension, 0, len(a.suspensions.entries))
+    for _, s := range a.suspensions.entries {
+        if s.ExpiresAt == 0 || now < s.ExpiresAt {
+            list = append(list, *s)
+        }
+    }
+    a.suspensions.mu.RUnlock()
+
+    sort.Slice(list, func(i, j int) bool {
+        return list[i].SuspendedAt > list[j].SuspendedAt
+    })
+    c.JSON(http.StatusOK, gin.H{"suspensions": list})
+}
+
+// createSuspension suspends a peer ID, an IP hash (or a raw IP, which is
+// hashed) or a tenant, for duration seconds or until lifted
+func (a *API) createSuspension(c *gin.Context) {
+    var req struct {
+        Kind  string `json:"kind" binding:"required"`
+        Value string `json:"value"`
+        // IP may be given instead of Value for kind "ip"
+        IP       string `json:"ip"`
+        Reason   string `json:"reason"`
+        Duration int64  `json:"duration"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+
+    switch req.Kind {
+    case suspendPeer, suspendTenant:
+    case suspendIP:
+        if req.IP != "" {
+            req.Value = suspensionIPHash(req.IP)
+        }
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be peer, ip or tenant"})
+        return
+    }
+    if req.Value == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "value is required"})
+        return
+    }
+    if req.Duration < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "duration must not be negative"})
+        return
+    }
+
+    s, revoked := a.suspend(req.Kind, req.Value, req.Reason, "admin", time.Duration(req.Duration)*time.Second)
+    if revoked == nil {
+        revoked = []string{}
+    }
+    c.JSON(http.StatusCreated, gin.H{"suspension": s, "revokedRooms": revoked})
+}
+
+// liftSuspension removes a suspension. Revoked memberships are not
+// restored.
+func (a *API) liftSuspension(c *gin.Context) {
+    kind, value := c.Param("kind"), c.Param("value")
+
+    a.suspensions.mu.Lock()
+    key := suspensionKey(kind, value)
+    _, exists := a.suspensions.entries[key]
+    delete(a.suspensions.entries, key)
+    a.suspensions.mu.Unlock()
+
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Suspension not found"})
+        return
+    }
+    log.Printf("✅ Suspension lifted: %s %s", kind, value)
+    a.recordAudit("", "suspension_lifted", "admin", kind+":"+value, nil)
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// pruneSuspensions drops expired suspensions
+func (a *API) pruneSuspensions() {
+    now := time.Now().Unix()
+    a.suspensions.mu.Lock()
+    defer a.suspensions.mu.Unlock()
+    for key, s := range a.suspensions.entries {
+        if s.ExpiresAt != 0 && now >= s.ExpiresAt {
+            delete(a.suspensions.entries, key)
+        }
+    }
+}
@@ -0,0 +1,276 @@
+package httpapi
+
+import (
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// ICE outcomes a client can report
+var connectionOutcomes = map[string]bool{
+    "direct": true, // host candidates, same network
+    "srflx":  true, // hole-punched through NAT via STUN
+    "relay":  true, // relayed through TURN
+    "failed": true,
+}
+
+const (
+    telemetryPerMinute    = 30 // reports per client IP
+    telemetryRetention    = 7 * 24 * time.Hour
+    defaultAnalyticsHours = 24
+    maxSetupMs            = 120000
+)
+
+// setupBucketsMs are the upper bounds of the setup-time histogram
+var setupBucketsMs = []int64{250, 500, 1000, 2000, 3000, 5000, 10000, 20000, 30000, maxSetupMs}
+
+// outcomeStats aggregates the reports for one outcome
+type outcomeStats struct {
+    count        int64
+    setupTotalMs int64
+    setupHist    []int64
+    relayedBytes int64
+}
+
+// analyticsBucket aggregates the reports received in one hour
+type analyticsBucket struct {
+    byOutcome map[string]*outcomeStats
+    byBrowser map[string]map[string]int64
+}
+
+// connectionAnalytics keeps hourly buckets of connection reports for
+// telemetryRetention
+type connectionAnalytics struct {
+    mu      sync.Mutex
+    buckets map[int64]*analyticsBucket
+}
+
+// reportConnection records how a client's peer connection was established,
+// counting it towards the room's insights too when roomCode is given
+func (a *API) reportConnection(c *gin.Context) {
+    var req struct {
+        Outcome      string `json:"outcome" binding:"required"`
+        SetupMs      int64  `json:"setupMs"`
+        Browser      string `json:"browser"`
+        RelayedBytes int64  `json:"relayedBytes"`
+        RoomCode     string `json:"roomCode"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    if !connectionOutcomes[req.Outcome] {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "outcome must be one of direct, srflx, relay, failed"})
+        return
+    }
+    if req.SetupMs < 0 || req.SetupMs > maxSetupMs || req.RelayedBytes < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "setupMs must be between 0 and " + strconv.Itoa(maxSetupMs) + " and relayedBytes non-negative"})
+        return
+    }
+
+    if !a.telemetryLimiter.Allow(c.ClientIP()) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+        return
+    }
+
+    browser := normalizeBrowser(req.Browser)
+    if browser == "" {
+        browser = browserFromUserAgent(c.Request.UserAgent())
+    }
+
+    hour := time.Now().Truncate(time.Hour).Unix()
+    a.analytics.mu.Lock()
+    bucket := a.analytics.buckets[hour]
+    if bucket == nil {
+        bucket = &analyticsBucket{
+            byOutcome: make(map[string]*outcomeStats),
+            byBrowser: make(map[string]map[string]int64),
+        }
+        a.analytics.buckets[hour] = bucket
+        a.pruneAnalytics()
+    }
+    stats := bucket.byOutcome[req.Outcome]
+    if stats == nil {
+        stats = &outcomeStats{setupHist: make([]int64, len(setupBucketsMs))}
+        bucket.byOutcome[req.Outcome] = stats
+    }
+    stats.count++
+    stats.setupTotalMs += req.SetupMs
+    stats.setupHist[sort.Search(len(setupBucketsMs), func(i int) bool { return req.SetupMs <= setupBucketsMs[i] })]++
+    stats.relayedBytes += req.RelayedBytes
+    if bucket.byBrowser[browser] == nil {
+        bucket.byBrowser[browser] = make(map[string]int64)
+    }
+    bucket.byBrowser[browser][req.Outcome]++
+    a.analytics.mu.Unlock()
+
+    if req.RoomCode != "" {
+        a.recordRoomConnection(req.RoomCode, req.Outcome, req.RelayedBytes)
+    }
+
+    c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// pruneAnalytics drops buckets older than telemetryRetention. The caller
+// must hold the analytics lock.
+func (a *API) pruneAnalytics() {
+    cutoff := time.Now().Add(-telemetryRetention).Unix()
+    for hour := range a.analytics.buckets {
+        if hour < cutoff {
+            delete(a.analytics.buckets, hour)
+        }
+    }
+}
+
+// getAnalytics breaks down connection outcomes over the last ?hours= hours
+// (default 24, at most a week): counts and setup times per outcome, outcomes
+// per browser, and the share of connections relayed through TURN
+func (a *API) getAnalytics(c *gin.Context) {
+    hours := defaultAnalyticsHours
+    if v := c.Query("hours"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n <= 0 || n > int(telemetryRetention/time.Hour) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be between 1 and " + strconv.Itoa(int(telemetryRetention/time.Hour))})
+            return
+        }
+        hours = n
+    }
+    since := time.Now().Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour).Unix()
+
+    totals := make(map[string]*outcomeStats)
+    browsers := make(map[string]map[string]int64)
+    var hourly []gin.H
+
+    a.analytics.mu.Lock()
+    for hour, bucket := range a.analytics.buckets {
+        if hour < since {
+            continue
+        }
+        counts := gin.H{"hour": hour}
+        for outcome, stats := range bucket.byOutcome {
+            counts[outcome] = stats.count
+            total := totals[outcome]
+            if total == nil {
+                total = &outcomeStats{setupHist: make([]int64, len(setupBucketsMs))}
+                totals[outcome] = total
+            }
+            total.count += stats.count
+            total.setupTotalMs += stats.setupTotalMs
+            total.relayedBytes += stats.relayedBytes
+            for i, n := range stats.setupHist {
+                total.setupHist[i] += n
+            }
+        }
+        hourly = append(hourly, counts)
+        for browser, outcomes := range bucket.byBrowser {
+            if browsers[browser] == nil {
+                browsers[browser] = make(map[string]int64)
+            }
+            for outcome, n := range outcomes {
+                browsers[browser][outcome] += n
+            }
+        }
+    }
+    a.analytics.mu.Unlock()
+
+    sort.Slice(hourly, func(i, j int) bool { return hourly[i]["hour"].(int64) < hourly[j]["hour"].(int64) })
+
+    var connections, relayed, connected int64
+    outcomes := gin.H{}
+    for outcome, stats := range totals {
+        connections += stats.count
+        if outcome != "failed" {
+            connected += stats.count
+        }
+        if outcome == "relay" {
+            relayed = stats.count
+        }
+        outcomes[outcome] = gin.H{
+            "count":        stats.count,
+            "avgSetupMs":   stats.setupTotalMs / stats.count,
+            "p50SetupMs":   histogramPercentile(stats.setupHist, stats.count, 0.50),
+            "p90SetupMs":   histogramPercentile(stats.setupHist, stats.count, 0.90),
+            "relayedBytes": stats.relayedBytes,
+        }
+    }
+
+    resp := gin.H{
+        "hours":       hours,
+        "connections": connections,
+        "outcomes":    outcomes,
+        "browsers":    browsers,
+        "hourly":      hourly,
+    }
+    if connected > 0 {
+        // TURN cost scales with relayed connections, not failed attempts
+        resp["relayShare"] = float64(relayed) / float64(connected)
+    }
+    if connections > 0 {
+        var failed int64
+        if stats := totals["failed"]; stats != nil {
+            failed = stats.count
+        }
+        resp["failureRate"] = float64(failed) / float64(connections)
+    }
+    c.JSON(http.StatusOK, resp)
+}
+
+// histogramPercentile returns the upper bound of the setup-time bucket
+// holding the given percentile
+func histogramPercentile(hist []int64, count int64, p float64) int64 {
+    target := int64(float64(count)*p + 0.5)
+    if target < 1 {
+        target = 1
+    }
+    var seen int64
+    for i, n := range hist {
+        seen += n
+        if seen >= target {
+            return setupBucketsMs[i]
+        }
+    }
+    return setupBucketsMs[len(setupBucketsMs)-1]
+}
+
+// knownBrowsers bounds the browser breakdown; anything else counts as other
+var knownBrowsers = map[string]bool{
+    "chrome":  true,
+    "firefox": true,
+    "safari":  true,
+    "edge":    true,
+    "opera":   true,
+    "samsung": true,
+    "other":   true,
+}
+
+// normalizeBrowser maps a client-reported browser name onto knownBrowsers,
+// or returns "" if none was reported
+func normalizeBrowser(name string) string {
+    name = strings.ToLower(strings.TrimSpace(name))
+    if name == "" || knownBrowsers[name] {
+        return name
+    }
+    return "other"
+}
+
+// browserFromUserAgent makes a coarse guess at the browser family
+func browserFromUserAgent(ua string) string {
+    switch {
+    case strings.Contains(ua, "Edg/"):
+        return "edge"
+    case strings.Contains(ua, "Firefox/"):
+        return "firefox"
+    case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "Chromium/"):
+        return "chrome"
+    case strings.Contains(ua, "Safari/"):
+        return "safari"
+    default:
+        return "other"
+    }
+}
@@ -0,0 +1,260 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    // maxTemplatesPerPeer bounds how many templates one peer can save
+    maxTemplatesPerPeer = 20
+    // maxTemplateNameLength bounds a template name, in characters
+    maxTemplateNameLength = 64
+)
+
+// roomTemplate is a template saved under a name by a peer
+type roomTemplate struct {
+    Name string `json:"name"`
+    rooms.Template
+    UpdatedAt int64 `json:"updatedAt"`
+}
+
+// templateLibrary holds each peer's saved templates, keyed by name
+type templateLibrary struct {
+    mu    sync.Mutex
+    peers map[string]map[string]roomTemplate
+}
+
+// lookupTemplate returns the template peerID saved as name
+func (a *API) lookupTemplate(peerID, name string) (rooms.Template, bool) {
+    a.templates.mu.Lock()
+    defer a.templates.mu.Unlock()
+    t, ok := a.templates.peers[peerID][name]
+    return t.Template, ok
+}
+
+// eraseTemplates forgets peerID's templates and returns how many it had
+func (a *API) eraseTemplates(peerID string) int {
+    a.templates.mu.Lock()
+    defer a.templates.mu.Unlock()
+    n := len(a.templates.peers[peerID])
+    delete(a.templates.peers, peerID)
+    return n
+}
+
+// getTemplates lists the bearer-token peer's saved templates by name
+func (a *API) getTemplates(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    tagRequest(c, "", peerID)
+
+    a.templates.mu.Lock()
+    list := make([]roomTemplate, 0, len(a.templates.peers[peerID]))
+    for _, t := range a.templates.peers[peerID] {
+        list = append(list, t)
+    }
+    a.templates.mu.Unlock()
+    sort.Slice(list, func(i, j int) bool {
+        return list[i].Name < list[j].Name
+    })
+
+    c.JSON(http.StatusOK, gin.H{"templates": list})
+}
+
+// saveTemplate saves or replaces a named template for the bearer-token
+// peer, either from the settings in the body or, with "fromRoom", from a
+// room the peer hosts
+func (a *API) saveTemplate(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    name := c.Param("name")
+    if len([]rune(name)) > maxTemplateNameLength {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "name must be at most " + strconv.Itoa(maxTemplateNameLength) + " characters"})
+        return
+    }
+
+    var req struct {
+        FromRoom string `json:"fromRoom"`
+        rooms.Template
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, req.FromRoom, peerID)
+
+    tmpl := req.Template
+    if req.FromRoom != "" {
+        room, exists := a.rooms.Get(req.FromRoom)
+        if !exists {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+            return
+        }
+        room.RLock()
+        isHost := room.HostID == peerID
+        tmpl = room.Template()
+        room.RUnlock()
+        if !isHost {
+            c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can save a room as a template"})
+            return
+        }
+    }
+    if err := tmpl.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    saved := roomTemplate{Name: name, Template: tmpl, UpdatedAt: time.Now().Unix()}
+    a.templates.mu.Lock()
+    library := a.templates.peers[peerID]
+    if _, replacing := library[name]; !replacing && len(library) >= maxTemplatesPerPeer {
+        a.templates.mu.Unlock()
+        c.JSON(http.StatusConflict, gin.H{"error": "At most " + strconv.Itoa(maxTemplatesPerPeer) + " templates can be saved"})
+        return
+    }
+    if library == nil {
+        library = make(map[string]roomTemplate)
+        a.templates.peers[peerID] = library
+    }
+    library[name] = saved
+    a.templates.mu.Unlock()
+
+    log.Printf("🧩 Template saved: %q by %s", name, peerID)
+    c.JSON(http.StatusOK, saved)
+}
+
+// deleteTemplate removes one of the bearer-token peer's templates
+func (a *API) deleteTemplate(c *gin.Context) {
+    peerID, ok := authenticatedPeer(c)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid peer token is required"})
+        return
+    }
+    tagRequest(c, "", peerID)
+    name := c.Param("name")
+
+    a.templates.mu.Lock()
+    _, exists := a.templates.peers[peerID][name]
+    delete(a.templates.peers[peerID], name)
+    if len(a.templates.peers[peerID]) == 0 {
+        delete(a.templates.peers, peerID)
+    }
+    a.templates.mu.Unlock()
+
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"name": name, "deleted": true})
+}
+
+// cloneRoom creates a new room with the source room's settings, with the
+// source's host as its host and first peer. Peers, files and any schedule
+// are not copied.
+func (a *API) cloneRoom(c *gin.Context) {
+    sourceCode := c.Param("roomCode")
+
+    var req struct {
+        HostID   string `json:"hostId" binding:"required"`
+        RoomCode string `json:"roomCode" binding:"required,roomcode"`
+        rooms.PeerProfile
+
+        CaptchaToken string `json:"captchaToken"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, req.RoomCode, req.HostID)
+
+    if err := req.PeerProfile.Validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    source, exists := a.rooms.Get(sourceCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+    source.RLock()
+    isHost := source.HostID == req.HostID
+    tmpl := source.Template()
+    source.RUnlock()
+    if !isHost {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can clone the room"})
+        return
+    }
+
+    if captchaEnabled() {
+        if err := verifyCaptcha(c.Request.Context(), req.CaptchaToken, c.ClientIP()); err != nil {
+            log.Printf("🤖 Captcha verification failed for room %s: %v", req.RoomCode, err)
+            c.JSON(http.StatusForbidden, gin.H{"error": "Captcha verification failed"})
+            return
+        }
+    }
+
+    if !a.checkRoomCreate(c, req.RoomCode, req.HostID, tmpl.Mode) {
+        return
+    }
+
+    room, created, err := a.rooms.Create(req.RoomCode, func(census rooms.Census) (*rooms.Room, error) {
+        if a.draining() {
+            return nil, drainRefusal
+        }
+        if qerr := a.checkRoomQuotas(census, c.ClientIP()); qerr != nil {
+            return nil, qerr
+        }
+        room := rooms.New(req.HostID, tmpl.Mode, c.ClientIP(), time.Now().Unix())
+        tmpl.Apply(room)
+        room.Tenant = tenantOf(c)
+        return room, nil
+    })
+    if err != nil {
+        qerr := err.(*quotaError)
+        if qerr.retry {
+            c.Header("Retry-After", strconv.Itoa(int(a.quotas.retryAfter.Seconds())))
+        }
+        c.JSON(qerr.status, gin.H{"error": qerr.message})
+        return
+    }
+    if !created {
+        c.JSON(http.StatusConflict, gin.H{"error": "Room code is already in use"})
+        return
+    }
+
+    room.Lock()
+    role, permissions := admitPeer(room, req.HostID, req.PeerProfile)
+    room.Unlock()
+
+    log.Printf("🧬 Room cloned: %s → %s, peer: %s", sourceCode, req.RoomCode, req.HostID)
+    a.recordRecentRoom(req.HostID, req.RoomCode, role)
+    a.recordAudit(req.RoomCode, "room_created", req.HostID, "", gin.H{"mode": tmpl.Mode, "clonedFrom": sourceCode})
+
+    c.JSON(http.StatusCreated, gin.H{
+        "roomCode":    req.RoomCode,
+        "clonedFrom":  sourceCode,
+        "settings":    tmpl,
+        "peers":       []string{},
+        "roomSize":    1,
+        "role":        role,
+        "permissions": permissions,
+        "resumeToken": a.issueResumeToken(req.RoomCode, req.HostID),
+    })
+}
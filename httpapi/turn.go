@@ -0,0 +1,87 @@
+package httpapi
+
+import (
+    "errors"
+    "log"
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/turn"
+)
+
+// getTurnCredentials issues ICE server credentials. With ?roomCode= they
+// count towards that room's insights.
+func (a *API) getTurnCredentials(c *gin.Context) {
+    if a.turn == nil {
+        log.Printf("❌ Missing Twilio credentials")
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error":   "Twilio credentials not configured",
+            "message": "Set TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN environment variables",
+        })
+        return
+    }
+
+    var creds *turn.Credentials
+    var err error
+    if regional, ok := a.turn.(turn.Regional); ok {
+        region, status, errMsg := a.turnRegion(c, regional.Regions())
+        if errMsg != "" {
+            c.JSON(status, gin.H{"error": errMsg})
+            return
+        }
+        if region != "" {
+            creds, err = regional.RegionalCredentials(c.Request.Context(), region)
+        } else {
+            creds, err = a.turn.Credentials(c.Request.Context())
+        }
+    } else {
+        creds, err = a.turn.Credentials(c.Request.Context())
+    }
+    if err != nil {
+        c.Error(err)
+        var apiErr *turn.APIError
+        if errors.As(err, &apiErr) {
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "error":   apiErr.Error(),
+                "details": apiErr.Body,
+            })
+            return
+        }
+        log.Printf("❌ Error fetching TURN credentials: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{
+            "error":   "Failed to fetch TURN credentials",
+            "message": err.Error(),
+        })
+        return
+    }
+
+    log.Printf("✅ TURN credentials fetched successfully")
+    a.overview.count(overviewTurnFetches)
+    a.usage.addTurnCredentials(tenantOf(c))
+    if roomCode := c.Query("roomCode"); roomCode != "" {
+        // Query parameters are not namespaced by namespaceRooms
+        a.recordRoomTurn(namespacedCode(roomNamespace(c.GetHeader("Origin")), roomCode))
+    }
+    c.JSON(http.StatusOK, creds)
+}
+
+// turnRegion picks the relay region for a client: ?region= if given,
+// otherwise the region nearest the client's location, or "" to let the
+// provider route by itself
+func (a *API) turnRegion(c *gin.Context, regions []turn.Region) (string, int, string) {
+    if requested := c.Query("region"); requested != "" {
+        for _, r := range regions {
+            if r.Name == requested {
+                return requested, http.StatusOK, ""
+            }
+        }
+        return "", http.StatusBadRequest, "Unknown region " + requested
+    }
+
+    lat, lon, ok := a.geo.locate(c)
+    if !ok {
+        return "", http.StatusOK, ""
+    }
+    return turn.Nearest(regions, lat, lon), http.StatusOK, ""
+}
@@ -0,0 +1,302 @@
+package httpapi
+
+import (
+    "bytes"
+    "context"
+    "encoding/csv"
+    "fmt"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/parquet"
+    "p2p-file-share-backend/relay"
+    "p2p-file-share-backend/rooms"
+)
+
+const (
+    // usageSampleEvery is how often open rooms are metered
+    usageSampleEvery           = time.Minute
+    defaultUsageExportInterval = time.Hour
+    usageExportTimeout         = time.Minute
+)
+
+// tenantUsage is what one tenant used in the current period
+type tenantUsage struct {
+    roomSeconds     float64
+    relayedBytes    int64
+    turnCredentials int64
+}
+
+// usageMeter accumulates per-tenant usage since the last export
+type usageMeter struct {
+    mu      sync.Mutex
+    since   time.Time
+    sampled time.Time
+    tenants map[string]*tenantUsage
+}
+
+// tenant returns the usage of tenant, creating it. The caller must hold
+// m.mu.
+func (m *usageMeter) tenant(tenant string) *tenantUsage {
+    u := m.tenants[tenant]
+    if u == nil {
+        u = &tenantUsage{}
+        m.tenants[tenant] = u
+    }
+    return u
+}
+
+func (m *usageMeter) addRelayed(tenant string, n int64) {
+    m.mu.Lock()
+    m.tenant(tenant).relayedBytes += n
+    m.mu.Unlock()
+}
+
+func (m *usageMeter) addTurnCredentials(tenant string) {
+    m.mu.Lock()
+    m.tenant(tenant).turnCredentials++
+    m.mu.Unlock()
+}
+
+// meterRooms charges each tenant for its open rooms since the last sample
+func (a *API) meterRooms() {
+    open := make(map[string]int)
+    a.rooms.Range(func(_ string, room *rooms.Room) bool {
+        room.RLock()
+        open[room.Tenant]++
+        room.RUnlock()
+        return true
+    })
+
+    now := time.Now()
+    a.usage.mu.Lock()
+    elapsed := now.Sub(a.usage.sampled).Seconds()
+    a.usage.sampled = now
+    for tenant, n := range open {
+        a.usage.tenant(tenant).roomSeconds += float64(n) * elapsed
+    }
+    a.usage.mu.Unlock()
+}
+
+// usageRecord is one tenant's row in an export
+type usageRecord struct {
+    Tenant          string  `json:"tenant"`
+    RoomHours       float64 `json:"roomHours"`
+    RelayedBytes    int64   `json:"relayedBytes"`
+    TurnCredentials int64   `json:"turnCredentials"`
+}
+
+// usageRecords returns the tenants' usage sorted by tenant. The caller must
+// hold m.mu.
+func (m *usageMeter) usageRecords() []usageRecord {
+    records := make([]usageRecord, 0, len(m.tenants))
+    for tenant, u := range m.tenants {
+        records = append(records, usageRecord{
+            Tenant:          tenant,
+            RoomHours:       u.roomSeconds / 3600,
+            RelayedBytes:    u.relayedBytes,
+            TurnCredentials: u.turnCredentials,
+        })
+    }
+    sort.Slice(records, func(i, j int) bool { return records[i].Tenant < records[j].Tenant })
+    return records
+}
+
+// usageExporter writes each period's usage somewhere as CSV or Parquet
+type usageExporter struct {
+    format   string
+    interval time.Duration
+    // dir is a local directory; store an object store bucket, when dir is
+    // empty
+    dir   string
+    store relay.Store
+    host  string
+}
+
+// loadUsageExporter reads USAGE_EXPORT_DEST, a local directory or an object
+// store URL (s3://, minio:// or gcs://bucket/prefix, using the S3_*
+// credentials), USAGE_EXPORT_FORMAT (csv or parquet) and
+// USAGE_EXPORT_INTERVAL. It returns nil when exports are off.
+func loadUsageExporter() *usageExporter {
+    dest := os.Getenv("USAGE_EXPORT_DEST")
+    if dest == "" {
+        return nil
+    }
+    e := &usageExporter{
+        format:   os.Getenv("USAGE_EXPORT_FORMAT"),
+        interval: envDuration("USAGE_EXPORT_INTERVAL", defaultUsageExportInterval),
+    }
+    switch e.format {
+    case "":
+        e.format = "csv"
+    case "csv", "parquet":
+    default:
+        log.Fatalf("❌ USAGE_EXPORT_FORMAT must be csv or parquet, not %q", e.format)
+    }
+    e.host, _ = os.Hostname()
+    if e.host == "" {
+        e.host = "server"
+    }
+
+    if flavor, rest, ok := strings.Cut(dest, "://"); ok {
+        if flavor != "s3" && flavor != "minio" && flavor != "gcs" {
+            log.Fatalf("❌ USAGE_EXPORT_DEST must be a directory or an s3://, minio:// or gcs:// URL")
+        }
+        cfg := relay.S3ConfigFromEnv(flavor)
+        cfg.Bucket, cfg.Prefix, _ = strings.Cut(rest, "/")
+        if cfg.Prefix != "" && !strings.HasSuffix(cfg.Prefix, "/") {
+            cfg.Prefix += "/"
+        }
+        store, err := relay.NewS3(cfg)
+        if err != nil {
+            log.Fatalf("❌ Invalid USAGE_EXPORT_DEST: %v", err)
+        }
+        e.store = store
+    } else {
+        if err := os.MkdirAll(dest, 0o755); err != nil {
+            log.Fatalf("❌ Failed to create usage export directory: %v", err)
+        }
+        e.dir = dest
+    }
+    log.Printf("🧾 Exporting tenant usage as %s to %s every %s", e.format, dest, e.interval)
+    return e
+}
+
+// exportUsage meters open rooms, then writes the period's usage and starts
+// a new period. If the write fails the usage is kept, and goes out with the
+// next period's.
+func (a *API) exportUsage(ctx context.Context) error {
+    a.meterRooms()
+    e := a.usageExport
+    a.usage.mu.Lock()
+    start, end := a.usage.since, a.usage.sampled
+    records := a.usage.usageRecords()
+    a.usage.mu.Unlock()
+
+    data, err := encodeUsage(e.format, start, end, records)
+    if err == nil {
+        name := fmt.Sprintf("usage-%s-%s-%s.%s", e.host, start.UTC().Format("20060102T150405Z"), end.UTC().Format("20060102T150405Z"), e.format)
+        ctx, cancel := context.WithTimeout(ctx, usageExportTimeout)
+        err = e.write(ctx, name, data)
+        cancel()
+    }
+    if err != nil {
+        return fmt.Errorf("usage kept for the next export: %w", err)
+    }
+
+    // Usage metered while writing stays in the new period
+    a.usage.mu.Lock()
+    for _, r := range records {
+        u := a.usage.tenants[r.Tenant]
+        u.roomSeconds -= r.RoomHours * 3600
+        u.relayedBytes -= r.RelayedBytes
+        u.turnCredentials -= r.TurnCredentials
+        if u.roomSeconds < 1e-6 && u.relayedBytes == 0 && u.turnCredentials == 0 {
+            delete(a.usage.tenants, r.Tenant)
+        }
+    }
+    a.usage.since = end
+    a.usage.mu.Unlock()
+    log.Printf("🧾 Exported usage of %d tenants", len(records))
+    return nil
+}
+
+func (e *usageExporter) write(ctx context.Context, name string, data []byte) error {
+    if e.store != nil {
+        _, err := e.store.Put(ctx, name, bytes.NewReader(data))
+        return err
+    }
+    tmp, err := os.CreateTemp(e.dir, ".usage-*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    _, err = tmp.Write(data)
+    if cerr := tmp.Close(); err == nil {
+        err = cerr
+    }
+    if err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), filepath.Join(e.dir, name))
+}
+
+// usageColumns are the columns of an export, in order
+var usageColumns = []parquet.Column{
+    {Name: "period_start", Type: parquet.Int64},
+    {Name: "period_end", Type: parquet.Int64},
+    {Name: "tenant", Type: parquet.String},
+    {Name: "room_hours", Type: parquet.Double},
+    {Name: "relayed_bytes", Type: parquet.Int64},
+    {Name: "turn_credentials", Type: parquet.Int64},
+}
+
+// encodeUsage renders an export; periods are Unix seconds
+func encodeUsage(format string, start, end time.Time, records []usageRecord) ([]byte, error) {
+    var buf bytes.Buffer
+    if format == "parquet" {
+        rows := make([][]any, len(records))
+        for i, r := range records {
+            rows[i] = []any{start.Unix(), end.Unix(), r.Tenant, r.RoomHours, r.RelayedBytes, r.TurnCredentials}
+        }
+        err := parquet.Write(&buf, usageColumns, rows)
+        return buf.Bytes(), err
+    }
+
+    w := csv.NewWriter(&buf)
+    header := make([]string, len(usageColumns))
+    for i, col := range usageColumns {
+        header[i] = col.Name
+    }
+    w.Write(header)
+    for _, r := range records {
+        w.Write([]string{
+            strconv.FormatInt(start.Unix(), 10),
+            strconv.FormatInt(end.Unix(), 10),
+            csvText(r.Tenant),
+            strconv.FormatFloat(r.RoomHours, 'f', 4, 64),
+            strconv.FormatInt(r.RelayedBytes, 10),
+            strconv.FormatInt(r.TurnCredentials, 10),
+        })
+    }
+    w.Flush()
+    return buf.Bytes(), w.Error()
+}
+
+// csvText quotes a text cell so spreadsheets opening the export take it as
+// text: a tenant named like "=HYPERLINK(...)" would otherwise run as a
+// formula
+func csvText(s string) string {
+    if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+        return "'" + s
+    }
+    return s
+}
+
+// getUsage shows each tenant's usage in the period not yet exported
+func (a *API) getUsage(c *gin.Context) {
+    a.meterRooms()
+    a.usage.mu.Lock()
+    start := a.usage.since
+    records := a.usage.usageRecords()
+    a.usage.mu.Unlock()
+
+    export := gin.H{"enabled": false}
+    if e := a.usageExport; e != nil {
+        export = gin.H{"enabled": true, "format": e.format, "intervalSeconds": int64(e.interval.Seconds())}
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "periodStart": start.Unix(),
+        "tenants":     records,
+        "export":      export,
+    })
+}
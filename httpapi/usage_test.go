@@ -0,0 +1,37 @@
+package httpapi
+
+import (
+    "bytes"
+    "encoding/csv"
+    "testing"
+    "time"
+)
+
+func TestEncodeUsageCSVFormulas(t *testing.T) {
+    tenants := map[string]string{
+        "acme":              "acme",
+        "=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+        "+1":                "'+1",
+        "-1":                "'-1",
+        "@SUM(A1)":          "'@SUM(A1)",
+        "a=b":               "a=b",
+    }
+    var records []usageRecord
+    for tenant := range tenants {
+        records = append(records, usageRecord{Tenant: tenant})
+    }
+
+    data, err := encodeUsage("csv", time.Unix(0, 0), time.Unix(3600, 0), records)
+    if err != nil {
+        t.Fatal(err)
+    }
+    rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for i, r := range records {
+        if got, want := rows[i+1][2], tenants[r.Tenant]; got != want {
+            t.Errorf("tenant %q exported as %q, want %q", r.Tenant, got, want)
+        }
+    }
+}
@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "reflect"
+    "regexp"
+    "strings"
+    "sync"
+
+    "github.com/gin-gonic/gin"
+    "github.com/gin-gonic/gin/binding"
+    "github.com/go-playground/validator/v10"
+)
+
+// roomCodePattern is what a client-chosen room code may look like, once
+// namespaceRooms has put it in the client's namespace
+var roomCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}(~[0-9a-f]{12})?$`)
+
+var registerOnce sync.Once
+
+// registerValidations makes binding errors name fields by their JSON names
+// and adds the "roomcode" rule
+func registerValidations() {
+    registerOnce.Do(func() {
+        v, ok := binding.Validator.Engine().(*validator.Validate)
+        if !ok {
+            return
+        }
+        v.RegisterTagNameFunc(func(field reflect.StructField) string {
+            name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+            if name == "-" {
+                return ""
+            }
+            return name
+        })
+        v.RegisterValidation("roomcode", func(fl validator.FieldLevel) bool {
+            return roomCodePattern.MatchString(fl.Field().String())
+        })
+    })
+}
+
+// fieldError is one invalid field of a request body
+type fieldError struct {
+    Field   string `json:"field"`
+    Rule    string `json:"rule"`
+    Message string `json:"message"`
+}
+
+// invalidRequest responds 400 to a request body that failed to bind. When
+// particular fields are at fault they are listed under "fields", each with
+// the rule it broke, so a form can point at them; "error" is the first
+// one's message.
+func invalidRequest(c *gin.Context, err error) {
+    fields := bindingFieldErrors(err)
+    if len(fields) == 0 {
+        message := "Request body is not valid JSON"
+        if errors.Is(err, io.EOF) {
+            message = "Request body is required"
+        }
+        c.JSON(http.StatusBadRequest, gin.H{"error": message})
+        return
+    }
+    c.JSON(http.StatusBadRequest, gin.H{
+        "error":  fields[0].Message,
+        "code":   CodeValidationFailed,
+        "fields": fields,
+    })
+}
+
+// bindingFieldErrors lists the fields a binding error blames, if any
+func bindingFieldErrors(err error) []fieldError {
+    var typeErr *json.UnmarshalTypeError
+    if errors.As(err, &typeErr) && typeErr.Field != "" {
+        return []fieldError{{
+            Field:   typeErr.Field,
+            Rule:    "type",
+            Message: typeErr.Field + " must be " + jsonKind(typeErr.Type),
+        }}
+    }
+
+    var invalid validator.ValidationErrors
+    if !errors.As(err, &invalid) {
+        return nil
+    }
+    fields := make([]fieldError, len(invalid))
+    for i, fe := range invalid {
+        fields[i] = fieldError{Field: jsonPath(fe), Rule: fe.Tag()}
+        fields[i].Message = ruleMessage(fields[i].Field, fe)
+    }
+    return fields
+}
+
+// jsonPath is a failed field's path by JSON names, e.g.
+// operations[0].roomCode. Leading segments named the same in Go and JSON
+// are the request's type name or embedded structs, which JSON doesn't see.
+func jsonPath(fe validator.FieldError) string {
+    path, goPath := fe.Namespace(), fe.StructNamespace()
+    for {
+        head, rest, ok := strings.Cut(path, ".")
+        goHead, goRest, _ := strings.Cut(goPath, ".")
+        if !ok || head != goHead {
+            return path
+        }
+        path, goPath = rest, goRest
+    }
+}
+
+func ruleMessage(field string, fe validator.FieldError) string {
+    switch fe.Tag() {
+    case "required":
+        return field + " is required"
+    case "roomcode":
+        return field + " must be 1-64 letters, digits, '-' or '_'"
+    case "oneof":
+        return field + " must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+    case "min", "gte":
+        return field + " must be at least " + fe.Param()
+    case "max", "lte":
+        return field + " must be at most " + fe.Param()
+    }
+    return field + " is invalid (" + fe.Tag() + ")"
+}
+
+// jsonKind describes the JSON a Go type decodes from
+func jsonKind(t reflect.Type) string {
+    switch t.Kind() {
+    case reflect.String:
+        return "a string"
+    case reflect.Bool:
+        return "true or false"
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return "an integer"
+    case reflect.Float32, reflect.Float64:
+        return "a number"
+    case reflect.Slice, reflect.Array:
+        return "an array"
+    case reflect.Map, reflect.Struct:
+        return "an object"
+    }
+    return "a " + t.String()
+}
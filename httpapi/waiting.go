@@ -0,0 +1,208 @@
+package httpapi
+
+import (
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// pendingJoinTTL is how long a join request waits for the host before it is
+// dropped
+const pendingJoinTTL = 10 * time.Minute
+
+// needsApproval reports whether a join must wait for the host: the room has
+// a waiting room and the peer is neither the host nor already a member. The
+// caller must hold the room lock.
+func needsApproval(room *rooms.Room, peerID string) bool {
+    if !room.WaitingRoom || peerID == room.HostID {
+        return false
+    }
+    _, member := room.Peers[peerID]
+    return !member
+}
+
+// joinRequested tells the host about a new join request and builds the
+// 202 response sent to the waiting peer, which then polls its notifications
+// for join_approved or join_denied
+func (a *API) joinRequested(roomCode, hostID, peerID string, profile rooms.PeerProfile, created, ok bool) (gin.H, int, string) {
+    if !ok {
+        return nil, http.StatusTooManyRequests, "Too many peers are waiting to join this room"
+    }
+    if created {
+        a.queueNotification(hostID, notifications.Notification{
+            Type:      "join_request",
+            PeerID:    peerID,
+            Timestamp: time.Now().Unix(),
+            Payload:   notifications.Payload(profile),
+        })
+        log.Printf("🚪 Join request: %s → Room: %s", peerID, roomCode)
+        a.recordAudit(roomCode, "join_requested", peerID, "", nil)
+    }
+
+    return gin.H{
+        "pending":  true,
+        "roomCode": roomCode,
+    }, http.StatusAccepted, ""
+}
+
+// withdrawJoinRequest drops a peer's pending request, reporting whether it
+// had one
+func (a *API) withdrawJoinRequest(roomCode, peerID string) bool {
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        return false
+    }
+
+    room.Lock()
+    _, withdrawn := room.TakePending(peerID)
+    hostID := room.HostID
+    room.Unlock()
+    if !withdrawn {
+        return false
+    }
+
+    a.queueNotification(hostID, notifications.Notification{
+        Type:      "join_request_withdrawn",
+        PeerID:    peerID,
+        Timestamp: time.Now().Unix(),
+    })
+    log.Printf("🚪 Join request withdrawn: %s from Room: %s", peerID, roomCode)
+    a.recordAudit(roomCode, "join_withdrawn", peerID, "", nil)
+    return true
+}
+
+// getPendingPeers lists the peers waiting for the host's approval
+func (a *API) getPendingPeers(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+    hostID := c.Query("hostId")
+    tagRequest(c, roomCode, hostID)
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.RLock()
+    if hostID == "" || hostID != room.HostID {
+        room.RUnlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can see the waiting room"})
+        return
+    }
+    pending := room.PendingPeers()
+    room.RUnlock()
+
+    c.JSON(http.StatusOK, gin.H{
+        "roomCode": roomCode,
+        "pending":  pending,
+    })
+}
+
+// approveJoin lets the host admit or turn away a peer in the waiting room.
+// An approved peer joins with the room's default role and permissions and
+// receives what a join would have returned in its join_approved
+// notification.
+func (a *API) approveJoin(c *gin.Context) {
+    roomCode := c.Param("roomCode")
+
+    var req struct {
+        HostID  string `json:"hostId" binding:"required"`
+        PeerID  string `json:"peerId" binding:"required"`
+        Approve *bool  `json:"approve" binding:"required"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        invalidRequest(c, err)
+        return
+    }
+    tagRequest(c, roomCode, req.HostID)
+
+    room, exists := a.rooms.Get(roomCode)
+    if !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+        return
+    }
+
+    room.Lock()
+    if req.HostID != room.HostID {
+        room.Unlock()
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can approve joins"})
+        return
+    }
+    pending, ok := room.TakePending(req.PeerID)
+    if !ok {
+        room.Unlock()
+        c.JSON(http.StatusNotFound, gin.H{"error": "No pending join request from that peer"})
+        return
+    }
+
+    if !*req.Approve {
+        room.Unlock()
+        a.queueNotification(req.PeerID, notifications.Notification{
+            Type:      "join_denied",
+            PeerID:    req.HostID,
+            Timestamp: time.Now().Unix(),
+            Payload:   notifications.Payload(gin.H{"roomCode": roomCode}),
+        })
+        log.Printf("⛔ Join denied: %s → Room: %s", req.PeerID, roomCode)
+        a.recordAudit(roomCode, "join_denied", req.HostID, req.PeerID, nil)
+        c.JSON(http.StatusOK, gin.H{"peerId": req.PeerID, "approved": false})
+        return
+    }
+
+    existingPeers := room.ConnectedPeers(req.PeerID)
+    role, permissions := admitPeer(room, req.PeerID, pending.PeerProfile)
+    roomSize := len(room.Peers)
+    joined := roomEvent(room, req.PeerID, notifications.Notification{
+        Type:      "peer_joined",
+        PeerID:    req.PeerID,
+        Timestamp: time.Now().Unix(),
+    })
+    room.Unlock()
+
+    a.dispatcher.Fanout(existingPeers, joined)
+    a.queueNotification(req.PeerID, notifications.Notification{
+        Type:      "join_approved",
+        PeerID:    req.HostID,
+        Timestamp: time.Now().Unix(),
+        Payload: notifications.Payload(gin.H{
+            "roomCode":    roomCode,
+            "peers":       existingPeers,
+            "roomSize":    roomSize,
+            "role":        role,
+            "permissions": permissions,
+            "roomSeq":     joined.RoomSeq,
+            "resumeToken": a.issueResumeToken(roomCode, req.PeerID),
+        }),
+    })
+
+    log.Printf("✅ Peer joined: %s → Room: %s (approved)", req.PeerID, roomCode)
+    a.recordRecentRoom(req.PeerID, roomCode, role)
+    a.recordAudit(roomCode, "peer_joined", req.PeerID, "", gin.H{"approvedBy": req.HostID})
+
+    c.JSON(http.StatusOK, gin.H{
+        "peerId":   req.PeerID,
+        "approved": true,
+        "roomSize": roomSize,
+    })
+}
+
+// eraseJoinRequests drops peerID's pending requests from every waiting room,
+// returning how many there were
+func (a *API) eraseJoinRequests(peerID string) int {
+    removed := 0
+    a.rooms.Range(func(_ string, room *rooms.Room) bool {
+        room.Lock()
+        if _, ok := room.TakePending(peerID); ok {
+            removed++
+        }
+        room.Unlock()
+        return true
+    })
+    return removed
+}
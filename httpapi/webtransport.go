@@ -0,0 +1,414 @@
+package httpapi
+
+import (
+    "context"
+    "crypto/tls"
+    "io"
+    "log"
+    "net"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/quic-go/quic-go/http3"
+    "github.com/quic-go/webtransport-go"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+)
+
+// WebTransportPath is where browsers open WebTransport sessions. It is served
+// on the HTTP/3 listener only, since WebTransport runs over QUIC.
+const WebTransportPath = "/wt"
+
+// wtSlowConsumer is the session error code sent to a client evicted for not
+// reading its events; it should reconnect and refetch room state
+const wtSlowConsumer webtransport.SessionErrorCode = 1
+
+// wtSuspended is the session error code sent to a client whose peer, IP or
+// tenant an operator suspended
+const wtSuspended webtransport.SessionErrorCode = 2
+
+// NewWebTransportServer returns the HTTP/3 server for addr. It doubles as
+// the WebTransport endpoint and serves every other request with next.
+func (a *API) NewWebTransportServer(addr string, tlsConfig *tls.Config, next http.Handler) *webtransport.Server {
+    a.wt = &webtransport.Server{
+        H3: http3.Server{
+            Addr:      addr,
+            Handler:   a.withWebTransport(next),
+            TLSConfig: http3.ConfigureTLSConfig(tlsConfig),
+            // Idle timeout, keep-alive pings and stream caps
+            QUICConfig: a.wtLimits.quicConfig(),
+        },
+        CheckOrigin: a.checkWebTransportOrigin,
+    }
+    return a.wt
+}
+
+// checkWebTransportOrigin applies the CORS origin list to WebTransport.
+// Native clients send no Origin header and are allowed.
+func (a *API) checkWebTransportOrigin(r *http.Request) bool {
+    origin := r.Header.Get("Origin")
+    if origin == "" {
+        return true
+    }
+    return a.originAllowed(origin)
+}
+
+// originAllowed reports whether a browser origin may call the API: one of
+// the configured origins, or in dev mode any localhost origin
+func (a *API) originAllowed(origin string) bool {
+    for _, allowed := range a.cfg.AllowedOrigins {
+        if origin == allowed {
+            return true
+        }
+    }
+    return a.cfg.Dev && isLocalOrigin(origin)
+}
+
+// withWebTransport routes WebTransport CONNECT requests to the session
+// handler and everything else to next. This sits outside Gin because the
+// upgrade needs the raw HTTP/3 response writer.
+func (a *API) withWebTransport(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == WebTransportPath && r.Method == http.MethodConnect {
+            a.handleWebTransport(w, r)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+// handleWebTransport opens an event/signaling session for a peer, which must
+// present its peer token: CONNECT /wt?peerId=...&token=...
+//
+// The server opens one unidirectional stream and writes the peer's
+// notifications to it as newline-delimited JSON as soon as they are queued.
+// A client that stops reading them is evicted: the session is closed and
+// its next connection starts with a resync_required event. A reconnecting
+// client can add &roomCode=...&since=<roomSeq> to have the room events it
+// missed replayed first; if they are no longer retained it gets
+// resync_required instead and should refetch the room.
+// The client relays messages by opening a bidirectional stream per message,
+// writing {"to", "roomCode", "payload"} and reading back {"success"} or
+// {"error"}, with the same limits and permissions as POST /messages.
+//
+// Events and messages are JSON unless the request's Accept (events) or
+// Content-Type (messages) header asks for application/x-protobuf, the
+// schema in notifications/events.proto.
+func (a *API) handleWebTransport(w http.ResponseWriter, r *http.Request) {
+    // QUIC terminates here, so the remote address is the client's own
+    ip, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        ip = r.RemoteAddr
+    }
+    if !a.ipPermitted(ip, "") {
+        http.Error(w, "Access denied", http.StatusForbidden)
+        return
+    }
+
+    peerID := r.URL.Query().Get("peerId")
+    if tokenPeer, ok := verifyPeerToken(r.URL.Query().Get("token")); !ok || tokenPeer != peerID {
+        http.Error(w, "Invalid peer token", http.StatusForbidden)
+        return
+    }
+    tenant := originTenant(r.Header.Get("Origin"))
+    if !a.flagEnabled(flagWebTransport, tenant, peerID) {
+        http.Error(w, "WebTransport is not enabled", http.StatusNotFound)
+        return
+    }
+    if _, suspended := a.suspendedActor(ip, tenant, peerID); suspended {
+        http.Error(w, "Suspended by an operator", http.StatusForbidden)
+        return
+    }
+    // Sessions are long-lived, so while draining new ones belong on
+    // another instance
+    if a.draining() {
+        w.Header().Set("Retry-After", strconv.Itoa(int(a.quotas.retryAfter.Seconds())))
+        http.Error(w, drainRefusal.message, drainRefusal.status)
+        return
+    }
+
+    events, ok := notifications.NegotiateEncoding(r.Header.Get("Accept"))
+    if !ok {
+        http.Error(w, "Supported event encodings: "+notifications.EncodingJSON.ContentType()+", "+notifications.EncodingProtobuf.ContentType(), http.StatusNotAcceptable)
+        return
+    }
+    messages, ok := notifications.NegotiateEncoding(r.Header.Get("Content-Type"))
+    if !ok {
+        http.Error(w, "Unsupported message encoding", http.StatusUnsupportedMediaType)
+        return
+    }
+    opts := wtOptions{
+        events:    events,
+        messages:  messages,
+        namespace: roomNamespace(r.Header.Get("Origin")),
+        ip:        ip,
+        tenant:    tenant,
+    }
+
+    if v := r.URL.Query().Get("since"); v != "" {
+        since, err := strconv.ParseUint(v, 10, 64)
+        if err != nil {
+            http.Error(w, "since must be a room event sequence number", http.StatusBadRequest)
+            return
+        }
+        room, exists := a.rooms.Get(namespacedCode(opts.namespace, r.URL.Query().Get("roomCode")))
+        if !exists {
+            http.Error(w, "Room not found", http.StatusNotFound)
+            return
+        }
+        room.RLock()
+        _, member := room.Peers[peerID]
+        room.RUnlock()
+        if !member {
+            http.Error(w, "Peer not in room", http.StatusNotFound)
+            return
+        }
+        opts.replay = &roomReplay{room: room, since: since}
+    }
+
+    if !a.wtLimits.acquire(ip) {
+        http.Error(w, "Too many sessions from this address", http.StatusTooManyRequests)
+        return
+    }
+    defer a.wtLimits.release(ip)
+
+    w.Header().Set("Content-Type", events.ContentType())
+    session, err := a.wt.Upgrade(w, r)
+    if err != nil {
+        log.Printf("❌ WebTransport upgrade failed: %v", err)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+
+    open := a.wtSessions.add(peerID, ip, tenant, session.CloseWithError)
+    defer a.wtSessions.remove(open)
+    log.Printf("🛰️  WebTransport session opened: %s", peerID)
+    a.serveWebTransportSession(session, peerID, opts)
+    log.Printf("🛰️  WebTransport session closed: %s", peerID)
+}
+
+// wtOptions are what a session negotiated when it opened
+type wtOptions struct {
+    events   notifications.Encoding
+    messages notifications.Encoding
+    replay   *roomReplay
+    // namespace is the origin's room namespace, see namespaceRooms
+    namespace string
+    // ip and tenant are checked against suspensions on every message
+    ip     string
+    tenant string
+}
+
+// wtSession is an open WebTransport session and who it acts for
+type wtSession struct {
+    peerID string
+    ip     string
+    tenant string
+    close  func(code webtransport.SessionErrorCode, msg string) error
+}
+
+// wtSessionSet tracks the open WebTransport sessions, so a suspension can
+// close the ones it bars
+type wtSessionSet struct {
+    mu   sync.Mutex
+    open map[*wtSession]bool
+}
+
+func (s *wtSessionSet) add(peerID, ip, tenant string, close func(webtransport.SessionErrorCode, string) error) *wtSession {
+    session := &wtSession{peerID: peerID, ip: ip, tenant: tenant, close: close}
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.open[session] = true
+    return session
+}
+
+func (s *wtSessionSet) remove(session *wtSession) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.open, session)
+}
+
+// closeSuspendedSessions closes the WebTransport sessions of suspended
+// peers, IPs and tenants
+func (a *API) closeSuspendedSessions() {
+    a.wtSessions.mu.Lock()
+    var closing []*wtSession
+    for session := range a.wtSessions.open {
+        if _, suspended := a.suspendedActor(session.ip, session.tenant, session.peerID); suspended {
+            closing = append(closing, session)
+        }
+    }
+    a.wtSessions.mu.Unlock()
+
+    for _, session := range closing {
+        session.close(wtSuspended, "suspended by an operator")
+        log.Printf("⛔ WebTransport session closed: %s suspended", session.peerID)
+    }
+}
+
+// roomReplay is a reconnecting client's position in a room's events
+type roomReplay struct {
+    room  *rooms.Room
+    since uint64
+}
+
+func (a *API) serveWebTransportSession(session *webtransport.Session, peerID string, opts wtOptions) {
+    ctx := session.Context()
+    go a.acceptWebTransportMessages(ctx, session, peerID, opts, a.wtLimits.newBucket())
+
+    events, err := session.OpenUniStreamSync(ctx)
+    if err != nil {
+        return
+    }
+    defer events.Close()
+
+    sub := a.notifications.Subscribe(peerID)
+    defer a.notifications.Unsubscribe(peerID, sub)
+    // An evicted subscriber is usually stuck in a write, so closing the
+    // session is what unblocks it
+    go func() {
+        select {
+        case <-sub.Evicted:
+            session.CloseWithError(wtSlowConsumer, "slow consumer, resync required")
+        case <-ctx.Done():
+        }
+    }()
+
+    eventWriter := notifications.NewEventWriter(events, opts.events)
+    write := func(n notifications.Notification) error {
+        n.Payload = stripNamespace(n.Payload, opts.namespace)
+        return eventWriter.Write(n)
+    }
+    // Room events just replayed may also be waiting in the queue. Events go
+    // out one at a time on a single stream, so the urgent ones go first.
+    replayed := make(map[uint64]bool)
+    flush := func() error {
+        for _, n := range a.notifications.DrainPrioritized(peerID) {
+            if n.RoomSeq != 0 && replayed[n.RoomSeq] {
+                continue
+            }
+            if err := write(n); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    if replay := opts.replay; replay != nil {
+        missed, latest, ok := replayRoomEvents(replay.room, peerID, replay.since)
+        if !ok {
+            missed = []notifications.Notification{{
+                Type:      "resync_required",
+                Timestamp: time.Now().Unix(),
+                Payload:   notifications.Payload(gin.H{"reason": "history_expired", "roomSeq": latest}),
+            }}
+        }
+        for _, n := range missed {
+            if err := write(n); err != nil {
+                return
+            }
+            replayed[n.RoomSeq] = true
+        }
+    }
+    if err := flush(); err != nil {
+        return
+    }
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-sub.C:
+            if err := flush(); err != nil {
+                return
+            }
+        }
+    }
+}
+
+func (a *API) acceptWebTransportMessages(ctx context.Context, session *webtransport.Session, peerID string, opts wtOptions, bucket *messageBucket) {
+    for {
+        stream, err := session.AcceptStream(ctx)
+        if err != nil {
+            return
+        }
+        go a.handleWebTransportMessage(stream, peerID, opts, bucket)
+    }
+}
+
+func (a *API) handleWebTransportMessage(stream io.ReadWriteCloser, peerID string, opts wtOptions, bucket *messageBucket) {
+    defer stream.Close()
+    enc := opts.messages
+
+    reply := func(r notifications.RelayResult) {
+        notifications.WriteRelayResult(stream, enc, r)
+    }
+    if _, suspended := a.suspendedActor(opts.ip, opts.tenant, peerID); suspended {
+        reply(notifications.RelayResult{Error: "Suspended by an operator", Status: http.StatusForbidden})
+        return
+    }
+    if a.throttledActor(opts.ip, peerID) {
+        reply(notifications.RelayResult{Error: "Rate limit exceeded", Status: http.StatusTooManyRequests})
+        return
+    }
+    if message, refused := a.readOnlyRefusal("/messages"); refused {
+        reply(notifications.RelayResult{Error: message, Status: http.StatusServiceUnavailable})
+        return
+    }
+    if !bucket.allow() {
+        a.wtLimits.limitedMessages.Add(1)
+        reply(notifications.RelayResult{Error: "Rate limit exceeded", Status: http.StatusTooManyRequests})
+        return
+    }
+    var body io.Reader = stream
+    if a.wtLimits.maxMessage > 0 {
+        body = io.LimitReader(stream, a.wtLimits.maxMessage+1)
+    }
+    data, err := io.ReadAll(body)
+    if a.wtLimits.maxMessage > 0 && int64(len(data)) > a.wtLimits.maxMessage {
+        a.wtLimits.oversizeMessages.Add(1)
+        reply(notifications.RelayResult{Error: "Message too large", Status: http.StatusRequestEntityTooLarge})
+        return
+    }
+    if err != nil {
+        return
+    }
+    req, err := notifications.DecodeRelayMessage(data, enc)
+    if err != nil || req.To == "" || len(req.Payload) == 0 {
+        reply(notifications.RelayResult{Error: "Invalid message", Status: http.StatusBadRequest})
+        return
+    }
+
+    roomCode := namespacedCode(opts.namespace, req.RoomCode)
+    if status, msg := a.relayMessage(peerID, req.To, roomCode, req.Session, req.Seq, req.Payload); status != http.StatusOK {
+        reply(notifications.RelayResult{Error: msg, Status: status})
+        return
+    }
+    // The session's peer token proved peerID
+    a.anomalies.observe(signalFlood, suspendPeer, peerID)
+    reply(notifications.RelayResult{Success: true})
+}
+
+// getWebTransportStats reports the WebTransport session limits, the open
+// sessions and how often the limits have been hit
+func (a *API) getWebTransportStats(c *gin.Context) {
+    l := a.wtLimits
+    c.JSON(http.StatusOK, gin.H{
+        "enabled":  a.wt != nil,
+        "sessions": l.sessions(),
+        "limits": gin.H{
+            "sessionsPerIp":      l.perIP,
+            "messagesPerSecond":  l.rate,
+            "maxMessageBytes":    l.maxMessage,
+            "idleTimeoutSeconds": int64(l.idleTimeout.Seconds()),
+            "keepAliveSeconds":   int64(l.keepAlive.Seconds()),
+            "maxStreams":         l.maxStreams,
+        },
+        "rejectedSessions": l.rejectedSessions.Load(),
+        "limitedMessages":  l.limitedMessages.Load(),
+        "oversizeMessages": l.oversizeMessages.Load(),
+    })
+}
@@ -0,0 +1,232 @@
+package httpapi
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/quic-go/webtransport-go"
+
+    "p2p-file-share-backend/notifications"
+)
+
+// connectWebTransport sends a WebTransport CONNECT from remoteAddr and
+// returns the response, which is an error for every refused session
+func connectWebTransport(a *API, remoteAddr, query string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(http.MethodConnect, WebTransportPath+"?"+query, nil)
+    req.RemoteAddr = remoteAddr
+    w := httptest.NewRecorder()
+    a.withWebTransport(http.NotFoundHandler()).ServeHTTP(w, req)
+    return w
+}
+
+func TestWebTransportIPAccess(t *testing.T) {
+    t.Setenv("IP_DENYLIST", "203.0.113.0/24")
+    a, _ := newTestRouter(t)
+    query := "peerId=peer-a&token=" + issuePeerToken("peer-a")
+
+    tests := []struct {
+        name       string
+        remoteAddr string
+        want       int
+        wantBody   string
+    }{
+        {"denied", "203.0.113.7:4433", http.StatusForbidden, "Access denied"},
+        {"denied, mapped", "[::ffff:203.0.113.7]:4433", http.StatusForbidden, "Access denied"},
+        {"unparseable", "not-an-ip", http.StatusForbidden, "Access denied"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            w := connectWebTransport(a, tt.remoteAddr, query)
+            if w.Code != tt.want || !strings.Contains(w.Body.String(), tt.wantBody) {
+                t.Fatalf("got %d %q, want %d %q", w.Code, w.Body, tt.want, tt.wantBody)
+            }
+            if n := a.wtLimits.sessions(); n != 0 {
+                t.Fatalf("%d session slots taken", n)
+            }
+        })
+    }
+}
+
+// fakeStream is a message stream holding one request and recording the
+// reply
+type fakeStream struct {
+    *bytes.Reader
+    reply bytes.Buffer
+}
+
+func (s *fakeStream) Write(p []byte) (int, error) { return s.reply.Write(p) }
+func (s *fakeStream) Close() error                { return nil }
+
+// sendWebTransportMessage relays a message over a fake session stream from
+// peerID at ip and returns the result
+func sendWebTransportMessage(t *testing.T, a *API, peerID, ip, body string) notifications.RelayResult {
+    t.Helper()
+    stream := &fakeStream{Reader: bytes.NewReader([]byte(body))}
+    opts := wtOptions{events: notifications.EncodingJSON, messages: notifications.EncodingJSON, ip: ip}
+    a.handleWebTransportMessage(stream, peerID, opts, a.wtLimits.newBucket())
+
+    var result notifications.RelayResult
+    if err := json.Unmarshal(stream.reply.Bytes(), &result); err != nil {
+        t.Fatalf("reply %q: %v", stream.reply.String(), err)
+    }
+    return result
+}
+
+// newWebTransportRoom returns an API and its router, with a room holding
+// peer-a and peer-b
+func newWebTransportRoom(t *testing.T) (*API, http.Handler) {
+    t.Helper()
+    a, h := newTestRouter(t)
+    for _, body := range []string{
+        `{"roomCode":"wt","peerId":"peer-a"}`,
+        `{"roomCode":"wt","peerId":"peer-b"}`,
+    } {
+        path := "/room/create"
+        if strings.Contains(body, "peer-b") {
+            path = "/room/join"
+        }
+        if w := request(h, http.MethodPost, path, "application/json", "", body); w.Code != http.StatusOK {
+            t.Fatalf("%s: status = %d: %s", path, w.Code, w.Body)
+        }
+    }
+    return a, h
+}
+
+const wtMessage = `{"to":"peer-b","roomCode":"wt","payload":{"text":"hi"}}`
+
+func TestWebTransportSuspension(t *testing.T) {
+    tests := []struct {
+        name     string
+        kind     string
+        value    string
+        wantOpen int
+    }{
+        {"none", "", "", http.StatusOK},
+        {"peer", suspendPeer, "peer-a", http.StatusForbidden},
+        {"ip", suspendIP, suspensionIPHash("198.51.100.4"), http.StatusForbidden},
+        {"other peer", suspendPeer, "peer-c", http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            a, _ := newWebTransportRoom(t)
+            var closed []webtransport.SessionErrorCode
+            open := a.wtSessions.add("peer-a", "198.51.100.4", "", func(code webtransport.SessionErrorCode, _ string) error {
+                closed = append(closed, code)
+                return nil
+            })
+            defer a.wtSessions.remove(open)
+
+            if tt.kind != "" {
+                a.suspend(tt.kind, tt.value, "test", "admin", 0)
+            }
+            refused := tt.wantOpen == http.StatusForbidden
+            if got := len(closed) == 1 && closed[0] == wtSuspended; got != refused {
+                t.Errorf("session closed = %v, want %v", got, refused)
+            }
+
+            w := connectWebTransport(a, "198.51.100.4:4433", "peerId=peer-a&token="+issuePeerToken("peer-a"))
+            if refused != strings.Contains(w.Body.String(), "Suspended") {
+                t.Errorf("connect: got %d %q, want suspended = %v", w.Code, w.Body, refused)
+            }
+
+            result := sendWebTransportMessage(t, a, "peer-a", "198.51.100.4", wtMessage)
+            if refused && result.Status != http.StatusForbidden {
+                t.Errorf("message: got %+v, want 403", result)
+            }
+            if !refused && !result.Success {
+                t.Errorf("message: got %+v, want success", result)
+            }
+        })
+    }
+}
+
+func TestWebTransportThrottle(t *testing.T) {
+    t.Setenv("ANOMALY_THROTTLED_PER_MINUTE", "1")
+    tests := []struct {
+        name      string
+        throttled string
+        want      []int
+    }{
+        {"not throttled", "", []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+        {"peer", suspensionKey(suspendPeer, "peer-a"), []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+        {"ip", suspensionKey(suspendIP, suspensionIPHash("198.51.100.4")), []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            a, _ := newWebTransportRoom(t)
+            if tt.throttled != "" {
+                a.anomalies.mu.Lock()
+                a.anomalies.throttled[tt.throttled] = time.Now().Add(time.Hour).Unix()
+                a.anomalies.mu.Unlock()
+            }
+            for i, want := range tt.want {
+                result := sendWebTransportMessage(t, a, "peer-a", "198.51.100.4", wtMessage)
+                got := http.StatusOK
+                if !result.Success {
+                    got = result.Status
+                }
+                if got != want {
+                    t.Fatalf("message %d: got %+v, want %d", i, result, want)
+                }
+            }
+        })
+    }
+}
+
+func TestWebTransportDrain(t *testing.T) {
+    a, h := newTestRouter(t)
+    admin := httptest.NewRequest(http.MethodPost, "/admin/drain", nil)
+    t.Setenv("ADMIN_TOKEN", "admin")
+    admin.Header.Set("Authorization", "Bearer admin")
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, admin)
+    if !a.draining() {
+        t.Fatalf("drain: status = %d: %s", w.Code, w.Body)
+    }
+
+    w = connectWebTransport(a, "198.51.100.4:4433", "peerId=peer-a&token="+issuePeerToken("peer-a"))
+    if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+        t.Fatalf("connect: got %d %q, want 503 with Retry-After", w.Code, w.Body)
+    }
+}
+
+func TestWebTransportReadOnly(t *testing.T) {
+    t.Setenv("ADMIN_TOKEN", "admin")
+    tests := []struct {
+        name string
+        body string
+        want int
+    }{
+        {"writable", "", http.StatusOK},
+        {"every route", `{"message":"migrating"}`, http.StatusServiceUnavailable},
+        {"messages", `{"message":"migrating","routes":["/messages"]}`, http.StatusServiceUnavailable},
+        {"other route", `{"message":"migrating","routes":["/room/create"]}`, http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            a, h := newWebTransportRoom(t)
+            if tt.body != "" {
+                w := request(h, http.MethodPut, "/admin/readonly", "application/json", "admin", tt.body)
+                if w.Code != http.StatusOK {
+                    t.Fatalf("readonly: status = %d: %s", w.Code, w.Body)
+                }
+            }
+            result := sendWebTransportMessage(t, a, "peer-a", "198.51.100.4", wtMessage)
+            got := http.StatusOK
+            if !result.Success {
+                got = result.Status
+            }
+            if got != tt.want {
+                t.Fatalf("got %+v, want %d", result, tt.want)
+            }
+            if got != http.StatusOK && result.Error != "migrating" {
+                t.Errorf("error = %q, want the read-only message", result.Error)
+            }
+        })
+    }
+}
@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/quic-go/quic-go"
+)
+
+// Defaults for the WebTransport session limits
+const (
+    defaultWTSessionsPerIP   = 8
+    defaultWTMessagesPerSec  = 20
+    defaultWTMaxMessageBytes = maxMessagePayloadBytes + 1024
+    defaultWTIdleTimeout     = 30 * time.Second
+    defaultWTKeepAlive       = 10 * time.Second
+    defaultWTMaxStreams      = 100
+    wtMessageBurstMultiplier = 2
+)
+
+// sessionLimits bound what one client can do over WebTransport, the
+// server's streaming hub, so a single misbehaving client can't flood it. A
+// limit of zero or less disables that check.
+type sessionLimits struct {
+    perIP       int           // concurrent sessions from one IP
+    rate        float64       // messages per second on one session
+    maxMessage  int64         // bytes in one message, envelope included
+    idleTimeout time.Duration // connections silent this long are closed
+    keepAlive   time.Duration // how often the server pings an idle connection
+    maxStreams  int64         // concurrent streams one connection may open
+
+    mu   sync.Mutex
+    byIP map[string]int
+
+    rejectedSessions atomic.Int64
+    limitedMessages  atomic.Int64
+    oversizeMessages atomic.Int64
+}
+
+// loadSessionLimits reads WT_SESSIONS_PER_IP, WT_MESSAGES_PER_SECOND,
+// WT_MAX_MESSAGE_BYTES, WT_IDLE_TIMEOUT, WT_KEEPALIVE and WT_MAX_STREAMS
+func loadSessionLimits() *sessionLimits {
+    return &sessionLimits{
+        perIP:       envInt("WT_SESSIONS_PER_IP", defaultWTSessionsPerIP),
+        rate:        envFloat("WT_MESSAGES_PER_SECOND", defaultWTMessagesPerSec),
+        maxMessage:  int64(envInt("WT_MAX_MESSAGE_BYTES", defaultWTMaxMessageBytes)),
+        idleTimeout: envDuration("WT_IDLE_TIMEOUT", defaultWTIdleTimeout),
+        keepAlive:   envDuration("WT_KEEPALIVE", defaultWTKeepAlive),
+        maxStreams:  int64(envInt("WT_MAX_STREAMS", defaultWTMaxStreams)),
+        byIP:        make(map[string]int),
+    }
+}
+
+// quicConfig applies the connection-level limits: QUIC itself sends the
+// keep-alive pings and closes connections that stop answering
+func (l *sessionLimits) quicConfig() *quic.Config {
+    cfg := &quic.Config{Allow0RTT: true}
+    if l.idleTimeout > 0 {
+        cfg.MaxIdleTimeout = l.idleTimeout
+    }
+    if l.keepAlive > 0 {
+        cfg.KeepAlivePeriod = l.keepAlive
+    }
+    if l.maxStreams > 0 {
+        cfg.MaxIncomingStreams = l.maxStreams
+    }
+    return cfg
+}
+
+// acquire takes a session slot for ip, reporting false when it already has
+// its maximum
+func (l *sessionLimits) acquire(ip string) bool {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.perIP > 0 && l.byIP[ip] >= l.perIP {
+        l.rejectedSessions.Add(1)
+        return false
+    }
+    l.byIP[ip]++
+    return true
+}
+
+// release gives back a slot taken by acquire
+func (l *sessionLimits) release(ip string) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.byIP[ip] <= 1 {
+        delete(l.byIP, ip)
+        return
+    }
+    l.byIP[ip]--
+}
+
+// sessions returns how many sessions are open
+func (l *sessionLimits) sessions() int {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    n := 0
+    for _, count := range l.byIP {
+        n += count
+    }
+    return n
+}
+
+// messageBucket is a token bucket limiting the messages of one session
+type messageBucket struct {
+    mu     sync.Mutex
+    rate   float64
+    burst  float64
+    tokens float64
+    last   time.Time
+}
+
+func (l *sessionLimits) newBucket() *messageBucket {
+    burst := l.rate * wtMessageBurstMultiplier
+    return &messageBucket{rate: l.rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// allow takes a token if one is available. A bucket with no rate allows
+// everything.
+func (b *messageBucket) allow() bool {
+    if b.rate <= 0 {
+        return true
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    now := time.Now()
+    b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+    b.last = now
+    if b.tokens < 1 {
+        return false
+    }
+    b.tokens--
+    return true
+}
@@ -0,0 +1,217 @@
+// Package jobs runs the server's periodic background work, such as sweeps,
+// retention and exports, on one scheduler that keeps each job's run history
+// so operators can see it and run a job on demand.
+package jobs
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Job is a piece of periodic background work
+type Job struct {
+    // Name identifies the job in its status and when triggering it
+    Name string
+    // Every is how often the job runs
+    Every time.Duration
+    // Immediate runs the job when the scheduler starts, rather than only
+    // after its first interval
+    Immediate bool
+    // Final runs the job once more when the scheduler stops, with a context
+    // that isn't cancelled, e.g. for a last flush
+    Final bool
+    // Run does the work; an error marks the run failed
+    Run func(ctx context.Context) error
+}
+
+// Status is a job's schedule and run history. Times are Unix seconds.
+type Status struct {
+    Name         string  `json:"name"`
+    EverySeconds float64 `json:"everySeconds"`
+    // Since is when the scheduler started the job
+    Since   int64 `json:"since,omitempty"`
+    Running bool  `json:"running"`
+    NextRun int64 `json:"nextRun,omitempty"`
+    // Runs counts finished runs, Failures those that failed and Triggered
+    // the runs asked for by hand
+    Runs      int64 `json:"runs"`
+    Failures  int64 `json:"failures"`
+    Triggered int64 `json:"triggered"`
+    // LastRun is when the last run started
+    LastRun        int64   `json:"lastRun,omitempty"`
+    LastDurationMs float64 `json:"lastDurationMs"`
+    LastSuccess    int64   `json:"lastSuccess,omitempty"`
+    LastError      string  `json:"lastError,omitempty"`
+    // TotalSeconds is the time spent in all runs
+    TotalSeconds float64 `json:"totalSeconds"`
+}
+
+type entry struct {
+    job     Job
+    trigger chan struct{}
+    status  Status
+}
+
+// Scheduler runs jobs on their intervals. Each job runs on its own
+// goroutine, so a slow job never delays another and never overlaps itself.
+type Scheduler struct {
+    mu   sync.Mutex
+    jobs map[string]*entry
+}
+
+// NewScheduler returns a scheduler without jobs
+func NewScheduler() *Scheduler {
+    return &Scheduler{jobs: make(map[string]*entry)}
+}
+
+// Add registers a job. Jobs must be added before Run, and names must be
+// unique.
+func (s *Scheduler) Add(job Job) {
+    if job.Every <= 0 {
+        panic("jobs: " + job.Name + " has no interval")
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, taken := s.jobs[job.Name]; taken {
+        panic("jobs: duplicate job " + job.Name)
+    }
+    s.jobs[job.Name] = &entry{
+        job:     job,
+        trigger: make(chan struct{}, 1),
+        status:  Status{Name: job.Name, EverySeconds: job.Every.Seconds()},
+    }
+}
+
+// Run runs every job until ctx is cancelled, returning once they have all
+// stopped, final runs included
+func (s *Scheduler) Run(ctx context.Context) {
+    s.mu.Lock()
+    entries := make([]*entry, 0, len(s.jobs))
+    for _, e := range s.jobs {
+        entries = append(entries, e)
+    }
+    s.mu.Unlock()
+
+    var wg sync.WaitGroup
+    for _, e := range entries {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            s.loop(ctx, e)
+        }()
+    }
+    wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+    ticker := time.NewTicker(e.job.Every)
+    defer ticker.Stop()
+
+    now := time.Now()
+    s.mu.Lock()
+    e.status.Since = now.Unix()
+    e.status.NextRun = now.Add(e.job.Every).Unix()
+    s.mu.Unlock()
+
+    if e.job.Immediate {
+        s.run(ctx, e)
+    }
+    for {
+        select {
+        case <-ctx.Done():
+            if e.job.Final {
+                s.run(context.WithoutCancel(ctx), e)
+            }
+            return
+        case tick := <-ticker.C:
+            s.mu.Lock()
+            e.status.NextRun = tick.Add(e.job.Every).Unix()
+            s.mu.Unlock()
+        case <-e.trigger:
+        }
+        s.run(ctx, e)
+    }
+}
+
+// run runs a job once and records the outcome. A panicking job fails the
+// run instead of taking the server down.
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+    start := time.Now()
+    s.mu.Lock()
+    e.status.Running = true
+    e.status.LastRun = start.Unix()
+    s.mu.Unlock()
+
+    err := func() (err error) {
+        defer func() {
+            if r := recover(); r != nil {
+                err = fmt.Errorf("panic: %v", r)
+            }
+        }()
+        return e.job.Run(ctx)
+    }()
+    elapsed := time.Since(start)
+    if err != nil {
+        log.Printf("❌ Job %s failed: %v", e.job.Name, err)
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    e.status.Running = false
+    e.status.Runs++
+    e.status.LastDurationMs = float64(elapsed.Microseconds()) / 1000
+    e.status.TotalSeconds += elapsed.Seconds()
+    if err != nil {
+        e.status.Failures++
+        e.status.LastError = err.Error()
+    } else {
+        e.status.LastSuccess = time.Now().Unix()
+        e.status.LastError = ""
+    }
+}
+
+// Trigger asks for a job to run now, reporting whether it exists. A run
+// asked for while one is already waiting is folded into it.
+func (s *Scheduler) Trigger(name string) bool {
+    s.mu.Lock()
+    e, ok := s.jobs[name]
+    if ok {
+        e.status.Triggered++
+    }
+    s.mu.Unlock()
+    if !ok {
+        return false
+    }
+    select {
+    case e.trigger <- struct{}{}:
+    default:
+    }
+    return true
+}
+
+// Job returns a job's status
+func (s *Scheduler) Job(name string) (Status, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    e, ok := s.jobs[name]
+    if !ok {
+        return Status{}, false
+    }
+    return e.status, true
+}
+
+// Jobs returns every job's status, by name
+func (s *Scheduler) Jobs() []Status {
+    s.mu.Lock()
+    statuses := make([]Status, 0, len(s.jobs))
+    for _, e := range s.jobs {
+        statuses = append(statuses, e.status)
+    }
+    s.mu.Unlock()
+    sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+    return statuses
+}
@@ -0,0 +1,67 @@
+// Package leader decides which replica runs cluster-wide background work,
+// such as pruning the relay store the replicas share, so it isn't
+// duplicated. Rooms live in each instance's memory, so every instance
+// sweeps its own whether it leads or not.
+package leader
+
+import (
+    "context"
+    "log"
+    "os"
+    "time"
+
+    "github.com/google/uuid"
+    "github.com/redis/go-redis/v9"
+)
+
+// Elector tracks whether this instance is the leader
+type Elector interface {
+    // Run campaigns for leadership until ctx is cancelled, then steps down
+    Run(ctx context.Context)
+    // IsLeader reports whether this instance currently leads
+    IsLeader() bool
+}
+
+// Single is the elector for an instance running on its own: it always leads
+type Single struct{}
+
+func (Single) Run(ctx context.Context) {}
+
+func (Single) IsLeader() bool { return true }
+
+// FromEnv returns a Redis elector when LEADER_REDIS_URL is set, and Single
+// otherwise. Set it when replicas share a relay store; followers leave
+// cluster-wide work to the leader.
+func FromEnv() Elector {
+    redisURL := os.Getenv("LEADER_REDIS_URL")
+    if redisURL == "" {
+        return Single{}
+    }
+
+    opts, err := redis.ParseURL(redisURL)
+    if err != nil {
+        log.Fatalf("❌ Invalid LEADER_REDIS_URL: %v", err)
+    }
+
+    key := os.Getenv("LEADER_KEY")
+    if key == "" {
+        key = "p2p-file-share:cleanup-leader"
+    }
+    ttl := 15 * time.Second
+    if v := os.Getenv("LEADER_TTL"); v != "" {
+        if ttl, err = time.ParseDuration(v); err != nil || ttl <= 0 {
+            log.Fatalf("❌ Invalid LEADER_TTL %q", v)
+        }
+    }
+
+    return NewRedis(redis.NewClient(opts), key, instanceID(), ttl)
+}
+
+// instanceID names this replica in the lock so operators can see who leads
+func instanceID() string {
+    host, err := os.Hostname()
+    if err != nil {
+        host = "unknown"
+    }
+    return host + "/" + uuid.New().String()
+}
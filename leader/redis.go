@@ -0,0 +1,109 @@
+package leader
+
+import (
+    "context"
+    "log"
+    "sync/atomic"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// renewScript extends the lock only if this instance still holds it
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseScript deletes the lock only if this instance still holds it
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Redis elects a leader with a lock key holding the leader's ID. The leader
+// renews the lock every third of its TTL; if it dies, the key expires and
+// another replica takes over.
+type Redis struct {
+    client *redis.Client
+    key    string
+    id     string
+    ttl    time.Duration
+
+    leading atomic.Bool
+    // renewed is when the lock was last confirmed, so a leader that can't
+    // reach Redis steps down before its lock could have expired
+    renewed time.Time
+}
+
+// NewRedis returns an elector competing for key as id
+func NewRedis(client *redis.Client, key, id string, ttl time.Duration) *Redis {
+    return &Redis{client: client, key: key, id: id, ttl: ttl}
+}
+
+// IsLeader reports whether this instance currently holds the lock
+func (r *Redis) IsLeader() bool {
+    return r.leading.Load()
+}
+
+// Run campaigns for the lock until ctx is cancelled, then releases it
+func (r *Redis) Run(ctx context.Context) {
+    ticker := time.NewTicker(r.ttl / 3)
+    defer ticker.Stop()
+
+    for {
+        r.campaign(ctx)
+
+        select {
+        case <-ctx.Done():
+            r.release()
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// campaign renews the lock if we hold it, or tries to take it if nobody does
+func (r *Redis) campaign(ctx context.Context) {
+    ctx, cancel := context.WithTimeout(ctx, r.ttl/3)
+    defer cancel()
+
+    if r.leading.Load() {
+        renewed, err := renewScript.Run(ctx, r.client, []string{r.key}, r.id, r.ttl.Milliseconds()).Int()
+        switch {
+        case err != nil && time.Since(r.renewed) < r.ttl:
+            log.Printf("⚠️  Could not renew leader lock: %v", err)
+        case err != nil || renewed == 0:
+            r.leading.Store(false)
+            log.Printf("👑 Lost cleanup leadership (%s)", r.id)
+        default:
+            r.renewed = time.Now()
+        }
+        return
+    }
+
+    acquired, err := r.client.SetNX(ctx, r.key, r.id, r.ttl).Result()
+    if err != nil {
+        log.Printf("⚠️  Leader election failed: %v", err)
+        return
+    }
+    if acquired {
+        r.renewed = time.Now()
+        r.leading.Store(true)
+        log.Printf("👑 Elected cleanup leader (%s)", r.id)
+    }
+}
+
+// release gives up the lock so another replica can take over immediately
+func (r *Redis) release() {
+    if !r.leading.Swap(false) {
+        return
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), r.ttl/3)
+    defer cancel()
+    if err := releaseScript.Run(ctx, r.client, []string{r.key}, r.id).Err(); err != nil {
+        log.Printf("⚠️  Could not release leader lock: %v", err)
+    }
+}
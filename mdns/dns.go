@@ -0,0 +1,186 @@
+package mdns
+
+import (
+    "encoding/binary"
+    "errors"
+    "net"
+    "strings"
+)
+
+// DNS record types and classes used by the beacon
+const (
+    typeA    = 1
+    typePTR  = 12
+    typeTXT  = 16
+    typeAAAA = 28
+    typeSRV  = 33
+    typeANY  = 255
+
+    classIN = 1
+    // classCacheFlush marks a record as the only one of its name and type
+    classCacheFlush = 0x8000
+    // classUnicast, set on a question, asks for a unicast response
+    classUnicast = 0x8000
+)
+
+var errMalformed = errors.New("malformed DNS message")
+
+// question is one entry of a query's question section
+type question struct {
+    name    string
+    qtype   uint16
+    unicast bool
+}
+
+// query is the part of an incoming message the beacon cares about
+type query struct {
+    id        uint16
+    response  bool
+    questions []question
+}
+
+// record is a resource record to be written into a response
+type record struct {
+    name   string
+    rtype  uint16
+    unique bool
+    ttl    uint32
+    data   []byte
+}
+
+// parseQuery reads the header and questions of a DNS message
+func parseQuery(msg []byte) (query, error) {
+    if len(msg) < 12 {
+        return query{}, errMalformed
+    }
+    q := query{
+        id:       binary.BigEndian.Uint16(msg[0:]),
+        response: msg[2]&0x80 != 0,
+    }
+    count := int(binary.BigEndian.Uint16(msg[4:]))
+    off := 12
+    for i := 0; i < count; i++ {
+        name, next, err := readName(msg, off)
+        if err != nil {
+            return query{}, err
+        }
+        if next+4 > len(msg) {
+            return query{}, errMalformed
+        }
+        class := binary.BigEndian.Uint16(msg[next+2:])
+        q.questions = append(q.questions, question{
+            name:    name,
+            qtype:   binary.BigEndian.Uint16(msg[next:]),
+            unicast: class&classUnicast != 0,
+        })
+        off = next + 4
+    }
+    return q, nil
+}
+
+// readName decodes a possibly compressed name at off, returning it in lower
+// case with a trailing dot and the offset just past it
+func readName(msg []byte, off int) (string, int, error) {
+    var labels []string
+    end := -1
+    for jumps := 0; ; {
+        if off >= len(msg) {
+            return "", 0, errMalformed
+        }
+        n := int(msg[off])
+        switch {
+        case n == 0:
+            if end < 0 {
+                end = off + 1
+            }
+            return strings.ToLower(strings.Join(labels, ".")) + ".", end, nil
+        case n&0xC0 == 0xC0:
+            if off+1 >= len(msg) || jumps > 16 {
+                return "", 0, errMalformed
+            }
+            if end < 0 {
+                end = off + 2
+            }
+            off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+            jumps++
+        case n&0xC0 != 0 || off+1+n > len(msg):
+            return "", 0, errMalformed
+        default:
+            labels = append(labels, string(msg[off+1:off+1+n]))
+            off += 1 + n
+        }
+    }
+}
+
+// appendName encodes name, uncompressed
+func appendName(b []byte, name string) []byte {
+    for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+        if label == "" {
+            continue
+        }
+        b = append(b, byte(len(label)))
+        b = append(b, label...)
+    }
+    return append(b, 0)
+}
+
+// encodeResponse builds an authoritative response. Legacy unicast replies
+// echo the query's ID and questions; multicast ones use ID zero and none.
+func encodeResponse(id uint16, questions []question, answers, extra []record) []byte {
+    b := make([]byte, 12, 512)
+    binary.BigEndian.PutUint16(b[0:], id)
+    binary.BigEndian.PutUint16(b[2:], 0x8400)
+    binary.BigEndian.PutUint16(b[4:], uint16(len(questions)))
+    binary.BigEndian.PutUint16(b[6:], uint16(len(answers)))
+    binary.BigEndian.PutUint16(b[10:], uint16(len(extra)))
+    for _, q := range questions {
+        b = appendName(b, q.name)
+        b = binary.BigEndian.AppendUint16(b, q.qtype)
+        b = binary.BigEndian.AppendUint16(b, classIN)
+    }
+    for _, r := range append(answers, extra...) {
+        b = appendName(b, r.name)
+        b = binary.BigEndian.AppendUint16(b, r.rtype)
+        class := uint16(classIN)
+        if r.unique {
+            class |= classCacheFlush
+        }
+        b = binary.BigEndian.AppendUint16(b, class)
+        b = binary.BigEndian.AppendUint32(b, r.ttl)
+        b = binary.BigEndian.AppendUint16(b, uint16(len(r.data)))
+        b = append(b, r.data...)
+    }
+    return b
+}
+
+func ptrData(target string) []byte {
+    return appendName(nil, target)
+}
+
+func srvData(port uint16, target string) []byte {
+    b := make([]byte, 6)
+    binary.BigEndian.PutUint16(b[4:], port)
+    return appendName(b, target)
+}
+
+func txtData(entries []string) []byte {
+    if len(entries) == 0 {
+        return []byte{0}
+    }
+    var b []byte
+    for _, e := range entries {
+        if len(e) > 255 {
+            e = e[:255]
+        }
+        b = append(b, byte(len(e)))
+        b = append(b, e...)
+    }
+    return b
+}
+
+func addrData(ip net.IP) (uint16, []byte) {
+    if v4 := ip.To4(); v4 != nil {
+        return typeA, []byte(v4)
+    }
+    return typeAAAA, []byte(ip.To16())
+}
@@ -0,0 +1,260 @@
+// Package mdns advertises the signaling server on the local network over
+// multicast DNS (DNS-SD), so devices in an office or home deployment can
+// find it without anyone typing a URL.
+package mdns
+
+import (
+    "context"
+    "log"
+    "net"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const (
+    // DefaultService is the DNS-SD service type advertised
+    DefaultService = "_p2pshare._tcp"
+
+    // Record TTLs from RFC 6762: host records are short-lived, the rest
+    // are not
+    hostTTL  = 120
+    otherTTL = 4500
+    // legacyTTL caps TTLs in replies to plain unicast DNS resolvers
+    legacyTTL = 10
+
+    servicesName = "_services._dns-sd._udp.local."
+)
+
+var group = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Beacon answers mDNS queries for the server's service, instance and host
+// names, and announces them when it starts and stops
+type Beacon struct {
+    service  string // e.g. _p2pshare._tcp.local.
+    instance string // e.g. P2P Share (office)._p2pshare._tcp.local.
+    host     string // e.g. office.local.
+    port     uint16
+    txt      []string
+    iface    *net.Interface
+}
+
+// Config describes what a Beacon advertises
+type Config struct {
+    // Instance is the human-readable service name shown to users
+    Instance string
+    // Service is the DNS-SD service type (default DefaultService)
+    Service string
+    // Host is the .local host name the service is on (default the
+    // machine's host name)
+    Host string
+    Port int
+    // TXT holds key=value details for clients
+    TXT []string
+    // Interface restricts the beacon to one network interface; nil uses
+    // the system default
+    Interface *net.Interface
+}
+
+// New returns a Beacon for cfg
+func New(cfg Config) *Beacon {
+    hostname, _ := os.Hostname()
+    hostname, _, _ = strings.Cut(hostname, ".")
+    if cfg.Host == "" {
+        cfg.Host = hostname
+    }
+    if cfg.Service == "" {
+        cfg.Service = DefaultService
+    }
+    if cfg.Instance == "" {
+        cfg.Instance = "P2P Share (" + hostname + ")"
+    }
+    service := strings.ToLower(strings.TrimSuffix(cfg.Service, ".local")) + ".local."
+    // The instance name is a single label, so it can't hold dots
+    instance := strings.ReplaceAll(cfg.Instance, ".", " ")
+    return &Beacon{
+        service:  service,
+        instance: instance + "." + service,
+        host:     strings.ToLower(strings.TrimSuffix(cfg.Host, ".local")) + ".local.",
+        port:     uint16(cfg.Port),
+        txt:      cfg.TXT,
+        iface:    cfg.Interface,
+    }
+}
+
+// FromEnv returns a Beacon for a server on port when MDNS_ENABLED=true, and
+// nil otherwise. MDNS_NAME, MDNS_SERVICE, MDNS_HOSTNAME and MDNS_INTERFACE
+// override the defaults; scheme says whether clients should use http or
+// https.
+func FromEnv(port, scheme string) *Beacon {
+    if os.Getenv("MDNS_ENABLED") != "true" {
+        return nil
+    }
+    p, err := strconv.Atoi(port)
+    if err != nil || p <= 0 || p > 65535 {
+        log.Fatalf("❌ mDNS needs a numeric port, got %q", port)
+    }
+    cfg := Config{
+        Instance: os.Getenv("MDNS_NAME"),
+        Service:  os.Getenv("MDNS_SERVICE"),
+        Host:     os.Getenv("MDNS_HOSTNAME"),
+        Port:     p,
+        TXT:      []string{"txtvers=1", "scheme=" + scheme, "path=/"},
+    }
+    if name := os.Getenv("MDNS_INTERFACE"); name != "" {
+        if cfg.Interface, err = net.InterfaceByName(name); err != nil {
+            log.Fatalf("❌ Invalid MDNS_INTERFACE %q: %v", name, err)
+        }
+    }
+    return New(cfg)
+}
+
+// Run answers queries until ctx is cancelled, then says goodbye so caches
+// drop the records
+func (b *Beacon) Run(ctx context.Context) {
+    conn, err := net.ListenMulticastUDP("udp4", b.iface, group)
+    if err != nil {
+        log.Printf("❌ mDNS beacon failed to listen: %v", err)
+        return
+    }
+    defer conn.Close()
+    log.Printf("📡 mDNS: advertising %s on %s port %d", b.instance, b.host, b.port)
+
+    go func() {
+        // Announce twice, a second apart, as RFC 6762 section 8.3 asks
+        for i := 0; i < 2; i++ {
+            b.announce(conn, otherTTL)
+            select {
+            case <-ctx.Done():
+                return
+            case <-time.After(time.Second):
+            }
+        }
+    }()
+    go func() {
+        <-ctx.Done()
+        b.announce(conn, 0)
+        conn.Close()
+    }()
+
+    buf := make([]byte, 9000)
+    for {
+        n, from, err := conn.ReadFromUDP(buf)
+        if err != nil {
+            if ctx.Err() == nil {
+                log.Printf("❌ mDNS beacon stopped: %v", err)
+            }
+            return
+        }
+        q, err := parseQuery(buf[:n])
+        if err != nil || q.response {
+            continue
+        }
+        b.respond(conn, q, from)
+    }
+}
+
+// announce multicasts every record unsolicited; a TTL of zero withdraws them
+func (b *Beacon) announce(conn *net.UDPConn, ttl uint32) {
+    answers := append([]record{b.ptr(b.service, b.instance, ttl), b.srv(ttl), b.txtRecord(ttl)}, b.addresses(ttl)...)
+    if _, err := conn.WriteToUDP(encodeResponse(0, nil, answers, nil), group); err != nil {
+        log.Printf("⚠️  mDNS announcement failed: %v", err)
+    }
+}
+
+// respond answers the questions a query asks about the beacon's names
+func (b *Beacon) respond(conn *net.UDPConn, q query, from *net.UDPAddr) {
+    var answers, extra []record
+    unicast := false
+    for _, qn := range q.questions {
+        all := qn.qtype == typeANY
+        switch qn.name {
+        case servicesName:
+            if all || qn.qtype == typePTR {
+                answers = append(answers, b.ptr(servicesName, b.service, otherTTL))
+            }
+        case b.service:
+            if all || qn.qtype == typePTR {
+                answers = append(answers, b.ptr(b.service, b.instance, otherTTL))
+                extra = append(append(extra, b.srv(otherTTL), b.txtRecord(otherTTL)), b.addresses(hostTTL)...)
+            }
+        case strings.ToLower(b.instance):
+            if all || qn.qtype == typeSRV {
+                answers = append(answers, b.srv(otherTTL))
+                extra = append(extra, b.addresses(hostTTL)...)
+            }
+            if all || qn.qtype == typeTXT {
+                answers = append(answers, b.txtRecord(otherTTL))
+            }
+        case b.host:
+            for _, r := range b.addresses(hostTTL) {
+                if all || qn.qtype == r.rtype {
+                    answers = append(answers, r)
+                }
+            }
+        default:
+            continue
+        }
+        unicast = unicast || qn.unicast
+    }
+    if len(answers) == 0 {
+        return
+    }
+
+    // Queries not sent from port 5353 come from plain DNS resolvers, which
+    // want a conventional reply sent back to them, without the cache-flush
+    // bit
+    if from.Port != group.Port {
+        for _, rs := range [][]record{answers, extra} {
+            for i := range rs {
+                rs[i].ttl = min(rs[i].ttl, legacyTTL)
+                rs[i].unique = false
+            }
+        }
+        conn.WriteToUDP(encodeResponse(q.id, q.questions, answers, extra), from)
+        return
+    }
+    to := group
+    if unicast {
+        to = from
+    }
+    conn.WriteToUDP(encodeResponse(0, nil, answers, extra), to)
+}
+
+func (b *Beacon) ptr(name, target string, ttl uint32) record {
+    return record{name: name, rtype: typePTR, ttl: ttl, data: ptrData(target)}
+}
+
+func (b *Beacon) srv(ttl uint32) record {
+    return record{name: b.instance, rtype: typeSRV, unique: true, ttl: ttl, data: srvData(b.port, b.host)}
+}
+
+func (b *Beacon) txtRecord(ttl uint32) record {
+    return record{name: b.instance, rtype: typeTXT, unique: true, ttl: ttl, data: txtData(b.txt)}
+}
+
+// addresses returns A and AAAA records for the host's current unicast
+// addresses, looked up each time so they follow network changes
+func (b *Beacon) addresses(ttl uint32) []record {
+    var addrs []net.Addr
+    var err error
+    if b.iface != nil {
+        addrs, err = b.iface.Addrs()
+    } else {
+        addrs, err = net.InterfaceAddrs()
+    }
+    if err != nil {
+        return nil
+    }
+    var records []record
+    for _, addr := range addrs {
+        ipnet, ok := addr.(*net.IPNet)
+        if !ok || ipnet.IP.IsLoopback() || !(ipnet.IP.IsGlobalUnicast() || ipnet.IP.IsLinkLocalUnicast()) {
+            continue
+        }
+        rtype, data := addrData(ipnet.IP)
+        records = append(records, record{name: b.host, rtype: rtype, unique: true, ttl: ttl, data: data})
+    }
+    return records
+}
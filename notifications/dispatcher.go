@@ -0,0 +1,168 @@
+package notifications
+
+import (
+    "context"
+    "hash/fnv"
+    "sync/atomic"
+)
+
+// Dispatcher fans notifications out to peers on a pool of workers, so the
+// request that triggered them doesn't wait on every recipient's queue. Each
+// peer is always served by the same worker, so its notifications stay in
+// order. When a worker falls behind, senders block until it catches up.
+type Dispatcher struct {
+    hub     *Hub
+    queues  []chan delivery
+    running atomic.Bool
+    done    chan struct{}
+
+    enqueued  atomic.Int64
+    delivered atomic.Int64
+    blocked   atomic.Int64
+    maxDepth  atomic.Int64
+}
+
+type delivery struct {
+    peerID string
+    n      Notification
+}
+
+// DispatcherStats reports how far behind the dispatcher is. Blocked counts
+// sends that had to wait for a full worker queue; Evictions counts streaming
+// subscribers dropped for falling behind and Dropped the notifications
+// discarded with them. Coalesced counts notifications superseded before
+// delivery.
+type DispatcherStats struct {
+    Workers     int   `json:"workers"`
+    Capacity    int   `json:"capacity"`
+    Depth       int   `json:"depth"`
+    MaxDepth    int64 `json:"maxDepth"`
+    Enqueued    int64 `json:"enqueued"`
+    Delivered   int64 `json:"delivered"`
+    Blocked     int64 `json:"blocked"`
+    Subscribers int   `json:"subscribers"`
+    Evictions   int64 `json:"evictions"`
+    Dropped     int64 `json:"dropped"`
+    Coalesced   int64 `json:"coalesced"`
+}
+
+// NewDispatcher returns a dispatcher delivering into hub with the given
+// number of workers, each buffering up to queueSize notifications. Until Run
+// is called, notifications are delivered synchronously.
+func NewDispatcher(hub *Hub, workers, queueSize int) *Dispatcher {
+    if workers < 1 {
+        workers = 1
+    }
+    d := &Dispatcher{
+        hub:    hub,
+        queues: make([]chan delivery, workers),
+        done:   make(chan struct{}),
+    }
+    for i := range d.queues {
+        d.queues[i] = make(chan delivery, queueSize)
+    }
+    return d
+}
+
+// Run starts the workers and returns once ctx is cancelled and every queued
+// notification has been delivered. Later sends are delivered synchronously.
+func (d *Dispatcher) Run(ctx context.Context) {
+    finished := make(chan struct{}, len(d.queues))
+    for _, q := range d.queues {
+        go d.work(q, finished)
+    }
+    d.running.Store(true)
+
+    <-ctx.Done()
+    d.running.Store(false)
+    close(d.done)
+    for range d.queues {
+        <-finished
+    }
+}
+
+func (d *Dispatcher) work(q chan delivery, finished chan<- struct{}) {
+    defer func() { finished <- struct{}{} }()
+    for {
+        select {
+        case dl := <-q:
+            d.deliver(dl)
+        case <-d.done:
+            // Drain what was queued before shutdown
+            for {
+                select {
+                case dl := <-q:
+                    d.deliver(dl)
+                default:
+                    return
+                }
+            }
+        }
+    }
+}
+
+func (d *Dispatcher) deliver(dl delivery) {
+    d.hub.Queue(dl.peerID, dl.n)
+    d.delivered.Add(1)
+}
+
+// Send queues n for peerID
+func (d *Dispatcher) Send(peerID string, n Notification) {
+    d.enqueued.Add(1)
+    dl := delivery{peerID: peerID, n: n}
+    if !d.running.Load() {
+        d.deliver(dl)
+        return
+    }
+
+    q := d.queues[d.worker(peerID)]
+    select {
+    case q <- dl:
+    default:
+        d.blocked.Add(1)
+        select {
+        case q <- dl:
+        case <-d.done:
+            d.deliver(dl)
+            return
+        }
+    }
+    for depth := int64(len(q)); ; {
+        max := d.maxDepth.Load()
+        if depth <= max || d.maxDepth.CompareAndSwap(max, depth) {
+            break
+        }
+    }
+}
+
+// Fanout queues n for every peer in peerIDs
+func (d *Dispatcher) Fanout(peerIDs []string, n Notification) {
+    for _, peerID := range peerIDs {
+        d.Send(peerID, n)
+    }
+}
+
+// Stats returns the current queue depth and lifetime counters
+func (d *Dispatcher) Stats() DispatcherStats {
+    stats := DispatcherStats{
+        Workers:   len(d.queues),
+        MaxDepth:  d.maxDepth.Load(),
+        Enqueued:  d.enqueued.Load(),
+        Delivered: d.delivered.Load(),
+        Blocked:   d.blocked.Load(),
+    }
+    stats.Subscribers, stats.Evictions, stats.Dropped = d.hub.SubscriberStats()
+    stats.Coalesced = d.hub.Coalesced()
+    for _, q := range d.queues {
+        stats.Capacity += cap(q)
+        stats.Depth += len(q)
+    }
+    return stats
+}
+
+// worker picks the worker that owns peerID
+func (d *Dispatcher) worker(peerID string) int {
+    h := fnv.New32a()
+    h.Write([]byte(peerID))
+    return int(h.Sum32() % uint32(len(d.queues)))
+}
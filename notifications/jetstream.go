@@ -0,0 +1,309 @@
+package notifications
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/nats-io/nats.go"
+    "github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+    // fromHeader and typeHeader let ErasePeer find a peer's notifications
+    // without decoding every message body
+    fromHeader = "P2P-From"
+    typeHeader = "P2P-Type"
+
+    jetStreamBatch   = 256
+    jetStreamTimeout = 5 * time.Second
+)
+
+// JetStream stores notifications in a NATS JetStream stream, one subject per
+// recipient, each drained through that peer's durable consumer. Messages
+// stay in the stream until MaxAge, so they can be replayed for debugging or
+// by another instance after a restart.
+type JetStream struct {
+    nc     *nats.Conn
+    js     jetstream.JetStream
+    stream jetstream.Stream
+    prefix string
+    maxAge time.Duration
+
+    consumersMu sync.Mutex
+    consumers   map[string]jetstream.Consumer
+}
+
+// NewJetStream creates (or updates) the stream on nc and returns a backend
+// publishing under prefix. Notifications older than maxAge are discarded by
+// the server.
+func NewJetStream(nc *nats.Conn, streamName, prefix string, maxAge time.Duration) (*JetStream, error) {
+    js, err := jetstream.New(nc)
+    if err != nil {
+        return nil, err
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), jetStreamTimeout)
+    defer cancel()
+
+    stream, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+        Name:     streamName,
+        Subjects: []string{prefix + ".>"},
+        MaxAge:   maxAge,
+        Storage:  jetstream.FileStorage,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("create stream %s: %w", streamName, err)
+    }
+
+    return &JetStream{
+        nc:        nc,
+        js:        js,
+        stream:    stream,
+        prefix:    prefix,
+        maxAge:    maxAge,
+        consumers: make(map[string]jetstream.Consumer),
+    }, nil
+}
+
+// subjectToken encodes a peer ID so it is a single valid subject token and
+// consumer name
+func subjectToken(peerID string) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(peerID))
+}
+
+func (j *JetStream) subject(peerID string) string {
+    return j.prefix + "." + subjectToken(peerID)
+}
+
+// consumer returns the peer's durable consumer, creating it on first use.
+// Idle consumers are removed by the server after maxAge.
+func (j *JetStream) consumer(ctx context.Context, peerID string) (jetstream.Consumer, error) {
+    j.consumersMu.Lock()
+    defer j.consumersMu.Unlock()
+
+    if cons, ok := j.consumers[peerID]; ok {
+        return cons, nil
+    }
+    cons, err := j.stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+        Durable:           "peer-" + subjectToken(peerID),
+        FilterSubject:     j.subject(peerID),
+        AckPolicy:         jetstream.AckExplicitPolicy,
+        DeliverPolicy:     jetstream.DeliverAllPolicy,
+        InactiveThreshold: j.maxAge,
+    })
+    if err != nil {
+        return nil, err
+    }
+    j.consumers[peerID] = cons
+    return cons, nil
+}
+
+func (j *JetStream) Append(peerID string, n Notification) error {
+    ctx, cancel := context.WithTimeout(context.Background(), jetStreamTimeout)
+    defer cancel()
+
+    // Create the consumer before publishing so the message is delivered
+    // even if this is the peer's first notification
+    if _, err := j.consumer(ctx, peerID); err != nil {
+        return err
+    }
+
+    data, err := json.Marshal(n)
+    if err != nil {
+        return err
+    }
+    msg := nats.NewMsg(j.subject(peerID))
+    msg.Data = data
+    msg.Header.Set(fromHeader, subjectToken(n.PeerID))
+    msg.Header.Set(typeHeader, n.Type)
+
+    _, err = j.js.PublishMsg(ctx, msg)
+    return err
+}
+
+// AppendLimited counts the peer's pending notifications before publishing.
+// The check and the publish are not atomic, so concurrent instances may
+// briefly exceed max.
+func (j *JetStream) AppendLimited(peerID string, n Notification, max int) (bool, error) {
+    pending, err := j.Peek(peerID)
+    if err != nil {
+        return false, err
+    }
+    queued := 0
+    for _, p := range pending {
+        if p.Type == n.Type {
+            queued++
+        }
+    }
+    if queued >= max {
+        return false, nil
+    }
+    return true, j.Append(peerID, n)
+}
+
+// Drain fetches and acks everything waiting on the peer's consumer
+func (j *JetStream) Drain(peerID string) ([]Notification, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), jetStreamTimeout)
+    defer cancel()
+
+    cons, err := j.consumer(ctx, peerID)
+    if err != nil {
+        return nil, err
+    }
+
+    notifications := make([]Notification, 0)
+    for {
+        batch, err := cons.FetchNoWait(jetStreamBatch)
+        if err != nil {
+            return notifications, err
+        }
+        fetched := 0
+        for msg := range batch.Messages() {
+            fetched++
+            var n Notification
+            if err := json.Unmarshal(msg.Data(), &n); err == nil {
+                notifications = append(notifications, n)
+            }
+            if err := msg.Ack(); err != nil {
+                return notifications, err
+            }
+        }
+        if err := batch.Error(); err != nil {
+            return notifications, err
+        }
+        if fetched < jetStreamBatch {
+            return notifications, nil
+        }
+    }
+}
+
+// Peek replays the peer's unacknowledged notifications with a throwaway
+// ordered consumer, leaving the durable consumer untouched
+func (j *JetStream) Peek(peerID string) ([]Notification, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), jetStreamTimeout)
+    defer cancel()
+
+    cons, err := j.consumer(ctx, peerID)
+    if err != nil {
+        return nil, err
+    }
+    info, err := cons.Info(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    notifications := make([]Notification, 0)
+    err = j.scan(ctx, jetstream.OrderedConsumerConfig{
+        FilterSubjects: []string{j.subject(peerID)},
+        DeliverPolicy:  jetstream.DeliverByStartSequencePolicy,
+        OptStartSeq:    info.AckFloor.Stream + 1,
+    }, func(msg jetstream.Msg) error {
+        var n Notification
+        if err := json.Unmarshal(msg.Data(), &n); err == nil {
+            notifications = append(notifications, n)
+        }
+        return nil
+    })
+    return notifications, err
+}
+
+// ErasePeer purges the peer's subject, deletes its consumer, then walks the
+// stream's headers to delete notifications the peer sent to others
+func (j *JetStream) ErasePeer(peerID string) (int, error) {
+    pending, err := j.Peek(peerID)
+    if err != nil {
+        return 0, err
+    }
+    removed := len(pending)
+
+    ctx, cancel := context.WithTimeout(context.Background(), jetStreamTimeout)
+    defer cancel()
+
+    if err := j.stream.Purge(ctx, jetstream.WithPurgeSubject(j.subject(peerID))); err != nil {
+        return 0, err
+    }
+
+    j.consumersMu.Lock()
+    delete(j.consumers, peerID)
+    j.consumersMu.Unlock()
+    err = j.stream.DeleteConsumer(ctx, "peer-"+subjectToken(peerID))
+    if err != nil && !errors.Is(err, jetstream.ErrConsumerNotFound) {
+        return removed, err
+    }
+
+    from := subjectToken(peerID)
+    err = j.scan(ctx, jetstream.OrderedConsumerConfig{
+        FilterSubjects: []string{j.prefix + ".>"},
+        DeliverPolicy:  jetstream.DeliverAllPolicy,
+        HeadersOnly:    true,
+    }, func(msg jetstream.Msg) error {
+        headers := msg.Headers()
+        if headers.Get(fromHeader) != from || headers.Get(typeHeader) == "peer_left" {
+            return nil
+        }
+        meta, err := msg.Metadata()
+        if err != nil {
+            return err
+        }
+        if err := j.stream.DeleteMsg(ctx, meta.Sequence.Stream); err != nil {
+            return err
+        }
+        removed++
+        return nil
+    })
+    return removed, err
+}
+
+// PurgeBefore is a no-op: the stream's MaxAge expires old notifications
+func (j *JetStream) PurgeBefore(cutoff time.Time) (int, error) {
+    return 0, nil
+}
+
+// Watch wakes local subscribers for notifications published by any
+// instance. JetStream subjects are also delivered to plain subscribers.
+func (j *JetStream) Watch(wake func(peerID string)) error {
+    _, err := j.nc.Subscribe(j.prefix+".>", func(msg *nats.Msg) {
+        token := strings.TrimPrefix(msg.Subject, j.prefix+".")
+        peerID, err := base64.RawURLEncoding.DecodeString(token)
+        if err != nil {
+            return
+        }
+        wake(string(peerID))
+    })
+    return err
+}
+
+// scan feeds every message matching cfg to fn using an ephemeral ordered
+// consumer, stopping at the end of the stream
+func (j *JetStream) scan(ctx context.Context, cfg jetstream.OrderedConsumerConfig, fn func(jetstream.Msg) error) error {
+    cfg.InactiveThreshold = jetStreamTimeout
+    cons, err := j.stream.OrderedConsumer(ctx, cfg)
+    if err != nil {
+        return err
+    }
+    for {
+        batch, err := cons.FetchNoWait(jetStreamBatch)
+        if err != nil {
+            return err
+        }
+        fetched := 0
+        for msg := range batch.Messages() {
+            fetched++
+            if err := fn(msg); err != nil {
+                return err
+            }
+        }
+        if err := batch.Error(); err != nil {
+            return err
+        }
+        if fetched < jetStreamBatch {
+            return nil
+        }
+    }
+}
@@ -0,0 +1,105 @@
+package notifications
+
+import (
+    "sync"
+    "time"
+)
+
+// memoryBackend keeps pending notifications in process memory. It is the
+// default backend and never returns errors.
+type memoryBackend struct {
+    mu      sync.RWMutex
+    pending map[string][]Notification
+}
+
+func newMemoryBackend() *memoryBackend {
+    return &memoryBackend{pending: make(map[string][]Notification)}
+}
+
+func (m *memoryBackend) Append(peerID string, n Notification) error {
+    m.mu.Lock()
+    m.pending[peerID] = append(m.pending[peerID], n)
+    m.mu.Unlock()
+    return nil
+}
+
+func (m *memoryBackend) AppendLimited(peerID string, n Notification, max int) (bool, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    queued := 0
+    for _, pending := range m.pending[peerID] {
+        if pending.Type == n.Type {
+            queued++
+        }
+    }
+    if queued >= max {
+        return false, nil
+    }
+    m.pending[peerID] = append(m.pending[peerID], n)
+    return true, nil
+}
+
+func (m *memoryBackend) Drain(peerID string) ([]Notification, error) {
+    m.mu.Lock()
+    notifications, exists := m.pending[peerID]
+    if !exists {
+        notifications = make([]Notification, 0)
+    }
+    delete(m.pending, peerID)
+    m.mu.Unlock()
+
+    return notifications, nil
+}
+
+func (m *memoryBackend) Peek(peerID string) ([]Notification, error) {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+
+    return append([]Notification{}, m.pending[peerID]...), nil
+}
+
+func (m *memoryBackend) ErasePeer(peerID string) (int, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    removed := len(m.pending[peerID])
+    delete(m.pending, peerID)
+
+    for recipient, queue := range m.pending {
+        kept := queue[:0]
+        for _, n := range queue {
+            if n.PeerID == peerID && n.Type != "peer_left" {
+                removed++
+                continue
+            }
+            kept = append(kept, n)
+        }
+        m.pending[recipient] = kept
+    }
+
+    return removed, nil
+}
+
+func (m *memoryBackend) PurgeBefore(cutoff time.Time) (int, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    removed := 0
+    for peerID, queue := range m.pending {
+        kept := queue[:0]
+        for _, n := range queue {
+            if n.Timestamp < cutoff.Unix() {
+                removed++
+                continue
+            }
+            kept = append(kept, n)
+        }
+        if len(kept) == 0 {
+            delete(m.pending, peerID)
+        } else {
+            m.pending[peerID] = kept
+        }
+    }
+    return removed, nil
+}
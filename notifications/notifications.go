@@ -0,0 +1,341 @@
+// Package notifications queues events for peers until they poll for them or
+// a streaming transport pushes them out.
+package notifications
+
+import (
+    "encoding/json"
+    "log"
+    "os"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/nats-io/nats.go"
+)
+
+// Notification represents a peer notification
+type Notification struct {
+    Type      string `json:"type"`
+    PeerID    string `json:"peerId"`
+    Timestamp int64  `json:"timestamp"`
+    // Seq is the sender's sequence number for ordered signaling messages
+    Seq uint64 `json:"seq,omitempty"`
+    // RoomSeq is the room event sequence number of room-wide
+    // notifications, for replay after a reconnect
+    RoomSeq uint64          `json:"roomSeq,omitempty"`
+    Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Backend stores each peer's pending notifications. The in-memory backend
+// is the default; JetStream shares queues between instances.
+type Backend interface {
+    // Append adds n to the peer's queue
+    Append(peerID string, n Notification) error
+    // AppendLimited adds n unless the peer already has max pending
+    // notifications of the same type, reporting whether it was added
+    AppendLimited(peerID string, n Notification, max int) (bool, error)
+    // Drain removes and returns everything queued for the peer
+    Drain(peerID string) ([]Notification, error)
+    // Peek returns the peer's queue without removing anything
+    Peek(peerID string) ([]Notification, error)
+    // ErasePeer drops the peer's queue and the notifications it originated
+    // (except peer_left notices), returning how many were removed
+    ErasePeer(peerID string) (int, error)
+    // PurgeBefore drops notifications older than cutoff
+    PurgeBefore(cutoff time.Time) (int, error)
+}
+
+// Watcher is implemented by backends shared between instances, so a
+// notification queued elsewhere still wakes this instance's subscribers
+type Watcher interface {
+    Watch(wake func(peerID string)) error
+}
+
+// DefaultSlowSubscriberTimeout is how long a subscriber may leave a wake-up
+// unread, while more notifications arrive, before the hub drops it
+const DefaultSlowSubscriberTimeout = 30 * time.Second
+
+// Hub holds every peer's pending notifications and wakes subscribers when
+// something new is queued. Backend errors are logged; a failed drain
+// returns nothing rather than losing the queue.
+type Hub struct {
+    backend Backend
+
+    subscribersMu sync.Mutex
+    subscribers   map[string]map[*Subscription]struct{}
+    slowAfter     time.Duration
+
+    evictions atomic.Int64
+    dropped   atomic.Int64
+    coalesced atomic.Int64
+}
+
+// Subscription is a streaming transport's hold on one peer's notifications
+type Subscription struct {
+    // C receives a value whenever a notification is queued for the peer
+    C <-chan struct{}
+    // Evicted is closed when the hub drops the subscription for falling
+    // behind; the transport should close its connection
+    Evicted <-chan struct{}
+
+    wake    chan struct{}
+    evicted chan struct{}
+    // fullSince is when a wake-up first found C still full, zero while the
+    // subscriber keeps up
+    fullSince time.Time
+}
+
+// NewHub returns an empty in-memory hub
+func NewHub() *Hub {
+    return NewHubWithBackend(newMemoryBackend())
+}
+
+// NewHubWithBackend returns a hub storing notifications in backend
+func NewHubWithBackend(backend Backend) *Hub {
+    h := &Hub{
+        backend:     backend,
+        subscribers: make(map[string]map[*Subscription]struct{}),
+        slowAfter:   DefaultSlowSubscriberTimeout,
+    }
+    if w, ok := backend.(Watcher); ok {
+        if err := w.Watch(h.wake); err != nil {
+            log.Printf("❌ Failed to watch notification backend: %v", err)
+        }
+    }
+    return h
+}
+
+// FromEnv returns a JetStream-backed hub when NATS_URL is set, and an
+// in-memory hub otherwise. NATS_STREAM, NATS_SUBJECT_PREFIX and NATS_MAX_AGE
+// name the stream, its subjects and how long notifications are retained.
+// NOTIFY_SLOW_SUBSCRIBER_TIMEOUT overrides DefaultSlowSubscriberTimeout.
+func FromEnv() *Hub {
+    slowAfter := DefaultSlowSubscriberTimeout
+    if v := os.Getenv("NOTIFY_SLOW_SUBSCRIBER_TIMEOUT"); v != "" {
+        var err error
+        if slowAfter, err = time.ParseDuration(v); err != nil || slowAfter < 0 {
+            log.Fatalf("❌ Invalid NOTIFY_SLOW_SUBSCRIBER_TIMEOUT %q", v)
+        }
+    }
+
+    url := os.Getenv("NATS_URL")
+    if url == "" {
+        h := NewHub()
+        h.slowAfter = slowAfter
+        return h
+    }
+
+    streamName := os.Getenv("NATS_STREAM")
+    if streamName == "" {
+        streamName = "P2P_NOTIFICATIONS"
+    }
+    prefix := os.Getenv("NATS_SUBJECT_PREFIX")
+    if prefix == "" {
+        prefix = "p2p.notify"
+    }
+    maxAge := 24 * time.Hour
+    if v := os.Getenv("NATS_MAX_AGE"); v != "" {
+        var err error
+        if maxAge, err = time.ParseDuration(v); err != nil || maxAge <= 0 {
+            log.Fatalf("❌ Invalid NATS_MAX_AGE %q", v)
+        }
+    }
+
+    nc, err := nats.Connect(url, nats.Name("p2p-file-share-backend"))
+    if err != nil {
+        log.Fatalf("❌ Failed to connect to NATS: %v", err)
+    }
+    backend, err := NewJetStream(nc, streamName, prefix, maxAge)
+    if err != nil {
+        log.Fatalf("❌ Failed to set up JetStream notifications: %v", err)
+    }
+    log.Printf("📮 Notifications stored in JetStream stream %s", streamName)
+    h := NewHubWithBackend(backend)
+    h.slowAfter = slowAfter
+    return h
+}
+
+// Queue appends a notification to a peer's pending queue
+func (h *Hub) Queue(peerID string, n Notification) {
+    if err := h.backend.Append(peerID, n); err != nil {
+        log.Printf("❌ Failed to queue %s notification for %s: %v", n.Type, peerID, err)
+        return
+    }
+    h.wake(peerID)
+}
+
+// QueueLimited queues n unless the peer already has max pending
+// notifications of the same type. It reports whether n was queued.
+func (h *Hub) QueueLimited(peerID string, n Notification, max int) bool {
+    queued, err := h.backend.AppendLimited(peerID, n, max)
+    if err != nil {
+        log.Printf("❌ Failed to queue %s notification for %s: %v", n.Type, peerID, err)
+        return false
+    }
+    if queued {
+        h.wake(peerID)
+    }
+    return queued
+}
+
+// Drain removes and returns everything queued for peerID, less the
+// notifications a later one superseded (see Coalesce)
+func (h *Hub) Drain(peerID string) []Notification {
+    notifications, err := h.backend.Drain(peerID)
+    if err != nil {
+        log.Printf("❌ Failed to drain notifications for %s: %v", peerID, err)
+        return make([]Notification, 0)
+    }
+    coalesced := Coalesce(notifications)
+    h.coalesced.Add(int64(len(notifications) - len(coalesced)))
+    return coalesced
+}
+
+// DrainPrioritized drains peerID's queue like Drain, ordered by lane so
+// signaling goes out before membership, chat and telemetry. Transports on
+// constrained links use it so a backlog of updates doesn't hold up a
+// connection attempt.
+func (h *Hub) DrainPrioritized(peerID string) []Notification {
+    return Prioritize(h.Drain(peerID))
+}
+
+// Peek returns a copy of peerID's pending notifications without draining them
+func (h *Hub) Peek(peerID string) []Notification {
+    notifications, err := h.backend.Peek(peerID)
+    if err != nil {
+        log.Printf("❌ Failed to read notifications for %s: %v", peerID, err)
+        return make([]Notification, 0)
+    }
+    return notifications
+}
+
+// ErasePeer drops the peer's own queue and any queued notifications it
+// originated, except peer_left notices about its departure. It returns how
+// many were removed.
+func (h *Hub) ErasePeer(peerID string) int {
+    removed, err := h.backend.ErasePeer(peerID)
+    if err != nil {
+        log.Printf("❌ Failed to erase notifications for %s: %v", peerID, err)
+    }
+    return removed
+}
+
+// PurgeBefore drops notifications nobody collected in time, e.g. messages
+// queued for a peer that never came back
+func (h *Hub) PurgeBefore(cutoff time.Time) int {
+    removed, err := h.backend.PurgeBefore(cutoff)
+    if err != nil {
+        log.Printf("❌ Failed to purge old notifications: %v", err)
+    }
+    return removed
+}
+
+// Subscribe returns a subscription woken whenever a notification is queued
+// for peerID, so streaming transports need not poll. Call Unsubscribe when
+// done.
+func (h *Hub) Subscribe(peerID string) *Subscription {
+    wake := make(chan struct{}, 1)
+    evicted := make(chan struct{})
+    sub := &Subscription{C: wake, Evicted: evicted, wake: wake, evicted: evicted}
+
+    h.subscribersMu.Lock()
+    if h.subscribers[peerID] == nil {
+        h.subscribers[peerID] = make(map[*Subscription]struct{})
+    }
+    h.subscribers[peerID][sub] = struct{}{}
+    h.subscribersMu.Unlock()
+
+    return sub
+}
+
+// Unsubscribe releases a subscription returned by Subscribe. It is safe to
+// call after the subscription was evicted.
+func (h *Hub) Unsubscribe(peerID string, sub *Subscription) {
+    h.subscribersMu.Lock()
+    delete(h.subscribers[peerID], sub)
+    if len(h.subscribers[peerID]) == 0 {
+        delete(h.subscribers, peerID)
+    }
+    h.subscribersMu.Unlock()
+}
+
+// wake signals every subscriber of peerID without blocking. A subscriber
+// that has left its previous wake-up unread for longer than the slow
+// subscriber timeout is stuck (a dead connection, or a client that can't
+// keep up) and is evicted.
+func (h *Hub) wake(peerID string) {
+    now := time.Now()
+    evicted := 0
+
+    h.subscribersMu.Lock()
+    for sub := range h.subscribers[peerID] {
+        select {
+        case sub.wake <- struct{}{}:
+            sub.fullSince = time.Time{}
+        default:
+            if sub.fullSince.IsZero() {
+                sub.fullSince = now
+            } else if h.slowAfter > 0 && now.Sub(sub.fullSince) >= h.slowAfter {
+                delete(h.subscribers[peerID], sub)
+                close(sub.evicted)
+                evicted++
+            }
+        }
+    }
+    if len(h.subscribers[peerID]) == 0 {
+        delete(h.subscribers, peerID)
+    }
+    h.subscribersMu.Unlock()
+
+    if evicted > 0 {
+        h.evictions.Add(int64(evicted))
+        h.resync(peerID)
+    }
+}
+
+// resync discards the backlog an evicted subscriber left behind and queues
+// a resync_required notification in its place, so the client reconnects,
+// reads the hint first and refetches room state instead of replaying a
+// stale queue
+func (h *Hub) resync(peerID string) {
+    dropped, err := h.backend.Drain(peerID)
+    if err != nil {
+        log.Printf("❌ Failed to drop notifications for %s: %v", peerID, err)
+    }
+    h.dropped.Add(int64(len(dropped)))
+    log.Printf("🐢 Evicted slow subscriber %s, dropped %d notifications", peerID, len(dropped))
+
+    h.Queue(peerID, Notification{
+        Type:      "resync_required",
+        Timestamp: time.Now().Unix(),
+        Payload:   Payload(map[string]interface{}{"reason": "slow_consumer", "dropped": len(dropped)}),
+    })
+}
+
+// SubscriberStats reports how many subscriptions are open, how many were
+// evicted for falling behind and how many notifications were dropped with
+// them
+func (h *Hub) SubscriberStats() (subscribers int, evictions, dropped int64) {
+    h.subscribersMu.Lock()
+    for _, subs := range h.subscribers {
+        subscribers += len(subs)
+    }
+    h.subscribersMu.Unlock()
+    return subscribers, h.evictions.Load(), h.dropped.Load()
+}
+
+// Coalesced reports how many notifications were dropped on drain for being
+// superseded by a later one
+func (h *Hub) Coalesced() int64 {
+    return h.coalesced.Load()
+}
+
+// Payload encodes v for use as a Notification payload
+func Payload(v interface{}) json.RawMessage {
+    data, err := json.Marshal(v)
+    if err != nil {
+        log.Printf("❌ Failed to encode notification payload: %v", err)
+        return nil
+    }
+    return data
+}
@@ -0,0 +1,142 @@
+package notifications
+
+import "encoding/json"
+
+// Priority is a notification's delivery lane. Lower values are more urgent.
+type Priority int
+
+// Lanes, most urgent first
+const (
+    // PrioritySignaling carries WebRTC offers, answers and ICE candidates,
+    // which stall a connection attempt while they wait
+    PrioritySignaling Priority = iota
+    // PriorityMembership covers who is in the room and what it holds:
+    // joins, leaves, approvals, files offered and room changes
+    PriorityMembership
+    // PriorityChat is any other message between peers
+    PriorityChat
+    // PriorityTelemetry is presence and transfer progress, which the next
+    // update supersedes anyway
+    PriorityTelemetry
+)
+
+func (p Priority) String() string {
+    switch p {
+    case PrioritySignaling:
+        return "signaling"
+    case PriorityMembership:
+        return "membership"
+    case PriorityChat:
+        return "chat"
+    }
+    return "telemetry"
+}
+
+// telemetryTypes are the notification types in the telemetry lane
+var telemetryTypes = map[string]bool{
+    "presence_changed":  true,
+    "transfer_progress": true,
+}
+
+// signalTypes are the payload types of messages that carry WebRTC signaling
+var signalTypes = map[string]bool{
+    "offer":         true,
+    "answer":        true,
+    "candidate":     true,
+    "ice-candidate": true,
+    "ice_candidate": true,
+    "ice":           true,
+    "pranswer":      true,
+    "rollback":      true,
+}
+
+// Classify returns n's lane. Messages are signaling when they belong to an
+// ordered session (a non-zero Seq) or their payload's "type" is an SDP or
+// ICE type, and chat otherwise; everything that isn't telemetry is
+// membership.
+func Classify(n Notification) Priority {
+    switch {
+    case n.Type == "message":
+        if n.Seq != 0 {
+            return PrioritySignaling
+        }
+        var payload struct {
+            Type string `json:"type"`
+        }
+        if json.Unmarshal(n.Payload, &payload) == nil && signalTypes[payload.Type] {
+            return PrioritySignaling
+        }
+        return PriorityChat
+    case telemetryTypes[n.Type]:
+        return PriorityTelemetry
+    }
+    return PriorityMembership
+}
+
+// coalesceKey returns the key of notifications that a later one with the
+// same key supersedes: a peer's presence, or its progress on one transfer
+func coalesceKey(n Notification) (string, bool) {
+    switch n.Type {
+    case "presence_changed":
+        return n.Type + "\x00" + n.PeerID, true
+    case "transfer_progress":
+        var transfer struct {
+            FileID string `json:"fileId"`
+            PeerID string `json:"peerId"`
+        }
+        if json.Unmarshal(n.Payload, &transfer) != nil {
+            return "", false
+        }
+        return n.Type + "\x00" + n.PeerID + "\x00" + transfer.FileID + "\x00" + transfer.PeerID, true
+    }
+    return "", false
+}
+
+// Coalesce drops notifications superseded by a later one in pending, like
+// all but the last presence update from each peer, keeping the rest in
+// order. It returns pending itself when nothing was dropped.
+func Coalesce(pending []Notification) []Notification {
+    latest := make(map[string]int)
+    for i, n := range pending {
+        if key, ok := coalesceKey(n); ok {
+            latest[key] = i
+        }
+    }
+    kept := make([]Notification, 0, len(pending))
+    for i, n := range pending {
+        if key, ok := coalesceKey(n); ok && latest[key] != i {
+            continue
+        }
+        kept = append(kept, n)
+    }
+    if len(kept) == len(pending) {
+        return pending
+    }
+    return kept
+}
+
+// Prioritize orders pending by lane, most urgent first, keeping the order
+// within each lane. Room events may then arrive out of RoomSeq order.
+// Telemetry about a peer that left later in the batch is dropped, since
+// moving it behind the peer_left would show a departed peer as present.
+func Prioritize(pending []Notification) []Notification {
+    left := make(map[string]int)
+    for i, n := range pending {
+        if n.Type == "peer_left" {
+            left[n.PeerID] = i
+        }
+    }
+    lanes := make([][]Notification, PriorityTelemetry+1)
+    for i, n := range pending {
+        p := Classify(n)
+        if at, ok := left[n.PeerID]; ok && p == PriorityTelemetry && i < at {
+            continue
+        }
+        lanes[p] = append(lanes[p], n)
+    }
+    ordered := pending[:0]
+    for _, lane := range lanes {
+        ordered = append(ordered, lane...)
+    }
+    return ordered
+}
@@ -0,0 +1,130 @@
+package notifications
+
+import (
+    "encoding/json"
+    "slices"
+    "testing"
+)
+
+// n builds a notification with a JSON payload, naming it by id in the
+// payload so tests can tell copies apart
+func n(typ, peerID string, payload string) Notification {
+    return Notification{Type: typ, PeerID: peerID, Payload: json.RawMessage(payload)}
+}
+
+// ids returns each notification's payload "id"
+func ids(t *testing.T, list []Notification) []string {
+    t.Helper()
+    out := make([]string, 0, len(list))
+    for _, item := range list {
+        var p struct {
+            ID string `json:"id"`
+        }
+        if err := json.Unmarshal(item.Payload, &p); err != nil {
+            t.Fatalf("payload %s: %v", item.Payload, err)
+        }
+        out = append(out, p.ID)
+    }
+    return out
+}
+
+func TestClassify(t *testing.T) {
+    tests := []struct {
+        name string
+        n    Notification
+        want Priority
+    }{
+        {"offer", n("message", "a", `{"type":"offer"}`), PrioritySignaling},
+        {"ice", n("message", "a", `{"type":"ice-candidate"}`), PrioritySignaling},
+        {"sequenced", Notification{Type: "message", PeerID: "a", Seq: 3, Payload: json.RawMessage(`{"text":"hi"}`)}, PrioritySignaling},
+        {"chat", n("message", "a", `{"text":"hi"}`), PriorityChat},
+        {"non-object payload", n("message", "a", `"hi"`), PriorityChat},
+        {"join", n("peer_joined", "a", ``), PriorityMembership},
+        {"leave", n("peer_left", "a", ``), PriorityMembership},
+        {"presence", n("presence_changed", "a", `{}`), PriorityTelemetry},
+        {"progress", n("transfer_progress", "a", `{}`), PriorityTelemetry},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := Classify(tt.n); got != tt.want {
+                t.Errorf("Classify = %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestCoalesce(t *testing.T) {
+    tests := []struct {
+        name    string
+        pending []Notification
+        want    []string
+    }{
+        {"empty", nil, []string{}},
+        {"nothing to coalesce", []Notification{
+            n("message", "a", `{"id":"1"}`),
+            n("peer_joined", "b", `{"id":"2"}`),
+        }, []string{"1", "2"}},
+        {"presence keeps the last per peer", []Notification{
+            n("presence_changed", "a", `{"id":"1"}`),
+            n("presence_changed", "b", `{"id":"2"}`),
+            n("message", "a", `{"id":"3"}`),
+            n("presence_changed", "a", `{"id":"4"}`),
+        }, []string{"2", "3", "4"}},
+        {"progress keeps the last per transfer", []Notification{
+            n("transfer_progress", "a", `{"id":"1","fileId":"f1","peerId":"b"}`),
+            n("transfer_progress", "a", `{"id":"2","fileId":"f2","peerId":"b"}`),
+            n("transfer_progress", "a", `{"id":"3","fileId":"f1","peerId":"c"}`),
+            n("transfer_progress", "a", `{"id":"4","fileId":"f1","peerId":"b"}`),
+        }, []string{"2", "3", "4"}},
+        {"messages are never coalesced", []Notification{
+            n("message", "a", `{"id":"1"}`),
+            n("message", "a", `{"id":"2"}`),
+        }, []string{"1", "2"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := ids(t, Coalesce(tt.pending)); !slices.Equal(got, tt.want) {
+                t.Errorf("Coalesce = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestPrioritize(t *testing.T) {
+    tests := []struct {
+        name    string
+        pending []Notification
+        want    []string
+    }{
+        {"lanes in order", []Notification{
+            n("presence_changed", "a", `{"id":"1"}`),
+            n("message", "a", `{"id":"2","text":"hi"}`),
+            n("peer_joined", "b", `{"id":"3"}`),
+            n("message", "a", `{"id":"4","type":"offer"}`),
+        }, []string{"4", "3", "2", "1"}},
+        {"stable within a lane", []Notification{
+            n("message", "a", `{"id":"1"}`),
+            n("peer_joined", "b", `{"id":"2"}`),
+            n("message", "b", `{"id":"3"}`),
+            n("peer_joined", "c", `{"id":"4"}`),
+        }, []string{"2", "4", "1", "3"}},
+        {"telemetry before its peer left is dropped", []Notification{
+            n("presence_changed", "a", `{"id":"1"}`),
+            n("transfer_progress", "a", `{"id":"2","fileId":"f"}`),
+            n("presence_changed", "b", `{"id":"3"}`),
+            n("peer_left", "a", `{"id":"4"}`),
+        }, []string{"4", "3"}},
+        {"telemetry after a rejoin is kept", []Notification{
+            n("peer_left", "a", `{"id":"1"}`),
+            n("peer_joined", "a", `{"id":"2"}`),
+            n("presence_changed", "a", `{"id":"3"}`),
+        }, []string{"1", "2", "3"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := ids(t, Prioritize(tt.pending)); !slices.Equal(got, tt.want) {
+                t.Errorf("Prioritize = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,241 @@
+package notifications
+
+import (
+    "encoding/json"
+    "errors"
+    "io"
+    "mime"
+    "strconv"
+    "strings"
+
+    "google.golang.org/protobuf/encoding/protojson"
+    "google.golang.org/protobuf/encoding/protowire"
+    "google.golang.org/protobuf/proto"
+    "google.golang.org/protobuf/types/known/structpb"
+)
+
+// SchemaVersion is the version of the event schema in events.proto.
+// Clients may ask for it with a version= media type parameter.
+const SchemaVersion = 1
+
+// Media types for the streaming encodings
+const (
+    ContentTypeJSON     = "application/json"
+    ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Encoding is the wire format negotiated for a stream
+type Encoding int
+
+const (
+    // EncodingJSON writes newline-delimited JSON
+    EncodingJSON Encoding = iota
+    // EncodingProtobuf writes varint length-prefixed protobuf messages
+    EncodingProtobuf
+)
+
+var errMalformedMessage = errors.New("malformed message")
+
+// ContentType returns the media type of e, with the schema version
+func (e Encoding) ContentType() string {
+    mediaType := ContentTypeJSON
+    if e == EncodingProtobuf {
+        mediaType = ContentTypeProtobuf
+    }
+    return mediaType + "; version=" + strconv.Itoa(SchemaVersion)
+}
+
+// NegotiateEncoding picks the first supported encoding listed in an Accept
+// or Content-Type header. An empty header means JSON. ok is false when the
+// header names only unsupported media types or schema versions.
+func NegotiateEncoding(header string) (enc Encoding, ok bool) {
+    if strings.TrimSpace(header) == "" {
+        return EncodingJSON, true
+    }
+    for _, part := range strings.Split(header, ",") {
+        mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+        if err != nil {
+            continue
+        }
+        if v, set := params["version"]; set && v != strconv.Itoa(SchemaVersion) {
+            continue
+        }
+        if q := params["q"]; q == "0" || q == "0.0" {
+            continue
+        }
+        switch mediaType {
+        case ContentTypeProtobuf, "application/protobuf":
+            return EncodingProtobuf, true
+        case ContentTypeJSON, "application/x-ndjson", "application/*", "*/*":
+            return EncodingJSON, true
+        }
+    }
+    return EncodingJSON, false
+}
+
+// EventWriter writes notifications to a stream in one encoding
+type EventWriter struct {
+    w    io.Writer
+    enc  Encoding
+    json *json.Encoder
+}
+
+// NewEventWriter returns a writer of enc-encoded events to w
+func NewEventWriter(w io.Writer, enc Encoding) *EventWriter {
+    return &EventWriter{w: w, enc: enc, json: json.NewEncoder(w)}
+}
+
+// Write sends one event: a JSON line, or a length-prefixed Event message
+func (ew *EventWriter) Write(n Notification) error {
+    if ew.enc == EncodingJSON {
+        return ew.json.Encode(n)
+    }
+    msg, err := MarshalEvent(n)
+    if err != nil {
+        return err
+    }
+    frame := protowire.AppendVarint(make([]byte, 0, len(msg)+4), uint64(len(msg)))
+    _, err = ew.w.Write(append(frame, msg...))
+    return err
+}
+
+// MarshalEvent encodes n as a p2pshare.events.v1.Event message
+func MarshalEvent(n Notification) ([]byte, error) {
+    var b []byte
+    b = appendString(b, 1, n.Type)
+    b = appendString(b, 2, n.PeerID)
+    b = appendVarint(b, 3, uint64(n.Timestamp))
+    b = appendVarint(b, 4, n.Seq)
+    b = appendVarint(b, 5, n.RoomSeq)
+    if len(n.Payload) > 0 {
+        payload, err := marshalValue(n.Payload)
+        if err != nil {
+            return nil, err
+        }
+        b = protowire.AppendTag(b, 6, protowire.BytesType)
+        b = protowire.AppendBytes(b, payload)
+    }
+    return b, nil
+}
+
+// RelayMessage is a signaling message a peer sends over a stream, to be
+// relayed as POST /messages would
+type RelayMessage struct {
+    To       string          `json:"to"`
+    RoomCode string          `json:"roomCode"`
+    Session  string          `json:"session"`
+    Seq      uint64          `json:"seq"`
+    Payload  json.RawMessage `json:"payload"`
+}
+
+// RelayResult answers a RelayMessage
+type RelayResult struct {
+    Success bool   `json:"success,omitempty"`
+    Error   string `json:"error,omitempty"`
+    Status  int    `json:"status,omitempty"`
+}
+
+// DecodeRelayMessage parses one message in enc; protobuf messages are
+// unframed, since each has a stream to itself
+func DecodeRelayMessage(data []byte, enc Encoding) (RelayMessage, error) {
+    var m RelayMessage
+    if enc == EncodingJSON {
+        err := json.Unmarshal(data, &m)
+        return m, err
+    }
+    for len(data) > 0 {
+        num, typ, n := protowire.ConsumeTag(data)
+        if n < 0 {
+            return m, errMalformedMessage
+        }
+        data = data[n:]
+        switch {
+        case num >= 1 && num <= 3 && typ == protowire.BytesType:
+            v, n := protowire.ConsumeString(data)
+            if n < 0 {
+                return m, errMalformedMessage
+            }
+            switch num {
+            case 1:
+                m.To = v
+            case 2:
+                m.RoomCode = v
+            case 3:
+                m.Session = v
+            }
+            data = data[n:]
+        case num == 4 && typ == protowire.VarintType:
+            v, n := protowire.ConsumeVarint(data)
+            if n < 0 {
+                return m, errMalformedMessage
+            }
+            m.Seq = v
+            data = data[n:]
+        case num == 5 && typ == protowire.BytesType:
+            v, n := protowire.ConsumeBytes(data)
+            if n < 0 {
+                return m, errMalformedMessage
+            }
+            var value structpb.Value
+            if err := proto.Unmarshal(v, &value); err != nil {
+                return m, errMalformedMessage
+            }
+            payload, err := protojson.Marshal(&value)
+            if err != nil {
+                return m, errMalformedMessage
+            }
+            m.Payload = payload
+            data = data[n:]
+        default:
+            // Fields from a newer schema are skipped
+            n := protowire.ConsumeFieldValue(num, typ, data)
+            if n < 0 {
+                return m, errMalformedMessage
+            }
+            data = data[n:]
+        }
+    }
+    return m, nil
+}
+
+// WriteRelayResult writes r to w in enc
+func WriteRelayResult(w io.Writer, enc Encoding, r RelayResult) error {
+    if enc == EncodingJSON {
+        return json.NewEncoder(w).Encode(r)
+    }
+    var b []byte
+    if r.Success {
+        b = appendVarint(b, 1, 1)
+    }
+    b = appendString(b, 2, r.Error)
+    b = appendVarint(b, 3, uint64(r.Status))
+    _, err := w.Write(b)
+    return err
+}
+
+// marshalValue encodes a JSON value as a google.protobuf.Value message
+func marshalValue(raw json.RawMessage) ([]byte, error) {
+    var value structpb.Value
+    if err := protojson.Unmarshal(raw, &value); err != nil {
+        return nil, err
+    }
+    return proto.Marshal(&value)
+}
+
+// appendString and appendVarint append a field, leaving it out when it
+// holds the zero value as proto3 does
+func appendString(b []byte, num protowire.Number, v string) []byte {
+    if v == "" {
+        return b
+    }
+    b = protowire.AppendTag(b, num, protowire.BytesType)
+    return protowire.AppendString(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+    if v == 0 {
+        return b
+    }
+    b = protowire.AppendTag(b, num, protowire.VarintType)
+    return protowire.AppendVarint(b, v)
+}
@@ -0,0 +1,234 @@
+package oidc
+
+import (
+    "context"
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rsa"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math/big"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// clockSkew is how far the provider's clock may be ahead of or behind ours
+const clockSkew = 2 * time.Minute
+
+// claims are the ID token claims used here
+type claims struct {
+    Issuer        string   `json:"iss"`
+    Subject       string   `json:"sub"`
+    Audience      audience `json:"aud"`
+    Expiry        int64    `json:"exp"`
+    IssuedAt      int64    `json:"iat"`
+    Nonce         string   `json:"nonce"`
+    Name          string   `json:"name"`
+    Email         string   `json:"email"`
+    EmailVerified *bool    `json:"email_verified"`
+}
+
+// verifiedEmail returns the email unless the provider says it is unverified
+func (c *claims) verifiedEmail() string {
+    if c.EmailVerified != nil && !*c.EmailVerified {
+        return ""
+    }
+    return c.Email
+}
+
+// audience is the aud claim, which may be a string or a list
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+    var single string
+    if err := json.Unmarshal(data, &single); err == nil {
+        *a = audience{single}
+        return nil
+    }
+    var list []string
+    if err := json.Unmarshal(data, &list); err != nil {
+        return err
+    }
+    *a = list
+    return nil
+}
+
+func (a audience) contains(id string) bool {
+    for _, v := range a {
+        if v == id {
+            return true
+        }
+    }
+    return false
+}
+
+// keySet is a provider's signing keys by key ID
+type keySet map[string]crypto.PublicKey
+
+// jwk is one JSON Web Key; only RSA and P-256 keys are understood
+type jwk struct {
+    Kid string `json:"kid"`
+    Kty string `json:"kty"`
+    Use string `json:"use"`
+    N   string `json:"n"`
+    E   string `json:"e"`
+    Crv string `json:"crv"`
+    X   string `json:"x"`
+    Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, bool) {
+    decode := func(s string) *big.Int {
+        b, err := base64.RawURLEncoding.DecodeString(s)
+        if err != nil || len(b) == 0 {
+            return nil
+        }
+        return new(big.Int).SetBytes(b)
+    }
+    switch k.Kty {
+    case "RSA":
+        n, e := decode(k.N), decode(k.E)
+        if n == nil || e == nil || !e.IsInt64() {
+            return nil, false
+        }
+        return &rsa.PublicKey{N: n, E: int(e.Int64())}, true
+    case "EC":
+        x, y := decode(k.X), decode(k.Y)
+        if k.Crv != "P-256" || x == nil || y == nil {
+            return nil, false
+        }
+        return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, true
+    }
+    return nil, false
+}
+
+// key returns the signing key kid, refetching the key set when kid is
+// unknown, since providers rotate keys
+func (p *Provider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+    d, err := p.discover(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if key, ok := p.keys.lookup(kid); ok && time.Since(p.keysLoaded) < discoveryTTL {
+        return key, nil
+    }
+    // Don't let a stream of bogus key IDs hammer the provider
+    if p.keys != nil && time.Since(p.keysLoaded) < time.Minute {
+        return nil, fmt.Errorf("unknown signing key %q", kid)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.JWKSURI, nil)
+    if err != nil {
+        return nil, err
+    }
+    var doc struct {
+        Keys []jwk `json:"keys"`
+    }
+    if err := p.doJSON(req, &doc); err != nil {
+        return nil, fmt.Errorf("%s keys: %w", p.Name, err)
+    }
+    keys := make(keySet)
+    for _, k := range doc.Keys {
+        if k.Use != "" && k.Use != "sig" {
+            continue
+        }
+        if pub, ok := k.publicKey(); ok {
+            keys[k.Kid] = pub
+        }
+    }
+    p.keys, p.keysLoaded = &keys, time.Now()
+
+    if key, ok := p.keys.lookup(kid); ok {
+        return key, nil
+    }
+    return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (ks *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+    if ks == nil {
+        return nil, false
+    }
+    key, ok := (*ks)[kid]
+    return key, ok
+}
+
+// verify checks an ID token's signature and claims and returns the claims
+func (p *Provider) verify(ctx context.Context, token, nonce string) (*claims, error) {
+    parts := strings.Split(token, ".")
+    if len(parts) != 3 {
+        return nil, errors.New("malformed ID token")
+    }
+    var header struct {
+        Alg string `json:"alg"`
+        Kid string `json:"kid"`
+    }
+    if err := decodeSegment(parts[0], &header); err != nil {
+        return nil, err
+    }
+    sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil {
+        return nil, errors.New("malformed ID token signature")
+    }
+
+    key, err := p.key(ctx, header.Kid)
+    if err != nil {
+        return nil, err
+    }
+    digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+    switch k := key.(type) {
+    case *rsa.PublicKey:
+        if header.Alg != "RS256" || rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) != nil {
+            return nil, errors.New("invalid ID token signature")
+        }
+    case *ecdsa.PublicKey:
+        if header.Alg != "ES256" || len(sig) != 64 {
+            return nil, errors.New("invalid ID token signature")
+        }
+        r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+        if !ecdsa.Verify(k, digest[:], r, s) {
+            return nil, errors.New("invalid ID token signature")
+        }
+    default:
+        return nil, errors.New("unsupported ID token key")
+    }
+
+    var c claims
+    if err := decodeSegment(parts[1], &c); err != nil {
+        return nil, err
+    }
+    now := time.Now()
+    switch {
+    case strings.TrimSuffix(c.Issuer, "/") != p.Issuer:
+        return nil, errors.New("ID token is from another issuer")
+    case !c.Audience.contains(p.ClientID):
+        return nil, errors.New("ID token is for another client")
+    case c.Expiry == 0 || now.After(time.Unix(c.Expiry, 0).Add(clockSkew)):
+        return nil, errors.New("ID token has expired")
+    case c.IssuedAt != 0 && time.Unix(c.IssuedAt, 0).After(now.Add(clockSkew)):
+        return nil, errors.New("ID token is issued in the future")
+    case c.Nonce != nonce:
+        return nil, errors.New("ID token nonce does not match")
+    case c.Subject == "":
+        return nil, errors.New("ID token has no subject")
+    }
+    return &c, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+    data, err := base64.RawURLEncoding.DecodeString(segment)
+    if err != nil {
+        return errors.New("malformed ID token")
+    }
+    if err := json.Unmarshal(data, v); err != nil {
+        return errors.New("malformed ID token")
+    }
+    return nil
+}
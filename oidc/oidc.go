@@ -0,0 +1,300 @@
+// Package oidc signs users in with an OpenID Connect provider (Google or any
+// standards-compliant issuer) or GitHub's OAuth, using the authorization
+// code flow with PKCE, and returns who they are.
+package oidc
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "net/url"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    // GoogleIssuer is Google's OpenID Connect issuer
+    GoogleIssuer = "https://accounts.google.com"
+
+    githubAuthURL  = "https://github.com/login/oauth/authorize"
+    githubTokenURL = "https://github.com/login/oauth/access_token"
+    githubUserURL  = "https://api.github.com/user"
+
+    // discoveryTTL is how long a provider's discovery document and keys
+    // are cached
+    discoveryTTL = time.Hour
+)
+
+// Identity is a signed-in user as the provider knows them
+type Identity struct {
+    // Provider is the name the provider was configured under
+    Provider string
+    // Issuer and Subject together identify the user; neither changes when
+    // the user renames themselves or changes email
+    Issuer  string
+    Subject string
+    Name    string
+    Email   string
+}
+
+// Provider is one configured login provider
+type Provider struct {
+    Name         string
+    ClientID     string
+    ClientSecret string
+    // Issuer is the OpenID Connect issuer URL; endpoints and keys are
+    // discovered from it. Empty for GitHub, which only speaks OAuth.
+    Issuer string
+    Scopes []string
+    Client *http.Client
+
+    mu         sync.Mutex
+    endpoints  *discovery
+    keys       *keySet
+    fetchedAt  time.Time
+    keysLoaded time.Time
+}
+
+// discovery is the part of an OpenID Provider's configuration used here
+type discovery struct {
+    Issuer                string `json:"issuer"`
+    AuthorizationEndpoint string `json:"authorization_endpoint"`
+    TokenEndpoint         string `json:"token_endpoint"`
+    JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewOIDC returns a provider for an OpenID Connect issuer
+func NewOIDC(name, issuer, clientID, clientSecret string) *Provider {
+    return &Provider{
+        Name:         name,
+        Issuer:       strings.TrimSuffix(issuer, "/"),
+        ClientID:     clientID,
+        ClientSecret: clientSecret,
+        Scopes:       []string{"openid", "profile", "email"},
+        Client:       &http.Client{Timeout: 10 * time.Second},
+    }
+}
+
+// NewGitHub returns a provider for GitHub's OAuth apps
+func NewGitHub(clientID, clientSecret string) *Provider {
+    return &Provider{
+        Name:         "github",
+        ClientID:     clientID,
+        ClientSecret: clientSecret,
+        Scopes:       []string{"read:user", "user:email"},
+        Client:       &http.Client{Timeout: 10 * time.Second},
+    }
+}
+
+// FromEnv returns the providers configured by the environment, by name.
+// OIDC_GOOGLE_CLIENT_ID/SECRET enable Google, OIDC_GITHUB_CLIENT_ID/SECRET
+// GitHub, and OIDC_ISSUER with OIDC_CLIENT_ID/SECRET any other issuer, named
+// OIDC_PROVIDER_NAME (default "oidc"). With none set, login is disabled and
+// only anonymous peers are available.
+func FromEnv() map[string]*Provider {
+    providers := make(map[string]*Provider)
+    if id := os.Getenv("OIDC_GOOGLE_CLIENT_ID"); id != "" {
+        providers["google"] = NewOIDC("google", GoogleIssuer, id, os.Getenv("OIDC_GOOGLE_CLIENT_SECRET"))
+    }
+    if id := os.Getenv("OIDC_GITHUB_CLIENT_ID"); id != "" {
+        providers["github"] = NewGitHub(id, os.Getenv("OIDC_GITHUB_CLIENT_SECRET"))
+    }
+    if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+        name := os.Getenv("OIDC_PROVIDER_NAME")
+        if name == "" {
+            name = "oidc"
+        }
+        id := os.Getenv("OIDC_CLIENT_ID")
+        if id == "" {
+            log.Fatalf("❌ OIDC_ISSUER is set but OIDC_CLIENT_ID is not")
+        }
+        providers[name] = NewOIDC(name, issuer, id, os.Getenv("OIDC_CLIENT_SECRET"))
+    }
+    if len(providers) > 0 {
+        names := make([]string, 0, len(providers))
+        for name := range providers {
+            names = append(names, name)
+        }
+        sort.Strings(names)
+        log.Printf("🔐 Login enabled with %s", strings.Join(names, ", "))
+    }
+    return providers
+}
+
+// Challenge returns the PKCE S256 code challenge for verifier
+func Challenge(verifier string) string {
+    sum := sha256.Sum256([]byte(verifier))
+    return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider page to send the user to. state, nonce
+// and the PKCE verifier must be random and kept until the callback.
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, verifier string) (string, error) {
+    authURL := githubAuthURL
+    if p.Issuer != "" {
+        d, err := p.discover(ctx)
+        if err != nil {
+            return "", err
+        }
+        authURL = d.AuthorizationEndpoint
+    }
+    q := url.Values{
+        "response_type":         {"code"},
+        "client_id":             {p.ClientID},
+        "redirect_uri":          {redirectURI},
+        "scope":                 {strings.Join(p.Scopes, " ")},
+        "state":                 {state},
+        "code_challenge":        {Challenge(verifier)},
+        "code_challenge_method": {"S256"},
+    }
+    if p.Issuer != "" {
+        q.Set("nonce", nonce)
+    }
+    return authURL + "?" + q.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the signed-in user.
+// For OpenID Connect providers the ID token's signature, issuer, audience,
+// expiry and nonce are all checked.
+func (p *Provider) Exchange(ctx context.Context, redirectURI, code, verifier, nonce string) (*Identity, error) {
+    tokenURL := githubTokenURL
+    if p.Issuer != "" {
+        d, err := p.discover(ctx)
+        if err != nil {
+            return nil, err
+        }
+        tokenURL = d.TokenEndpoint
+    }
+
+    form := url.Values{
+        "grant_type":    {"authorization_code"},
+        "code":          {code},
+        "redirect_uri":  {redirectURI},
+        "client_id":     {p.ClientID},
+        "code_verifier": {verifier},
+    }
+    if p.ClientSecret != "" {
+        form.Set("client_secret", p.ClientSecret)
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.Header.Set("Accept", "application/json")
+
+    var tokens struct {
+        AccessToken string `json:"access_token"`
+        IDToken     string `json:"id_token"`
+        Error       string `json:"error"`
+        Description string `json:"error_description"`
+    }
+    if err := p.doJSON(req, &tokens); err != nil {
+        return nil, err
+    }
+    if tokens.Error != "" {
+        return nil, fmt.Errorf("%s token exchange failed: %s %s", p.Name, tokens.Error, tokens.Description)
+    }
+
+    if p.Issuer == "" {
+        return p.githubUser(ctx, tokens.AccessToken)
+    }
+    if tokens.IDToken == "" {
+        return nil, errors.New(p.Name + " returned no ID token")
+    }
+    claims, err := p.verify(ctx, tokens.IDToken, nonce)
+    if err != nil {
+        return nil, err
+    }
+    return &Identity{
+        Provider: p.Name,
+        Issuer:   claims.Issuer,
+        Subject:  claims.Subject,
+        Name:     claims.Name,
+        Email:    claims.verifiedEmail(),
+    }, nil
+}
+
+// githubUser looks up the user an access token belongs to
+func (p *Provider) githubUser(ctx context.Context, accessToken string) (*Identity, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubUserURL, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer "+accessToken)
+    req.Header.Set("Accept", "application/vnd.github+json")
+
+    var user struct {
+        ID    int64  `json:"id"`
+        Login string `json:"login"`
+        Name  string `json:"name"`
+        Email string `json:"email"`
+    }
+    if err := p.doJSON(req, &user); err != nil {
+        return nil, err
+    }
+    if user.ID == 0 {
+        return nil, errors.New("github returned no user")
+    }
+    name := user.Name
+    if name == "" {
+        name = user.Login
+    }
+    return &Identity{
+        Provider: p.Name,
+        Issuer:   "https://github.com",
+        Subject:  fmt.Sprint(user.ID),
+        Name:     name,
+        Email:    user.Email,
+    }, nil
+}
+
+// discover fetches and caches the issuer's OpenID configuration
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if p.endpoints != nil && time.Since(p.fetchedAt) < discoveryTTL {
+        return p.endpoints, nil
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Issuer+"/.well-known/openid-configuration", nil)
+    if err != nil {
+        return nil, err
+    }
+    var d discovery
+    if err := p.doJSON(req, &d); err != nil {
+        return nil, fmt.Errorf("%s discovery failed: %w", p.Name, err)
+    }
+    if strings.TrimSuffix(d.Issuer, "/") != p.Issuer || d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+        return nil, fmt.Errorf("%s discovery document is incomplete or for another issuer", p.Name)
+    }
+    p.endpoints, p.fetchedAt = &d, time.Now()
+    return p.endpoints, nil
+}
+
+// doJSON sends req and decodes a successful JSON response into v
+func (p *Provider) doJSON(req *http.Request, v interface{}) error {
+    resp, err := p.Client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+    if err != nil {
+        return err
+    }
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("%s returned %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+    }
+    return json.Unmarshal(body, v)
+}
@@ -0,0 +1,210 @@
+// Package parquet writes small, flat Apache Parquet files: one row group of
+// required INT64, DOUBLE and UTF-8 string columns, PLAIN encoded and
+// uncompressed. That is all the usage export needs, and it reads in any
+// Parquet consumer (Spark, DuckDB, pandas, BigQuery).
+package parquet
+
+import (
+    "bytes"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "math"
+)
+
+// Type is a column's physical type
+type Type int
+
+const (
+    Int64  Type = 2
+    Double Type = 5
+    // String is a BYTE_ARRAY annotated as UTF8
+    String Type = 6
+)
+
+// Column is one column of a file
+type Column struct {
+    Name string
+    Type Type
+}
+
+const magic = "PAR1"
+
+// Thrift enum values used in the file metadata
+const (
+    repetitionRequired = 0
+    convertedUTF8      = 0
+    encodingPlain      = 0
+    encodingRLE        = 3
+    codecUncompressed  = 0
+    pageData           = 0
+)
+
+// Write writes rows, whose values must be int64, float64 or string as their
+// column's type says, as a Parquet file to w
+func Write(w io.Writer, columns []Column, rows [][]any) error {
+    out := &countingWriter{w: w}
+    if _, err := io.WriteString(out, magic); err != nil {
+        return err
+    }
+
+    chunks := make([]columnChunk, len(columns))
+    for i, col := range columns {
+        if len(rows) == 0 {
+            // An empty file has no row group, so no pages
+            break
+        }
+        var values bytes.Buffer
+        for r, row := range rows {
+            if len(row) != len(columns) {
+                return fmt.Errorf("parquet: row %d has %d values, want %d", r, len(row), len(columns))
+            }
+            if err := encodePlain(&values, col.Type, row[i]); err != nil {
+                return fmt.Errorf("parquet: row %d column %s: %w", r, col.Name, err)
+            }
+        }
+
+        var header thriftWriter
+        header.begin()
+        header.i32Field(1, pageData)
+        header.i32Field(2, int32(values.Len()))
+        header.i32Field(3, int32(values.Len()))
+        header.structField(5)
+        header.i32Field(1, int32(len(rows)))
+        header.i32Field(2, encodingPlain)
+        header.i32Field(3, encodingRLE)
+        header.i32Field(4, encodingRLE)
+        header.end()
+        header.end()
+
+        chunks[i] = columnChunk{offset: out.n, size: int64(header.buf.Len() + values.Len())}
+        if _, err := out.Write(header.buf.Bytes()); err != nil {
+            return err
+        }
+        if _, err := out.Write(values.Bytes()); err != nil {
+            return err
+        }
+    }
+
+    footer := fileMetadata(columns, chunks, int64(len(rows)))
+    if _, err := out.Write(footer); err != nil {
+        return err
+    }
+    var length [4]byte
+    binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+    if _, err := out.Write(length[:]); err != nil {
+        return err
+    }
+    _, err := io.WriteString(out, magic)
+    return err
+}
+
+// columnChunk is where one column's single data page was written
+type columnChunk struct {
+    offset int64
+    size   int64
+}
+
+func encodePlain(buf *bytes.Buffer, typ Type, v any) error {
+    var scratch [8]byte
+    switch typ {
+    case Int64:
+        n, ok := v.(int64)
+        if !ok {
+            return fmt.Errorf("want int64, got %T", v)
+        }
+        binary.LittleEndian.PutUint64(scratch[:], uint64(n))
+        buf.Write(scratch[:])
+    case Double:
+        f, ok := v.(float64)
+        if !ok {
+            return fmt.Errorf("want float64, got %T", v)
+        }
+        binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(f))
+        buf.Write(scratch[:])
+    case String:
+        s, ok := v.(string)
+        if !ok {
+            return fmt.Errorf("want string, got %T", v)
+        }
+        binary.LittleEndian.PutUint32(scratch[:4], uint32(len(s)))
+        buf.Write(scratch[:4])
+        buf.WriteString(s)
+    default:
+        return fmt.Errorf("unsupported type %d", typ)
+    }
+    return nil
+}
+
+// fileMetadata encodes the FileMetaData footer
+func fileMetadata(columns []Column, chunks []columnChunk, numRows int64) []byte {
+    var t thriftWriter
+    t.begin()
+    t.i32Field(1, 1) // version
+
+    t.listField(2, thriftStruct, len(columns)+1)
+    t.begin()
+    t.binaryField(4, "schema")
+    t.i32Field(5, int32(len(columns)))
+    t.end()
+    for _, col := range columns {
+        t.begin()
+        t.i32Field(1, int32(col.Type))
+        t.i32Field(3, repetitionRequired)
+        t.binaryField(4, col.Name)
+        if col.Type == String {
+            t.i32Field(6, convertedUTF8)
+        }
+        t.end()
+    }
+
+    t.i64Field(3, numRows)
+
+    rowGroups := 0
+    if numRows > 0 {
+        rowGroups = 1
+    }
+    t.listField(4, thriftStruct, rowGroups)
+    if rowGroups == 1 {
+        var total int64
+        t.begin()
+        t.listField(1, thriftStruct, len(columns))
+        for i, col := range columns {
+            total += chunks[i].size
+            t.begin()
+            t.i64Field(2, chunks[i].offset)
+            t.structField(3)
+            t.i32Field(1, int32(col.Type))
+            t.listField(2, thriftI32, 1)
+            t.i32(encodingPlain)
+            t.listField(3, thriftBinary, 1)
+            t.binary(col.Name)
+            t.i32Field(4, codecUncompressed)
+            t.i64Field(5, numRows)
+            t.i64Field(6, chunks[i].size)
+            t.i64Field(7, chunks[i].size)
+            t.i64Field(9, chunks[i].offset)
+            t.end()
+            t.end()
+        }
+        t.i64Field(2, total)
+        t.i64Field(3, numRows)
+        t.end()
+    }
+
+    t.binaryField(6, "p2p-file-share-backend")
+    t.end()
+    return t.buf.Bytes()
+}
+
+// countingWriter tracks the offset written so far
+type countingWriter struct {
+    w io.Writer
+    n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+    n, err := c.w.Write(p)
+    c.n += int64(n)
+    return n, err
+}
@@ -0,0 +1,94 @@
+package parquet
+
+import (
+    "bytes"
+    "encoding/binary"
+)
+
+// Thrift compact protocol type IDs
+const (
+    thriftI32    = 5
+    thriftI64    = 6
+    thriftBinary = 8
+    thriftList   = 9
+    thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol, which
+// Parquet uses for its page headers and footer. Every struct, including
+// the outermost one and each struct in a list, is opened with begin (or
+// structField) and closed with end.
+type thriftWriter struct {
+    buf bytes.Buffer
+    // last holds the last field ID written in each open struct
+    last []int16
+}
+
+func (t *thriftWriter) begin() {
+    t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+    t.buf.WriteByte(0)
+    t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+    top := &t.last[len(t.last)-1]
+    if delta := id - *top; delta > 0 && delta <= 15 {
+        t.buf.WriteByte(byte(delta)<<4 | typ)
+    } else {
+        t.buf.WriteByte(typ)
+        t.i32(int32(id))
+    }
+    *top = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+    t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (t *thriftWriter) i32(v int32) {
+    t.varint(uint64(uint32((v << 1) ^ (v >> 31))))
+}
+
+func (t *thriftWriter) i64(v int64) {
+    t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) binary(s string) {
+    t.varint(uint64(len(s)))
+    t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) i32Field(id int16, v int32) {
+    t.fieldHeader(id, thriftI32)
+    t.i32(v)
+}
+
+func (t *thriftWriter) i64Field(id int16, v int64) {
+    t.fieldHeader(id, thriftI64)
+    t.i64(v)
+}
+
+func (t *thriftWriter) binaryField(id int16, s string) {
+    t.fieldHeader(id, thriftBinary)
+    t.binary(s)
+}
+
+// structField opens a struct-valued field; close it with end
+func (t *thriftWriter) structField(id int16) {
+    t.fieldHeader(id, thriftStruct)
+    t.begin()
+}
+
+// listField starts a list of n elements of typ, which follow directly
+func (t *thriftWriter) listField(id int16, typ byte, n int) {
+    t.fieldHeader(id, thriftList)
+    if n < 15 {
+        t.buf.WriteByte(byte(n)<<4 | typ)
+    } else {
+        t.buf.WriteByte(0xf0 | typ)
+        t.varint(uint64(n))
+    }
+}
@@ -0,0 +1,168 @@
+package relay
+
+import (
+    "context"
+    "encoding/base64"
+    "errors"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// ErrOffsetMismatch is returned when appending anywhere but at the end of a
+// blob, or to a blob another append is still writing
+var ErrOffsetMismatch = errors.New("relay: offset does not match blob length")
+
+// Disk keeps each blob in its own file in a directory. File names are the
+// encoded keys, so keys may contain any characters.
+type Disk struct {
+    dir string
+
+    mu        sync.Mutex
+    appending map[string]bool
+}
+
+// NewDisk opens (or creates) dir for blobs
+func NewDisk(dir string) (*Disk, error) {
+    if err := os.MkdirAll(dir, 0o700); err != nil {
+        return nil, err
+    }
+    return &Disk{dir: dir, appending: make(map[string]bool)}, nil
+}
+
+func (d *Disk) path(key string) string {
+    return filepath.Join(d.dir, base64.RawURLEncoding.EncodeToString([]byte(key)))
+}
+
+// Put writes to a temporary file and renames it into place, so a failed
+// upload never replaces a complete blob
+func (d *Disk) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+    tmp, err := os.CreateTemp(d.dir, ".upload-*")
+    if err != nil {
+        return 0, err
+    }
+    defer os.Remove(tmp.Name())
+
+    n, err := io.Copy(tmp, r)
+    if cerr := tmp.Close(); err == nil {
+        err = cerr
+    }
+    if err != nil {
+        return n, err
+    }
+    return n, os.Rename(tmp.Name(), d.path(key))
+}
+
+// Append writes r to the end of the blob under key, which must hold exactly
+// offset bytes (none when it does not exist yet), and returns how many bytes
+// it wrote. Those are kept even if reading r fails, so an interrupted upload
+// can carry on from where it stopped.
+func (d *Disk) Append(ctx context.Context, key string, offset int64, r io.Reader) (int64, error) {
+    d.mu.Lock()
+    if d.appending[key] {
+        d.mu.Unlock()
+        return 0, ErrOffsetMismatch
+    }
+    d.appending[key] = true
+    d.mu.Unlock()
+    defer func() {
+        d.mu.Lock()
+        delete(d.appending, key)
+        d.mu.Unlock()
+    }()
+
+    f, err := os.OpenFile(d.path(key), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+    if err != nil {
+        return 0, err
+    }
+    info, err := f.Stat()
+    if err != nil {
+        f.Close()
+        return 0, err
+    }
+    if info.Size() != offset {
+        f.Close()
+        return 0, ErrOffsetMismatch
+    }
+    n, err := io.Copy(f, r)
+    if cerr := f.Close(); err == nil {
+        err = cerr
+    }
+    return n, err
+}
+
+// Stat returns the size of the blob under key
+func (d *Disk) Stat(ctx context.Context, key string) (int64, error) {
+    info, err := os.Stat(d.path(key))
+    if errors.Is(err, fs.ErrNotExist) {
+        return 0, ErrNotFound
+    }
+    if err != nil {
+        return 0, err
+    }
+    return info.Size(), nil
+}
+
+func (d *Disk) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+    f, err := os.Open(d.path(key))
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil, 0, ErrNotFound
+    }
+    if err != nil {
+        return nil, 0, err
+    }
+    info, err := f.Stat()
+    if err != nil {
+        f.Close()
+        return nil, 0, err
+    }
+    return f, info.Size(), nil
+}
+
+func (d *Disk) Delete(ctx context.Context, key string) error {
+    err := os.Remove(d.path(key))
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil
+    }
+    return err
+}
+
+func (d *Disk) List(ctx context.Context) ([]string, error) {
+    entries, err := os.ReadDir(d.dir)
+    if err != nil {
+        return nil, err
+    }
+    keys := make([]string, 0, len(entries))
+    for _, e := range entries {
+        if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+            continue
+        }
+        key, err := base64.RawURLEncoding.DecodeString(e.Name())
+        if err != nil {
+            continue
+        }
+        keys = append(keys, string(key))
+    }
+    return keys, nil
+}
+
+// Prune deletes blobs that have not been written to for maxAge
+func (d *Disk) Prune(maxAge time.Duration) error {
+    entries, err := os.ReadDir(d.dir)
+    if err != nil {
+        return err
+    }
+    cutoff := time.Now().Add(-maxAge)
+    for _, e := range entries {
+        info, err := e.Info()
+        if err != nil || e.IsDir() || info.ModTime().After(cutoff) {
+            continue
+        }
+        os.Remove(filepath.Join(d.dir, e.Name()))
+    }
+    return nil
+}
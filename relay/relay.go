@@ -0,0 +1,60 @@
+// Package relay stores file contents on the server for peers that cannot
+// reach each other directly, and scans them before anyone can download them.
+package relay
+
+import (
+    "context"
+    "errors"
+    "io"
+    "log"
+    "os"
+)
+
+// ErrNotFound is returned for a key with no blob
+var ErrNotFound = errors.New("relay: blob not found")
+
+// Store holds relayed blobs by key
+type Store interface {
+    // Put stores everything read from r under key, replacing any blob
+    // already there, and returns its size
+    Put(ctx context.Context, key string, r io.Reader) (int64, error)
+    // Open returns the blob under key and its size
+    Open(ctx context.Context, key string) (io.ReadCloser, int64, error)
+    // Delete removes the blob under key; deleting a missing blob is not an
+    // error
+    Delete(ctx context.Context, key string) error
+    // List returns every stored key
+    List(ctx context.Context) ([]string, error)
+}
+
+// FromEnv returns the store selected by RELAY_STORE, or nil when relaying
+// through the server is disabled. RELAY_STORE=disk keeps blobs in
+// RELAY_DATA_DIR (default ./data/relay); s3, minio and gcs keep them in an
+// object store bucket (see NewS3).
+func FromEnv() Store {
+    switch backend := os.Getenv("RELAY_STORE"); backend {
+    case "", "none":
+        return nil
+    case "disk":
+        dir := os.Getenv("RELAY_DATA_DIR")
+        if dir == "" {
+            dir = "data/relay"
+        }
+        store, err := NewDisk(dir)
+        if err != nil {
+            log.Fatalf("❌ Failed to open relay store: %v", err)
+        }
+        log.Printf("📦 Relaying files through %s", dir)
+        return store
+    case "s3", "minio", "gcs":
+        store, err := s3FromEnv(backend)
+        if err != nil {
+            log.Fatalf("❌ Invalid %s relay store: %v", backend, err)
+        }
+        log.Printf("📦 Relaying files through bucket %s at %s", store.cfg.Bucket, store.base.Host)
+        return store
+    default:
+        log.Fatalf("❌ Unknown RELAY_STORE %q", backend)
+        return nil
+    }
+}
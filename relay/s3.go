@@ -0,0 +1,384 @@
+package relay
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/xml"
+    "errors"
+    "fmt"
+    "io"
+    "maps"
+    "net/http"
+    "net/url"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// unsignedPayload skips hashing bodies, which S3, MinIO and GCS accept over
+// HTTPS; it is also what presigned URLs sign
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Presigner is implemented by stores that can hand clients URLs to upload
+// and download blobs directly, so large blobs never pass through the server
+type Presigner interface {
+    // PresignPut returns a URL accepting a PUT of exactly size bytes
+    PresignPut(key string, size int64, expires time.Duration) (string, error)
+    // PresignGet returns a URL serving the blob as an attachment named
+    // filename
+    PresignGet(key, filename string, expires time.Duration) (string, error)
+    // Stat returns the size of a blob, e.g. after a direct upload
+    Stat(ctx context.Context, key string) (int64, error)
+}
+
+// S3Config configures an S3 store
+type S3Config struct {
+    // Endpoint is the service URL, e.g. https://s3.amazonaws.com,
+    // http://minio:9000 or https://storage.googleapis.com
+    Endpoint string
+    Bucket   string
+    // Region signs requests (default us-east-1; GCS accepts "auto")
+    Region    string
+    AccessKey string
+    SecretKey string
+    // PathStyle addresses the bucket in the path rather than the host
+    // name, as MinIO usually needs
+    PathStyle bool
+    // Prefix is prepended to every key
+    Prefix string
+}
+
+// S3 keeps blobs in a bucket of any S3-compatible object store (AWS S3,
+// MinIO, or Google Cloud Storage through its interoperability API with
+// HMAC keys), signing requests with AWS Signature Version 4
+type S3 struct {
+    cfg    S3Config
+    base   *url.URL
+    client *http.Client
+}
+
+// s3FromEnv configures an S3 store from the environment; see
+// S3ConfigFromEnv
+func s3FromEnv(flavor string) (*S3, error) {
+    return NewS3(S3ConfigFromEnv(flavor))
+}
+
+// S3ConfigFromEnv reads S3_ENDPOINT, S3_BUCKET, S3_REGION,
+// S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY, S3_PATH_STYLE and S3_PREFIX. The
+// minio flavor defaults to path-style addressing, and gcs to the Cloud
+// Storage endpoint.
+func S3ConfigFromEnv(flavor string) S3Config {
+    cfg := S3Config{
+        Endpoint:  os.Getenv("S3_ENDPOINT"),
+        Bucket:    os.Getenv("S3_BUCKET"),
+        Region:    os.Getenv("S3_REGION"),
+        AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+        SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+        PathStyle: os.Getenv("S3_PATH_STYLE") == "true" || (flavor == "minio" && os.Getenv("S3_PATH_STYLE") == ""),
+        Prefix:    os.Getenv("S3_PREFIX"),
+    }
+    if flavor == "gcs" {
+        if cfg.Endpoint == "" {
+            cfg.Endpoint = "https://storage.googleapis.com"
+        }
+        if cfg.Region == "" {
+            cfg.Region = "auto"
+        }
+    }
+    return cfg
+}
+
+// NewS3 returns a store for cfg
+func NewS3(cfg S3Config) (*S3, error) {
+    if cfg.Endpoint == "" {
+        cfg.Endpoint = "https://s3.amazonaws.com"
+    }
+    if cfg.Region == "" {
+        cfg.Region = "us-east-1"
+    }
+    if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+        return nil, errors.New("bucket, access key and secret key are required")
+    }
+    base, err := url.Parse(cfg.Endpoint)
+    if err != nil || base.Host == "" {
+        return nil, fmt.Errorf("invalid endpoint %q", cfg.Endpoint)
+    }
+    if !cfg.PathStyle {
+        base.Host = cfg.Bucket + "." + base.Host
+    }
+    return &S3{cfg: cfg, base: base, client: &http.Client{}}, nil
+}
+
+// objectURL addresses key, or the bucket itself when key is empty
+func (s *S3) objectURL(key string, query url.Values) *url.URL {
+    u := *s.base
+    path := "/"
+    if s.cfg.PathStyle {
+        path += s.cfg.Bucket + "/"
+    }
+    if key != "" {
+        path += s.cfg.Prefix + key
+    }
+    u.Path = path
+    u.RawPath = uriEncode(path, false)
+    u.RawQuery = canonicalQuery(query)
+    return &u
+}
+
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+    req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), body)
+    if err != nil {
+        return nil, err
+    }
+    if body != nil {
+        req.ContentLength = size
+    }
+    s.sign(req, time.Now().UTC())
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode == http.StatusNotFound && key != "" {
+        resp.Body.Close()
+        return nil, ErrNotFound
+    }
+    if resp.StatusCode >= 300 {
+        defer resp.Body.Close()
+        var s3err struct {
+            Code    string `xml:"Code"`
+            Message string `xml:"Message"`
+        }
+        data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+        xml.Unmarshal(data, &s3err)
+        return nil, fmt.Errorf("s3 %s %s: %d %s %s", method, key, resp.StatusCode, s3err.Code, s3err.Message)
+    }
+    return resp, nil
+}
+
+// Put spools blobs of unknown size to a temporary file first, since S3
+// needs the length of an object before it is sent
+func (s *S3) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+    tmp, err := os.CreateTemp("", "relay-*")
+    if err != nil {
+        return 0, err
+    }
+    defer os.Remove(tmp.Name())
+    defer tmp.Close()
+
+    size, err := io.Copy(tmp, r)
+    if err != nil {
+        return size, err
+    }
+    if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+        return size, err
+    }
+    resp, err := s.do(ctx, http.MethodPut, key, nil, tmp, size)
+    if err != nil {
+        return size, err
+    }
+    resp.Body.Close()
+    return size, nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+    resp, err := s.do(ctx, http.MethodGet, key, nil, nil, 0)
+    if err != nil {
+        return nil, 0, err
+    }
+    return resp.Body, resp.ContentLength, nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (int64, error) {
+    resp, err := s.do(ctx, http.MethodHead, key, nil, nil, 0)
+    if err != nil {
+        return 0, err
+    }
+    resp.Body.Close()
+    return resp.ContentLength, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+    resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0)
+    if errors.Is(err, ErrNotFound) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    return nil
+}
+
+func (s *S3) List(ctx context.Context) ([]string, error) {
+    var keys []string
+    query := url.Values{"list-type": {"2"}, "prefix": {s.cfg.Prefix}}
+    for {
+        resp, err := s.do(ctx, http.MethodGet, "", query, nil, 0)
+        if err != nil {
+            return nil, err
+        }
+        var page struct {
+            Contents []struct {
+                Key string `xml:"Key"`
+            } `xml:"Contents"`
+            IsTruncated           bool   `xml:"IsTruncated"`
+            NextContinuationToken string `xml:"NextContinuationToken"`
+        }
+        err = xml.NewDecoder(resp.Body).Decode(&page)
+        resp.Body.Close()
+        if err != nil {
+            return nil, err
+        }
+        for _, obj := range page.Contents {
+            keys = append(keys, strings.TrimPrefix(obj.Key, s.cfg.Prefix))
+        }
+        if !page.IsTruncated || page.NextContinuationToken == "" {
+            return keys, nil
+        }
+        query.Set("continuation-token", page.NextContinuationToken)
+    }
+}
+
+func (s *S3) PresignPut(key string, size int64, expires time.Duration) (string, error) {
+    return s.presign(http.MethodPut, key, nil, map[string]string{"content-length": strconv.FormatInt(size, 10)}, expires), nil
+}
+
+func (s *S3) PresignGet(key, filename string, expires time.Duration) (string, error) {
+    query := url.Values{}
+    if filename != "" {
+        query.Set("response-content-disposition", "attachment; filename*=UTF-8''"+uriEncode(filename, true))
+    }
+    return s.presign(http.MethodGet, key, query, nil, expires), nil
+}
+
+// presign signs a request, including any extra headers the client must
+// send with it, into the query string of its URL
+func (s *S3) presign(method, key string, query url.Values, headers map[string]string, expires time.Duration) string {
+    now := time.Now().UTC()
+    if query == nil {
+        query = url.Values{}
+    }
+    u := s.objectURL(key, nil)
+    headers = maps.Clone(headers)
+    if headers == nil {
+        headers = make(map[string]string)
+    }
+    headers["host"] = u.Host
+    canonicalHeaders, signedHeaders := canonicalizeHeaders(headers)
+
+    query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+    query.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+s.scope(now))
+    query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+    query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+    query.Set("X-Amz-SignedHeaders", signedHeaders)
+    u.RawQuery = canonicalQuery(query)
+
+    canonical := strings.Join([]string{
+        method,
+        u.RawPath,
+        u.RawQuery,
+        canonicalHeaders,
+        signedHeaders,
+        unsignedPayload,
+    }, "\n")
+    u.RawQuery += "&X-Amz-Signature=" + s.signature(now, canonical)
+    return u.String()
+}
+
+// sign adds a Signature Version 4 Authorization header to req
+func (s *S3) sign(req *http.Request, now time.Time) {
+    req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+    req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+    headers := map[string]string{"host": req.URL.Host}
+    for name, values := range req.Header {
+        headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+    }
+    canonicalHeaders, signedHeaders := canonicalizeHeaders(headers)
+
+    canonical := strings.Join([]string{
+        req.Method,
+        req.URL.EscapedPath(),
+        req.URL.RawQuery,
+        canonicalHeaders,
+        signedHeaders,
+        unsignedPayload,
+    }, "\n")
+    req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        s.cfg.AccessKey, s.scope(now), signedHeaders, s.signature(now, canonical)))
+}
+
+// canonicalizeHeaders lists lowercase headers sorted by name, one per
+// line, and their names joined by semicolons
+func canonicalizeHeaders(headers map[string]string) (canonical, signed string) {
+    names := make([]string, 0, len(headers))
+    for name := range headers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    var b strings.Builder
+    for _, name := range names {
+        b.WriteString(name + ":" + headers[name] + "\n")
+    }
+    return b.String(), strings.Join(names, ";")
+}
+
+func (s *S3) scope(now time.Time) string {
+    return now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request with the key derived for its day
+func (s *S3) signature(now time.Time, canonical string) string {
+    hash := sha256.Sum256([]byte(canonical))
+    stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + s.scope(now) + "\n" + hex.EncodeToString(hash[:])
+
+    key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), now.Format("20060102"))
+    for _, part := range []string{s.cfg.Region, "s3", "aws4_request", stringToSign} {
+        key = hmacSHA256(key, part)
+    }
+    return hex.EncodeToString(key)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(data))
+    return mac.Sum(nil)
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and
+// slashes too when encodeSlash is set, as Signature Version 4 requires
+func uriEncode(s string, encodeSlash bool) string {
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+        case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+            c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+            b.WriteByte(c)
+        default:
+            fmt.Fprintf(&b, "%%%02X", c)
+        }
+    }
+    return b.String()
+}
+
+// canonicalQuery encodes query sorted by name, as Signature Version 4
+// requires
+func canonicalQuery(query url.Values) string {
+    names := make([]string, 0, len(query))
+    for name := range query {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    var parts []string
+    for _, name := range names {
+        for _, v := range query[name] {
+            parts = append(parts, uriEncode(name, true)+"="+uriEncode(v, true))
+        }
+    }
+    return strings.Join(parts, "&")
+}
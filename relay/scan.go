@@ -0,0 +1,177 @@
+package relay
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+)
+
+// Verdict is a scanner's decision about a blob
+type Verdict struct {
+    Clean bool
+    // Reason names what was found, e.g. a signature, when not clean
+    Reason string
+}
+
+// Scanner checks relayed content before it can be downloaded
+type Scanner interface {
+    // Scan reads the size bytes of the blob from r. An error means no
+    // verdict was reached.
+    Scan(ctx context.Context, name string, size int64, r io.Reader) (Verdict, error)
+}
+
+// ScannerFromEnv returns the scanner configured by the environment, or nil
+// to make relayed files downloadable unscanned. CLAMAV_ADDR selects a clamd
+// daemon, as host:port or unix:/path/to/clamd.sock; SCAN_WEBHOOK_URL an
+// external HTTP scanner, sent SCAN_WEBHOOK_TOKEN as a bearer token.
+func ScannerFromEnv() Scanner {
+    clamAddr := os.Getenv("CLAMAV_ADDR")
+    webhook := os.Getenv("SCAN_WEBHOOK_URL")
+    switch {
+    case clamAddr != "" && webhook != "":
+        log.Fatal("❌ Set only one of CLAMAV_ADDR and SCAN_WEBHOOK_URL")
+        return nil
+    case clamAddr != "":
+        log.Printf("🦠 Scanning relayed files with clamd at %s", clamAddr)
+        return NewClamAV(clamAddr)
+    case webhook != "":
+        log.Printf("🦠 Scanning relayed files with %s", webhook)
+        return NewWebhook(webhook, os.Getenv("SCAN_WEBHOOK_TOKEN"))
+    default:
+        return nil
+    }
+}
+
+// clamChunkSize is the size of each INSTREAM chunk sent to clamd
+const clamChunkSize = 64 * 1024
+
+// ClamAV scans with a clamd daemon over its INSTREAM protocol
+type ClamAV struct {
+    network string
+    address string
+}
+
+// NewClamAV returns a scanner for the clamd at addr, either host:port or
+// unix:/path/to/socket
+func NewClamAV(addr string) *ClamAV {
+    if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+        return &ClamAV{network: "unix", address: path}
+    }
+    return &ClamAV{network: "tcp", address: addr}
+}
+
+func (s *ClamAV) Scan(ctx context.Context, name string, size int64, r io.Reader) (Verdict, error) {
+    var dialer net.Dialer
+    conn, err := dialer.DialContext(ctx, s.network, s.address)
+    if err != nil {
+        return Verdict{}, err
+    }
+    defer conn.Close()
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+
+    if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+        return Verdict{}, err
+    }
+    buf := make([]byte, 4+clamChunkSize)
+    for {
+        n, err := io.ReadFull(r, buf[4:])
+        if n > 0 {
+            binary.BigEndian.PutUint32(buf, uint32(n))
+            if _, werr := conn.Write(buf[:4+n]); werr != nil {
+                return Verdict{}, werr
+            }
+        }
+        if err == io.EOF || err == io.ErrUnexpectedEOF {
+            break
+        }
+        if err != nil {
+            return Verdict{}, err
+        }
+    }
+    if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+        return Verdict{}, err
+    }
+
+    reply, err := bufio.NewReader(conn).ReadString(0)
+    if err != nil && !errors.Is(err, io.EOF) {
+        return Verdict{}, err
+    }
+    return parseClamReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamReply reads clamd's "stream: OK" or "stream: <signature> FOUND"
+func parseClamReply(reply string) (Verdict, error) {
+    result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+    switch {
+    case result == "OK":
+        return Verdict{Clean: true}, nil
+    case strings.HasSuffix(result, " FOUND"):
+        return Verdict{Reason: strings.TrimSuffix(result, " FOUND")}, nil
+    default:
+        return Verdict{}, fmt.Errorf("clamd: %s", reply)
+    }
+}
+
+// Webhook posts each blob to an external scanner, which answers with JSON
+// {"clean": bool, "reason": string}
+type Webhook struct {
+    url    string
+    token  string
+    client *http.Client
+}
+
+// NewWebhook returns a scanner posting to url
+func NewWebhook(url, token string) *Webhook {
+    return &Webhook{url: url, token: token, client: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+func (s *Webhook) Scan(ctx context.Context, name string, size int64, r io.Reader) (Verdict, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, r)
+    if err != nil {
+        return Verdict{}, err
+    }
+    req.ContentLength = size
+    req.Header.Set("Content-Type", "application/octet-stream")
+    req.Header.Set("X-File-Name", name)
+    if s.token != "" {
+        req.Header.Set("Authorization", "Bearer "+s.token)
+    }
+
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return Verdict{}, err
+    }
+    defer resp.Body.Close()
+    body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+    if err != nil {
+        return Verdict{}, err
+    }
+    if resp.StatusCode != http.StatusOK {
+        return Verdict{}, fmt.Errorf("scanner returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+    }
+
+    var result struct {
+        Clean  *bool  `json:"clean"`
+        Reason string `json:"reason"`
+    }
+    if err := json.Unmarshal(body, &result); err != nil {
+        return Verdict{}, fmt.Errorf("scanner response: %v", err)
+    }
+    if result.Clean == nil {
+        return Verdict{}, errors.New("scanner response has no clean field")
+    }
+    return Verdict{Clean: *result.Clean, Reason: result.Reason}, nil
+}
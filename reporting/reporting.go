@@ -0,0 +1,91 @@
+// Package reporting sends panics and server errors to an error tracker such
+// as Sentry, so production failures are visible beyond stdout logs.
+package reporting
+
+import (
+    "log"
+    "os"
+    "runtime"
+    "strings"
+    "time"
+)
+
+// Level is an event's severity
+type Level string
+
+const (
+    LevelFatal Level = "fatal"
+    LevelError Level = "error"
+)
+
+// Frame is one stack frame, innermost first
+type Frame struct {
+    Function string
+    File     string
+    Line     int
+}
+
+// Request describes the HTTP request an event happened in. Headers must
+// already be redacted.
+type Request struct {
+    Method  string
+    URL     string
+    Route   string
+    Headers map[string]string
+}
+
+// Event is a panic or error to report
+type Event struct {
+    Level Level
+    // Type is the error class, e.g. "panic" or "http_500"
+    Type    string
+    Message string
+    // Stack is where the panic or error happened, if known
+    Stack []Frame
+    // Tags are indexed by the tracker for filtering, e.g. room and peer
+    Tags    map[string]string
+    Request *Request
+    Time    time.Time
+}
+
+// Reporter delivers events to an error tracker. Report must not block the
+// caller for long; implementations queue and send in the background.
+type Reporter interface {
+    Report(ev Event)
+}
+
+// FromEnv returns the reporter configured by the environment, or nil if none
+// is. SENTRY_DSN selects Sentry, tagged with SENTRY_ENVIRONMENT and
+// SENTRY_RELEASE.
+func FromEnv() Reporter {
+    dsn := os.Getenv("SENTRY_DSN")
+    if dsn == "" {
+        return nil
+    }
+    sentry, err := NewSentry(dsn, os.Getenv("SENTRY_ENVIRONMENT"), os.Getenv("SENTRY_RELEASE"))
+    if err != nil {
+        log.Fatalf("❌ Invalid SENTRY_DSN: %v", err)
+    }
+    log.Printf("🛰️  Reporting errors to Sentry project %s", sentry.projectID)
+    return sentry
+}
+
+// Callers returns the stack of the calling goroutine, skipping skip frames
+// above the caller of Callers and the runtime's own panic machinery
+func Callers(skip int) []Frame {
+    pcs := make([]uintptr, 64)
+    n := runtime.Callers(skip+2, pcs)
+    frames := runtime.CallersFrames(pcs[:n])
+
+    var stack []Frame
+    for {
+        frame, more := frames.Next()
+        if !strings.HasPrefix(frame.Function, "runtime.") {
+            stack = append(stack, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+        }
+        if !more {
+            break
+        }
+    }
+    return stack
+}
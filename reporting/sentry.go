@@ -0,0 +1,184 @@
+package reporting
+
+import (
+    "bytes"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+)
+
+const (
+    sentryQueueSize = 256
+    sentryClient    = "p2p-file-share-backend/1.0"
+    // modulePrefix marks frames from this module as in-app, so Sentry groups
+    // events by our code rather than Gin's
+    modulePrefix = "p2p-file-share-backend/"
+)
+
+// Sentry reports events to Sentry's store endpoint, one at a time from a
+// background goroutine. Events are dropped when the queue is full rather than
+// slowing requests down.
+type Sentry struct {
+    endpoint    string
+    key         string
+    projectID   string
+    environment string
+    release     string
+    client      *http.Client
+    queue       chan Event
+}
+
+// NewSentry returns a reporter for dsn, e.g.
+// https://<key>@o0.ingest.sentry.io/<project>
+func NewSentry(dsn, environment, release string) (*Sentry, error) {
+    u, err := url.Parse(dsn)
+    if err != nil {
+        return nil, err
+    }
+    if u.User == nil || u.User.Username() == "" {
+        return nil, fmt.Errorf("DSN has no public key")
+    }
+    i := strings.LastIndexByte(u.Path, '/')
+    projectID := u.Path[i+1:]
+    if projectID == "" {
+        return nil, fmt.Errorf("DSN has no project ID")
+    }
+
+    s := &Sentry{
+        endpoint:    fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, u.Path[:i], projectID),
+        key:         u.User.Username(),
+        projectID:   projectID,
+        environment: environment,
+        release:     release,
+        client:      &http.Client{Timeout: 10 * time.Second},
+        queue:       make(chan Event, sentryQueueSize),
+    }
+    go s.run()
+    return s, nil
+}
+
+// Report queues ev for delivery
+func (s *Sentry) Report(ev Event) {
+    if ev.Time.IsZero() {
+        ev.Time = time.Now()
+    }
+    select {
+    case s.queue <- ev:
+    default:
+        log.Printf("⚠️  Sentry queue full, dropping %s event: %s", ev.Level, ev.Message)
+    }
+}
+
+func (s *Sentry) run() {
+    for ev := range s.queue {
+        if err := s.send(ev); err != nil {
+            log.Printf("⚠️  Failed to report error to Sentry: %v", err)
+        }
+    }
+}
+
+// sentryFrame and friends follow Sentry's event payload schema
+type sentryFrame struct {
+    Function string `json:"function"`
+    Filename string `json:"filename"`
+    Lineno   int    `json:"lineno"`
+    InApp    bool   `json:"in_app"`
+}
+
+type sentryException struct {
+    Type       string `json:"type"`
+    Value      string `json:"value"`
+    Stacktrace *struct {
+        Frames []sentryFrame `json:"frames"`
+    } `json:"stacktrace,omitempty"`
+}
+
+type sentryRequest struct {
+    Method  string            `json:"method"`
+    URL     string            `json:"url"`
+    Headers map[string]string `json:"headers,omitempty"`
+}
+
+type sentryEvent struct {
+    EventID     string            `json:"event_id"`
+    Timestamp   string            `json:"timestamp"`
+    Level       Level             `json:"level"`
+    Platform    string            `json:"platform"`
+    Logger      string            `json:"logger"`
+    Transaction string            `json:"transaction,omitempty"`
+    Environment string            `json:"environment,omitempty"`
+    Release     string            `json:"release,omitempty"`
+    Tags        map[string]string `json:"tags,omitempty"`
+    Request     *sentryRequest    `json:"request,omitempty"`
+    Exception   struct {
+        Values []sentryException `json:"values"`
+    } `json:"exception"`
+}
+
+func (s *Sentry) send(ev Event) error {
+    id := make([]byte, 16)
+    if _, err := rand.Read(id); err != nil {
+        return err
+    }
+
+    payload := sentryEvent{
+        EventID:     hex.EncodeToString(id),
+        Timestamp:   ev.Time.UTC().Format(time.RFC3339Nano),
+        Level:       ev.Level,
+        Platform:    "go",
+        Logger:      "httpapi",
+        Environment: s.environment,
+        Release:     s.release,
+        Tags:        ev.Tags,
+    }
+    exception := sentryException{Type: ev.Type, Value: ev.Message}
+    if len(ev.Stack) > 0 {
+        exception.Stacktrace = &struct {
+            Frames []sentryFrame `json:"frames"`
+        }{}
+        // Sentry expects the outermost frame first
+        for i := len(ev.Stack) - 1; i >= 0; i-- {
+            frame := ev.Stack[i]
+            exception.Stacktrace.Frames = append(exception.Stacktrace.Frames, sentryFrame{
+                Function: frame.Function,
+                Filename: frame.File,
+                Lineno:   frame.Line,
+                InApp:    strings.HasPrefix(frame.Function, modulePrefix),
+            })
+        }
+    }
+    payload.Exception.Values = []sentryException{exception}
+    if ev.Request != nil {
+        payload.Transaction = ev.Request.Method + " " + ev.Request.Route
+        payload.Request = &sentryRequest{Method: ev.Request.Method, URL: ev.Request.URL, Headers: ev.Request.Headers}
+    }
+
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequest("POST", s.endpoint, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, s.key))
+
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("Sentry returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+    }
+    return nil
+}
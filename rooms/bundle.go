@@ -0,0 +1,143 @@
+package rooms
+
+import (
+    "fmt"
+    "path"
+    "sort"
+    "strings"
+)
+
+// Bundle limits, so a folder offer stays a small manifest
+const (
+    MaxBundleEntries    = 1000
+    MaxBundlePathLength = 1024
+    MaxBundleDepth      = 32
+)
+
+// BundleEntry is one file inside a folder bundle, at a slash-separated path
+// relative to the bundle's root
+type BundleEntry struct {
+    Path     string `json:"path"`
+    Size     int64  `json:"size"`
+    MimeType string `json:"mimeType,omitempty"`
+}
+
+// ValidateBundle checks a bundle's entries are distinct, clean relative
+// paths and returns their total size
+func ValidateBundle(entries []BundleEntry) (int64, error) {
+    if len(entries) > MaxBundleEntries {
+        return 0, fmt.Errorf("a bundle may hold at most %d entries", MaxBundleEntries)
+    }
+    var total int64
+    seen := make(map[string]bool, len(entries))
+    for _, e := range entries {
+        if err := validBundlePath(e.Path); err != nil {
+            return 0, err
+        }
+        if e.Size < 0 {
+            return 0, fmt.Errorf("invalid size for %q", e.Path)
+        }
+        if seen[e.Path] {
+            return 0, fmt.Errorf("duplicate path %q", e.Path)
+        }
+        seen[e.Path] = true
+        total += e.Size
+    }
+    // A path can't be both a file and a folder holding other entries
+    for _, e := range entries {
+        for dir := path.Dir(e.Path); dir != "."; dir = path.Dir(dir) {
+            if seen[dir] {
+                return 0, fmt.Errorf("%q is a file, but %q is inside it", dir, e.Path)
+            }
+        }
+    }
+    return total, nil
+}
+
+func validBundlePath(p string) error {
+    switch {
+    case p == "" || len(p) > MaxBundlePathLength:
+        return fmt.Errorf("entry paths must be 1 to %d bytes", MaxBundlePathLength)
+    case strings.HasPrefix(p, "/") || strings.Contains(p, "\\") || path.Clean(p) != p:
+        return fmt.Errorf("%q is not a clean relative path", p)
+    case p == ".." || strings.HasPrefix(p, "../"):
+        return fmt.Errorf("%q points outside the bundle", p)
+    case strings.Count(p, "/") >= MaxBundleDepth:
+        return fmt.Errorf("%q is nested more than %d levels deep", p, MaxBundleDepth)
+    }
+    for _, name := range strings.Split(p, "/") {
+        if len(name) > MaxFileNameLength {
+            return fmt.Errorf("%q has a name longer than %d bytes", p, MaxFileNameLength)
+        }
+    }
+    return nil
+}
+
+// TreeNode is a folder or file in the tree view of a bundle. Folders carry
+// the total size and file count of everything under them.
+type TreeNode struct {
+    Name     string      `json:"name"`
+    Path     string      `json:"path"`
+    Size     int64       `json:"size"`
+    MimeType string      `json:"mimeType,omitempty"`
+    Files    int         `json:"files,omitempty"`
+    Children []*TreeNode `json:"children,omitempty"`
+}
+
+// BundleTree arranges a bundle's entries into a tree under a root named
+// after the bundle, with folders before files and each sorted by name
+func BundleTree(name string, entries []BundleEntry) *TreeNode {
+    root := &TreeNode{Name: name, Path: ""}
+    dirs := map[string]*TreeNode{"": root}
+    var folder func(p string) *TreeNode
+    folder = func(p string) *TreeNode {
+        if node, ok := dirs[p]; ok {
+            return node
+        }
+        parent := path.Dir(p)
+        if parent == "." {
+            parent = ""
+        }
+        node := &TreeNode{Name: path.Base(p), Path: p}
+        dirs[p] = node
+        up := folder(parent)
+        up.Children = append(up.Children, node)
+        return node
+    }
+
+    for _, e := range entries {
+        parent := path.Dir(e.Path)
+        if parent == "." {
+            parent = ""
+        }
+        dir := folder(parent)
+        dir.Children = append(dir.Children, &TreeNode{Name: path.Base(e.Path), Path: e.Path, Size: e.Size, MimeType: e.MimeType})
+        for p := parent; ; p = path.Dir(p) {
+            if p == "." {
+                p = ""
+            }
+            dirs[p].Size += e.Size
+            dirs[p].Files++
+            if p == "" {
+                break
+            }
+        }
+    }
+
+    for _, dir := range dirs {
+        sort.Slice(dir.Children, func(i, j int) bool {
+            a, b := dir.Children[i], dir.Children[j]
+            if isDir(a) != isDir(b) {
+                return isDir(a)
+            }
+            return a.Name < b.Name
+        })
+    }
+    return root
+}
+
+// isDir tells folders from files in a bundle tree: every folder holds at
+// least one file
+func isDir(n *TreeNode) bool {
+    return n.Files > 0
+}
@@ -0,0 +1,44 @@
+package rooms
+
+import "fmt"
+
+// Transports a peer can declare support for
+const (
+    TransportDataChannel = "datachannel"
+    TransportRelay       = "relay"
+)
+
+const maxCompressionCodecs = 8
+
+// PeerCapabilities are transfer hints a peer declares on join so senders can
+// pick chunk size, compression, and transport before dialing
+type PeerCapabilities struct {
+    MaxChunkSize  int      `json:"maxChunkSize,omitempty"`  // bytes
+    Compression   []string `json:"compression,omitempty"`   // e.g. "gzip", "zstd"
+    Transports    []string `json:"transports,omitempty"`    // "datachannel" and/or "relay"
+    BandwidthKbps int      `json:"bandwidthKbps,omitempty"` // client-side estimate
+}
+
+// Validate rejects out-of-range or unknown capability values
+func (pc *PeerCapabilities) Validate() error {
+    if pc.MaxChunkSize < 0 {
+        return fmt.Errorf("capabilities.maxChunkSize must not be negative")
+    }
+    if pc.BandwidthKbps < 0 {
+        return fmt.Errorf("capabilities.bandwidthKbps must not be negative")
+    }
+    if len(pc.Compression) > maxCompressionCodecs {
+        return fmt.Errorf("capabilities.compression may list at most %d codecs", maxCompressionCodecs)
+    }
+    for _, codec := range pc.Compression {
+        if codec == "" || len(codec) > 32 {
+            return fmt.Errorf("capabilities.compression contains an invalid codec")
+        }
+    }
+    for _, transport := range pc.Transports {
+        if transport != TransportDataChannel && transport != TransportRelay {
+            return fmt.Errorf("capabilities.transports: unknown transport %q", transport)
+        }
+    }
+    return nil
+}
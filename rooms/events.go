@@ -0,0 +1,108 @@
+package rooms
+
+import (
+    "encoding/json"
+    "sync/atomic"
+)
+
+// MaxRoomEvents bounds how many recent events a room keeps for replay;
+// clients that fall further behind must resync instead
+const MaxRoomEvents = 256
+
+// RoomEvent is a notification sent to the whole room, kept so reconnecting
+// clients can replay what they missed
+type RoomEvent struct {
+    Seq       uint64
+    Type      string
+    PeerID    string
+    Timestamp int64
+    Payload   json.RawMessage
+    // Except is the peer the event wasn't sent to, usually its sender
+    Except string
+}
+
+// eventLog is a ring of the room's most recent events
+type eventLog struct {
+    ring []RoomEvent
+    // start indexes the oldest event once the ring is full
+    start int
+    last  uint64
+    // floor is the newest sequence number not retained: the last one
+    // issued before the room was created, then the last one discarded
+    floor uint64
+}
+
+// eventSeqs hands out event sequence numbers. Like versions, a single
+// counter keeps them unique across rooms, so a client's position in a room
+// that has since been recreated can't be mistaken for one in the new room.
+var eventSeqs atomic.Uint64
+
+// RecordEvent assigns e the next sequence number and keeps it for replay,
+// discarding the oldest event when the log is full. The caller must hold
+// the room lock.
+func (r *Room) RecordEvent(e RoomEvent) uint64 {
+    el := &r.events
+    e.Seq = eventSeqs.Add(1)
+    if len(el.ring) < MaxRoomEvents {
+        el.ring = append(el.ring, e)
+    } else {
+        el.floor = el.ring[el.start].Seq
+        el.ring[el.start] = e
+        el.start = (el.start + 1) % MaxRoomEvents
+    }
+    el.last = e.Seq
+    return e.Seq
+}
+
+// EventSeq returns the sequence number of the room's latest event, or the
+// floor when it has none yet. The caller must hold the room lock.
+func (r *Room) EventSeq() uint64 {
+    return max(r.events.last, r.events.floor)
+}
+
+// EventsSince returns the events after since that viewerID received. ok is
+// false when since predates the retained history or the room itself, in
+// which case the client must refetch room state. The caller must hold the
+// room lock.
+func (r *Room) EventsSince(since uint64, viewerID string) (events []RoomEvent, ok bool) {
+    el := &r.events
+    if since < el.floor {
+        return nil, false
+    }
+    events = make([]RoomEvent, 0)
+    for i := range el.ring {
+        e := el.ring[(el.start+i)%len(el.ring)]
+        if e.Seq > since && e.Except != viewerID {
+            events = append(events, e)
+        }
+    }
+    return events, true
+}
+
+// Events returns every event the room still retains, oldest first. The
+// caller must hold the room lock.
+func (r *Room) Events() []RoomEvent {
+    el := &r.events
+    events := make([]RoomEvent, 0, len(el.ring))
+    for i := range el.ring {
+        events = append(events, el.ring[(el.start+i)%len(el.ring)])
+    }
+    return events
+}
+
+// EraseEvents drops the retained events a peer sent or that are about it,
+// except its peer_left notices, which the rest of the room still needs, and
+// returns how many were dropped. The caller must hold the room lock.
+func (r *Room) EraseEvents(peerID string) int {
+    el := &r.events
+    kept := make([]RoomEvent, 0, len(el.ring))
+    for _, e := range r.Events() {
+        if (e.PeerID == peerID || e.Except == peerID) && e.Type != "peer_left" {
+            continue
+        }
+        kept = append(kept, e)
+    }
+    removed := len(el.ring) - len(kept)
+    el.ring, el.start = kept, 0
+    return removed
+}
@@ -0,0 +1,86 @@
+package rooms
+
+import (
+    "testing"
+)
+
+func TestEraseEvents(t *testing.T) {
+    tests := []struct {
+        name        string
+        events      []RoomEvent
+        wantRemoved int
+        wantTypes   []string
+    }{
+        {"none", nil, 0, nil},
+        {"sent and about the peer", []RoomEvent{
+            {Type: "peer_joined", PeerID: "b", Except: "b"},
+            {Type: "presence_changed", PeerID: "a", Except: "a"},
+            {Type: "presence_changed", PeerID: "b", Except: "b"},
+            {Type: "file_offered", PeerID: "b"},
+        }, 3, []string{"presence_changed"}},
+        {"peer_left is kept", []RoomEvent{
+            {Type: "presence_changed", PeerID: "b", Except: "b"},
+            {Type: "peer_left", PeerID: "b", Except: "b"},
+            {Type: "peer_joined", PeerID: "c", Except: "c"},
+        }, 1, []string{"peer_left", "peer_joined"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            room := New("a", "", "", 0)
+            for _, e := range tt.events {
+                room.RecordEvent(e)
+            }
+            if removed := room.EraseEvents("b"); removed != tt.wantRemoved {
+                t.Errorf("removed %d, want %d", removed, tt.wantRemoved)
+            }
+            events := room.Events()
+            if len(events) != len(tt.wantTypes) {
+                t.Fatalf("kept %d events, want %d", len(events), len(tt.wantTypes))
+            }
+            for i, e := range events {
+                if e.Type != tt.wantTypes[i] {
+                    t.Errorf("event %d is %s, want %s", i, e.Type, tt.wantTypes[i])
+                }
+            }
+        })
+    }
+}
+
+func TestEraseEventsWrapped(t *testing.T) {
+    room := New("a", "", "", 0)
+    for i := 0; i < MaxRoomEvents+10; i++ {
+        peer := "a"
+        if i%2 == 1 {
+            peer = "b"
+        }
+        room.RecordEvent(RoomEvent{Type: "message", PeerID: peer, Except: peer})
+    }
+    floor := room.events.floor
+
+    if removed := room.EraseEvents("b"); removed != MaxRoomEvents/2 {
+        t.Fatalf("removed %d, want %d", removed, MaxRoomEvents/2)
+    }
+    events, ok := room.EventsSince(floor, "c")
+    if !ok || len(events) != MaxRoomEvents/2 {
+        t.Fatalf("EventsSince = %d events, %v", len(events), ok)
+    }
+    for i, e := range events {
+        if e.PeerID != "a" {
+            t.Fatalf("event %d is from %s", i, e.PeerID)
+        }
+        if i > 0 && e.Seq <= events[i-1].Seq {
+            t.Fatalf("events out of order at %d", i)
+        }
+    }
+
+    // The log keeps filling and discarding in order after an erasure
+    for i := 0; i < MaxRoomEvents; i++ {
+        room.RecordEvent(RoomEvent{Type: "message", PeerID: "a"})
+    }
+    if n := len(room.Events()); n != MaxRoomEvents {
+        t.Fatalf("%d events retained, want %d", n, MaxRoomEvents)
+    }
+    if seq := room.EventSeq(); room.Events()[MaxRoomEvents-1].Seq != seq {
+        t.Fatalf("newest event is not last")
+    }
+}
@@ -0,0 +1,66 @@
+package rooms
+
+import (
+    "fmt"
+    "strings"
+)
+
+const (
+    maxRoomNameLength        = 64
+    maxRoomDescriptionLength = 500
+    maxRoomIconLength        = 32 // bytes; room for a multi-codepoint emoji
+    maxRoomTags              = 10
+    maxRoomTagLength         = 32
+)
+
+// RoomInfo is the optional, host-supplied description of a room shown on
+// join screens
+type RoomInfo struct {
+    Name        string   `json:"name,omitempty"`
+    Description string   `json:"description,omitempty"`
+    Icon        string   `json:"icon,omitempty"`
+    Tags        []string `json:"tags,omitempty"`
+}
+
+// Normalize trims whitespace and lowercases and de-duplicates tags
+func (info *RoomInfo) Normalize() {
+    info.Name = strings.TrimSpace(info.Name)
+    info.Description = strings.TrimSpace(info.Description)
+    info.Icon = strings.TrimSpace(info.Icon)
+
+    seen := make(map[string]bool, len(info.Tags))
+    tags := make([]string, 0, len(info.Tags))
+    for _, tag := range info.Tags {
+        tag = strings.ToLower(strings.TrimSpace(tag))
+        if tag != "" && !seen[tag] {
+            seen[tag] = true
+            tags = append(tags, tag)
+        }
+    }
+    if len(tags) == 0 {
+        tags = nil
+    }
+    info.Tags = tags
+}
+
+// Validate rejects fields that are too long to be reasonable
+func (info RoomInfo) Validate() error {
+    if len([]rune(info.Name)) > maxRoomNameLength {
+        return fmt.Errorf("name must be at most %d characters", maxRoomNameLength)
+    }
+    if len([]rune(info.Description)) > maxRoomDescriptionLength {
+        return fmt.Errorf("description must be at most %d characters", maxRoomDescriptionLength)
+    }
+    if len(info.Icon) > maxRoomIconLength {
+        return fmt.Errorf("icon must be at most %d bytes", maxRoomIconLength)
+    }
+    if len(info.Tags) > maxRoomTags {
+        return fmt.Errorf("at most %d tags", maxRoomTags)
+    }
+    for _, tag := range info.Tags {
+        if len([]rune(tag)) > maxRoomTagLength {
+            return fmt.Errorf("tags must be at most %d characters", maxRoomTagLength)
+        }
+    }
+    return nil
+}
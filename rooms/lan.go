@@ -0,0 +1,48 @@
+package rooms
+
+import (
+    "fmt"
+    "net/netip"
+    "sort"
+    "strings"
+)
+
+// MaxLocalCandidates bounds the local addresses a peer may offer
+const MaxLocalCandidates = 8
+
+// ValidLocalCandidate checks a client-reported local address: a private,
+// link-local or loopback IP, or a browser's obfuscated mDNS name ending in
+// .local, with a port
+func ValidLocalCandidate(candidate string) error {
+    if len(candidate) > 128 {
+        return fmt.Errorf("candidate %.32q... is too long", candidate)
+    }
+    if ap, err := netip.ParseAddrPort(candidate); err == nil {
+        addr := ap.Addr().Unmap()
+        if ap.Port() == 0 || !(addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLoopback()) {
+            return fmt.Errorf("%q is not a local address", candidate)
+        }
+        return nil
+    }
+    host, port, ok := strings.Cut(candidate, ":")
+    if !ok || !strings.HasSuffix(host, ".local") || len(host) <= len(".local") || port == "" || port == "0" || strings.Trim(port, "0123456789") != "" {
+        return fmt.Errorf("%q is not a local address", candidate)
+    }
+    return nil
+}
+
+// SameNetwork returns the other peers whose network hash matches, sorted.
+// The caller must hold the room lock.
+func (r *Room) SameNetwork(peerID, network string) []string {
+    var peers []string
+    if network == "" {
+        return peers
+    }
+    for id, peer := range r.Peers {
+        if id != peerID && peer.Network == network && !peer.Disconnected() {
+            peers = append(peers, id)
+        }
+    }
+    sort.Strings(peers)
+    return peers
+}
@@ -0,0 +1,40 @@
+package rooms
+
+import "sort"
+
+// PeerFilter narrows a peer listing
+type PeerFilter struct {
+    Presence    string
+    JoinedAfter int64
+    // Exclude hides peers by ID, e.g. the ones the viewer has blocked
+    Exclude map[string]bool
+}
+
+func (f PeerFilter) match(peer *PeerMetadata) bool {
+    if f.Exclude[peer.PeerID] {
+        return false
+    }
+    if f.Presence != "" && peer.Presence != f.Presence {
+        return false
+    }
+    return f.JoinedAfter == 0 || peer.JoinedAt > f.JoinedAfter
+}
+
+// Page returns up to limit peers ordered by ID, starting after cursor.
+// next is empty on the last page.
+func (s *PeerSnapshot) Page(filter PeerFilter, cursor string, limit int) (page []PeerMetadata, next string) {
+    start := sort.Search(len(s.Peers), func(i int) bool { return s.Peers[i].PeerID > cursor })
+
+    page = make([]PeerMetadata, 0, min(limit, len(s.Peers)-start))
+    for i := start; i < len(s.Peers); i++ {
+        if !filter.match(&s.Peers[i]) {
+            continue
+        }
+        if len(page) == limit {
+            next = page[limit-1].PeerID
+            break
+        }
+        page = append(page, s.Peers[i])
+    }
+    return page, next
+}
@@ -0,0 +1,71 @@
+package rooms
+
+// maxDepartures bounds how many leave tombstones a room remembers; clients
+// that fall further behind get a full peer list instead of a delta
+const maxDepartures = 256
+
+// peerChanges tracks the version at which each peer last changed, so
+// GET /room/:roomCode/peers?since=N can answer with a delta
+type peerChanges struct {
+    present  map[string]uint64
+    departed map[string]uint64
+    // floor is the newest version whose tombstone has been discarded
+    floor uint64
+}
+
+// TouchPeer bumps the room version and records that peerID joined or
+// changed. The caller must hold the room lock.
+func (r *Room) TouchPeer(peerID string) {
+    r.Touch()
+    if r.changes.present == nil {
+        r.changes.present = make(map[string]uint64)
+        r.changes.departed = make(map[string]uint64)
+    }
+    r.changes.present[peerID] = r.Version
+    delete(r.changes.departed, peerID)
+}
+
+// recordDeparture remembers that peerID left at the current version. The
+// caller must hold the room lock.
+func (r *Room) recordDeparture(peerID string) {
+    if r.changes.present == nil {
+        return
+    }
+    delete(r.changes.present, peerID)
+    r.changes.departed[peerID] = r.Version
+
+    for len(r.changes.departed) > maxDepartures {
+        oldestID, oldest := "", uint64(0)
+        for id, v := range r.changes.departed {
+            if oldestID == "" || v < oldest {
+                oldestID, oldest = id, v
+            }
+        }
+        delete(r.changes.departed, oldestID)
+        if oldest > r.changes.floor {
+            r.changes.floor = oldest
+        }
+    }
+}
+
+// PeerDelta returns peers that joined or changed after since, and the IDs of
+// peers that left. ok is false when since predates the retained history. The
+// caller must hold the room lock.
+func (r *Room) PeerDelta(since uint64) (changed []PeerMetadata, left []string, ok bool) {
+    if since < r.changes.floor {
+        return nil, nil, false
+    }
+    changed = make([]PeerMetadata, 0)
+    left = make([]string, 0)
+    for peerID, v := range r.changes.present {
+        if peer, exists := r.Peers[peerID]; exists && v > since {
+            changed = append(changed, *peer)
+        }
+    }
+    for peerID, v := range r.changes.departed {
+        if v > since {
+            left = append(left, peerID)
+        }
+    }
+    return changed, left, true
+}
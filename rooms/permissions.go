@@ -0,0 +1,71 @@
+package rooms
+
+// Room modes decide the default permissions given to guests
+const (
+    ModeOpen         = "open"         // everyone may send and receive
+    ModeDropbox      = "dropbox"      // guests may only send to the host
+    ModeDistribution = "distribution" // only the host may offer files
+)
+
+// Peer roles
+const (
+    RoleHost  = "host"
+    RoleGuest = "guest"
+)
+
+// Peer permissions
+const (
+    PermissionsFull        = "full"
+    PermissionsSendOnly    = "send-only"
+    PermissionsReceiveOnly = "receive-only"
+)
+
+// ValidMode reports whether mode is a known room mode
+func ValidMode(mode string) bool {
+    switch mode {
+    case ModeOpen, ModeDropbox, ModeDistribution:
+        return true
+    }
+    return false
+}
+
+// ValidPermissions reports whether p is a known permission level
+func ValidPermissions(p string) bool {
+    switch p {
+    case PermissionsFull, PermissionsSendOnly, PermissionsReceiveOnly:
+        return true
+    }
+    return false
+}
+
+// DefaultAccess returns the role and permissions a peer gets on joining.
+// The caller must hold the room lock.
+func (r *Room) DefaultAccess(peerID string) (string, string) {
+    if peerID == r.HostID {
+        return RoleHost, PermissionsFull
+    }
+
+    switch r.Mode {
+    case ModeDropbox:
+        return RoleGuest, PermissionsSendOnly
+    case ModeDistribution:
+        return RoleGuest, PermissionsReceiveOnly
+    default:
+        return RoleGuest, PermissionsFull
+    }
+}
+
+// CanSend reports whether the peer may offer files
+func (p *PeerMetadata) CanSend() bool {
+    return p.Permissions != PermissionsReceiveOnly
+}
+
+// CanReceive reports whether the peer may be sent files
+func (p *PeerMetadata) CanReceive() bool {
+    return p.Permissions != PermissionsSendOnly
+}
+
+// CanTransfer reports whether files may flow from one peer to another
+func CanTransfer(from, to *PeerMetadata) bool {
+    return from.CanSend() && to.CanReceive()
+}
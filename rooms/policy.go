@@ -0,0 +1,124 @@
+package rooms
+
+import (
+    "errors"
+    "fmt"
+    "mime"
+    "path"
+    "strings"
+)
+
+// FilePolicy limits the files that may be offered in a room. Zero values and
+// empty lists impose no limit. When both lists are set, a file must match
+// both.
+type FilePolicy struct {
+    MaxFileSize int64 `json:"maxFileSize,omitempty"`
+    MaxFiles    int   `json:"maxFiles,omitempty"`
+    // AllowedTypes are MIME types, or wildcards like "image/*"
+    AllowedTypes []string `json:"allowedTypes,omitempty"`
+    // AllowedExtensions are file name extensions like ".pdf"
+    AllowedExtensions []string `json:"allowedExtensions,omitempty"`
+}
+
+// PolicyViolation says which rule of a FilePolicy a file broke
+type PolicyViolation struct {
+    Rule    string
+    Message string
+}
+
+func (v *PolicyViolation) Error() string {
+    return v.Message
+}
+
+// Normalize lowercases types and extensions and gives extensions a leading
+// dot
+func (p *FilePolicy) Normalize() {
+    for i, t := range p.AllowedTypes {
+        p.AllowedTypes[i] = strings.ToLower(strings.TrimSpace(t))
+    }
+    for i, ext := range p.AllowedExtensions {
+        ext = strings.ToLower(strings.TrimSpace(ext))
+        if ext != "" && !strings.HasPrefix(ext, ".") {
+            ext = "." + ext
+        }
+        p.AllowedExtensions[i] = ext
+    }
+}
+
+// Validate rejects negative limits and malformed types
+func (p FilePolicy) Validate() error {
+    if p.MaxFileSize < 0 || p.MaxFiles < 0 {
+        return errors.New("maxFileSize and maxFiles must not be negative")
+    }
+    for _, t := range p.AllowedTypes {
+        major, minor, ok := strings.Cut(t, "/")
+        if !ok || major == "" || minor == "" {
+            return fmt.Errorf("invalid MIME type %q", t)
+        }
+    }
+    for _, ext := range p.AllowedExtensions {
+        if len(ext) < 2 {
+            return fmt.Errorf("invalid extension %q", ext)
+        }
+    }
+    return nil
+}
+
+// Check decides whether a file may be offered in a room that already holds
+// the given number of offers
+func (p FilePolicy) Check(name, mimeType string, size int64, files int) *PolicyViolation {
+    if p.MaxFileSize > 0 && size > p.MaxFileSize {
+        return &PolicyViolation{Rule: "maxFileSize", Message: fmt.Sprintf("Files may be at most %d bytes", p.MaxFileSize)}
+    }
+    if p.MaxFiles > 0 && files >= p.MaxFiles {
+        return &PolicyViolation{Rule: "maxFiles", Message: fmt.Sprintf("The room already has its maximum of %d files", p.MaxFiles)}
+    }
+    if len(p.AllowedTypes) > 0 && !p.typeAllowed(mimeType) {
+        return &PolicyViolation{Rule: "allowedTypes", Message: "This file type is not allowed in the room"}
+    }
+    if len(p.AllowedExtensions) > 0 && !p.extensionAllowed(name) {
+        return &PolicyViolation{Rule: "allowedExtensions", Message: "This file extension is not allowed in the room"}
+    }
+    return nil
+}
+
+// CheckBundle decides whether a folder bundle may be offered. The bundle is
+// one offer whose total size counts against the size limit, while the type
+// and extension rules apply to each of its entries.
+func (p FilePolicy) CheckBundle(entries []BundleEntry, size int64, files int) *PolicyViolation {
+    whole := p
+    whole.AllowedTypes, whole.AllowedExtensions = nil, nil
+    if v := whole.Check("", "", size, files); v != nil {
+        return v
+    }
+    for _, e := range entries {
+        if v := p.Check(e.Path, e.MimeType, 0, 0); v != nil {
+            return &PolicyViolation{Rule: v.Rule, Message: v.Message + ": " + e.Path}
+        }
+    }
+    return nil
+}
+
+func (p FilePolicy) typeAllowed(mimeType string) bool {
+    mediaType, _, err := mime.ParseMediaType(mimeType)
+    if err != nil {
+        return false
+    }
+    major, _, _ := strings.Cut(mediaType, "/")
+    for _, t := range p.AllowedTypes {
+        if t == mediaType || t == major+"/*" {
+            return true
+        }
+    }
+    return false
+}
+
+func (p FilePolicy) extensionAllowed(name string) bool {
+    ext := strings.ToLower(path.Ext(name))
+    for _, allowed := range p.AllowedExtensions {
+        if ext == allowed {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,98 @@
+package rooms
+
+// Presence states a peer can report
+const (
+    PresenceOnline       = "online"
+    PresenceIdle         = "idle"
+    PresenceTransferring = "transferring"
+    PresenceAway         = "away"
+
+    // PresenceDisconnected is set by the server for a peer in its grace
+    // period after leaving; clients cannot report it
+    PresenceDisconnected = "disconnected"
+)
+
+// ValidPresence reports whether p is a known presence state
+func ValidPresence(p string) bool {
+    switch p {
+    case PresenceOnline, PresenceIdle, PresenceTransferring, PresenceAway:
+        return true
+    }
+    return false
+}
+
+// Disconnected reports whether the peer is in its grace period after leaving
+func (p *PeerMetadata) Disconnected() bool {
+    return p.DisconnectedAt != 0
+}
+
+// DisconnectPeer marks a peer disconnected at ts, keeping its files, role and
+// permissions so it can pick up where it left off. It reports whether the
+// peer was connected. The caller must hold the room lock.
+func (r *Room) DisconnectPeer(peerID string, ts int64) bool {
+    peer, ok := r.Peers[peerID]
+    if !ok || peer.Disconnected() {
+        return false
+    }
+    peer.DisconnectedAt = ts
+    peer.Presence = PresenceDisconnected
+    r.TouchPeer(peerID)
+    return true
+}
+
+// ReconnectPeer brings a disconnected peer back online and reports whether
+// it was disconnected. The caller must hold the room lock.
+func (r *Room) ReconnectPeer(peerID string, ts int64) bool {
+    peer, ok := r.Peers[peerID]
+    if !ok || !peer.Disconnected() {
+        return false
+    }
+    peer.DisconnectedAt = 0
+    peer.Presence = PresenceOnline
+    peer.LastSeen.Store(ts)
+    r.TouchPeer(peerID)
+    return true
+}
+
+// ConnectedPeers returns the IDs of peers not in their grace period, except
+// exceptPeer. The caller must hold the room lock.
+func (r *Room) ConnectedPeers(exceptPeer string) []string {
+    peers := make([]string, 0, len(r.Peers))
+    for peerID, peer := range r.Peers {
+        if peerID != exceptPeer && !peer.Disconnected() {
+            peers = append(peers, peerID)
+        }
+    }
+    return peers
+}
+
+// RenamePeer moves a peer's membership, role, permissions, files and host
+// status to newID, for a peer resuming its session under a new ID. It fails
+// if oldID is not in the room or newID already is. The caller must hold the
+// room lock.
+func (r *Room) RenamePeer(oldID, newID string) bool {
+    peer, ok := r.Peers[oldID]
+    if !ok {
+        return false
+    }
+    if _, taken := r.Peers[newID]; taken {
+        return false
+    }
+
+    delete(r.Peers, oldID)
+    r.Touch()
+    r.recordDeparture(oldID)
+
+    peer.PeerID = newID
+    r.Peers[newID] = peer
+    for _, file := range r.Files {
+        if file.PeerID == oldID {
+            file.PeerID = newID
+        }
+    }
+    if r.HostID == oldID {
+        r.HostID = newID
+    }
+    r.TouchPeer(newID)
+    return true
+}
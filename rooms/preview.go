@@ -0,0 +1,64 @@
+package rooms
+
+import (
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "net/http"
+)
+
+// Preview bounds
+const (
+    DefaultMaxPreviewBytes = 16 * 1024
+    MaxPreviewDimension    = 512
+)
+
+// previewTypes are the image formats a preview may be in. Anything that
+// could run script, like SVG, is left out, since clients display previews
+// before the file is accepted.
+var previewTypes = map[string]bool{
+    "image/png":  true,
+    "image/jpeg": true,
+    "image/webp": true,
+    "image/gif":  true,
+}
+
+// Preview is a small image a sender attaches to a file offer, like a
+// thumbnail of a photo or a render of a document's first page, so receivers
+// can see what they are about to accept
+type Preview struct {
+    MimeType string `json:"mimeType"`
+    // Data is the image, base64 encoded
+    Data   string `json:"data"`
+    Width  int    `json:"width,omitempty"`
+    Height int    `json:"height,omitempty"`
+}
+
+// Normalize checks the preview is an image of an allowed type, at most
+// maxBytes once decoded, whose content matches its declared type, and
+// re-encodes its data in standard base64
+func (p *Preview) Normalize(maxBytes int) error {
+    if !previewTypes[p.MimeType] {
+        return fmt.Errorf("previews must be PNG, JPEG, WebP or GIF images, not %q", p.MimeType)
+    }
+    if base64.StdEncoding.DecodedLen(len(p.Data)) > maxBytes+2 {
+        return fmt.Errorf("previews may be at most %d bytes", maxBytes)
+    }
+    raw, err := base64.StdEncoding.DecodeString(p.Data)
+    if err != nil {
+        if raw, err = base64.RawStdEncoding.DecodeString(p.Data); err != nil {
+            return errors.New("preview data must be base64")
+        }
+    }
+    if len(raw) == 0 || len(raw) > maxBytes {
+        return fmt.Errorf("previews must be 1 to %d bytes", maxBytes)
+    }
+    if sniffed := http.DetectContentType(raw); sniffed != p.MimeType {
+        return fmt.Errorf("preview data is %s, not %s", sniffed, p.MimeType)
+    }
+    if p.Width < 0 || p.Height < 0 || p.Width > MaxPreviewDimension || p.Height > MaxPreviewDimension {
+        return fmt.Errorf("preview dimensions must be at most %dx%d", MaxPreviewDimension, MaxPreviewDimension)
+    }
+    p.Data = base64.StdEncoding.EncodeToString(raw)
+    return nil
+}
@@ -0,0 +1,32 @@
+package rooms
+
+import "fmt"
+
+const (
+    maxDisplayNameLength  = 64
+    maxProfileFieldLength = 128
+)
+
+// Validate rejects profile fields that are too long to be reasonable
+func (p PeerProfile) Validate() error {
+    if len([]rune(p.DisplayName)) > maxDisplayNameLength {
+        return fmt.Errorf("displayName must be at most %d characters", maxDisplayNameLength)
+    }
+
+    fields := []struct{ name, value string }{
+        {"avatarHash", p.AvatarHash},
+        {"platform", p.Platform},
+        {"clientVersion", p.ClientVersion},
+    }
+    for _, f := range fields {
+        if len(f.value) > maxProfileFieldLength {
+            return fmt.Errorf("%s must be at most %d characters", f.name, maxProfileFieldLength)
+        }
+    }
+
+    if p.Capabilities != nil {
+        return p.Capabilities.Validate()
+    }
+
+    return nil
+}
@@ -0,0 +1,224 @@
+// Package rooms holds the in-memory room model: rooms, their peers and the
+// files they offer, plus the rules deciding who may send to whom.
+package rooms
+
+import (
+    "sync"
+    "sync/atomic"
+)
+
+// PeerProfile holds optional, client-supplied details about a peer
+type PeerProfile struct {
+    DisplayName   string `json:"displayName,omitempty"`
+    AvatarHash    string `json:"avatarHash,omitempty"`
+    Platform      string `json:"platform,omitempty"`
+    ClientVersion string `json:"clientVersion,omitempty"`
+
+    Capabilities *PeerCapabilities `json:"capabilities,omitempty"`
+}
+
+// PeerMetadata stores peer information
+type PeerMetadata struct {
+    PeerID   string `json:"peerId"`
+    JoinedAt int64  `json:"joinedAt"`
+    LastSeen *Clock `json:"lastSeen"`
+    Presence string `json:"presence"`
+    PeerProfile
+
+    Role        string `json:"role"`
+    Permissions string `json:"permissions"`
+
+    // DisconnectedAt is when the peer left or went stale, while it is kept
+    // in the room for a grace period in case it comes back; zero otherwise
+    DisconnectedAt int64 `json:"disconnectedAt,omitempty"`
+
+    // AddressFamily is the IP version ("ipv4" or "ipv6") the peer joined
+    // over, so clients can tell which pairs need a relay
+    AddressFamily string `json:"addressFamily,omitempty"`
+
+    // Network is a keyed hash of the peer's public IP, set when it asks for
+    // LAN hints, and LocalCandidates the local addresses it offered to peers
+    // on the same network. Neither is ever shown or persisted.
+    Network         string   `json:"-"`
+    LocalCandidates []string `json:"-"`
+}
+
+// FileOffer describes a file a peer is offering to the room. The file itself
+// is transferred peer-to-peer; only the manifest lives on the server, unless
+// the owner uploads it to be relayed.
+type FileOffer struct {
+    FileID    string `json:"fileId"`
+    PeerID    string `json:"peerId"`
+    Name      string `json:"name"`
+    Size      int64  `json:"size"`
+    MimeType  string `json:"mimeType,omitempty"`
+    OfferedAt int64  `json:"offeredAt"`
+
+    // Relay is the state of a copy uploaded for relaying, if any
+    Relay string `json:"relay,omitempty"`
+
+    // Entries make the offer a folder bundle: the files in it, by path
+    // relative to the folder named Name. Size is their total.
+    Entries []BundleEntry `json:"entries,omitempty"`
+    // Preview is an optional thumbnail of the file
+    Preview *Preview `json:"preview,omitempty"`
+
+    // Integrity is set once the sender publishes the file's hashes
+    Integrity *Integrity `json:"integrity,omitempty"`
+}
+
+// Relay states of a file offer
+const (
+    // RelayUploading is a direct upload to the object store in progress
+    RelayUploading   = "uploading"
+    RelayScanning    = "scanning"
+    RelayAvailable   = "available"
+    RelayQuarantined = "quarantined"
+)
+
+// MaxFileNameLength bounds the name of an offered file
+const MaxFileNameLength = 255
+
+// Room stores peers in a room. Its embedded lock guards every field.
+type Room struct {
+    Peers map[string]*PeerMetadata
+    Files map[string]*FileOffer
+    // Transfers follow each receiver's copy of a file, by file and then
+    // receiver ID. They are not persisted.
+    Transfers map[string]map[string]*Transfer
+    HostID    string
+    Mode      string
+    Info      RoomInfo
+    sync.RWMutex
+
+    // CreatorIP is used to enforce per-IP room quotas
+    CreatorIP string
+    CreatedAt int64
+
+    // Suspended rooms are frozen pending moderator review
+    Suspended bool
+    // Locked rooms admit no new peers; members can still rejoin
+    Locked bool
+    // WaitingRoom rooms hold new peers in Pending until the host approves
+    // them
+    WaitingRoom bool
+    Pending     map[string]*PendingPeer
+    // Schedule, if set, limits when new peers can join; the room is kept
+    // while empty until its last session is over
+    Schedule *Schedule
+    // Pinned rooms are kept while empty, e.g. as a standing drop zone
+    Pinned bool
+
+    // Tenant is the frontend the room was created from; its operator's
+    // file policy applies on top of the room's own
+    Tenant string
+    Policy FilePolicy
+
+    // Version changes whenever the room's peers or files do
+    Version  uint64
+    changes  peerChanges
+    snapshot atomic.Pointer[PeerSnapshot]
+    // events are kept for replay to reconnecting clients; not persisted
+    events eventLog
+}
+
+// New returns an empty room
+func New(hostID, mode, creatorIP string, createdAt int64) *Room {
+    return &Room{
+        Peers:     make(map[string]*PeerMetadata),
+        Files:     make(map[string]*FileOffer),
+        Transfers: make(map[string]map[string]*Transfer),
+        Pending:   make(map[string]*PendingPeer),
+        HostID:    hostID,
+        Mode:      mode,
+        CreatorIP: creatorIP,
+        CreatedAt: createdAt,
+        events:    eventLog{floor: eventSeqs.Load()},
+    }
+}
+
+// versions hands out room versions. A single counter keeps versions unique
+// across rooms, so a room recreated under the same code never reuses an ETag
+// from its previous life.
+var versions atomic.Uint64
+
+// Touch bumps the room version after a change to its peers or files and
+// discards the peer snapshot. The caller must hold the room lock.
+func (r *Room) Touch() {
+    r.Version = versions.Add(1)
+    r.snapshot.Store(nil)
+}
+
+// Abandoned reports whether the room can be deleted: it has no peers, is not
+// pinned and has no scheduled session still to come. The caller must hold
+// the room lock.
+func (r *Room) Abandoned(now int64) bool {
+    if len(r.Peers) > 0 || r.Pinned {
+        return false
+    }
+    if r.Schedule != nil {
+        _, _, upcoming := r.Schedule.Session(now)
+        return !upcoming
+    }
+    return true
+}
+
+// RemovePeer drops a peer and any files it was offering. The caller must
+// hold the room lock.
+func (r *Room) RemovePeer(peerID string) {
+    if _, ok := r.Peers[peerID]; !ok {
+        return
+    }
+    delete(r.Peers, peerID)
+    r.Touch()
+    r.recordDeparture(peerID)
+    for fileID, file := range r.Files {
+        if file.PeerID == peerID {
+            delete(r.Files, fileID)
+            delete(r.Transfers, fileID)
+        }
+    }
+    for _, transfers := range r.Transfers {
+        delete(transfers, peerID)
+    }
+}
+
+// VisibleFiles returns the offers a peer is allowed to see. The caller must
+// hold the room lock.
+func (r *Room) VisibleFiles(viewerID string) []FileOffer {
+    viewer := r.Peers[viewerID]
+    files := make([]FileOffer, 0, len(r.Files))
+    for _, file := range r.Files {
+        if r.fileVisible(file, viewer, viewerID) {
+            files = append(files, *file)
+        }
+    }
+    return files
+}
+
+// FileRecipients returns the peers, other than its owner, allowed to
+// receive a file. The caller must hold the room lock.
+func (r *Room) FileRecipients(file *FileOffer) []string {
+    owner, ok := r.Peers[file.PeerID]
+    if !ok {
+        return nil
+    }
+    recipients := make([]string, 0, len(r.Peers))
+    for peerID, peer := range r.Peers {
+        if peerID != file.PeerID && CanTransfer(owner, peer) {
+            recipients = append(recipients, peerID)
+        }
+    }
+    return recipients
+}
+
+// FileVisible reports whether a peer is allowed to see an offer. The caller
+// must hold the room lock.
+func (r *Room) FileVisible(file *FileOffer, viewerID string) bool {
+    return r.fileVisible(file, r.Peers[viewerID], viewerID)
+}
+
+func (r *Room) fileVisible(file *FileOffer, viewer *PeerMetadata, viewerID string) bool {
+    owner, ok := r.Peers[file.PeerID]
+    return file.PeerID == viewerID || (ok && viewer != nil && CanTransfer(owner, viewer))
+}
@@ -0,0 +1,154 @@
+package rooms
+
+import (
+    "errors"
+    "fmt"
+    "strconv"
+    "time"
+
+    // Recurring sessions follow the host's wall clock, so zone data must be
+    // available even on images without /usr/share/zoneinfo
+    _ "time/tzdata"
+)
+
+// Recurrence frequencies for scheduled rooms
+const (
+    FrequencyDaily  = "daily"
+    FrequencyWeekly = "weekly"
+)
+
+// MaxSessionDuration bounds how long one scheduled session stays open
+const MaxSessionDuration = 24 * 60 * 60
+
+// Schedule says when a scheduled room admits peers: a first session at
+// StartsAt lasting Duration seconds, optionally repeating every Interval
+// days or weeks in TimeZone, so a 9:00 class stays at 9:00 across daylight
+// saving changes. Count and Until each optionally end the series.
+type Schedule struct {
+    StartsAt int64  `json:"startsAt"`
+    Duration int64  `json:"duration"`
+    TimeZone string `json:"timeZone"`
+
+    Frequency string `json:"frequency,omitempty"`
+    Interval  int    `json:"interval,omitempty"`
+    Count     int    `json:"count,omitempty"`
+    Until     int64  `json:"until,omitempty"`
+}
+
+// Validate checks the schedule and fills in the default time zone and
+// interval
+func (s *Schedule) Validate() error {
+    if s.Duration <= 0 || s.Duration > MaxSessionDuration {
+        return fmt.Errorf("sessions must last more than zero and at most %d hours", MaxSessionDuration/3600)
+    }
+    if s.TimeZone == "" {
+        s.TimeZone = "UTC"
+    }
+    if _, err := time.LoadLocation(s.TimeZone); err != nil {
+        return fmt.Errorf("unknown time zone %q", s.TimeZone)
+    }
+
+    switch s.Frequency {
+    case "":
+        if s.Interval != 0 || s.Count != 0 || s.Until != 0 {
+            return errors.New("interval, count and until need a recurrence frequency")
+        }
+        return nil
+    case FrequencyDaily, FrequencyWeekly:
+    default:
+        return errors.New("frequency must be daily or weekly")
+    }
+    if s.Interval < 0 || s.Count < 0 {
+        return errors.New("interval and count must not be negative")
+    }
+    if s.Interval == 0 {
+        s.Interval = 1
+    }
+    if s.Duration > int64(s.stepDays())*24*60*60 {
+        return errors.New("sessions must not overlap the next one")
+    }
+    if s.Until != 0 && s.Until < s.StartsAt {
+        return errors.New("until must not be before the first session")
+    }
+    return nil
+}
+
+func (s *Schedule) location() *time.Location {
+    loc, err := time.LoadLocation(s.TimeZone)
+    if err != nil {
+        return time.UTC
+    }
+    return loc
+}
+
+func (s *Schedule) stepDays() int {
+    if s.Frequency == FrequencyWeekly {
+        return 7 * s.Interval
+    }
+    return s.Interval
+}
+
+// start returns when the k-th session (from 0) starts, and false past the
+// end of the series
+func (s *Schedule) start(k int) (int64, bool) {
+    if s.Count > 0 && k >= s.Count {
+        return 0, false
+    }
+    t := time.Unix(s.StartsAt, 0).In(s.location()).AddDate(0, 0, k*s.stepDays()).Unix()
+    if s.Until != 0 && t > s.Until {
+        return 0, false
+    }
+    return t, true
+}
+
+// Session returns the session in progress at now or, failing that, the next
+// one. ok is false once the series is over.
+func (s *Schedule) Session(now int64) (start, end int64, ok bool) {
+    if s.Frequency == "" {
+        return s.StartsAt, s.StartsAt + s.Duration, now < s.StartsAt+s.Duration
+    }
+
+    // Jump close to now, then step; one step back absorbs DST shifts
+    k := 0
+    if elapsed := now - s.StartsAt - s.Duration; elapsed > 0 {
+        k = int(elapsed/(int64(s.stepDays())*24*60*60)) - 1
+        if k < 0 {
+            k = 0
+        }
+    }
+    for ; ; k++ {
+        start, ok := s.start(k)
+        if !ok {
+            return 0, 0, false
+        }
+        if start+s.Duration > now {
+            return start, start + s.Duration, true
+        }
+    }
+}
+
+// Open reports whether a session is in progress at now
+func (s *Schedule) Open(now int64) bool {
+    start, _, ok := s.Session(now)
+    return ok && start <= now
+}
+
+// RRule returns the iCalendar recurrence rule for the series, or "" for a
+// one-off session
+func (s *Schedule) RRule() string {
+    if s.Frequency == "" {
+        return ""
+    }
+    rule := "FREQ=DAILY"
+    if s.Frequency == FrequencyWeekly {
+        rule = "FREQ=WEEKLY"
+    }
+    rule += ";INTERVAL=" + strconv.Itoa(s.Interval)
+    if s.Count > 0 {
+        rule += ";COUNT=" + strconv.Itoa(s.Count)
+    }
+    if s.Until != 0 {
+        rule += ";UNTIL=" + time.Unix(s.Until, 0).UTC().Format("20060102T150405Z")
+    }
+    return rule
+}
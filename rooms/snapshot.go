@@ -0,0 +1,94 @@
+package rooms
+
+import (
+    "sort"
+    "strconv"
+    "sync/atomic"
+)
+
+// Clock is a Unix timestamp that can be updated without the room lock. Copies
+// of a PeerMetadata share their Clock, so a snapshot always reports a peer's
+// latest LastSeen.
+type Clock struct {
+    v atomic.Int64
+}
+
+// NewClock returns a Clock set to ts
+func NewClock(ts int64) *Clock {
+    c := &Clock{}
+    c.v.Store(ts)
+    return c
+}
+
+// Load returns the timestamp; a nil Clock reads as zero
+func (c *Clock) Load() int64 {
+    if c == nil {
+        return 0
+    }
+    return c.v.Load()
+}
+
+// Store sets the timestamp
+func (c *Clock) Store(ts int64) {
+    c.v.Store(ts)
+}
+
+func (c *Clock) MarshalJSON() ([]byte, error) {
+    return strconv.AppendInt(nil, c.Load(), 10), nil
+}
+
+func (c *Clock) UnmarshalJSON(data []byte) error {
+    ts, err := strconv.ParseInt(string(data), 10, 64)
+    if err != nil {
+        return err
+    }
+    c.v.Store(ts)
+    return nil
+}
+
+// PeerSnapshot is an immutable view of a room's peers at one version, shared
+// by readers without locking. Peers are sorted by ID and must not be
+// modified, apart from their LastSeen clocks.
+type PeerSnapshot struct {
+    Version uint64
+    Peers   []PeerMetadata
+}
+
+// Snapshot returns the room's current peer snapshot, building it on first
+// use after a change. Touch discards it, so it is never older than the
+// room's version.
+func (r *Room) Snapshot() *PeerSnapshot {
+    if snap := r.snapshot.Load(); snap != nil {
+        return snap
+    }
+
+    // Built and published under the read lock so a concurrent Touch can't be
+    // overwritten by a stale snapshot
+    r.RLock()
+    defer r.RUnlock()
+
+    if snap := r.snapshot.Load(); snap != nil {
+        return snap
+    }
+    snap := &PeerSnapshot{
+        Version: r.Version,
+        Peers:   make([]PeerMetadata, 0, len(r.Peers)),
+    }
+    for _, peer := range r.Peers {
+        snap.Peers = append(snap.Peers, *peer)
+    }
+    sort.Slice(snap.Peers, func(i, j int) bool {
+        return snap.Peers[i].PeerID < snap.Peers[j].PeerID
+    })
+    r.snapshot.Store(snap)
+    return snap
+}
+
+// Peer returns the snapshot's entry for peerID, or nil
+func (s *PeerSnapshot) Peer(peerID string) *PeerMetadata {
+    i := sort.Search(len(s.Peers), func(i int) bool { return s.Peers[i].PeerID >= peerID })
+    if i < len(s.Peers) && s.Peers[i].PeerID == peerID {
+        return &s.Peers[i]
+    }
+    return nil
+}
@@ -0,0 +1,220 @@
+package rooms
+
+import (
+    "cmp"
+    "slices"
+    "sync"
+    "testing"
+)
+
+// addPeer adds a peer as the join handlers do
+func addPeer(r *Room, peerID, presence string, joinedAt int64) {
+    r.Lock()
+    r.Peers[peerID] = &PeerMetadata{PeerID: peerID, JoinedAt: joinedAt, LastSeen: NewClock(joinedAt), Presence: presence}
+    r.TouchPeer(peerID)
+    r.Unlock()
+}
+
+func peerIDs(peers []PeerMetadata) []string {
+    ids := make([]string, len(peers))
+    for i, p := range peers {
+        ids[i] = p.PeerID
+    }
+    return ids
+}
+
+func TestSnapshot(t *testing.T) {
+    r := New("c", "", "", 0)
+    addPeer(r, "c", "online", 1)
+    addPeer(r, "a", "online", 2)
+
+    snap := r.Snapshot()
+    if r.Snapshot() != snap {
+        t.Error("unchanged room built a new snapshot")
+    }
+    if snap.Version != r.Version {
+        t.Errorf("snapshot version %d, room version %d", snap.Version, r.Version)
+    }
+    if got := peerIDs(snap.Peers); !slices.Equal(got, []string{"a", "c"}) {
+        t.Errorf("peers %v, want sorted [a c]", got)
+    }
+
+    // A heartbeat shows through the shared clock without a new snapshot
+    r.Peers["a"].LastSeen.Store(99)
+    if got := snap.Peer("a").LastSeen.Load(); got != 99 {
+        t.Errorf("snapshot LastSeen %d, want 99", got)
+    }
+
+    addPeer(r, "b", "away", 3)
+    next := r.Snapshot()
+    if next == snap {
+        t.Fatal("snapshot not rebuilt after a change")
+    }
+    if got := peerIDs(snap.Peers); !slices.Equal(got, []string{"a", "c"}) {
+        t.Errorf("old snapshot changed to %v", got)
+    }
+    if next.Version <= snap.Version {
+        t.Errorf("new snapshot version %d not after %d", next.Version, snap.Version)
+    }
+}
+
+func TestSnapshotPeer(t *testing.T) {
+    r := New("a", "", "", 0)
+    for _, id := range []string{"a", "c", "e"} {
+        addPeer(r, id, "online", 1)
+    }
+    snap := r.Snapshot()
+    tests := []struct {
+        peerID string
+        found  bool
+    }{
+        {"a", true},
+        {"c", true},
+        {"e", true},
+        {"", false},
+        {"b", false},
+        {"f", false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.peerID, func(t *testing.T) {
+            peer := snap.Peer(tt.peerID)
+            if (peer != nil) != tt.found {
+                t.Fatalf("Peer(%q) = %v, want found %v", tt.peerID, peer, tt.found)
+            }
+            if peer != nil && peer.PeerID != tt.peerID {
+                t.Errorf("Peer(%q) returned %q", tt.peerID, peer.PeerID)
+            }
+        })
+    }
+}
+
+func TestSnapshotPage(t *testing.T) {
+    r := New("a", "", "", 0)
+    addPeer(r, "a", "online", 1)
+    addPeer(r, "b", "away", 2)
+    addPeer(r, "c", "online", 3)
+    addPeer(r, "d", "online", 4)
+    addPeer(r, "e", "away", 5)
+    snap := r.Snapshot()
+
+    tests := []struct {
+        name     string
+        filter   PeerFilter
+        cursor   string
+        limit    int
+        want     []string
+        wantNext string
+    }{
+        {"first page", PeerFilter{}, "", 2, []string{"a", "b"}, "b"},
+        {"after cursor", PeerFilter{}, "b", 2, []string{"c", "d"}, "d"},
+        {"last page", PeerFilter{}, "d", 2, []string{"e"}, ""},
+        {"exact fit", PeerFilter{}, "c", 2, []string{"d", "e"}, ""},
+        {"cursor past the end", PeerFilter{}, "z", 2, []string{}, ""},
+        {"presence", PeerFilter{Presence: "online"}, "", 2, []string{"a", "c"}, "c"},
+        {"joined after", PeerFilter{JoinedAfter: 3}, "", 5, []string{"d", "e"}, ""},
+        {"exclude", PeerFilter{Exclude: map[string]bool{"a": true, "c": true}}, "", 5, []string{"b", "d", "e"}, ""},
+        {"combined", PeerFilter{Presence: "online", JoinedAfter: 1}, "", 1, []string{"c"}, "c"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            page, next := snap.Page(tt.filter, tt.cursor, tt.limit)
+            if got := peerIDs(page); !slices.Equal(got, tt.want) {
+                t.Errorf("page %v, want %v", got, tt.want)
+            }
+            if next != tt.wantNext {
+                t.Errorf("next %q, want %q", next, tt.wantNext)
+            }
+        })
+    }
+}
+
+func TestPeerDelta(t *testing.T) {
+    r := New("a", "", "", 0)
+    addPeer(r, "a", "online", 1)
+    v1 := r.Version
+    addPeer(r, "b", "online", 2)
+    v2 := r.Version
+    r.Lock()
+    r.RemovePeer("a")
+    r.Unlock()
+
+    tests := []struct {
+        name        string
+        since       uint64
+        wantChanged []string
+        wantLeft    []string
+    }{
+        {"from the start", 0, []string{"b"}, []string{"a"}},
+        {"after a joined", v1, []string{"b"}, []string{"a"}},
+        {"after b joined", v2, []string{}, []string{"a"}},
+        {"up to date", r.Version, []string{}, []string{}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r.RLock()
+            changed, left, ok := r.PeerDelta(tt.since)
+            r.RUnlock()
+            if !ok {
+                t.Fatal("delta refused")
+            }
+            if got := peerIDs(changed); !slices.Equal(got, tt.wantChanged) {
+                t.Errorf("changed %v, want %v", got, tt.wantChanged)
+            }
+            slices.Sort(left)
+            if !slices.Equal(left, tt.wantLeft) {
+                t.Errorf("left %v, want %v", left, tt.wantLeft)
+            }
+        })
+    }
+}
+
+func TestPeerDeltaFloor(t *testing.T) {
+    r := New("a", "", "", 0)
+    addPeer(r, "first", "online", 1)
+    since := r.Version
+    r.Lock()
+    r.RemovePeer("first")
+    r.Unlock()
+    for i := range maxDepartures + 1 {
+        id := string(rune('A'+i%26)) + string(rune('0'+i/26))
+        addPeer(r, id, "online", 1)
+        r.Lock()
+        r.RemovePeer(id)
+        r.Unlock()
+    }
+    r.RLock()
+    _, _, ok := r.PeerDelta(since)
+    r.RUnlock()
+    if ok {
+        t.Error("delta served from before the oldest retained departure")
+    }
+}
+
+// TestSnapshotConcurrent reads snapshots while peers join; run it with -race
+func TestSnapshotConcurrent(t *testing.T) {
+    r := New("a", "", "", 0)
+    var wg sync.WaitGroup
+    wg.Add(2)
+    go func() {
+        defer wg.Done()
+        for i := range 200 {
+            addPeer(r, string(rune('A'+i%26))+string(rune('0'+i/26)), "online", int64(i))
+        }
+    }()
+    go func() {
+        defer wg.Done()
+        for range 200 {
+            snap := r.Snapshot()
+            if !slices.IsSortedFunc(snap.Peers, func(a, b PeerMetadata) int {
+                return cmp.Compare(a.PeerID, b.PeerID)
+            }) {
+                t.Error("snapshot peers not sorted")
+                return
+            }
+        }
+    }()
+    wg.Wait()
+    if got := len(r.Snapshot().Peers); got != len(r.Peers) {
+        t.Errorf("final snapshot has %d peers, room has %d", got, len(r.Peers))
+    }
+}
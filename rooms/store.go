@@ -0,0 +1,174 @@
+package rooms
+
+import (
+    "hash/fnv"
+    "sync"
+)
+
+// shardCount is how many independently locked partitions the store is split
+// into, so operations on different rooms rarely wait on each other
+const shardCount = 64
+
+// Store is the set of live rooms, keyed by room code. Rooms are spread over
+// shards by a hash of their code. Callbacks run with the room's shard locked
+// and must not call back into the store.
+type Store struct {
+    shards [shardCount]shard
+
+    // countsMu guards the room census used by admission checks. It is
+    // always taken after a shard lock, never before.
+    countsMu  sync.Mutex
+    total     int
+    byCreator map[string]int
+}
+
+type shard struct {
+    mu    sync.RWMutex
+    rooms map[string]*Room
+}
+
+// Census is a snapshot of how many rooms exist, for admission checks made
+// while creating a room
+type Census struct {
+    Total     int
+    byCreator map[string]int
+}
+
+// CreatedBy returns how many live rooms were created from ip
+func (c Census) CreatedBy(ip string) int {
+    return c.byCreator[ip]
+}
+
+// NewStore returns an empty in-memory store
+func NewStore() *Store {
+    s := &Store{byCreator: make(map[string]int)}
+    for i := range s.shards {
+        s.shards[i].rooms = make(map[string]*Room)
+    }
+    return s
+}
+
+func (s *Store) shard(code string) *shard {
+    h := fnv.New32a()
+    h.Write([]byte(code))
+    return &s.shards[h.Sum32()%shardCount]
+}
+
+// forget removes a deleted room from the census. The caller must hold the
+// room's shard lock.
+func (s *Store) forget(room *Room) {
+    s.countsMu.Lock()
+    s.total--
+    if s.byCreator[room.CreatorIP]--; s.byCreator[room.CreatorIP] <= 0 {
+        delete(s.byCreator, room.CreatorIP)
+    }
+    s.countsMu.Unlock()
+}
+
+// Get returns the room with the given code
+func (s *Store) Get(code string) (*Room, bool) {
+    sh := s.shard(code)
+    sh.mu.RLock()
+    room, ok := sh.rooms[code]
+    sh.mu.RUnlock()
+    return room, ok
+}
+
+// Len returns the number of rooms
+func (s *Store) Len() int {
+    s.countsMu.Lock()
+    defer s.countsMu.Unlock()
+    return s.total
+}
+
+// Range calls fn for every room until it returns false. Each shard is
+// locked only while its own rooms are visited.
+func (s *Store) Range(fn func(code string, room *Room) bool) {
+    for i := range s.shards {
+        sh := &s.shards[i]
+        sh.mu.RLock()
+        for code, room := range sh.rooms {
+            if !fn(code, room) {
+                sh.mu.RUnlock()
+                return
+            }
+        }
+        sh.mu.RUnlock()
+    }
+}
+
+// Create returns the room with the given code, creating it with build if it
+// does not exist. build sees a census of every existing room, and creations
+// are serialized while it runs, so admission checks such as quotas cannot
+// race with other creations; an error from build is returned as is and
+// nothing is stored.
+func (s *Store) Create(code string, build func(census Census) (*Room, error)) (room *Room, created bool, err error) {
+    sh := s.shard(code)
+    sh.mu.Lock()
+    defer sh.mu.Unlock()
+
+    if room, ok := sh.rooms[code]; ok {
+        return room, false, nil
+    }
+
+    s.countsMu.Lock()
+    defer s.countsMu.Unlock()
+
+    room, err = build(Census{Total: s.total, byCreator: s.byCreator})
+    if err != nil {
+        return nil, false, err
+    }
+    sh.rooms[code] = room
+    s.total++
+    s.byCreator[room.CreatorIP]++
+    return room, true, nil
+}
+
+// Delete removes a room, returning it if it existed
+func (s *Store) Delete(code string) (*Room, bool) {
+    sh := s.shard(code)
+    sh.mu.Lock()
+    defer sh.mu.Unlock()
+
+    room, ok := sh.rooms[code]
+    if ok {
+        delete(sh.rooms, code)
+        s.forget(room)
+    }
+    return room, ok
+}
+
+// Update calls fn on one room with its shard locked, deleting the room if fn
+// returns true. It reports whether the room existed.
+func (s *Store) Update(code string, fn func(room *Room) (remove bool)) bool {
+    sh := s.shard(code)
+    sh.mu.Lock()
+    defer sh.mu.Unlock()
+
+    room, ok := sh.rooms[code]
+    if !ok {
+        return false
+    }
+    if fn(room) {
+        delete(sh.rooms, code)
+        s.forget(room)
+    }
+    return true
+}
+
+// Sweep calls fn on every room, one shard at a time, deleting each room for
+// which fn returns true. Only the shard being swept is locked, so requests
+// for rooms elsewhere carry on during a sweep.
+func (s *Store) Sweep(fn func(code string, room *Room) (remove bool)) {
+    for i := range s.shards {
+        sh := &s.shards[i]
+        sh.mu.Lock()
+        for code, room := range sh.rooms {
+            if fn(code, room) {
+                delete(sh.rooms, code)
+                s.forget(room)
+            }
+        }
+        sh.mu.Unlock()
+    }
+}
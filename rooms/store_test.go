@@ -0,0 +1,169 @@
+package rooms
+
+import (
+    "errors"
+    "fmt"
+    "sync"
+    "testing"
+)
+
+// newStoreRoom builds a room created from ip
+func newStoreRoom(ip string) func(Census) (*Room, error) {
+    return func(Census) (*Room, error) {
+        return New("host", "", ip, 0), nil
+    }
+}
+
+func TestStoreCreate(t *testing.T) {
+    errFull := errors.New("full")
+    tests := []struct {
+        name        string
+        existing    []string
+        code        string
+        build       func(Census) (*Room, error)
+        wantCreated bool
+        wantErr     error
+        wantLen     int
+    }{
+        {"new room", nil, "A", newStoreRoom("1.1.1.1"), true, nil, 1},
+        {"existing room is returned", []string{"A"}, "A", func(Census) (*Room, error) {
+            t.Error("build called for an existing room")
+            return nil, nil
+        }, false, nil, 1},
+        {"build error stores nothing", []string{"B"}, "A", func(Census) (*Room, error) {
+            return nil, errFull
+        }, false, errFull, 1},
+        {"census counts rooms and creators", []string{"B", "C"}, "A", func(c Census) (*Room, error) {
+            if c.Total != 2 || c.CreatedBy("1.1.1.1") != 2 || c.CreatedBy("2.2.2.2") != 0 {
+                return nil, fmt.Errorf("census %d rooms, %d by 1.1.1.1", c.Total, c.CreatedBy("1.1.1.1"))
+            }
+            return New("host", "", "2.2.2.2", 0), nil
+        }, true, nil, 3},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := NewStore()
+            for _, code := range tt.existing {
+                s.Create(code, newStoreRoom("1.1.1.1"))
+            }
+            room, created, err := s.Create(tt.code, tt.build)
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("err %v, want %v", err, tt.wantErr)
+            }
+            if created != tt.wantCreated {
+                t.Errorf("created %v, want %v", created, tt.wantCreated)
+            }
+            if got, ok := s.Get(tt.code); (tt.wantErr == nil) != ok || (ok && got != room) {
+                t.Errorf("Get(%q) = %p, %v; Create returned %p", tt.code, got, ok, room)
+            }
+            if s.Len() != tt.wantLen {
+                t.Errorf("Len() = %d, want %d", s.Len(), tt.wantLen)
+            }
+        })
+    }
+}
+
+func TestStoreRemoval(t *testing.T) {
+    tests := []struct {
+        name    string
+        remove  func(s *Store)
+        wantLen int
+        // wantCreatedBy is the census count for 1.1.1.1 afterwards
+        wantCreatedBy int
+    }{
+        {"delete", func(s *Store) { s.Delete("A") }, 2, 1},
+        {"delete missing", func(s *Store) { s.Delete("Z") }, 3, 2},
+        {"update keeps", func(s *Store) { s.Update("A", func(*Room) bool { return false }) }, 3, 2},
+        {"update removes", func(s *Store) { s.Update("A", func(*Room) bool { return true }) }, 2, 1},
+        {"sweep by creator", func(s *Store) {
+            s.Sweep(func(_ string, room *Room) bool { return room.CreatorIP == "1.1.1.1" })
+        }, 1, 0},
+        {"sweep nothing", func(s *Store) { s.Sweep(func(string, *Room) bool { return false }) }, 3, 2},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := NewStore()
+            s.Create("A", newStoreRoom("1.1.1.1"))
+            s.Create("B", newStoreRoom("1.1.1.1"))
+            s.Create("C", newStoreRoom("2.2.2.2"))
+            tt.remove(s)
+            if s.Len() != tt.wantLen {
+                t.Errorf("Len() = %d, want %d", s.Len(), tt.wantLen)
+            }
+            rooms := 0
+            s.Range(func(string, *Room) bool { rooms++; return true })
+            if rooms != tt.wantLen {
+                t.Errorf("Range visited %d rooms, want %d", rooms, tt.wantLen)
+            }
+            s.Create("D", func(c Census) (*Room, error) {
+                if got := c.CreatedBy("1.1.1.1"); got != tt.wantCreatedBy {
+                    t.Errorf("CreatedBy(1.1.1.1) = %d, want %d", got, tt.wantCreatedBy)
+                }
+                return New("host", "", "", 0), nil
+            })
+        })
+    }
+}
+
+func TestStoreUpdateMissing(t *testing.T) {
+    s := NewStore()
+    if s.Update("A", func(*Room) bool { t.Error("fn called for a missing room"); return false }) {
+        t.Error("Update reported a missing room as existing")
+    }
+}
+
+func TestStoreRangeStops(t *testing.T) {
+    s := NewStore()
+    for i := range 10 {
+        s.Create(fmt.Sprintf("R%d", i), newStoreRoom(""))
+    }
+    visited := 0
+    s.Range(func(string, *Room) bool {
+        visited++
+        return visited < 3
+    })
+    if visited != 3 {
+        t.Errorf("Range visited %d rooms after stopping at 3", visited)
+    }
+}
+
+// TestStoreConcurrent creates, updates and deletes rooms spread over the
+// shards from many goroutines; run it with -race
+func TestStoreConcurrent(t *testing.T) {
+    s := NewStore()
+    const workers, rooms = 8, 200
+    var wg sync.WaitGroup
+    for w := range workers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range rooms {
+                code := fmt.Sprintf("R%d", i)
+                s.Create(code, newStoreRoom(fmt.Sprintf("ip%d", w)))
+                s.Update(code, func(room *Room) bool {
+                    room.Lock()
+                    room.Touch()
+                    room.Unlock()
+                    return false
+                })
+                if i%2 == 0 {
+                    s.Delete(code)
+                }
+            }
+        }()
+    }
+    wg.Wait()
+
+    counted := 0
+    s.Range(func(string, *Room) bool { counted++; return true })
+    if counted != s.Len() {
+        t.Errorf("Range found %d rooms, Len() = %d", counted, s.Len())
+    }
+    if counted > rooms/2 {
+        t.Errorf("%d rooms left, want at most %d", counted, rooms/2)
+    }
+    s.Sweep(func(string, *Room) bool { return true })
+    if s.Len() != 0 {
+        t.Errorf("Len() = %d after sweeping every room", s.Len())
+    }
+}
@@ -0,0 +1,49 @@
+package rooms
+
+import "errors"
+
+// Template is the reusable part of a room's configuration: everything a
+// host sets up front, but not its peers, files or schedule. Cloning a room
+// or creating one from a saved template starts it with these settings.
+type Template struct {
+    // Mode decides the default permissions guests join with
+    Mode        string   `json:"mode"`
+    WaitingRoom bool     `json:"waitingRoom"`
+    Info        RoomInfo `json:"info"`
+    // Policy is the host's own file policy for the room
+    Policy FilePolicy `json:"policy"`
+}
+
+// Template captures the room's reusable settings. The caller must hold the
+// room lock.
+func (r *Room) Template() Template {
+    t := Template{Mode: r.Mode, WaitingRoom: r.WaitingRoom, Info: r.Info, Policy: r.Policy}
+    t.Info.Tags = append([]string(nil), r.Info.Tags...)
+    t.Policy.AllowedTypes = append([]string(nil), r.Policy.AllowedTypes...)
+    t.Policy.AllowedExtensions = append([]string(nil), r.Policy.AllowedExtensions...)
+    return t
+}
+
+// Apply configures a room that is being created from the template
+func (t Template) Apply(r *Room) {
+    r.Mode = t.Mode
+    r.WaitingRoom = t.WaitingRoom
+    r.Info = t.Info
+    r.Policy = t.Policy
+}
+
+// Validate normalizes the template and checks its settings
+func (t *Template) Validate() error {
+    if t.Mode == "" {
+        t.Mode = ModeOpen
+    }
+    if !ValidMode(t.Mode) {
+        return errors.New("mode must be one of open, dropbox, distribution")
+    }
+    t.Info.Normalize()
+    if err := t.Info.Validate(); err != nil {
+        return err
+    }
+    t.Policy.Normalize()
+    return t.Policy.Validate()
+}
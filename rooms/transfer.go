@@ -0,0 +1,175 @@
+package rooms
+
+import (
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "sort"
+    "time"
+)
+
+// MaxChunkHashes bounds how many chunk hashes a sender may publish for one
+// file
+const MaxChunkHashes = 4096
+
+// Integrity is the SHA-256 hashes a sender publishes for a file, chunk by
+// chunk, so receivers can check what arrived over the data channel. It is
+// never modified once published.
+type Integrity struct {
+    ChunkSize int64    `json:"chunkSize"`
+    Chunks    []string `json:"chunks"`
+    // SHA256 is the hash of the whole file, if the sender computed it
+    SHA256 string `json:"sha256,omitempty"`
+}
+
+// Validate checks the hashes are well-formed and cover a file of size
+// bytes exactly
+func (in Integrity) Validate(size int64) error {
+    if in.ChunkSize <= 0 {
+        return errors.New("chunkSize must be positive")
+    }
+    if len(in.Chunks) > MaxChunkHashes {
+        return fmt.Errorf("at most %d chunk hashes can be published", MaxChunkHashes)
+    }
+    if want := (size + in.ChunkSize - 1) / in.ChunkSize; int64(len(in.Chunks)) != want {
+        return fmt.Errorf("a %d byte file in %d byte chunks needs %d chunk hashes", size, in.ChunkSize, want)
+    }
+    for _, h := range in.Chunks {
+        if !validSHA256(h) {
+            return fmt.Errorf("invalid SHA-256 hash %q", h)
+        }
+    }
+    if in.SHA256 != "" && !validSHA256(in.SHA256) {
+        return fmt.Errorf("invalid SHA-256 hash %q", in.SHA256)
+    }
+    return nil
+}
+
+func validSHA256(h string) bool {
+    b, err := hex.DecodeString(h)
+    return err == nil && len(b) == 32
+}
+
+// Transfer states
+const (
+    // TransferRequested is a receiver asking for a file, which the sender
+    // has yet to accept or decline
+    TransferRequested       = "requested"
+    TransferAccepted        = "accepted"
+    TransferDeclined        = "declined"
+    TransferInProgress      = "in_progress"
+    TransferCompleted       = "completed"
+    TransferVerified        = "verified"
+    TransferIntegrityFailed = "integrity_failed"
+)
+
+// Transfer follows one receiver's copy of a file offer
+type Transfer struct {
+    FileID string `json:"fileId"`
+    PeerID string `json:"peerId"`
+    State  string `json:"state"`
+    // RequestedAt is when the receiver last asked for the file
+    RequestedAt int64 `json:"requestedAt,omitempty"`
+    // Reason is the sender's note when declining a request
+    Reason string `json:"reason,omitempty"`
+    // Progress as last reported by the sender or receiver
+    Bytes          int64 `json:"bytes,omitempty"`
+    Percent        int   `json:"percent,omitempty"`
+    BytesPerSecond int64 `json:"bytesPerSecond,omitempty"`
+    // FailedChunks are the indexes of chunks whose hash did not match
+    FailedChunks []int `json:"failedChunks,omitempty"`
+    UpdatedAt    int64 `json:"updatedAt"`
+
+    // announced is when progress was last passed on to the room, and at
+    // what percent
+    announcedAt      time.Time
+    announcedPercent int
+}
+
+// Progress announcements are coarse: at most one every
+// progressAnnounceInterval unless the percentage moves by progressStep
+const (
+    progressAnnounceInterval = 2 * time.Second
+    progressStep             = 5
+)
+
+// ReportProgress records how far a transfer has got and reports whether the
+// change is worth announcing to the room. The caller must hold the room
+// lock.
+func (t *Transfer) ReportProgress(bytes int64, percent int, rate int64, now time.Time) bool {
+    t.Bytes, t.Percent, t.BytesPerSecond = bytes, percent, rate
+    t.UpdatedAt = now.Unix()
+    if percent >= 100 {
+        t.State = TransferCompleted
+        t.BytesPerSecond = 0
+    } else {
+        t.State = TransferInProgress
+    }
+
+    step := percent - t.announcedPercent
+    if t.announcedAt.IsZero() || percent >= 100 || step >= progressStep || step < 0 || now.Sub(t.announcedAt) >= progressAnnounceInterval {
+        t.announcedAt, t.announcedPercent = now, percent
+        return true
+    }
+    return false
+}
+
+// Transfer returns the transfer of fileID to peerID, starting one if there
+// is none. The caller must hold the room lock.
+func (r *Room) Transfer(fileID, peerID string, now int64) *Transfer {
+    transfers := r.Transfers[fileID]
+    if transfers == nil {
+        transfers = make(map[string]*Transfer)
+        r.Transfers[fileID] = transfers
+    }
+    t, ok := transfers[peerID]
+    if !ok {
+        t = &Transfer{FileID: fileID, PeerID: peerID, UpdatedAt: now}
+        transfers[peerID] = t
+    }
+    return t
+}
+
+// FileTransfers returns copies of a file's transfers, ordered by receiver.
+// The caller must hold the room lock.
+func (r *Room) FileTransfers(fileID string) []Transfer {
+    list := make([]Transfer, 0, len(r.Transfers[fileID]))
+    for _, t := range r.Transfers[fileID] {
+        c := *t
+        c.FailedChunks = append([]int(nil), t.FailedChunks...)
+        list = append(list, c)
+    }
+    sort.Slice(list, func(i, j int) bool {
+        return list[i].PeerID < list[j].PeerID
+    })
+    return list
+}
+
+// VisibleTransfers returns copies of the transfers of every file the viewer
+// may see, newest first. The caller must hold the room lock.
+func (r *Room) VisibleTransfers(viewerID string) []Transfer {
+    var list []Transfer
+    for fileID, file := range r.Files {
+        if r.FileVisible(file, viewerID) {
+            list = append(list, r.FileTransfers(fileID)...)
+        }
+    }
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].UpdatedAt != list[j].UpdatedAt {
+            return list[i].UpdatedAt > list[j].UpdatedAt
+        }
+        if list[i].FileID != list[j].FileID {
+            return list[i].FileID < list[j].FileID
+        }
+        return list[i].PeerID < list[j].PeerID
+    })
+    return list
+}
+
+// RemoveFile withdraws a file offer along with its transfers. The caller
+// must hold the room lock.
+func (r *Room) RemoveFile(fileID string) {
+    delete(r.Files, fileID)
+    delete(r.Transfers, fileID)
+    r.Touch()
+}
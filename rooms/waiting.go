@@ -0,0 +1,71 @@
+package rooms
+
+import "sort"
+
+// MaxPendingPeers bounds how many peers can wait for approval in one room
+const MaxPendingPeers = 50
+
+// PendingPeer is a peer waiting in a room's waiting room for the host to let
+// it in. It is not a member: it does not appear in the peer list and cannot
+// signal or offer files.
+type PendingPeer struct {
+    PeerID      string `json:"peerId"`
+    RequestedAt int64  `json:"requestedAt"`
+    PeerProfile
+}
+
+// RequestJoin puts a peer in the waiting room, or refreshes its profile if
+// it is already waiting. It reports whether this is a new request, and
+// false for ok when the waiting room is full. The caller must hold the room
+// lock.
+func (r *Room) RequestJoin(peerID string, profile PeerProfile, ts int64) (created, ok bool) {
+    if pending, waiting := r.Pending[peerID]; waiting {
+        pending.PeerProfile = profile
+        return false, true
+    }
+    if len(r.Pending) >= MaxPendingPeers {
+        return false, false
+    }
+    r.Pending[peerID] = &PendingPeer{PeerID: peerID, RequestedAt: ts, PeerProfile: profile}
+    return true, true
+}
+
+// TakePending removes a peer from the waiting room and returns its request.
+// The caller must hold the room lock.
+func (r *Room) TakePending(peerID string) (PendingPeer, bool) {
+    pending, ok := r.Pending[peerID]
+    if !ok {
+        return PendingPeer{}, false
+    }
+    delete(r.Pending, peerID)
+    return *pending, true
+}
+
+// PendingPeers lists the waiting room, oldest request first. The caller must
+// hold the room lock.
+func (r *Room) PendingPeers() []PendingPeer {
+    list := make([]PendingPeer, 0, len(r.Pending))
+    for _, pending := range r.Pending {
+        list = append(list, *pending)
+    }
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].RequestedAt != list[j].RequestedAt {
+            return list[i].RequestedAt < list[j].RequestedAt
+        }
+        return list[i].PeerID < list[j].PeerID
+    })
+    return list
+}
+
+// ExpirePending drops requests made at or before cutoff and returns the
+// peers dropped. The caller must hold the room lock.
+func (r *Room) ExpirePending(cutoff int64) []string {
+    var expired []string
+    for peerID, pending := range r.Pending {
+        if pending.RequestedAt <= cutoff {
+            delete(r.Pending, peerID)
+            expired = append(expired, peerID)
+        }
+    }
+    return expired
+}
@@ -0,0 +1,211 @@
+package secrets
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "sort"
+    "strings"
+    "time"
+)
+
+// AWS reads a secret from AWS Secrets Manager. Requests are signed with
+// Signature Version 4 using static credentials from the environment or, on
+// ECS and EKS, the task role's credentials endpoint.
+type AWS struct {
+    SecretID string
+    Region   string
+    // Endpoint overrides the regional endpoint, e.g. for a VPC endpoint or
+    // LocalStack
+    Endpoint string
+    Client   *http.Client
+}
+
+type awsCredentials struct {
+    AccessKeyID     string
+    SecretAccessKey string
+    Token           string
+}
+
+// awsFromEnv reads AWS_SECRET_ID, AWS_REGION (or AWS_DEFAULT_REGION) and
+// AWS_SECRETS_ENDPOINT
+func awsFromEnv() (*AWS, error) {
+    a := &AWS{
+        SecretID: os.Getenv("AWS_SECRET_ID"),
+        Region:   os.Getenv("AWS_REGION"),
+        Endpoint: strings.TrimRight(os.Getenv("AWS_SECRETS_ENDPOINT"), "/"),
+        Client:   &http.Client{Timeout: 10 * time.Second},
+    }
+    if a.Region == "" {
+        a.Region = os.Getenv("AWS_DEFAULT_REGION")
+    }
+    if a.SecretID == "" || a.Region == "" {
+        return nil, fmt.Errorf("AWS_SECRET_ID and AWS_REGION must be set")
+    }
+    if a.Endpoint == "" {
+        a.Endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", a.Region)
+    }
+    return a, nil
+}
+
+// Name identifies the secret in logs
+func (a *AWS) Name() string {
+    return "aws:" + a.SecretID
+}
+
+// Fetch reads the current version of the secret
+func (a *AWS) Fetch(ctx context.Context) (map[string]string, error) {
+    creds, err := a.credentials(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    body, _ := json.Marshal(map[string]string{"SecretId": a.SecretID})
+    req, err := http.NewRequestWithContext(ctx, "POST", a.Endpoint+"/", bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+    req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+    signV4(req, body, creds, a.Region, "secretsmanager", time.Now().UTC())
+
+    resp, err := a.Client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+        return nil, fmt.Errorf("Secrets Manager returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+    }
+
+    var result struct {
+        SecretString string `json:"SecretString"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return nil, fmt.Errorf("failed to parse Secrets Manager response: %v", err)
+    }
+    if result.SecretString == "" {
+        return nil, fmt.Errorf("secret %s has no SecretString", a.SecretID)
+    }
+    return decodeSecret([]byte(result.SecretString))
+}
+
+// credentials returns AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY if set,
+// otherwise fetches the container role's credentials
+func (a *AWS) credentials(ctx context.Context) (awsCredentials, error) {
+    if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+        return awsCredentials{
+            AccessKeyID:     id,
+            SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+            Token:           os.Getenv("AWS_SESSION_TOKEN"),
+        }, nil
+    }
+
+    endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+    if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+        endpoint = "http://169.254.170.2" + relative
+    }
+    if endpoint == "" {
+        return awsCredentials{}, fmt.Errorf("no AWS credentials: set AWS_ACCESS_KEY_ID or run with a task role")
+    }
+    req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+    if err != nil {
+        return awsCredentials{}, err
+    }
+    if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+        req.Header.Set("Authorization", token)
+    }
+    resp, err := a.Client.Do(req)
+    if err != nil {
+        return awsCredentials{}, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return awsCredentials{}, fmt.Errorf("container credentials endpoint returned %d", resp.StatusCode)
+    }
+    var result struct {
+        AccessKeyID     string `json:"AccessKeyId"`
+        SecretAccessKey string `json:"SecretAccessKey"`
+        Token           string `json:"Token"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return awsCredentials{}, fmt.Errorf("failed to parse container credentials: %v", err)
+    }
+    return awsCredentials(result), nil
+}
+
+// signV4 adds AWS Signature Version 4 headers to req
+func signV4(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+    amzDate := now.Format("20060102T150405Z")
+    date := now.Format("20060102")
+    payloadHash := sha256Hex(body)
+
+    req.Header.Set("Host", req.URL.Host)
+    req.Header.Set("X-Amz-Date", amzDate)
+    req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+    if creds.Token != "" {
+        req.Header.Set("X-Amz-Security-Token", creds.Token)
+    }
+
+    // Sign every header on the request
+    var names []string
+    for name := range req.Header {
+        names = append(names, strings.ToLower(name))
+    }
+    sort.Strings(names)
+    var canonicalHeaders strings.Builder
+    for _, name := range names {
+        canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+    }
+    signedHeaders := strings.Join(names, ";")
+
+    path := req.URL.EscapedPath()
+    if path == "" {
+        path = "/"
+    }
+    canonicalRequest := strings.Join([]string{
+        req.Method,
+        path,
+        canonicalQuery(req.URL.Query()),
+        canonicalHeaders.String(),
+        signedHeaders,
+        payloadHash,
+    }, "\n")
+
+    scope := date + "/" + region + "/" + service + "/aws4_request"
+    stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+    key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+    key = hmacSHA256(key, region)
+    key = hmacSHA256(key, service)
+    key = hmacSHA256(key, "aws4_request")
+    signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+    req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(query url.Values) string {
+    // url.Values.Encode sorts by key but encodes spaces as "+"
+    return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(data))
+    return mac.Sum(nil)
+}
@@ -0,0 +1,125 @@
+package secrets
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+// GCP reads a secret version from Google Cloud Secret Manager, authenticating
+// with GCP_ACCESS_TOKEN or the instance's service account from the metadata
+// server (GCE, Cloud Run, GKE with Workload Identity)
+type GCP struct {
+    // Version is the full resource name, e.g.
+    // projects/my-project/secrets/p2p/versions/latest
+    Version string
+    Client  *http.Client
+
+    mu          sync.Mutex
+    token       string
+    tokenExpiry time.Time
+}
+
+// gcpFromEnv reads GCP_SECRET, either a secret
+// (projects/<project>/secrets/<name>, read at its latest version) or a
+// specific version
+func gcpFromEnv() (*GCP, error) {
+    name := strings.Trim(os.Getenv("GCP_SECRET"), "/")
+    if !strings.HasPrefix(name, "projects/") || !strings.Contains(name, "/secrets/") {
+        return nil, fmt.Errorf("GCP_SECRET must be projects/<project>/secrets/<name>")
+    }
+    if !strings.Contains(name, "/versions/") {
+        name += "/versions/latest"
+    }
+    return &GCP{Version: name, Client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// Name identifies the secret in logs
+func (g *GCP) Name() string {
+    return "gcp:" + g.Version
+}
+
+// Fetch reads the secret version's payload
+func (g *GCP) Fetch(ctx context.Context) (map[string]string, error) {
+    token, err := g.accessToken(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    url := "https://secretmanager.googleapis.com/v1/" + g.Version + ":access"
+    req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+
+    resp, err := g.Client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+        return nil, fmt.Errorf("Secret Manager returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+    }
+
+    var result struct {
+        Payload struct {
+            Data string `json:"data"`
+        } `json:"payload"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return nil, fmt.Errorf("failed to parse Secret Manager response: %v", err)
+    }
+    data, err := base64.StdEncoding.DecodeString(result.Payload.Data)
+    if err != nil {
+        return nil, fmt.Errorf("invalid secret payload: %v", err)
+    }
+    return decodeSecret(data)
+}
+
+// accessToken returns GCP_ACCESS_TOKEN if set, otherwise a metadata server
+// token cached until shortly before it expires
+func (g *GCP) accessToken(ctx context.Context) (string, error) {
+    if token := os.Getenv("GCP_ACCESS_TOKEN"); token != "" {
+        return token, nil
+    }
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.token != "" && time.Now().Before(g.tokenExpiry) {
+        return g.token, nil
+    }
+
+    url := "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+    req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("Metadata-Flavor", "Google")
+    resp, err := g.Client.Do(req)
+    if err != nil {
+        return "", fmt.Errorf("metadata server unavailable (set GCP_ACCESS_TOKEN outside GCP): %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("metadata server returned %d", resp.StatusCode)
+    }
+    var result struct {
+        AccessToken string `json:"access_token"`
+        ExpiresIn   int    `json:"expires_in"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return "", fmt.Errorf("failed to parse metadata token: %v", err)
+    }
+    g.token = result.AccessToken
+    g.tokenExpiry = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+    return g.token, nil
+}
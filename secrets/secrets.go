@@ -0,0 +1,179 @@
+// Package secrets loads credentials such as TWILIO_AUTH_TOKEN, ADMIN_TOKEN
+// and PEER_TOKEN_SECRET from a secrets manager instead of plaintext
+// environment variables on the host.
+//
+// A secret holds a JSON object of environment variable names to values. Its
+// entries are copied into the process environment at startup, before the
+// rest of the configuration is read, and refreshed periodically so rotated
+// values reach code that reads them per request.
+package secrets
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Source fetches the current secret values
+type Source interface {
+    // Fetch returns every key in the secret
+    Fetch(ctx context.Context) (map[string]string, error)
+    // Name identifies the source in logs
+    Name() string
+}
+
+// Loader copies a Source into the process environment and keeps it current
+type Loader struct {
+    source   Source
+    interval time.Duration
+
+    mu      sync.Mutex
+    applied map[string]string
+    hooks   []func(changed []string)
+}
+
+// NewLoader returns a Loader refreshing source every interval
+func NewLoader(source Source, interval time.Duration) *Loader {
+    if interval <= 0 {
+        interval = 5 * time.Minute
+    }
+    return &Loader{source: source, interval: interval, applied: make(map[string]string)}
+}
+
+// FromEnv returns the Loader configured by SECRETS_PROVIDER (vault, aws or
+// gcp), or nil if none is, after loading the secret once. Startup fails if
+// the secret cannot be read, since the server would otherwise run without
+// its credentials. SECRETS_REFRESH_INTERVAL sets how often rotated values are
+// picked up (default 5m).
+func FromEnv() *Loader {
+    var (
+        source Source
+        err    error
+    )
+    switch provider := os.Getenv("SECRETS_PROVIDER"); provider {
+    case "":
+        return nil
+    case "vault":
+        source, err = vaultFromEnv()
+    case "aws":
+        source, err = awsFromEnv()
+    case "gcp":
+        source, err = gcpFromEnv()
+    default:
+        err = fmt.Errorf("SECRETS_PROVIDER must be vault, aws or gcp, got %q", provider)
+    }
+    if err != nil {
+        log.Fatalf("❌ Invalid secrets configuration: %v", err)
+    }
+
+    interval := 5 * time.Minute
+    if v := os.Getenv("SECRETS_REFRESH_INTERVAL"); v != "" {
+        if interval, err = time.ParseDuration(v); err != nil || interval <= 0 {
+            log.Fatalf("❌ Invalid SECRETS_REFRESH_INTERVAL %q", v)
+        }
+    }
+
+    loader := NewLoader(source, interval)
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+    if err := loader.Load(ctx); err != nil {
+        log.Fatalf("❌ Failed to load secrets from %s: %v", source.Name(), err)
+    }
+    return loader
+}
+
+// OnChange registers fn to be called with the names of the keys whose values
+// changed on a refresh
+func (l *Loader) OnChange(fn func(changed []string)) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.hooks = append(l.hooks, fn)
+}
+
+// Load fetches the secret and sets every changed key in the environment.
+// Keys removed from the secret keep their last value, so a partial edit in
+// the secrets manager cannot blank out a credential that is in use.
+func (l *Loader) Load(ctx context.Context) error {
+    values, err := l.source.Fetch(ctx)
+    if err != nil {
+        return err
+    }
+
+    l.mu.Lock()
+    first := len(l.applied) == 0
+    var changed []string
+    for key, value := range values {
+        if old, ok := l.applied[key]; ok && old == value {
+            continue
+        }
+        if err := os.Setenv(key, value); err != nil {
+            l.mu.Unlock()
+            return fmt.Errorf("setting %s: %v", key, err)
+        }
+        l.applied[key] = value
+        changed = append(changed, key)
+    }
+    hooks := append([]func([]string){}, l.hooks...)
+    l.mu.Unlock()
+
+    if len(changed) == 0 {
+        return nil
+    }
+    sort.Strings(changed)
+    if first {
+        log.Printf("🔐 Loaded %d secrets from %s", len(changed), l.source.Name())
+    } else {
+        log.Printf("🔐 Rotated secrets from %s: %v", l.source.Name(), changed)
+    }
+    for _, fn := range hooks {
+        fn(changed)
+    }
+    return nil
+}
+
+// Run refreshes the secret every interval until ctx is cancelled. Failed
+// refreshes are logged and the previous values stay in effect.
+func (l *Loader) Run(ctx context.Context) {
+    ticker := time.NewTicker(l.interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+            if err := l.Load(fetchCtx); err != nil {
+                log.Printf("⚠️  Failed to refresh secrets from %s: %v", l.source.Name(), err)
+            }
+            cancel()
+        }
+    }
+}
+
+// decodeSecret parses a secret payload holding a JSON object. Non-string
+// values are kept as their JSON encoding.
+func decodeSecret(data []byte) (map[string]string, error) {
+    var raw map[string]json.RawMessage
+    if err := json.Unmarshal(data, &raw); err != nil {
+        return nil, fmt.Errorf("secret is not a JSON object: %v", err)
+    }
+    return flatten(raw), nil
+}
+
+func flatten(raw map[string]json.RawMessage) map[string]string {
+    values := make(map[string]string, len(raw))
+    for key, value := range raw {
+        var s string
+        if err := json.Unmarshal(value, &s); err == nil {
+            values[key] = s
+        } else {
+            values[key] = string(value)
+        }
+    }
+    return values
+}
@@ -0,0 +1,99 @@
+package secrets
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+)
+
+// Vault reads a secret from a HashiCorp Vault KV version 2 engine
+type Vault struct {
+    Addr      string
+    Mount     string
+    Path      string
+    Namespace string
+    // Token authenticates requests; TokenFile, if set, is re-read on every
+    // fetch so a token renewed by Vault Agent is picked up
+    Token     string
+    TokenFile string
+    Client    *http.Client
+}
+
+// vaultFromEnv reads VAULT_ADDR, VAULT_SECRET_PATH, VAULT_MOUNT (default
+// secret), VAULT_NAMESPACE and VAULT_TOKEN or VAULT_TOKEN_FILE
+func vaultFromEnv() (*Vault, error) {
+    v := &Vault{
+        Addr:      strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+        Mount:     strings.Trim(os.Getenv("VAULT_MOUNT"), "/"),
+        Path:      strings.Trim(os.Getenv("VAULT_SECRET_PATH"), "/"),
+        Namespace: os.Getenv("VAULT_NAMESPACE"),
+        Token:     os.Getenv("VAULT_TOKEN"),
+        TokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+        Client:    &http.Client{Timeout: 10 * time.Second},
+    }
+    if v.Mount == "" {
+        v.Mount = "secret"
+    }
+    if v.Addr == "" || v.Path == "" {
+        return nil, fmt.Errorf("VAULT_ADDR and VAULT_SECRET_PATH must be set")
+    }
+    if v.Token == "" && v.TokenFile == "" {
+        return nil, fmt.Errorf("VAULT_TOKEN or VAULT_TOKEN_FILE must be set")
+    }
+    return v, nil
+}
+
+// Name identifies the secret in logs
+func (v *Vault) Name() string {
+    return "vault:" + v.Mount + "/" + v.Path
+}
+
+// Fetch reads the latest version of the secret
+func (v *Vault) Fetch(ctx context.Context) (map[string]string, error) {
+    token := v.Token
+    if v.TokenFile != "" {
+        data, err := os.ReadFile(v.TokenFile)
+        if err != nil {
+            return nil, err
+        }
+        token = strings.TrimSpace(string(data))
+    }
+
+    url := fmt.Sprintf("%s/v1/%s/data/%s", v.Addr, v.Mount, v.Path)
+    req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("X-Vault-Token", token)
+    if v.Namespace != "" {
+        req.Header.Set("X-Vault-Namespace", v.Namespace)
+    }
+
+    resp, err := v.Client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+        return nil, fmt.Errorf("Vault returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+    }
+
+    var result struct {
+        Data struct {
+            Data map[string]json.RawMessage `json:"data"`
+        } `json:"data"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return nil, fmt.Errorf("failed to parse Vault response: %v", err)
+    }
+    if result.Data.Data == nil {
+        return nil, fmt.Errorf("secret %s has no data", v.Path)
+    }
+    return flatten(result.Data.Data), nil
+}
@@ -0,0 +1,156 @@
+// Package server assembles the signaling backend so it can run as its own
+// binary or be embedded in another Go program:
+//
+//  srv := server.New(server.ConfigFromEnv())
+//  srv.Start(ctx)
+//  mux.Handle("/signal/", http.StripPrefix("/signal", srv.Router()))
+package server
+
+import (
+    "context"
+    "net/http"
+    "os"
+
+    "p2p-file-share-backend/hooks"
+    "p2p-file-share-backend/httpapi"
+    "p2p-file-share-backend/leader"
+    "p2p-file-share-backend/mdns"
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/oidc"
+    "p2p-file-share-backend/relay"
+    "p2p-file-share-backend/reporting"
+    "p2p-file-share-backend/rooms"
+    "p2p-file-share-backend/secrets"
+    "p2p-file-share-backend/storage"
+    "p2p-file-share-backend/turn"
+)
+
+// Config configures a Server
+type Config struct {
+    // Port to listen on (default 3001, or 443 when serving HTTPS directly)
+    Port string
+    // H2C serves HTTP/2 over cleartext
+    H2C bool
+    // HTTP3 also serves HTTP/3 over QUIC; requires TLS
+    HTTP3 bool
+
+    // AllowedOrigins for CORS (default httpapi.DefaultAllowedOrigins)
+    AllowedOrigins []string
+    // TURN issues ICE credentials; nil means TURN is not configured
+    TURN turn.Provider
+    // Rooms and Notifications default to fresh in-memory instances
+    Rooms         *rooms.Store
+    Notifications *notifications.Hub
+    // Leader decides whether this replica sweeps stale rooms; see
+    // leader.FromEnv
+    Leader leader.Elector
+    // Storage persists rooms across restarts; see storage.FromEnv
+    Storage storage.Store
+    // Secrets keeps credentials loaded from a secrets manager current; see
+    // secrets.FromEnv. Nil when credentials come from the environment only.
+    Secrets *secrets.Loader
+    // Reporter receives panics and server errors; see reporting.FromEnv
+    Reporter reporting.Reporter
+    // Relay and Scanner store and scan relayed files; see relay.FromEnv and
+    // relay.ScannerFromEnv
+    Relay   relay.Store
+    Scanner relay.Scanner
+    // Beacon advertises the server on the LAN over mDNS; see mdns.FromEnv
+    Beacon *mdns.Beacon
+    // Login are the sign-in providers; see oidc.FromEnv
+    Login map[string]*oidc.Provider
+    // Hooks intercept the room lifecycle; see httpapi.Config.Hooks
+    Hooks *hooks.Registry
+
+    // Dev enables developer mode; see httpapi.Config.Dev
+    Dev bool
+}
+
+// ConfigFromEnv reads PORT, ENABLE_H2C, ENABLE_HTTP3, DEV_MODE and the TURN
+// provider, notification backend, leader election, storage, error reporting,
+// mDNS and login provider settings from the environment, after loading any
+// secrets manager into it
+func ConfigFromEnv() Config {
+    loader := secrets.FromEnv()
+    cfg := Config{
+        Port:          os.Getenv("PORT"),
+        H2C:           os.Getenv("ENABLE_H2C") == "true",
+        HTTP3:         os.Getenv("ENABLE_HTTP3") == "true",
+        Dev:           os.Getenv("DEV_MODE") == "true",
+        TURN:          turn.FromEnv(),
+        Notifications: notifications.FromEnv(),
+        Leader:        leader.FromEnv(),
+        Storage:       storage.FromEnv(),
+        Secrets:       loader,
+        Reporter:      reporting.FromEnv(),
+        Relay:         relay.FromEnv(),
+        Scanner:       relay.ScannerFromEnv(),
+        Login:         oidc.FromEnv(),
+    }
+    if cfg.Port == "" {
+        cfg.Port = "3001"
+        if tlsMode() != "" {
+            cfg.Port = "443"
+        }
+    }
+    scheme := "http"
+    if tlsMode() != "" {
+        scheme = "https"
+    }
+    cfg.Beacon = mdns.FromEnv(cfg.Port, scheme)
+    return cfg
+}
+
+// Server is one instance of the signaling backend
+type Server struct {
+    cfg    Config
+    api    *httpapi.API
+    router http.Handler
+}
+
+// New builds a Server. Call Start to run its background maintenance.
+func New(cfg Config) *Server {
+    api := httpapi.New(httpapi.Config{
+        AllowedOrigins: cfg.AllowedOrigins,
+        TURN:           cfg.TURN,
+        Rooms:          cfg.Rooms,
+        Notifications:  cfg.Notifications,
+        Leader:         cfg.Leader,
+        Storage:        cfg.Storage,
+        Reporter:       cfg.Reporter,
+        Relay:          cfg.Relay,
+        Scanner:        cfg.Scanner,
+        Login:          cfg.Login,
+        Hooks:          cfg.Hooks,
+        Dev:            cfg.Dev,
+    })
+    return &Server{cfg: cfg, api: api, router: api.Router()}
+}
+
+// Router returns the HTTP handler for every endpoint, for mounting inside
+// a larger service
+func (s *Server) Router() http.Handler {
+    return s.router
+}
+
+// Start runs notification delivery, stale-peer cleanup, data retention,
+// access-list reloads, secret rotation and the mDNS beacon until ctx is
+// cancelled
+func (s *Server) Start(ctx context.Context) {
+    s.api.Start(ctx)
+    if s.cfg.Beacon != nil {
+        go s.cfg.Beacon.Run(ctx)
+    }
+    if s.cfg.Secrets != nil {
+        s.cfg.Secrets.OnChange(func(changed []string) {
+            turn.Reload(s.cfg.TURN)
+        })
+        go s.cfg.Secrets.Run(ctx)
+    }
+}
+
+// ListenAndServe serves the router on the configured port, with TLS,
+// h2c and HTTP/3 as configured
+func (s *Server) ListenAndServe() error {
+    return serve(s.api, s.router, s.cfg.Port, serveOptions{h2c: s.cfg.H2C, http3: s.cfg.HTTP3})
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+    "context"
+    "net/http/httptest"
+
+    "p2p-file-share-backend/notifications"
+    "p2p-file-share-backend/rooms"
+    "p2p-file-share-backend/turn"
+)
+
+// TestServer is a Server listening on a local httptest listener, for
+// integration tests that need a real backend without Twilio or network
+// access. Its state is exposed so tests can seed and inspect it directly.
+type TestServer struct {
+    *httptest.Server
+
+    Rooms         *rooms.Store
+    Notifications *notifications.Hub
+    TURN          *turn.Fake
+
+    cancel context.CancelFunc
+}
+
+// NewTestServer starts a Server with a fresh in-memory store and a Fake TURN
+// provider returning deterministic credentials. Call Close when done.
+func NewTestServer() *TestServer {
+    ts := &TestServer{
+        Rooms:         rooms.NewStore(),
+        Notifications: notifications.NewHub(),
+        TURN:          turn.NewFake(),
+    }
+
+    srv := New(Config{
+        TURN:          ts.TURN,
+        Rooms:         ts.Rooms,
+        Notifications: ts.Notifications,
+    })
+
+    var ctx context.Context
+    ctx, ts.cancel = context.WithCancel(context.Background())
+    srv.Start(ctx)
+
+    ts.Server = httptest.NewServer(srv.Router())
+    return ts
+}
+
+// Close shuts down the listener and stops the background routines
+func (ts *TestServer) Close() {
+    ts.Server.Close()
+    ts.cancel()
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+    "encoding/json"
+    "errors"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+
+    "p2p-file-share-backend/turn"
+)
+
+func TestMain(m *testing.M) {
+    gin.SetMode(gin.TestMode)
+    os.Setenv("ACCESS_LOG", "off")
+    log.SetOutput(io.Discard)
+    os.Exit(m.Run())
+}
+
+func TestTestServerRooms(t *testing.T) {
+    ts := NewTestServer()
+    defer ts.Close()
+
+    resp, err := http.Post(ts.URL+"/room/create", "application/json", strings.NewReader(`{"roomCode":"seeded","peerId":"peer-a"}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("create status = %d", resp.StatusCode)
+    }
+    room, ok := ts.Rooms.Get("seeded")
+    if !ok {
+        t.Fatal("created room not in the exposed store")
+    }
+    room.RLock()
+    _, joined := room.Peers["peer-a"]
+    room.RUnlock()
+    if !joined {
+        t.Error("creator not in the room")
+    }
+}
+
+func TestTestServerTURN(t *testing.T) {
+    tests := []struct {
+        name         string
+        err          error
+        want         int
+        wantUsername string
+    }{
+        {"fake credentials", nil, http.StatusOK, turn.FakeUsername},
+        {"provider down", errors.New("unavailable"), http.StatusInternalServerError, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ts := NewTestServer()
+            defer ts.Close()
+            ts.TURN.Err = tt.err
+
+            resp, err := http.Get(ts.URL + "/turn-credentials")
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer resp.Body.Close()
+            if resp.StatusCode != tt.want {
+                t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+            }
+            if tt.wantUsername == "" {
+                return
+            }
+            var creds turn.Credentials
+            if err := json.NewDecoder(resp.Body).Decode(&creds); err != nil {
+                t.Fatal(err)
+            }
+            found := false
+            for _, server := range creds.ICEServers {
+                if server["username"] == tt.wantUsername && server["credential"] == turn.FakeCredential {
+                    found = true
+                }
+            }
+            if !found {
+                t.Errorf("ICE servers %v lack the fake TURN credentials", creds.ICEServers)
+            }
+        })
+    }
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+    "crypto/tls"
+    "log"
+    "net"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+
+    "golang.org/x/crypto/acme/autocert"
+    "golang.org/x/net/http2"
+    "golang.org/x/net/http2/h2c"
+
+    "p2p-file-share-backend/httpapi"
+)
+
+// tlsMode reports how HTTPS is configured: "autocert" when TLS_DOMAINS is set,
+// "files" when TLS_CERT_FILE and TLS_KEY_FILE are set, or "" for plain HTTP
+func tlsMode() string {
+    if os.Getenv("TLS_DOMAINS") != "" {
+        return "autocert"
+    }
+    if os.Getenv("TLS_CERT_FILE") != "" && os.Getenv("TLS_KEY_FILE") != "" {
+        return "files"
+    }
+    return ""
+}
+
+// serveOptions selects the optional protocols to listen with
+type serveOptions struct {
+    h2c   bool // HTTP/2 without TLS, for clients/proxies that speak prior-knowledge h2
+    http3 bool // HTTP/3 over QUIC on the same port (UDP); needs TLS
+}
+
+// serve runs the router over plain HTTP, or over HTTPS with an HTTP listener
+// on HTTP_PORT (default 80, "off" to disable) that redirects to HTTPS and
+// answers ACME challenges
+func serve(api *httpapi.API, handler http.Handler, port string, opts serveOptions) error {
+    mode := tlsMode()
+    if mode == "" {
+        if opts.http3 {
+            log.Println("⚠️  HTTP/3 requires TLS, ignoring -http3")
+        }
+        if opts.h2c {
+            log.Println("⚡ Serving HTTP/2 cleartext (h2c)")
+            handler = h2c.NewHandler(handler, &http2.Server{})
+        }
+        return newHTTPServer(":"+port, handler).ListenAndServe()
+    }
+
+    srv := newHTTPServer(":"+port, handler)
+    var redirect http.Handler = http.HandlerFunc(redirectToHTTPS(port))
+
+    if mode == "autocert" {
+        domains := strings.Split(os.Getenv("TLS_DOMAINS"), ",")
+        for i := range domains {
+            domains[i] = strings.TrimSpace(domains[i])
+        }
+        cacheDir := os.Getenv("TLS_CACHE_DIR")
+        if cacheDir == "" {
+            cacheDir = "certs"
+        }
+
+        m := &autocert.Manager{
+            Prompt:     autocert.AcceptTOS,
+            HostPolicy: autocert.HostWhitelist(domains...),
+            Cache:      autocert.DirCache(cacheDir),
+            Email:      os.Getenv("TLS_EMAIL"),
+        }
+        srv.TLSConfig = m.TLSConfig()
+        redirect = m.HTTPHandler(redirect)
+        log.Printf("🔒 HTTPS via Let's Encrypt for %s", strings.Join(domains, ", "))
+    } else {
+        cert, err := tls.LoadX509KeyPair(os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"))
+        if err != nil {
+            return err
+        }
+        srv.TLSConfig = &tls.Config{
+            MinVersion:   tls.VersionTLS12,
+            Certificates: []tls.Certificate{cert},
+        }
+        log.Printf("🔒 HTTPS with certificate %s", os.Getenv("TLS_CERT_FILE"))
+    }
+
+    if opts.http3 {
+        // The HTTP/3 server doubles as the WebTransport endpoint
+        wtServer := api.NewWebTransportServer(":"+port, srv.TLSConfig, handler)
+        // Advertise HTTP/3 to clients that connect over TCP first
+        srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            wtServer.H3.SetQUICHeaders(w.Header())
+            handler.ServeHTTP(w, r)
+        })
+        go func() {
+            log.Printf("⚡ Serving HTTP/3 (QUIC) and WebTransport (%s) on UDP port %s", httpapi.WebTransportPath, port)
+            if err := wtServer.ListenAndServe(); err != nil {
+                log.Printf("❌ HTTP/3 listener stopped: %v", err)
+            }
+        }()
+    }
+
+    if httpPort := os.Getenv("HTTP_PORT"); httpPort != "off" {
+        if httpPort == "" {
+            httpPort = "80"
+        }
+        go func() {
+            log.Printf("↪️  Redirecting HTTP on port %s to HTTPS", httpPort)
+            if err := newHTTPServer(":"+httpPort, redirect).ListenAndServe(); err != nil {
+                log.Printf("❌ HTTP redirect listener stopped: %v", err)
+            }
+        }()
+    }
+
+    // Certificates come from TLSConfig in both modes
+    return srv.ListenAndServeTLS("", "")
+}
+
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+    return &http.Server{
+        Addr:              addr,
+        Handler:           handler,
+        ReadHeaderTimeout: 10 * time.Second,
+    }
+}
+
+// redirectToHTTPS sends plain-HTTP clients to the same path on the HTTPS port
+func redirectToHTTPS(httpsPort string) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        host := r.Host
+        if h, _, err := net.SplitHostPort(host); err == nil {
+            host = h
+        }
+        if httpsPort != "443" {
+            host = net.JoinHostPort(host, httpsPort)
+        }
+        http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+    }
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+    "context"
+    "encoding/binary"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+)
+
+var (
+    roomsBucket = []byte("rooms")
+    auditBucket = []byte("audit")
+    flagsBucket = []byte("flags")
+    usersBucket = []byte("users")
+)
+
+// Bolt keeps rooms and the audit trail in a single embedded database file,
+// for self-hosted instances that want durability without running a database
+// server. The file is locked, so only one instance can use a data directory.
+type Bolt struct {
+    db *bolt.DB
+}
+
+// NewBolt opens (or creates) p2p.db in dir
+func NewBolt(dir string) (*Bolt, error) {
+    if err := os.MkdirAll(dir, 0o700); err != nil {
+        return nil, err
+    }
+    path := filepath.Join(dir, "p2p.db")
+    db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("open %s: %w", path, err)
+    }
+    err = db.Update(func(tx *bolt.Tx) error {
+        for _, name := range [][]byte{roomsBucket, auditBucket, flagsBucket, usersBucket} {
+            if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        db.Close()
+        return nil, err
+    }
+    return &Bolt{db: db}, nil
+}
+
+// auditKey orders audit entries by ID
+func auditKey(id int64) []byte {
+    key := make([]byte, 8)
+    binary.BigEndian.PutUint64(key, uint64(id))
+    return key
+}
+
+func (b *Bolt) SaveRoom(ctx context.Context, room RoomRecord) error {
+    data, err := json.Marshal(room)
+    if err != nil {
+        return err
+    }
+    return b.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(roomsBucket).Put([]byte(room.Code), data)
+    })
+}
+
+func (b *Bolt) DeleteRoom(ctx context.Context, code string) error {
+    return b.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(roomsBucket).Delete([]byte(code))
+    })
+}
+
+func (b *Bolt) LoadRooms(ctx context.Context) ([]RoomRecord, error) {
+    list := make([]RoomRecord, 0)
+    err := b.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(roomsBucket).ForEach(func(_, data []byte) error {
+            var rec RoomRecord
+            if err := json.Unmarshal(data, &rec); err != nil {
+                return err
+            }
+            list = append(list, rec)
+            return nil
+        })
+    })
+    return list, err
+}
+
+func (b *Bolt) AppendAudit(ctx context.Context, entries []AuditRecord) error {
+    if len(entries) == 0 {
+        return nil
+    }
+    return b.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(auditBucket)
+        for _, e := range entries {
+            data, err := json.Marshal(e)
+            if err != nil {
+                return err
+            }
+            if err := bucket.Put(auditKey(e.ID), data); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (b *Bolt) LoadAudit(ctx context.Context, limit int) ([]AuditRecord, error) {
+    var newest []AuditRecord
+    err := b.db.View(func(tx *bolt.Tx) error {
+        c := tx.Bucket(auditBucket).Cursor()
+        for k, data := c.Last(); k != nil && len(newest) < limit; k, data = c.Prev() {
+            var e AuditRecord
+            if err := json.Unmarshal(data, &e); err != nil {
+                return err
+            }
+            newest = append(newest, e)
+        }
+        return nil
+    })
+
+    // Walked newest first; return oldest first
+    list := make([]AuditRecord, 0, len(newest))
+    for i := len(newest) - 1; i >= 0; i-- {
+        list = append(list, newest[i])
+    }
+    return list, err
+}
+
+func (b *Bolt) ErasePeerAudit(ctx context.Context, peerID string) error {
+    return b.db.Update(func(tx *bolt.Tx) error {
+        c := tx.Bucket(auditBucket).Cursor()
+        for k, data := c.First(); k != nil; {
+            var e AuditRecord
+            if err := json.Unmarshal(data, &e); err != nil {
+                return err
+            }
+            if e.Actor != peerID && e.Target != peerID {
+                k, data = c.Next()
+                continue
+            }
+            if err := c.Delete(); err != nil {
+                return err
+            }
+            // Delete leaves the cursor before the next item
+            k, data = c.Seek(k)
+        }
+        return nil
+    })
+}
+
+func (b *Bolt) PurgeAuditBefore(ctx context.Context, cutoff int64) error {
+    return b.db.Update(func(tx *bolt.Tx) error {
+        // Entries are keyed in append order, so everything old is a prefix
+        c := tx.Bucket(auditBucket).Cursor()
+        for k, data := c.First(); k != nil; k, data = c.Seek(k) {
+            var e AuditRecord
+            if err := json.Unmarshal(data, &e); err != nil {
+                return err
+            }
+            if e.Time >= cutoff {
+                return nil
+            }
+            if err := c.Delete(); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (b *Bolt) SaveFlag(ctx context.Context, flag FlagRecord) error {
+    data, err := json.Marshal(flag)
+    if err != nil {
+        return err
+    }
+    return b.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(flagsBucket).Put([]byte(flag.Name), data)
+    })
+}
+
+func (b *Bolt) DeleteFlag(ctx context.Context, name string) error {
+    return b.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(flagsBucket).Delete([]byte(name))
+    })
+}
+
+func (b *Bolt) LoadFlags(ctx context.Context) ([]FlagRecord, error) {
+    list := make([]FlagRecord, 0)
+    err := b.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(flagsBucket).ForEach(func(_, data []byte) error {
+            var flag FlagRecord
+            if err := json.Unmarshal(data, &flag); err != nil {
+                return err
+            }
+            list = append(list, flag)
+            return nil
+        })
+    })
+    return list, err
+}
+
+func (b *Bolt) SaveUser(ctx context.Context, user UserRecord) error {
+    data, err := json.Marshal(user)
+    if err != nil {
+        return err
+    }
+    return b.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(usersBucket).Put([]byte(user.ID), data)
+    })
+}
+
+func (b *Bolt) DeleteUser(ctx context.Context, id string) error {
+    return b.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(usersBucket).Delete([]byte(id))
+    })
+}
+
+func (b *Bolt) LoadUsers(ctx context.Context) ([]UserRecord, error) {
+    list := make([]UserRecord, 0)
+    err := b.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(usersBucket).ForEach(func(_, data []byte) error {
+            var user UserRecord
+            if err := json.Unmarshal(data, &user); err != nil {
+                return err
+            }
+            list = append(list, user)
+            return nil
+        })
+    })
+    return list, err
+}
+
+func (b *Bolt) Ping(ctx context.Context) error {
+    return b.db.View(func(tx *bolt.Tx) error { return nil })
+}
+
+func (b *Bolt) Close() {
+    b.db.Close()
+}
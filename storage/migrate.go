@@ -0,0 +1,103 @@
+package storage
+
+import (
+    "context"
+    "embed"
+    "fmt"
+    "io/fs"
+    "log"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLockID is the advisory lock held while migrating, so replicas
+// starting together don't apply the same migration twice
+const migrationLockID = 0x70327066
+
+// migration is one numbered schema change, e.g. migrations/0001_init.sql
+type migration struct {
+    version int
+    name    string
+    sql     string
+}
+
+func loadMigrations() ([]migration, error) {
+    files, err := fs.Glob(migrations, "migrations/*.sql")
+    if err != nil {
+        return nil, err
+    }
+
+    list := make([]migration, 0, len(files))
+    for _, file := range files {
+        name := strings.TrimPrefix(file, "migrations/")
+        prefix, _, _ := strings.Cut(name, "_")
+        version, err := strconv.Atoi(prefix)
+        if err != nil {
+            return nil, fmt.Errorf("migration %s has no version prefix", name)
+        }
+        data, err := migrations.ReadFile(file)
+        if err != nil {
+            return nil, err
+        }
+        list = append(list, migration{version: version, name: name, sql: string(data)})
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+    return list, nil
+}
+
+// migrate applies every migration newer than the schema's recorded version,
+// each in its own transaction
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+    list, err := loadMigrations()
+    if err != nil {
+        return err
+    }
+
+    conn, err := pool.Acquire(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Release()
+
+    if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+        return err
+    }
+    defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+    if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version    INTEGER PRIMARY KEY,
+        applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+    )`); err != nil {
+        return err
+    }
+
+    var current int
+    if err := conn.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+        return err
+    }
+
+    for _, m := range list {
+        if m.version <= current {
+            continue
+        }
+        err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+            if _, err := tx.Exec(ctx, m.sql); err != nil {
+                return err
+            }
+            _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", m.version)
+            return err
+        })
+        if err != nil {
+            return fmt.Errorf("migration %s: %w", m.name, err)
+        }
+        log.Printf("🗄️  Applied migration %s", m.name)
+    }
+    return nil
+}
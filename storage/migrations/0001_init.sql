@@ -0,0 +1,46 @@
+CREATE TABLE rooms (
+    code        TEXT PRIMARY KEY,
+    host_id     TEXT NOT NULL,
+    mode        TEXT NOT NULL,
+    creator_ip  TEXT NOT NULL,
+    created_at  BIGINT NOT NULL,
+    suspended   BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE peers (
+    room_code    TEXT NOT NULL REFERENCES rooms (code) ON DELETE CASCADE,
+    peer_id      TEXT NOT NULL,
+    joined_at    BIGINT NOT NULL,
+    last_seen    BIGINT NOT NULL,
+    presence     TEXT NOT NULL,
+    role         TEXT NOT NULL,
+    permissions  TEXT NOT NULL,
+    profile      JSONB NOT NULL DEFAULT '{}',
+    PRIMARY KEY (room_code, peer_id)
+);
+
+CREATE INDEX peers_peer_id_idx ON peers (peer_id);
+
+CREATE TABLE file_offers (
+    room_code   TEXT NOT NULL REFERENCES rooms (code) ON DELETE CASCADE,
+    file_id     TEXT NOT NULL,
+    peer_id     TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    size        BIGINT NOT NULL,
+    mime_type   TEXT NOT NULL DEFAULT '',
+    offered_at  BIGINT NOT NULL,
+    PRIMARY KEY (room_code, file_id)
+);
+
+CREATE TABLE audit_log (
+    id         BIGINT PRIMARY KEY,
+    time       BIGINT NOT NULL,
+    room_code  TEXT NOT NULL,
+    type       TEXT NOT NULL,
+    actor      TEXT NOT NULL DEFAULT '',
+    target     TEXT NOT NULL DEFAULT '',
+    details    JSONB
+);
+
+CREATE INDEX audit_log_room_code_idx ON audit_log (room_code, id);
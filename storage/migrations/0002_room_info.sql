@@ -0,0 +1,3 @@
+ALTER TABLE rooms ADD COLUMN info JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE peers ADD COLUMN disconnected_at BIGINT NOT NULL DEFAULT 0;
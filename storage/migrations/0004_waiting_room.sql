@@ -0,0 +1 @@
+ALTER TABLE rooms ADD COLUMN waiting_room BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+ALTER TABLE rooms ADD COLUMN schedule JSONB;
@@ -0,0 +1 @@
+ALTER TABLE rooms ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE rooms ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE rooms ADD COLUMN policy JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE file_offers ADD COLUMN integrity JSONB;
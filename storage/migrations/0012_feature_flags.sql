@@ -0,0 +1,5 @@
+CREATE TABLE feature_flags (
+    name        TEXT PRIMARY KEY,
+    rule        JSONB NOT NULL,
+    updated_at  BIGINT NOT NULL
+);
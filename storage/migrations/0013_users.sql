@@ -0,0 +1,5 @@
+CREATE TABLE users (
+    id       TEXT PRIMARY KEY,
+    profile  JSONB,
+    devices  JSONB NOT NULL DEFAULT '[]'
+);
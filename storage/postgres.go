@@ -0,0 +1,325 @@
+package storage
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgxpool"
+
+    "p2p-file-share-backend/rooms"
+)
+
+// Postgres stores rooms and the audit trail in PostgreSQL through a
+// connection pool. Pool size and timeouts are set with the usual DATABASE_URL
+// parameters, e.g. ?pool_max_conns=20.
+type Postgres struct {
+    pool *pgxpool.Pool
+}
+
+// NewPostgres connects to url and brings the schema up to date
+func NewPostgres(ctx context.Context, url string) (*Postgres, error) {
+    if url == "" {
+        return nil, fmt.Errorf("DATABASE_URL is not set")
+    }
+    pool, err := pgxpool.New(ctx, url)
+    if err != nil {
+        return nil, err
+    }
+    if err := pool.Ping(ctx); err != nil {
+        pool.Close()
+        return nil, err
+    }
+    if err := migrate(ctx, pool); err != nil {
+        pool.Close()
+        return nil, err
+    }
+    return &Postgres{pool: pool}, nil
+}
+
+func (p *Postgres) SaveRoom(ctx context.Context, room RoomRecord) error {
+    info, err := json.Marshal(room.Info)
+    if err != nil {
+        return err
+    }
+    policy, err := json.Marshal(room.Policy)
+    if err != nil {
+        return err
+    }
+    // Unscheduled rooms store NULL
+    var schedule []byte
+    if room.Schedule != nil {
+        if schedule, err = json.Marshal(room.Schedule); err != nil {
+            return err
+        }
+    }
+    return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+        batch := &pgx.Batch{}
+        batch.Queue(`INSERT INTO rooms (code, host_id, mode, creator_ip, created_at, suspended, info, locked, waiting_room, schedule, pinned,
+                tenant, policy)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+            ON CONFLICT (code) DO UPDATE SET host_id = $2, mode = $3, creator_ip = $4,
+                created_at = $5, suspended = $6, info = $7, locked = $8, waiting_room = $9,
+                schedule = $10, pinned = $11, tenant = $12, policy = $13, updated_at = now()`,
+            room.Code, room.HostID, room.Mode, room.CreatorIP, room.CreatedAt, room.Suspended, info, room.Locked, room.WaitingRoom, schedule, room.Pinned,
+            room.Tenant, policy)
+        batch.Queue("DELETE FROM peers WHERE room_code = $1", room.Code)
+        batch.Queue("DELETE FROM file_offers WHERE room_code = $1", room.Code)
+
+        for _, peer := range room.Peers {
+            profile, err := json.Marshal(peer.PeerProfile)
+            if err != nil {
+                return err
+            }
+            batch.Queue(`INSERT INTO peers (room_code, peer_id, joined_at, last_seen, presence, role, permissions, profile, disconnected_at)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+                room.Code, peer.PeerID, peer.JoinedAt, peer.LastSeen.Load(), peer.Presence, peer.Role, peer.Permissions, profile, peer.DisconnectedAt)
+        }
+        for _, file := range room.Files {
+            var integrity []byte
+            if file.Integrity != nil {
+                if integrity, err = json.Marshal(file.Integrity); err != nil {
+                    return err
+                }
+            }
+            var entries []byte
+            if file.Entries != nil {
+                if entries, err = json.Marshal(file.Entries); err != nil {
+                    return err
+                }
+            }
+            var preview []byte
+            if file.Preview != nil {
+                if preview, err = json.Marshal(file.Preview); err != nil {
+                    return err
+                }
+            }
+            batch.Queue(`INSERT INTO file_offers (room_code, file_id, peer_id, name, size, mime_type, offered_at, relay, integrity, entries, preview)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+                room.Code, file.FileID, file.PeerID, file.Name, file.Size, file.MimeType, file.OfferedAt, file.Relay, integrity, entries, preview)
+        }
+        return tx.SendBatch(ctx, batch).Close()
+    })
+}
+
+func (p *Postgres) DeleteRoom(ctx context.Context, code string) error {
+    _, err := p.pool.Exec(ctx, "DELETE FROM rooms WHERE code = $1", code)
+    return err
+}
+
+func (p *Postgres) LoadRooms(ctx context.Context) ([]RoomRecord, error) {
+    rows, err := p.pool.Query(ctx, `SELECT code, host_id, mode, creator_ip, created_at, suspended, info, locked, waiting_room, schedule, pinned,
+        tenant, policy FROM rooms ORDER BY code`)
+    if err != nil {
+        return nil, err
+    }
+    list, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (RoomRecord, error) {
+        rec := RoomRecord{Peers: []rooms.PeerMetadata{}, Files: []rooms.FileOffer{}}
+        var info, schedule, policy []byte
+        if err := row.Scan(&rec.Code, &rec.HostID, &rec.Mode, &rec.CreatorIP, &rec.CreatedAt, &rec.Suspended, &info, &rec.Locked, &rec.WaitingRoom, &schedule, &rec.Pinned,
+            &rec.Tenant, &policy); err != nil {
+            return rec, err
+        }
+        if schedule != nil {
+            rec.Schedule = &rooms.Schedule{}
+            if err := json.Unmarshal(schedule, rec.Schedule); err != nil {
+                return rec, err
+            }
+        }
+        if err := json.Unmarshal(policy, &rec.Policy); err != nil {
+            return rec, err
+        }
+        return rec, json.Unmarshal(info, &rec.Info)
+    })
+    if err != nil {
+        return nil, err
+    }
+    byCode := make(map[string]*RoomRecord, len(list))
+    for i := range list {
+        byCode[list[i].Code] = &list[i]
+    }
+
+    rows, err = p.pool.Query(ctx, `SELECT room_code, peer_id, joined_at, last_seen, presence, role, permissions, profile, disconnected_at
+        FROM peers`)
+    if err != nil {
+        return nil, err
+    }
+    var code string
+    var peer rooms.PeerMetadata
+    var lastSeen int64
+    var profile []byte
+    _, err = pgx.ForEachRow(rows, []any{&code, &peer.PeerID, &peer.JoinedAt, &lastSeen, &peer.Presence, &peer.Role, &peer.Permissions, &profile, &peer.DisconnectedAt}, func() error {
+        rec, ok := byCode[code]
+        if !ok {
+            return nil
+        }
+        meta := peer
+        meta.PeerProfile = rooms.PeerProfile{}
+        if err := json.Unmarshal(profile, &meta.PeerProfile); err != nil {
+            return err
+        }
+        meta.LastSeen = rooms.NewClock(lastSeen)
+        rec.Peers = append(rec.Peers, meta)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err = p.pool.Query(ctx, "SELECT room_code, file_id, peer_id, name, size, mime_type, offered_at, relay, integrity, entries, preview FROM file_offers")
+    if err != nil {
+        return nil, err
+    }
+    var file rooms.FileOffer
+    var integrity, entries, preview []byte
+    _, err = pgx.ForEachRow(rows, []any{&code, &file.FileID, &file.PeerID, &file.Name, &file.Size, &file.MimeType, &file.OfferedAt, &file.Relay, &integrity, &entries, &preview}, func() error {
+        rec, ok := byCode[code]
+        if !ok {
+            return nil
+        }
+        offer := file
+        if integrity != nil {
+            offer.Integrity = &rooms.Integrity{}
+            if err := json.Unmarshal(integrity, offer.Integrity); err != nil {
+                return err
+            }
+        }
+        if entries != nil {
+            if err := json.Unmarshal(entries, &offer.Entries); err != nil {
+                return err
+            }
+        }
+        if preview != nil {
+            offer.Preview = &rooms.Preview{}
+            if err := json.Unmarshal(preview, offer.Preview); err != nil {
+                return err
+            }
+        }
+        rec.Files = append(rec.Files, offer)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (p *Postgres) AppendAudit(ctx context.Context, entries []AuditRecord) error {
+    if len(entries) == 0 {
+        return nil
+    }
+    batch := &pgx.Batch{}
+    for _, e := range entries {
+        var details []byte
+        if e.Details != nil {
+            var err error
+            if details, err = json.Marshal(e.Details); err != nil {
+                return err
+            }
+        }
+        batch.Queue(`INSERT INTO audit_log (id, time, room_code, type, actor, target, details)
+            VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO NOTHING`,
+            e.ID, e.Time, e.RoomCode, e.Type, e.Actor, e.Target, details)
+    }
+    return p.pool.SendBatch(ctx, batch).Close()
+}
+
+func (p *Postgres) LoadAudit(ctx context.Context, limit int) ([]AuditRecord, error) {
+    rows, err := p.pool.Query(ctx, `SELECT id, time, room_code, type, actor, target, details
+        FROM (SELECT * FROM audit_log ORDER BY id DESC LIMIT $1) newest ORDER BY id`, limit)
+    if err != nil {
+        return nil, err
+    }
+    return pgx.CollectRows(rows, func(row pgx.CollectableRow) (AuditRecord, error) {
+        var e AuditRecord
+        var details []byte
+        if err := row.Scan(&e.ID, &e.Time, &e.RoomCode, &e.Type, &e.Actor, &e.Target, &details); err != nil {
+            return e, err
+        }
+        if details != nil {
+            if err := json.Unmarshal(details, &e.Details); err != nil {
+                return e, err
+            }
+        }
+        return e, nil
+    })
+}
+
+func (p *Postgres) ErasePeerAudit(ctx context.Context, peerID string) error {
+    _, err := p.pool.Exec(ctx, "DELETE FROM audit_log WHERE actor = $1 OR target = $1", peerID)
+    return err
+}
+
+func (p *Postgres) PurgeAuditBefore(ctx context.Context, cutoff int64) error {
+    _, err := p.pool.Exec(ctx, "DELETE FROM audit_log WHERE time < $1", cutoff)
+    return err
+}
+
+func (p *Postgres) SaveFlag(ctx context.Context, flag FlagRecord) error {
+    _, err := p.pool.Exec(ctx, `INSERT INTO feature_flags (name, rule, updated_at) VALUES ($1, $2, $3)
+        ON CONFLICT (name) DO UPDATE SET rule = EXCLUDED.rule, updated_at = EXCLUDED.updated_at`,
+        flag.Name, []byte(flag.Rule), flag.UpdatedAt)
+    return err
+}
+
+func (p *Postgres) DeleteFlag(ctx context.Context, name string) error {
+    _, err := p.pool.Exec(ctx, "DELETE FROM feature_flags WHERE name = $1", name)
+    return err
+}
+
+func (p *Postgres) LoadFlags(ctx context.Context) ([]FlagRecord, error) {
+    rows, err := p.pool.Query(ctx, "SELECT name, rule, updated_at FROM feature_flags ORDER BY name")
+    if err != nil {
+        return nil, err
+    }
+    return pgx.CollectRows(rows, func(row pgx.CollectableRow) (FlagRecord, error) {
+        var flag FlagRecord
+        var rule []byte
+        err := row.Scan(&flag.Name, &rule, &flag.UpdatedAt)
+        flag.Rule = rule
+        return flag, err
+    })
+}
+
+func (p *Postgres) SaveUser(ctx context.Context, user UserRecord) error {
+    devices := []byte(user.Devices)
+    if len(devices) == 0 {
+        devices = []byte("[]")
+    }
+    var profile []byte
+    if len(user.Profile) > 0 {
+        profile = user.Profile
+    }
+    _, err := p.pool.Exec(ctx, `INSERT INTO users (id, profile, devices) VALUES ($1, $2, $3)
+        ON CONFLICT (id) DO UPDATE SET profile = EXCLUDED.profile, devices = EXCLUDED.devices`,
+        user.ID, profile, devices)
+    return err
+}
+
+func (p *Postgres) DeleteUser(ctx context.Context, id string) error {
+    _, err := p.pool.Exec(ctx, "DELETE FROM users WHERE id = $1", id)
+    return err
+}
+
+func (p *Postgres) LoadUsers(ctx context.Context) ([]UserRecord, error) {
+    rows, err := p.pool.Query(ctx, "SELECT id, profile, devices FROM users ORDER BY id")
+    if err != nil {
+        return nil, err
+    }
+    return pgx.CollectRows(rows, func(row pgx.CollectableRow) (UserRecord, error) {
+        var user UserRecord
+        var profile, devices []byte
+        err := row.Scan(&user.ID, &profile, &devices)
+        user.Profile, user.Devices = profile, devices
+        return user, err
+    })
+}
+
+func (p *Postgres) Ping(ctx context.Context) error {
+    return p.pool.Ping(ctx)
+}
+
+func (p *Postgres) Close() {
+    p.pool.Close()
+}
@@ -0,0 +1,190 @@
+// Package storage persists room state and the audit trail, so an instance
+// can restart without losing rooms. Live state stays in memory; a Store is
+// written to in the background and read back on startup.
+package storage
+
+import (
+    "context"
+    "encoding/json"
+    "log"
+    "os"
+
+    "p2p-file-share-backend/rooms"
+)
+
+// RoomRecord is a room as persisted: its settings, peers and file offers
+type RoomRecord struct {
+    Code      string               `json:"code"`
+    HostID    string               `json:"hostId"`
+    Mode      string               `json:"mode"`
+    CreatorIP string               `json:"creatorIp"`
+    CreatedAt int64                `json:"createdAt"`
+    Suspended bool                 `json:"suspended"`
+    Locked    bool                 `json:"locked"`
+    Info      rooms.RoomInfo       `json:"info"`
+    Peers     []rooms.PeerMetadata `json:"peers"`
+    Files     []rooms.FileOffer    `json:"files"`
+
+    // WaitingRoom is kept, but peers waiting for approval are not; they
+    // ask again after a restart
+    WaitingRoom bool `json:"waitingRoom"`
+
+    Schedule *rooms.Schedule `json:"schedule,omitempty"`
+    Pinned   bool            `json:"pinned,omitempty"`
+
+    Tenant string           `json:"tenant,omitempty"`
+    Policy rooms.FilePolicy `json:"policy"`
+}
+
+// AuditRecord is a persisted audit entry
+type AuditRecord struct {
+    ID       int64                  `json:"id"`
+    Time     int64                  `json:"time"`
+    RoomCode string                 `json:"roomCode"`
+    Type     string                 `json:"type"`
+    Actor    string                 `json:"actor,omitempty"`
+    Target   string                 `json:"target,omitempty"`
+    Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// FlagRecord is a feature flag set at runtime. The rule is kept as the
+// JSON the API accepted, so storage doesn't depend on its shape.
+type FlagRecord struct {
+    Name      string          `json:"name"`
+    Rule      json.RawMessage `json:"rule"`
+    UpdatedAt int64           `json:"updatedAt"`
+}
+
+// UserRecord is a signed-in user as persisted: its profile and registered
+// devices, kept as the JSON the API accepted, like flag rules
+type UserRecord struct {
+    ID      string          `json:"id"`
+    Profile json.RawMessage `json:"profile,omitempty"`
+    Devices json.RawMessage `json:"devices,omitempty"`
+}
+
+// Store is durable storage for rooms and the audit trail
+type Store interface {
+    // SaveRoom creates or replaces a room with its peers and files
+    SaveRoom(ctx context.Context, room RoomRecord) error
+    // DeleteRoom removes a room with its peers and files
+    DeleteRoom(ctx context.Context, code string) error
+    // LoadRooms returns every persisted room
+    LoadRooms(ctx context.Context) ([]RoomRecord, error)
+
+    // AppendAudit saves audit entries; entries already saved are skipped
+    AppendAudit(ctx context.Context, entries []AuditRecord) error
+    // LoadAudit returns up to limit of the newest audit entries, oldest first
+    LoadAudit(ctx context.Context, limit int) ([]AuditRecord, error)
+    // ErasePeerAudit deletes audit entries naming peerID as actor or target
+    ErasePeerAudit(ctx context.Context, peerID string) error
+    // PurgeAuditBefore deletes audit entries older than cutoff (Unix seconds)
+    PurgeAuditBefore(ctx context.Context, cutoff int64) error
+
+    // SaveFlag creates or replaces a runtime feature flag
+    SaveFlag(ctx context.Context, flag FlagRecord) error
+    // DeleteFlag removes a runtime feature flag
+    DeleteFlag(ctx context.Context, name string) error
+    // LoadFlags returns every runtime feature flag
+    LoadFlags(ctx context.Context) ([]FlagRecord, error)
+
+    // SaveUser creates or replaces a signed-in user with its devices
+    SaveUser(ctx context.Context, user UserRecord) error
+    // DeleteUser removes a signed-in user with its devices
+    DeleteUser(ctx context.Context, id string) error
+    // LoadUsers returns every persisted user
+    LoadUsers(ctx context.Context) ([]UserRecord, error)
+
+    // Ping checks the store is reachable
+    Ping(ctx context.Context) error
+    // Close releases the store's connections
+    Close()
+}
+
+// FromEnv returns the store selected by STORE_BACKEND, or nil to keep state
+// in memory only. STORE_BACKEND=postgres connects to DATABASE_URL;
+// STORE_BACKEND=sqlite (or bolt) keeps a single-node database file in
+// STORE_DATA_DIR (default ./data).
+func FromEnv() Store {
+    switch backend := os.Getenv("STORE_BACKEND"); backend {
+    case "", "memory":
+        return nil
+    case "postgres":
+        store, err := NewPostgres(context.Background(), os.Getenv("DATABASE_URL"))
+        if err != nil {
+            log.Fatalf("❌ Failed to open Postgres store: %v", err)
+        }
+        log.Println("🗄️  Persisting rooms to Postgres")
+        return store
+    case "sqlite", "bolt":
+        dir := os.Getenv("STORE_DATA_DIR")
+        if dir == "" {
+            dir = "data"
+        }
+        store, err := NewBolt(dir)
+        if err != nil {
+            log.Fatalf("❌ Failed to open local store: %v", err)
+        }
+        log.Printf("🗄️  Persisting rooms to %s", dir)
+        return store
+    default:
+        log.Fatalf("❌ Unknown STORE_BACKEND %q", backend)
+        return nil
+    }
+}
+
+// Record captures a room for saving. The caller must hold the room lock.
+func Record(code string, room *rooms.Room) RoomRecord {
+    rec := RoomRecord{
+        Code:        code,
+        HostID:      room.HostID,
+        Mode:        room.Mode,
+        CreatorIP:   room.CreatorIP,
+        CreatedAt:   room.CreatedAt,
+        Suspended:   room.Suspended,
+        Locked:      room.Locked,
+        WaitingRoom: room.WaitingRoom,
+        Schedule:    room.Schedule,
+        Pinned:      room.Pinned,
+        Tenant:      room.Tenant,
+        Policy:      room.Policy,
+        Info:        room.Info,
+        Peers:       make([]rooms.PeerMetadata, 0, len(room.Peers)),
+        Files:       make([]rooms.FileOffer, 0, len(room.Files)),
+    }
+    for _, peer := range room.Peers {
+        p := *peer
+        p.LastSeen = rooms.NewClock(peer.LastSeen.Load())
+        rec.Peers = append(rec.Peers, p)
+    }
+    for _, file := range room.Files {
+        rec.Files = append(rec.Files, *file)
+    }
+    return rec
+}
+
+// Room rebuilds a live room from its record
+func (rec RoomRecord) Room() *rooms.Room {
+    room := rooms.New(rec.HostID, rec.Mode, rec.CreatorIP, rec.CreatedAt)
+    room.Suspended = rec.Suspended
+    room.Locked = rec.Locked
+    room.WaitingRoom = rec.WaitingRoom
+    room.Schedule = rec.Schedule
+    room.Pinned = rec.Pinned
+    room.Tenant = rec.Tenant
+    room.Policy = rec.Policy
+    room.Info = rec.Info
+    for i := range rec.Peers {
+        peer := rec.Peers[i]
+        if peer.LastSeen == nil {
+            peer.LastSeen = rooms.NewClock(0)
+        }
+        room.Peers[peer.PeerID] = &peer
+    }
+    for i := range rec.Files {
+        file := rec.Files[i]
+        room.Files[file.FileID] = &file
+    }
+    room.Touch()
+    return room
+}
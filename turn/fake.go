@@ -0,0 +1,63 @@
+package turn
+
+import "context"
+
+// Credentials handed out by Fake
+const (
+    FakeUsername   = "test-user"
+    FakeCredential = "test-credential"
+)
+
+// Fake is a Provider for tests and local development. It never touches the
+// network and always returns the same credentials.
+type Fake struct {
+    // Err, if set, is returned by Credentials and Verify to simulate an
+    // unavailable provider
+    Err error
+}
+
+// NewFake returns a Fake that succeeds
+func NewFake() *Fake {
+    return &Fake{}
+}
+
+// Credentials returns fixed STUN and TURN servers on localhost
+func (f *Fake) Credentials(ctx context.Context) (*Credentials, error) {
+    if f.Err != nil {
+        return nil, f.Err
+    }
+    return &Credentials{
+        ICEServers: []map[string]interface{}{
+            {"urls": "stun:127.0.0.1:3478"},
+            {
+                "urls":       "turn:127.0.0.1:3478?transport=udp",
+                "username":   FakeUsername,
+                "credential": FakeCredential,
+            },
+        },
+        TTL: "86400",
+    }, nil
+}
+
+// Verify always reports the fake as healthy unless Err is set
+func (f *Fake) Verify(ctx context.Context) (string, error) {
+    if f.Err != nil {
+        return "", f.Err
+    }
+    return "fake provider", nil
+}
+
+// Regions mirrors Twilio's edges so region selection can be tested
+func (f *Fake) Regions() []Region {
+    return TwilioEdges
+}
+
+// RegionalCredentials returns the fixed credentials tagged with region
+func (f *Fake) RegionalCredentials(ctx context.Context, region string) (*Credentials, error) {
+    creds, err := f.Credentials(ctx)
+    if err != nil {
+        return nil, err
+    }
+    creds.Region = region
+    return creds, nil
+}
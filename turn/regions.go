@@ -0,0 +1,79 @@
+package turn
+
+import (
+    "context"
+    "math"
+    "strings"
+)
+
+// Region is a relay location clients can be steered to
+type Region struct {
+    Name string
+    Lat  float64
+    Lon  float64
+}
+
+// TwilioEdges are Twilio's edge locations. Credentials for an edge point
+// their TURN URLs at <edge>.turn.twilio.com instead of the global,
+// latency-routed hostname.
+var TwilioEdges = []Region{
+    {Name: "ashburn", Lat: 39.04, Lon: -77.49},
+    {Name: "umatilla", Lat: 45.92, Lon: -119.34},
+    {Name: "dublin", Lat: 53.35, Lon: -6.26},
+    {Name: "frankfurt", Lat: 50.11, Lon: 8.68},
+    {Name: "sao-paulo", Lat: -23.55, Lon: -46.63},
+    {Name: "singapore", Lat: 1.35, Lon: 103.82},
+    {Name: "sydney", Lat: -33.87, Lon: 151.21},
+    {Name: "tokyo", Lat: 35.68, Lon: 139.69},
+}
+
+// Regional is implemented by providers that can issue credentials for a
+// specific region
+type Regional interface {
+    // Regions lists the regions the provider serves
+    Regions() []Region
+    // RegionalCredentials returns credentials whose relays are in region
+    RegionalCredentials(ctx context.Context, region string) (*Credentials, error)
+}
+
+// Nearest returns the name of the region closest to lat, lon
+func Nearest(regions []Region, lat, lon float64) string {
+    best, bestDist := "", math.Inf(1)
+    for _, r := range regions {
+        if d := greatCircle(lat, lon, r.Lat, r.Lon); d < bestDist {
+            best, bestDist = r.Name, d
+        }
+    }
+    return best
+}
+
+// greatCircle returns the angular distance in radians between two points
+func greatCircle(lat1, lon1, lat2, lon2 float64) float64 {
+    rad := math.Pi / 180
+    dLat := (lat2 - lat1) * rad
+    dLon := (lon2 - lon1) * rad
+    h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+        math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+    return 2 * math.Asin(math.Sqrt(h))
+}
+
+func (t *Twilio) Regions() []Region {
+    return TwilioEdges
+}
+
+// RegionalCredentials issues a token and rewrites its TURN URLs to the edge
+func (t *Twilio) RegionalCredentials(ctx context.Context, region string) (*Credentials, error) {
+    creds, err := t.Credentials(ctx)
+    if err != nil {
+        return nil, err
+    }
+    for _, server := range creds.ICEServers {
+        for _, key := range []string{"url", "urls"} {
+            if url, ok := server[key].(string); ok {
+                server[key] = strings.Replace(url, "global.turn.twilio.com", region+".turn.twilio.com", 1)
+            }
+        }
+    }
+    creds.Region = region
+    return creds, nil
+}
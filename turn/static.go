@@ -0,0 +1,149 @@
+package turn
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha1"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// DefaultSTUNServers is the ICE configuration used in STUN-only mode
+var DefaultSTUNServers = []map[string]interface{}{
+    {"urls": []string{"stun:stun.l.google.com:19302", "stun:stun1.l.google.com:19302"}},
+}
+
+// Static serves an operator-configured ICE server list without calling any
+// external API, for air-gapped or cost-sensitive deployments. TURN servers
+// with no username get short-lived credentials from SharedSecret using the
+// TURN REST API scheme understood by coturn (use-auth-secret).
+type Static struct {
+    Servers      []map[string]interface{}
+    SharedSecret string
+    TTL          time.Duration
+
+    mu sync.RWMutex
+}
+
+// NewStatic returns a provider serving servers as configured
+func NewStatic(servers []map[string]interface{}, sharedSecret string, ttl time.Duration) *Static {
+    if ttl <= 0 {
+        ttl = 24 * time.Hour
+    }
+    return &Static{Servers: servers, SharedSecret: sharedSecret, TTL: ttl}
+}
+
+// staticFromEnv reads the server list from ICE_SERVERS (inline JSON) or
+// ICE_SERVERS_FILE, falling back to public STUN only. TURN_SHARED_SECRET and
+// TURN_CREDENTIAL_TTL configure coturn credentials.
+func staticFromEnv() (*Static, error) {
+    servers := DefaultSTUNServers
+
+    data := []byte(os.Getenv("ICE_SERVERS"))
+    if path := os.Getenv("ICE_SERVERS_FILE"); len(data) == 0 && path != "" {
+        var err error
+        if data, err = os.ReadFile(path); err != nil {
+            return nil, err
+        }
+    }
+    if len(data) > 0 {
+        servers = nil
+        if err := json.Unmarshal(data, &servers); err != nil {
+            return nil, fmt.Errorf("invalid ICE server list: %v", err)
+        }
+        for i, server := range servers {
+            if _, ok := server["urls"]; !ok {
+                return nil, fmt.Errorf("ICE server %d has no urls", i)
+            }
+        }
+    }
+
+    var ttl time.Duration
+    if v := os.Getenv("TURN_CREDENTIAL_TTL"); v != "" {
+        var err error
+        if ttl, err = time.ParseDuration(v); err != nil {
+            return nil, fmt.Errorf("invalid TURN_CREDENTIAL_TTL: %v", err)
+        }
+    }
+    return NewStatic(servers, os.Getenv("TURN_SHARED_SECRET"), ttl), nil
+}
+
+// SetSharedSecret replaces the coturn shared secret, e.g. after it is
+// rotated in a secrets manager
+func (s *Static) SetSharedSecret(secret string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.SharedSecret = secret
+}
+
+// Credentials returns the configured servers, minting credentials for TURN
+// servers that need them
+func (s *Static) Credentials(ctx context.Context) (*Credentials, error) {
+    s.mu.RLock()
+    secret := s.SharedSecret
+    s.mu.RUnlock()
+
+    expires := time.Now().Add(s.TTL).Unix()
+    username := strconv.FormatInt(expires, 10)
+    mac := hmac.New(sha1.New, []byte(secret))
+    mac.Write([]byte(username))
+    credential := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+    servers := make([]map[string]interface{}, 0, len(s.Servers))
+    for _, server := range s.Servers {
+        entry := make(map[string]interface{}, len(server)+2)
+        for k, v := range server {
+            entry[k] = v
+        }
+        if _, hasUser := entry["username"]; !hasUser && secret != "" && isTURN(entry["urls"]) {
+            entry["username"] = username
+            entry["credential"] = credential
+        }
+        servers = append(servers, entry)
+    }
+    return &Credentials{ICEServers: servers, TTL: strconv.Itoa(int(s.TTL.Seconds()))}, nil
+}
+
+// Verify reports what is configured; there is nothing remote to check
+func (s *Static) Verify(ctx context.Context) (string, error) {
+    if len(s.Servers) == 0 {
+        return "", fmt.Errorf("no ICE servers configured")
+    }
+    turnCount := 0
+    for _, server := range s.Servers {
+        if isTURN(server["urls"]) {
+            turnCount++
+        }
+    }
+    return fmt.Sprintf("static: %d ICE servers (%d TURN)", len(s.Servers), turnCount), nil
+}
+
+// isTURN reports whether urls (a string or list of strings) names a TURN
+// server rather than only STUN
+func isTURN(urls interface{}) bool {
+    var list []string
+    switch v := urls.(type) {
+    case string:
+        list = []string{v}
+    case []string:
+        list = v
+    case []interface{}:
+        for _, u := range v {
+            if s, ok := u.(string); ok {
+                list = append(list, s)
+            }
+        }
+    }
+    for _, u := range list {
+        if strings.HasPrefix(u, "turn:") || strings.HasPrefix(u, "turns:") {
+            return true
+        }
+    }
+    return false
+}
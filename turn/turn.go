@@ -0,0 +1,80 @@
+// Package turn fetches ICE server credentials (STUN/TURN) for peers that
+// cannot connect directly.
+package turn
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+)
+
+// Credentials is the ICE configuration handed to clients
+type Credentials struct {
+    ICEServers []map[string]interface{} `json:"iceServers"`
+    TTL        string                   `json:"ttl"`
+    // Region is set when the relays were picked for the client's location
+    Region string `json:"region,omitempty"`
+}
+
+// Provider issues ICE credentials
+type Provider interface {
+    // Credentials returns a fresh set of ICE servers
+    Credentials(ctx context.Context) (*Credentials, error)
+    // Verify checks the provider's configuration without issuing
+    // credentials, returning a short human-readable status
+    Verify(ctx context.Context) (string, error)
+}
+
+// APIError is a non-success response from the provider's API
+type APIError struct {
+    Provider string
+    Status   int
+    Body     string
+}
+
+func (e *APIError) Error() string {
+    return fmt.Sprintf("%s API error: %d", e.Provider, e.Status)
+}
+
+// FromEnv returns the provider configured by the environment, or nil if
+// none is. TURN_PROVIDER=fake selects the Fake provider, e.g. for frontend CI;
+// TURN_PROVIDER=static serves a fixed ICE server list (see Static), and
+// TURN_PROVIDER=stun public STUN servers only. Otherwise Twilio is used when
+// TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN are set.
+func FromEnv() Provider {
+    switch os.Getenv("TURN_PROVIDER") {
+    case "fake":
+        return NewFake()
+    case "static":
+        provider, err := staticFromEnv()
+        if err != nil {
+            log.Fatalf("❌ Invalid static ICE configuration: %v", err)
+        }
+        return provider
+    case "stun":
+        return NewStatic(DefaultSTUNServers, "", 0)
+    }
+
+    accountSid := os.Getenv("TWILIO_ACCOUNT_SID")
+    authToken := os.Getenv("TWILIO_AUTH_TOKEN")
+    if accountSid == "" || authToken == "" {
+        return nil
+    }
+    return NewTwilio(accountSid, authToken)
+}
+
+// Reload re-reads p's secrets from the environment after they have been
+// rotated. Providers without secrets are left as they are.
+func Reload(p Provider) {
+    switch p := p.(type) {
+    case *Twilio:
+        accountSid := os.Getenv("TWILIO_ACCOUNT_SID")
+        authToken := os.Getenv("TWILIO_AUTH_TOKEN")
+        if accountSid != "" && authToken != "" {
+            p.SetCredentials(accountSid, authToken)
+        }
+    case *Static:
+        p.SetSharedSecret(os.Getenv("TURN_SHARED_SECRET"))
+    }
+}
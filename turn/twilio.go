@@ -0,0 +1,106 @@
+package turn
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// Twilio issues credentials from Twilio's Network Traversal Service
+type Twilio struct {
+    AccountSID string
+    AuthToken  string
+    Client     *http.Client
+
+    mu sync.RWMutex
+}
+
+// NewTwilio returns a Twilio provider for the given account
+func NewTwilio(accountSid, authToken string) *Twilio {
+    return &Twilio{
+        AccountSID: accountSid,
+        AuthToken:  authToken,
+        Client:     &http.Client{Timeout: 10 * time.Second},
+    }
+}
+
+// SetCredentials replaces the account credentials, e.g. after they are
+// rotated in a secrets manager
+func (t *Twilio) SetCredentials(accountSid, authToken string) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.AccountSID = accountSid
+    t.AuthToken = authToken
+}
+
+func (t *Twilio) account() (string, string) {
+    t.mu.RLock()
+    defer t.mu.RUnlock()
+    return t.AccountSID, t.AuthToken
+}
+
+// Credentials creates a short-lived Twilio token
+func (t *Twilio) Credentials(ctx context.Context) (*Credentials, error) {
+    accountSid, authToken := t.account()
+    url := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Tokens.json", accountSid)
+
+    req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.SetBasicAuth(accountSid, authToken)
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    resp, err := t.Client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    // Log the response status
+    log.Printf("📥 Twilio API response status: %d", resp.StatusCode)
+
+    if resp.StatusCode != http.StatusCreated {
+        // Read the error body for debugging
+        body, _ := io.ReadAll(resp.Body)
+        log.Printf("❌ Twilio API error body: %s", string(body))
+        return nil, &APIError{Provider: "Twilio", Status: resp.StatusCode, Body: string(body)}
+    }
+
+    var result struct {
+        IceServers []map[string]interface{} `json:"ice_servers"`
+        TTL        string                   `json:"ttl"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return nil, fmt.Errorf("failed to parse Twilio response: %v", err)
+    }
+
+    return &Credentials{ICEServers: result.IceServers, TTL: result.TTL}, nil
+}
+
+// Verify validates the credentials against the account endpoint
+func (t *Twilio) Verify(ctx context.Context) (string, error) {
+    accountSid, authToken := t.account()
+    url := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s.json", accountSid)
+    req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+    if err != nil {
+        return "", err
+    }
+    req.SetBasicAuth(accountSid, authToken)
+
+    resp, err := t.Client.Do(req)
+    if err != nil {
+        return "", err
+    }
+    resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("Twilio API returned %d", resp.StatusCode)
+    }
+    return "credentials valid", nil
+}